@@ -3,10 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/conops/conops/internal/api"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +21,7 @@ var (
 	addPollInterval  string
 	addAuthMethod    string
 	addDeployKey     string
+	addDeployKeyFile string
 	addSkipPrompts   bool
 )
 
@@ -36,6 +39,9 @@ Examples:
   # Using flags (non-interactive)
   conops-ctl apps add --name "MyApp" --repo "https://github.com/user/repo" --branch main --yes
 
+  # A private repo, with its deploy key read from a file
+  conops-ctl apps add --name "MyApp" --repo "git@github.com:user/repo.git" --deploy-key-file ~/.ssh/deploy_key --yes
+
   # Interactive mode (just run add)
   conops-ctl apps add`,
 	Args: cobra.MaximumNArgs(1),
@@ -141,6 +147,32 @@ Examples:
 					if err != nil { return err }
 					appData["poll_interval"] = result
 				}
+
+				// Repository Access
+				if addAuthMethod == "" && addDeployKey == "" && addDeployKeyFile == "" {
+					prompt := promptui.Select{
+						Label: "Repository Access",
+						Items: []string{"public", "deploy_key"},
+					}
+					_, result, err := prompt.Run()
+					if err != nil { return err }
+					addAuthMethod = result
+				}
+
+				// Deploy Key, read from a file so it is not pasted into the terminal
+				if addAuthMethod == "deploy_key" && addDeployKey == "" && addDeployKeyFile == "" {
+					prompt := promptui.Prompt{
+						Label:   "Deploy Key File",
+						Default: "~/.ssh/id_ed25519",
+						Validate: func(input string) error {
+							_, err := readDeployKey(input)
+							return err
+						},
+					}
+					result, err := prompt.Run()
+					if err != nil { return err }
+					addDeployKeyFile = result
+				}
 			}
 
 			// Auth Config (Flags only for now)
@@ -149,6 +181,11 @@ Examples:
 			} else {
 				appData["repo_auth_method"] = "public"
 			}
+			if addDeployKeyFile != "" {
+				key, err := readDeployKey(addDeployKeyFile)
+				if err != nil { return err }
+				addDeployKey = key
+			}
 			if addDeployKey != "" {
 				appData["deploy_key"] = addDeployKey
 				appData["repo_auth_method"] = "deploy_key"
@@ -166,9 +203,12 @@ Examples:
 			return CheckResponse(resp)
 		}
 
-		var apiResp api.APIResponse
-		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-			return fmt.Errorf("error decoding response: %v", err)
+		apiResp, err := decodeResponse(resp.Body)
+		if err != nil {
+			return err
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
 		}
 
 		fmt.Println("App registered successfully.")
@@ -176,6 +216,33 @@ Examples:
 	},
 }
 
+// readDeployKey reads a private key from path, which may start with ~/, or
+// from stdin when path is -.
+func readDeployKey(path string) (string, error) {
+	var data []byte
+	var err error
+	switch {
+	case path == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(path, "~/"):
+		home, homeErr := os.UserHomeDir()
+		if homeErr != nil {
+			return "", fmt.Errorf("error reading deploy key: %v", homeErr)
+		}
+		data, err = os.ReadFile(filepath.Join(home, path[2:]))
+	default:
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading deploy key: %v", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if !strings.Contains(key, "PRIVATE KEY-----") {
+		return "", fmt.Errorf("deploy key %s is not an SSH private key", path)
+	}
+	return key + "\n", nil
+}
+
 func init() {
 	addCmd.Flags().StringVar(&addName, "name", "", "Application name")
 	addCmd.Flags().StringVar(&addRepoURL, "repo", "", "Git repository URL")
@@ -183,7 +250,9 @@ func init() {
 	addCmd.Flags().StringVar(&addComposePath, "compose-path", "", "Path to compose file (default: docker-compose.yml)")
 	addCmd.Flags().StringVar(&addPollInterval, "poll-interval", "", "Sync interval (default: 30s)")
 	addCmd.Flags().StringVar(&addAuthMethod, "auth-method", "", "Auth method: public or deploy_key")
-	addCmd.Flags().StringVar(&addDeployKey, "deploy-key", "", "SSH private key for private repos")
+	addCmd.Flags().StringVar(&addDeployKey, "deploy-key", "", "SSH private key for private repos (prefer --deploy-key-file)")
+	addCmd.Flags().StringVar(&addDeployKeyFile, "deploy-key-file", "", "File with the SSH private key for private repos, or - for stdin")
+	addCmd.MarkFlagsMutuallyExclusive("deploy-key", "deploy-key-file")
 	addCmd.Flags().BoolVarP(&addSkipPrompts, "yes", "y", false, "Skip interactive prompts (use defaults)")
 
 	appsCmd.AddCommand(addCmd)
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// adminCmd represents the admin command
+var adminCmd = &cobra.Command{
+	Use:     "admin",
+	Short:   "Maintain the controller",
+	Example: `  conops-ctl admin rotate-key`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// rotateKeyCmd represents the admin rotate-key command
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Re-encrypt all credentials with a new encryption key",
+	Long: `Generate a new credential encryption key, re-encrypt every app's deploy key, env vars and docker TLS
+material with it in one transaction, and replace the controller's key file. With Vault transit, the
+transit key is rotated in Vault instead. If anything fails to re-encrypt, nothing changes and the old
+key stays in use. Keys set with CONOPS_ENCRYPTION_KEY cannot be
+rotated this way. Back up the old key file first if you keep database backups taken with it.`,
+	Args:              cobra.NoArgs,
+	Example:           `  conops-ctl admin rotate-key`,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		client.Client.Timeout = 5 * time.Minute
+		resp, err := client.Post("/api/v1/admin/rotate-key", nil)
+		if err != nil {
+			return fmt.Errorf("error rotating key: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Message string `json:"message"`
+			Data    struct {
+				KeyFile   string `json:"key_file"`
+				KeySource string `json:"key_source"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		fmt.Println(apiResp.Message)
+		if apiResp.Data.KeyFile != "" {
+			fmt.Printf("Key file: %s\n", apiResp.Data.KeyFile)
+		} else {
+			fmt.Printf("Key: %s\n", apiResp.Data.KeySource)
+		}
+		return nil
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(rotateKeyCmd)
+	rootCmd.AddCommand(adminCmd)
+}
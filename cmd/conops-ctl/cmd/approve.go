@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var approveCommit string
+
+// approveCmd represents the approve command
+var approveCmd = &cobra.Command{
+	Use:   "approve [app-id]",
+	Short: "Approve the commit an application is holding for approval",
+	Long:  `Release the commit held in awaiting_approval so the reconciler deploys it. Review it first with "apps diff".`,
+	Args:  cobra.ExactArgs(1),
+	Example: `  conops-ctl apps diff <app-id>
+  conops-ctl apps approve <app-id> --commit 3f2a9c1`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Post("/api/v1/apps/"+args[0]+"/approve", map[string]string{"commit": approveCommit})
+		if err != nil {
+			return fmt.Errorf("error approving app: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Message string  `json:"message"`
+			Data    api.App `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
+		}
+		fmt.Printf("Approved %s; it deploys on the next reconcile.\n", apiResp.Data.LastSeenCommit)
+		return nil
+	},
+}
+
+func init() {
+	approveCmd.Flags().StringVar(&approveCommit, "commit", "", "Only approve if this (full or 7+ character) commit is the one held")
+	appsCmd.AddCommand(approveCmd)
+}
@@ -9,6 +9,8 @@ var appsCmd = &cobra.Command{
 	Use:   "apps",
 	Short: "Manage applications",
 	Long:  `Manage registered applications (list, add, update, delete, sync).`,
+	Example: `  conops-ctl apps list
+  conops-ctl apps status <app-id>`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
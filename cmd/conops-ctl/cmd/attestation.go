@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/provenance"
+	"github.com/spf13/cobra"
+)
+
+var (
+	attestationSync   string
+	attestationVerify bool
+)
+
+// attestationCmd represents the attestation command
+var attestationCmd = &cobra.Command{
+	Use:   "attestation [app-id]",
+	Short: "Print the signed provenance of an application's latest attested sync",
+	Long: `Print the DSSE envelope of the most recent sync that has an attestation, or of the sync given with --sync.
+With --verify, the signature is checked against the controller's key and the decoded statement is printed instead.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  conops-ctl apps attestation <app-id> > provenance.json
+  conops-ctl apps attestation <app-id> --verify`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + args[0] + "/history")
+		if err != nil {
+			return fmt.Errorf("error fetching sync history: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data []api.SyncRecord `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		var attestation *api.Attestation
+		for _, record := range apiResp.Data {
+			if record.Attestation != nil && (attestationSync == "" || record.ID == attestationSync) {
+				attestation = record.Attestation
+				break
+			}
+		}
+		if attestation == nil {
+			if attestationSync != "" {
+				return fmt.Errorf("sync %s has no attestation", attestationSync)
+			}
+			return fmt.Errorf("no attested sync in the app's history")
+		}
+
+		if !attestationVerify {
+			PrintJSON(attestation)
+			return nil
+		}
+
+		keyResp, err := client.Get("/api/v1/attestation/key")
+		if err != nil {
+			return fmt.Errorf("error fetching attestation key: %v", err)
+		}
+		defer keyResp.Body.Close()
+		if keyResp.StatusCode != http.StatusOK {
+			return CheckResponse(keyResp)
+		}
+		keyPEM, err := io.ReadAll(keyResp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading attestation key: %v", err)
+		}
+		pub, err := provenance.ParsePublicKey(keyPEM)
+		if err != nil {
+			return fmt.Errorf("invalid attestation key: %v", err)
+		}
+		statement, err := provenance.Verify(attestation, pub)
+		if err != nil {
+			return fmt.Errorf("attestation does not verify: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Verified signature by key %s\n", provenance.KeyID(pub))
+		PrintJSON(statement)
+		return nil
+	},
+}
+
+func init() {
+	attestationCmd.Flags().StringVar(&attestationSync, "sync", "", "ID of the sync to print, from \"apps history\" output as JSON")
+	attestationCmd.Flags().BoolVar(&attestationVerify, "verify", false, "Verify the signature and print the decoded statement")
+	appsCmd.AddCommand(attestationCmd)
+}
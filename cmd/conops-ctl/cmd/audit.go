@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditAppID string
+	auditLimit int
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:     "audit",
+	Short:   "Inspect the audit log",
+	Long:    `Inspect the record of mutating API calls (create, update, delete, sync).`,
+	Example: `  conops-ctl audit list --app <app-id>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// auditListCmd represents the audit list command
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent audit entries",
+	Example: `  conops-ctl audit list
+  conops-ctl audit list --app <app-id> --limit 20`,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := url.Values{}
+		if auditAppID != "" {
+			query.Set("app_id", auditAppID)
+		}
+		if auditLimit > 0 {
+			query.Set("limit", strconv.Itoa(auditLimit))
+		}
+		path := "/api/v1/audit"
+		if encoded := query.Encode(); encoded != "" {
+			path += "?" + encoded
+		}
+
+		client := NewClient()
+		resp, err := client.Get(path)
+		if err != nil {
+			return fmt.Errorf("error fetching audit log: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data []api.AuditEntry `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTOR\tSOURCE\tACTION\tAPP\tOUTCOME\tCHANGES")
+		for _, entry := range apiResp.Data {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				entry.CreatedAt.Format(time.RFC3339),
+				entry.Actor,
+				entry.Source,
+				entry.Action,
+				entry.AppID,
+				entry.Outcome,
+				formatAuditChanges(entry.Changes),
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+func formatAuditChanges(changes map[string]api.FieldChange) string {
+	if len(changes) == 0 {
+		return "-"
+	}
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		change := changes[field]
+		parts = append(parts, fmt.Sprintf("%s: %q -> %q", field, change.From, change.To))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	auditListCmd.Flags().StringVar(&auditAppID, "app", "", "Only show entries for this app ID")
+	auditListCmd.Flags().IntVar(&auditLimit, "limit", 0, "Maximum number of entries (default 100)")
+	auditListCmd.RegisterFlagCompletionFunc("app", completeAppIDs)
+	auditCmd.AddCommand(auditListCmd)
+	rootCmd.AddCommand(auditCmd)
+}
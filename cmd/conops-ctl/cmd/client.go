@@ -7,9 +7,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/version"
 	"github.com/spf13/viper"
 )
 
@@ -17,53 +21,119 @@ import (
 type APIClient struct {
 	BaseURL string
 	Client  *http.Client
+	// Token is sent as a bearer token when set.
+	Token string
+	// SkipVersionCheck disables the skew check on responses.
+	SkipVersionCheck bool
 }
 
 // NewClient creates a new APIClient
 func NewClient() *APIClient {
 	return &APIClient{
-		BaseURL: viper.GetString("url"),
+		BaseURL: strings.TrimRight(viper.GetString("url"), "/"),
+		Token:   viper.GetString("token"),
 		Client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: clientTransport,
 		},
 	}
 }
 
 // Get performs a GET request
 func (c *APIClient) Get(path string) (*http.Response, error) {
-	return c.Client.Get(c.BaseURL + path)
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
 }
 
 // Post performs a POST request
 func (c *APIClient) Post(path string, body interface{}) (*http.Response, error) {
-	jsonBody, err := json.Marshal(body)
+	return c.sendJSON(http.MethodPost, path, body)
+}
+
+// Patch performs a PATCH request
+func (c *APIClient) Patch(path string, body interface{}) (*http.Response, error) {
+	return c.sendJSON(http.MethodPatch, path, body)
+}
+
+// Delete performs a DELETE request
+func (c *APIClient) Delete(path string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodDelete, c.BaseURL+path, nil)
 	if err != nil {
 		return nil, err
 	}
-	return c.Client.Post(c.BaseURL+path, "application/json", bytes.NewBuffer(jsonBody))
+	return c.do(req)
 }
 
-// Patch performs a PATCH request
-func (c *APIClient) Patch(path string, body interface{}) (*http.Response, error) {
+func (c *APIClient) sendJSON(method, path string, body interface{}) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(http.MethodPatch, c.BaseURL+path, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequest(method, c.BaseURL+path, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	return c.Client.Do(req)
+	return c.do(req)
 }
 
-// Delete performs a DELETE request
-func (c *APIClient) Delete(path string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodDelete, c.BaseURL+path, nil)
-	if err != nil {
-		return nil, err
+// do sends req and refuses responses from a controller this CLI cannot
+// talk to safely.
+func (c *APIClient) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", "conops-ctl/"+Version)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil || c.SkipVersionCheck || skipSkewCheck() {
+		return resp, err
+	}
+
+	controllerVersion := resp.Header.Get(version.HeaderVersion)
+	if controllerVersion == "" {
+		// Controllers older than the version headers.
+		return resp, nil
+	}
+	apiVersion, _ := strconv.Atoi(resp.Header.Get(version.HeaderAPIVersion))
+	if err := checkSkew(controllerVersion, apiVersion, resp.Header.Get(version.HeaderMinClientVersion)); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w (set CONOPS_SKIP_VERSION_CHECK=true to override)", err)
+	}
+	if warning := skewWarning(controllerVersion); warning != "" {
+		skewWarnOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		})
+	}
+	return resp, nil
+}
+
+var skewWarnOnce sync.Once
+
+func skipSkewCheck() bool {
+	return viper.GetBool("skip_version_check")
+}
+
+// checkSkew reports an error when the controller speaks a different API
+// version or requires a newer CLI.
+func checkSkew(controllerVersion string, apiVersion int, minClientVersion string) error {
+	if apiVersion != 0 && apiVersion != version.APIVersion {
+		return fmt.Errorf("controller %s speaks API v%d but conops-ctl %s speaks v%d; install a matching conops-ctl", controllerVersion, apiVersion, Version, version.APIVersion)
+	}
+	if cmp, ok := version.Compare(Version, minClientVersion); ok && cmp < 0 {
+		return fmt.Errorf("conops-ctl %s is older than %s, the oldest release controller %s supports; upgrade conops-ctl", Version, minClientVersion, controllerVersion)
+	}
+	return nil
+}
+
+// skewWarning describes a compatible but mismatched controller release.
+func skewWarning(controllerVersion string) string {
+	if _, ok := version.Compare(Version, controllerVersion); !ok || version.SameMinor(Version, controllerVersion) {
+		return ""
 	}
-	return c.Client.Do(req)
+	return fmt.Sprintf("conops-ctl %s and controller %s are different releases; some flags or fields may not be supported", Version, controllerVersion)
 }
 
 // CheckResponse checks the API response for errors
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// Completions ask the controller while the user waits at a prompt, so they
+// give up sooner than commands do and print nothing when it fails.
+const completionTimeout = 3 * time.Second
+
+// completeAppIDs completes the first argument with the IDs of the
+// controller's apps, described by their names.
+func completeAppIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	client := NewClient()
+	client.Client.Timeout = completionTimeout
+	resp, err := client.Get("/api/v1/apps/?fields=summary")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		Data []api.App `json:"data"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&apiResp) != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for _, app := range apiResp.Data {
+		if strings.HasPrefix(app.ID, toComplete) {
+			ids = append(ids, app.ID+"\t"+app.Name)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAppServices completes an app ID and then the services of that
+// app's running containers.
+func completeAppServices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeAppIDs(cmd, args, toComplete)
+	}
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	client := NewClient()
+	client.Client.Timeout = completionTimeout
+	containers, _, err := listContainers(client, args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	seen := make(map[string]bool)
+	var services []string
+	for _, c := range containers {
+		if !seen[c.Service] && strings.HasPrefix(c.Service, toComplete) {
+			seen[c.Service] = true
+			services = append(services, c.Service)
+		}
+	}
+	return services, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeContexts completes the names of the contexts in the config file.
+func completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	config, _, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, c := range config.Contexts {
+		if strings.HasPrefix(c.Name, toComplete) {
+			names = append(names, c.Name+"\t"+c.URL)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// appStatuses are the states "apps list --status" filters on.
+var appStatuses = []string{"pending", "syncing", "synced", "error", "awaiting_approval", "blocked_unsigned", "stopped"}
+
+// completeStatuses completes the last state of a comma-separated list.
+func completeStatuses(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	chosen := toComplete[:strings.LastIndex(toComplete, ",")+1]
+	statuses := make([]string, 0, len(appStatuses))
+	for _, status := range appStatuses {
+		statuses = append(statuses, chosen+status)
+	}
+	return statuses, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
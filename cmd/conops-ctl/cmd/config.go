@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// cliConfig is the file that names the controllers conops-ctl talks to,
+// ~/.conops/config.yaml unless CONOPS_CONFIG says otherwise.
+type cliConfig struct {
+	CurrentContext string       `yaml:"current-context,omitempty" json:"current_context,omitempty"`
+	Contexts       []cliContext `yaml:"contexts" json:"contexts"`
+}
+
+// cliContext is one controller: where it is, how to authenticate and how to
+// verify its certificate.
+type cliContext struct {
+	Name                  string `yaml:"name" json:"name"`
+	URL                   string `yaml:"url,omitempty" json:"url,omitempty"`
+	Token                 string `yaml:"token,omitempty" json:"-"`
+	CAFile                string `yaml:"ca-file,omitempty" json:"ca_file,omitempty"`
+	InsecureSkipTLSVerify bool   `yaml:"insecure-skip-tls-verify,omitempty" json:"insecure_skip_tls_verify,omitempty"`
+}
+
+func (c *cliConfig) context(name string) *cliContext {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i]
+		}
+	}
+	return nil
+}
+
+func configPath() (string, error) {
+	if path := viper.GetString("config"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding the config file: %v", err)
+	}
+	return filepath.Join(home, ".conops", "config.yaml"), nil
+}
+
+// loadConfig reads the config file; a missing file is an empty config.
+func loadConfig() (*cliConfig, string, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, "", err
+	}
+	config := &cliConfig{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s: %v", path, err)
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, "", fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	return config, path, nil
+}
+
+// saveConfig writes the config file readable only by the user, since it
+// holds tokens.
+func saveConfig(config *cliConfig, path string) error {
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return fmt.Errorf("error encoding config: %v", err)
+	}
+	encoder.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return nil
+}
+
+// clientTransport is the transport NewClient uses; nil is the default one.
+var clientTransport http.RoundTripper
+
+// applyContext makes the context given with --context, or the config's
+// current one, the default for --url and --token, so flags and CONOPS_*
+// variables still override it, and sets up its TLS settings.
+func applyContext() error {
+	config, path, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	name := viper.GetString("context")
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name != "" {
+		context := config.context(name)
+		if context == nil {
+			return fmt.Errorf("context %q not found in %s", name, path)
+		}
+		viper.SetDefault("url", context.URL)
+		viper.SetDefault("token", context.Token)
+		viper.SetDefault("ca_file", context.CAFile)
+		viper.SetDefault("insecure_skip_tls_verify", context.InsecureSkipTLSVerify)
+	}
+
+	caFile := viper.GetString("ca_file")
+	insecure := viper.GetBool("insecure_skip_tls_verify")
+	if caFile == "" && !insecure {
+		return nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("error reading CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("invalid CA file: no certificates in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	clientTransport = transport
+	return nil
+}
+
+var (
+	contextURL      string
+	contextToken    string
+	contextCAFile   string
+	contextInsecure bool
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the controllers conops-ctl talks to",
+	Long: `Manage named contexts in ~/.conops/config.yaml (or CONOPS_CONFIG), each with a controller URL,
+token and TLS settings. Commands use the current context unless --context names another; --url,
+--token and CONOPS_* variables override it.`,
+	Example: `  conops-ctl config set-context staging --url https://conops.staging.example.com --token "$TOKEN"
+  conops-ctl config use-context staging`,
+	// The config commands do not need a usable context.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkOutputFormat()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// getContextsCmd represents the config get-contexts command
+var getContextsCmd = &cobra.Command{
+	Use:               "get-contexts",
+	Short:             "List the contexts",
+	Args:              cobra.NoArgs,
+	Example:           `  conops-ctl config get-contexts`,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if printed, err := printData(config); printed || err != nil {
+			return err
+		}
+		if len(config.Contexts) == 0 {
+			fmt.Printf("No contexts in %s.\n", path)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tURL\tTOKEN\tTLS")
+		for _, c := range config.Contexts {
+			current, token, tlsMode := "", "-", "-"
+			if c.Name == config.CurrentContext {
+				current = "*"
+			}
+			if c.Token != "" {
+				token = "set"
+			}
+			switch {
+			case c.InsecureSkipTLSVerify:
+				tlsMode = "insecure"
+			case c.CAFile != "":
+				tlsMode = c.CAFile
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, c.Name, c.URL, token, tlsMode)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// currentContextCmd represents the config current-context command
+var currentContextCmd = &cobra.Command{
+	Use:               "current-context",
+	Short:             "Print the current context",
+	Args:              cobra.NoArgs,
+	Example:           `  conops-ctl config current-context`,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if config.CurrentContext == "" {
+			return fmt.Errorf("no current context in %s", path)
+		}
+		fmt.Println(config.CurrentContext)
+		return nil
+	},
+}
+
+// useContextCmd represents the config use-context command
+var useContextCmd = &cobra.Command{
+	Use:               "use-context [name]",
+	Short:             "Make a context the current one",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl config use-context prod`,
+	ValidArgsFunction: completeContexts,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if config.context(args[0]) == nil {
+			return fmt.Errorf("context %q not found in %s", args[0], path)
+		}
+		config.CurrentContext = args[0]
+		if err := saveConfig(config, path); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to context %s.\n", args[0])
+		return nil
+	},
+}
+
+// setContextCmd represents the config set-context command
+var setContextCmd = &cobra.Command{
+	Use:   "set-context [name]",
+	Short: "Add a context or change the settings given",
+	Long: `Add a context, or change the settings given as flags on an existing one. The first context
+added becomes the current one.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  conops-ctl config set-context prod --url https://conops.example.com --ca-file prod-ca.pem
+  conops-ctl config set-context prod --token "$NEW_TOKEN"`,
+	ValidArgsFunction: completeContexts,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		context := config.context(args[0])
+		added := context == nil
+		if added {
+			config.Contexts = append(config.Contexts, cliContext{Name: args[0]})
+			context = &config.Contexts[len(config.Contexts)-1]
+		}
+		flags := cmd.Flags()
+		if flags.Changed("url") {
+			context.URL = contextURL
+		}
+		if flags.Changed("token") {
+			context.Token = contextToken
+		}
+		if flags.Changed("ca-file") {
+			context.CAFile = contextCAFile
+		}
+		if flags.Changed("insecure-skip-tls-verify") {
+			context.InsecureSkipTLSVerify = contextInsecure
+		}
+		if config.CurrentContext == "" {
+			config.CurrentContext = context.Name
+		}
+		if err := saveConfig(config, path); err != nil {
+			return err
+		}
+		if added {
+			fmt.Printf("Context %s added.\n", context.Name)
+		} else {
+			fmt.Printf("Context %s updated.\n", context.Name)
+		}
+		return nil
+	},
+}
+
+// deleteContextCmd represents the config delete-context command
+var deleteContextCmd = &cobra.Command{
+	Use:               "delete-context [name]",
+	Short:             "Remove a context",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl config delete-context staging`,
+	ValidArgsFunction: completeContexts,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		kept := config.Contexts[:0]
+		for _, c := range config.Contexts {
+			if c.Name != args[0] {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == len(config.Contexts) {
+			return fmt.Errorf("context %q not found in %s", args[0], path)
+		}
+		config.Contexts = kept
+		if config.CurrentContext == args[0] {
+			config.CurrentContext = ""
+		}
+		if err := saveConfig(config, path); err != nil {
+			return err
+		}
+		fmt.Printf("Context %s deleted.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	// set-context's --url and --token shadow the global flags of the same
+	// name, which would otherwise only apply to this invocation.
+	setContextCmd.Flags().StringVar(&contextURL, "url", "", "Controller URL")
+	setContextCmd.Flags().StringVar(&contextToken, "token", "", "API token (admin or app token)")
+	setContextCmd.Flags().StringVar(&contextCAFile, "ca-file", "", "PEM file with the CA that signed the controller's certificate")
+	setContextCmd.Flags().BoolVar(&contextInsecure, "insecure-skip-tls-verify", false, "Do not verify the controller's certificate")
+
+	configCmd.AddCommand(getContextsCmd)
+	configCmd.AddCommand(currentContextCmd)
+	configCmd.AddCommand(useContextCmd)
+	configCmd.AddCommand(setContextCmd)
+	configCmd.AddCommand(deleteContextCmd)
+	rootCmd.AddCommand(configCmd)
+}
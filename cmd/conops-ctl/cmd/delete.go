@@ -4,18 +4,43 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
+var (
+	deleteVolumes bool
+	deleteYes     bool
+)
+
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete [app-id]",
 	Short: "Delete an application",
-	Args:  cobra.ExactArgs(1),
+	Long: `Delete an application and stop its containers. Volumes are kept unless --volumes is given,
+which asks for confirmation first because the data in them is lost.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  conops-ctl apps delete <app-id>
+  conops-ctl apps delete <app-id> --volumes --yes`,
+	ValidArgsFunction: completeAppIDs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		appID := args[0]
+		path := "/api/v1/apps/" + appID
+		if deleteVolumes {
+			if !deleteYes {
+				prompt := promptui.Prompt{
+					Label:     fmt.Sprintf("Delete app %s and permanently remove its volumes", appID),
+					IsConfirm: true,
+				}
+				if _, err := prompt.Run(); err != nil {
+					return fmt.Errorf("aborted; nothing was deleted")
+				}
+			}
+			path += "?volumes=true"
+		}
+
 		client := NewClient()
-		resp, err := client.Delete("/api/v1/apps/" + appID)
+		resp, err := client.Delete(path)
 		if err != nil {
 			return fmt.Errorf("error deleting app: %v", err)
 		}
@@ -25,11 +50,17 @@ var deleteCmd = &cobra.Command{
 			return CheckResponse(resp)
 		}
 
+		if deleteVolumes {
+			fmt.Println("App and its volumes deleted successfully.")
+			return nil
+		}
 		fmt.Println("App deleted successfully.")
 		return nil
 	},
 }
 
 func init() {
+	deleteCmd.Flags().BoolVar(&deleteVolumes, "volumes", false, "Also remove the app's volumes and the data in them")
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Do not ask for confirmation before removing volumes")
 	appsCmd.AddCommand(deleteCmd)
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:               "diff [app-id]",
+	Short:             "Show how the next sync changes the rendered compose configuration",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps diff <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + args[0] + "/diff")
+		if err != nil {
+			return fmt.Errorf("error getting diff: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data api.AppDiff `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+
+		if !apiResp.Data.Changed {
+			fmt.Printf("No changes: %s is already synced.\n", apiResp.Data.TargetCommit)
+			return nil
+		}
+		fmt.Print(apiResp.Data.Diff)
+		return nil
+	},
+}
+
+func init() {
+	appsCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+// downCmd represents the down command
+var downCmd = &cobra.Command{
+	Use:               "down [app-id]",
+	Short:             "Stop an application's stack without deleting the app",
+	Long:              `Tear down the app's containers and mark it stopped. New commits are recorded but not deployed until "apps up".`,
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps down <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Post("/api/v1/apps/"+args[0]+"/down", nil)
+		if err != nil {
+			return fmt.Errorf("error stopping app: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		fmt.Println("App stopped.")
+		return nil
+	},
+}
+
+func init() {
+	appsCmd.AddCommand(downCmd)
+}
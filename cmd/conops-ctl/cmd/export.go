@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// exportPassphraseEnv supplies the transport passphrase without a prompt.
+const exportPassphraseEnv = "CONOPS_EXPORT_PASSPHRASE"
+
+var (
+	exportOutput         string
+	exportPassphraseFile string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Dump the controller's apps, credentials and history to a file",
+	Long: `Dump every app with its deploy key, env vars, revisions, sync history, audit log and schedules.
+Credentials are re-encrypted with a passphrase given with --passphrase-file, ` + exportPassphraseEnv + ` or a prompt,
+so the file can be imported into a controller with a different encryption key or store. App tokens are not exported.`,
+	Args: cobra.NoArgs,
+	Example: `  conops-ctl export -o conops-backup.json
+  conops-ctl export -o conops-backup.json --passphrase-file passphrase.txt`,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		passphrase, err := readExportPassphrase(true)
+		if err != nil {
+			return err
+		}
+
+		client := NewClient()
+		client.Client.Timeout = 5 * time.Minute
+		resp, err := client.Post("/api/v1/export", map[string]string{"passphrase": passphrase})
+		if err != nil {
+			return fmt.Errorf("error exporting state: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		out := os.Stdout
+		if exportOutput != "" && exportOutput != "-" {
+			file, err := os.OpenFile(exportOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("error creating %s: %v", exportOutput, err)
+			}
+			defer file.Close()
+			out = file
+		}
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			return fmt.Errorf("error writing export: %v", err)
+		}
+		if out != os.Stdout {
+			if err := out.Close(); err != nil {
+				return fmt.Errorf("error writing export: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "State exported to %s\n", exportOutput)
+		}
+		return nil
+	},
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Load apps, credentials and history from an export file",
+	Long: `Load a file written by "conops-ctl export" into this controller. Apps keep their IDs and history;
+ones that already exist are skipped. Imported apps are deployed on the next reconcile unless they were stopped.`,
+	Args:    cobra.ExactArgs(1),
+	Example: `  conops-ctl import conops-backup.json --passphrase-file passphrase.txt`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", args[0], err)
+		}
+		var export json.RawMessage
+		if err := json.Unmarshal(data, &export); err != nil {
+			return fmt.Errorf("%s is not an export: %v", args[0], err)
+		}
+		passphrase, err := readExportPassphrase(false)
+		if err != nil {
+			return err
+		}
+
+		client := NewClient()
+		client.Client.Timeout = 5 * time.Minute
+		resp, err := client.Post("/api/v1/import", map[string]interface{}{
+			"passphrase": passphrase,
+			"export":     export,
+		})
+		if err != nil {
+			return fmt.Errorf("error importing state: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Message string `json:"message"`
+			Data    struct {
+				Skipped []string `json:"skipped"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		fmt.Println(apiResp.Message)
+		if len(apiResp.Data.Skipped) > 0 {
+			fmt.Printf("Skipped: %s\n", strings.Join(apiResp.Data.Skipped, ", "))
+		}
+		return nil
+	},
+}
+
+// readExportPassphrase reads the transport passphrase from the passphrase
+// file, the environment or a prompt, asking twice when exporting.
+func readExportPassphrase(confirm bool) (string, error) {
+	if exportPassphraseFile != "" {
+		data, err := os.ReadFile(exportPassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading passphrase file: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if passphrase := os.Getenv(exportPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	prompt := promptui.Prompt{Label: "Passphrase", Mask: '*'}
+	passphrase, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		prompt = promptui.Prompt{Label: "Confirm passphrase", Mask: '*'}
+		again, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the export to (default stdout)")
+	exportCmd.Flags().StringVar(&exportPassphraseFile, "passphrase-file", "", "Read the passphrase from this file")
+	importCmd.Flags().StringVar(&exportPassphraseFile, "passphrase-file", "", "Read the passphrase from this file")
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
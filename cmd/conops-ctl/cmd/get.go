@@ -14,6 +14,9 @@ var getCmd = &cobra.Command{
 	Use:   "get [app-id]",
 	Short: "Get application details",
 	Args:  cobra.ExactArgs(1),
+	Example: `  conops-ctl apps get <app-id>
+  conops-ctl apps get <app-id> -o yaml`,
+	ValidArgsFunction: completeAppIDs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		appID := args[0]
 		client := NewClient()
@@ -35,6 +38,9 @@ var getCmd = &cobra.Command{
 			return fmt.Errorf("error decoding response: %v", err)
 		}
 
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
 		PrintJSON(apiResp.Data)
 		return nil
 	},
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyLimit int
+	historyLogs  bool
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history [app-id] [sync-id]",
+	Short: "Show an app's recent syncs, including rollbacks",
+	Long: `List an app's recent syncs, or show the steps of one sync with how long each
+took. The log of a failed step is printed below it; --logs prints every step's.`,
+	Args: cobra.RangeArgs(1, 2),
+	Example: `  conops-ctl apps history <app-id>
+  conops-ctl apps history <app-id> --limit 5
+  conops-ctl apps history <app-id> <sync-id> --logs`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 2 {
+			return showSync(args[0], args[1])
+		}
+		path := "/api/v1/apps/" + args[0] + "/history"
+		if historyLimit > 0 {
+			path += "?limit=" + strconv.Itoa(historyLimit)
+		}
+
+		client := NewClient()
+		resp, err := client.Get(path)
+		if err != nil {
+			return fmt.Errorf("error fetching sync history: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data []api.SyncRecord `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tTRIGGER\tCOMMIT\tSTATUS\tERROR")
+		for _, record := range apiResp.Data {
+			commit := record.Commit
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+			if commit == "" {
+				commit = "-"
+			}
+			errText := record.Error
+			if errText == "" {
+				errText = "-"
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				record.ID,
+				record.StartedAt.Format(time.RFC3339),
+				record.FinishedAt.Sub(record.StartedAt).Round(time.Second),
+				record.Trigger,
+				commit,
+				record.Status,
+				errText,
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+// showSync prints the steps of one sync, with the logs of failed steps or,
+// with --logs, of all of them.
+func showSync(appID, syncID string) error {
+	client := NewClient()
+	resp, err := client.Get("/api/v1/apps/" + appID + "/history/" + syncID)
+	if err != nil {
+		return fmt.Errorf("error fetching sync: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return CheckResponse(resp)
+	}
+
+	var apiResp struct {
+		Data api.SyncRecord `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	record := apiResp.Data
+
+	if printed, err := printData(record); printed || err != nil {
+		return err
+	}
+
+	commit := record.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if commit == "" {
+		commit = "-"
+	}
+	fmt.Printf("Sync %s of %s: %s (%s, trigger %s, commit %s)\n", record.ID, appID, record.Status,
+		record.FinishedAt.Sub(record.StartedAt).Round(time.Second), record.Trigger, commit)
+	if record.Error != "" {
+		fmt.Printf("Error: %s\n", record.Error)
+	}
+	fmt.Println()
+
+	for _, step := range record.Steps {
+		mark := "ok"
+		if step.Status == "failed" {
+			mark = "FAILED"
+		}
+		duration := (time.Duration(step.DurationMs) * time.Millisecond).Round(time.Millisecond)
+		fmt.Printf("%-6s  %-16s  %s\n", mark, step.Name, duration)
+		if step.Log != "" && (historyLogs || step.Status == "failed") {
+			for _, line := range strings.Split(step.Log, "\n") {
+				if line != "" {
+					line = "        " + line
+				}
+				fmt.Println(line)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 0, "Maximum number of syncs (default 100)")
+	historyCmd.Flags().BoolVar(&historyLogs, "logs", false, "With a sync ID, print the log of every step")
+	appsCmd.AddCommand(historyCmd)
+}
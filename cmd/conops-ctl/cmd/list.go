@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"text/tabwriter"
 	"time"
 
@@ -12,13 +14,43 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	listStatus  string
+	listSearch  string
+	listSort    string
+	listPage    int
+	listPerPage int
+)
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all applications",
+	Example: `  conops-ctl apps list
+  conops-ctl apps list --status error,awaiting_approval
+  conops-ctl apps list -q billing --sort -last_sync_at`,
+	ValidArgsFunction: cobra.NoFileCompletions,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// The table shows no sync output, so skip downloading it.
+		query := url.Values{"fields": {"summary"}}
+		if listStatus != "" {
+			query.Set("status", listStatus)
+		}
+		if listSearch != "" {
+			query.Set("q", listSearch)
+		}
+		if listSort != "" {
+			query.Set("sort", listSort)
+		}
+		if listPage > 0 {
+			query.Set("page", strconv.Itoa(listPage))
+		}
+		if listPerPage > 0 {
+			query.Set("per_page", strconv.Itoa(listPerPage))
+		}
+
 		client := NewClient()
-		resp, err := client.Get("/api/v1/apps/")
+		resp, err := client.Get("/api/v1/apps/?" + query.Encode())
 		if err != nil {
 			return fmt.Errorf("error fetching apps: %v", err)
 		}
@@ -36,6 +68,10 @@ var listCmd = &cobra.Command{
 			return fmt.Errorf("error decoding response: %v", err)
 		}
 
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 		fmt.Fprintln(w, "ID\tNAME\tREPO\tBRANCH\tSTATUS\tLAST SYNC")
 		for _, app := range apiResp.Data {
@@ -43,10 +79,20 @@ var listCmd = &cobra.Command{
 		}
 		w.Flush()
 
+		if total, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil && total > len(apiResp.Data) {
+			fmt.Printf("\nShowing %d of %d apps\n", len(apiResp.Data), total)
+		}
 		return nil
 	},
 }
 
 func init() {
+	listCmd.Flags().StringVar(&listStatus, "status", "", "Only list apps in these states, comma-separated (e.g. error,awaiting_approval)")
+	listCmd.Flags().StringVarP(&listSearch, "search", "q", "", "Only list apps whose ID, name or repo URL contains this")
+	listCmd.Flags().StringVar(&listSort, "sort", "", "Sort by id, name, status or last_sync_at; prefix with - to reverse")
+	listCmd.Flags().IntVar(&listPage, "page", 0, "Page to show, starting at 1")
+	listCmd.Flags().IntVar(&listPerPage, "per-page", 0, "Apps per page (default 50 with --page, otherwise all)")
+	listCmd.RegisterFlagCompletionFunc("status", completeStatuses)
+	listCmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions([]string{"id", "name", "status", "last_sync_at", "-id", "-name", "-status", "-last_sync_at"}, cobra.ShellCompDirectiveNoFileComp))
 	appsCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsTail   string
+	logsFollow bool
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs [app-id] [service]",
+	Short: "Print the container logs of one service of an application",
+	Long: `Print the last lines of a service's container logs, 200 by default or --tail all for the whole log.
+With --follow, new lines are printed until interrupted.`,
+	Args: cobra.ExactArgs(2),
+	Example: `  conops-ctl apps logs <app-id> web
+  conops-ctl apps logs <app-id> web --tail 50 -f`,
+	ValidArgsFunction: completeAppServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		if logsFollow {
+			// A followed log stays open for as long as it is watched.
+			client.Client.Timeout = 0
+		}
+		query := url.Values{}
+		query.Set("tail", logsTail)
+		if logsFollow {
+			query.Set("follow", "true")
+		}
+		resp, err := client.Get("/api/v1/apps/" + args[0] + "/services/" + url.PathEscape(args[1]) + "/logs?" + query.Encode())
+		if err != nil {
+			return fmt.Errorf("error fetching logs: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+		if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+			return fmt.Errorf("error reading logs: %v", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	logsCmd.Flags().StringVar(&logsTail, "tail", "200", "Number of lines to show from the end of the log, or \"all\"")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new log lines")
+	appsCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// manifestCmd represents the manifest command
+var manifestCmd = &cobra.Command{
+	Use:               "manifest [app-id]",
+	Short:             "Show the effective configuration the next sync applies",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps manifest <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appID := args[0]
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + appID + "/manifest")
+		if err != nil {
+			return fmt.Errorf("error getting manifest: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data api.AppManifest `json:"data"`
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+		PrintJSON(apiResp.Data)
+		return nil
+	},
+}
+
+func init() {
+	appsCmd.AddCommand(manifestCmd)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// Formats of the --output flag. Commands print tables or messages for
+// people by default; json and yaml print what the controller returned, for
+// scripts.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// checkOutputFormat rejects an unknown --output before a command runs.
+func checkOutputFormat() error {
+	switch format := viper.GetString("output"); format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid output %q: use table, json or yaml", format)
+	}
+}
+
+// printData prints data as JSON or YAML when --output asks for it and
+// reports whether it did; otherwise the command prints its table.
+func printData(data interface{}) (bool, error) {
+	switch viper.GetString("output") {
+	case outputJSON:
+		PrintJSON(data)
+		return true, nil
+	case outputYAML:
+		return true, PrintYAML(data)
+	}
+	return false, nil
+}
+
+// PrintYAML prints data as YAML, with the fields named and ordered as in
+// its JSON.
+func PrintYAML(data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding YAML: %v", err)
+	}
+	// JSON is YAML; decoding it into a node keeps the field order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(encoded, &node); err != nil {
+		return fmt.Errorf("error encoding YAML: %v", err)
+	}
+	blockStyle(&node)
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("error encoding YAML: %v", err)
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style and quotes the nodes have from JSON; the
+// encoder quotes the strings that need it.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// decodeResponse decodes an API response, keeping its data as sent so that
+// --output prints the fields in the controller's order.
+func decodeResponse(body io.Reader) (api.APIResponse, error) {
+	var data json.RawMessage
+	apiResp := api.APIResponse{Data: &data}
+	if err := json.NewDecoder(body).Decode(&apiResp); err != nil {
+		return apiResp, fmt.Errorf("error decoding response: %v", err)
+	}
+	if apiResp.Data != nil && len(data) == 0 {
+		apiResp.Data = nil
+	}
+	return apiResp, nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+// pauseCmd represents the pause command
+var pauseCmd = &cobra.Command{
+	Use:               "pause [app-id]",
+	Short:             "Hold an application's automatic syncs",
+	Long:              `Stop the reconciler from deploying new commits or repairing drift for the app. Its containers keep running; "apps resume" hands it back.`,
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps pause <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return postAppAction(args[0], "pause", "pausing")
+	},
+}
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:               "resume [app-id]",
+	Aliases:           []string{"unpin"},
+	Short:             "Resume a paused application's automatic syncs",
+	Long:              `Hand a paused app back to the reconciler. If it runs another commit than the desired one, e.g. after a rollback or pin, the desired commit is deployed.`,
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps resume <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return postAppAction(args[0], "resume", "resuming")
+	},
+}
+
+// postAppAction posts to one of the app's action endpoints and prints the
+// controller's message.
+func postAppAction(appID, action, doing string) error {
+	client := NewClient()
+	resp, err := client.Post("/api/v1/apps/"+appID+"/"+action, nil)
+	if err != nil {
+		return fmt.Errorf("error %s app: %v", doing, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return CheckResponse(resp)
+	}
+
+	apiResp, err := decodeResponse(resp.Body)
+	if err != nil {
+		return err
+	}
+	if printed, err := printData(apiResp); printed || err != nil {
+		return err
+	}
+	fmt.Println(apiResp.Message + ".")
+	return nil
+}
+
+func init() {
+	appsCmd.AddCommand(pauseCmd)
+	appsCmd.AddCommand(resumeCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+// restartCmd represents the restart command
+var restartCmd = &cobra.Command{
+	Use:               "restart [app-id] [service]",
+	Short:             "Restart one service of an application",
+	Long:              `Restart the containers of one service of an app's running stack, keeping their configuration.`,
+	Args:              cobra.ExactArgs(2),
+	Example:           `  conops-ctl apps restart <app-id> worker`,
+	ValidArgsFunction: completeAppServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Post("/api/v1/apps/"+args[0]+"/services/"+url.PathEscape(args[1])+"/restart", nil)
+		if err != nil {
+			return fmt.Errorf("error restarting service: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		apiResp, err := decodeResponse(resp.Body)
+		if err != nil {
+			return err
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
+		}
+		fmt.Println(apiResp.Message + ".")
+		return nil
+	},
+}
+
+func init() {
+	appsCmd.AddCommand(restartCmd)
+}
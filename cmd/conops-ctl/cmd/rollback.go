@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackTo   string
+	rollbackYes  bool
+	rollbackWait bool
+	pinTo        string
+	pinYes       bool
+	pinWait      bool
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [app-id]",
+	Short: "Redeploy the commit an application ran before",
+	Long: `Pause the app and deploy the commit synced before the current one, or --to. It stays on that commit until "apps resume".
+The controller runs the rollback in the background; with --wait, the command waits for it to finish and fails if it does.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  conops-ctl apps rollback <app-id>
+  conops-ctl apps rollback <app-id> --to 3f2a9c1 --yes --wait`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := "the commit synced before the current one"
+		if rollbackTo != "" {
+			target = rollbackTo
+		}
+		if !rollbackYes && !confirm(fmt.Sprintf("Roll back app %s to %s and pause its automatic syncs", args[0], target)) {
+			return fmt.Errorf("aborted; nothing was rolled back")
+		}
+		return postRollback(args[0], rollbackTo, "rolling back", rollbackWait)
+	},
+}
+
+// pinCmd represents the pin command
+var pinCmd = &cobra.Command{
+	Use:   "pin [app-id]",
+	Short: "Deploy a commit or tag and hold the application on it",
+	Long:  `Pause the app and deploy the commit or tag given with --to. New commits are recorded but not deployed until "apps unpin".`,
+	Args:  cobra.ExactArgs(1),
+	Example: `  conops-ctl apps pin <app-id> --to v1.4.2
+  conops-ctl apps unpin <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pinTo == "" {
+			return fmt.Errorf("--to is required")
+		}
+		if !pinYes && !confirm(fmt.Sprintf("Deploy %s to app %s and hold it there", pinTo, args[0])) {
+			return fmt.Errorf("aborted; nothing was pinned")
+		}
+		return postRollback(args[0], pinTo, "pinning", pinWait)
+	},
+}
+
+// postRollback asks the controller to deploy revision, a commit or tag, and
+// pause the app; an empty revision is the commit synced before the current
+// one. With wait it waits for the rollback's job to finish.
+func postRollback(appID, revision, doing string, wait bool) error {
+	client := NewClient()
+	resp, err := client.Post("/api/v1/apps/"+appID+"/rollback", map[string]string{"commit": revision})
+	if err != nil {
+		return fmt.Errorf("error %s app: %v", doing, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		var apiResp struct {
+			Message string      `json:"message"`
+			Data    api.SyncJob `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		if wait {
+			return waitForSync(client, appID, apiResp.Message, apiResp.Data)
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
+		}
+		fmt.Printf("%s (job %s).\n", apiResp.Message, apiResp.Data.ID)
+		return nil
+	}
+	// Controllers before asynchronous rollbacks answer once it is done.
+	if resp.StatusCode != http.StatusOK {
+		return CheckResponse(resp)
+	}
+
+	apiResp, err := decodeResponse(resp.Body)
+	if err != nil {
+		return err
+	}
+	if printed, err := printData(apiResp); printed || err != nil {
+		return err
+	}
+	fmt.Println(apiResp.Message + ".")
+	return nil
+}
+
+// confirm asks a yes/no question and reports whether it was answered yes.
+func confirm(label string) bool {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+func init() {
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Commit hash or tag to roll back to (default: the one synced before the current commit)")
+	rollbackCmd.Flags().StringVar(&rollbackTo, "commit", "", "Commit hash to roll back to")
+	rollbackCmd.Flags().MarkDeprecated("commit", "use --to")
+	rollbackCmd.Flags().BoolVarP(&rollbackYes, "yes", "y", false, "Do not ask for confirmation")
+	rollbackCmd.Flags().BoolVar(&rollbackWait, "wait", false, "Wait for the rollback to finish")
+	pinCmd.Flags().StringVar(&pinTo, "to", "", "Commit hash or tag to deploy")
+	pinCmd.Flags().BoolVarP(&pinYes, "yes", "y", false, "Do not ask for confirmation")
+	pinCmd.Flags().BoolVar(&pinWait, "wait", false, "Wait for the deploy to finish")
+	appsCmd.AddCommand(rollbackCmd)
+	appsCmd.AddCommand(pinCmd)
+}
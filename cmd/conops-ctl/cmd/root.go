@@ -10,6 +10,9 @@ import (
 
 var (
 	controllerURL string
+	apiToken      string
+	outputFormat  string
+	contextName   string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -17,6 +20,15 @@ var rootCmd = &cobra.Command{
 	Use:   "conops-ctl",
 	Short: "Command line interface for the Conops platform",
 	Long:  `CLI for managing Conops applications (Git-based Docker Compose sync).`,
+	Example: `  conops-ctl apps list
+  conops-ctl --context prod apps status <app-id>
+  source <(conops-ctl completion bash)`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(); err != nil {
+			return err
+		}
+		return applyContext()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -35,9 +47,18 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&controllerURL, "url", "http://localhost:8080", "Controller URL")
+	rootCmd.PersistentFlags().StringVar(&apiToken, "token", "", "API token (admin or app token)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context from the config file to use (default: the current one)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format: table, json or yaml")
 
 	// Bind flags to viper
+	rootCmd.RegisterFlagCompletionFunc("context", completeContexts)
+	rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{outputTable, outputJSON, outputYAML}, cobra.ShellCompDirectiveNoFileComp))
+
 	viper.BindPFlag("url", rootCmd.PersistentFlags().Lookup("url"))
+	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
+	viper.BindPFlag("context", rootCmd.PersistentFlags().Lookup("context"))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
 }
 
 // initConfig reads in ENV variables if set.
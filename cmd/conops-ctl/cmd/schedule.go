@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// scheduleCmd represents the apps schedule command
+var scheduleCmd = &cobra.Command{
+	Use:     "schedule",
+	Short:   "Manage an app's scheduled syncs",
+	Long:    `List and cancel syncs scheduled with "apps sync --at".`,
+	Example: `  conops-ctl apps schedule list <app-id>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// scheduleListCmd represents the apps schedule list command
+var scheduleListCmd = &cobra.Command{
+	Use:               "list [app-id]",
+	Short:             "List an app's upcoming scheduled syncs",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps schedule list <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + args[0] + "/schedules")
+		if err != nil {
+			return fmt.Errorf("error fetching scheduled syncs: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data []api.ScheduledSync `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ID\tRUN AT\tCOMMIT\tBY")
+		for _, schedule := range apiResp.Data {
+			commit := schedule.Commit
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+			if commit == "" {
+				commit = "latest"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				schedule.ID,
+				schedule.RunAt.Local().Format(time.RFC3339),
+				commit,
+				schedule.Actor,
+			)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// scheduleCancelCmd represents the apps schedule cancel command
+var scheduleCancelCmd = &cobra.Command{
+	Use:               "cancel [app-id] [schedule-id]",
+	Short:             "Cancel a scheduled sync",
+	Args:              cobra.ExactArgs(2),
+	Example:           `  conops-ctl apps schedule cancel <app-id> <schedule-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Delete("/api/v1/apps/" + args[0] + "/schedules/" + args[1])
+		if err != nil {
+			return fmt.Errorf("error cancelling scheduled sync: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		fmt.Println("Scheduled sync cancelled.")
+		return nil
+	},
+}
+
+func init() {
+	scheduleCmd.AddCommand(scheduleListCmd, scheduleCancelCmd)
+	appsCmd.AddCommand(scheduleCmd)
+}
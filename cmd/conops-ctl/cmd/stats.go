@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:               "stats [app-id]",
+	Short:             "Show the CPU, memory and restart counts of an app's containers",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps stats <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + args[0] + "/stats")
+		if err != nil {
+			return fmt.Errorf("error fetching stats: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Message string       `json:"message"`
+			Data    api.AppStats `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+		if apiResp.Data.CollectedAt.IsZero() {
+			fmt.Println(apiResp.Message + ".")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tCONTAINER\tCPU\tMEMORY\tRESTARTS")
+		for _, s := range apiResp.Data.Services {
+			cpu, memory := "-", "-"
+			if s.Running {
+				cpu = fmt.Sprintf("%.1f%%", s.CPUPercent)
+				memory = fmt.Sprintf("%.1f MiB", float64(s.MemoryBytes)/(1<<20))
+				if s.MemoryLimitBytes > 0 {
+					memory += fmt.Sprintf(" (%.1f%%)", s.MemoryPercent)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", s.Service, s.Container, cpu, memory, s.RestartCount)
+		}
+		w.Flush()
+		fmt.Printf("Sampled at %s.\n", apiResp.Data.CollectedAt.Local().Format(time.RFC3339))
+		return nil
+	},
+}
+
+func init() {
+	appsCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status [app-id]",
+	Short: "Show an app's commit drift, containers and last error",
+	Args:  cobra.ExactArgs(1),
+	Example: `  conops-ctl apps status <app-id>
+  conops-ctl apps status <app-id> -o json | jq .containers`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + args[0])
+		if err != nil {
+			return fmt.Errorf("error getting app: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var appResp struct {
+			Data api.App `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&appResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		app := appResp.Data
+
+		containers, listed, err := listContainers(client, app.ID)
+		if err != nil {
+			return err
+		}
+		if printed, err := printData(appStatus{App: app, Containers: containers}); printed || err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		status := app.Status
+		if app.Paused {
+			status += " (paused)"
+		}
+		fmt.Fprintf(w, "App:\t%s (%s)\n", app.Name, app.ID)
+		fmt.Fprintf(w, "Status:\t%s\n", status)
+		fmt.Fprintf(w, "Desired commit:\t%s\n", statusCommit(app.LastSeenCommit, app.LastSeenCommitMessage))
+		fmt.Fprintf(w, "Synced commit:\t%s\n", statusCommit(app.LastSyncedCommit, app.LastSyncedCommitMessage))
+		switch {
+		case app.LastSeenCommit == "":
+			fmt.Fprintln(w, "Drift:\tno commit seen yet")
+		case app.LastSeenCommit == app.LastSyncedCommit:
+			fmt.Fprintln(w, "Drift:\tin sync")
+		default:
+			fmt.Fprintln(w, "Drift:\tthe desired commit is not deployed")
+		}
+		if !app.LastSyncAt.IsZero() {
+			fmt.Fprintf(w, "Last sync:\t%s\n", app.LastSyncAt.Local().Format(time.RFC3339))
+		}
+		if app.LastSyncError != "" {
+			fmt.Fprintf(w, "Last error:\t%s\n", app.LastSyncError)
+		}
+		if app.SyncFailures > 0 {
+			fmt.Fprintf(w, "Failures:\t%d in a row\n", app.SyncFailures)
+		}
+		if app.NextRetryAt != nil {
+			fmt.Fprintf(w, "Next retry:\t%s\n", app.NextRetryAt.Local().Format(time.RFC3339))
+		}
+		w.Flush()
+		fmt.Println()
+
+		if !listed {
+			fmt.Println("The controller's runtime cannot list containers.")
+			return nil
+		}
+		if len(containers) == 0 {
+			fmt.Println("No containers.")
+			return nil
+		}
+
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tCONTAINER\tSTATE\tHEALTH\tRESTARTS\tPORTS")
+		for _, c := range containers {
+			state, health, ports := c.Status, c.Health, c.Ports
+			if c.ExitCode != 0 {
+				state = fmt.Sprintf("%s (%d)", state, c.ExitCode)
+			}
+			if health == "" {
+				health = "-"
+			}
+			if ports == "" {
+				ports = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", c.Service, c.Name, state, health, c.RestartCount, ports)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// appStatus is what status prints with --output json or yaml. Containers
+// is null when the controller's runtime cannot list them.
+type appStatus struct {
+	App        api.App                `json:"app"`
+	Containers []api.ServiceContainer `json:"containers"`
+}
+
+// listContainers returns the app's containers, and false when the
+// controller's runtime cannot list them.
+func listContainers(client *APIClient, appID string) ([]api.ServiceContainer, bool, error) {
+	resp, err := client.Get("/api/v1/apps/" + appID + "/containers")
+	if err != nil {
+		return nil, false, fmt.Errorf("error listing containers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotImplemented {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, CheckResponse(resp)
+	}
+
+	var apiResp struct {
+		Data []api.ServiceContainer `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, false, fmt.Errorf("error decoding response: %v", err)
+	}
+	return apiResp.Data, true, nil
+}
+
+// statusCommit shows a commit as its short hash and subject.
+func statusCommit(commit, message string) string {
+	if commit == "" {
+		return "-"
+	}
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if subject, _, _ := strings.Cut(message, "\n"); subject != "" {
+		return commit + " " + subject
+	}
+	return commit
+}
+
+func init() {
+	appsCmd.AddCommand(statusCmd)
+}
@@ -1,20 +1,49 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/conops/conops/internal/api"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	syncAt     string
+	syncCommit string
+	syncWait   bool
 )
 
 // syncCmd represents the sync command
 var syncCmd = &cobra.Command{
 	Use:   "sync [app-id]",
 	Short: "Force sync an application",
-	Long:  `Trigger immediate Git sync and Compose update.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Trigger immediate Git sync and Compose update. The controller runs the sync in the background;
+with --wait, the command waits for it to finish and fails if the sync does.
+With --at, the sync is scheduled instead and runs at that time, optionally deploying the commit given with --commit.`,
+	Args: cobra.ExactArgs(1),
+	Example: `  conops-ctl apps sync <app-id>
+  conops-ctl apps sync <app-id> --wait
+  conops-ctl apps sync <app-id> --at 02:00
+  conops-ctl apps sync <app-id> --at 2h --commit 3f2a9c1e4b5d6a7f8091a2b3c4d5e6f708192a3b`,
+	ValidArgsFunction: completeAppIDs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		appID := args[0]
+		if syncWait && syncAt != "" {
+			return fmt.Errorf("--wait cannot be used with --at")
+		}
+		if syncAt != "" {
+			return scheduleSync(appID)
+		}
+		if syncCommit != "" {
+			return fmt.Errorf("--commit requires --at")
+		}
+
 		client := NewClient()
 		// sync is a POST request to /apps/{id}/sync with empty body
 		resp, err := client.Post("/api/v1/apps/"+appID+"/sync", nil)
@@ -23,15 +52,158 @@ var syncCmd = &cobra.Command{
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusAccepted {
+			var apiResp struct {
+				Message string      `json:"message"`
+				Data    api.SyncJob `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+				return fmt.Errorf("error decoding response: %v", err)
+			}
+			if syncWait {
+				// A standby hands the sync to the leader as a schedule, which
+				// has no state to wait for.
+				if apiResp.Data.State == "" {
+					return fmt.Errorf("%s (schedule %s) and cannot be waited for; follow it with \"apps status %s\" or point --url at the leader", apiResp.Message, apiResp.Data.ID, appID)
+				}
+				return waitForSync(client, appID, apiResp.Message, apiResp.Data)
+			}
+			if printed, err := printData(apiResp); printed || err != nil {
+				return err
+			}
+			fmt.Printf("%s (job %s).\n", apiResp.Message, apiResp.Data.ID)
+			return nil
+		}
+		// Controllers before asynchronous syncs answer once the sync is done.
 		if resp.StatusCode != http.StatusOK {
 			return CheckResponse(resp)
 		}
 
+		apiResp, err := decodeResponse(resp.Body)
+		if err != nil {
+			return err
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
+		}
 		fmt.Println("Sync triggered successfully.")
 		return nil
 	},
 }
 
+// syncPollInterval is how often --wait asks for the job's state.
+const syncPollInterval = 2 * time.Second
+
+// waitForSync polls job until it finishes and fails when the sync did.
+func waitForSync(client *APIClient, appID, message string, job api.SyncJob) error {
+	table := viper.GetString("output") == outputTable
+	if table {
+		fmt.Printf("%s (job %s); waiting for it to finish...\n", message, job.ID)
+	}
+	for job.State == "queued" || job.State == "running" {
+		time.Sleep(syncPollInterval)
+		resp, err := client.Get("/api/v1/apps/" + appID + "/syncs/" + job.ID)
+		if err != nil {
+			// The controller may be restarting; keep waiting.
+			fmt.Fprintf(os.Stderr, "warning: error fetching sync job: %v\n", err)
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return fmt.Errorf("the controller no longer knows job %s, e.g. after a restart; check \"apps status %s\"", job.ID, appID)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+		var apiResp struct {
+			Data api.SyncJob `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&apiResp)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		if table && apiResp.Data.State != job.State && apiResp.Data.State == "running" {
+			fmt.Println("Sync running...")
+		}
+		job = apiResp.Data
+	}
+
+	if printed, err := printData(job); printed || err != nil {
+		if err == nil && job.State == "failed" {
+			err = fmt.Errorf("sync failed: %s", job.Error)
+		}
+		return err
+	}
+	if job.State == "failed" {
+		return fmt.Errorf("sync failed: %s", job.Error)
+	}
+	fmt.Printf("Sync finished in %s.\n", job.FinishedAt.Sub(*job.StartedAt).Round(time.Second))
+	return nil
+}
+
+func scheduleSync(appID string) error {
+	at, err := parseSyncTime(syncAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	client := NewClient()
+	resp, err := client.Post("/api/v1/apps/"+appID+"/schedules", map[string]interface{}{
+		"at":     at.Format(time.RFC3339),
+		"commit": syncCommit,
+	})
+	if err != nil {
+		return fmt.Errorf("error scheduling sync: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return CheckResponse(resp)
+	}
+
+	var apiResp struct {
+		Message string            `json:"message"`
+		Data    api.ScheduledSync `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	if printed, err := printData(apiResp); printed || err != nil {
+		return err
+	}
+	fmt.Printf("Sync scheduled for %s (schedule %s).\n", apiResp.Data.RunAt.Local().Format(time.RFC3339), apiResp.Data.ID)
+	return nil
+}
+
+// parseSyncTime accepts an RFC 3339 timestamp, a local "YYYY-MM-DD HH:MM",
+// a local "HH:MM" meaning its next occurrence, or a delay such as "90m".
+func parseSyncTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if at, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return at, nil
+		}
+	}
+	if clock, err := time.Parse("15:04", value); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+	if delay, err := time.ParseDuration(value); err == nil && delay > 0 {
+		return now.Add(delay), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --at %q: want RFC 3339, \"YYYY-MM-DD HH:MM\", \"HH:MM\" or a delay such as 90m", value)
+}
+
 func init() {
+	syncCmd.Flags().StringVar(&syncAt, "at", "", "Schedule the sync instead of running it now, e.g. 02:00, \"2026-01-31 02:00\" or 2h")
+	syncCmd.Flags().BoolVar(&syncWait, "wait", false, "Wait for the sync to finish and fail if it does")
+	syncCmd.Flags().StringVar(&syncCommit, "commit", "", "Full hash of the commit a scheduled sync deploys (default: latest on branch at run time)")
 	appsCmd.AddCommand(syncCmd)
 }
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenName   string
+	tokenScopes []string
+)
+
+// tokenCmd represents the apps token command
+var tokenCmd = &cobra.Command{
+	Use:     "token",
+	Short:   "Manage an app's API tokens",
+	Long:    `Manage API tokens that can only read, sync or operate a single app, e.g. for CI.`,
+	Example: `  conops-ctl apps token create <app-id> --name ci --scope sync`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// tokenCreateCmd represents the apps token create command
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create [app-id]",
+	Short: "Create an API token for an app",
+	Args:  cobra.ExactArgs(1),
+	Example: `  conops-ctl apps token create <app-id> --name ci
+  conops-ctl apps token create <app-id> --name monitor --scope read`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Post("/api/v1/apps/"+args[0]+"/tokens", map[string]interface{}{
+			"name":   tokenName,
+			"scopes": tokenScopes,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating token: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusCreated {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Message string       `json:"message"`
+			Data    api.AppToken `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
+		}
+		fmt.Printf("Token %s created with scopes %s.\n", apiResp.Data.Name, strings.Join(apiResp.Data.Scopes, ", "))
+		fmt.Println("Store it now; it cannot be shown again:")
+		fmt.Println(apiResp.Data.Token)
+		return nil
+	},
+}
+
+// tokenListCmd represents the apps token list command
+var tokenListCmd = &cobra.Command{
+	Use:               "list [app-id]",
+	Short:             "List an app's API tokens",
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps token list <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Get("/api/v1/apps/" + args[0] + "/tokens")
+		if err != nil {
+			return fmt.Errorf("error fetching tokens: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		var apiResp struct {
+			Data []api.AppToken `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+
+		if printed, err := printData(apiResp.Data); printed || err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED\tLAST USED")
+		for _, token := range apiResp.Data {
+			lastUsed := "never"
+			if token.LastUsedAt != nil {
+				lastUsed = token.LastUsedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				token.ID,
+				token.Name,
+				strings.Join(token.Scopes, ","),
+				token.CreatedAt.Format(time.RFC3339),
+				lastUsed,
+			)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// tokenRevokeCmd represents the apps token revoke command
+var tokenRevokeCmd = &cobra.Command{
+	Use:               "revoke [app-id] [token-id]",
+	Short:             "Revoke an app's API token",
+	Args:              cobra.ExactArgs(2),
+	Example:           `  conops-ctl apps token revoke <app-id> <token-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Delete("/api/v1/apps/" + args[0] + "/tokens/" + args[1])
+		if err != nil {
+			return fmt.Errorf("error revoking token: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		fmt.Println("Token revoked.")
+		return nil
+	},
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringVar(&tokenName, "name", "", "Token name, e.g. the CI pipeline using it (required)")
+	tokenCreateCmd.Flags().StringSliceVar(&tokenScopes, "scope", []string{"read", "sync"}, "Scopes to grant: read, sync, operate (repeatable)")
+	tokenCreateCmd.RegisterFlagCompletionFunc("scope", cobra.FixedCompletions([]string{"read", "sync", "operate"}, cobra.ShellCompDirectiveNoFileComp))
+	tokenCreateCmd.MarkFlagRequired("name")
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
+	appsCmd.AddCommand(tokenCmd)
+}
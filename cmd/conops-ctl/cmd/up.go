@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+// upCmd represents the up command
+var upCmd = &cobra.Command{
+	Use:               "up [app-id]",
+	Short:             "Start a stopped application again",
+	Long:              `Hand a stopped app back to the reconciler, which redeploys its desired commit.`,
+	Args:              cobra.ExactArgs(1),
+	Example:           `  conops-ctl apps up <app-id>`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := NewClient()
+		resp, err := client.Post("/api/v1/apps/"+args[0]+"/up", nil)
+		if err != nil {
+			return fmt.Errorf("error starting app: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return CheckResponse(resp)
+		}
+
+		apiResp, err := decodeResponse(resp.Body)
+		if err != nil {
+			return err
+		}
+		if printed, err := printData(apiResp); printed || err != nil {
+			return err
+		}
+		fmt.Println(apiResp.Message + ".")
+		return nil
+	},
+}
+
+func init() {
+	appsCmd.AddCommand(upCmd)
+}
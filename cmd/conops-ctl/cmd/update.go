@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 )
@@ -12,6 +14,27 @@ var (
 	updateBranch       string
 	updateComposePath  string
 	updatePollInterval string
+	updateGateScript   string
+	updateWebhooks     []string
+	updateSlackWebhook string
+	updateSlackChannel string
+	updateSigningKeys  string
+	updateTrack        string
+	updateWatchPaths   []string
+	updateProfiles     []string
+	updateEnvFile      string
+	updatePreviewTTL   string
+	updatePriority     string
+	updateApproval     bool
+	updatePinImages    bool
+	updateFreeze       []string
+	updatePostDeploy   []string
+	updatePreDeploy    []string
+	updateNotifyEvents []string
+	updateQuietHours   string
+	updateDockerHost   string
+	updateDockerTLS    string
+	updateSOPSKey      string
 )
 
 // updateCmd represents the update command
@@ -20,6 +43,9 @@ var updateCmd = &cobra.Command{
 	Short: "Update an application",
 	Long:  `Update app settings. Only provided flags are changed.`,
 	Args:  cobra.ExactArgs(1),
+	Example: `  conops-ctl apps update <app-id> --branch release --poll-interval 1m
+  conops-ctl apps update <app-id> --require-approval --freeze-window "fri 18:00-08:00 Europe/Berlin"`,
+	ValidArgsFunction: completeAppIDs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		appID := args[0]
 		updates := make(map[string]interface{})
@@ -30,12 +56,156 @@ var updateCmd = &cobra.Command{
 		if cmd.Flags().Changed("branch") {
 			updates["branch"] = updateBranch
 		}
+		if cmd.Flags().Changed("track") {
+			updates["track"] = updateTrack
+		}
 		if cmd.Flags().Changed("compose-path") {
 			updates["compose_path"] = updateComposePath
 		}
+		if cmd.Flags().Changed("watch-path") {
+			paths := []string{}
+			for _, watchPath := range updateWatchPaths {
+				if watchPath != "" {
+					paths = append(paths, watchPath)
+				}
+			}
+			updates["watch_paths"] = paths
+		}
+		if cmd.Flags().Changed("profile") {
+			profiles := []string{}
+			for _, profile := range updateProfiles {
+				if profile != "" {
+					profiles = append(profiles, profile)
+				}
+			}
+			updates["profiles"] = profiles
+		}
+		if cmd.Flags().Changed("env-file") {
+			updates["env_file"] = updateEnvFile
+		}
 		if cmd.Flags().Changed("poll-interval") {
 			updates["poll_interval"] = updatePollInterval
 		}
+		if cmd.Flags().Changed("pre-deploy") {
+			checks := []string{}
+			for _, check := range updatePreDeploy {
+				if check != "" {
+					checks = append(checks, check)
+				}
+			}
+			updates["pre_deploy"] = checks
+		}
+		if cmd.Flags().Changed("post-deploy") {
+			commands := []string{}
+			for _, command := range updatePostDeploy {
+				if command != "" {
+					commands = append(commands, command)
+				}
+			}
+			updates["post_deploy"] = commands
+		}
+		if cmd.Flags().Changed("preview-ttl") {
+			updates["preview_ttl"] = updatePreviewTTL
+		}
+		if cmd.Flags().Changed("priority") {
+			updates["priority"] = updatePriority
+		}
+		if cmd.Flags().Changed("require-approval") {
+			updates["require_approval"] = updateApproval
+		}
+		if cmd.Flags().Changed("pin-images") {
+			updates["pin_images"] = updatePinImages
+		}
+		if cmd.Flags().Changed("freeze-window") {
+			windows := []string{}
+			for _, window := range updateFreeze {
+				if window != "" {
+					windows = append(windows, window)
+				}
+			}
+			updates["freeze_windows"] = windows
+		}
+		if cmd.Flags().Changed("gate-script") {
+			// An empty path clears the script.
+			script := ""
+			if updateGateScript != "" {
+				data, err := os.ReadFile(updateGateScript)
+				if err != nil {
+					return fmt.Errorf("error reading gate script: %v", err)
+				}
+				script = string(data)
+			}
+			updates["gate_script"] = script
+		}
+		if cmd.Flags().Changed("notify-webhook") {
+			webhooks := []string{}
+			for _, webhook := range updateWebhooks {
+				if webhook != "" {
+					webhooks = append(webhooks, webhook)
+				}
+			}
+			updates["notify_webhooks"] = webhooks
+		}
+		if cmd.Flags().Changed("slack-webhook") {
+			updates["slack_webhook_url"] = updateSlackWebhook
+		}
+		if cmd.Flags().Changed("slack-channel") {
+			updates["slack_channel"] = updateSlackChannel
+		}
+		if cmd.Flags().Changed("notify-event") {
+			events := []string{}
+			for _, event := range updateNotifyEvents {
+				if event != "" {
+					events = append(events, event)
+				}
+			}
+			updates["notify_events"] = events
+		}
+		if cmd.Flags().Changed("quiet-hours") {
+			updates["quiet_hours"] = updateQuietHours
+		}
+		if cmd.Flags().Changed("docker-host") {
+			updates["docker_host"] = updateDockerHost
+		}
+		if cmd.Flags().Changed("docker-tls-dir") {
+			// An empty path removes the stored certificates.
+			tls := map[string]string{}
+			if updateDockerTLS != "" {
+				files := map[string]string{"ca_cert": "ca.pem", "client_cert": "cert.pem", "client_key": "key.pem"}
+				for field, name := range files {
+					data, err := os.ReadFile(filepath.Join(updateDockerTLS, name))
+					if err != nil {
+						return fmt.Errorf("error reading docker tls: %v", err)
+					}
+					tls[field] = string(data)
+				}
+			}
+			updates["docker_tls"] = tls
+		}
+		if cmd.Flags().Changed("sops-key-file") {
+			// An empty path removes the stored key.
+			key := ""
+			if updateSOPSKey != "" {
+				data, err := os.ReadFile(updateSOPSKey)
+				if err != nil {
+					return fmt.Errorf("error reading sops key: %v", err)
+				}
+				key = string(data)
+			}
+			updates["sops_key"] = key
+		}
+		if cmd.Flags().Changed("signing-keys") {
+			// An empty path turns signature verification off.
+			keys := []string{}
+			if updateSigningKeys != "" {
+				data, err := os.ReadFile(updateSigningKeys)
+				if err != nil {
+					return fmt.Errorf("error reading signing keys: %v", err)
+				}
+				keys = append(keys, string(data))
+			}
+			updates["signing_keys"] = keys
+		}
 
 		if len(updates) == 0 {
 			return fmt.Errorf("no updates provided")
@@ -60,7 +230,30 @@ var updateCmd = &cobra.Command{
 func init() {
 	updateCmd.Flags().StringVar(&updateName, "name", "", "New name for the app")
 	updateCmd.Flags().StringVar(&updateBranch, "branch", "", "New branch to track")
+	updateCmd.Flags().StringVar(&updateTrack, "track", "", "Follow the highest tag matching a semver constraint, e.g. v1.2.x (empty to follow the branch)")
 	updateCmd.Flags().StringVar(&updateComposePath, "compose-path", "", "New compose file path")
+	updateCmd.Flags().StringSliceVar(&updateWatchPaths, "watch-path", nil, "Path or glob whose changes trigger a sync; repeatable, empty to watch the compose file's directory")
+	updateCmd.Flags().StringSliceVar(&updateProfiles, "profile", nil, "Compose profile to enable; repeatable, empty to fall back to COMPOSE_PROFILES")
+	updateCmd.Flags().StringVar(&updateEnvFile, "env-file", "", "Repo-relative .env file passed to compose with --env-file (empty to use the default .env)")
 	updateCmd.Flags().StringVar(&updatePollInterval, "poll-interval", "", "New poll interval (e.g. 30s)")
+	// StringArray, not StringSlice: commands may contain commas.
+	updateCmd.Flags().StringArrayVar(&updatePreDeploy, "pre-deploy", nil, "URL that must answer 2xx, or \"service: command\" run in a one-off container, before up; repeatable, empty to remove all")
+	updateCmd.Flags().StringArrayVar(&updatePostDeploy, "post-deploy", nil, "\"service: command\" run with compose exec after every successful up; repeatable, in order, empty to remove all")
+	updateCmd.Flags().StringVar(&updatePreviewTTL, "preview-ttl", "", "Remove the app once it has been idle this long, e.g. 72h for a PR preview (empty to keep it)")
+	updateCmd.Flags().StringVar(&updatePriority, "priority", "", "Priority class: critical, high, normal or low")
+	updateCmd.Flags().BoolVar(&updateApproval, "require-approval", false, "Hold new commits until they are approved (--require-approval=false to deploy them directly)")
+	updateCmd.Flags().BoolVar(&updatePinImages, "pin-images", false, "Re-apply a commit with the image digests its first sync recorded (--pin-images=false to pull tags)")
+	updateCmd.Flags().StringArrayVar(&updateFreeze, "freeze-window", nil, "Window during which new commits are not deployed, e.g. \"fri 18:00-08:00 Europe/Berlin\"; repeatable, empty to remove all")
+	updateCmd.Flags().StringVar(&updateGateScript, "gate-script", "", "Path to a Starlark gate script (empty to remove)")
+	updateCmd.Flags().StringSliceVar(&updateWebhooks, "notify-webhook", nil, "Webhook URL for sync and drift events; repeatable, empty to remove all")
+	updateCmd.Flags().StringVar(&updateSlackWebhook, "slack-webhook", "", "Slack incoming webhook URL for failure/recovery messages (empty to remove)")
+	updateCmd.Flags().StringVar(&updateSlackChannel, "slack-channel", "", "Slack channel for failure/recovery messages; needs CONOPS_SLACK_BOT_TOKEN (empty to remove)")
+	updateCmd.Flags().StringSliceVar(&updateNotifyEvents, "notify-event", nil, "Event the app's own targets receive (sync.succeeded, sync.failed, drift.detected, preview.expired); repeatable, empty for all")
+	updateCmd.Flags().StringVar(&updateQuietHours, "quiet-hours", "", "Daily window in which the app's own targets only get failures, e.g. \"22:00-07:00 Europe/Berlin\" (empty to remove)")
+	updateCmd.Flags().StringVar(&updateSigningKeys, "signing-keys", "", "File of allowed commit signers: SSH public keys and/or armored GPG public keys (empty to stop requiring signatures)")
+	updateCmd.Flags().StringVar(&updateDockerHost, "docker-host", "", "Docker daemon to deploy to: an ssh:// or tcp:// URL or a docker context name; the stack is removed from the old one (empty for the local daemon)")
+	updateCmd.Flags().StringVar(&updateDockerTLS, "docker-tls-dir", "", "Directory with ca.pem, cert.pem and key.pem for a tcp:// docker host (empty to remove)")
+	updateCmd.Flags().StringVar(&updateSOPSKey, "sops-key-file", "", "age identity file or armored GPG private key that decrypts the app's SOPS-encrypted env file (empty to remove)")
+	updateCmd.RegisterFlagCompletionFunc("priority", cobra.FixedCompletions([]string{"critical", "high", "normal", "low"}, cobra.ShellCompDirectiveNoFileComp))
 	appsCmd.AddCommand(updateCmd)
 }
@@ -1,20 +1,95 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/version"
 	"github.com/spf13/cobra"
 )
 
+// Version is set at build time by goreleaser.
+var Version = "dev"
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
-	Short: "Print the version number of conops-ctl",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("conops-ctl v0.1.0")
+	Short: "Print the version of conops-ctl and the controller",
+	Example: `  conops-ctl version
+  conops-ctl version -o json`,
+	ValidArgsFunction: cobra.NoFileCompletions,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := controllerVersion()
+		output := versionOutput{
+			Client:     clientVersion{Version: Version, Channel: version.Channel(Version), APIVersion: version.APIVersion},
+			Controller: info,
+		}
+		if err != nil {
+			output.ControllerError = err.Error()
+		}
+		if printed, err := printData(output); printed || err != nil {
+			return err
+		}
+
+		fmt.Printf("conops-ctl %s (%s, API v%d)\n", Version, version.Channel(Version), version.APIVersion)
+		if err != nil {
+			fmt.Printf("controller: %v\n", err)
+			return nil
+		}
+		build := ""
+		if info.Commit != "" {
+			commit := info.Commit
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			build = ", commit " + commit
+		}
+		fmt.Printf("controller %s (%s, API v%d, schema %d%s)\n", info.Version, info.Channel, info.APIVersion, info.SchemaVersion, build)
+		if err := checkSkew(info.Version, info.APIVersion, info.MinClientVersion); err != nil {
+			fmt.Printf("warning: %v\n", err)
+		} else if warning := skewWarning(info.Version); warning != "" {
+			fmt.Printf("warning: %s\n", warning)
+		}
+		return nil
 	},
 }
 
+// versionOutput is what version prints with --output json or yaml.
+type versionOutput struct {
+	Client     clientVersion    `json:"client"`
+	Controller *api.VersionInfo `json:"controller,omitempty"`
+	// ControllerError says why the controller's version is missing.
+	ControllerError string `json:"controller_error,omitempty"`
+}
+
+type clientVersion struct {
+	Version    string `json:"version"`
+	Channel    string `json:"channel"`
+	APIVersion int    `json:"api_version"`
+}
+
+// controllerVersion asks the controller for its version.
+func controllerVersion() (*api.VersionInfo, error) {
+	client := NewClient()
+	client.SkipVersionCheck = true
+	resp, err := client.Get("/api/v1/version")
+	if err != nil {
+		return nil, fmt.Errorf("unreachable (%v)", err)
+	}
+	defer resp.Body.Close()
+	if err := CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var info api.VersionInfo
+	result := api.APIResponse{Data: &info}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response (%v)", err)
+	}
+	return &info, nil
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	watchInterval time.Duration
+	watchSearch   string
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch [app-id]",
+	Short: "Follow the status of apps as it changes",
+	Long: `List the apps, or one app, and print a row again whenever an app's status,
+commit or error changes, until interrupted. With --output json or yaml, each
+change prints the app instead.`,
+	Args: cobra.MaximumNArgs(1),
+	Example: `  conops-ctl apps watch
+  conops-ctl apps watch <app-id> --interval 5s`,
+	ValidArgsFunction: completeAppIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchInterval < time.Second {
+			return fmt.Errorf("--interval must be at least 1s")
+		}
+		appID := ""
+		if len(args) == 1 {
+			appID = args[0]
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		client := NewClient()
+		apps, err := watchPoll(client, appID)
+		if err != nil {
+			return err
+		}
+		if appID != "" && len(apps) == 0 {
+			return fmt.Errorf("app %s not found", appID)
+		}
+		rows := newWatchTable(apps)
+		seen := make(map[string]api.App, len(apps))
+		if err := rows.print(apps...); err != nil {
+			return err
+		}
+		for _, app := range apps {
+			seen[app.ID] = app
+		}
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			apps, err := watchPoll(client, appID)
+			if err != nil {
+				// The controller may be restarting; keep watching.
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+				continue
+			}
+			current := make(map[string]bool, len(apps))
+			var changed []api.App
+			for _, app := range apps {
+				current[app.ID] = true
+				if previous, ok := seen[app.ID]; !ok || watchChanged(previous, app) {
+					changed = append(changed, app)
+				}
+				seen[app.ID] = app
+			}
+			for id, app := range seen {
+				if !current[id] {
+					app.Status = "deleted"
+					changed = append(changed, app)
+					delete(seen, id)
+				}
+			}
+			if err := rows.print(changed...); err != nil {
+				return err
+			}
+			if appID != "" && len(apps) == 0 {
+				return nil
+			}
+		}
+	},
+}
+
+// watchPoll fetches the watched app, or the apps matching --search; a
+// watched app that was deleted is returned as no apps. There is no status
+// filter, since an app leaving it would look deleted.
+func watchPoll(client *APIClient, appID string) ([]api.App, error) {
+	path := "/api/v1/apps/" + appID
+	if appID == "" {
+		query := url.Values{"fields": {"summary"}}
+		if watchSearch != "" {
+			query.Set("q", watchSearch)
+		}
+		path += "?" + query.Encode()
+	}
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching apps: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if appID != "" && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, CheckResponse(resp)
+	}
+	if appID != "" {
+		var apiResp struct {
+			Data api.App `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return nil, fmt.Errorf("error decoding response: %v", err)
+		}
+		return []api.App{apiResp.Data}, nil
+	}
+	var apiResp struct {
+		Data []api.App `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+	return apiResp.Data, nil
+}
+
+// watchChanged tells whether an app changed in a way the table shows.
+func watchChanged(previous, app api.App) bool {
+	return previous.Status != app.Status ||
+		previous.Paused != app.Paused ||
+		previous.LastSeenCommit != app.LastSeenCommit ||
+		previous.LastSyncedCommit != app.LastSyncedCommit ||
+		previous.LastSyncError != app.LastSyncError ||
+		!previous.LastSyncAt.Equal(app.LastSyncAt)
+}
+
+// watchTable prints rows as they change. Unlike a tabwriter, which aligns
+// what it is given at once, it keeps the column widths of the first rows so
+// later rows line up under them.
+type watchTable struct {
+	idWidth, nameWidth int
+	header             bool
+}
+
+func newWatchTable(apps []api.App) *watchTable {
+	t := &watchTable{idWidth: len("ID"), nameWidth: len("NAME")}
+	for _, app := range apps {
+		t.idWidth = max(t.idWidth, len(app.ID))
+		t.nameWidth = max(t.nameWidth, len(app.Name))
+	}
+	return t
+}
+
+func (t *watchTable) print(apps ...api.App) error {
+	switch viper.GetString("output") {
+	case outputJSON:
+		for _, app := range apps {
+			encoded, err := json.Marshal(app)
+			if err != nil {
+				return fmt.Errorf("error encoding JSON: %v", err)
+			}
+			fmt.Println(string(encoded))
+		}
+		return nil
+	case outputYAML:
+		for _, app := range apps {
+			fmt.Println("---")
+			if err := PrintYAML(app); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	const row = "%-*s   %-*s   %-18s   %-7s   %-7s   %-20s   %s\n"
+	if !t.header {
+		fmt.Printf(row, t.idWidth, "ID", t.nameWidth, "NAME", "STATUS", "SYNCED", "DESIRED", "LAST SYNC", "ERROR")
+		t.header = true
+	}
+	for _, app := range apps {
+		status := app.Status
+		if app.Paused && status != "deleted" {
+			status += " (paused)"
+		}
+		lastSync := "-"
+		if !app.LastSyncAt.IsZero() {
+			lastSync = app.LastSyncAt.Format(time.RFC3339)
+		}
+		errText, _, _ := strings.Cut(app.LastSyncError, "\n")
+		if errText == "" {
+			errText = "-"
+		}
+		fmt.Printf(row, t.idWidth, app.ID, t.nameWidth, app.Name, status,
+			watchCommit(app.LastSyncedCommit), watchCommit(app.LastSeenCommit), lastSync, errText)
+	}
+	return nil
+}
+
+func watchCommit(commit string) string {
+	if commit == "" {
+		return "-"
+	}
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 2*time.Second, "How often to poll the controller")
+	watchCmd.Flags().StringVarP(&watchSearch, "search", "q", "", "Only watch apps whose ID, name or repo URL contains this")
+	appsCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	// listenAddrEnv is the address the API and UI are served on.
+	listenAddrEnv = "CONOPS_LISTEN_ADDR"
+	// tlsCertFileEnv and tlsKeyFileEnv serve HTTPS with a certificate from
+	// disk, reloaded when the files change.
+	tlsCertFileEnv = "CONOPS_TLS_CERT_FILE"
+	tlsKeyFileEnv  = "CONOPS_TLS_KEY_FILE"
+	// acmeDomainsEnv serves HTTPS with certificates obtained over ACME for
+	// the comma-separated domains.
+	acmeDomainsEnv = "CONOPS_ACME_DOMAINS"
+	// acmeEmailEnv is the contact address of the ACME account.
+	acmeEmailEnv = "CONOPS_ACME_EMAIL"
+	// acmeDirectoryEnv is the ACME directory URL, Let's Encrypt by default.
+	acmeDirectoryEnv = "CONOPS_ACME_DIRECTORY_URL"
+	// acmeCacheDirEnv is where ACME accounts and certificates are kept.
+	acmeCacheDirEnv = "CONOPS_ACME_CACHE_DIR"
+	// httpRedirectAddrEnv is an address on which plain HTTP requests are
+	// redirected to HTTPS, e.g. ":80".
+	httpRedirectAddrEnv = "CONOPS_HTTP_REDIRECT_ADDR"
+	// basePathEnv is the path prefix the UI and API are served under when a
+	// reverse proxy forwards a sub-path, e.g. "/conops".
+	basePathEnv = "CONOPS_BASE_PATH"
+)
+
+// certReloadInterval is the least time between two checks of the
+// certificate files for changes.
+const certReloadInterval = time.Minute
+
+// listenConfig is where and how the controller serves its API and UI.
+type listenConfig struct {
+	Addr         string
+	CertFile     string
+	KeyFile      string
+	ACMEDomains  []string
+	ACMEEmail    string
+	ACMEURL      string
+	ACMECacheDir string
+	RedirectAddr string
+	// BasePath starts with a slash and has none at the end, or is empty.
+	BasePath string
+}
+
+// loadListenConfigFromEnv reads the listener settings. The controller serves
+// plain HTTP on :8080 by default; a certificate or ACME domains turn on
+// HTTPS, and a redirect address adds a plain HTTP listener sending clients
+// there.
+func loadListenConfigFromEnv(dataDir string) (listenConfig, error) {
+	cfg := listenConfig{
+		Addr:         ":8080",
+		CertFile:     strings.TrimSpace(os.Getenv(tlsCertFileEnv)),
+		KeyFile:      strings.TrimSpace(os.Getenv(tlsKeyFileEnv)),
+		ACMEEmail:    strings.TrimSpace(os.Getenv(acmeEmailEnv)),
+		ACMEURL:      strings.TrimSpace(os.Getenv(acmeDirectoryEnv)),
+		ACMECacheDir: strings.TrimSpace(os.Getenv(acmeCacheDirEnv)),
+		RedirectAddr: strings.TrimSpace(os.Getenv(httpRedirectAddrEnv)),
+	}
+	if value := strings.TrimSpace(os.Getenv(listenAddrEnv)); value != "" {
+		cfg.Addr = value
+	}
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		return listenConfig{}, fmt.Errorf("invalid %s: %s", listenAddrEnv, cfg.Addr)
+	}
+	if value := strings.TrimSpace(os.Getenv(acmeDomainsEnv)); value != "" {
+		for _, domain := range strings.Split(value, ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				cfg.ACMEDomains = append(cfg.ACMEDomains, domain)
+			}
+		}
+	}
+	if cfg.ACMECacheDir == "" {
+		cfg.ACMECacheDir = filepath.Join(dataDir, "conops-acme")
+	}
+	if value := strings.Trim(strings.TrimSpace(os.Getenv(basePathEnv)), "/"); value != "" {
+		if strings.ContainsAny(value, "?#{}* ") || path.Clean("/"+value) != "/"+value {
+			return listenConfig{}, fmt.Errorf("invalid %s: %s", basePathEnv, os.Getenv(basePathEnv))
+		}
+		cfg.BasePath = "/" + value
+	}
+
+	switch {
+	case (cfg.CertFile == "") != (cfg.KeyFile == ""):
+		return listenConfig{}, fmt.Errorf("%s and %s must be set together", tlsCertFileEnv, tlsKeyFileEnv)
+	case cfg.CertFile != "" && len(cfg.ACMEDomains) > 0:
+		return listenConfig{}, fmt.Errorf("%s and %s cannot be combined", tlsCertFileEnv, acmeDomainsEnv)
+	case cfg.RedirectAddr != "" && !cfg.TLS():
+		return listenConfig{}, fmt.Errorf("%s requires %s or %s", httpRedirectAddrEnv, tlsCertFileEnv, acmeDomainsEnv)
+	}
+	if cfg.RedirectAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.RedirectAddr); err != nil {
+			return listenConfig{}, fmt.Errorf("invalid %s: %s", httpRedirectAddrEnv, cfg.RedirectAddr)
+		}
+	}
+	return cfg, nil
+}
+
+// TLS reports whether the controller serves HTTPS.
+func (c listenConfig) TLS() bool {
+	return c.CertFile != "" || len(c.ACMEDomains) > 0
+}
+
+// TLSSource describes where certificates come from, for the startup log.
+func (c listenConfig) TLSSource() string {
+	switch {
+	case len(c.ACMEDomains) > 0:
+		return "acme"
+	case c.CertFile != "":
+		return "file"
+	}
+	return "none"
+}
+
+// tlsConfig returns the server's TLS settings and, for ACME, the handler
+// that must answer plain HTTP so http-01 challenges can be solved; it is
+// nil with a certificate from disk.
+func (c listenConfig) tlsConfig() (*tls.Config, func(http.Handler) http.Handler, error) {
+	if len(c.ACMEDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(c.ACMECacheDir),
+			HostPolicy: autocert.HostWhitelist(c.ACMEDomains...),
+			Email:      c.ACMEEmail,
+		}
+		if c.ACMEURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: c.ACMEURL}
+		}
+		config := manager.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config, manager.HTTPHandler, nil
+	}
+
+	certs := &certReloader{certFile: c.CertFile, keyFile: c.KeyFile}
+	if _, err := certs.load(); err != nil {
+		return nil, nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}, nil, nil
+}
+
+// certReloader serves a certificate from disk and loads it again when the
+// files change, so a renewed certificate is picked up without a restart.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// GetCertificate implements tls.Config.GetCertificate. A renewal that
+// cannot be loaded, e.g. while one of the files is still being written,
+// keeps the previous certificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	due := time.Since(r.checked) >= certReloadInterval
+	r.mu.Unlock()
+	if due {
+		cert, _ := r.load()
+		return cert, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// load reads the key pair unless it is unchanged since the last load. On
+// failure it returns the previous certificate, if any, with the error.
+func (r *certReloader) load() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	modTime := time.Time{}
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return r.cert, fmt.Errorf("read tls certificate: %w", err)
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return r.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return r.cert, fmt.Errorf("load tls certificate: %w", err)
+	}
+	r.cert, r.modTime = &cert, modTime
+	return r.cert, nil
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same URL over HTTPS on
+// the port of tlsAddr.
+func redirectToHTTPS(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Trim(r.Host, "[]")
+		if hostname, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = hostname
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -2,57 +2,79 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/conops/conops/internal/compose"
 	"github.com/conops/conops/internal/controller"
 	"github.com/conops/conops/internal/credentials"
+	"github.com/conops/conops/internal/hooks"
+	"github.com/conops/conops/internal/notify"
+	"github.com/conops/conops/internal/provenance"
 	"github.com/conops/conops/internal/store"
+	"github.com/conops/conops/internal/systemd"
 	"github.com/conops/conops/internal/ui"
+	"github.com/conops/conops/internal/version"
+	"github.com/conops/conops/web"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+// subcommands are dispatched on the first argument; with none the
+// controller starts.
+var subcommands = map[string]func(args []string) error{
+	"install-service": runInstallService,
+	"migrate":         runMigrate,
+	"schema-version":  runSchemaVersion,
+	"upgrade":         runUpgrade,
+}
 
-	dataDir := "/data"
-	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
-		// Fallback for local development if /data doesn't exist
-		dataDir = "."
-	}
+var storeLabels = map[string]string{"postgres": "PostgreSQL", "sqlite": "SQLite"}
 
-	var dbStore store.Store
-	var err error
+// appRuntime is everything the controller and UI drive on the container
+// runtime: the compose executor, or the fake in CONOPS_FAKE_RUNTIME mode.
+type appRuntime interface {
+	controller.ReconcilerRuntime
+	controller.RuntimeCleaner
+	controller.RuntimePurger
+	controller.RuntimePlanner
+	controller.RuntimeRenderer
+	controller.RuntimeStatsReader
+	controller.RuntimeImagePruner
+	controller.RuntimePreflighter
+	ui.Runtime
+}
 
-	dbType := os.Getenv("DB_TYPE")
-	if dbType == "postgres" {
-		connString := os.Getenv("DB_CONNECTION_STRING")
-		if connString == "" {
-			logger.Error("DB_CONNECTION_STRING is required for postgres")
-			os.Exit(1)
-		}
-		dbStore, err = store.NewPostgresStore(context.Background(), connString)
-		if err != nil {
-			logger.Error("Failed to initialize postgres store", "error", err)
-			os.Exit(1)
+func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
 		}
-		logger.Info("Using PostgreSQL store")
-	} else {
-		// Default to SQLite
-		dbPath := filepath.Join(dataDir, "conops.db")
+	}
 
-		dbStore, err = store.NewSQLiteStore(dbPath)
-		if err != nil {
-			logger.Error("Failed to initialize sqlite store", "error", err)
-			os.Exit(1)
-		}
-		logger.Info("Using SQLite store")
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	dataDir := resolveDataDir()
+
+	dbStore, kind, err := openStore(dataDir)
+	if err != nil {
+		logger.Error("Failed to initialize "+kind+" store", "error", err)
+		os.Exit(1)
 	}
+	logger.Info("Using "+storeLabels[kind]+" store", "version", version.Version, "schema_version", store.SchemaVersion)
 	defer dbStore.Close()
 
 	credentialService, err := credentials.NewServiceFromEnv(filepath.Join(dataDir, "conops-encryption.key"))
@@ -64,17 +86,47 @@ func main() {
 
 	registry := controller.NewRegistry(dbStore, credentialService)
 
+	hookRunner, err := hooks.LoadFromEnv(logger)
+	if err != nil {
+		logger.Error("Failed to load extension hooks", "error", err)
+		os.Exit(1)
+	}
+	registry.SetHooks(hookRunner)
+	if hookRunner.Count() > 0 {
+		logger.Info("Extension hooks loaded", "count", hookRunner.Count())
+	}
+
+	notifier, err := notify.LoadFromEnv(logger)
+	if err != nil {
+		logger.Error("Failed to configure notifications", "error", err)
+		os.Exit(1)
+	}
+	registry.SetNotifier(notifier)
+	if notifier.Count() > 0 {
+		logger.Info("Global notification webhooks configured", "count", notifier.Count())
+	}
+
+	attestor, err := provenance.LoadFromEnv(filepath.Join(dataDir, "conops-attestation.key"))
+	if err != nil {
+		logger.Error("Failed to configure attestations", "error", err)
+		os.Exit(1)
+	}
+	if attestor != nil {
+		logger.Info("Deployment attestations are enabled", "key_id", attestor.KeyID(), "source", attestor.KeySource(), "push", attestor.Pushes())
+	}
+
 	// Start Git Watcher
 	watcher := controller.NewGitWatcher(registry, logger)
-	ctx, cancel := context.WithCancel(context.Background())
+	// SIGTERM (systemctl stop/restart, docker stop) shuts down gracefully.
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
-	go watcher.Start(ctx)
 
 	reconcilerCfg, err := controller.LoadReconcilerConfigFromEnv()
 	if err != nil {
 		logger.Error("Failed to load reconciler config", "error", err)
 		os.Exit(1)
 	}
+	registry.SetRetryPolicy(reconcilerCfg.RetryPolicy())
 	executor := compose.NewComposeExecutor(logger)
 	if runtimeDir := strings.TrimSpace(os.Getenv("CONOPS_RUNTIME_DIR")); runtimeDir != "" {
 		executor.WorkDir = runtimeDir
@@ -84,56 +136,416 @@ func main() {
 	} else if dataDir != "." {
 		executor.ToolsDir = filepath.Join(dataDir, "conops-tools")
 	}
+	executor.HealthTimeout, err = compose.LoadHealthTimeoutFromEnv()
+	if err != nil {
+		logger.Error("Failed to load health wait config", "error", err)
+		os.Exit(1)
+	}
+	// Apps with a docker host deploy to that daemon instead of the local one.
+	executor.Endpoints = registry
+	secretResolver, err := credentials.NewSecretResolverFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure secret references", "error", err)
+		os.Exit(1)
+	}
+	executor.Secrets = secretResolver
+	executor.SOPSKeys = registry
 	logger.Info("Runtime workspace configured", "dir", executor.WorkDir, "tools_dir", executor.ToolsDir)
-	reconciler := controller.NewReconciler(registry, executor, logger, reconcilerCfg)
-	go reconciler.Run(ctx)
+	var runtime appRuntime = executor
+	if value := strings.TrimSpace(os.Getenv(compose.FakeRuntimeEnv)); value == "1" || strings.EqualFold(value, "true") {
+		runtime = compose.NewFakeRuntime(logger)
+		logger.Warn("Using the in-memory fake runtime; apps are not deployed", "env", compose.FakeRuntimeEnv)
+	}
+	reconciler := controller.NewReconciler(registry, runtime, logger, reconcilerCfg)
+	// Signed-commit policies are checked against the watcher's repo cache.
+	reconciler.Syncer.Verifier = watcher
+	reconciler.Syncer.Attestor = attestor
+	pruneCfg, err := controller.LoadImagePruneConfigFromEnv()
+	if err != nil {
+		logger.Error("Failed to load image prune config", "error", err)
+		os.Exit(1)
+	}
+	pruner := &controller.ImagePruner{
+		Runtime:   runtime,
+		Config:    pruneCfg,
+		Queue:     reconciler.Syncer.Queue,
+		Logger:    logger,
+		Endpoints: registry,
+	}
+	if pruneCfg.Mode != controller.ImagePruneOff {
+		logger.Info("Image pruning is enabled", "mode", pruneCfg.Mode, "filters", pruneCfg.Filters(), "interval", pruneCfg.Interval)
+	}
+	reconciler.Syncer.Pruner = pruner
+
+	previewInterval, err := controller.LoadPreviewSweepIntervalFromEnv()
+	if err != nil {
+		logger.Error("Failed to load preview sweeper config", "error", err)
+		os.Exit(1)
+	}
+	sweeper := &controller.PreviewSweeper{
+		Registry: registry,
+		Purger:   runtime,
+		Queue:    reconciler.Syncer.Queue,
+		Logger:   logger,
+		Interval: previewInterval,
+	}
+
+	janitorCfg, err := controller.LoadJanitorConfigFromEnv()
+	if err != nil {
+		logger.Error("Failed to load janitor config", "error", err)
+		os.Exit(1)
+	}
+	janitor := &controller.Janitor{
+		Registry:   registry,
+		CacheDir:   watcher.CacheDir,
+		RuntimeDir: executor.WorkDir,
+		Cache:      watcher,
+		Queue:      reconciler.Syncer.Queue,
+		Config:     janitorCfg,
+		Logger:     logger,
+	}
+	if janitorCfg.Budget > 0 {
+		logger.Info("Disk budget is enabled", "budget", janitorCfg.Budget, "interval", janitorCfg.Interval)
+	}
+
+	scheduler := &controller.SyncScheduler{
+		Registry: registry,
+		Syncer:   reconciler.Syncer,
+		Logger:   logger,
+		Interval: 10 * time.Second,
+	}
+
+	// Controllers sharing a Postgres store elect one leader, which alone
+	// watches repos and deploys; every controller serves the API and UI.
+	election := &controller.LeaderElection{Logger: logger}
+	if locker, ok := dbStore.(store.LeaderLocker); ok {
+		election.Locker = locker
+	}
+	electionDone := make(chan struct{})
+	go func() {
+		defer close(electionDone)
+		election.Run(ctx, func(ctx context.Context) {
+			var loops sync.WaitGroup
+			for _, run := range []func(context.Context){watcher.Start, pruner.Run, sweeper.Run, janitor.Run, scheduler.Run, reconciler.Run} {
+				loops.Add(1)
+				go func() {
+					defer loops.Done()
+					run(ctx)
+				}()
+			}
+			loops.Wait()
+			// Syncs the API or the scheduler started finish before the lock
+			// is released, so they never overlap with the next leader's.
+			drainCtx, stop := context.WithTimeout(context.Background(), reconcilerCfg.SyncTimeout)
+			defer stop()
+			if err := reconciler.Syncer.Queue.Wait(drainCtx); err != nil {
+				logger.Warn("Timed out waiting for running syncs to finish", "error", err)
+			}
+		})
+	}()
+
+	statsInterval, err := controller.LoadStatsIntervalFromEnv()
+	if err != nil {
+		logger.Error("Failed to load stats collector config", "error", err)
+		os.Exit(1)
+	}
+	statsCollector := &controller.StatsCollector{
+		Registry: registry,
+		Runtime:  runtime,
+		Logger:   logger,
+		Interval: statsInterval,
+	}
+	go statsCollector.Run(ctx)
+
+	listenCfg, err := loadListenConfigFromEnv(dataDir)
+	if err != nil {
+		logger.Error("Failed to load listener config", "error", err)
+		os.Exit(1)
+	}
+	basePath := listenCfg.BasePath
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	appHandler := controller.NewHandler(registry, executor, executor, logger)
-	uiHandler, err := ui.NewHandler(registry, executor, "web/templates")
+	appHandler := controller.NewHandler(registry, runtime, runtime, logger)
+	// Manual syncs share the reconciler's syncer: one queue so syncs of an
+	// app never overlap, and the same rollback, verification and attestation.
+	appHandler.Syncer = reconciler.Syncer
+	appHandler.HealthStatusCodes, err = controller.LoadHealthStatusCodesFromEnv()
+	if err != nil {
+		logger.Error("Failed to load health endpoint config", "error", err)
+		os.Exit(1)
+	}
+	appHandler.Stats = statsCollector
+	appHandler.Leader = election.IsLeader
+	appHandler.Revisions = watcher
+	appHandler.BasePath = basePath
+	templates, static, err := web.Assets(strings.TrimSpace(os.Getenv(web.DirEnv)))
+	if err != nil {
+		logger.Error("Failed to load UI assets", "error", err)
+		os.Exit(1)
+	}
+	uiHandler, err := ui.NewHandler(registry, runtime, templates)
 	if err != nil {
 		logger.Error("Failed to initialize UI handler", "error", err)
 		os.Exit(1)
 	}
+	uiHandler.Stats = statsCollector
+	uiHandler.BasePath = basePath
+
+	auth := controller.NewAuthenticator(registry, os.Getenv(controller.APITokenEnv), logger)
+	if externalURL := strings.TrimSpace(os.Getenv(notify.ExternalURLEnv)); externalURL != "" {
+		if err := auth.TrustOrigin(externalURL); err != nil {
+			logger.Error("Invalid "+notify.ExternalURLEnv, "error", err)
+			os.Exit(1)
+		}
+	}
+	if auth.Enabled() {
+		logger.Info("API authentication is enabled")
+	} else {
+		logger.Warn("API authentication is disabled; set " + controller.APITokenEnv + " to require a token")
+	}
+
+	limits, err := controller.LoadRequestLimitsFromEnv()
+	if err != nil {
+		logger.Error("Failed to load request limits", "error", err)
+		os.Exit(1)
+	}
+	limiter := controller.NewRateLimiter(limits)
+	if limiter.Enabled() {
+		logger.Info("API rate limiting is enabled", "rate", limits.Rate, "burst", limits.Burst, "trusted_proxies", len(limits.TrustedProxies))
+	}
 
 	// UI Routes
 	r.Route("/ui", func(r chi.Router) {
-		r.Get("/apps", uiHandler.ServeAppsPage)
-		r.Get("/apps/new", uiHandler.ServeNewAppPage)
-		r.Get("/apps/fragment", uiHandler.ServeAppsFragment)
-		r.Get("/apps/{id}", uiHandler.ServeAppDetailPage)
-		r.Get("/apps/{id}/fragment", uiHandler.ServeAppDetailFragment)
-		r.Get("/apps/{id}/edit", uiHandler.ServeEditAppPage)
-		r.Post("/apps", uiHandler.HandleAddApp)
-		r.Post("/apps/add", uiHandler.HandleAddApp)
-		r.Post("/apps/{id}/edit", uiHandler.HandleEditApp)
-		r.Handle("/static/*", http.StripPrefix("/ui/static/", http.FileServer(http.Dir("web/static"))))
+		r.Handle("/static/*", http.StripPrefix(basePath+"/ui/static/", http.FileServer(http.FS(static))))
+		r.Group(func(r chi.Router) {
+			// The UI edits every app, so it is for admins only.
+			r.Use(limiter.ByIP, controller.MaxBodySize(limits.MaxBody), auth.Authenticate, controller.RequireAdmin)
+			r.Get("/dashboard", uiHandler.ServeDashboardPage)
+			r.Get("/dashboard/fragment", uiHandler.ServeDashboardFragment)
+			r.Get("/apps", uiHandler.ServeAppsPage)
+			r.Get("/apps/new", uiHandler.ServeNewAppPage)
+			r.Get("/apps/fragment", uiHandler.ServeAppsFragment)
+			r.Get("/apps/{id}", uiHandler.ServeAppDetailPage)
+			r.Get("/apps/{id}/fragment", uiHandler.ServeAppDetailFragment)
+			r.Get("/apps/{id}/edit", uiHandler.ServeEditAppPage)
+			r.Get("/apps/{id}/syncs", uiHandler.ServeSyncHistory)
+			r.Get("/apps/{id}/syncs/{syncID}/output", uiHandler.ServeSyncOutput)
+			r.Get("/apps/{id}/logs/sync", uiHandler.ServeSyncLogStream)
+			r.Get("/apps/{id}/services/{service}/logs", uiHandler.ServeServiceLogStream)
+			r.Post("/apps", uiHandler.HandleAddApp)
+			r.Post("/apps/add", uiHandler.HandleAddApp)
+			r.Post("/apps/{id}/edit", uiHandler.HandleEditApp)
+			r.Post("/apps/{id}/env", uiHandler.HandleSaveEnv)
+		})
 	})
 
-	// Redirect root to UI
+	// Probes for load balancers and orchestrators; unauthenticated like
+	// the version endpoint.
+	probes := &controller.Probes{
+		Store:    dbStore,
+		Runtime:  runtime,
+		CacheDir: watcher.CacheDir,
+		Healthy:  reconciler.Healthy,
+	}
+	if election.Locker != nil {
+		probes.Role = election.Role
+	}
+	r.Get("/healthz", probes.Live)
+	r.Get("/readyz", probes.Ready)
+	uiHandler.Probes = probes
+	uiHandler.Watcher = watcher
+
+	// Redirect root to the dashboard
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/ui/apps", http.StatusFound)
+		http.Redirect(w, r, basePath+"/ui/dashboard", http.StatusFound)
 	})
 
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Route("/apps", func(r chi.Router) {
-			r.Post("/", appHandler.RegisterApp)
-			r.Get("/", appHandler.ListApps)
-			r.Get("/{id}", appHandler.GetApp)
-			r.Patch("/{id}", appHandler.UpdateApp)
-			r.Post("/{id}/sync", appHandler.ForceSyncApp)
-			r.Delete("/{id}", appHandler.DeleteApp)
+		r.Use(controller.VersionHeaders, limiter.ByIP)
+		r.Get("/version", appHandler.GetVersion)
+		r.Get("/openapi.yaml", appHandler.GetOpenAPISpec)
+		// The verification key is public, like the attestations it checks.
+		r.Get("/attestation/key", appHandler.GetAttestationKey)
+		r.Group(func(r chi.Router) {
+			// A state import holds every app, so it gets a limit of its own.
+			r.Use(controller.MaxBodySize(limits.MaxImportBody), auth.Authenticate, controller.RequireAdmin)
+			r.Post("/import", appHandler.ImportState)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(controller.MaxBodySize(limits.MaxBody), auth.Authenticate, limiter.ByToken)
+			readScope := controller.RequireScope(controller.ScopeRead)
+			r.Route("/apps", func(r chi.Router) {
+				// App tokens reach only their own app's read and sync endpoints.
+				r.With(readScope).Get("/{id}", appHandler.GetApp)
+				r.With(readScope).Get("/{id}/manifest", appHandler.GetAppManifest)
+				r.With(readScope).Get("/{id}/diff", appHandler.GetAppDiff)
+				r.With(readScope).Get("/{id}/revisions", appHandler.ListAppRevisions)
+				r.With(readScope).Get("/{id}/history", appHandler.ListSyncHistory)
+				r.With(readScope).Get("/{id}/history/{sync}", appHandler.GetSyncRecord)
+				r.With(readScope).Get("/{id}/healthz", appHandler.GetAppHealth)
+				r.With(readScope).Get("/{id}/syncs/{job}", appHandler.GetSyncJob)
+				r.With(controller.RequireScope(controller.ScopeSync)).Post("/{id}/sync", appHandler.ForceSyncApp)
+				r.With(controller.RequireScope(controller.ScopeSync)).Post("/{id}/approve", appHandler.ApproveApp)
+				r.With(controller.RequireScope(controller.ScopeOperate)).Post("/{id}/down", appHandler.StopApp)
+				r.With(controller.RequireScope(controller.ScopeSync)).Post("/{id}/up", appHandler.StartApp)
+				r.With(controller.RequireScope(controller.ScopeOperate)).Post("/{id}/pause", appHandler.PauseApp)
+				r.With(controller.RequireScope(controller.ScopeOperate)).Post("/{id}/resume", appHandler.ResumeApp)
+				r.With(controller.RequireScope(controller.ScopeOperate)).Post("/{id}/rollback", appHandler.RollbackApp)
+				r.With(readScope).Get("/{id}/stats", appHandler.GetAppStats)
+				r.With(readScope).Get("/{id}/containers", appHandler.ListAppContainers)
+				r.With(readScope).Get("/{id}/services/{service}/logs", appHandler.GetServiceLogs)
+				r.With(controller.RequireScope(controller.ScopeSync)).Post("/{id}/services/{service}/restart", appHandler.RestartService)
+				r.With(readScope).Get("/{id}/schedules", appHandler.ListScheduledSyncs)
+				r.With(controller.RequireScope(controller.ScopeSync)).Post("/{id}/schedules", appHandler.ScheduleSync)
+				r.With(controller.RequireScope(controller.ScopeSync)).Delete("/{id}/schedules/{scheduleID}", appHandler.CancelScheduledSync)
+
+				r.Group(func(r chi.Router) {
+					r.Use(controller.RequireAdmin)
+					r.Post("/", appHandler.RegisterApp)
+					r.Get("/", appHandler.ListApps)
+					r.Patch("/{id}", appHandler.UpdateApp)
+					r.Delete("/{id}", appHandler.DeleteApp)
+					r.Post("/{id}/tokens", appHandler.CreateAppToken)
+					r.Get("/{id}/tokens", appHandler.ListAppTokens)
+					r.Delete("/{id}/tokens/{tokenID}", appHandler.RevokeAppToken)
+				})
+			})
+			r.With(controller.RequireAdmin).Get("/audit", appHandler.ListAudit)
+			r.With(controller.RequireAdmin).Get("/previews", appHandler.ListPreviews)
+			r.With(controller.RequireAdmin).Post("/export", appHandler.ExportState)
+			r.With(controller.RequireAdmin).Post("/admin/rotate-key", appHandler.RotateKey)
+			r.With(readScope).Get("/jobs", appHandler.ListSyncJobs)
 		})
 	})
 
-	addr := ":8080"
-	logger.Info("Starting controller", "addr", addr)
-	if err := http.ListenAndServe(addr, r); err != nil {
+	// Behind a proxy forwarding a sub-path, everything is served under it;
+	// the probes stay at the root too, for health checks that reach the
+	// controller directly.
+	var handler http.Handler = r
+	if basePath != "" {
+		root := chi.NewRouter()
+		root.Mount(basePath, r)
+		root.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, basePath+"/ui/dashboard", http.StatusFound)
+		})
+		root.Get("/healthz", probes.Live)
+		root.Get("/readyz", probes.Ready)
+		handler = root
+	}
+
+	addr := listenCfg.Addr
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("Failed to listen", "addr", addr, "error", err)
+		os.Exit(1)
+	}
+	// Only the headers are timed: log streams and large imports may take
+	// long, but a client trickling in headers just holds a connection.
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	var redirectServer *http.Server
+	var redirectListener net.Listener
+	if listenCfg.TLS() {
+		tlsConfig, challenges, err := listenCfg.tlsConfig()
+		if err != nil {
+			logger.Error("Failed to configure TLS", "error", err)
+			os.Exit(1)
+		}
+		server.TLSConfig = tlsConfig
+		if listenCfg.RedirectAddr != "" {
+			redirect := redirectToHTTPS(addr)
+			if challenges != nil {
+				// ACME http-01 challenges are answered on the same port.
+				redirect = challenges(redirect)
+			}
+			redirectServer = &http.Server{Handler: redirect, ReadHeaderTimeout: 10 * time.Second}
+			redirectListener, err = net.Listen("tcp", listenCfg.RedirectAddr)
+			if err != nil {
+				logger.Error("Failed to listen", "addr", listenCfg.RedirectAddr, "error", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Tell systemd we are ready once the API is accepting connections, and
+	// keep the watchdog fed for as long as the reconciler makes progress.
+	if notified, err := systemd.Notify(systemd.StateReady); err != nil {
+		logger.Warn("Failed to notify systemd readiness", "error", err)
+	} else if notified {
+		logger.Info("Notified systemd of readiness")
+	}
+	go systemd.RunWatchdog(ctx, reconciler.Healthy)
+
+	serveErr := make(chan error, 2)
+	go func() {
+		if listenCfg.TLS() {
+			// The certificates come from TLSConfig.GetCertificate.
+			serveErr <- server.ServeTLS(listener, "", "")
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+	if redirectServer != nil {
+		go func() {
+			serveErr <- redirectServer.Serve(redirectListener)
+		}()
+		logger.Info("Redirecting plain HTTP to HTTPS", "addr", listenCfg.RedirectAddr)
+	}
+	logger.Info("Starting controller", "addr", addr, "tls", listenCfg.TLSSource(), "base_path", basePath, "version", version.Version)
+
+	select {
+	case err := <-serveErr:
 		logger.Error("Server failed", "error", err)
 		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// Stop accepting requests, then let an in-flight reconcile pass and
+	// running syncs finish so a restart never interrupts a compose apply
+	// halfway.
+	logger.Info("Shutting down controller")
+	_, _ = systemd.Notify(systemd.StateStopping)
+	shutdownCtx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stop()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("HTTP server did not shut down cleanly", "error", err)
+	}
+	if redirectServer != nil {
+		_ = redirectServer.Shutdown(shutdownCtx)
+	}
+	select {
+	case <-electionDone:
+	case <-time.After(2*reconcilerCfg.SyncTimeout + 30*time.Second):
+		logger.Warn("Timed out waiting for the reconciler to stop")
 	}
 }
+
+func resolveDataDir() string {
+	dataDir := "/data"
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		// Fallback for local development if /data doesn't exist
+		dataDir = "."
+	}
+	return dataDir
+}
+
+// openStore opens and migrates the configured database. kind is "postgres"
+// or "sqlite".
+func openStore(dataDir string) (dbStore store.Store, kind string, err error) {
+	dbType := os.Getenv("DB_TYPE")
+	if dbType == "postgres" {
+		connString := os.Getenv("DB_CONNECTION_STRING")
+		if connString == "" {
+			return nil, "postgres", fmt.Errorf("DB_CONNECTION_STRING is required for postgres")
+		}
+		dbStore, err = store.NewPostgresStore(context.Background(), connString)
+		return dbStore, "postgres", err
+	}
+
+	// Default to SQLite
+	dbStore, err = store.NewSQLiteStore(filepath.Join(dataDir, "conops.db"))
+	return dbStore, "sqlite", err
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/store"
+	"github.com/conops/conops/internal/version"
+)
+
+const (
+	defaultReleaseURL = "https://github.com/anuragxxd/conops/releases"
+	checksumsAsset    = "checksums.txt"
+	maxReleaseSize    = 256 << 20
+)
+
+var releaseClient = &http.Client{Timeout: 5 * time.Minute}
+
+// runMigrate implements `conops migrate`, which applies pending schema
+// migrations in a single transaction and exits.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dbStore, _, err := openStore(resolveDataDir())
+	if err != nil {
+		return err
+	}
+	defer dbStore.Close()
+
+	current, err := dbStore.SchemaVersion(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database schema is at version %d\n", current)
+	return nil
+}
+
+// runSchemaVersion prints the schema version this binary understands.
+// `conops upgrade` asks a downloaded release for it before installing.
+func runSchemaVersion([]string) error {
+	fmt.Println(store.SchemaVersion)
+	return nil
+}
+
+// runUpgrade implements `conops upgrade`, which downloads a release,
+// verifies it against the release checksums, migrates the database with the
+// new binary and swaps it into place.
+func runUpgrade(args []string) error {
+	fs := flag.NewFlagSet("upgrade", flag.ContinueOnError)
+	target := fs.String("version", "latest", "Release tag to install, e.g. v1.4.0")
+	releaseURL := fs.String("release-url", defaultReleaseURL, "Base URL for release downloads")
+	binary := fs.String("binary", "", "Binary to replace (default: this executable)")
+	workDir := fs.String("workdir", "", "Working directory of the controller (default: current directory)")
+	restartUnit := fs.String("restart-unit", "", "systemd unit to restart once the release is installed")
+	dryRun := fs.Bool("dry-run", false, "Download and verify the release without installing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *binary == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to resolve executable path: %w", err)
+		}
+		*binary = executable
+	}
+	if *workDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to resolve working directory: %w", err)
+		}
+		*workDir = cwd
+	}
+	for _, path := range []*string{binary, workDir} {
+		absolute, err := filepath.Abs(*path)
+		if err != nil {
+			return err
+		}
+		*path = absolute
+	}
+
+	// Stage next to the install targets so the final renames are atomic.
+	binStage, err := os.MkdirTemp(filepath.Dir(*binary), ".conops-upgrade-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(binStage)
+	webStage, err := os.MkdirTemp(*workDir, ".conops-upgrade-web-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(webStage)
+
+	asset := releaseAssetName(runtime.GOOS, runtime.GOARCH)
+	base := releaseDownloadBase(*releaseURL, *target)
+	fmt.Printf("Current version: %s\nDownloading %s (%s)...\n", version.Version, asset, *target)
+
+	checksums, err := fetchChecksums(base + "/" + checksumsAsset)
+	if err != nil {
+		return err
+	}
+	want, ok := checksums[asset]
+	if !ok {
+		return fmt.Errorf("release has no checksum for %s", asset)
+	}
+	archivePath := filepath.Join(binStage, asset)
+	got, err := downloadFile(base+"/"+asset, archivePath)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset, want, got)
+	}
+	fmt.Println("Checksum verified")
+
+	newBinary := filepath.Join(binStage, "conops")
+	hasWeb, err := extractRelease(archivePath, newBinary, webStage)
+	if err != nil {
+		return err
+	}
+
+	supported, err := releaseSchemaVersion(newBinary)
+	if err != nil {
+		return err
+	}
+	dbStore, _, err := openStore(resolveDataDir())
+	if err != nil {
+		return err
+	}
+	current, err := dbStore.SchemaVersion(context.Background())
+	dbStore.Close()
+	if err != nil {
+		return err
+	}
+	if current > supported {
+		return fmt.Errorf("database schema version %d is newer than %s supports (%d); refusing to install it", current, *target, supported)
+	}
+	fmt.Printf("Database schema version %d, release supports %d\n", current, supported)
+
+	if *dryRun {
+		fmt.Println("Dry run: release verified, nothing installed")
+		return nil
+	}
+
+	// Migrations run in one transaction, so a failure here leaves both the
+	// database and the installed binary as they were.
+	migrate := exec.Command(newBinary, "migrate")
+	migrate.Stdout = os.Stdout
+	migrate.Stderr = os.Stderr
+	if err := migrate.Run(); err != nil {
+		return fmt.Errorf("migration failed, nothing was installed: %w", err)
+	}
+
+	if err := swapInto(newBinary, *binary); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s to %s (previous binary kept as %s.previous)\n", *target, *binary, *binary)
+	if hasWeb {
+		if err := swapInto(filepath.Join(webStage, "web"), filepath.Join(*workDir, "web")); err != nil {
+			return err
+		}
+		fmt.Printf("Updated %s\n", filepath.Join(*workDir, "web"))
+	}
+
+	if *restartUnit == "" {
+		fmt.Println("Restart the controller to finish the upgrade, e.g. systemctl restart conops")
+		return nil
+	}
+	restart := exec.Command("systemctl", "restart", *restartUnit)
+	restart.Stdout = os.Stdout
+	restart.Stderr = os.Stderr
+	if err := restart.Run(); err != nil {
+		return fmt.Errorf("failed to restart %s: %w", *restartUnit, err)
+	}
+	fmt.Printf("Restarted %s\n", *restartUnit)
+	return nil
+}
+
+// releaseAssetName matches the controller archive names in .goreleaser.yaml.
+func releaseAssetName(goos, goarch string) string {
+	arch := goarch
+	if goarch == "amd64" {
+		arch = "x86_64"
+	}
+	return fmt.Sprintf("conops_%s%s_%s.tar.gz", strings.ToUpper(goos[:1]), goos[1:], arch)
+}
+
+func releaseDownloadBase(releaseURL, tag string) string {
+	releaseURL = strings.TrimRight(releaseURL, "/")
+	if tag == "" || tag == "latest" {
+		return releaseURL + "/latest/download"
+	}
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+	return releaseURL + "/download/" + tag
+}
+
+func openRelease(url string) (io.ReadCloser, error) {
+	resp, err := releaseClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: HTTP %d", url, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// fetchChecksums parses a sha256sum-style checksums file into asset → hash.
+func fetchChecksums(url string) (map[string]string, error) {
+	body, err := openRelease(url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(io.LimitReader(body, 1<<20))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return checksums, nil
+}
+
+// downloadFile saves url to path and returns the hex sha256 of its content.
+func downloadFile(url, path string) (string, error) {
+	body, err := openRelease(url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(body, maxReleaseSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if written > maxReleaseSize {
+		return "", fmt.Errorf("release archive exceeds %d bytes", maxReleaseSize)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extractRelease writes the conops binary to binaryPath and the web/ tree
+// under webDir. It reports whether the archive contained web assets.
+func extractRelease(archivePath, binaryPath, webDir string) (bool, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return false, fmt.Errorf("invalid release archive: %w", err)
+	}
+	defer gz.Close()
+
+	foundBinary, foundWeb := false, false
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("invalid release archive: %w", err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return false, fmt.Errorf("invalid path in release archive: %s", header.Name)
+		}
+
+		var dest string
+		mode := os.FileMode(0o644)
+		switch {
+		case name == "conops":
+			dest, mode, foundBinary = binaryPath, 0o755, true
+		case name == "web" || strings.HasPrefix(name, "web"+string(filepath.Separator)):
+			dest, foundWeb = filepath.Join(webDir, name), true
+		default:
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				return false, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return false, err
+			}
+			out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return false, err
+			}
+			_, err = io.Copy(out, reader)
+			out.Close()
+			if err != nil {
+				return false, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		}
+	}
+	if !foundBinary {
+		return false, fmt.Errorf("release archive does not contain the conops binary")
+	}
+	return foundWeb, nil
+}
+
+func releaseSchemaVersion(binary string) (int, error) {
+	output, err := exec.Command(binary, "schema-version").Output()
+	if err != nil {
+		return 0, fmt.Errorf("release binary cannot report its schema version (it may predate versioned migrations): %w", err)
+	}
+	supported, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, fmt.Errorf("release binary reported an invalid schema version %q", strings.TrimSpace(string(output)))
+	}
+	return supported, nil
+}
+
+// swapInto replaces dest with src, keeping the old copy at dest.previous.
+func swapInto(src, dest string) error {
+	previous := dest + ".previous"
+	if err := os.RemoveAll(previous); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", previous, err)
+	}
+	if err := os.Rename(dest, previous); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to back up %s: %w", dest, err)
+	}
+	if err := os.Rename(src, dest); err != nil {
+		// Put the previous copy back so the install is never left empty.
+		_ = os.Rename(previous, dest)
+		return fmt.Errorf("failed to install %s: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// OpenAPISpec is the OpenAPI 3 document describing the controller API.
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
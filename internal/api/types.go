@@ -5,21 +5,391 @@ import "time"
 // App represents a Git repository configuration to track.
 // Moved from controller/registry.go to avoid circular dependency with store package.
 type App struct {
-	ID                      string    `json:"id"`
-	Name                    string    `json:"name"`
-	RepoURL                 string    `json:"repo_url"`
-	RepoAuthMethod          string    `json:"repo_auth_method"`
-	Branch                  string    `json:"branch"`
-	ComposePath             string    `json:"compose_path"`
-	PollInterval            string    `json:"poll_interval"` // Duration string e.g. "30s"
-	LastSeenCommit          string    `json:"last_seen_commit"`
-	LastSeenCommitMessage   string    `json:"last_seen_commit_message"`
-	LastSyncedCommit        string    `json:"last_synced_commit"`
-	LastSyncedCommitMessage string    `json:"last_synced_commit_message"`
-	LastSyncOutput          string    `json:"last_sync_output"`
-	LastSyncError           string    `json:"last_sync_error"`
-	LastSyncAt              time.Time `json:"last_sync_at"`
-	Status                  string    `json:"status"` // e.g., "active", "error"
+	ID                    string `json:"id"`
+	Name                  string `json:"name"`
+	RepoURL               string `json:"repo_url"`
+	RepoAuthMethod        string `json:"repo_auth_method"`
+	Branch                string `json:"branch"`
+	ComposePath           string `json:"compose_path"`
+	PollInterval          string `json:"poll_interval"` // Duration string e.g. "30s"
+	LastSeenCommit        string `json:"last_seen_commit"`
+	LastSeenCommitMessage string `json:"last_seen_commit_message"`
+	// LastSeenCommitInfo describes the desired commit and what it changes
+	// relative to the synced commit. Nil until the git watcher has read it.
+	LastSeenCommitInfo      *CommitInfo `json:"last_seen_commit_info,omitempty"`
+	LastSyncedCommit        string      `json:"last_synced_commit"`
+	LastSyncedCommitMessage string      `json:"last_synced_commit_message"`
+	LastSyncOutput          string      `json:"last_sync_output"`
+	LastSyncError           string      `json:"last_sync_error"`
+	LastSyncAt              time.Time   `json:"last_sync_at"`
+	Status                  string      `json:"status"` // e.g., "active", "error"
+	// GateScript is an optional Starlark script defining promote/health gates.
+	GateScript string `json:"gate_script,omitempty"`
+	// NotifyWebhooks receive sync and drift events for this app.
+	NotifyWebhooks []string `json:"notify_webhooks,omitempty"`
+	// SlackWebhookURL and SlackChannel route failure and recovery messages
+	// to Slack. A channel requires CONOPS_SLACK_BOT_TOKEN.
+	SlackWebhookURL string `json:"slack_webhook_url,omitempty"`
+	SlackChannel    string `json:"slack_channel,omitempty"`
+	// NotifyEvents limits the app's own targets to these event types; empty
+	// means every event. Global targets are unaffected.
+	NotifyEvents []string `json:"notify_events,omitempty"`
+	// QuietHours is a daily window such as "22:00-07:00 Europe/Berlin" in
+	// which the app's own targets only receive sync.failed events.
+	QuietHours string `json:"quiet_hours,omitempty"`
+	// SigningKeys, when set, restricts deploys to commits signed by one of
+	// these GPG or SSH public keys.
+	SigningKeys []string `json:"signing_keys,omitempty"`
+	// Track, when set, is a semver constraint such as "v1.2.x"; the app then
+	// follows the highest matching tag instead of the branch head.
+	Track string `json:"track,omitempty"`
+	// WatchPaths are repo-relative paths or globs; a new commit only marks
+	// the app pending when it touches one of them. Empty means the compose
+	// file's directory.
+	WatchPaths []string `json:"watch_paths,omitempty"`
+	// Profiles are the compose profiles enabled for pull and up. Empty
+	// falls back to COMPOSE_PROFILES from the controller environment.
+	Profiles []string `json:"profiles,omitempty"`
+	// EnvFile is a repo-relative .env file passed to compose with
+	// --env-file for variable interpolation.
+	EnvFile string `json:"env_file,omitempty"`
+	// PreviewTTL marks the app as a preview environment, e.g. for a pull
+	// request. Once it has sat synced or failed for this long without a new
+	// sync, its stack, volumes and images are removed and the app deleted.
+	PreviewTTL string `json:"preview_ttl,omitempty"`
+	// PostDeploy are commands run inside a service container after every
+	// successful up, written "service: command", e.g. "web: ./migrate".
+	// A failing command fails the sync.
+	PostDeploy []string `json:"post_deploy,omitempty"`
+	// PreDeploy are checks run after checkout and pull but before up: an
+	// http(s) URL that must answer 2xx, or "service: command" run in a
+	// one-off container. A failing check aborts the sync and leaves the
+	// running stack untouched.
+	PreDeploy []string `json:"pre_deploy,omitempty"`
+	// Priority is "critical", "high" or "low"; empty means normal. When
+	// several apps need a sync at once, higher priorities go first.
+	Priority string `json:"priority,omitempty"`
+	// RequireApproval holds each new commit in "awaiting_approval" until
+	// someone approves it; only then does the reconciler deploy it.
+	RequireApproval bool `json:"require_approval,omitempty"`
+	// PinImages records the image digests each sync ran and, when the same
+	// commit is applied again, deploys those digests instead of the tags.
+	PinImages bool `json:"pin_images,omitempty"`
+	// FreezeWindows are recurring windows such as "fri 18:00-08:00
+	// Europe/Berlin" during which the reconciler records new commits as
+	// pending but does not deploy them.
+	FreezeWindows []string `json:"freeze_windows,omitempty"`
+	// DockerHost is the daemon the app's stack runs on: a DOCKER_HOST URL
+	// such as "ssh://deploy@web-1" or "tcp://10.0.0.5:2376", or the name of
+	// a docker context on the controller host. Empty is the local daemon.
+	// TLS client certificates for a tcp:// host are kept encrypted with the
+	// app's credentials.
+	DockerHost string `json:"docker_host,omitempty"`
+	// SyncFailures counts the app's failed syncs since its last successful
+	// or manual one.
+	SyncFailures int `json:"sync_failures,omitempty"`
+	// Paused holds automatic syncs: the reconciler neither deploys new
+	// commits nor repairs drift until the app is resumed. Manual syncs and
+	// rollbacks still run.
+	Paused bool `json:"paused,omitempty"`
+	// NextRetryAt is when the reconciler retries the app in error next;
+	// nil when it does not retry errors.
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+}
+
+// CommitInfo is the metadata of a commit and a summary of the files it
+// changes relative to Base.
+type CommitInfo struct {
+	Author       string        `json:"author"`    // "Name <email>"
+	Committer    string        `json:"committer"` // "Name <email>"
+	AuthoredAt   time.Time     `json:"authored_at"`
+	CommittedAt  time.Time     `json:"committed_at"`
+	Message      string        `json:"message"`
+	Base         string        `json:"base,omitempty"` // empty for a root commit
+	FilesChanged int           `json:"files_changed"`
+	Additions    int           `json:"additions"`
+	Deletions    int           `json:"deletions"`
+	Files        []ChangedFile `json:"files,omitempty"` // capped; FilesChanged is the full count
+}
+
+// ChangedFile is one file in a commit's change summary.
+type ChangedFile struct {
+	Path      string `json:"path"`
+	Change    string `json:"change"`         // "added", "modified", "deleted" or "renamed"
+	From      string `json:"from,omitempty"` // previous path of a renamed file
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// AuditEntry records who performed a mutating API call, what it changed and when.
+type AuditEntry struct {
+	ID        string                 `json:"id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Actor     string                 `json:"actor"`
+	Source    string                 `json:"source"`
+	Action    string                 `json:"action"` // e.g. "app.create", "app.sync"
+	Method    string                 `json:"method"`
+	Path      string                 `json:"path"`
+	AppID     string                 `json:"app_id,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Outcome   string                 `json:"outcome"` // "success" or "error"
+}
+
+// AppSpec is the user-editable configuration of an app as captured by its
+// revision history. Credentials and environment values are never included.
+type AppSpec struct {
+	Name            string   `json:"name"`
+	RepoURL         string   `json:"repo_url"`
+	RepoAuthMethod  string   `json:"repo_auth_method"`
+	Branch          string   `json:"branch"`
+	ComposePath     string   `json:"compose_path"`
+	PollInterval    string   `json:"poll_interval"`
+	GateScript      string   `json:"gate_script,omitempty"`
+	NotifyWebhooks  []string `json:"notify_webhooks,omitempty"`
+	SlackWebhookURL string   `json:"slack_webhook_url,omitempty"` // "(redacted)" when set
+	SlackChannel    string   `json:"slack_channel,omitempty"`
+	NotifyEvents    []string `json:"notify_events,omitempty"`
+	QuietHours      string   `json:"quiet_hours,omitempty"`
+	SigningKeys     []string `json:"signing_keys,omitempty"`
+	Track           string   `json:"track,omitempty"`
+	WatchPaths      []string `json:"watch_paths,omitempty"`
+	Profiles        []string `json:"profiles,omitempty"`
+	EnvFile         string   `json:"env_file,omitempty"`
+	PreviewTTL      string   `json:"preview_ttl,omitempty"`
+	PostDeploy      []string `json:"post_deploy,omitempty"`
+	PreDeploy       []string `json:"pre_deploy,omitempty"`
+	Priority        string   `json:"priority,omitempty"`
+	RequireApproval bool     `json:"require_approval,omitempty"`
+	PinImages       bool     `json:"pin_images,omitempty"`
+	FreezeWindows   []string `json:"freeze_windows,omitempty"`
+	DockerHost      string   `json:"docker_host,omitempty"`
+}
+
+// PreviewStatus reports when a preview app will be removed.
+type PreviewStatus struct {
+	AppID      string    `json:"app_id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	TTL        string    `json:"ttl"`
+	LastSyncAt time.Time `json:"last_sync_at"`
+	// ExpiresAt is nil while the preview is pending, syncing or was never
+	// synced.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AppRevision is one version of an app's configuration: who changed it,
+// when, what changed and the resulting spec.
+type AppRevision struct {
+	AppID     string                 `json:"app_id"`
+	Revision  int                    `json:"revision"`
+	CreatedAt time.Time              `json:"created_at"`
+	Actor     string                 `json:"actor"`
+	Source    string                 `json:"source"`
+	Action    string                 `json:"action"`
+	Spec      AppSpec                `json:"spec"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange describes the old and new value of one changed field.
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// AppManifest is the effective configuration conops applies for an app.
+// Environment values are never included, only variable names.
+type AppManifest struct {
+	AppID        string              `json:"app_id"`
+	ProjectName  string              `json:"project_name"`
+	RepoURL      string              `json:"repo_url"`
+	Branch       string              `json:"branch"`
+	Track        string              `json:"track,omitempty"`
+	WatchPaths   []string            `json:"watch_paths"`   // effective, including the default
+	TargetCommit string              `json:"target_commit"` // empty means latest on branch
+	SyncedCommit string              `json:"synced_commit,omitempty"`
+	WorkingDir   string              `json:"working_dir"`
+	ComposeFiles []string            `json:"compose_files"`
+	EnvFile      string              `json:"env_file,omitempty"`
+	Profiles     []string            `json:"profiles"`
+	EnvVars      map[string][]string `json:"env_vars"`             // service name -> variable names
+	PreDeploy    [][]string          `json:"pre_deploy,omitempty"` // checks run before Command
+	Command      []string            `json:"command"`
+	PostDeploy   [][]string          `json:"post_deploy,omitempty"` // commands run after Command
+	Gates        []string            `json:"gates,omitempty"`
+}
+
+// AppDiff is the change in rendered compose configuration between the
+// commit an app runs and the one the next sync deploys.
+type AppDiff struct {
+	AppID        string `json:"app_id"`
+	SyncedCommit string `json:"synced_commit,omitempty"` // empty before the first sync
+	TargetCommit string `json:"target_commit"`
+	Changed      bool   `json:"changed"`
+	Diff         string `json:"diff"` // unified diff of docker compose config output
+}
+
+// AppHealth is the compact status of an app served to external monitors.
+type AppHealth struct {
+	AppID string `json:"app_id"`
+	// State is "synced", "drifted", "error" or "stopped".
+	State string `json:"state"`
+	// Reason explains a drifted or errored state, e.g. "commit_pending",
+	// "runtime_restarting" or "runtime_exited".
+	Reason  string `json:"reason,omitempty"`
+	Status  string `json:"status"` // the app's status
+	Commit  string `json:"commit,omitempty"`
+	Running int    `json:"running"`
+	Total   int    `json:"total"`
+	// LastSuccessAt is when the app last synced successfully, and
+	// LastSuccessAgeSeconds how long ago that was.
+	LastSuccessAt         *time.Time `json:"last_success_at,omitempty"`
+	LastSuccessAgeSeconds *int64     `json:"last_success_age_seconds,omitempty"`
+}
+
+// AppStats is the latest resource usage snapshot of an app's containers.
+type AppStats struct {
+	AppID string `json:"app_id"`
+	// CollectedAt is zero until the first snapshot was taken.
+	CollectedAt time.Time      `json:"collected_at"`
+	Services    []ServiceStats `json:"services"`
+}
+
+// ServiceStats is the resource usage of one container of an app.
+type ServiceStats struct {
+	Service          string  `json:"service"`
+	Container        string  `json:"container"`
+	Running          bool    `json:"running"`
+	CPUPercent       float64 `json:"cpu_percent"`
+	MemoryBytes      uint64  `json:"memory_bytes"`
+	MemoryLimitBytes uint64  `json:"memory_limit_bytes"`
+	MemoryPercent    float64 `json:"memory_percent"`
+	RestartCount     int     `json:"restart_count"`
+}
+
+// ServiceContainer is one container of an app's compose project.
+type ServiceContainer struct {
+	Service string `json:"service"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	// Status is docker's state: "running", "restarting", "paused",
+	// "created", "exited" or "dead".
+	Status       string `json:"status"`
+	Health       string `json:"health,omitempty"`    // "healthy", "unhealthy" or "starting"
+	ExitCode     int    `json:"exit_code,omitempty"` // of an exited or dead container
+	RestartCount int    `json:"restart_count"`
+	Ports        string `json:"ports,omitempty"`
+}
+
+// SyncJob is a sync that is running or waiting for the app's current sync to
+// finish. Repeated requests while a job is queued are coalesced into it.
+// Finished jobs are kept for an hour with their outcome.
+type SyncJob struct {
+	ID         string     `json:"id"`
+	AppID      string     `json:"app_id"`
+	State      string     `json:"state"`   // "queued", "running", "succeeded" or "failed"
+	Trigger    string     `json:"trigger"` // "reconcile", "manual" or "scheduled"
+	Actor      string     `json:"actor,omitempty"`
+	Requests   int        `json:"requests"` // requests coalesced into this job
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ScheduledSync is a deploy queued to run at a later time. The scheduler
+// removes it once the sync starts, so only upcoming deploys are listed.
+type ScheduledSync struct {
+	ID        string    `json:"id"`
+	AppID     string    `json:"app_id"`
+	RunAt     time.Time `json:"run_at"`
+	Commit    string    `json:"commit,omitempty"` // empty means latest on branch at run time
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SyncRecord is one entry in an app's sync history.
+type SyncRecord struct {
+	ID         string    `json:"id"`
+	AppID      string    `json:"app_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Trigger    string    `json:"trigger"` // "reconcile", "manual", "scheduled" or "rollback"
+	Actor      string    `json:"actor,omitempty"`
+	Commit     string    `json:"commit,omitempty"` // empty means latest on branch
+	Status     string    `json:"status"`           // "synced" or "error"
+	Error      string    `json:"error,omitempty"`
+	// RollbackOf is the ID of the failed sync a rollback recovered from.
+	RollbackOf string `json:"rollback_of,omitempty"`
+	// Images maps each service to the image digest it ran, recorded for
+	// apps that pin images and when attestations are on.
+	Images map[string]string `json:"images,omitempty"`
+	// Attestation is the signed provenance of a successful sync when the
+	// controller has attestations turned on.
+	Attestation *Attestation `json:"attestation,omitempty"`
+	// Output is the masked transcript of the sync. It is stored with the
+	// record but not listed with it.
+	Output string `json:"-"`
+	// Steps splits Output into the steps of the sync. Like Output, they are
+	// only returned for a single sync.
+	Steps []SyncStep `json:"steps,omitempty"`
+}
+
+// SyncStep is one step of a sync, e.g. "git" or "compose-up", with the part
+// of the transcript it wrote.
+type SyncStep struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"` // "succeeded" or "failed"
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Log        string    `json:"log,omitempty"`
+}
+
+// Attestation is a DSSE envelope holding a signed in-toto statement. The
+// payload is the base64 of the statement and each signature covers its
+// DSSE pre-authentication encoding.
+type Attestation struct {
+	PayloadType string                 `json:"payloadType"`
+	Payload     string                 `json:"payload"`
+	Signatures  []AttestationSignature `json:"signatures"`
+}
+
+// AttestationSignature is one signature of an Attestation.
+type AttestationSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// AppToken is an API token limited to one app and a set of scopes. Only a
+// hash is stored; Token holds the secret in the response that creates it.
+type AppToken struct {
+	ID         string     `json:"id"`
+	AppID      string     `json:"app_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"` // "read" and/or "sync"
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// VersionInfo describes the controller build and the clients it supports.
+type VersionInfo struct {
+	Version          string `json:"version"`
+	Channel          string `json:"channel"` // "stable", "prerelease" or "dev"
+	APIVersion       int    `json:"api_version"`
+	MinClientVersion string `json:"min_client_version"`
+	SchemaVersion    int    `json:"schema_version"`
+	Commit           string `json:"commit,omitempty"`
+	BuildDate        string `json:"build_date,omitempty"`
+	GoVersion        string `json:"go_version"`
+}
+
+// ProbeReport is the body of the controller's /healthz and /readyz
+// probes. Checks maps each check to "ok" or the reason it failed.
+type ProbeReport struct {
+	Status string            `json:"status"` // "ok" or "unavailable"
+	Checks map[string]string `json:"checks,omitempty"`
+	// Role is "leader" or "standby" when controllers share a store.
+	Role string `json:"role,omitempty"`
 }
 
 // APIResponse is a standard wrapper for API responses.
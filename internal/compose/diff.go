@@ -0,0 +1,230 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines surround each hunk.
+const diffContextLines = 3
+
+// maxDiffCells bounds the line comparison table; larger inputs are shown
+// as a full replacement.
+const maxDiffCells = 16 << 20
+
+// RenderConfig checks out commitHash in a scratch copy of the app's
+// repository and returns the output of docker compose config for it, with
+// the app's profiles and env file. The running stack and the checkout used
+// by Apply are left alone. Stored service environments are not applied, so
+// no secret values end up in the output.
+func (e *ComposeExecutor) RenderConfig(
+	ctx context.Context,
+	appID, repoURL, branch, composePath string,
+	profiles []string,
+	envFile, commitHash string,
+	deployKey []byte,
+) (string, error) {
+	if strings.TrimSpace(repoURL) == "" {
+		return "", fmt.Errorf("repo url is empty")
+	}
+	if strings.TrimSpace(composePath) == "" {
+		return "", fmt.Errorf("compose path is empty")
+	}
+	if strings.TrimSpace(commitHash) == "" {
+		return "", fmt.Errorf("commit is required")
+	}
+
+	// Renders share one scratch checkout per app.
+	e.renderMu.Lock()
+	defer e.renderMu.Unlock()
+
+	scratchDir, err := filepath.Abs(filepath.Join(e.WorkDir, appID, "diff"))
+	if err != nil {
+		return "", fmt.Errorf("resolve diff dir failed: %w", err)
+	}
+	if err := os.MkdirAll(scratchDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create diff dir: %w", err)
+	}
+	repoDir := filepath.Join(scratchDir, "repo")
+	if repoLog, err := e.prepareRepo(ctx, scratchDir, repoDir, repoURL, branch, commitHash, deployKey); err != nil {
+		return "", fmt.Errorf("checkout of %s failed: %w: %s", commitHash, err, truncateOutput(repoLog))
+	}
+
+	composeFullPath := filepath.Join(repoDir, composePath)
+	if _, err := os.Stat(composeFullPath); err != nil {
+		return "", fmt.Errorf("compose file not found at %s: %s", commitHash, composePath)
+	}
+	var overrideArgs []string
+	if envFile != "" {
+		envFileFullPath := filepath.Join(repoDir, envFile)
+		if fileInfo, err := os.Stat(envFileFullPath); err != nil || fileInfo.IsDir() {
+			return "", fmt.Errorf("env file not found at %s: %s", commitHash, envFile)
+		}
+		overrideArgs = append(overrideArgs, "--env-file", envFileFullPath)
+	}
+	overrideArgs = append(overrideArgs, profileArgs(profiles)...)
+
+	args := composeArgs(composeProjectName(appID), filepath.Base(composeFullPath), overrideArgs, "config")
+	output, err := e.runCommand(ctx, "docker", args, filepath.Dir(composeFullPath), nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid compose file at %s: %s", commitHash, truncateOutput(strings.TrimSpace(output)))
+	}
+	return output, nil
+}
+
+// ConfigDiff returns a unified diff from one rendered configuration to
+// another, or an empty string when they are equal. fromName and toName
+// label the two sides.
+func ConfigDiff(fromName, from, toName, to string) string {
+	if from == to {
+		return ""
+	}
+	a := splitLines(from)
+	b := splitLines(to)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the run of changes it belongs to; runs
+		// separated by fewer than two contexts' worth of equal lines merge.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			} else if i-last > 2*diffContextLines {
+				break
+			}
+		}
+		lo := max(first-diffContextLines, start)
+		hi := min(last+diffContextLines+1, len(ops))
+
+		aStart, bStart := ops[lo].aLine, ops[lo].bLine
+		var aCount, bCount int
+		for _, op := range ops[lo:hi] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[lo:hi] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		start = hi
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind  byte // ' ', '-' or '+'
+	text  string
+	aLine int // 1-based line in from the op is at or before
+	bLine int // 1-based line in to the op is at or before
+}
+
+func splitLines(value string) []string {
+	value = strings.TrimSuffix(value, "\n")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffLines aligns a and b on their longest common subsequence of lines.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	equal := func(text string) {
+		ops = append(ops, diffOp{kind: ' ', text: text, aLine: i + 1, bLine: j + 1})
+		i++
+		j++
+	}
+	removed := func(text string) {
+		ops = append(ops, diffOp{kind: '-', text: text, aLine: i + 1, bLine: j + 1})
+		i++
+	}
+	added := func(text string) {
+		ops = append(ops, diffOp{kind: '+', text: text, aLine: i + 1, bLine: j + 1})
+		j++
+	}
+
+	for _, line := range a[:prefix] {
+		equal(line)
+	}
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		for _, line := range midA {
+			removed(line)
+		}
+		for _, line := range midB {
+			added(line)
+		}
+	} else {
+		// lcs[x][y] is the common subsequence length of midA[x:] and midB[y:].
+		cols := len(midB) + 1
+		lcs := make([]int32, (len(midA)+1)*cols)
+		for x := len(midA) - 1; x >= 0; x-- {
+			for y := len(midB) - 1; y >= 0; y-- {
+				if midA[x] == midB[y] {
+					lcs[x*cols+y] = lcs[(x+1)*cols+y+1] + 1
+				} else {
+					lcs[x*cols+y] = max(lcs[(x+1)*cols+y], lcs[x*cols+y+1])
+				}
+			}
+		}
+		x, y := 0, 0
+		for x < len(midA) || y < len(midB) {
+			switch {
+			case x < len(midA) && y < len(midB) && midA[x] == midB[y]:
+				equal(midA[x])
+				x++
+				y++
+			case x < len(midA) && (y == len(midB) || lcs[(x+1)*cols+y] >= lcs[x*cols+y+1]):
+				removed(midA[x])
+				x++
+			default:
+				added(midB[y])
+				y++
+			}
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		equal(line)
+	}
+	return ops
+}
@@ -94,6 +94,13 @@ type composeAssetEntry struct {
 	BrowserDownloadURL string `json:"browser_download_url"`
 }
 
+// Preflight checks that a docker CLI with compose can reach the local
+// daemon. The result is cached like the one syncs use.
+func (e *ComposeExecutor) Preflight(ctx context.Context) error {
+	_, err := e.ensureDockerPreflight(ctx)
+	return err
+}
+
 func (e *ComposeExecutor) ensureDockerPreflight(ctx context.Context) (dockerPreflightReport, error) {
 	resolution, err := e.resolveDockerCommand(ctx)
 	if err != nil {
@@ -0,0 +1,161 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DockerEndpoint is the docker daemon an app's stack runs on. The zero
+// value is the controller's own daemon.
+type DockerEndpoint struct {
+	// Host is a DOCKER_HOST URL (ssh://, tcp:// or unix://) or the name of
+	// a docker context configured on the controller host.
+	Host string
+	// TLS holds client certificates for a tcp:// host that verifies them.
+	TLS *DockerTLS
+}
+
+// DockerTLS is the PEM material of a TLS-protected docker daemon.
+type DockerTLS struct {
+	CACert     string `json:"ca_cert"`
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"`
+}
+
+// IsLocal reports whether e is the controller's own daemon.
+func (e DockerEndpoint) IsLocal() bool {
+	return strings.TrimSpace(e.Host) == ""
+}
+
+// IsContext reports whether Host names a docker context rather than a URL.
+func (e DockerEndpoint) IsContext() bool {
+	return !e.IsLocal() && !strings.Contains(e.Host, "://")
+}
+
+func (e DockerEndpoint) String() string {
+	if e.IsLocal() {
+		return "local"
+	}
+	return e.Host
+}
+
+// EndpointResolver tells the executor which daemon each app runs on.
+type EndpointResolver interface {
+	// DockerEndpoint returns the endpoint of the app with the given ID or
+	// compose project name.
+	DockerEndpoint(appID string) (DockerEndpoint, error)
+	// DockerEndpoints returns the distinct remote endpoints apps use.
+	DockerEndpoints() ([]DockerEndpoint, error)
+}
+
+var dockerContextName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.+-]*$`)
+
+// ValidateDockerHost checks a docker host setting: empty for the local
+// daemon, an ssh://, tcp:// or unix:// URL, or a docker context name.
+func ValidateDockerHost(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if !strings.Contains(value, "://") {
+		if !dockerContextName.MatchString(value) {
+			return fmt.Errorf("invalid docker host: %s is neither a URL nor a docker context name", value)
+		}
+		return nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid docker host: %s", value)
+	}
+	switch parsed.Scheme {
+	case "ssh", "tcp":
+		if parsed.Host == "" {
+			return fmt.Errorf("invalid docker host: %s has no host", value)
+		}
+	case "unix":
+		if parsed.Path == "" {
+			return fmt.Errorf("invalid docker host: %s has no socket path", value)
+		}
+	default:
+		return fmt.Errorf("invalid docker host: scheme %s is not ssh, tcp or unix", parsed.Scheme)
+	}
+	return nil
+}
+
+// ValidateDockerTLS checks that TLS material is complete and PEM encoded.
+func ValidateDockerTLS(tls *DockerTLS) error {
+	if tls == nil {
+		return nil
+	}
+	for name, value := range map[string]string{"ca_cert": tls.CACert, "client_cert": tls.ClientCert, "client_key": tls.ClientKey} {
+		if !strings.Contains(value, "-----BEGIN ") {
+			return fmt.Errorf("invalid docker tls: %s is not PEM encoded", name)
+		}
+	}
+	return nil
+}
+
+type dockerEndpointKey struct{}
+
+// WithDockerEndpoint makes the docker commands run with ctx talk to
+// endpoint instead of the daemon of the app they act on.
+func WithDockerEndpoint(ctx context.Context, endpoint DockerEndpoint) context.Context {
+	return context.WithValue(ctx, dockerEndpointKey{}, endpoint)
+}
+
+func dockerEndpointFromContext(ctx context.Context) (DockerEndpoint, bool) {
+	endpoint, ok := ctx.Value(dockerEndpointKey{}).(DockerEndpoint)
+	return endpoint, ok
+}
+
+// appContext pins the docker commands run with ctx to the daemon of appID,
+// unless ctx already names one.
+func (e *ComposeExecutor) appContext(ctx context.Context, appID string) (context.Context, error) {
+	if _, ok := dockerEndpointFromContext(ctx); ok || e.Endpoints == nil {
+		return ctx, nil
+	}
+	endpoint, err := e.Endpoints.DockerEndpoint(appID)
+	if err != nil {
+		return ctx, fmt.Errorf("resolve docker host failed: %w", err)
+	}
+	return WithDockerEndpoint(ctx, endpoint), nil
+}
+
+// endpointEnv returns the environment that points the docker CLI at the
+// endpoint of ctx, and a cleanup removing the TLS files it wrote.
+func endpointEnv(ctx context.Context) (map[string]string, func(), error) {
+	endpoint, ok := dockerEndpointFromContext(ctx)
+	if !ok || endpoint.IsLocal() {
+		return nil, func() {}, nil
+	}
+	if endpoint.IsContext() {
+		// DOCKER_HOST takes precedence over the context; clear it in case
+		// the controller runs with one set.
+		return map[string]string{"DOCKER_CONTEXT": endpoint.Host, "DOCKER_HOST": ""}, func() {}, nil
+	}
+
+	env := map[string]string{"DOCKER_HOST": endpoint.Host, "DOCKER_CONTEXT": ""}
+	if endpoint.TLS == nil {
+		return env, func() {}, nil
+	}
+	certDir, err := os.MkdirTemp("", "conops-docker-tls-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create docker tls dir: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(certDir) }
+	files := map[string]string{"ca.pem": endpoint.TLS.CACert, "cert.pem": endpoint.TLS.ClientCert, "key.pem": endpoint.TLS.ClientKey}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(certDir, name), []byte(content), 0600); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to write docker tls files: %w", err)
+		}
+	}
+	env["DOCKER_TLS_VERIFY"] = "1"
+	env["DOCKER_CERT_PATH"] = certDir
+	return env, cleanup, nil
+}
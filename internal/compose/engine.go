@@ -0,0 +1,260 @@
+package compose
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// engineClient returns a Docker Engine API client for the daemon ctx points
+// at, or nil when only the docker CLI can reach it: ssh:// hosts and docker
+// contexts need the CLI's connection helpers and context store. Clients are
+// kept per daemon, so queries reuse their connections.
+func (e *ComposeExecutor) engineClient(ctx context.Context) (*client.Client, error) {
+	endpoint, _ := dockerEndpointFromContext(ctx)
+	host := endpoint.Host
+	switch {
+	case endpoint.IsContext():
+		return nil, nil
+	case endpoint.IsLocal():
+		if os.Getenv("DOCKER_CONTEXT") != "" {
+			return nil, nil
+		}
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if strings.HasPrefix(host, "ssh://") {
+		return nil, nil
+	}
+
+	key := host
+	if endpoint.TLS != nil {
+		key += "\x00" + endpoint.TLS.CACert + endpoint.TLS.ClientCert + endpoint.TLS.ClientKey
+	}
+	e.engineMu.Lock()
+	defer e.engineMu.Unlock()
+	if cli, ok := e.engines[key]; ok {
+		return cli, nil
+	}
+
+	opts := []client.Opt{client.FromEnv}
+	if !endpoint.IsLocal() {
+		opts = []client.Opt{client.WithHost(host)}
+		if endpoint.TLS != nil {
+			httpClient, err := engineTLSClient(endpoint.TLS)
+			if err != nil {
+				return nil, err
+			}
+			// WithHost configures the transport, so it must come second.
+			opts = []client.Opt{client.WithHTTPClient(httpClient), client.WithHost(host)}
+		}
+	}
+	cli, err := client.NewClientWithOpts(append(opts, client.WithAPIVersionNegotiation())...)
+	if err != nil {
+		return nil, fmt.Errorf("docker engine client failed: %w", err)
+	}
+	if e.engines == nil {
+		e.engines = make(map[string]*client.Client)
+	}
+	e.engines[key] = cli
+	return cli, nil
+}
+
+// engineTLSClient builds an HTTP client presenting the endpoint's client
+// certificate and trusting its CA.
+func engineTLSClient(material *DockerTLS) (*http.Client, error) {
+	certificate, err := tls.X509KeyPair([]byte(material.ClientCert), []byte(material.ClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker tls client certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(material.CACert)) {
+		return nil, fmt.Errorf("invalid docker tls ca certificate")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      pool,
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// engineProjectFilter selects the service containers of a compose project,
+// or of every project when projectName is empty.
+func engineProjectFilter(projectName string) filters.Args {
+	label := "com.docker.compose.project"
+	if projectName != "" {
+		label += "=" + projectName
+	}
+	return filters.NewArgs(
+		filters.Arg("label", label),
+		filters.Arg("label", "com.docker.compose.oneoff=False"),
+	)
+}
+
+// engineContainers lists the containers of a project, or of every project,
+// with their compose labels.
+func engineContainers(ctx context.Context, cli *client.Client, projectName string) ([]container.Summary, error) {
+	summaries, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: engineProjectFilter(projectName)})
+	if err != nil {
+		return nil, fmt.Errorf("docker ps failed: %w", err)
+	}
+	return summaries, nil
+}
+
+// summaryContainer converts a listed container. The list has no health or
+// exit code fields, so they are read from the status text like for docker ps.
+func summaryContainer(summary container.Summary) ServiceContainer {
+	name := ""
+	if len(summary.Names) > 0 {
+		name = strings.TrimPrefix(summary.Names[0], "/")
+	}
+	publishers := make([]composePublisher, 0, len(summary.Ports))
+	for _, port := range summary.Ports {
+		publishers = append(publishers, composePublisher{
+			URL:           port.IP,
+			TargetPort:    int(port.PrivatePort),
+			PublishedPort: int(port.PublicPort),
+			Protocol:      port.Type,
+		})
+	}
+	return ServiceContainer{
+		ID:       summary.ID,
+		Service:  summary.Labels["com.docker.compose.service"],
+		Name:     name,
+		Image:    summary.Image,
+		Status:   dockerState(string(summary.State), summary.Status),
+		Health:   healthFromStatus(summary.Status),
+		ExitCode: exitCodeFromStatus(summary.Status),
+		Ports:    formatPublishers(publishers),
+	}
+}
+
+// engineProjectContainers lists a project's containers and inspects each
+// for its health, exit code and restart count. A container removed between
+// the two calls is left out.
+func engineProjectContainers(ctx context.Context, cli *client.Client, projectName string) ([]ServiceContainer, error) {
+	summaries, err := engineContainers(ctx, cli, projectName)
+	if err != nil {
+		return nil, err
+	}
+	containers := make([]ServiceContainer, 0, len(summaries))
+	for _, summary := range summaries {
+		c := summaryContainer(summary)
+		inspect, err := cli.ContainerInspect(ctx, summary.ID)
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("docker inspect failed: %w", err)
+		}
+		c.RestartCount = inspect.RestartCount
+		if state := inspect.State; state != nil {
+			c.Health = ""
+			if state.Health != nil && state.Health.Status != container.NoHealthcheck {
+				c.Health = string(state.Health.Status)
+			}
+			if c.Status == ContainerExited || c.Status == ContainerDead {
+				c.ExitCode = state.ExitCode
+			}
+		}
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
+
+// engineProjectStats is ProjectStats over the Engine API. Each stats call
+// waits for a second sample to compute CPU usage from, so the running
+// containers are sampled at once.
+func engineProjectStats(ctx context.Context, cli *client.Client, projectName string) ([]ServiceStats, error) {
+	summaries, err := engineContainers(ctx, cli, projectName)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]ServiceStats, len(summaries))
+	errs := make([]error, len(summaries))
+	var wg sync.WaitGroup
+	for i, summary := range summaries {
+		inspect, err := cli.ContainerInspect(ctx, summary.ID)
+		if err != nil && !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("docker inspect failed: %w", err)
+		}
+		c := summaryContainer(summary)
+		stats[i] = ServiceStats{
+			Service:      c.Service,
+			Name:         c.Name,
+			Running:      c.Status == ContainerRunning,
+			RestartCount: inspect.RestartCount,
+		}
+		if !stats[i].Running {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = sampleContainerStats(ctx, cli, summary.ID, &stats[i])
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("docker stats failed: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// sampleContainerStats fills in CPU and memory usage the way docker stats
+// computes them.
+func sampleContainerStats(ctx context.Context, cli *client.Client, id string, stats *ServiceStats) error {
+	reader, err := cli.ContainerStats(ctx, id, false)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			stats.Running = false
+			return nil
+		}
+		return err
+	}
+	defer reader.Body.Close()
+	var sample container.StatsResponse
+	if err := json.NewDecoder(reader.Body).Decode(&sample); err != nil {
+		return err
+	}
+
+	cpuDelta := float64(sample.CPUStats.CPUUsage.TotalUsage) - float64(sample.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(sample.CPUStats.SystemUsage) - float64(sample.PreCPUStats.SystemUsage)
+	cpus := float64(sample.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(sample.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// Like docker stats, page cache the kernel can reclaim is not usage;
+	// cgroup v1 reports it as total_inactive_file, v2 as inactive_file.
+	usage := sample.MemoryStats.Usage
+	cache, ok := sample.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		cache = sample.MemoryStats.Stats["inactive_file"]
+	}
+	if cache < usage {
+		usage -= cache
+	}
+	stats.MemoryBytes = usage
+	stats.MemoryLimitBytes = sample.MemoryStats.Limit
+	if sample.MemoryStats.Limit > 0 {
+		stats.MemoryPercent = float64(usage) / float64(sample.MemoryStats.Limit) * 100
+	}
+	return nil
+}
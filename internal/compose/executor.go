@@ -15,7 +15,9 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/conops/conops/internal/redact"
 	"github.com/conops/conops/internal/repoauth"
+	"github.com/docker/docker/client"
 )
 
 // ComposeExecutor handles Docker Compose operations.
@@ -23,18 +25,36 @@ type ComposeExecutor struct {
 	WorkDir  string
 	ToolsDir string
 	Logger   *slog.Logger
+	// HealthTimeout bounds the wait after up for services with a
+	// healthcheck to become healthy. Zero skips the wait.
+	HealthTimeout time.Duration
+	// Endpoints, when set, maps apps to the docker daemon they run on;
+	// without it every app runs on the local daemon.
+	Endpoints EndpointResolver
+	// Secrets, when set, resolves env values that reference a secret store
+	// just before compose up, so the secrets are never stored.
+	Secrets SecretResolver
+	// SOPSKeys, when set, supplies the keys that decrypt SOPS-encrypted env
+	// files at apply time.
+	SOPSKeys SOPSKeyResolver
 
 	toolchainMu      sync.Mutex
 	dockerResolution dockerCommandResolution
 	resolutionAt     time.Time
+
+	renderMu sync.Mutex
+
+	engineMu sync.Mutex
+	engines  map[string]*client.Client // by daemon; see engineClient
 }
 
 // ProjectRuntimeState summarizes runtime state for one compose project.
 type ProjectRuntimeState struct {
-	ContainerCount int
-	RunningCount   int
-	ExitedCount    int
-	UnhealthyCount int
+	ContainerCount  int
+	RunningCount    int
+	RestartingCount int
+	ExitedCount     int
+	UnhealthyCount  int
 }
 
 // IsHealthy reports whether all tracked service containers are running and healthy.
@@ -48,102 +68,133 @@ func (s ProjectRuntimeState) IsHealthy() bool {
 // NewComposeExecutor creates a new executor.
 func NewComposeExecutor(logger *slog.Logger) *ComposeExecutor {
 	return &ComposeExecutor{
-		WorkDir:  "./.conops-runtime",
-		ToolsDir: "./.conops-tools",
-		Logger:   logger,
+		WorkDir:       "./.conops-runtime",
+		ToolsDir:      "./.conops-tools",
+		Logger:        logger,
+		HealthTimeout: 2 * time.Minute,
 	}
 }
 
-// Apply executes the compose file.
-func (e *ComposeExecutor) Apply(
-	ctx context.Context,
-	appID, content string,
-	envVars map[string]string,
-	repoURL, branch, composePath, commitHash string,
-	deployKey []byte,
-	onProgress func(string),
-) (string, error) {
-	var syncLog strings.Builder
+// ApplyOptions is what Apply deploys for an app.
+type ApplyOptions struct {
+	AppID string
+	// Content, when set, replaces the compose file of the checkout.
+	Content     string
+	EnvVars     map[string]string
+	RepoURL     string
+	Branch      string
+	ComposePath string
+	Profiles    []string
+	PreDeploy   []string
+	PostDeploy  []string
+	EnvFile     string
+	// CommitHash pins the commit to check out; empty deploys the head of
+	// Branch.
+	CommitHash string
+	DeployKey  []byte
+	// PinnedImages maps services to the image digests they are pinned to.
+	PinnedImages map[string]string
+	// OnProgress, when set, receives the transcript so far as it grows.
+	OnProgress func(string)
+}
+
+// Apply executes the compose file and returns the transcript of the sync.
+func (e *ComposeExecutor) Apply(ctx context.Context, opts ApplyOptions) (*Transcript, error) {
+	syncLog := NewTranscript(ctx)
+	syncLog.Step(StepPreflight)
 	emitProgress := func() {
-		if onProgress != nil {
-			onProgress(strings.TrimSpace(syncLog.String()))
+		if opts.OnProgress != nil {
+			opts.OnProgress(syncLog.String())
 		}
 	}
+	ctx, err := e.appContext(ctx, opts.AppID)
+	if err != nil {
+		appendLogSection(&syncLog.Builder, "Docker host")
+		appendLogLine(&syncLog.Builder, err.Error())
+		emitProgress()
+		return syncLog, err
+	}
 
-	appDir := filepath.Join(e.WorkDir, appID)
+	appDir := filepath.Join(e.WorkDir, opts.AppID)
 	appDirAbs, err := filepath.Abs(appDir)
 	if err != nil {
-		appendLogSection(&syncLog, "Sync setup")
-		appendLogLine(&syncLog, "failed to resolve runtime directory")
-		appendLogLine(&syncLog, err.Error())
+		appendLogSection(&syncLog.Builder, "Sync setup")
+		appendLogLine(&syncLog.Builder, "failed to resolve runtime directory")
+		appendLogLine(&syncLog.Builder, err.Error())
 		emitProgress()
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("resolve app dir failed: %w", err)
+		return syncLog, fmt.Errorf("resolve app dir failed: %w", err)
 	}
 	if err := os.MkdirAll(appDirAbs, 0755); err != nil {
-		appendLogSection(&syncLog, "Sync setup")
-		appendLogLine(&syncLog, "failed to create runtime directory")
-		appendLogLine(&syncLog, err.Error())
+		appendLogSection(&syncLog.Builder, "Sync setup")
+		appendLogLine(&syncLog.Builder, "failed to create runtime directory")
+		appendLogLine(&syncLog.Builder, err.Error())
 		emitProgress()
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("failed to create app dir: %w", err)
+		return syncLog, fmt.Errorf("failed to create app dir: %w", err)
 	}
 
-	if strings.TrimSpace(repoURL) == "" {
-		appendLogSection(&syncLog, "Validation")
-		appendLogLine(&syncLog, "repo url is empty")
+	if strings.TrimSpace(opts.RepoURL) == "" {
+		appendLogSection(&syncLog.Builder, "Validation")
+		appendLogLine(&syncLog.Builder, "repo url is empty")
 		emitProgress()
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("repo url is empty")
+		return syncLog, fmt.Errorf("repo url is empty")
 	}
-	if strings.TrimSpace(composePath) == "" {
-		appendLogSection(&syncLog, "Validation")
-		appendLogLine(&syncLog, "compose path is empty")
+	if strings.TrimSpace(opts.ComposePath) == "" {
+		appendLogSection(&syncLog.Builder, "Validation")
+		appendLogLine(&syncLog.Builder, "compose path is empty")
 		emitProgress()
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("compose path is empty")
+		return syncLog, fmt.Errorf("compose path is empty")
 	}
-	if strings.TrimSpace(branch) == "" {
-		branch = "main"
+	// A pinned commit may come from a tag rather than a branch, in which case
+	// the caller passes no branch and the remote default branch is cloned.
+	if strings.TrimSpace(opts.Branch) == "" && strings.TrimSpace(opts.CommitHash) == "" {
+		opts.Branch = "main"
 	}
 
-	appendLogSection(&syncLog, "Sync started")
-	appendLogLine(&syncLog, fmt.Sprintf("app_id: %s", appID))
-	appendLogLine(&syncLog, fmt.Sprintf("repository: %s", repoURL))
-	appendLogLine(&syncLog, fmt.Sprintf("branch: %s", branch))
-	if strings.TrimSpace(commitHash) != "" {
-		appendLogLine(&syncLog, fmt.Sprintf("target_commit: %s", commitHash))
+	appendLogSection(&syncLog.Builder, "Sync started")
+	appendLogLine(&syncLog.Builder, fmt.Sprintf("app_id: %s", opts.AppID))
+	appendLogLine(&syncLog.Builder, fmt.Sprintf("repository: %s", opts.RepoURL))
+	if opts.Branch != "" {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("branch: %s", opts.Branch))
+	}
+	if strings.TrimSpace(opts.CommitHash) != "" {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("target_commit: %s", opts.CommitHash))
 	} else {
-		appendLogLine(&syncLog, "target_commit: latest on branch")
+		appendLogLine(&syncLog.Builder, "target_commit: latest on branch")
 	}
 	emitProgress()
 
-	appendLogSection(&syncLog, "Docker preflight")
+	appendLogSection(&syncLog.Builder, "Docker preflight")
 	preflight, err := e.ensureDockerPreflight(ctx)
 	if err != nil {
-		appendLogLine(&syncLog, "failed")
-		appendLogLine(&syncLog, err.Error())
+		appendLogLine(&syncLog.Builder, "failed")
+		appendLogLine(&syncLog.Builder, err.Error())
 		emitProgress()
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("docker preflight failed: %w", err)
+		return syncLog, fmt.Errorf("docker preflight failed: %w", err)
 	}
 	for _, line := range preflight.LogLines() {
-		appendLogLine(&syncLog, line)
+		appendLogLine(&syncLog.Builder, line)
 	}
 	emitProgress()
 
+	syncLog.Step(StepGit)
 	repoDir := filepath.Join(appDirAbs, "repo")
-	e.Logger.Info("Preparing repo", "app_id", appID, "repo", repoURL, "branch", branch, "commit", commitHash, "dir", repoDir)
-	repoLog, err := e.prepareRepo(ctx, appDirAbs, repoDir, repoURL, branch, commitHash, deployKey)
-	appendLogBlock(&syncLog, repoLog)
+	e.Logger.Info("Preparing repo", "app_id", opts.AppID, "repo", opts.RepoURL, "branch", opts.Branch, "commit", opts.CommitHash, "dir", repoDir)
+	repoLog, err := e.prepareRepo(ctx, appDirAbs, repoDir, opts.RepoURL, opts.Branch, opts.CommitHash, opts.DeployKey)
+	appendLogBlock(&syncLog.Builder, repoLog)
 	emitProgress()
 	if err != nil {
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("prepare repo failed: %w", err)
+		return syncLog, fmt.Errorf("prepare repo failed: %w", err)
 	}
 
-	composeFullPath := filepath.Join(repoDir, composePath)
+	syncLog.Step(StepComposeConfig)
+	composeFullPath := filepath.Join(repoDir, opts.ComposePath)
 	composeDir := filepath.Dir(composeFullPath)
 	if _, err := os.Stat(composeDir); err != nil {
-		appendLogSection(&syncLog, "Compose file")
-		appendLogLine(&syncLog, fmt.Sprintf("compose directory does not exist: %s", composeDir))
-		appendLogLine(&syncLog, err.Error())
+		appendLogSection(&syncLog.Builder, "Compose file")
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("compose directory does not exist: %s", composeDir))
+		appendLogLine(&syncLog.Builder, err.Error())
 		emitProgress()
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("compose dir not found: %w", err)
+		return syncLog, fmt.Errorf("compose dir not found: %w", err)
 	}
 
 	// Prepare env var override files if needed (must happen after composeDir is determined)
@@ -151,110 +202,380 @@ func (e *ComposeExecutor) Apply(
 	var overrideArgs []string
 
 	wroteCompose := false
-	if strings.TrimSpace(content) != "" {
-		if err := os.WriteFile(composeFullPath, []byte(content), 0644); err != nil {
-			appendLogSection(&syncLog, "Compose file")
-			appendLogLine(&syncLog, fmt.Sprintf("failed to write compose file: %s", composeFullPath))
-			appendLogLine(&syncLog, err.Error())
+	if strings.TrimSpace(opts.Content) != "" {
+		if err := os.WriteFile(composeFullPath, []byte(opts.Content), 0644); err != nil {
+			appendLogSection(&syncLog.Builder, "Compose file")
+			appendLogLine(&syncLog.Builder, fmt.Sprintf("failed to write compose file: %s", composeFullPath))
+			appendLogLine(&syncLog.Builder, err.Error())
 			emitProgress()
-			return strings.TrimSpace(syncLog.String()), fmt.Errorf("failed to write compose file: %w", err)
+			return syncLog, fmt.Errorf("failed to write compose file: %w", err)
 		}
 		wroteCompose = true
 	} else {
 		if _, err := os.Stat(composeFullPath); err != nil {
-			appendLogSection(&syncLog, "Compose file")
-			appendLogLine(&syncLog, fmt.Sprintf("compose file not found: %s", composeFullPath))
-			appendLogLine(&syncLog, err.Error())
+			appendLogSection(&syncLog.Builder, "Compose file")
+			appendLogLine(&syncLog.Builder, fmt.Sprintf("compose file not found: %s", composeFullPath))
+			appendLogLine(&syncLog.Builder, err.Error())
 			emitProgress()
-			return strings.TrimSpace(syncLog.String()), fmt.Errorf("compose file not found: %w", err)
+			return syncLog, fmt.Errorf("compose file not found: %w", err)
 		}
 	}
 	composeFileName := filepath.Base(composeFullPath)
-	projectName := composeProjectName(appID)
+	projectName := composeProjectName(opts.AppID)
 
 	// Prepare env var override files if needed (must use composeDir as base)
-	if len(envVars) > 0 {
+	if len(opts.EnvVars) > 0 {
+		resolvedEnvs, resolvedNames, resolveErr := e.resolveSecretRefs(ctx, opts.EnvVars)
+		if resolveErr != nil {
+			appendLogSection(&syncLog.Builder, "Secret references")
+			appendLogLine(&syncLog.Builder, "failed to resolve secret references")
+			appendLogLine(&syncLog.Builder, resolveErr.Error())
+			emitProgress()
+			return syncLog, fmt.Errorf("secret resolve failed: %w", resolveErr)
+		}
+		if len(resolvedNames) > 0 {
+			appendLogSection(&syncLog.Builder, "Secret references")
+			appendLogLine(&syncLog.Builder, fmt.Sprintf("resolved: %s", strings.Join(resolvedNames, ", ")))
+			emitProgress()
+		}
+		opts.EnvVars = resolvedEnvs
+
 		var envErr error
-		overrideArgs, envFilesCleanup, envErr = e.prepareEnvOverrides(composeDir, envVars)
+		overrideArgs, envFilesCleanup, envErr = e.prepareEnvOverrides(composeDir, opts.EnvVars)
 		if envErr != nil {
-			appendLogSection(&syncLog, "Environment preparation")
-			appendLogLine(&syncLog, "failed to prepare environment variables")
-			appendLogLine(&syncLog, envErr.Error())
+			appendLogSection(&syncLog.Builder, "Environment preparation")
+			appendLogLine(&syncLog.Builder, "failed to prepare environment variables")
+			appendLogLine(&syncLog.Builder, envErr.Error())
 			emitProgress()
-			return strings.TrimSpace(syncLog.String()), fmt.Errorf("env prepare failed: %w", envErr)
+			return syncLog, fmt.Errorf("env prepare failed: %w", envErr)
 		}
 		defer envFilesCleanup()
 	}
 
-	appendLogSection(&syncLog, "Compose file")
-	appendLogLine(&syncLog, fmt.Sprintf("path: %s", composeFullPath))
-	appendLogLine(&syncLog, fmt.Sprintf("written_from_request: %t", wroteCompose))
+	appendLogSection(&syncLog.Builder, "Compose file")
+	appendLogLine(&syncLog.Builder, fmt.Sprintf("path: %s", composeFullPath))
+	appendLogLine(&syncLog.Builder, fmt.Sprintf("written_from_request: %t", wroteCompose))
+	if len(opts.Profiles) > 0 {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("profiles: %s", strings.Join(opts.Profiles, ", ")))
+	}
+	envFileArg := ""
+	if opts.EnvFile != "" {
+		envFileFullPath := filepath.Join(repoDir, opts.EnvFile)
+		if fileInfo, err := os.Stat(envFileFullPath); err != nil || fileInfo.IsDir() {
+			appendLogLine(&syncLog.Builder, fmt.Sprintf("env file not found: %s", envFileFullPath))
+			emitProgress()
+			return syncLog, fmt.Errorf("env file not found: %s", opts.EnvFile)
+		}
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("env_file: %s", envFileFullPath))
+		envFileArg = envFileFullPath
+	} else if defaultEnvFile := filepath.Join(composeDir, ".env"); isSOPSEnvFile(defaultEnvFile) {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("env_file: %s", defaultEnvFile))
+		envFileArg = defaultEnvFile
+	}
+	// An encrypted env file is decrypted next to the checkout and the
+	// plaintext copy is handed to compose instead.
+	if envFileArg != "" && isSOPSEnvFile(envFileArg) {
+		decrypted, sopsCleanup, err := e.decryptSOPSEnvFile(ctx, opts.AppID, appDirAbs, envFileArg)
+		if err != nil {
+			appendLogLine(&syncLog.Builder, "failed to decrypt env file with sops")
+			appendLogLine(&syncLog.Builder, err.Error())
+			emitProgress()
+			return syncLog, fmt.Errorf("sops decrypt failed: %w", err)
+		}
+		defer sopsCleanup()
+		appendLogLine(&syncLog.Builder, "env_file_encryption: sops (decrypted for this sync)")
+		envFileArg = decrypted
+	}
+	if envFileArg != "" {
+		overrideArgs = append(overrideArgs, "--env-file", envFileArg)
+	}
 	emitProgress()
+	overrideArgs = append(overrideArgs, profileArgs(opts.Profiles)...)
 
 	e.Logger.Info(
 		"Compose file ready",
-		"app_id", appID,
+		"app_id", opts.AppID,
 		"path", composeFullPath,
-		"bytes", len(content),
+		"bytes", len(opts.Content),
 		"written", wroteCompose,
 	)
 
-	// Pull images
-	appendLogSection(&syncLog, "Docker image pull")
-	e.Logger.Info("Pulling images", "app_id", appID)
+	// Validate the merged configuration first so a broken compose file fails
+	// with its own section instead of partway through pull or up.
+	appendLogSection(&syncLog.Builder, "Compose validation")
+	_, err = e.runCommandWithTranscript(
+		ctx,
+		&syncLog.Builder,
+		"docker",
+		composeArgs(projectName, composeFileName, overrideArgs, "config", "--quiet"),
+		composeDir,
+		nil,
+		opts.OnProgress,
+	)
+	if err != nil {
+		appendLogLine(&syncLog.Builder, "invalid compose file: fix the errors above and push a new commit")
+		emitProgress()
+		return syncLog, fmt.Errorf("invalid compose file: %w", err)
+	}
 
-	pullArgs := []string{"compose", "-p", projectName, "-f", composeFileName}
-	// Add override file args if any (must come after -f base)
-	if len(overrideArgs) > 0 {
-		pullArgs = append(pullArgs, overrideArgs...)
+	// Deploy the digests an earlier sync of this commit ran instead of
+	// whatever the tags point at now.
+	if len(opts.PinnedImages) > 0 {
+		appendLogSection(&syncLog.Builder, "Image pins")
+		servicesOutput, err := e.runCommand(ctx, "docker", composeArgs(projectName, composeFileName, overrideArgs, "config", "--services"), composeDir, nil, nil)
+		if err != nil {
+			appendCommandOutput(&syncLog.Builder, servicesOutput)
+			emitProgress()
+			return syncLog, fmt.Errorf("list services failed: %w", err)
+		}
+		pins := make(map[string]string)
+		for _, service := range strings.Fields(servicesOutput) {
+			if digest, ok := opts.PinnedImages[service]; ok {
+				pins[service] = digest
+			}
+		}
+		pinArgs, pinCleanup, err := writePinOverride(composeDir, pins)
+		if err != nil {
+			appendLogLine(&syncLog.Builder, err.Error())
+			emitProgress()
+			return syncLog, err
+		}
+		defer pinCleanup()
+		overrideArgs = append(overrideArgs, pinArgs...)
+		for _, service := range sortedKeys(pins) {
+			appendLogLine(&syncLog.Builder, fmt.Sprintf("%s: %s", service, pins[service]))
+		}
+		if len(pins) == 0 {
+			appendLogLine(&syncLog.Builder, "no pinned service is enabled; using tags")
+		}
+		emitProgress()
 	}
-	pullArgs = append(pullArgs, "pull")
+
+	// Pull images
+	syncLog.Step(StepComposePull)
+	appendLogSection(&syncLog.Builder, "Docker image pull")
+	e.Logger.Info("Pulling images", "app_id", opts.AppID)
+
+	pullArgs := composeArgs(projectName, composeFileName, overrideArgs, "pull")
 
 	_, err = e.runCommandWithTranscript(
 		ctx,
-		&syncLog,
+		&syncLog.Builder,
 		"docker",
 		pullArgs,
 		composeDir,
 		nil, // Do not inject process env vars for pull
-		onProgress,
+		opts.OnProgress,
 	)
 	if err != nil {
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("pull failed: %w", err)
+		return syncLog, fmt.Errorf("pull failed: %w", err)
 	}
 
-	// Up detached
-	appendLogSection(&syncLog, "Compose apply")
-	appendLogLine(&syncLog, "build output appears below when services require a build")
-	e.Logger.Info("Applying configuration", "app_id", appID)
+	// Pre-deploy checks run against the new checkout and images while the
+	// current stack keeps running; any failure stops the sync before up.
+	if len(opts.PreDeploy) > 0 {
+		syncLog.Step(StepPreDeploy)
+	}
+	for i, entry := range opts.PreDeploy {
+		check, err := ParsePreDeploy(entry)
+		if err != nil {
+			appendLogSection(&syncLog.Builder, "Pre-deploy")
+			appendLogLine(&syncLog.Builder, err.Error())
+			emitProgress()
+			return syncLog, err
+		}
+		appendLogSection(&syncLog.Builder, fmt.Sprintf("Pre-deploy %d/%d", i+1, len(opts.PreDeploy)))
+		e.Logger.Info("Running pre-deploy check", "app_id", opts.AppID, "check", check.String())
 
-	upArgs := []string{"compose", "-p", projectName, "-f", composeFileName}
-	if len(overrideArgs) > 0 {
-		upArgs = append(upArgs, overrideArgs...)
+		if check.URL != "" {
+			appendLogLine(&syncLog.Builder, "$ GET "+check.URL)
+			result, err := checkURL(ctx, check.URL)
+			appendCommandOutput(&syncLog.Builder, result)
+			if err != nil {
+				appendLogLine(&syncLog.Builder, "ERROR: "+err.Error())
+				emitProgress()
+				return syncLog, fmt.Errorf("pre-deploy check %q failed: %w", entry, err)
+			}
+			emitProgress()
+			continue
+		}
+		_, err = e.runCommandWithTranscript(
+			ctx,
+			&syncLog.Builder,
+			"docker",
+			composeArgs(projectName, composeFileName, overrideArgs, preDeployArgs(check.Service, check.Command)...),
+			composeDir,
+			nil,
+			opts.OnProgress,
+		)
+		if err != nil {
+			return syncLog, fmt.Errorf("pre-deploy check %q failed: %w", entry, err)
+		}
 	}
-	upArgs = append(upArgs, "up", "-d", "--remove-orphans", "--build")
+
+	// Up detached
+	syncLog.Step(StepComposeUp)
+	appendLogSection(&syncLog.Builder, "Compose apply")
+	appendLogLine(&syncLog.Builder, "build output appears below when services require a build")
+	e.Logger.Info("Applying configuration", "app_id", opts.AppID)
+
+	upArgs := composeArgs(projectName, composeFileName, overrideArgs, upCommand...)
 
 	_, err = e.runCommandWithTranscript(
 		ctx,
-		&syncLog,
+		&syncLog.Builder,
 		"docker",
 		upArgs,
 		composeDir,
 		nil,
-		onProgress,
+		opts.OnProgress,
 	)
 	if err != nil {
-		return strings.TrimSpace(syncLog.String()), fmt.Errorf("up failed: %w", err)
+		return syncLog, &RolloutError{Err: fmt.Errorf("up failed: %w", err)}
+	}
+
+	if len(opts.PostDeploy) > 0 {
+		syncLog.Step(StepPostDeploy)
+	}
+	for i, entry := range opts.PostDeploy {
+		service, command, err := ParsePostDeploy(entry)
+		if err != nil {
+			appendLogSection(&syncLog.Builder, "Post-deploy")
+			appendLogLine(&syncLog.Builder, err.Error())
+			emitProgress()
+			return syncLog, &RolloutError{Err: err}
+		}
+		appendLogSection(&syncLog.Builder, fmt.Sprintf("Post-deploy %d/%d: %s", i+1, len(opts.PostDeploy), service))
+		e.Logger.Info("Running post-deploy command", "app_id", opts.AppID, "service", service)
+
+		_, err = e.runCommandWithTranscript(
+			ctx,
+			&syncLog.Builder,
+			"docker",
+			composeArgs(projectName, composeFileName, overrideArgs, postDeployArgs(service, command)...),
+			composeDir,
+			nil,
+			opts.OnProgress,
+		)
+		if err != nil {
+			return syncLog, &RolloutError{Err: fmt.Errorf("post-deploy command %q failed: %w", entry, err)}
+		}
+	}
+
+	// Containers starting is not success. The wait comes after post-deploy
+	// commands because a healthcheck may depend on e.g. a migration.
+	if e.HealthTimeout > 0 {
+		syncLog.Step(StepHealthGate)
+		e.Logger.Info("Waiting for services to become healthy", "app_id", opts.AppID, "timeout", e.HealthTimeout.String())
+		if err := e.waitHealthy(ctx, &syncLog.Builder, projectName, e.HealthTimeout, emitProgress); err != nil {
+			return syncLog, &RolloutError{Err: fmt.Errorf("health wait failed: %w", err)}
+		}
 	}
 
-	appendLogSection(&syncLog, "Sync completed")
-	appendLogLine(&syncLog, "application reconciled successfully")
+	appendLogSection(&syncLog.Builder, "Sync completed")
+	appendLogLine(&syncLog.Builder, "application reconciled successfully")
 	emitProgress()
-	return strings.TrimSpace(syncLog.String()), nil
+	return syncLog, nil
+}
+
+// RolloutError is an Apply failure from up or a later step. The running
+// stack may already have been replaced, unlike failures before up which
+// leave it untouched.
+type RolloutError struct {
+	Err error
+}
+
+func (e *RolloutError) Error() string { return e.Err.Error() }
+
+func (e *RolloutError) Unwrap() error { return e.Err }
+
+// upCommand is the compose subcommand Apply runs after pulling images.
+var upCommand = []string{"up", "-d", "--remove-orphans", "--build"}
+
+// composeArgs builds a docker compose invocation. Override file args must
+// come after the base -f; --env-file and profile args may follow them.
+func composeArgs(projectName, composeFileName string, overrideArgs []string, command ...string) []string {
+	args := []string{"compose", "-p", projectName, "-f", composeFileName}
+	args = append(args, overrideArgs...)
+	return append(args, command...)
+}
+
+// profileArgs enables compose profiles. When an app sets none, compose falls
+// back to COMPOSE_PROFILES from the controller environment.
+func profileArgs(profiles []string) []string {
+	var args []string
+	for _, profile := range profiles {
+		args = append(args, "--profile", profile)
+	}
+	return args
+}
+
+// Plan describes how Apply invokes compose for an app.
+type Plan struct {
+	ProjectName  string
+	WorkingDir   string
+	ComposeFiles []string
+	EnvFile      string // absolute path, empty when compose reads .env itself
+	Profiles     []string
+	Command      []string
+
+	overrideArgs []string
+}
+
+// Plan reports the compose invocation Apply would run for an app without
+// touching the runtime. Only the presence of serviceEnvs matters; their
+// values are never inspected.
+func (e *ComposeExecutor) Plan(appID, composePath string, profiles []string, envFile string, serviceEnvs map[string]string) (Plan, error) {
+	appDirAbs, err := filepath.Abs(filepath.Join(e.WorkDir, appID))
+	if err != nil {
+		return Plan{}, fmt.Errorf("resolve app dir failed: %w", err)
+	}
+	composeFullPath := filepath.Join(appDirAbs, "repo", composePath)
+	composeDir := filepath.Dir(composeFullPath)
+	composeFileName := filepath.Base(composeFullPath)
+
+	plan := Plan{
+		ProjectName:  composeProjectName(appID),
+		WorkingDir:   composeDir,
+		ComposeFiles: []string{composeFileName},
+	}
+	var overrideArgs []string
+	if len(serviceEnvs) > 0 {
+		overridePath := envOverridePath(composeDir)
+		plan.ComposeFiles = append(plan.ComposeFiles, overridePath)
+		overrideArgs = []string{"-f", overridePath}
+	}
+	if envFile != "" {
+		plan.EnvFile = filepath.Join(appDirAbs, "repo", envFile)
+		overrideArgs = append(overrideArgs, "--env-file", plan.EnvFile)
+	}
+	if len(profiles) > 0 {
+		plan.Profiles = append(plan.Profiles, profiles...)
+		overrideArgs = append(overrideArgs, profileArgs(profiles)...)
+	} else {
+		// Compose reads COMPOSE_PROFILES from the controller environment,
+		// which its commands inherit.
+		for _, profile := range strings.Split(os.Getenv("COMPOSE_PROFILES"), ",") {
+			if profile = strings.TrimSpace(profile); profile != "" {
+				plan.Profiles = append(plan.Profiles, profile)
+			}
+		}
+	}
+	plan.overrideArgs = overrideArgs
+	plan.Command = append([]string{"docker"}, composeArgs(plan.ProjectName, composeFileName, overrideArgs, upCommand...)...)
+	return plan, nil
 }
 
 // Destroy tears down app containers and networks without removing volumes.
 func (e *ComposeExecutor) Destroy(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error) {
+	return e.destroy(ctx, appID, composePath, envVars, false)
+}
+
+func (e *ComposeExecutor) destroy(ctx context.Context, appID, composePath string, envVars map[string]string, removeVolumes bool) (string, error) {
+	ctx, err := e.appContext(ctx, appID)
+	if err != nil {
+		return "", err
+	}
 	projectName := composeProjectName(appID)
 	appDirAbs, err := filepath.Abs(filepath.Join(e.WorkDir, appID))
 	if err != nil {
@@ -274,11 +595,15 @@ func (e *ComposeExecutor) Destroy(ctx context.Context, appID, composePath string
 
 	if fileInfo, statErr := os.Stat(composeFullPath); statErr == nil && !fileInfo.IsDir() {
 		downAttempted = true
-		e.Logger.Info("Stopping app stack", "app_id", appID, "project", projectName, "compose_file", composeFullPath)
+		e.Logger.Info("Stopping app stack", "app_id", appID, "project", projectName, "compose_file", composeFullPath, "volumes", removeVolumes)
+		downArgs := []string{"compose", "-p", projectName, "-f", composeFileName, "down", "--remove-orphans"}
+		if removeVolumes {
+			downArgs = append(downArgs, "--volumes")
+		}
 		downOut, downErr := e.runCommand(
 			ctx,
 			"docker",
-			[]string{"compose", "-p", projectName, "-f", composeFileName, "down", "--remove-orphans"},
+			downArgs,
 			composeDir,
 			envVars,
 			nil,
@@ -299,7 +624,12 @@ func (e *ComposeExecutor) Destroy(ctx context.Context, appID, composePath string
 	}
 	if len(containerIDs) > 0 {
 		e.Logger.Info("Removing lingering containers", "app_id", appID, "containers", len(containerIDs))
-		args := append([]string{"rm", "-f"}, containerIDs...)
+		args := []string{"rm", "-f"}
+		if removeVolumes {
+			// Anonymous volumes go with their containers.
+			args = append(args, "-v")
+		}
+		args = append(args, containerIDs...)
 		rmOut, rmErr := e.runCommand(ctx, "docker", args, appDirAbs, nil, nil)
 		if strings.TrimSpace(rmOut) != "" {
 			outputs = append(outputs, rmOut)
@@ -319,66 +649,84 @@ func (e *ComposeExecutor) Destroy(ctx context.Context, appID, composePath string
 	return strings.Join(outputs, "\n"), nil
 }
 
-// SnapshotProjects captures compose runtime status from Docker for all projects.
+// SnapshotProjects captures compose runtime status from Docker for all
+// projects, on the local daemon and on every remote one apps run on. It
+// fails when any daemon cannot be read, so projects on an unreachable host
+// are not mistaken for missing ones.
 func (e *ComposeExecutor) SnapshotProjects(ctx context.Context) (map[string]ProjectRuntimeState, error) {
+	snapshot := make(map[string]ProjectRuntimeState)
+	endpoints := []DockerEndpoint{{}}
+	if e.Endpoints != nil {
+		remote, err := e.Endpoints.DockerEndpoints()
+		if err != nil {
+			return nil, fmt.Errorf("list docker hosts failed: %w", err)
+		}
+		endpoints = append(endpoints, remote...)
+	}
+	for _, endpoint := range endpoints {
+		if err := e.snapshotDaemon(WithDockerEndpoint(ctx, endpoint), snapshot); err != nil {
+			if endpoint.IsLocal() {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %w", endpoint, err)
+		}
+	}
+	return snapshot, nil
+}
+
+// snapshotDaemon adds the projects of the daemon ctx points at to snapshot.
+// It reads every container at once, over the Engine API or with docker ps,
+// since compose ps only lists one project.
+func (e *ComposeExecutor) snapshotDaemon(ctx context.Context, snapshot map[string]ProjectRuntimeState) error {
+	cli, err := e.engineClient(ctx)
+	if err != nil {
+		return err
+	}
+	if cli != nil {
+		summaries, err := engineContainers(ctx, cli, "")
+		if err != nil {
+			return err
+		}
+		for _, summary := range summaries {
+			projectName := summary.Labels["com.docker.compose.project"]
+			state := snapshot[projectName]
+			state.Add(summaryContainer(summary))
+			snapshot[projectName] = state
+		}
+		return nil
+	}
+
 	output, err := e.runCommand(
 		ctx,
 		"docker",
-		[]string{
-			"ps",
-			"-a",
-			"--format",
-			`{{.Label "com.docker.compose.project"}}|{{.Label "com.docker.compose.oneoff"}}|{{.Status}}`,
-		},
+		[]string{"ps", "-a", "--format", dockerPSFormat},
 		e.runtimeWorkDir(),
 		nil,
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("docker ps failed: %w", err)
+		return fmt.Errorf("docker ps failed: %w", err)
 	}
-
-	snapshot := make(map[string]ProjectRuntimeState)
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) != 3 {
-			continue
-		}
-
-		projectName := strings.TrimSpace(parts[0])
-		oneOff := strings.TrimSpace(parts[1])
-		status := strings.TrimSpace(parts[2])
-		if projectName == "" || strings.EqualFold(oneOff, "true") || oneOff == "1" {
+	entries, err := parseDockerPS(output)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Project == "" || strings.EqualFold(entry.OneOff, "true") || entry.OneOff == "1" {
 			continue
 		}
-
-		state := snapshot[projectName]
-		state.ContainerCount++
-		if dockerStatusIsRunning(status) {
-			state.RunningCount++
-		} else {
-			state.ExitedCount++
-		}
-		if strings.Contains(strings.ToLower(status), "(unhealthy)") {
-			state.UnhealthyCount++
-		}
-		snapshot[projectName] = state
+		state := snapshot[entry.Project]
+		state.Add(entry.container())
+		snapshot[entry.Project] = state
 	}
-
-	return snapshot, nil
+	return nil
 }
 
 func (e *ComposeExecutor) prepareRepo(ctx context.Context, appDir, repoDir, repoURL, branch, commitHash string, deployKey []byte) (string, error) {
 	var repoLog strings.Builder
 	appendLogSection(&repoLog, "Repository sync")
 
-	gitEnv, cleanup, err := e.buildGitEnv(appDir, deployKey)
+	gitEnv, cleanup, err := e.buildGitEnv(appDir, repoURL, deployKey)
 	if err != nil {
 		appendLogLine(&repoLog, "failed to configure git auth environment")
 		appendLogLine(&repoLog, err.Error())
@@ -389,7 +737,10 @@ func (e *ComposeExecutor) prepareRepo(ctx context.Context, appDir, repoDir, repo
 	gitDir := filepath.Join(repoDir, ".git")
 	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
 		appendLogLine(&repoLog, "repository cache missing; cloning fresh copy")
-		cloneArgs := []string{"clone", "--branch", branch}
+		cloneArgs := []string{"clone"}
+		if branch != "" {
+			cloneArgs = append(cloneArgs, "--branch", branch)
+		}
 		if commitHash == "" {
 			cloneArgs = append(cloneArgs, "--depth", "1")
 		}
@@ -399,7 +750,12 @@ func (e *ComposeExecutor) prepareRepo(ctx context.Context, appDir, repoDir, repo
 			return strings.TrimSpace(repoLog.String()), err
 		}
 		if commitHash != "" {
-			_, err := e.runCommandWithTranscript(ctx, &repoLog, "git", []string{"checkout", commitHash}, repoDir, gitEnv, nil)
+			// Tagged commits need not be reachable from any branch.
+			_, err := e.runCommandWithTranscript(ctx, &repoLog, "git", []string{"fetch", "origin", commitHash}, repoDir, gitEnv, nil)
+			if err != nil {
+				return strings.TrimSpace(repoLog.String()), err
+			}
+			_, err = e.runCommandWithTranscript(ctx, &repoLog, "git", []string{"checkout", commitHash}, repoDir, gitEnv, nil)
 			if err != nil {
 				return strings.TrimSpace(repoLog.String()), err
 			}
@@ -504,7 +860,7 @@ func (e *ComposeExecutor) prepareEnvOverrides(appDir string, serviceEnvs map[str
 		}
 	}
 
-	overridePath := filepath.Join(envDir, "docker-compose.override.yml")
+	overridePath := envOverridePath(appDir)
 	if err := os.WriteFile(overridePath, []byte(override.String()), 0600); err != nil {
 		cleanup()
 		return nil, nil, fmt.Errorf("failed to write override file: %w", err)
@@ -520,12 +876,16 @@ func (e *ComposeExecutor) prepareEnvOverrides(appDir string, serviceEnvs map[str
 	return []string{"-f", overrideAbs}, cleanup, nil
 }
 
-func (e *ComposeExecutor) buildGitEnv(appDir string, deployKey []byte) (map[string]string, func(), error) {
+func envOverridePath(composeDir string) string {
+	return filepath.Join(composeDir, ".envs", "docker-compose.override.yml")
+}
+
+func (e *ComposeExecutor) buildGitEnv(appDir, repoURL string, deployKey []byte) (map[string]string, func(), error) {
 	if len(deployKey) == 0 {
 		return nil, func() {}, nil
 	}
 
-	knownHostsPath, err := repoauth.ResolveKnownHostsPath()
+	knownHostsPath, err := repoauth.ResolveKnownHostsPath(repoURL)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -670,6 +1030,13 @@ func (e *ComposeExecutor) runCommand(
 	callerEnv := env
 
 	if isDocker {
+		hostEnv, cleanup, err := endpointEnv(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer cleanup()
+		callerEnv = mergeCommandEnv(hostEnv, callerEnv)
+
 		resolution, err := e.resolveDockerCommand(ctx)
 		if err != nil {
 			return "", err
@@ -728,6 +1095,9 @@ func (e *ComposeExecutor) execProcess(
 	start := time.Now()
 	command := exec.CommandContext(ctx, cmd, args...)
 	command.Dir = workDir
+	// Args can carry registry passwords or tokens, and output can echo them.
+	secrets := redact.FromContext(ctx)
+	cmdLine := secrets.String(command.String())
 
 	if len(env) > 0 {
 		mergedEnv := append([]string{}, os.Environ()...)
@@ -739,7 +1109,7 @@ func (e *ComposeExecutor) execProcess(
 
 	e.Logger.Info(
 		"Executing command",
-		"cmd", command.String(),
+		"cmd", cmdLine,
 		"dir", workDir,
 		"env_keys", sortedKeys(env),
 	)
@@ -789,7 +1159,7 @@ func (e *ComposeExecutor) execProcess(
 	_ = pipeReader.Close()
 
 	if streamErr != nil && e.Logger != nil {
-		e.Logger.Warn("Command output stream failed", "cmd", command.String(), "error", streamErr)
+		e.Logger.Warn("Command output stream failed", "cmd", cmdLine, "error", streamErr)
 	}
 
 	outputMu.Lock()
@@ -797,7 +1167,7 @@ func (e *ComposeExecutor) execProcess(
 	outputMu.Unlock()
 	trimmed := strings.TrimSpace(output)
 	if trimmed != "" {
-		e.Logger.Info("Command output", "cmd", command.String(), "output", truncateOutput(trimmed))
+		e.Logger.Info("Command output", "cmd", cmdLine, "output", truncateOutput(secrets.String(trimmed)))
 	}
 
 	if waitErr != nil {
@@ -807,11 +1177,11 @@ func (e *ComposeExecutor) execProcess(
 			exitCode = exitErr.ExitCode()
 		}
 		if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(ctx.Err(), context.Canceled) {
-			e.Logger.Error("Command context ended", "cmd", command.String(), "context_err", ctx.Err())
+			e.Logger.Error("Command context ended", "cmd", cmdLine, "context_err", ctx.Err())
 		}
 		e.Logger.Error(
 			"Command failed",
-			"cmd", command.String(),
+			"cmd", cmdLine,
 			"dir", workDir,
 			"exit_code", exitCode,
 			"elapsed_ms", time.Since(start).Milliseconds(),
@@ -821,7 +1191,7 @@ func (e *ComposeExecutor) execProcess(
 
 	e.Logger.Info(
 		"Command succeeded",
-		"cmd", command.String(),
+		"cmd", cmdLine,
 		"elapsed_ms", time.Since(start).Milliseconds(),
 	)
 
@@ -935,12 +1305,17 @@ func composeProjectName(appID string) string {
 
 // ServiceContainer represents runtime details for one container in a compose project.
 type ServiceContainer struct {
+	ID      string
 	Service string
 	Name    string
 	Image   string
-	Status  string // "running" or "exited"
-	Health  string // "healthy", "unhealthy", "starting", or "" (no healthcheck)
-	Ports   string
+	// Status is docker's state: "running", "restarting", "paused",
+	// "created", "exited" or "dead".
+	Status       string
+	Health       string // "healthy", "unhealthy", "starting", or "" (no healthcheck)
+	ExitCode     int    // of an exited or dead container
+	RestartCount int    // how often docker restarted the container
+	Ports        string
 }
 
 // InspectProjectContainers returns detailed container information for a compose project.
@@ -948,60 +1323,14 @@ func (e *ComposeExecutor) InspectProjectContainers(ctx context.Context, projectN
 	if strings.TrimSpace(projectName) == "" {
 		return nil, nil
 	}
-
-	output, err := e.runCommand(
-		ctx,
-		"docker",
-		[]string{
-			"ps", "-a",
-			"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", projectName),
-			"--filter", "label=com.docker.compose.oneoff=False",
-			"--format", `{{.Label "com.docker.compose.service"}}|{{.Image}}|{{.Status}}|{{.Ports}}|{{.Names}}`,
-		},
-		e.runtimeWorkDir(),
-		nil,
-		nil,
-	)
+	ctx, err := e.appContext(ctx, projectName)
 	if err != nil {
-		return nil, fmt.Errorf("docker ps failed: %w", err)
+		return nil, err
 	}
 
-	var containers []ServiceContainer
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 5)
-		if len(parts) != 5 {
-			continue
-		}
-
-		rawStatus := strings.TrimSpace(parts[2])
-		health := ""
-		lowerStatus := strings.ToLower(rawStatus)
-		if strings.Contains(lowerStatus, "(healthy)") {
-			health = "healthy"
-		} else if strings.Contains(lowerStatus, "(unhealthy)") {
-			health = "unhealthy"
-		} else if strings.Contains(lowerStatus, "(health: starting)") {
-			health = "starting"
-		}
-
-		state := "exited"
-		if dockerStatusIsRunning(rawStatus) {
-			state = "running"
-		}
-
-		containers = append(containers, ServiceContainer{
-			Service: strings.TrimSpace(parts[0]),
-			Image:   strings.TrimSpace(parts[1]),
-			Status:  state,
-			Health:  health,
-			Ports:   strings.TrimSpace(parts[3]),
-			Name:    strings.TrimSpace(parts[4]),
-		})
+	containers, err := e.listProjectContainers(ctx, projectName)
+	if err != nil {
+		return nil, err
 	}
 
 	slices.SortFunc(containers, func(a, b ServiceContainer) int {
@@ -1016,10 +1345,6 @@ func ProjectNameForApp(appID string) string {
 	return composeProjectName(appID)
 }
 
-func dockerStatusIsRunning(status string) bool {
-	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(status)), "up ")
-}
-
 func startsWithAlphaNum(value string) bool {
 	if value == "" {
 		return false
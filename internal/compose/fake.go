@@ -0,0 +1,334 @@
+package compose
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FakeRuntimeEnv switches the controller to FakeRuntime when set to 1 or true.
+const FakeRuntimeEnv = "CONOPS_FAKE_RUNTIME"
+
+// FakeRuntime is an in-memory stand-in for ComposeExecutor. It checks out
+// nothing and starts no containers: every apply marks the app's services
+// running, so the watcher, reconciler, syncer and UI can be exercised in
+// integration tests and demos without a Docker daemon.
+type FakeRuntime struct {
+	Logger *slog.Logger
+	// Services are the services every applied app runs, "app" by default.
+	Services []string
+	// Fail, when set, is consulted on every apply; a non-nil error fails
+	// it after the services were started, like a failing up would.
+	Fail func(appID, commitHash string) error
+
+	mu       sync.Mutex
+	projects map[string][]ServiceContainer
+	applied  map[string][]string          // app ID -> applied commits, oldest first
+	digests  map[string]map[string]string // project -> service -> digest reference
+}
+
+// NewFakeRuntime creates an empty fake runtime.
+func NewFakeRuntime(logger *slog.Logger) *FakeRuntime {
+	return &FakeRuntime{
+		Logger:   logger,
+		Services: []string{"app"},
+		projects: make(map[string][]ServiceContainer),
+		applied:  make(map[string][]string),
+		digests:  make(map[string]map[string]string),
+	}
+}
+
+// Apply records the sync and starts the fake services of appID.
+func (f *FakeRuntime) Apply(ctx context.Context, opts ApplyOptions) (*Transcript, error) {
+	syncLog := NewTranscript(ctx)
+	syncLog.Step(StepPreflight)
+	emitProgress := func() {
+		if opts.OnProgress != nil {
+			opts.OnProgress(syncLog.String())
+		}
+	}
+
+	if strings.TrimSpace(opts.RepoURL) == "" {
+		appendLogSection(&syncLog.Builder, "Validation")
+		appendLogLine(&syncLog.Builder, "repo url is empty")
+		emitProgress()
+		return syncLog, fmt.Errorf("repo url is empty")
+	}
+	if strings.TrimSpace(opts.ComposePath) == "" {
+		appendLogSection(&syncLog.Builder, "Validation")
+		appendLogLine(&syncLog.Builder, "compose path is empty")
+		emitProgress()
+		return syncLog, fmt.Errorf("compose path is empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return syncLog, err
+	}
+
+	appendLogSection(&syncLog.Builder, "Sync started")
+	appendLogLine(&syncLog.Builder, fmt.Sprintf("app_id: %s", opts.AppID))
+	appendLogLine(&syncLog.Builder, fmt.Sprintf("repository: %s", opts.RepoURL))
+	if opts.CommitHash != "" {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("target_commit: %s", opts.CommitHash))
+	} else {
+		appendLogLine(&syncLog.Builder, "target_commit: latest on branch")
+	}
+	appendLogLine(&syncLog.Builder, "runtime: fake (nothing is checked out or started)")
+
+	projectName := composeProjectName(opts.AppID)
+	containers := make([]ServiceContainer, 0, len(f.Services))
+	for _, service := range f.Services {
+		containers = append(containers, ServiceContainer{
+			Service: service,
+			Name:    fmt.Sprintf("%s-%s-1", projectName, service),
+			Image:   "fake/" + service + ":latest",
+			Status:  "running",
+		})
+	}
+
+	syncLog.Step(StepComposeUp)
+	appendLogSection(&syncLog.Builder, "Compose apply")
+	f.mu.Lock()
+	// Unpinned "latest" tags resolve to a new digest on every apply, as if
+	// the registry moved them; pins keep the digest they name.
+	digests := make(map[string]string, len(containers))
+	for _, container := range containers {
+		digest, ok := opts.PinnedImages[container.Service]
+		if !ok {
+			sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", opts.AppID, container.Service, len(f.applied[opts.AppID]))))
+			digest = fmt.Sprintf("fake/%s@sha256:%x", container.Service, sum)
+		}
+		digests[container.Service] = digest
+	}
+	f.projects[projectName] = containers
+	f.digests[projectName] = digests
+	f.applied[opts.AppID] = append(f.applied[opts.AppID], opts.CommitHash)
+	f.mu.Unlock()
+	for _, container := range containers {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("started %s", container.Name))
+	}
+	for _, service := range sortedKeys(opts.PinnedImages) {
+		appendLogLine(&syncLog.Builder, fmt.Sprintf("pinned %s to %s", service, opts.PinnedImages[service]))
+	}
+	emitProgress()
+	if f.Logger != nil {
+		f.Logger.Info("Fake runtime applied app", "app_id", opts.AppID, "commit", opts.CommitHash, "services", len(containers))
+	}
+
+	if f.Fail != nil {
+		if err := f.Fail(opts.AppID, opts.CommitHash); err != nil {
+			appendLogLine(&syncLog.Builder, "ERROR: "+err.Error())
+			emitProgress()
+			return syncLog, &RolloutError{Err: fmt.Errorf("up failed: %w", err)}
+		}
+	}
+
+	appendLogSection(&syncLog.Builder, "Sync completed")
+	appendLogLine(&syncLog.Builder, "application reconciled successfully")
+	emitProgress()
+	return syncLog, nil
+}
+
+// Destroy removes the fake services of appID.
+func (f *FakeRuntime) Destroy(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error) {
+	f.mu.Lock()
+	delete(f.projects, composeProjectName(appID))
+	delete(f.digests, composeProjectName(appID))
+	f.mu.Unlock()
+	return "fake runtime: project removed", nil
+}
+
+// DestroyVolumes is Destroy; the fake has no volumes.
+func (f *FakeRuntime) DestroyVolumes(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error) {
+	return f.Destroy(ctx, appID, composePath, envVars)
+}
+
+// Purge is Destroy; the fake has no volumes or images.
+func (f *FakeRuntime) Purge(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error) {
+	return f.Destroy(ctx, appID, composePath, envVars)
+}
+
+// Plan reports the plan a ComposeExecutor with default settings would run.
+func (f *FakeRuntime) Plan(appID, composePath string, profiles []string, envFile string, serviceEnvs map[string]string) (Plan, error) {
+	return NewComposeExecutor(f.Logger).Plan(appID, composePath, profiles, envFile, serviceEnvs)
+}
+
+// RenderConfig returns a minimal configuration naming the fake services
+// and commitHash, so diffs between commits are never empty.
+func (f *FakeRuntime) RenderConfig(ctx context.Context, appID, repoURL, branch, composePath string, profiles []string, envFile, commitHash string, deployKey []byte) (string, error) {
+	if strings.TrimSpace(commitHash) == "" {
+		return "", fmt.Errorf("commit is required")
+	}
+	var config strings.Builder
+	fmt.Fprintf(&config, "name: %s\nservices:\n", composeProjectName(appID))
+	for _, service := range f.Services {
+		fmt.Fprintf(&config, "  %s:\n    image: fake/%s:latest\n    labels:\n      conops.commit: %s\n", service, service, commitHash)
+	}
+	return config.String(), nil
+}
+
+// SnapshotProjects summarizes the fake projects like docker ps would.
+func (f *FakeRuntime) SnapshotProjects(ctx context.Context) (map[string]ProjectRuntimeState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snapshot := make(map[string]ProjectRuntimeState, len(f.projects))
+	for projectName, containers := range f.projects {
+		var state ProjectRuntimeState
+		for _, container := range containers {
+			state.Add(container)
+		}
+		snapshot[projectName] = state
+	}
+	return snapshot, nil
+}
+
+// InspectProjectContainers returns the fake containers of projectName.
+func (f *FakeRuntime) InspectProjectContainers(ctx context.Context, projectName string) ([]ServiceContainer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.projects[projectName]), nil
+}
+
+// ServiceGraph lists the fake services without dependencies.
+func (f *FakeRuntime) ServiceGraph(ctx context.Context, appID, composePath string, profiles []string) ([]ServiceNode, error) {
+	nodes := make([]ServiceNode, 0, len(f.Services))
+	for _, service := range f.Services {
+		nodes = append(nodes, ServiceNode{Service: service})
+	}
+	return nodes, nil
+}
+
+// SetContainerState changes one fake service of appID, e.g. to "exited" or
+// health "unhealthy", to simulate drift. It reports whether the service
+// exists.
+func (f *FakeRuntime) SetContainerState(appID, service, status, health string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	containers := f.projects[composeProjectName(appID)]
+	for i := range containers {
+		if containers[i].Service == service {
+			containers[i].Status = status
+			containers[i].Health = health
+			return true
+		}
+	}
+	return false
+}
+
+// RestartService marks the fake containers of service running again.
+func (f *FakeRuntime) RestartService(ctx context.Context, appID, composePath string, profiles []string, service string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	containers := f.projects[composeProjectName(appID)]
+	restarted := false
+	for i := range containers {
+		if containers[i].Service == service {
+			containers[i].Status = "running"
+			containers[i].Health = ""
+			restarted = true
+		}
+	}
+	if !restarted {
+		return "", fmt.Errorf("no such service: %s", service)
+	}
+	if f.Logger != nil {
+		f.Logger.Info("Fake runtime restarted service", "app_id", appID, "service", service)
+	}
+	return fmt.Sprintf("fake runtime: restarted %s", service), nil
+}
+
+// StreamServiceLogs writes a line per commit applied to appID, as the
+// service would log them on startup. With follow it then waits for ctx.
+func (f *FakeRuntime) StreamServiceLogs(ctx context.Context, appID, composePath string, profiles []string, service string, tail int, follow bool, w io.Writer) error {
+	f.mu.Lock()
+	found := slices.ContainsFunc(f.projects[composeProjectName(appID)], func(c ServiceContainer) bool { return c.Service == service })
+	commits := slices.Clone(f.applied[appID])
+	f.mu.Unlock()
+	if !found {
+		return fmt.Errorf("no such service: %s", service)
+	}
+
+	if tail >= 0 && len(commits) > tail {
+		commits = commits[len(commits)-tail:]
+	}
+	prefix := fmt.Sprintf("%s-%s-1  | ", composeProjectName(appID), service)
+	for _, commit := range commits {
+		if _, err := fmt.Fprintf(w, "%sfake runtime: started at commit %s\n", prefix, commit); err != nil {
+			return err
+		}
+	}
+	if follow {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+// ProjectStats reports fixed usage for every running fake container.
+func (f *FakeRuntime) ProjectStats(ctx context.Context, projectName string) ([]ServiceStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var stats []ServiceStats
+	for _, container := range f.projects[projectName] {
+		entry := ServiceStats{Service: container.Service, Name: container.Name, Running: container.Status == "running"}
+		if entry.Running {
+			entry.CPUPercent = 0.5
+			entry.MemoryBytes = 32 << 20
+			entry.MemoryLimitBytes = 1 << 30
+			entry.MemoryPercent = 3.125
+		}
+		stats = append(stats, entry)
+	}
+	return stats, nil
+}
+
+// Preflight always passes; the fake runtime needs no docker.
+func (f *FakeRuntime) Preflight(ctx context.Context) error {
+	return nil
+}
+
+// PruneImages removes nothing; fake services run no images.
+func (f *FakeRuntime) PruneImages(ctx context.Context, all bool, filters []string) (string, error) {
+	if f.Logger != nil {
+		f.Logger.Info("Fake runtime pruned images", "all", all, "filters", filters)
+	}
+	return "Total reclaimed space: 0B", nil
+}
+
+// ImageDigests returns the digests the fake services of appID were last
+// applied with.
+func (f *FakeRuntime) ImageDigests(ctx context.Context, appID string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.digests[composeProjectName(appID)]), nil
+}
+
+// ComposeDigest derives a digest from composePath and the commit last
+// applied for appID.
+func (f *FakeRuntime) ComposeDigest(appID, composePath string) (string, error) {
+	f.mu.Lock()
+	applied := f.applied[appID]
+	f.mu.Unlock()
+	if len(applied) == 0 {
+		return "", fmt.Errorf("app %s was never applied", appID)
+	}
+	sum := sha256.Sum256([]byte(composePath + "@" + applied[len(applied)-1]))
+	return fmt.Sprintf("%x", sum), nil
+}
+
+// Applied returns the commits applied for appID, oldest first. An empty
+// commit means the branch head was requested.
+func (f *FakeRuntime) Applied(appID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.applied[appID])
+}
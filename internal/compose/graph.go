@@ -0,0 +1,104 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ServiceNode is one service of a compose project and the services it waits
+// for on startup.
+type ServiceNode struct {
+	Service   string
+	DependsOn []ServiceDependency
+	Networks  []string
+}
+
+// ServiceDependency is one depends_on entry.
+type ServiceDependency struct {
+	Service   string
+	Condition string // "service_started", "service_healthy" or "service_completed_successfully"
+	Required  bool
+}
+
+// ServiceGraph reads the dependency graph of an app's checked-out compose
+// project from the rendered compose config. It returns nil when the app has
+// not been synced yet.
+func (e *ComposeExecutor) ServiceGraph(ctx context.Context, appID, composePath string, profiles []string) ([]ServiceNode, error) {
+	appDirAbs, err := filepath.Abs(filepath.Join(e.WorkDir, appID))
+	if err != nil {
+		return nil, fmt.Errorf("resolve app dir failed: %w", err)
+	}
+	if strings.TrimSpace(composePath) == "" {
+		composePath = "compose.yaml"
+	}
+	composeFullPath := filepath.Join(appDirAbs, "repo", composePath)
+	if info, err := os.Stat(composeFullPath); err != nil || info.IsDir() {
+		return nil, nil
+	}
+
+	args := []string{"compose", "-p", composeProjectName(appID), "-f", filepath.Base(composeFullPath)}
+	args = append(args, profileArgs(profiles)...)
+	// Interpolation is skipped so variables that are only set at sync time
+	// cannot fail the render; they never affect the graph.
+	args = append(args, "config", "--format", "json", "--no-interpolate")
+	output, err := e.runCommand(ctx, "docker", args, filepath.Dir(composeFullPath), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("docker compose config failed: %w", err)
+	}
+	return parseServiceGraph(output)
+}
+
+// parseServiceGraph extracts services from `docker compose config --format
+// json` output. Compose may print warnings around the document.
+func parseServiceGraph(output string) ([]ServiceNode, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("compose config returned no JSON document")
+	}
+
+	var config struct {
+		Services map[string]struct {
+			DependsOn map[string]struct {
+				Condition string `json:"condition"`
+				Required  *bool  `json:"required"`
+			} `json:"depends_on"`
+			Networks map[string]json.RawMessage `json:"networks"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &config); err != nil {
+		return nil, fmt.Errorf("invalid compose config: %w", err)
+	}
+
+	nodes := make([]ServiceNode, 0, len(config.Services))
+	for name, service := range config.Services {
+		node := ServiceNode{Service: name}
+		for dependency, options := range service.DependsOn {
+			condition := options.Condition
+			if condition == "" {
+				condition = "service_started"
+			}
+			node.DependsOn = append(node.DependsOn, ServiceDependency{
+				Service:   dependency,
+				Condition: condition,
+				Required:  options.Required == nil || *options.Required,
+			})
+		}
+		slices.SortFunc(node.DependsOn, func(a, b ServiceDependency) int {
+			return strings.Compare(a.Service, b.Service)
+		})
+		for network := range service.Networks {
+			node.Networks = append(node.Networks, network)
+		}
+		slices.Sort(node.Networks)
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b ServiceNode) int {
+		return strings.Compare(a.Service, b.Service)
+	})
+	return nodes, nil
+}
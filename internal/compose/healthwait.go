@@ -0,0 +1,102 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HealthTimeoutEnv sets how long Apply waits after up for services with a
+// healthcheck to report healthy.
+const HealthTimeoutEnv = "CONOPS_HEALTH_TIMEOUT"
+
+// healthPollInterval is how often container health is read while waiting.
+const healthPollInterval = 2 * time.Second
+
+// LoadHealthTimeoutFromEnv returns the health wait timeout, 2m by default.
+// Zero disables the wait.
+func LoadHealthTimeoutFromEnv() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(HealthTimeoutEnv))
+	if value == "" {
+		return 2 * time.Minute, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", HealthTimeoutEnv, value)
+	}
+	return timeout, nil
+}
+
+// waitHealthy polls the project's containers until every container that
+// has a healthcheck reports healthy. It fails as soon as one turns unhealthy
+// or exits, and when the timeout runs out. Containers without a healthcheck
+// are not waited for.
+func (e *ComposeExecutor) waitHealthy(ctx context.Context, syncLog *strings.Builder, projectName string, timeout time.Duration, emitProgress func()) error {
+	appendLogSection(syncLog, "Health wait")
+	started := time.Now()
+	deadline := started.Add(timeout)
+	// Names of containers seen with a healthcheck; an exited container no
+	// longer shows its health, so it is recognised by name.
+	var checked []string
+	reported := make(map[string]string)
+
+	for {
+		containers, err := e.InspectProjectContainers(ctx, projectName)
+		if err != nil {
+			appendLogLine(syncLog, err.Error())
+			emitProgress()
+			return err
+		}
+
+		var waiting []string
+		for _, container := range containers {
+			if container.Health != "" && !slices.Contains(checked, container.Name) {
+				checked = append(checked, container.Name)
+			}
+			if !slices.Contains(checked, container.Name) {
+				continue
+			}
+			state := container.Health
+			if container.Status != "running" {
+				state = container.Status
+			}
+			if reported[container.Name] != state {
+				reported[container.Name] = state
+				appendLogLine(syncLog, fmt.Sprintf("%s (%s): %s", container.Service, container.Name, state))
+				emitProgress()
+			}
+			switch state {
+			case "healthy":
+			case "unhealthy", ContainerRestarting, ContainerExited, ContainerDead:
+				return fmt.Errorf("service %s is %s", container.Service, state)
+			default:
+				waiting = append(waiting, container.Service)
+			}
+		}
+
+		if len(checked) == 0 {
+			appendLogLine(syncLog, "no running service defines a healthcheck")
+			emitProgress()
+			return nil
+		}
+		if len(waiting) == 0 {
+			appendLogLine(syncLog, fmt.Sprintf("%d healthchecked container(s) healthy after %s", len(checked), time.Since(started).Round(time.Second)))
+			emitProgress()
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			appendLogLine(syncLog, fmt.Sprintf("timed out after %s", timeout))
+			emitProgress()
+			return fmt.Errorf("services not healthy after %s: %s", timeout, strings.Join(waiting, ", "))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PruneImages runs `docker image prune`. Without all only dangling images
+// are removed, with it every image no container uses. Filters are passed on
+// as --filter values, e.g. "until=72h" or "label!=keep".
+func (e *ComposeExecutor) PruneImages(ctx context.Context, all bool, filters []string) (string, error) {
+	args := []string{"image", "prune", "-f"}
+	if all {
+		args = append(args, "-a")
+	}
+	for _, filter := range filters {
+		args = append(args, "--filter", filter)
+	}
+	output, err := e.runCommand(ctx, "docker", args, e.runtimeWorkDir(), nil, nil)
+	if err != nil {
+		return output, fmt.Errorf("docker image prune failed: %w: %s", err, truncateOutput(strings.TrimSpace(output)))
+	}
+	return output, nil
+}
+
+// ReclaimedSpace extracts the "Total reclaimed space" docker reports after
+// a prune, or "" when it printed none.
+func ReclaimedSpace(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "Total reclaimed space:"); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// StreamServiceLogs copies the output of `docker compose logs` for one
+// service of an app's project to w as it arrives. A negative tail prints
+// the whole log; with follow it keeps streaming until ctx is cancelled.
+func (e *ComposeExecutor) StreamServiceLogs(ctx context.Context, appID, composePath string, profiles []string, service string, tail int, follow bool, w io.Writer) error {
+	composeFullPath, err := e.checkoutComposeFile(appID, composePath)
+	if err != nil {
+		return err
+	}
+	ctx, err = e.appContext(ctx, appID)
+	if err != nil {
+		return err
+	}
+	hostEnv, cleanup, err := endpointEnv(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	resolution, err := e.resolveDockerCommand(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"compose", "-p", composeProjectName(appID), "-f", filepath.Base(composeFullPath)}
+	args = append(args, profileArgs(profiles)...)
+	args = append(args, "logs", "--no-color", "--tail", tailArg(tail))
+	if follow {
+		args = append(args, "--follow")
+	}
+	args = append(args, service)
+
+	// The output is streamed rather than collected with runCommand, which
+	// would hold a followed log in memory for as long as it is watched.
+	command := exec.CommandContext(ctx, resolution.Path, args...)
+	command.Dir = filepath.Dir(composeFullPath)
+	if env := mergeCommandEnv(hostEnv, resolution.Env); len(env) > 0 {
+		command.Env = os.Environ()
+		for key, value := range env {
+			command.Env = append(command.Env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	command.Stdout = w
+	command.Stderr = w
+	e.Logger.Debug("Streaming service logs", "cmd", command.String(), "dir", command.Dir)
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("docker compose logs failed: %w", err)
+	}
+	return nil
+}
+
+func tailArg(tail int) string {
+	if tail < 0 {
+		return "all"
+	}
+	return strconv.Itoa(tail)
+}
+
+// checkoutComposeFile returns the absolute path of the compose file in an
+// app's checkout, or an error when the app has not been synced yet.
+func (e *ComposeExecutor) checkoutComposeFile(appID, composePath string) (string, error) {
+	appDirAbs, err := filepath.Abs(filepath.Join(e.WorkDir, appID))
+	if err != nil {
+		return "", fmt.Errorf("resolve app dir failed: %w", err)
+	}
+	if strings.TrimSpace(composePath) == "" {
+		composePath = "compose.yaml"
+	}
+	composeFullPath := filepath.Join(appDirAbs, "repo", composePath)
+	if info, err := os.Stat(composeFullPath); err != nil || info.IsDir() {
+		return "", fmt.Errorf("app has no checkout; sync it first")
+	}
+	return composeFullPath, nil
+}
@@ -0,0 +1,176 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// ImageDigests returns the digest reference, e.g. "nginx@sha256:...", of
+// the image each service of the app's project runs. Services built locally
+// have no registry digest and are left out.
+func (e *ComposeExecutor) ImageDigests(ctx context.Context, appID string) (map[string]string, error) {
+	ctx, err := e.appContext(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	projectName := composeProjectName(appID)
+	cli, err := e.engineClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cli != nil {
+		summaries, err := engineContainers(ctx, cli, projectName)
+		if err != nil {
+			return nil, fmt.Errorf("list containers failed: %w", err)
+		}
+		images := make(map[string]string, len(summaries)) // service -> image
+		for _, summary := range summaries {
+			if service := summary.Labels["com.docker.compose.service"]; service != "" {
+				images[service] = summary.Image
+			}
+		}
+		return e.resolveDigests(ctx, cli, images), nil
+	}
+
+	output, err := e.runCommand(
+		ctx,
+		"docker",
+		[]string{
+			"ps", "-a",
+			"--filter", "label=com.docker.compose.project=" + projectName,
+			"--format", `{{.Label "com.docker.compose.service"}}` + "\t{{.Image}}",
+		},
+		e.runtimeWorkDir(),
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list containers failed: %w: %s", err, truncateOutput(strings.TrimSpace(output)))
+	}
+
+	images := make(map[string]string) // service -> image
+	for _, line := range strings.Split(output, "\n") {
+		service, image, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if ok && service != "" && image != "" {
+			images[service] = image
+		}
+	}
+	return e.resolveDigests(ctx, nil, images), nil
+}
+
+// resolveDigests maps each service to the digest reference of its image,
+// asking cli, or the docker CLI when it is nil, once per image.
+func (e *ComposeExecutor) resolveDigests(ctx context.Context, cli *client.Client, images map[string]string) map[string]string {
+	digests := make(map[string]string)
+	resolved := make(map[string]string) // image reference -> digest reference
+	for service, image := range images {
+		digest, seen := resolved[image]
+		if !seen {
+			digest = e.repoDigest(ctx, cli, image)
+			resolved[image] = digest
+		}
+		if digest != "" {
+			digests[service] = digest
+		}
+	}
+	return digests
+}
+
+// repoDigest resolves image to the registry digest it was pulled by, or ""
+// when it has none.
+func (e *ComposeExecutor) repoDigest(ctx context.Context, cli *client.Client, image string) string {
+	if strings.Contains(image, "@sha256:") {
+		return image
+	}
+	var digests []string
+	if cli != nil {
+		inspect, err := cli.ImageInspect(ctx, image)
+		if err != nil {
+			return ""
+		}
+		digests = inspect.RepoDigests
+	} else {
+		output, err := e.runCommand(
+			ctx,
+			"docker",
+			[]string{"image", "inspect", "--format", `{{join .RepoDigests "\n"}}`, image},
+			e.runtimeWorkDir(),
+			nil,
+			nil,
+		)
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if line = strings.TrimSpace(line); strings.Contains(line, "@sha256:") {
+				digests = append(digests, line)
+			}
+		}
+	}
+	// An image pulled under several names lists a digest for each; prefer
+	// the one for the repository the service names.
+	repository := imageRepository(image)
+	for _, digest := range digests {
+		name, _, _ := strings.Cut(digest, "@")
+		if name == repository || "docker.io/library/"+name == repository || "docker.io/"+name == repository {
+			return digest
+		}
+	}
+	if len(digests) == 1 {
+		return digests[0]
+	}
+	return ""
+}
+
+// imageRepository strips the tag from an image reference. A colon before
+// the last slash belongs to a registry port, not a tag.
+func imageRepository(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// writePinOverride writes a compose override setting each service's image
+// to its pinned digest and returns the arguments that apply it.
+func writePinOverride(composeDir string, pinned map[string]string) ([]string, func(), error) {
+	if len(pinned) == 0 {
+		return nil, func() {}, nil
+	}
+
+	var override strings.Builder
+	override.WriteString("services:\n")
+	for _, service := range sortedKeys(pinned) {
+		override.WriteString(fmt.Sprintf("  %s:\n", service))
+		override.WriteString(fmt.Sprintf("    image: \"%s\"\n", strings.ReplaceAll(pinned[service], "\"", "")))
+	}
+
+	overrideDir := filepath.Join(composeDir, ".envs")
+	if err := os.MkdirAll(overrideDir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create override dir: %w", err)
+	}
+	overridePath := pinOverridePath(composeDir)
+	if err := os.WriteFile(overridePath, []byte(override.String()), 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to write image pin override: %w", err)
+	}
+	cleanup := func() {
+		_ = os.Remove(overridePath)
+		_ = os.Remove(overrideDir) // remove dir if empty
+	}
+
+	overrideAbs, err := filepath.Abs(overridePath)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return []string{"-f", overrideAbs}, cleanup, nil
+}
+
+func pinOverridePath(composeDir string) string {
+	return filepath.Join(composeDir, ".envs", "images.override.yml")
+}
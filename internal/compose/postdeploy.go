@@ -0,0 +1,44 @@
+package compose
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// serviceNamePattern is the service name syntax compose accepts.
+var serviceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ParsePostDeploy splits a post-deploy entry written "service: command".
+func ParsePostDeploy(entry string) (service, command string, err error) {
+	service, command, ok := strings.Cut(entry, ":")
+	service, command = strings.TrimSpace(service), strings.TrimSpace(command)
+	if !ok || command == "" {
+		return "", "", fmt.Errorf("invalid post-deploy command %q: want \"service: command\"", entry)
+	}
+	if !serviceNamePattern.MatchString(service) {
+		return "", "", fmt.Errorf("invalid post-deploy command %q: %q is not a service name", entry, service)
+	}
+	return service, command, nil
+}
+
+// postDeployArgs is the compose subcommand running command in service. The
+// command goes through the container's sh so it can use pipes and &&.
+func postDeployArgs(service, command string) []string {
+	return []string{"exec", "-T", service, "sh", "-c", command}
+}
+
+// PostDeployCommands reports the commands Apply runs after up for entries.
+// Entries that do not parse are skipped; the registry rejects them.
+func (p Plan) PostDeployCommands(entries []string) [][]string {
+	var commands [][]string
+	for _, entry := range entries {
+		service, command, err := ParsePostDeploy(entry)
+		if err != nil {
+			continue
+		}
+		args := composeArgs(p.ProjectName, p.ComposeFiles[0], p.overrideArgs, postDeployArgs(service, command)...)
+		commands = append(commands, append([]string{"docker"}, args...))
+	}
+	return commands
+}
@@ -0,0 +1,97 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// preDeployHTTPTimeout bounds one HTTP pre-deploy check.
+const preDeployHTTPTimeout = 10 * time.Second
+
+// PreDeployCheck is one validation run between pull and up: either a
+// command in a one-off service container or an HTTP GET that must succeed.
+type PreDeployCheck struct {
+	Service string
+	Command string
+	URL     string
+}
+
+func (c PreDeployCheck) String() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return c.Service + ": " + c.Command
+}
+
+// ParsePreDeploy parses a pre-deploy entry: an http(s) URL, or a command
+// written "service: command" like a post-deploy command.
+func ParsePreDeploy(entry string) (PreDeployCheck, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(entry, "http://") || strings.HasPrefix(entry, "https://") {
+		parsed, err := url.Parse(entry)
+		if err != nil || parsed.Host == "" {
+			return PreDeployCheck{}, fmt.Errorf("invalid pre-deploy check %q: not a valid URL", entry)
+		}
+		return PreDeployCheck{URL: parsed.String()}, nil
+	}
+	if scheme, _, ok := strings.Cut(entry, "://"); ok && !strings.ContainsAny(scheme, " \t") {
+		return PreDeployCheck{}, fmt.Errorf("invalid pre-deploy check %q: only http and https URLs are supported", entry)
+	}
+	service, command, err := ParsePostDeploy(entry)
+	if err != nil {
+		return PreDeployCheck{}, fmt.Errorf("invalid pre-deploy check %q: want a URL or \"service: command\"", entry)
+	}
+	return PreDeployCheck{Service: service, Command: command}, nil
+}
+
+// preDeployArgs is the compose subcommand running command in a throwaway
+// container of service, built from the images just pulled. Dependencies are
+// not started and the running stack is left alone.
+func preDeployArgs(service, command string) []string {
+	return []string{"run", "--rm", "--no-deps", "-T", "--entrypoint", "sh", service, "-c", command}
+}
+
+// PreDeployCommands reports the checks Apply runs between pull and up for
+// entries; HTTP checks are listed as {"GET", url}.
+func (p Plan) PreDeployCommands(entries []string) [][]string {
+	var commands [][]string
+	for _, entry := range entries {
+		check, err := ParsePreDeploy(entry)
+		if err != nil {
+			continue
+		}
+		if check.URL != "" {
+			commands = append(commands, []string{"GET", check.URL})
+			continue
+		}
+		args := composeArgs(p.ProjectName, p.ComposeFiles[0], p.overrideArgs, preDeployArgs(check.Service, check.Command)...)
+		commands = append(commands, append([]string{"docker"}, args...))
+	}
+	return commands
+}
+
+// checkURL issues a GET and fails unless it returns 2xx.
+func checkURL(ctx context.Context, target string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, preDeployHTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return strings.TrimSpace(string(body)), fmt.Errorf("returned %s", resp.Status)
+	}
+	return resp.Status, nil
+}
@@ -0,0 +1,24 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ComposeDigest returns the hex SHA-256 of the compose file in the app's
+// checkout, i.e. the file the last apply deployed.
+func (e *ComposeExecutor) ComposeDigest(appID, composePath string) (string, error) {
+	appDirAbs, err := filepath.Abs(filepath.Join(e.WorkDir, appID))
+	if err != nil {
+		return "", fmt.Errorf("resolve app dir failed: %w", err)
+	}
+	content, err := os.ReadFile(filepath.Join(appDirAbs, "repo", composePath))
+	if err != nil {
+		return "", fmt.Errorf("read compose file failed: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
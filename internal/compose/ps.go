@@ -0,0 +1,315 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Container states as docker reports them.
+const (
+	ContainerRunning    = "running"
+	ContainerRestarting = "restarting"
+	ContainerPaused     = "paused"
+	ContainerCreated    = "created"
+	ContainerExited     = "exited"
+	ContainerDead       = "dead"
+)
+
+// Add counts container into the project's state. Paused containers are
+// neither running nor exited.
+func (s *ProjectRuntimeState) Add(container ServiceContainer) {
+	s.ContainerCount++
+	switch container.Status {
+	case ContainerRunning:
+		s.RunningCount++
+	case ContainerRestarting:
+		s.RestartingCount++
+	case ContainerExited, ContainerDead, ContainerCreated:
+		s.ExitedCount++
+	}
+	if container.Health == "unhealthy" {
+		s.UnhealthyCount++
+	}
+}
+
+// composePSEntry is one container in the output of `docker compose ps
+// --format json`.
+type composePSEntry struct {
+	ID         string
+	Name       string
+	Image      string
+	Service    string
+	State      string
+	Status     string
+	Health     string
+	ExitCode   int
+	Labels     string
+	Ports      string
+	Publishers []composePublisher
+}
+
+// composePublisher is a port of a container in `docker compose ps`.
+type composePublisher struct {
+	URL           string
+	TargetPort    int
+	PublishedPort int
+	Protocol      string
+}
+
+// dockerPSFormat makes `docker ps` print a JSON object per container. The
+// template quotes every field, so names and labels cannot break the parse
+// the way a delimited format could.
+const dockerPSFormat = `{"ID":{{json .ID}},"Names":{{json .Names}},"Image":{{json .Image}},` +
+	`"Project":{{json (.Label "com.docker.compose.project")}},"Service":{{json (.Label "com.docker.compose.service")}},` +
+	`"OneOff":{{json (.Label "com.docker.compose.oneoff")}},"State":{{json .State}},"Status":{{json .Status}},"Ports":{{json .Ports}}}`
+
+// dockerPSEntry is one container printed with dockerPSFormat.
+type dockerPSEntry struct {
+	ID      string
+	Names   string
+	Image   string
+	Project string
+	Service string
+	OneOff  string
+	State   string
+	Status  string
+	Ports   string
+}
+
+// container converts the entry; docker ps has no health or exit code
+// fields, so they are read from the status text.
+func (p dockerPSEntry) container() ServiceContainer {
+	return ServiceContainer{
+		ID:       p.ID,
+		Service:  p.Service,
+		Name:     p.Names,
+		Image:    p.Image,
+		Status:   dockerState(p.State, p.Status),
+		Health:   healthFromStatus(p.Status),
+		ExitCode: exitCodeFromStatus(p.Status),
+		Ports:    p.Ports,
+	}
+}
+
+// listProjectContainers lists a project's containers over the Engine API.
+// Daemons only the CLI reaches are listed with `docker compose ps`, falling
+// back to `docker ps` for compose versions without JSON output.
+func (e *ComposeExecutor) listProjectContainers(ctx context.Context, projectName string) ([]ServiceContainer, error) {
+	cli, err := e.engineClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cli != nil {
+		return engineProjectContainers(ctx, cli, projectName)
+	}
+
+	output, err := e.runCommand(
+		ctx,
+		"docker",
+		[]string{"compose", "-p", projectName, "ps", "-a", "--format", "json"},
+		e.runtimeWorkDir(),
+		nil,
+		nil,
+	)
+	if err == nil {
+		if containers, err := parseComposePS(output); err == nil {
+			e.addRestartCounts(ctx, containers)
+			return containers, nil
+		}
+	}
+
+	output, err = e.runCommand(
+		ctx,
+		"docker",
+		[]string{
+			"ps", "-a",
+			"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", projectName),
+			"--filter", "label=com.docker.compose.oneoff=False",
+			"--format", dockerPSFormat,
+		},
+		e.runtimeWorkDir(),
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("docker ps failed: %w", err)
+	}
+	entries, err := parseDockerPS(output)
+	if err != nil {
+		return nil, err
+	}
+	containers := make([]ServiceContainer, 0, len(entries))
+	for _, entry := range entries {
+		containers = append(containers, entry.container())
+	}
+	e.addRestartCounts(ctx, containers)
+	return containers, nil
+}
+
+// parseComposePS parses `docker compose ps --format json`, which is a JSON
+// array before compose 2.21 and a JSON object per line since. One-off
+// containers of `compose run` are left out.
+func parseComposePS(output string) ([]ServiceContainer, error) {
+	var entries []composePSEntry
+	trimmed := strings.TrimSpace(output)
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal([]byte(trimmed), &entries); err != nil {
+			return nil, fmt.Errorf("parse compose ps output: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		for {
+			var entry composePSEntry
+			if err := decoder.Decode(&entry); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("parse compose ps output: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	containers := make([]ServiceContainer, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry.Labels, "com.docker.compose.oneoff=True") {
+			continue
+		}
+		ports := entry.Ports
+		if ports == "" {
+			ports = formatPublishers(entry.Publishers)
+		}
+		container := ServiceContainer{
+			ID:      entry.ID,
+			Service: entry.Service,
+			Name:    entry.Name,
+			Image:   entry.Image,
+			Status:  dockerState(entry.State, entry.Status),
+			Health:  entry.Health,
+			Ports:   ports,
+		}
+		if container.Status == ContainerExited || container.Status == ContainerDead {
+			container.ExitCode = entry.ExitCode
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// parseDockerPS parses `docker ps --format dockerPSFormat`.
+func parseDockerPS(output string) ([]dockerPSEntry, error) {
+	var entries []dockerPSEntry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var entry dockerPSEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("parse docker ps output: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// addRestartCounts fills in how often docker restarted each container. The
+// counts are informational, so a container that is gone by now is skipped.
+func (e *ComposeExecutor) addRestartCounts(ctx context.Context, containers []ServiceContainer) {
+	var ids []string
+	for _, container := range containers {
+		if container.ID != "" {
+			ids = append(ids, container.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	args := append([]string{"inspect", "--format", "{{.Id}} {{.RestartCount}}"}, ids...)
+	output, _ := e.runCommand(ctx, "docker", args, e.runtimeWorkDir(), nil, nil)
+	counts := make(map[string]int)
+	for _, line := range strings.Split(output, "\n") {
+		id, count, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if restarts, err := strconv.Atoi(count); err == nil {
+			counts[id] = restarts
+		}
+	}
+	for i := range containers {
+		for id, restarts := range counts {
+			// compose ps prints short IDs, docker inspect full ones.
+			if containers[i].ID != "" && strings.HasPrefix(id, containers[i].ID) {
+				containers[i].RestartCount = restarts
+			}
+		}
+	}
+}
+
+// dockerState returns docker's state of a container, or derives it from the
+// status text for daemons that do not report it.
+func dockerState(state, status string) string {
+	if state = strings.ToLower(strings.TrimSpace(state)); state != "" {
+		return state
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch {
+	case strings.HasPrefix(status, "up ") && strings.Contains(status, "(paused)"):
+		return ContainerPaused
+	case strings.HasPrefix(status, "up "):
+		return ContainerRunning
+	case strings.HasPrefix(status, "restarting"):
+		return ContainerRestarting
+	case strings.HasPrefix(status, "created"):
+		return ContainerCreated
+	case strings.HasPrefix(status, "dead"):
+		return ContainerDead
+	default:
+		return ContainerExited
+	}
+}
+
+// healthFromStatus reads the health docker appends to a container's status.
+func healthFromStatus(status string) string {
+	status = strings.ToLower(status)
+	switch {
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "(health: starting)"):
+		return "starting"
+	}
+	return ""
+}
+
+// exitCodeFromStatus reads the code of a status such as "Exited (137) 2
+// minutes ago".
+func exitCodeFromStatus(status string) int {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(status), "Exited (")
+	if !ok {
+		return 0
+	}
+	code, _, _ := strings.Cut(rest, ")")
+	exitCode, _ := strconv.Atoi(code)
+	return exitCode
+}
+
+// formatPublishers formats structured ports like docker ps does.
+func formatPublishers(publishers []composePublisher) string {
+	var ports []string
+	for _, publisher := range publishers {
+		target := fmt.Sprintf("%d/%s", publisher.TargetPort, publisher.Protocol)
+		if publisher.PublishedPort == 0 {
+			ports = append(ports, target)
+			continue
+		}
+		ports = append(ports, fmt.Sprintf("%s:%d->%s", publisher.URL, publisher.PublishedPort, target))
+	}
+	return strings.Join(slices.Compact(ports), ", ")
+}
@@ -0,0 +1,101 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DestroyVolumes destroys an app's stack like Destroy and also removes the
+// volumes of its project. Volumes compose did not remove, e.g. because the
+// checkout is gone, are found by their project label; external volumes are
+// never touched.
+func (e *ComposeExecutor) DestroyVolumes(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error) {
+	ctx, err := e.appContext(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+	output, err := e.destroy(ctx, appID, composePath, envVars, true)
+	if err != nil {
+		return output, err
+	}
+	outputs := []string{output}
+
+	workDir := e.runtimeWorkDir()
+	projectFilter := fmt.Sprintf("label=com.docker.compose.project=%s", composeProjectName(appID))
+	volumes, err := e.listByFilter(ctx, []string{"volume", "ls", "-q"}, projectFilter, workDir)
+	if err != nil {
+		return output, err
+	}
+	if len(volumes) > 0 {
+		e.Logger.Info("Removing app volumes", "app_id", appID, "volumes", len(volumes))
+		rmOut, rmErr := e.runCommand(ctx, "docker", append([]string{"volume", "rm", "-f"}, volumes...), workDir, nil, nil)
+		outputs = append(outputs, rmOut)
+		if rmErr != nil {
+			return strings.Join(outputs, "\n"), fmt.Errorf("docker volume rm failed: %w", rmErr)
+		}
+	}
+	return strings.Join(outputs, "\n"), nil
+}
+
+// Purge destroys an app's stack and volumes like DestroyVolumes and also
+// removes the images its containers ran. Images still used by another
+// container are kept. It is meant for throwaway apps such as previews.
+func (e *ComposeExecutor) Purge(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error) {
+	ctx, err := e.appContext(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+	projectName := composeProjectName(appID)
+	workDir := e.runtimeWorkDir()
+	projectFilter := fmt.Sprintf("label=com.docker.compose.project=%s", projectName)
+
+	// Read the images before the containers referencing them are gone.
+	images, err := e.listByFilter(ctx, []string{"ps", "-a", "--format", "{{.Image}}"}, projectFilter, workDir)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := e.DestroyVolumes(ctx, appID, composePath, envVars)
+	outputs := []string{output}
+	if err != nil {
+		return output, err
+	}
+
+	removed := 0
+	for _, image := range images {
+		// One at a time, so an image shared with another app does not stop
+		// the rest from being removed.
+		rmiOut, rmiErr := e.runCommand(ctx, "docker", []string{"rmi", image}, workDir, nil, nil)
+		if rmiErr != nil {
+			e.Logger.Info("Keeping image still in use", "app_id", appID, "image", image)
+			continue
+		}
+		outputs = append(outputs, rmiOut)
+		removed++
+	}
+	if removed > 0 {
+		e.Logger.Info("Removed preview images", "app_id", appID, "images", removed)
+	}
+
+	return strings.Join(outputs, "\n"), nil
+}
+
+// listByFilter runs a docker list command with filter and returns the unique
+// non-empty lines it printed.
+func (e *ComposeExecutor) listByFilter(ctx context.Context, args []string, filter, workDir string) ([]string, error) {
+	args = append(slices.Clone(args), "--filter", filter)
+	output, err := e.runCommand(ctx, "docker", args, workDir, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("docker %s failed: %w", strings.Join(args[:2], " "), err)
+	}
+	var values []string
+	for _, line := range strings.Split(output, "\n") {
+		value := strings.TrimSpace(line)
+		if value != "" && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,31 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RestartService restarts the containers of one service of an app's
+// project with `docker compose restart`. The containers keep their
+// configuration; nothing is pulled or recreated.
+func (e *ComposeExecutor) RestartService(ctx context.Context, appID, composePath string, profiles []string, service string) (string, error) {
+	composeFullPath, err := e.checkoutComposeFile(appID, composePath)
+	if err != nil {
+		return "", err
+	}
+	ctx, err = e.appContext(ctx, appID)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"compose", "-p", composeProjectName(appID), "-f", filepath.Base(composeFullPath)}
+	args = append(args, profileArgs(profiles)...)
+	args = append(args, "restart", service)
+	output, err := e.runCommand(ctx, "docker", args, filepath.Dir(composeFullPath), nil, nil)
+	if err != nil {
+		return output, fmt.Errorf("docker compose restart failed: %w: %s", err, truncateOutput(strings.TrimSpace(output)))
+	}
+	return output, nil
+}
@@ -0,0 +1,70 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/conops/conops/internal/redact"
+)
+
+// SecretResolver looks up env values that reference a secret store, such as
+// vault:secret/data/app#PASSWORD.
+type SecretResolver interface {
+	// IsReference reports whether an env value is a secret reference.
+	IsReference(value string) bool
+	// Resolve returns the secret a reference points at.
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// resolveSecretRefs returns serviceEnvs with every secret reference replaced
+// by its secret, and the service/KEY names it resolved. The secrets are
+// added to the redactor of ctx, so nothing logged about the sync shows them.
+func (e *ComposeExecutor) resolveSecretRefs(ctx context.Context, serviceEnvs map[string]string) (map[string]string, []string, error) {
+	if e.Secrets == nil {
+		return serviceEnvs, nil, nil
+	}
+	secrets := redact.FromContext(ctx)
+	// The same reference used by several services is read once.
+	cache := make(map[string]string)
+	resolved := make(map[string]string, len(serviceEnvs))
+	var names []string
+	for service, rawEnv := range serviceEnvs {
+		lines := strings.Split(rawEnv, "\n")
+		for i, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(trimmed, "=")
+			if !ok {
+				continue
+			}
+			ref := strings.Trim(strings.TrimSpace(value), `"'`)
+			if !e.Secrets.IsReference(ref) {
+				continue
+			}
+			secret, ok := cache[ref]
+			if !ok {
+				var err error
+				secret, err = e.Secrets.Resolve(ctx, ref)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s %s: %w", service, strings.TrimSpace(key), err)
+				}
+				if strings.ContainsAny(secret, "\r\n") {
+					return nil, nil, fmt.Errorf("%s %s: secret %s spans several lines; env values must fit on one", service, strings.TrimSpace(key), ref)
+				}
+				cache[ref] = secret
+				if secrets != nil {
+					secrets.Add(secret)
+				}
+			}
+			lines[i] = strings.TrimSpace(key) + "=" + secret
+			names = append(names, service+"/"+strings.TrimSpace(key))
+		}
+		resolved[service] = strings.Join(lines, "\n")
+	}
+	sort.Strings(names)
+	return resolved, names, nil
+}
@@ -0,0 +1,138 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/conops/conops/internal/redact"
+)
+
+// SOPSBinaryEnv overrides the sops binary used to decrypt env files.
+const SOPSBinaryEnv = "CONOPS_SOPS_BINARY"
+
+// SOPSKeyResolver returns the key that decrypts an app's SOPS-encrypted
+// env file: an age identity (AGE-SECRET-KEY-...) or an armored GPG
+// private key.
+type SOPSKeyResolver interface {
+	// SOPSKey returns the app's key, or nil when it has none.
+	SOPSKey(appID string) ([]byte, error)
+}
+
+// isSOPSEnvFile reports whether the dotenv file at path was encrypted by
+// SOPS, which appends its metadata as sops_* entries.
+func isSOPSEnvFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	hasMAC, hasVersion := false, false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		hasMAC = hasMAC || strings.HasPrefix(line, "sops_mac=")
+		hasVersion = hasVersion || strings.HasPrefix(line, "sops_version=")
+	}
+	return hasMAC && hasVersion
+}
+
+// decryptSOPSEnvFile decrypts the env file at path with the app's SOPS key
+// into a private file under appDir and returns its path. The decrypted
+// values are added to the redactor of ctx. cleanup removes the file and any
+// GPG keyring made for it.
+func (e *ComposeExecutor) decryptSOPSEnvFile(ctx context.Context, appID, appDir, path string) (string, func(), error) {
+	if e.SOPSKeys == nil {
+		return "", nil, fmt.Errorf("sops env files are not supported by this runtime")
+	}
+	key, err := e.SOPSKeys.SOPSKey(appID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(key) == 0 {
+		return "", nil, fmt.Errorf("env file is encrypted with sops but the app has no sops key")
+	}
+	binary := strings.TrimSpace(os.Getenv(SOPSBinaryEnv))
+	if binary == "" {
+		binary = "sops"
+	}
+	sopsPath, err := exec.LookPath(binary)
+	if err != nil {
+		return "", nil, fmt.Errorf("env file is encrypted with sops but %s is not installed: %w", binary, err)
+	}
+
+	sopsDir := filepath.Join(appDir, ".sops")
+	_ = os.RemoveAll(sopsDir)
+	if err := os.MkdirAll(sopsDir, 0700); err != nil {
+		return "", nil, fmt.Errorf("failed to create sops dir: %w", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(sopsDir)
+	}
+
+	// Keys configured for the controller itself are not consulted: only the
+	// app's own key may decrypt its env file.
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, entry := range os.Environ() {
+		if strings.HasPrefix(entry, "SOPS_") || strings.HasPrefix(entry, "GNUPGHOME=") {
+			continue
+		}
+		env = append(env, entry)
+	}
+	if bytes.Contains(key, []byte("AGE-SECRET-KEY-")) {
+		env = append(env, "SOPS_AGE_KEY="+string(key))
+	} else {
+		gnupgHome := filepath.Join(sopsDir, "gnupg")
+		if err := os.MkdirAll(gnupgHome, 0700); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to create gpg home: %w", err)
+		}
+		importCmd := exec.CommandContext(ctx, "gpg", "--batch", "--quiet", "--import")
+		importCmd.Env = append(env, "GNUPGHOME="+gnupgHome)
+		importCmd.Stdin = bytes.NewReader(key)
+		if output, err := importCmd.CombinedOutput(); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to import sops gpg key: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		env = append(env, "GNUPGHOME="+gnupgHome)
+		// The import starts a gpg-agent for the keyring; stop it with the
+		// keyring.
+		removeDir := cleanup
+		cleanup = func() {
+			_ = exec.Command("gpgconf", "--homedir", gnupgHome, "--kill", "gpg-agent").Run()
+			removeDir()
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, sopsPath, "--decrypt", "--input-type", "dotenv", "--output-type", "dotenv", path)
+	command.Env = env
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("sops could not decrypt the env file: %w: %s", err, truncateOutput(strings.TrimSpace(stderr.String())))
+	}
+
+	if secrets := redact.FromContext(ctx); secrets != nil {
+		scanner := bufio.NewScanner(bytes.NewReader(stdout.Bytes()))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if _, value, ok := strings.Cut(line, "="); ok {
+				secrets.Add(value)
+			}
+		}
+	}
+	decrypted := filepath.Join(sopsDir, "decrypted.env")
+	if err := os.WriteFile(decrypted, stdout.Bytes(), 0600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write decrypted env file: %w", err)
+	}
+	return decrypted, cleanup, nil
+}
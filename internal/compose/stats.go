@@ -0,0 +1,166 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ServiceStats is a resource usage snapshot of one container. Stopped
+// containers only report their restart count.
+type ServiceStats struct {
+	Service          string
+	Name             string
+	Running          bool
+	CPUPercent       float64
+	MemoryBytes      uint64
+	MemoryLimitBytes uint64
+	MemoryPercent    float64
+	RestartCount     int
+}
+
+// ProjectStats takes a `docker stats` snapshot of the containers of a
+// compose project, together with how often docker restarted each of them.
+func (e *ComposeExecutor) ProjectStats(ctx context.Context, projectName string) ([]ServiceStats, error) {
+	if strings.TrimSpace(projectName) == "" {
+		return nil, nil
+	}
+	ctx, err := e.appContext(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.engineClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cli != nil {
+		stats, err := engineProjectStats(ctx, cli, projectName)
+		if err != nil {
+			return nil, err
+		}
+		sortServiceStats(stats)
+		return stats, nil
+	}
+
+	output, err := e.runCommand(
+		ctx,
+		"docker",
+		[]string{
+			"ps", "-aq", "--no-trunc",
+			"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", projectName),
+			"--filter", "label=com.docker.compose.oneoff=False",
+		},
+		e.runtimeWorkDir(),
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("docker ps failed: %w", err)
+	}
+	ids := strings.Fields(output)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := append([]string{"inspect", "--format",
+		`{{.Id}}|{{index .Config.Labels "com.docker.compose.service"}}|{{.Name}}|{{.RestartCount}}|{{.State.Running}}`,
+	}, ids...)
+	output, err = e.runCommand(ctx, "docker", args, e.runtimeWorkDir(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("docker inspect failed: %w: %s", err, truncateOutput(strings.TrimSpace(output)))
+	}
+	var stats []ServiceStats
+	index := make(map[string]int) // container ID -> position in stats
+	var running []string
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "|")
+		if len(parts) != 5 {
+			continue
+		}
+		restarts, _ := strconv.Atoi(parts[3])
+		entry := ServiceStats{
+			Service:      parts[1],
+			Name:         strings.TrimPrefix(parts[2], "/"),
+			Running:      parts[4] == "true",
+			RestartCount: restarts,
+		}
+		index[parts[0]] = len(stats)
+		stats = append(stats, entry)
+		if entry.Running {
+			running = append(running, parts[0])
+		}
+	}
+
+	if len(running) > 0 {
+		args := append([]string{"stats", "--no-stream", "--no-trunc", "--format", "{{.ID}}|{{.CPUPerc}}|{{.MemUsage}}|{{.MemPerc}}"}, running...)
+		output, err := e.runCommand(ctx, "docker", args, e.runtimeWorkDir(), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("docker stats failed: %w: %s", err, truncateOutput(strings.TrimSpace(output)))
+		}
+		for _, line := range strings.Split(output, "\n") {
+			parts := strings.Split(strings.TrimSpace(line), "|")
+			if len(parts) != 4 {
+				continue
+			}
+			i, ok := index[parts[0]]
+			if !ok {
+				continue
+			}
+			stats[i].CPUPercent = parsePercent(parts[1])
+			usage, limit, _ := strings.Cut(parts[2], "/")
+			stats[i].MemoryBytes = parseDockerSize(usage)
+			stats[i].MemoryLimitBytes = parseDockerSize(limit)
+			stats[i].MemoryPercent = parsePercent(parts[3])
+		}
+	}
+
+	sortServiceStats(stats)
+	return stats, nil
+}
+
+func sortServiceStats(stats []ServiceStats) {
+	slices.SortFunc(stats, func(a, b ServiceStats) int {
+		if c := strings.Compare(a.Service, b.Service); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// parsePercent parses docker's "12.34%"; "--", shown before the first
+// sample, is zero.
+func parsePercent(value string) float64 {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil {
+		return 0
+	}
+	return percent
+}
+
+// dockerSizeUnits are the suffixes docker prints sizes with: binary for
+// memory, decimal for I/O.
+var dockerSizeUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseDockerSize parses sizes such as "12.5MiB"; unparsable values are zero.
+func parseDockerSize(value string) uint64 {
+	value = strings.TrimSpace(value)
+	for _, unit := range dockerSizeUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || size < 0 {
+				return 0
+			}
+			return uint64(size * unit.multiplier)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package compose
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/redact"
+)
+
+// Steps of Apply's transcript.
+const (
+	StepPreflight     = "preflight"
+	StepGit           = "git"
+	StepComposeConfig = "compose-config"
+	StepComposePull   = "compose-pull"
+	StepPreDeploy     = "pre-deploy"
+	StepComposeUp     = "compose-up"
+	StepPostDeploy    = "post-deploy"
+	StepHealthGate    = "health-gate"
+)
+
+// SyncStep is one step of a sync with the part of the transcript it wrote.
+type SyncStep struct {
+	Name      string
+	Failed    bool
+	StartedAt time.Time
+	Duration  time.Duration
+	Log       string
+}
+
+// Transcript is the log of a sync. Its text is what the controller shows
+// while the sync runs; Steps splits it into the steps that wrote it. Both
+// are masked with the redactor of the context it was created with.
+type Transcript struct {
+	strings.Builder
+	secrets *redact.Redactor
+	steps   []transcriptStep
+}
+
+type transcriptStep struct {
+	name      string
+	offset    int
+	startedAt time.Time
+}
+
+// NewTranscript returns an empty transcript masking the secrets of ctx.
+func NewTranscript(ctx context.Context) *Transcript {
+	return &Transcript{secrets: redact.FromContext(ctx)}
+}
+
+// Step starts the step name, which owns the text written from now on. Text
+// written before the first step belongs to it.
+func (t *Transcript) Step(name string) {
+	if n := len(t.steps); n > 0 && t.steps[n-1].name == name {
+		return
+	}
+	offset := t.Len()
+	if len(t.steps) == 0 {
+		offset = 0
+	}
+	t.steps = append(t.steps, transcriptStep{name: name, offset: offset, startedAt: time.Now()})
+}
+
+// Section starts a titled section of the current step.
+func (t *Transcript) Section(title string) {
+	appendLogSection(&t.Builder, title)
+}
+
+// Line appends one line to the current section.
+func (t *Transcript) Line(line string) {
+	appendLogLine(&t.Builder, line)
+}
+
+// Block appends text, e.g. another transcript, as a paragraph of its own.
+func (t *Transcript) Block(text string) {
+	appendLogBlock(&t.Builder, text)
+}
+
+// String returns the whole text.
+func (t *Transcript) String() string {
+	return t.secrets.String(strings.TrimSpace(t.Builder.String()))
+}
+
+// Steps returns the steps so far. The last one ends now and is failed when
+// failed is set; the others ended when the next one started.
+func (t *Transcript) Steps(failed bool) []SyncStep {
+	text := t.Builder.String()
+	now := time.Now()
+	steps := make([]SyncStep, len(t.steps))
+	for i, mark := range t.steps {
+		end, endedAt := len(text), now
+		if i+1 < len(t.steps) {
+			end, endedAt = t.steps[i+1].offset, t.steps[i+1].startedAt
+		}
+		steps[i] = SyncStep{
+			Name:      mark.name,
+			Failed:    failed && i == len(t.steps)-1,
+			StartedAt: mark.startedAt,
+			Duration:  endedAt.Sub(mark.startedAt),
+			Log:       t.secrets.String(strings.TrimSpace(text[mark.offset:end])),
+		}
+	}
+	return steps
+}
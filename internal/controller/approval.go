@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/go-chi/chi/v5"
+)
+
+// StatusAwaitingApproval marks an app that requires approval and holds a
+// new commit the reconciler will not deploy until it is approved.
+const StatusAwaitingApproval = "awaiting_approval"
+
+// ErrNotAwaitingApproval is returned when approving an app that holds no
+// commit.
+var ErrNotAwaitingApproval = errors.New("app is not awaiting approval")
+
+// ErrStaleApproval is returned when the approved commit is no longer the
+// one the app holds, because a newer commit arrived in the meantime.
+var ErrStaleApproval = errors.New("approved commit is not the held commit")
+
+// Approve releases the commit an app holds in awaiting_approval so the
+// reconciler deploys it. When commit is set, a full hash or a prefix of at
+// least 7 characters, it must match the held commit.
+func (r *Registry) Approve(id, commit string) (*App, error) {
+	app, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if app.Status != StatusAwaitingApproval {
+		return nil, ErrNotAwaitingApproval
+	}
+	commit = strings.TrimSpace(commit)
+	if commit != "" && (len(commit) < 7 || !strings.HasPrefix(app.LastSeenCommit, commit)) {
+		return nil, fmt.Errorf("%w: %s is held, not %s", ErrStaleApproval, app.LastSeenCommit, commit)
+	}
+	if err := r.UpdateStatus(id, "pending", nil); err != nil {
+		return nil, err
+	}
+	app.Status = "pending"
+	return app, nil
+}
+
+type approveAppRequest struct {
+	// Commit guards against approving a commit other than the one reviewed.
+	Commit string `json:"commit"`
+}
+
+// ApproveApp handles POST /api/v1/apps/{id}/approve
+func (h *Handler) ApproveApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// The UI posts a form; API clients send JSON or no body at all.
+	var req approveAppRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Commit = r.FormValue("commit")
+	}
+
+	entry := NewAuditEntry(r, AuditActionApprove, id)
+	app, err := h.Registry.Approve(id, req.Commit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotAwaitingApproval) || errors.Is(err, ErrStaleApproval) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry.Changes = map[string]api.FieldChange{
+		"status": {From: StatusAwaitingApproval, To: app.Status},
+		"commit": {To: app.LastSeenCommit},
+	}
+	h.recordAudit(entry, nil)
+	if h.Logger != nil {
+		h.Logger.Info("Commit approved", "app_id", id, "commit", app.LastSeenCommit, "actor", entry.Actor)
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Commit approved; the next reconcile deploys it",
+		Data:    app,
+	})
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/signing"
+	"github.com/google/uuid"
+)
+
+const (
+	AuditActionCreate = "app.create"
+	AuditActionUpdate = "app.update"
+	AuditActionDelete = "app.delete"
+	AuditActionSync   = "app.sync"
+	// AuditActionApprove records the approval of an app's pending commit.
+	AuditActionApprove = "app.approve"
+
+	AuditActionTokenCreate = "token.create"
+	AuditActionTokenRevoke = "token.revoke"
+
+	auditOutcomeSuccess = "success"
+	auditOutcomeError   = "error"
+)
+
+// NewAuditEntry builds an audit entry describing the request that performed action.
+func NewAuditEntry(r *http.Request, action, appID string) *api.AuditEntry {
+	return &api.AuditEntry{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		Actor:     requestActor(r),
+		Source:    requestSource(r),
+		Action:    action,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		AppID:     appID,
+		Outcome:   auditOutcomeSuccess,
+	}
+}
+
+// RecordAudit persists an audit entry. Successful creates and updates that
+// changed the app also append a revision to its configuration history.
+func (r *Registry) RecordAudit(entry *api.AuditEntry) error {
+	if err := r.store.CreateAuditEntry(context.Background(), entry); err != nil {
+		return err
+	}
+	if entry.Outcome != auditOutcomeSuccess || len(entry.Changes) == 0 {
+		return nil
+	}
+	if entry.Action != AuditActionCreate && entry.Action != AuditActionUpdate {
+		return nil
+	}
+	return r.recordRevision(entry)
+}
+
+func (r *Registry) recordRevision(entry *api.AuditEntry) error {
+	app, err := r.Get(entry.AppID)
+	if err != nil {
+		return err
+	}
+	return r.store.CreateAppRevision(context.Background(), &api.AppRevision{
+		AppID:     app.ID,
+		CreatedAt: entry.CreatedAt,
+		Actor:     entry.Actor,
+		Source:    entry.Source,
+		Action:    entry.Action,
+		Spec:      SpecOf(app),
+		Changes:   entry.Changes,
+	})
+}
+
+// ListRevisions returns an app's configuration history, newest first.
+func (r *Registry) ListRevisions(appID string, limit int) ([]*api.AppRevision, error) {
+	return r.store.ListAppRevisions(context.Background(), appID, limit)
+}
+
+// SpecOf captures the editable configuration of app for its history.
+func SpecOf(app *App) api.AppSpec {
+	return api.AppSpec{
+		Name:            app.Name,
+		RepoURL:         app.RepoURL,
+		RepoAuthMethod:  app.RepoAuthMethod,
+		Branch:          app.Branch,
+		ComposePath:     app.ComposePath,
+		PollInterval:    app.PollInterval,
+		GateScript:      app.GateScript,
+		NotifyWebhooks:  app.NotifyWebhooks,
+		SlackWebhookURL: redactedIfSet(app.SlackWebhookURL),
+		SlackChannel:    app.SlackChannel,
+		NotifyEvents:    app.NotifyEvents,
+		QuietHours:      app.QuietHours,
+		SigningKeys:     app.SigningKeys,
+		Track:           app.Track,
+		WatchPaths:      app.WatchPaths,
+		Profiles:        app.Profiles,
+		EnvFile:         app.EnvFile,
+		PreviewTTL:      app.PreviewTTL,
+		PostDeploy:      app.PostDeploy,
+		PreDeploy:       app.PreDeploy,
+		Priority:        app.Priority,
+		RequireApproval: app.RequireApproval,
+		PinImages:       app.PinImages,
+		FreezeWindows:   app.FreezeWindows,
+		DockerHost:      app.DockerHost,
+	}
+}
+
+// ListAudit returns the newest audit entries, optionally scoped to one app.
+func (r *Registry) ListAudit(appID string, limit int) ([]*api.AuditEntry, error) {
+	return r.store.ListAuditEntries(context.Background(), appID, limit)
+}
+
+// DiffAppFields reports editable fields that differ between two app records.
+func DiffAppFields(before, after *App) map[string]api.FieldChange {
+	if before == nil {
+		before = &App{}
+	}
+	if after == nil {
+		after = &App{}
+	}
+
+	changes := make(map[string]api.FieldChange)
+	compare := func(field, from, to string) {
+		if from != to {
+			changes[field] = api.FieldChange{From: from, To: to}
+		}
+	}
+	compare("name", before.Name, after.Name)
+	compare("repo_url", before.RepoURL, after.RepoURL)
+	compare("repo_auth_method", before.RepoAuthMethod, after.RepoAuthMethod)
+	compare("branch", before.Branch, after.Branch)
+	compare("track", before.Track, after.Track)
+	compare("compose_path", before.ComposePath, after.ComposePath)
+	compare("watch_paths", strings.Join(before.WatchPaths, ","), strings.Join(after.WatchPaths, ","))
+	compare("profiles", strings.Join(before.Profiles, ","), strings.Join(after.Profiles, ","))
+	compare("env_file", before.EnvFile, after.EnvFile)
+	compare("pre_deploy", strings.Join(before.PreDeploy, "\n"), strings.Join(after.PreDeploy, "\n"))
+	compare("post_deploy", strings.Join(before.PostDeploy, "\n"), strings.Join(after.PostDeploy, "\n"))
+	compare("poll_interval", before.PollInterval, after.PollInterval)
+	compare("preview_ttl", before.PreviewTTL, after.PreviewTTL)
+	compare("priority", before.Priority, after.Priority)
+	compare("require_approval", strconv.FormatBool(before.RequireApproval), strconv.FormatBool(after.RequireApproval))
+	compare("pin_images", strconv.FormatBool(before.PinImages), strconv.FormatBool(after.PinImages))
+	compare("freeze_windows", strings.Join(before.FreezeWindows, "; "), strings.Join(after.FreezeWindows, "; "))
+	compare("docker_host", before.DockerHost, after.DockerHost)
+	compare("gate_script", before.GateScript, after.GateScript)
+	compare("notify_webhooks", strings.Join(before.NotifyWebhooks, ","), strings.Join(after.NotifyWebhooks, ","))
+	compare("slack_channel", before.SlackChannel, after.SlackChannel)
+	compare("notify_events", strings.Join(before.NotifyEvents, ","), strings.Join(after.NotifyEvents, ","))
+	compare("quiet_hours", before.QuietHours, after.QuietHours)
+	compare("signing_keys", strings.Join(signing.Fingerprints(before.SigningKeys), ","), strings.Join(signing.Fingerprints(after.SigningKeys), ","))
+	// Slack webhook URLs embed a credential; record that it changed only.
+	if before.SlackWebhookURL != after.SlackWebhookURL {
+		changes["slack_webhook_url"] = api.FieldChange{From: redactedIfSet(before.SlackWebhookURL), To: redactedIfSet(after.SlackWebhookURL)}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func redactedIfSet(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "(redacted)"
+}
+
+// requestActor identifies who issued the request: the app token's name, the
+// admin token, or anonymous when the controller runs without authentication.
+func requestActor(r *http.Request) string {
+	p := principalFrom(r)
+	switch {
+	case p.token != nil:
+		return "token:" + p.token.Name
+	case p.authenticated:
+		return "admin"
+	default:
+		return "anonymous"
+	}
+}
+
+func requestSource(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
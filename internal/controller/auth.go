@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// APITokenEnv holds the admin token. When it is set every API and UI request
+// must authenticate; without it anonymous callers keep full access.
+const APITokenEnv = "CONOPS_API_TOKEN"
+
+// Scopes an app token can be granted. Sync is for CI deploying the app;
+// operate takes its stack down, or holds it on or rolls it back to another
+// commit than CI deployed, so it is granted separately.
+const (
+	ScopeRead    = "read"
+	ScopeSync    = "sync"
+	ScopeOperate = "operate"
+)
+
+// TokenScopes lists every app token scope.
+var TokenScopes = []string{ScopeRead, ScopeSync, ScopeOperate}
+
+// appTokenPrefix marks app tokens so they are easy to spot in CI secrets.
+const appTokenPrefix = "conops_"
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	// admin callers may do anything; others are limited to token.
+	admin bool
+	// authenticated is false for anonymous callers on an open controller.
+	authenticated bool
+	token         *api.AppToken
+}
+
+type principalKey struct{}
+
+func principalFrom(r *http.Request) principal {
+	if p, ok := r.Context().Value(principalKey{}).(principal); ok {
+		return p
+	}
+	// Requests that bypass the authenticator, e.g. from the UI on an open
+	// controller, are anonymous admins as before.
+	return principal{admin: true}
+}
+
+// Authenticator resolves the caller of API and UI requests from a bearer
+// token, or from the password of HTTP basic auth so browsers can sign in.
+type Authenticator struct {
+	registry   *Registry
+	adminToken string
+	logger     *slog.Logger
+	// origins refuses cross-site requests that change state. Browsers resend
+	// basic auth on their own, so without it any page could make a signed-in
+	// browser sync or delete apps.
+	origins *http.CrossOriginProtection
+}
+
+// NewAuthenticator creates an authenticator. An empty adminToken leaves the
+// controller open to anonymous callers; app tokens are still enforced for
+// requests that present one.
+func NewAuthenticator(registry *Registry, adminToken string, logger *slog.Logger) *Authenticator {
+	return &Authenticator{
+		registry:   registry,
+		adminToken: strings.TrimSpace(adminToken),
+		logger:     logger,
+		origins:    http.NewCrossOriginProtection(),
+	}
+}
+
+// TrustOrigin admits cross-origin requests from the origin of rawURL, e.g.
+// the public URL of a controller behind a proxy that rewrites the Host
+// header.
+func (a *Authenticator) TrustOrigin(rawURL string) error {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("invalid origin %q", rawURL)
+	}
+	return a.origins.AddTrustedOrigin(parsed.Scheme + "://" + parsed.Host)
+}
+
+// Enabled reports whether anonymous access is refused.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && a.adminToken != ""
+}
+
+// Authenticate identifies the caller and rejects invalid credentials, and
+// missing ones when an admin token is configured. Requests a browser may
+// send on its own, with basic auth or no credentials, are refused when they
+// change state from another site; bearer tokens are only sent on purpose.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, bearer := requestToken(r)
+		if !bearer {
+			if err := a.origins.Check(r); err != nil {
+				http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		}
+		var p principal
+		switch {
+		case secret == "" && a.Enabled():
+			w.Header().Set("WWW-Authenticate", `Basic realm="conops"`)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		case secret == "":
+			p = principal{admin: true}
+		case a.Enabled() && subtle.ConstantTimeCompare([]byte(secret), []byte(a.adminToken)) == 1:
+			p = principal{admin: true, authenticated: true}
+		default:
+			token, err := a.registry.AuthenticateAppToken(secret)
+			if err != nil {
+				if !errors.Is(err, store.ErrTokenNotFound) && a.logger != nil {
+					a.logger.Warn("Failed to look up API token", "error", err)
+				}
+				w.Header().Set("WWW-Authenticate", `Basic realm="conops"`)
+				http.Error(w, "Invalid API token", http.StatusUnauthorized)
+				return
+			}
+			p = principal{authenticated: true, token: token}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+}
+
+// RequireAdmin refuses callers authenticated with an app token.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !principalFrom(r).admin {
+			http.Error(w, "App tokens cannot access this endpoint", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope admits admins and app tokens granted scope for the app named
+// by the {id} route parameter or, failing that, the app_id query parameter.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := principalFrom(r)
+			if !p.admin {
+				appID := chi.URLParam(r, "id")
+				if appID == "" {
+					appID = r.URL.Query().Get("app_id")
+				}
+				if p.token == nil || appID != p.token.AppID {
+					http.Error(w, "Token is not valid for this app", http.StatusForbidden)
+					return
+				}
+				if !slices.Contains(p.token.Scopes, scope) {
+					http.Error(w, fmt.Sprintf("Token lacks the %q scope", scope), http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestToken returns the bearer token or basic auth password of r, and
+// whether it was a bearer token.
+func requestToken(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if scheme, value, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(value), true
+		}
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password, false
+	}
+	return "", false
+}
+
+// CreateAppToken issues a token for appID limited to scopes. The returned
+// token carries the secret, which is not stored and cannot be shown again.
+func (r *Registry) CreateAppToken(appID, name string, scopes []string) (*api.AppToken, error) {
+	if _, err := r.Get(appID); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("token name is required")
+	}
+	normalized, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := appTokenPrefix + hex.EncodeToString(raw)
+	token := &api.AppToken{
+		ID:        uuid.NewString(),
+		AppID:     appID,
+		Name:      name,
+		Scopes:    normalized,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := r.store.CreateAppToken(context.Background(), token, hashToken(secret)); err != nil {
+		return nil, err
+	}
+	token.Token = secret
+	return token, nil
+}
+
+// ListAppTokens returns an app's tokens without their secrets.
+func (r *Registry) ListAppTokens(appID string) ([]*api.AppToken, error) {
+	return r.store.ListAppTokens(context.Background(), appID)
+}
+
+// RevokeAppToken deletes one of an app's tokens.
+func (r *Registry) RevokeAppToken(appID, id string) error {
+	return r.store.DeleteAppToken(context.Background(), appID, id)
+}
+
+// AuthenticateAppToken resolves secret to its token and records the use.
+func (r *Registry) AuthenticateAppToken(secret string) (*api.AppToken, error) {
+	if !strings.HasPrefix(secret, appTokenPrefix) {
+		return nil, store.ErrTokenNotFound
+	}
+	token, err := r.store.GetAppTokenByHash(context.Background(), hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if err := r.store.TouchAppToken(context.Background(), token.ID, now); err == nil {
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" || slices.Contains(normalized, scope) {
+			continue
+		}
+		if !slices.Contains(TokenScopes, scope) {
+			return nil, fmt.Errorf("invalid scope %q: must be one of %s", scope, strings.Join(TokenScopes, ", "))
+		}
+		normalized = append(normalized, scope)
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return normalized, nil
+}
+
+// hashToken is the stored form of a token secret. Secrets are random, so an
+// unsalted digest is enough.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
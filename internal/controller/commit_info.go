@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	// maxCommitInfoFiles caps the per-file summary stored with a commit.
+	maxCommitInfoFiles = 200
+	// commitInfoTimeout bounds the diff so a huge change cannot stall polling.
+	commitInfoTimeout = 30 * time.Second
+)
+
+// readCommitInfo describes commit and summarises the files it changes
+// relative to base, the commit currently deployed. Without a usable base the
+// summary is relative to the commit's first parent.
+func readCommitInfo(repo *git.Repository, commit *object.Commit, base string) (*api.CommitInfo, error) {
+	info := &api.CommitInfo{
+		Author:      signature(commit.Author),
+		Committer:   signature(commit.Committer),
+		AuthoredAt:  commit.Author.When,
+		CommittedAt: commit.Committer.When,
+		Message:     strings.TrimSpace(commit.Message),
+	}
+
+	var baseCommit *object.Commit
+	if base != "" && base != commit.Hash.String() {
+		baseCommit, _ = repo.CommitObject(plumbing.NewHash(base))
+	}
+	if baseCommit == nil && commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parent of %s: %w", commit.Hash, err)
+		}
+		baseCommit = parent
+	}
+	if baseCommit != nil && baseCommit.Hash == commit.Hash {
+		return info, nil
+	}
+
+	var fromTree *object.Tree
+	if baseCommit != nil {
+		info.Base = baseCommit.Hash.String()
+		tree, err := baseCommit.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree of %s: %w", baseCommit.Hash, err)
+		}
+		fromTree = tree
+	}
+	toTree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", commit.Hash, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitInfoTimeout)
+	defer cancel()
+	changes, err := object.DiffTreeWithOptions(ctx, fromTree, toTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %w", commit.Hash, err)
+	}
+	patch, err := changes.PatchContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %w", commit.Hash, err)
+	}
+
+	for _, filePatch := range patch.FilePatches() {
+		file := changedFile(filePatch)
+		info.FilesChanged++
+		info.Additions += file.Additions
+		info.Deletions += file.Deletions
+		if len(info.Files) < maxCommitInfoFiles {
+			info.Files = append(info.Files, file)
+		}
+	}
+	return info, nil
+}
+
+func changedFile(filePatch fdiff.FilePatch) api.ChangedFile {
+	var file api.ChangedFile
+	from, to := filePatch.Files()
+	switch {
+	case from == nil:
+		file.Path, file.Change = to.Path(), "added"
+	case to == nil:
+		file.Path, file.Change = from.Path(), "deleted"
+	case from.Path() != to.Path():
+		file.Path, file.Change, file.From = to.Path(), "renamed", from.Path()
+	default:
+		file.Path, file.Change = to.Path(), "modified"
+	}
+	for _, chunk := range filePatch.Chunks() {
+		lines := countLines(chunk.Content())
+		switch chunk.Type() {
+		case fdiff.Add:
+			file.Additions += lines
+		case fdiff.Delete:
+			file.Deletions += lines
+		}
+	}
+	return file
+}
+
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	lines := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	return lines
+}
+
+func signature(sig object.Signature) string {
+	if sig.Email == "" {
+		return sig.Name
+	}
+	return fmt.Sprintf("%s <%s>", sig.Name, sig.Email)
+}
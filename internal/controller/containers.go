@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListAppContainers handles GET /api/v1/apps/{id}/containers
+func (h *Handler) ListAppContainers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.Inspector == nil {
+		http.Error(w, "the runtime cannot list containers", http.StatusNotImplemented)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	found, err := h.Inspector.InspectProjectContainers(ctx, compose.ProjectNameForApp(app.ID))
+	if err != nil {
+		http.Error(w, "failed to inspect containers", http.StatusServiceUnavailable)
+		return
+	}
+	containers := make([]api.ServiceContainer, 0, len(found))
+	for _, c := range found {
+		containers = append(containers, api.ServiceContainer{
+			Service:      c.Service,
+			Name:         c.Name,
+			Image:        c.Image,
+			Status:       c.Status,
+			Health:       c.Health,
+			ExitCode:     c.ExitCode,
+			RestartCount: c.RestartCount,
+			Ports:        c.Ports,
+		})
+	}
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: containers,
+	})
+}
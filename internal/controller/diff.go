@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/go-chi/chi/v5"
+)
+
+// diffRenderTimeout bounds rendering both sides of a diff.
+const diffRenderTimeout = 2 * time.Minute
+
+// RuntimeRenderer renders an app's compose configuration at a commit
+// without deploying it.
+type RuntimeRenderer interface {
+	RenderConfig(ctx context.Context, appID, repoURL, branch, composePath string, profiles []string, envFile, commitHash string, deployKey []byte) (string, error)
+}
+
+// GetAppDiff handles GET /api/v1/apps/{id}/diff
+func (h *Handler) GetAppDiff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.Renderer == nil {
+		http.Error(w, "runtime does not support diffs", http.StatusNotImplemented)
+		return
+	}
+	if app.LastSeenCommit == "" {
+		http.Error(w, "no commit seen yet: wait for the next poll", http.StatusConflict)
+		return
+	}
+
+	diff := &api.AppDiff{
+		AppID:        app.ID,
+		SyncedCommit: app.LastSyncedCommit,
+		TargetCommit: app.LastSeenCommit,
+	}
+	if diff.SyncedCommit != diff.TargetCommit {
+		deployKey, err := h.Registry.GetDeployKey(app.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer zeroBytes(deployKey)
+
+		ctx, cancel := context.WithTimeout(r.Context(), diffRenderTimeout)
+		defer cancel()
+
+		render := func(commit string) (string, error) {
+			if commit == "" {
+				return "", nil
+			}
+			return h.Renderer.RenderConfig(ctx, app.ID, app.RepoURL, applyBranch(app), app.ComposePath, app.Profiles, app.EnvFile, commit, deployKey)
+		}
+		synced, err := render(diff.SyncedCommit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("render synced commit: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+		target, err := render(diff.TargetCommit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("render target commit: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+
+		fromName := "synced (none)"
+		if diff.SyncedCommit != "" {
+			fromName = "synced " + diff.SyncedCommit
+		}
+		diff.Diff = compose.ConfigDiff(fromName, synced, "target "+diff.TargetCommit, target)
+		diff.Changed = diff.Diff != ""
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{Data: diff})
+}
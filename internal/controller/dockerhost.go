@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/credentials"
+	"github.com/conops/conops/internal/store"
+)
+
+// DockerEndpoint returns the docker daemon the app with the given ID or
+// compose project name deploys to. Unknown apps, such as stale projects
+// left behind by deleted ones, run on the local daemon.
+func (r *Registry) DockerEndpoint(appID string) (compose.DockerEndpoint, error) {
+	app, err := r.Get(appID)
+	if err != nil {
+		app = nil
+		for _, candidate := range r.List() {
+			if compose.ProjectNameForApp(candidate.ID) == appID {
+				app = candidate
+				break
+			}
+		}
+	}
+	if app == nil || strings.TrimSpace(app.DockerHost) == "" {
+		return compose.DockerEndpoint{}, nil
+	}
+	return r.dockerEndpointOf(app)
+}
+
+// DockerEndpoints returns the distinct remote docker daemons apps deploy
+// to. Apps sharing a host share its endpoint, TLS included.
+func (r *Registry) DockerEndpoints() ([]compose.DockerEndpoint, error) {
+	var endpoints []compose.DockerEndpoint
+	seen := make(map[string]bool)
+	for _, app := range r.List() {
+		host := strings.TrimSpace(app.DockerHost)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		endpoint, err := r.dockerEndpointOf(app)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func (r *Registry) dockerEndpointOf(app *api.App) (compose.DockerEndpoint, error) {
+	endpoint := compose.DockerEndpoint{Host: strings.TrimSpace(app.DockerHost)}
+	if !strings.HasPrefix(endpoint.Host, "tcp://") {
+		return endpoint, nil
+	}
+	tls, err := r.GetDockerTLS(app.ID)
+	if err != nil {
+		return compose.DockerEndpoint{}, err
+	}
+	endpoint.TLS = tls
+	return endpoint, nil
+}
+
+// GetDockerTLS returns the decrypted TLS material of the app's docker host,
+// or nil when none is stored.
+func (r *Registry) GetDockerTLS(id string) (*compose.DockerTLS, error) {
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
+	credential, err := r.store.GetAppCredential(context.Background(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrCredentialNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(credential.DockerTLSCiphertext) == 0 {
+		return nil, nil
+	}
+
+	if r.credentials == nil || !r.credentials.Enabled() {
+		return nil, fmt.Errorf("docker tls support is unavailable: set %s", credentials.EncryptionKeyEnv)
+	}
+	jsonBytes, err := r.credentials.Decrypt(credential.DockerTLSCiphertext, credential.DockerTLSNonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt docker tls: %w", err)
+	}
+	defer zeroBytes(jsonBytes)
+
+	var tls compose.DockerTLS
+	if err := json.Unmarshal(jsonBytes, &tls); err != nil {
+		return nil, fmt.Errorf("failed to deserialize docker tls: %w", err)
+	}
+	return &tls, nil
+}
+
+// SetDockerTLS stores the TLS material of the app's docker host encrypted;
+// nil removes it.
+func (r *Registry) SetDockerTLS(id string, tls *compose.DockerTLS) error {
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
+	if tls == nil {
+		return r.store.UpdateAppDockerTLS(context.Background(), id, nil, nil)
+	}
+	if err := compose.ValidateDockerTLS(tls); err != nil {
+		return err
+	}
+	if err := r.CanStoreDockerTLS(); err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(tls)
+	if err != nil {
+		return fmt.Errorf("failed to serialize docker tls: %w", err)
+	}
+	defer zeroBytes(jsonBytes)
+	ciphertext, nonce, err := r.credentials.Encrypt(jsonBytes)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt docker tls: %w", err)
+	}
+	return r.store.UpdateAppDockerTLS(context.Background(), id, ciphertext, nonce)
+}
+
+// CanStoreDockerTLS reports an error when TLS material cannot be stored
+// because credential encryption is off.
+func (r *Registry) CanStoreDockerTLS() error {
+	if r.credentials == nil || !r.credentials.Enabled() {
+		return fmt.Errorf("encryption support is unavailable: set %s", credentials.EncryptionKeyEnv)
+	}
+	return nil
+}
+
+// validateDockerTLSFor checks TLS material given for an app whose docker
+// host is host. An empty object clears the stored material.
+func validateDockerTLSFor(host string, tls *compose.DockerTLS) error {
+	if tls == nil || *tls == (compose.DockerTLS{}) {
+		return nil
+	}
+	if !strings.HasPrefix(strings.TrimSpace(host), "tcp://") {
+		return fmt.Errorf("invalid docker tls: only tcp:// docker hosts use TLS")
+	}
+	return compose.ValidateDockerTLS(tls)
+}
+
+// removeFromDockerHost takes the app's stack down on the docker daemon it
+// ran on before its host changed. The next sync deploys it on the new one.
+func (h *Handler) removeFromDockerHost(ctx context.Context, app *api.App, endpoint compose.DockerEndpoint) error {
+	if h.Cleaner == nil {
+		return nil
+	}
+	cleanupCtx, cancel := context.WithTimeout(compose.WithDockerEndpoint(ctx, endpoint), 2*time.Minute)
+	defer cancel()
+
+	if _, err := h.Cleaner.Destroy(cleanupCtx, app.ID, app.ComposePath, nil); err != nil {
+		if h.Logger != nil {
+			h.Logger.Warn("Failed to remove app from its previous docker host", "id", app.ID, "docker_host", endpoint.String(), "error", err)
+		}
+		return err
+	}
+	if h.Logger != nil {
+		h.Logger.Info("App removed from its previous docker host", "id", app.ID, "docker_host", endpoint.String())
+	}
+	return nil
+}
+
+// normalizeDockerHost trims and validates an app's docker host.
+func normalizeDockerHost(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if err := compose.ValidateDockerHost(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,412 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/credentials"
+	"github.com/conops/conops/internal/repoauth"
+	"github.com/conops/conops/internal/store"
+	"github.com/conops/conops/internal/version"
+)
+
+// StateExportFormat is the layout version of export files.
+const StateExportFormat = 1
+
+const (
+	// AuditActionExport records dumping the controller's state.
+	AuditActionExport = "state.export"
+	// AuditActionImport records loading an export into the controller.
+	AuditActionImport = "state.import"
+)
+
+// exportHistoryLimit caps the revisions, sync records and audit entries
+// exported per app; the newest are kept.
+const exportHistoryLimit = 1000
+
+// StateExport is the controller's state as written by export and read by
+// import: every app with its history, and their credentials sealed with
+// the export passphrase rather than the controller's own key.
+type StateExport struct {
+	Format        int           `json:"format"`
+	Version       string        `json:"version"`
+	SchemaVersion int           `json:"schema_version"`
+	ExportedAt    time.Time     `json:"exported_at"`
+	Apps          []ExportedApp `json:"apps"`
+	// Secrets is the JSON of each app's credentials keyed by app ID.
+	Secrets *credentials.PassphraseBox `json:"secrets,omitempty"`
+}
+
+// ExportedApp is one app and its history, newest entries first.
+type ExportedApp struct {
+	App       *App                 `json:"app"`
+	Revisions []*api.AppRevision   `json:"revisions,omitempty"`
+	History   []*api.SyncRecord    `json:"history,omitempty"`
+	Audit     []*api.AuditEntry    `json:"audit,omitempty"`
+	Schedules []*api.ScheduledSync `json:"schedules,omitempty"`
+}
+
+type exportedSecrets struct {
+	DeployKey   string             `json:"deploy_key,omitempty"`
+	ServiceEnvs map[string]string  `json:"service_envs,omitempty"`
+	DockerTLS   *compose.DockerTLS `json:"docker_tls,omitempty"`
+	SOPSKey     string             `json:"sops_key,omitempty"`
+}
+
+// ImportResult lists the apps an import created and the ones it left alone
+// because an app with the same ID already exists.
+type ImportResult struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+// Export dumps every app with its credentials and history.
+func (r *Registry) Export(passphrase string) (*StateExport, error) {
+	ctx := context.Background()
+	schemaVersion, err := r.store.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := r.store.ListApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &StateExport{
+		Format:        StateExportFormat,
+		Version:       version.Version,
+		SchemaVersion: schemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Apps:          []ExportedApp{},
+	}
+	secrets := make(map[string]exportedSecrets)
+	for _, app := range apps {
+		exported := ExportedApp{App: app}
+		if exported.Revisions, err = r.store.ListAppRevisions(ctx, app.ID, exportHistoryLimit); err != nil {
+			return nil, fmt.Errorf("failed to export revisions of %s: %w", app.ID, err)
+		}
+		if exported.History, err = r.store.ListSyncRecords(ctx, app.ID, exportHistoryLimit, 0); err != nil {
+			return nil, fmt.Errorf("failed to export sync history of %s: %w", app.ID, err)
+		}
+		if exported.Audit, err = r.store.ListAuditEntries(ctx, app.ID, exportHistoryLimit); err != nil {
+			return nil, fmt.Errorf("failed to export audit log of %s: %w", app.ID, err)
+		}
+		if exported.Schedules, err = r.store.ListScheduledSyncs(ctx, app.ID); err != nil {
+			return nil, fmt.Errorf("failed to export schedules of %s: %w", app.ID, err)
+		}
+		export.Apps = append(export.Apps, exported)
+
+		appSecrets, err := r.exportSecrets(app.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export credentials of %s: %w", app.ID, err)
+		}
+		if appSecrets != nil {
+			secrets[app.ID] = *appSecrets
+		}
+	}
+
+	// The box is sealed even without secrets so that import checks the
+	// passphrase either way.
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize credentials: %w", err)
+	}
+	defer zeroBytes(plaintext)
+	if export.Secrets, err = credentials.SealWithPassphrase(passphrase, plaintext); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *Registry) exportSecrets(id string) (*exportedSecrets, error) {
+	var secrets exportedSecrets
+	deployKey, err := r.GetDeployKey(id)
+	if err != nil {
+		return nil, err
+	}
+	secrets.DeployKey = string(deployKey)
+	zeroBytes(deployKey)
+	if secrets.ServiceEnvs, err = r.GetAppEnvs(id); err != nil {
+		return nil, err
+	}
+	if secrets.DockerTLS, err = r.GetDockerTLS(id); err != nil {
+		return nil, err
+	}
+	sopsKey, err := r.SOPSKey(id)
+	if err != nil {
+		return nil, err
+	}
+	secrets.SOPSKey = string(sopsKey)
+	zeroBytes(sopsKey)
+	if secrets.DeployKey == "" && len(secrets.ServiceEnvs) == 0 && secrets.DockerTLS == nil && secrets.SOPSKey == "" {
+		return nil, nil
+	}
+	return &secrets, nil
+}
+
+// Import loads an export. Apps keep their IDs, commits, history and
+// credentials, which are re-encrypted with this controller's key. Apps
+// that already exist are skipped. Imported apps are handed to the
+// reconciler as pending, so their stacks are brought up on this host,
+// unless they were stopped or awaiting approval.
+func (r *Registry) Import(export *StateExport, passphrase string) (*ImportResult, error) {
+	if export.Format != StateExportFormat {
+		return nil, fmt.Errorf("invalid export: unsupported format %d", export.Format)
+	}
+	if export.SchemaVersion > store.SchemaVersion {
+		return nil, fmt.Errorf("invalid export: it was written with schema version %d, newer than this binary supports (%d)", export.SchemaVersion, store.SchemaVersion)
+	}
+	if export.Secrets == nil {
+		return nil, fmt.Errorf("invalid export: it has no sealed credentials")
+	}
+	plaintext, err := credentials.OpenWithPassphrase(passphrase, export.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	defer zeroBytes(plaintext)
+	var secrets map[string]exportedSecrets
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid export: failed to deserialize credentials: %w", err)
+	}
+	if len(secrets) > 0 && (r.credentials == nil || !r.credentials.Enabled()) {
+		return nil, fmt.Errorf("encryption support is unavailable: set %s", credentials.EncryptionKeyEnv)
+	}
+
+	result := &ImportResult{Imported: []string{}}
+	for _, exported := range export.Apps {
+		if exported.App == nil || strings.TrimSpace(exported.App.ID) == "" {
+			return result, fmt.Errorf("invalid export: app without an id")
+		}
+		if _, err := r.Get(exported.App.ID); err == nil {
+			result.Skipped = append(result.Skipped, exported.App.ID)
+			continue
+		}
+		if err := r.importApp(exported, secrets[exported.App.ID]); err != nil {
+			return result, fmt.Errorf("failed to import app %s: %w", exported.App.ID, err)
+		}
+		result.Imported = append(result.Imported, exported.App.ID)
+	}
+	return result, nil
+}
+
+// importApp creates one app and its history, removing it again when any
+// part fails. The app is validated like a registered one, so a hand-edited
+// or old export cannot load settings the API refuses.
+func (r *Registry) importApp(exported ExportedApp, secrets exportedSecrets) error {
+	ctx := context.Background()
+	app := *exported.App
+	// The ID names the app's directories.
+	if app.ID == "." || app.ID == ".." || strings.ContainsAny(app.ID, `/\`) {
+		return fmt.Errorf("invalid app id %q", app.ID)
+	}
+	if err := repoauth.ValidateCreateInput(app.RepoURL, app.RepoAuthMethod, secrets.DeployKey); err != nil {
+		return err
+	}
+	if err := r.validateApp(&app); err != nil {
+		return err
+	}
+	if app.Status != StatusStopped && app.Status != StatusAwaitingApproval {
+		app.Status = "pending"
+	}
+	if err := r.store.CreateApp(ctx, &app); err != nil {
+		return err
+	}
+
+	err := func() error {
+		if app.Paused {
+			if err := r.store.UpdateAppPaused(ctx, app.ID, true); err != nil {
+				return err
+			}
+		}
+		if err := r.importSecrets(app.ID, secrets); err != nil {
+			return err
+		}
+		// Revisions and audit entries outlive their app, so an app deleted
+		// here before may have left some behind; those are kept as they are.
+		revisions, err := r.store.ListAppRevisions(ctx, app.ID, 1)
+		if err != nil {
+			return err
+		}
+		if len(revisions) > 0 {
+			exported.Revisions = nil
+		}
+		audited := make(map[string]bool)
+		entries, err := r.store.ListAuditEntries(ctx, app.ID, exportHistoryLimit)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			audited[entry.ID] = true
+		}
+
+		// Revisions are numbered as they are stored, so oldest first.
+		for _, revision := range slices.Backward(exported.Revisions) {
+			revision.AppID = app.ID
+			if err := r.store.CreateAppRevision(ctx, revision); err != nil {
+				return fmt.Errorf("failed to import revision: %w", err)
+			}
+		}
+		for _, record := range exported.History {
+			record.AppID = app.ID
+			if err := r.store.CreateSyncRecord(ctx, record); err != nil {
+				return fmt.Errorf("failed to import sync record: %w", err)
+			}
+		}
+		for _, entry := range slices.Backward(exported.Audit) {
+			if audited[entry.ID] {
+				continue
+			}
+			entry.AppID = app.ID
+			if err := r.store.CreateAuditEntry(ctx, entry); err != nil {
+				return fmt.Errorf("failed to import audit entry: %w", err)
+			}
+		}
+		for _, schedule := range exported.Schedules {
+			schedule.AppID = app.ID
+			if err := r.store.CreateScheduledSync(ctx, schedule); err != nil {
+				return fmt.Errorf("failed to import scheduled sync: %w", err)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		_ = r.store.DeleteApp(ctx, app.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *Registry) importSecrets(id string, secrets exportedSecrets) error {
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
+	cred := &store.AppCredential{AppID: id}
+	if secrets.DeployKey != "" {
+		plaintext := []byte(secrets.DeployKey)
+		ciphertext, nonce, err := r.credentials.Encrypt(plaintext)
+		zeroBytes(plaintext)
+		if err != nil {
+			return err
+		}
+		cred.DeployKeyCiphertext, cred.DeployKeyNonce = ciphertext, nonce
+	}
+	if len(secrets.ServiceEnvs) > 0 {
+		jsonBytes, err := json.Marshal(secrets.ServiceEnvs)
+		if err != nil {
+			return fmt.Errorf("failed to serialize env vars: %w", err)
+		}
+		ciphertext, nonce, err := r.credentials.Encrypt(jsonBytes)
+		zeroBytes(jsonBytes)
+		if err != nil {
+			return err
+		}
+		cred.EnvCiphertext, cred.EnvNonce = ciphertext, nonce
+	}
+	if secrets.DockerTLS != nil {
+		jsonBytes, err := json.Marshal(secrets.DockerTLS)
+		if err != nil {
+			return fmt.Errorf("failed to serialize docker tls: %w", err)
+		}
+		ciphertext, nonce, err := r.credentials.Encrypt(jsonBytes)
+		zeroBytes(jsonBytes)
+		if err != nil {
+			return err
+		}
+		cred.DockerTLSCiphertext, cred.DockerTLSNonce = ciphertext, nonce
+	}
+	if secrets.SOPSKey != "" {
+		ciphertext, nonce, err := r.credentials.Encrypt([]byte(secrets.SOPSKey))
+		if err != nil {
+			return err
+		}
+		cred.SOPSKeyCiphertext, cred.SOPSKeyNonce = ciphertext, nonce
+	}
+	if cred.DeployKeyCiphertext == nil && cred.EnvCiphertext == nil && cred.DockerTLSCiphertext == nil && cred.SOPSKeyCiphertext == nil {
+		return nil
+	}
+	return r.store.UpsertAppCredential(context.Background(), cred)
+}
+
+type exportRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+type importRequest struct {
+	Passphrase string       `json:"passphrase"`
+	Export     *StateExport `json:"export"`
+}
+
+// ExportState handles POST /api/v1/export
+func (h *Handler) ExportState(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionExport, "")
+	export, err := h.Registry.Export(req.Passphrase)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "passphrase") {
+			status = http.StatusBadRequest
+		}
+		h.recordAudit(entry, err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry.Changes = map[string]api.FieldChange{"apps": {To: fmt.Sprint(len(export.Apps))}}
+	h.recordAudit(entry, nil)
+
+	w.Header().Set("Content-Disposition", `attachment; filename="conops-export.json"`)
+	json.NewEncoder(w).Encode(export)
+}
+
+// ImportState handles POST /api/v1/import
+func (h *Handler) ImportState(w http.ResponseWriter, r *http.Request) {
+	var req importRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Export == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionImport, "")
+	result, err := h.Registry.Import(req.Export, req.Passphrase)
+	if result != nil && len(result.Imported) > 0 {
+		entry.Changes = map[string]api.FieldChange{"apps": {To: strings.Join(result.Imported, ",")}}
+	}
+	h.recordAudit(entry, err)
+	if err != nil {
+		status := http.StatusInternalServerError
+		errText := err.Error()
+		if strings.Contains(errText, "invalid") {
+			status = http.StatusBadRequest
+		} else if strings.Contains(errText, "unavailable") {
+			status = http.StatusConflict
+		}
+		var message string
+		if result != nil && len(result.Imported) > 0 {
+			message = fmt.Sprintf("; %d apps were imported before the failure: %s", len(result.Imported), strings.Join(result.Imported, ", "))
+		}
+		http.Error(w, errText+message, status)
+		return
+	}
+	if h.Logger != nil {
+		h.Logger.Info("State imported", "imported", len(result.Imported), "skipped", len(result.Skipped), "actor", entry.Actor)
+	}
+
+	message := fmt.Sprintf("Imported %d apps", len(result.Imported))
+	if len(result.Skipped) > 0 {
+		message += fmt.Sprintf("; skipped %d that already exist", len(result.Skipped))
+	}
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: message,
+		Data:    result,
+	})
+}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weekdayNames are the cron day-of-week names, Sunday first like cron's 0.
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// FreezeWindow is a recurring window during which the reconciler does not
+// deploy new commits. It starts at Start on each of Days and ends at End,
+// on the next day when End is not after Start.
+type FreezeWindow struct {
+	Days     [7]bool // indexed by time.Weekday
+	Start    int     // minutes after midnight
+	End      int
+	Location *time.Location
+}
+
+// ParseFreezeWindow parses "[days] HH:MM-HH:MM [zone]". Days is a cron
+// day-of-week field such as "*", "mon-fri", "sat,sun" or "1-5" and defaults
+// to every day; the zone is an IANA name and defaults to the controller's
+// local time. An end at or before the start runs into the next day, so
+// "fri 18:00-08:00" freezes Friday night and "sat,sun 00:00-00:00" the
+// whole weekend.
+func ParseFreezeWindow(value string) (FreezeWindow, error) {
+	fields := strings.Fields(value)
+	syntaxErr := fmt.Errorf("invalid freeze window %q: want [days] HH:MM-HH:MM [zone]", value)
+	if len(fields) == 0 {
+		return FreezeWindow{}, syntaxErr
+	}
+
+	window := FreezeWindow{Location: time.Local}
+	days := "*"
+	if !strings.Contains(fields[0], ":") {
+		days, fields = fields[0], fields[1:]
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		return FreezeWindow{}, syntaxErr
+	}
+	if err := window.parseDays(days); err != nil {
+		return FreezeWindow{}, fmt.Errorf("invalid freeze window %q: %w", value, err)
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return FreezeWindow{}, syntaxErr
+	}
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return FreezeWindow{}, fmt.Errorf("invalid freeze window %q: %w", value, err)
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return FreezeWindow{}, fmt.Errorf("invalid freeze window %q: %w", value, err)
+	}
+	if len(fields) == 2 {
+		if window.Location, err = time.LoadLocation(fields[1]); err != nil {
+			return FreezeWindow{}, fmt.Errorf("invalid freeze window %q: unknown time zone %s", value, fields[1])
+		}
+	}
+	return window, nil
+}
+
+func (w *FreezeWindow) parseDays(value string) error {
+	if value == "*" {
+		for day := range w.Days {
+			w.Days[day] = true
+		}
+		return nil
+	}
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return err
+			}
+		}
+		// Ranges may wrap past Saturday, e.g. fri-mon.
+		for day := first; ; day = (day + 1) % 7 {
+			w.Days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseWeekday accepts a three-letter day name or a cron day number, where
+// both 0 and 7 are Sunday.
+func parseWeekday(value string) (int, error) {
+	if day := slices.Index(weekdayNames, value); day >= 0 {
+		return day, nil
+	}
+	if day, err := strconv.Atoi(value); err == nil && day >= 0 && day <= 7 {
+		return day % 7, nil
+	}
+	return 0, fmt.Errorf("%q is not a day of the week", value)
+}
+
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Contains reports whether t falls inside the window.
+func (w FreezeWindow) Contains(t time.Time) bool {
+	local := t.In(w.Location)
+	minute := local.Hour()*60 + local.Minute()
+	day := int(local.Weekday())
+	if w.Start < w.End {
+		return w.Days[day] && minute >= w.Start && minute < w.End
+	}
+	previous := (day + 6) % 7
+	return (w.Days[day] && minute >= w.Start) || (w.Days[previous] && minute < w.End)
+}
+
+// endAfter returns when the occurrence of the window containing t ends.
+func (w FreezeWindow) endAfter(t time.Time) time.Time {
+	local := t.In(w.Location)
+	minute := local.Hour()*60 + local.Minute()
+	end := time.Date(local.Year(), local.Month(), local.Day(), w.End/60, w.End%60, 0, 0, w.Location)
+	if w.Start >= w.End && minute >= w.Start {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+func (w FreezeWindow) String() string {
+	window := fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+	if days := w.daysString(); days != "*" {
+		window = days + " " + window
+	}
+	if w.Location == nil || w.Location == time.Local {
+		return window
+	}
+	return window + " " + w.Location.String()
+}
+
+// daysString lists the window's days Monday first, with runs of three or
+// more days written as ranges.
+func (w FreezeWindow) daysString() string {
+	order := []int{1, 2, 3, 4, 5, 6, 0}
+	var parts []string
+	for i := 0; i < len(order); {
+		if !w.Days[order[i]] {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < len(order) && w.Days[order[j+1]] {
+			j++
+		}
+		switch {
+		case i == 0 && j == len(order)-1:
+			return "*"
+		case j-i >= 2:
+			parts = append(parts, weekdayNames[order[i]]+"-"+weekdayNames[order[j]])
+		default:
+			for k := i; k <= j; k++ {
+				parts = append(parts, weekdayNames[order[k]])
+			}
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// normalizeFreezeWindows validates freeze windows and returns them in
+// canonical form without blanks or duplicates.
+func normalizeFreezeWindows(values []string) ([]string, error) {
+	var normalized []string
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		window, err := ParseFreezeWindow(value)
+		if err != nil {
+			return nil, err
+		}
+		if canonical := window.String(); !slices.Contains(normalized, canonical) {
+			normalized = append(normalized, canonical)
+		}
+	}
+	return normalized, nil
+}
+
+// FrozenUntil returns when the freeze windows of app covering now end, or
+// the zero time when none does. Back-to-back and overlapping windows are
+// followed to the end of the last one.
+func FrozenUntil(app *App, now time.Time) time.Time {
+	var windows []FreezeWindow
+	for _, value := range app.FreezeWindows {
+		if window, err := ParseFreezeWindow(value); err == nil {
+			windows = append(windows, window)
+		}
+	}
+
+	var until time.Time
+	at := now
+	// Every step moves at least a minute forward; a week of windows bounds
+	// the walk for windows covering every day.
+	for step := 0; step < 7*len(windows); step++ {
+		var next time.Time
+		for _, window := range windows {
+			if window.Contains(at) {
+				if end := window.endAfter(at); end.After(next) {
+					next = end
+				}
+			}
+		}
+		if next.IsZero() {
+			break
+		}
+		until, at = next, next
+	}
+	return until
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/conops/conops/internal/api"
 	"github.com/conops/conops/internal/repoauth"
+	"github.com/conops/conops/internal/semver"
+	"github.com/conops/conops/internal/signing"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -22,6 +27,38 @@ type GitWatcher struct {
 	Registry *Registry
 	Logger   *slog.Logger
 	CacheDir string
+
+	mu       sync.Mutex
+	lastPoll PollActivity
+	// cacheMu is held for writing while a repo cache is removed, so no
+	// check is using one at the time.
+	cacheMu sync.RWMutex
+}
+
+// PollActivity describes the watcher's most recent repository check.
+type PollActivity struct {
+	At    time.Time
+	AppID string
+	// Error is empty when the check succeeded.
+	Error string
+}
+
+// LastPoll returns the most recent repository check; its time is zero
+// before the first one.
+func (w *GitWatcher) LastPoll() PollActivity {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastPoll
+}
+
+func (w *GitWatcher) recordPoll(appID string, err error) {
+	activity := PollActivity{At: time.Now(), AppID: appID}
+	if err != nil {
+		activity.Error = err.Error()
+	}
+	w.mu.Lock()
+	w.lastPoll = activity
+	w.mu.Unlock()
 }
 
 // NewGitWatcher creates a new Git watcher.
@@ -39,7 +76,7 @@ func (w *GitWatcher) Start(ctx context.Context) {
 	defer ticker.Stop()
 
 	// Track running pollers to avoid duplicates
-	pollers := make(map[string]context.CancelFunc)
+	pollers := make(map[string]appPoller)
 	syncPollers := func() {
 		apps := w.Registry.List()
 		w.Logger.Debug("Registry poll tick", "app_count", len(apps))
@@ -47,19 +84,27 @@ func (w *GitWatcher) Start(ctx context.Context) {
 
 		for _, app := range apps {
 			activeIDs[app.ID] = true
-			if _, running := pollers[app.ID]; !running {
-				// Start a new poller for this app
-				pollCtx, cancel := context.WithCancel(ctx)
-				pollers[app.ID] = cancel
+			if existing, running := pollers[app.ID]; running {
+				if existing.matches(app) {
+					continue
+				}
+				// Source settings changed (e.g. branch edited via PATCH); restart
+				// the poller so it tracks the new configuration.
+				existing.cancel()
+				w.Logger.Info("Restarting app poller after config change", "id", app.ID, "branch", app.Branch, "interval", app.PollInterval)
+			} else {
 				w.Logger.Info("Starting app poller", "id", app.ID, "interval", app.PollInterval)
-				go w.pollApp(pollCtx, app)
 			}
+
+			pollCtx, cancel := context.WithCancel(ctx)
+			pollers[app.ID] = newAppPoller(app, cancel)
+			go w.pollApp(pollCtx, app)
 		}
 
 		// Cleanup stopped apps
-		for id, cancel := range pollers {
+		for id, poller := range pollers {
 			if !activeIDs[id] {
-				cancel()
+				poller.cancel()
 				delete(pollers, id)
 				w.Logger.Info("Stopped app poller", "id", id)
 			}
@@ -80,6 +125,29 @@ func (w *GitWatcher) Start(ctx context.Context) {
 	}
 }
 
+// appPoller records the source settings a running poller was started with.
+type appPoller struct {
+	cancel       context.CancelFunc
+	repoURL      string
+	branch       string
+	track        string
+	pollInterval string
+}
+
+func newAppPoller(app *App, cancel context.CancelFunc) appPoller {
+	return appPoller{
+		cancel:       cancel,
+		repoURL:      app.RepoURL,
+		branch:       app.Branch,
+		track:        app.Track,
+		pollInterval: app.PollInterval,
+	}
+}
+
+func (p appPoller) matches(app *App) bool {
+	return p.repoURL == app.RepoURL && p.branch == app.Branch && p.track == app.Track && p.pollInterval == app.PollInterval
+}
+
 func (w *GitWatcher) pollApp(ctx context.Context, app *App) {
 	interval, err := time.ParseDuration(app.PollInterval)
 	if err != nil {
@@ -93,7 +161,9 @@ func (w *GitWatcher) pollApp(ctx context.Context, app *App) {
 	w.Logger.Info("Started polling app", "id", app.ID, "repo", app.RepoURL)
 
 	// Run the first check immediately for new apps.
-	if err := w.checkRepo(app); err != nil {
+	err = w.checkRepo(app)
+	w.recordPoll(app.ID, err)
+	if err != nil {
 		w.Logger.Error("Failed initial repo check", "id", app.ID, "error", err)
 	}
 
@@ -103,14 +173,26 @@ func (w *GitWatcher) pollApp(ctx context.Context, app *App) {
 			return
 		case <-ticker.C:
 			w.Logger.Debug("Polling repo", "id", app.ID, "repo", app.RepoURL, "branch", app.Branch)
-			if err := w.checkRepo(app); err != nil {
+			err := w.checkRepo(app)
+			w.recordPoll(app.ID, err)
+			if err != nil {
 				w.Logger.Error("Failed to check repo", "id", app.ID, "error", err)
 			}
 		}
 	}
 }
 
+// RemoveCache deletes the app's repo cache. A poller still running for the
+// app clones it again on its next check.
+func (w *GitWatcher) RemoveCache(appID string) error {
+	w.cacheMu.Lock()
+	defer w.cacheMu.Unlock()
+	return os.RemoveAll(filepath.Join(w.CacheDir, appID))
+}
+
 func (w *GitWatcher) checkRepo(app *App) error {
+	w.cacheMu.RLock()
+	defer w.cacheMu.RUnlock()
 	repoPath := filepath.Join(w.CacheDir, app.ID)
 	w.Logger.Debug("Checking repo state", "id", app.ID, "path", repoPath)
 
@@ -147,10 +229,14 @@ func (w *GitWatcher) checkRepo(app *App) error {
 	w.Logger.Debug("Fetching latest", "id", app.ID, "remote", "origin")
 
 	// First fetch all changes
+	refSpecs := []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"}
+	if app.Track != "" {
+		refSpecs = append(refSpecs, "+refs/tags/*:refs/tags/*")
+	}
 	err = repo.Fetch(&git.FetchOptions{
 		RemoteName: "origin",
 		Progress:   nil,
-		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		RefSpecs:   refSpecs,
 		Auth:       auth,
 	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
@@ -160,15 +246,14 @@ func (w *GitWatcher) checkRepo(app *App) error {
 		w.Logger.Debug("Fetch up to date", "id", app.ID)
 	}
 
-	// Resolve remote branch and checkout its latest commit
-	remoteRefName := plumbing.NewRemoteReferenceName("origin", app.Branch)
-	remoteRef, refErr := repo.Reference(remoteRefName, true)
-	if refErr != nil {
-		return fmt.Errorf("remote branch not found: %w", refErr)
+	// Resolve the branch head or tracked tag and checkout its commit
+	target, targetRef, err := resolveTarget(repo, app)
+	if err != nil {
+		return err
 	}
-	w.Logger.Debug("Checking out remote commit", "id", app.ID, "branch", app.Branch, "remote_hash", remoteRef.Hash().String())
+	w.Logger.Debug("Checking out remote commit", "id", app.ID, "ref", targetRef, "remote_hash", target.String())
 	err = worktree.Checkout(&git.CheckoutOptions{
-		Hash:  remoteRef.Hash(),
+		Hash:  target,
 		Force: true,
 	})
 	if err != nil {
@@ -190,10 +275,31 @@ func (w *GitWatcher) checkRepo(app *App) error {
 	// Check if changed
 	if commitHash == app.LastSeenCommit {
 		w.Logger.Debug("No new commit detected", "id", app.ID, "commit", commitHash)
+		if app.LastSeenCommitInfo == nil {
+			// Backfill apps whose commit was seen before metadata was
+			// recorded; after a failure, wait for the next commit.
+			app.LastSeenCommitInfo = w.recordCommitInfo(repo, app.ID, ref.Hash())
+			if app.LastSeenCommitInfo == nil {
+				app.LastSeenCommitInfo = &api.CommitInfo{}
+			}
+		}
 		return nil
 	}
 
-	w.Logger.Info("New commit detected", "id", app.ID, "commit", commitHash)
+	// In a monorepo most pushes belong to other apps; only a change under
+	// the app's watched paths needs a redeploy.
+	skipped, err := w.skipUnwatchedCommit(repo, app.ID, ref.Hash(), commitMessage)
+	if err != nil {
+		w.Logger.Warn("Failed to compare commit with watched paths; treating it as a change", "id", app.ID, "commit", commitHash, "error", err)
+	}
+	if skipped {
+		app.LastSeenCommit = commitHash
+		app.LastSeenCommitMessage = commitMessage
+		app.LastSeenCommitInfo = w.recordCommitInfo(repo, app.ID, ref.Hash())
+		return nil
+	}
+
+	w.Logger.Info("New commit detected", "id", app.ID, "commit", commitHash, "ref", targetRef)
 
 	// Update registry
 	if err := w.Registry.UpdateCommitWithMessage(app.ID, commitHash, commitMessage); err != nil {
@@ -202,10 +308,181 @@ func (w *GitWatcher) checkRepo(app *App) error {
 	app.LastSeenCommit = commitHash
 	app.LastSeenCommitMessage = commitMessage
 	w.Logger.Debug("Registry updated", "id", app.ID, "commit", commitHash)
+	app.LastSeenCommitInfo = w.recordCommitInfo(repo, app.ID, ref.Hash())
 
 	return nil
 }
 
+// skipUnwatchedCommit records target as synced without a redeploy when it
+// changes nothing under the app's watched paths relative to the deployed
+// commit. Only settled apps are advanced; a pending or failed app still needs
+// a sync of the latest commit.
+func (w *GitWatcher) skipUnwatchedCommit(repo *git.Repository, appID string, target plumbing.Hash, message string) (bool, error) {
+	app, err := w.Registry.Get(appID)
+	if err != nil {
+		return false, err
+	}
+	if app.Status != "synced" || app.LastSyncedCommit == "" || app.LastSyncedCommit != app.LastSeenCommit {
+		return false, nil
+	}
+
+	deployed, err := repo.CommitObject(plumbing.NewHash(app.LastSyncedCommit))
+	if err != nil {
+		return false, fmt.Errorf("deployed commit %s not found: %w", app.LastSyncedCommit, err)
+	}
+	next, err := repo.CommitObject(target)
+	if err != nil {
+		return false, fmt.Errorf("commit %s not found: %w", target, err)
+	}
+	watchPaths := effectiveWatchPaths(app)
+	changed, err := changedWatchedPath(deployed, next, watchPaths)
+	if err != nil || changed != "" {
+		return false, err
+	}
+
+	if err := w.Registry.SkipCommit(appID, target.String(), message); err != nil {
+		return false, err
+	}
+	w.Logger.Info("New commit does not touch watched paths; skipping sync", "id", appID, "commit", target.String(), "watch_paths", watchPaths)
+	return true, nil
+}
+
+// recordCommitInfo stores the author, committer and changed files of the
+// app's desired commit. Failures are logged and return nil; the commit is
+// still synced.
+func (w *GitWatcher) recordCommitInfo(repo *git.Repository, appID string, hash plumbing.Hash) *api.CommitInfo {
+	info, err := func() (*api.CommitInfo, error) {
+		app, err := w.Registry.Get(appID)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		info, err := readCommitInfo(repo, commit, app.LastSyncedCommit)
+		if err != nil {
+			return nil, err
+		}
+		return info, w.Registry.UpdateCommitInfo(appID, hash.String(), info)
+	}()
+	if err != nil {
+		w.Logger.Warn("Failed to record commit metadata", "id", appID, "commit", hash.String(), "error", err)
+		return nil
+	}
+	return info
+}
+
+// resolveTarget returns the commit an app should run and the ref it came
+// from: the head of its branch or, when the app tracks a semver constraint,
+// the highest matching tag.
+func resolveTarget(repo *git.Repository, app *App) (plumbing.Hash, string, error) {
+	if app.Track == "" {
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", app.Branch), true)
+		if err != nil {
+			return plumbing.ZeroHash, "", fmt.Errorf("remote branch not found: %w", err)
+		}
+		return remoteRef.Hash(), app.Branch, nil
+	}
+
+	constraint, err := semver.ParseConstraint(app.Track)
+	if err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+	tags, err := repo.Tags()
+	if err != nil {
+		return plumbing.ZeroHash, "", fmt.Errorf("failed to list tags: %w", err)
+	}
+	hashes := make(map[string]plumbing.Hash)
+	var names []string
+	_ = tags.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		names = append(names, name)
+		hashes[name] = ref.Hash()
+		return nil
+	})
+	tag, ok := constraint.Highest(names)
+	if !ok {
+		return plumbing.ZeroHash, "", fmt.Errorf("no tag matches %q", app.Track)
+	}
+
+	hash := hashes[tag]
+	// Annotated tags point at a tag object; peel it to the commit.
+	if tagObj, err := repo.TagObject(hash); err == nil {
+		commit, err := tagObj.Commit()
+		if err != nil {
+			return plumbing.ZeroHash, "", fmt.Errorf("tag %s does not point to a commit: %w", tag, err)
+		}
+		hash = commit.Hash
+	}
+	return hash, tag, nil
+}
+
+// VerifyCommit checks commit, which must already be in the app's repo cache,
+// against the app's allowed signing keys.
+func (w *GitWatcher) VerifyCommit(app *App, commit string) (string, error) {
+	w.cacheMu.RLock()
+	defer w.cacheMu.RUnlock()
+	repo, err := git.PlainOpen(filepath.Join(w.CacheDir, app.ID))
+	if err != nil {
+		return "", fmt.Errorf("git error: %w", err)
+	}
+	commitObj, err := repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return "", fmt.Errorf("commit %s not found in repo cache: %w", commit, err)
+	}
+	return signing.VerifyCommit(commitObj, app.SigningKeys)
+}
+
+// ResolveRevision returns the full hash of a tag or abbreviated commit hash
+// of the app's repository. Tags are fetched first, since the repo cache only
+// keeps them for apps that track a constraint.
+func (w *GitWatcher) ResolveRevision(app *App, revision string) (string, error) {
+	w.cacheMu.RLock()
+	defer w.cacheMu.RUnlock()
+	repo, err := git.PlainOpen(filepath.Join(w.CacheDir, app.ID))
+	if err != nil {
+		return "", fmt.Errorf("git error: %w", err)
+	}
+	if _, err := repo.Tag(revision); err != nil {
+		auth, err := w.authForApp(app)
+		if err != nil {
+			return "", err
+		}
+		err = repo.Fetch(&git.FetchOptions{
+			RemoteName: "origin",
+			RefSpecs:   []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+			Auth:       auth,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return "", fmt.Errorf("fetch error: %w", err)
+		}
+	}
+
+	var hash plumbing.Hash
+	if ref, err := repo.Tag(revision); err == nil {
+		hash = ref.Hash()
+		// Annotated tags point at a tag object; peel it to the commit.
+		if tagObj, err := repo.TagObject(hash); err == nil {
+			commit, err := tagObj.Commit()
+			if err != nil {
+				return "", fmt.Errorf("tag %s does not point to a commit: %w", revision, err)
+			}
+			hash = commit.Hash
+		}
+	} else {
+		resolved, err := repo.ResolveRevision(plumbing.Revision(revision))
+		if err != nil {
+			return "", fmt.Errorf("no tag or commit %s in the repository", revision)
+		}
+		hash = *resolved
+	}
+	if _, err := repo.CommitObject(hash); err != nil {
+		return "", fmt.Errorf("%s is not a commit: %w", revision, err)
+	}
+	return hash.String(), nil
+}
+
 func commitSubject(message string) string {
 	for _, line := range strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n") {
 		trimmed := strings.TrimSpace(line)
@@ -230,21 +507,26 @@ func (w *GitWatcher) authForApp(app *App) (transport.AuthMethod, error) {
 	}
 	defer zeroBytes(deployKey)
 
-	knownHostsPath, err := repoauth.ResolveKnownHostsPath()
+	knownHostsPath, err := repoauth.ResolveKnownHostsPath(app.RepoURL)
+	if err != nil {
+		return nil, err
+	}
+	host, port, err := repoauth.SSHHostPort(app.RepoURL)
 	if err != nil {
 		return nil, err
 	}
-	callback, err := repoauth.NewHostKeyCallback(knownHostsPath)
+	callback, algorithms, err := repoauth.HostKeyCallback(knownHostsPath, net.JoinHostPort(host, port))
 	if err != nil {
 		return nil, err
 	}
 
-	auth, err := gitssh.NewPublicKeys("git", deployKey, "")
+	auth, err := gitssh.NewPublicKeys(repoauth.UserFromRepoURL(app.RepoURL), deployKey, "")
 	if err != nil {
 		return nil, fmt.Errorf("invalid deploy key: %w", err)
 	}
 	auth.HostKeyCallbackHelper = gitssh.HostKeyCallbackHelper{
-		HostKeyCallback: callback,
+		HostKeyCallback:   callback,
+		HostKeyAlgorithms: algorithms,
 	}
 	return auth, nil
 }
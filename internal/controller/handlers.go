@@ -3,33 +3,85 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/store"
+	"github.com/conops/conops/internal/version"
 	"github.com/go-chi/chi/v5"
 )
 
 type registerAppRequest struct {
-	Name           string            `json:"name"`
-	RepoURL        string            `json:"repo_url"`
-	RepoAuthMethod string            `json:"repo_auth_method"`
-	DeployKey      string            `json:"deploy_key"`
-	Branch         string            `json:"branch"`
-	ComposePath    string            `json:"compose_path"`
-	PollInterval   string            `json:"poll_interval"`
-	ServiceEnvs    map[string]string `json:"service_envs"`
+	Name            string             `json:"name"`
+	RepoURL         string             `json:"repo_url"`
+	RepoAuthMethod  string             `json:"repo_auth_method"`
+	DeployKey       string             `json:"deploy_key"`
+	Branch          string             `json:"branch"`
+	ComposePath     string             `json:"compose_path"`
+	PollInterval    string             `json:"poll_interval"`
+	ServiceEnvs     map[string]string  `json:"service_envs"`
+	GateScript      string             `json:"gate_script"`
+	NotifyWebhooks  []string           `json:"notify_webhooks"`
+	SlackWebhookURL string             `json:"slack_webhook_url"`
+	SlackChannel    string             `json:"slack_channel"`
+	NotifyEvents    []string           `json:"notify_events"`
+	QuietHours      string             `json:"quiet_hours"`
+	SigningKeys     []string           `json:"signing_keys"`
+	Track           string             `json:"track"`
+	WatchPaths      []string           `json:"watch_paths"`
+	Profiles        []string           `json:"profiles"`
+	EnvFile         string             `json:"env_file"`
+	PreviewTTL      string             `json:"preview_ttl"`
+	PostDeploy      []string           `json:"post_deploy"`
+	PreDeploy       []string           `json:"pre_deploy"`
+	Priority        string             `json:"priority"`
+	RequireApproval bool               `json:"require_approval"`
+	PinImages       bool               `json:"pin_images"`
+	FreezeWindows   []string           `json:"freeze_windows"`
+	DockerHost      string             `json:"docker_host"`
+	DockerTLS       *compose.DockerTLS `json:"docker_tls"`
+	SOPSKey         string             `json:"sops_key"`
 }
 
 type updateAppRequest struct {
-	Name         *string            `json:"name,omitempty"`
-	Branch       *string            `json:"branch,omitempty"`
-	ComposePath  *string            `json:"compose_path,omitempty"`
-	PollInterval *string            `json:"poll_interval,omitempty"`
-	ServiceEnvs  *map[string]string `json:"service_envs,omitempty"`
+	Name            *string            `json:"name,omitempty"`
+	Branch          *string            `json:"branch,omitempty"`
+	ComposePath     *string            `json:"compose_path,omitempty"`
+	PollInterval    *string            `json:"poll_interval,omitempty"`
+	ServiceEnvs     *map[string]string `json:"service_envs,omitempty"`
+	GateScript      *string            `json:"gate_script,omitempty"`
+	NotifyWebhooks  *[]string          `json:"notify_webhooks,omitempty"`
+	SlackWebhookURL *string            `json:"slack_webhook_url,omitempty"`
+	SlackChannel    *string            `json:"slack_channel,omitempty"`
+	NotifyEvents    *[]string          `json:"notify_events,omitempty"`
+	QuietHours      *string            `json:"quiet_hours,omitempty"`
+	SigningKeys     *[]string          `json:"signing_keys,omitempty"`
+	Track           *string            `json:"track,omitempty"`
+	WatchPaths      *[]string          `json:"watch_paths,omitempty"`
+	Profiles        *[]string          `json:"profiles,omitempty"`
+	EnvFile         *string            `json:"env_file,omitempty"`
+	PreviewTTL      *string            `json:"preview_ttl,omitempty"`
+	PostDeploy      *[]string          `json:"post_deploy,omitempty"`
+	PreDeploy       *[]string          `json:"pre_deploy,omitempty"`
+	Priority        *string            `json:"priority,omitempty"`
+	RequireApproval *bool              `json:"require_approval,omitempty"`
+	PinImages       *bool              `json:"pin_images,omitempty"`
+	FreezeWindows   *[]string          `json:"freeze_windows,omitempty"`
+	DockerHost      *string            `json:"docker_host,omitempty"`
+	// DockerTLS replaces the docker host's TLS material; an empty object
+	// removes it.
+	DockerTLS *compose.DockerTLS `json:"docker_tls,omitempty"`
+	// SOPSKey replaces the key that decrypts the app's SOPS env file; an
+	// empty value removes it.
+	SOPSKey *string `json:"sops_key,omitempty"`
 }
 
 // RuntimeCleaner performs best-effort runtime cleanup for an app.
@@ -37,16 +89,24 @@ type RuntimeCleaner interface {
 	Destroy(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error)
 }
 
+// RuntimeVolumeCleaner removes an app's stack together with its volumes.
+type RuntimeVolumeCleaner interface {
+	DestroyVolumes(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error)
+}
+
+// manualSyncTimeout bounds syncs requested through the API rather than
+// started by the reconciler.
+const manualSyncTimeout = 10 * time.Minute
+
 // RuntimeApplier applies desired app state to the runtime.
 type RuntimeApplier interface {
-	Apply(
-		ctx context.Context,
-		appID, content string,
-		envVars map[string]string,
-		repoURL, branch, composePath, commitHash string,
-		deployKey []byte,
-		onProgress func(string),
-	) (string, error)
+	Apply(ctx context.Context, opts compose.ApplyOptions) (*compose.Transcript, error)
+}
+
+// RevisionResolver resolves a tag or abbreviated commit hash of an app's
+// repository to the full commit hash.
+type RevisionResolver interface {
+	ResolveRevision(app *App, revision string) (string, error)
 }
 
 // Handler handles HTTP requests for the controller.
@@ -54,16 +114,50 @@ type Handler struct {
 	Registry *Registry
 	Cleaner  RuntimeCleaner
 	Applier  RuntimeApplier
+	Syncer   *Syncer
+	Planner  RuntimePlanner
+	Renderer RuntimeRenderer
+	// Inspector, when the runtime supports it, reports container counts to
+	// the health endpoint.
+	Inspector RuntimeInspector
+	// Restarter, when the runtime supports it, restarts single services.
+	Restarter RuntimeRestarter
+	// LogStreamer, when the runtime supports it, streams service logs.
+	LogStreamer RuntimeLogStreamer
+	// Revisions, when set, lets rollbacks name a tag or an abbreviated hash.
+	Revisions RevisionResolver
+	// Stats serves the resource usage snapshots; nil disables the endpoint.
+	Stats *StatsCollector
+	// HealthStatusCodes maps each app health state to the HTTP status the
+	// health endpoint answers with; nil uses DefaultHealthStatusCodes.
+	HealthStatusCodes map[string]int
+	// Leader reports whether this controller is the one deploying; nil
+	// means it always is.
+	Leader func() bool
+	// BasePath is the path prefix the API is served under, e.g. "/conops",
+	// or empty; it is put before the URLs responses link to.
+	BasePath string
 	Logger   *slog.Logger
 }
 
 // NewHandler creates a new controller handler.
 func NewHandler(registry *Registry, cleaner RuntimeCleaner, applier RuntimeApplier, logger *slog.Logger) *Handler {
+	planner, _ := applier.(RuntimePlanner)
+	renderer, _ := applier.(RuntimeRenderer)
+	inspector, _ := applier.(RuntimeInspector)
+	restarter, _ := applier.(RuntimeRestarter)
+	logStreamer, _ := applier.(RuntimeLogStreamer)
 	return &Handler{
-		Registry: registry,
-		Cleaner:  cleaner,
-		Applier:  applier,
-		Logger:   logger,
+		Registry:    registry,
+		Cleaner:     cleaner,
+		Applier:     applier,
+		Syncer:      NewSyncer(registry, applier, logger),
+		Planner:     planner,
+		Renderer:    renderer,
+		Inspector:   inspector,
+		Restarter:   restarter,
+		LogStreamer: logStreamer,
+		Logger:      logger,
 	}
 }
 
@@ -76,18 +170,59 @@ func (h *Handler) RegisterApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	app := App{
-		Name:           strings.TrimSpace(req.Name),
-		RepoURL:        strings.TrimSpace(req.RepoURL),
-		RepoAuthMethod: strings.TrimSpace(req.RepoAuthMethod),
-		Branch:         strings.TrimSpace(req.Branch),
-		ComposePath:    strings.TrimSpace(req.ComposePath),
-		PollInterval:   strings.TrimSpace(req.PollInterval),
+		Name:            strings.TrimSpace(req.Name),
+		RepoURL:         strings.TrimSpace(req.RepoURL),
+		RepoAuthMethod:  strings.TrimSpace(req.RepoAuthMethod),
+		Branch:          strings.TrimSpace(req.Branch),
+		ComposePath:     strings.TrimSpace(req.ComposePath),
+		PollInterval:    strings.TrimSpace(req.PollInterval),
+		GateScript:      req.GateScript,
+		NotifyWebhooks:  req.NotifyWebhooks,
+		SlackWebhookURL: req.SlackWebhookURL,
+		SlackChannel:    req.SlackChannel,
+		NotifyEvents:    req.NotifyEvents,
+		QuietHours:      req.QuietHours,
+		SigningKeys:     req.SigningKeys,
+		Track:           strings.TrimSpace(req.Track),
+		WatchPaths:      req.WatchPaths,
+		Profiles:        req.Profiles,
+		EnvFile:         req.EnvFile,
+		PreviewTTL:      req.PreviewTTL,
+		PostDeploy:      req.PostDeploy,
+		PreDeploy:       req.PreDeploy,
+		Priority:        req.Priority,
+		RequireApproval: req.RequireApproval,
+		PinImages:       req.PinImages,
+		FreezeWindows:   req.FreezeWindows,
+		DockerHost:      strings.TrimSpace(req.DockerHost),
 	}
 
 	if app.Name == "" || app.RepoURL == "" {
 		http.Error(w, "App name and repo URL are required", http.StatusBadRequest)
 		return
 	}
+	hasDockerTLS := req.DockerTLS != nil && *req.DockerTLS != (compose.DockerTLS{})
+	if hasDockerTLS {
+		if err := validateDockerTLSFor(app.DockerHost, req.DockerTLS); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.Registry.CanStoreDockerTLS(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	}
+	hasSOPSKey := strings.TrimSpace(req.SOPSKey) != ""
+	if hasSOPSKey {
+		if err := validateSOPSKey(req.SOPSKey); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.Registry.CanStoreSOPSKey(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	}
 
 	if err := h.Registry.AddWithDeployKeyAndEnvs(&app, req.DeployKey, req.ServiceEnvs); err != nil {
 		status := http.StatusConflict
@@ -98,6 +233,30 @@ func (h *Handler) RegisterApp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), status)
 		return
 	}
+	if hasDockerTLS {
+		if err := h.Registry.SetDockerTLS(app.ID, req.DockerTLS); err != nil {
+			_ = h.Registry.Delete(app.ID)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if hasSOPSKey {
+		if err := h.Registry.SetSOPSKey(app.ID, req.SOPSKey); err != nil {
+			_ = h.Registry.Delete(app.ID)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	entry := NewAuditEntry(r, AuditActionCreate, app.ID)
+	entry.Changes = DiffAppFields(nil, &app)
+	if hasDockerTLS {
+		entry.Changes["docker_tls"] = api.FieldChange{To: "(redacted)"}
+	}
+	if hasSOPSKey {
+		entry.Changes["sops_key"] = api.FieldChange{To: "(redacted)"}
+	}
+	h.recordAudit(entry, nil)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(api.APIResponse{
@@ -108,12 +267,80 @@ func (h *Handler) RegisterApp(w http.ResponseWriter, r *http.Request) {
 
 // ListApps handles GET /api/v1/apps
 func (h *Handler) ListApps(w http.ResponseWriter, r *http.Request) {
-	apps := h.Registry.List()
+	query, err := parseAppQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	apps, total, err := h.Registry.Query(query)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid") {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	json.NewEncoder(w).Encode(api.APIResponse{
 		Data: apps,
 	})
 }
 
+// maxAppsPerPage caps the per_page parameter of the apps list.
+const maxAppsPerPage = 500
+
+// defaultAppsPerPage is the page size when only page is given.
+const defaultAppsPerPage = 50
+
+// parseAppQuery reads the filter, sort and page parameters of the apps
+// list. Without page or per_page every matching app is returned.
+func parseAppQuery(values url.Values) (store.AppQuery, error) {
+	query := store.AppQuery{
+		Search: strings.TrimSpace(values.Get("q")),
+		Sort:   strings.TrimSpace(values.Get("sort")),
+	}
+	for _, value := range values["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, status)
+			}
+		}
+	}
+	switch fields := strings.TrimSpace(values.Get("fields")); fields {
+	case "", "full":
+	case "summary":
+		query.Summary = true
+	default:
+		return query, fmt.Errorf("invalid fields %q: use full or summary", fields)
+	}
+
+	page, perPage := 0, 0
+	if value := strings.TrimSpace(values.Get("page")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return query, fmt.Errorf("invalid page")
+		}
+		page, perPage = parsed, defaultAppsPerPage
+	}
+	if value := strings.TrimSpace(values.Get("per_page")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxAppsPerPage {
+			return query, fmt.Errorf("invalid per_page: use 1 to %d", maxAppsPerPage)
+		}
+		perPage = parsed
+	}
+	if perPage > 0 {
+		if page == 0 {
+			page = 1
+		}
+		query.Limit = perPage
+		query.Offset = (page - 1) * perPage
+	}
+	return query, nil
+}
+
 // GetApp handles GET /api/v1/apps/{id}
 func (h *Handler) GetApp(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -128,6 +355,35 @@ func (h *Handler) GetApp(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetAppManifest handles GET /api/v1/apps/{id}/manifest
+func (h *Handler) GetAppManifest(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.Planner == nil {
+		http.Error(w, "runtime does not support manifests", http.StatusNotImplemented)
+		return
+	}
+
+	envVars, err := h.Registry.GetAppEnvs(app.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan, err := h.Planner.Plan(app.ID, app.ComposePath, app.Profiles, app.EnvFile, envVars)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: BuildManifest(app, envVars, plan),
+	})
+}
+
 // DeleteApp handles DELETE /api/v1/apps/{id}
 func (h *Handler) DeleteApp(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -138,11 +394,33 @@ func (h *Handler) DeleteApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.rejectOnStandby(w) {
+		return
+	}
+
+	// ?volumes=true also removes the app's volumes and the data in them.
+	removeVolumes := false
+	if value := strings.TrimSpace(r.URL.Query().Get("volumes")); value != "" {
+		if removeVolumes, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "invalid volumes: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
 	if h.Cleaner != nil {
 		cleanupCtx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
 		defer cancel()
 
-		if _, err := h.Cleaner.Destroy(cleanupCtx, app.ID, app.ComposePath, nil); err != nil {
+		destroy := h.Cleaner.Destroy
+		if removeVolumes {
+			volumeCleaner, ok := h.Cleaner.(RuntimeVolumeCleaner)
+			if !ok {
+				http.Error(w, "runtime cannot remove volumes", http.StatusNotImplemented)
+				return
+			}
+			destroy = volumeCleaner.DestroyVolumes
+		}
+		if _, err := destroy(cleanupCtx, app.ID, app.ComposePath, nil); err != nil {
 			if h.Logger != nil {
 				h.Logger.Error("Failed to cleanup app runtime", "id", app.ID, "error", err)
 			}
@@ -155,10 +433,17 @@ func (h *Handler) DeleteApp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	entry := NewAuditEntry(r, AuditActionDelete, app.ID)
+	message := "App deleted successfully"
+	if removeVolumes {
+		entry.Changes = map[string]api.FieldChange{"volumes": {From: "kept", To: "removed"}}
+		message = "App and its volumes deleted successfully"
+	}
+	h.recordAudit(entry, nil)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(api.APIResponse{
-		Message: "App deleted successfully",
+		Message: message,
 	})
 }
 
@@ -178,45 +463,92 @@ func (h *Handler) UpdateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Collect current values and update with new ones if provided
-	name := app.Name
-	branch := app.Branch
-	composePath := app.ComposePath
-	pollInterval := app.PollInterval
-	var serviceEnvs map[string]string
-
-	// Track if sync-affecting fields changed
-	branchChanged := false
-	composePathChanged := false
-	envVarsChanged := false
+	update := AppUpdate{
+		Name:            req.Name,
+		Branch:          req.Branch,
+		ComposePath:     req.ComposePath,
+		PollInterval:    req.PollInterval,
+		GateScript:      req.GateScript,
+		NotifyWebhooks:  req.NotifyWebhooks,
+		SlackWebhookURL: req.SlackWebhookURL,
+		SlackChannel:    req.SlackChannel,
+		NotifyEvents:    req.NotifyEvents,
+		QuietHours:      req.QuietHours,
+		SigningKeys:     req.SigningKeys,
+		Track:           req.Track,
+		WatchPaths:      req.WatchPaths,
+		Profiles:        req.Profiles,
+		EnvFile:         req.EnvFile,
+		PreviewTTL:      req.PreviewTTL,
+		PostDeploy:      req.PostDeploy,
+		PreDeploy:       req.PreDeploy,
+		Priority:        req.Priority,
+		RequireApproval: req.RequireApproval,
+		PinImages:       req.PinImages,
+		FreezeWindows:   req.FreezeWindows,
+		DockerHost:      req.DockerHost,
+	}
 
-	if req.Name != nil {
-		name = strings.TrimSpace(*req.Name)
+	// A new docker host moves the stack: it is deployed there and taken
+	// down on the old one, which must not happen halfway through a sync.
+	dockerHostChanged := req.DockerHost != nil && strings.TrimSpace(*req.DockerHost) != app.DockerHost
+	dockerHost := app.DockerHost
+	if req.DockerHost != nil {
+		dockerHost = strings.TrimSpace(*req.DockerHost)
+		if err := compose.ValidateDockerHost(dockerHost); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := validateDockerTLSFor(dockerHost, req.DockerTLS); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if req.Branch != nil {
-		newBranch := strings.TrimSpace(*req.Branch)
-		if newBranch != app.Branch {
-			branchChanged = true
+	if req.DockerTLS != nil && *req.DockerTLS != (compose.DockerTLS{}) {
+		if err := h.Registry.CanStoreDockerTLS(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		}
-		branch = newBranch
 	}
-	if req.ComposePath != nil {
-		newPath := strings.TrimSpace(*req.ComposePath)
-		if newPath != app.ComposePath {
-			composePathChanged = true
+	if req.SOPSKey != nil && strings.TrimSpace(*req.SOPSKey) != "" {
+		if err := validateSOPSKey(*req.SOPSKey); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.Registry.CanStoreSOPSKey(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		}
-		composePath = newPath
 	}
-	if req.PollInterval != nil {
-		pollInterval = strings.TrimSpace(*req.PollInterval)
+	if dockerHostChanged && h.rejectOnStandby(w) {
+		return
+	}
+	if dockerHostChanged && h.Syncer.Queue.busy(app.ID) {
+		http.Error(w, "app is syncing; change its docker host once the sync finishes", http.StatusConflict)
+		return
+	}
+	previousEndpoint, err := h.Registry.DockerEndpoint(app.ID)
+	if err != nil && dockerHostChanged {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	// Track if sync-affecting fields changed
+	branchChanged := req.Branch != nil && strings.TrimSpace(*req.Branch) != app.Branch
+	composePathChanged := req.ComposePath != nil && strings.TrimSpace(*req.ComposePath) != app.ComposePath
+	profilesChanged := req.Profiles != nil
+	envFileChanged := req.EnvFile != nil && strings.TrimSpace(*req.EnvFile) != app.EnvFile
+	// A new signer list can unblock (or block) the current commit.
+	signingKeysChanged := req.SigningKeys != nil
+	envVarsChanged := false
+
 	if req.ServiceEnvs != nil {
-		serviceEnvs = *req.ServiceEnvs
+		update.ServiceEnvs = *req.ServiceEnvs
 		envVarsChanged = true
 	}
 
 	// Update the app
-	if err := h.Registry.UpdateApp(id, name, branch, composePath, pollInterval, serviceEnvs); err != nil {
+	if err := h.Registry.UpdateApp(id, update); err != nil {
 		status := http.StatusInternalServerError
 		errText := strings.ToLower(err.Error())
 		if strings.Contains(errText, "required") || strings.Contains(errText, "invalid") {
@@ -227,9 +559,43 @@ func (h *Handler) UpdateApp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), status)
 		return
 	}
+	dockerTLSChanged := false
+	if req.DockerTLS != nil {
+		tls := req.DockerTLS
+		if *tls == (compose.DockerTLS{}) {
+			tls = nil
+		}
+		if err := h.Registry.SetDockerTLS(id, tls); err != nil {
+			http.Error(w, "App updated but failed to store docker tls: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		dockerTLSChanged = true
+	}
+	sopsKeyChanged := false
+	if req.SOPSKey != nil {
+		if err := h.Registry.SetSOPSKey(id, *req.SOPSKey); err != nil {
+			http.Error(w, "App updated but failed to store sops key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sopsKeyChanged = true
+	}
+
+	message := "App updated successfully"
+	if dockerHostChanged && app.LastSyncedCommit != "" && app.Status != StatusStopped {
+		if err := h.removeFromDockerHost(r.Context(), app, previousEndpoint); err != nil {
+			message = fmt.Sprintf("App updated; its stack on %s could not be removed: %v", previousEndpoint, err)
+		}
+	}
 
 	// Trigger sync if sync-affecting fields changed
-	needsSync := branchChanged || composePathChanged || envVarsChanged
+	needsSync := branchChanged || composePathChanged || profilesChanged || envFileChanged || envVarsChanged || signingKeysChanged || dockerHostChanged || sopsKeyChanged
+	if needsSync {
+		// A held commit still needs its approval, and a stopped app stays
+		// down until it is started.
+		if current, err := h.Registry.Get(id); err == nil && (current.Status == StatusAwaitingApproval || current.Status == StatusStopped) {
+			needsSync = false
+		}
+	}
 	if needsSync {
 		if err := h.Registry.UpdateStatus(id, "pending", nil); err != nil && h.Logger != nil {
 			h.Logger.Warn("Failed to mark app pending after update", "id", id, "error", err)
@@ -243,9 +609,32 @@ func (h *Handler) UpdateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	entry := NewAuditEntry(r, AuditActionUpdate, id)
+	entry.Changes = DiffAppFields(app, updatedApp)
+	if envVarsChanged {
+		if entry.Changes == nil {
+			entry.Changes = make(map[string]api.FieldChange)
+		}
+		// Env values are secrets; record that they changed, not what they are.
+		entry.Changes["service_envs"] = api.FieldChange{From: "(redacted)", To: "(redacted)"}
+	}
+	if dockerTLSChanged {
+		if entry.Changes == nil {
+			entry.Changes = make(map[string]api.FieldChange)
+		}
+		entry.Changes["docker_tls"] = api.FieldChange{From: "(redacted)", To: "(redacted)"}
+	}
+	if sopsKeyChanged {
+		if entry.Changes == nil {
+			entry.Changes = make(map[string]api.FieldChange)
+		}
+		entry.Changes["sops_key"] = api.FieldChange{From: "(redacted)", To: "(redacted)"}
+	}
+	h.recordAudit(entry, nil)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(api.APIResponse{
-		Message: "App updated successfully",
+		Message: message,
 		Data:    updatedApp,
 	})
 }
@@ -264,85 +653,325 @@ func (h *Handler) ForceSyncApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if app.Status == "syncing" {
-		http.Error(w, "sync already in progress", http.StatusConflict)
+	// Manual syncs apply the latest commit on the branch and honour a longer
+	// timeout than the reconciler's default.
+	opts := SyncOptions{
+		Trigger: SyncTriggerManual,
+		Actor:   requestActor(r),
+		Timeout: manualSyncTimeout,
+	}
+	if h.Leader != nil && !h.Leader() {
+		h.handOffSync(w, r, app)
 		return
 	}
+	// The sync runs in the background so that proxies do not time out the
+	// request; callers poll the job for the outcome. Each request is audited
+	// when its job completes.
+	entry := NewAuditEntry(r, AuditActionSync, app.ID)
+	job, coalesced := h.Syncer.Enqueue(app, opts, func(err error) {
+		h.recordAudit(entry, err)
+	})
+	message := "Sync started"
+	switch {
+	case coalesced:
+		message = "Sync already queued; request coalesced"
+	case job.State == SyncJobQueued:
+		message = "Sync queued behind the one in progress"
+	}
+	w.Header().Set("Location", h.BasePath+"/api/v1/apps/"+app.ID+"/syncs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: message,
+		Data:    job,
+	})
+}
 
-	syncStartedAt := time.Now()
-	if err := h.Registry.UpdateStatus(app.ID, "syncing", &syncStartedAt); err != nil && h.Logger != nil {
-		h.Logger.Warn("Failed to mark app syncing", "id", app.ID, "error", err)
+// GetSyncJob handles GET /api/v1/apps/{id}/syncs/{job}
+func (h *Handler) GetSyncJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	job, ok := h.Syncer.Queue.Job(id, chi.URLParam(r, "job"))
+	if !ok {
+		http.Error(w, "sync job not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: job,
+	})
+}
+
+// ListSyncJobs handles GET /api/v1/jobs
+func (h *Handler) ListSyncJobs(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: h.Syncer.Queue.Jobs(strings.TrimSpace(r.URL.Query().Get("app_id"))),
+	})
+}
+
+// ListPreviews handles GET /api/v1/previews
+func (h *Handler) ListPreviews(w http.ResponseWriter, r *http.Request) {
+	previews := []api.PreviewStatus{}
+	for _, app := range h.Registry.List() {
+		if app.PreviewTTL == "" {
+			continue
+		}
+		preview := api.PreviewStatus{
+			AppID:      app.ID,
+			Name:       app.Name,
+			Status:     app.Status,
+			TTL:        app.PreviewTTL,
+			LastSyncAt: app.LastSyncAt,
+		}
+		if expiresAt, ok := PreviewExpiry(app); ok {
+			preview.ExpiresAt = &expiresAt
+		}
+		previews = append(previews, preview)
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: previews,
+	})
+}
+
+// ListAudit handles GET /api/v1/audit
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
 	}
 
-	deployKey, err := h.Registry.GetDeployKey(app.ID)
+	entries, err := h.Registry.ListAudit(strings.TrimSpace(r.URL.Query().Get("app_id")), limit)
 	if err != nil {
-		_ = h.Registry.UpdateStatus(app.ID, "error", nil)
-		http.Error(w, fmt.Sprintf("failed to load app credentials: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer zeroBytes(deployKey)
+	if entries == nil {
+		entries = []*api.AuditEntry{}
+	}
 
-	envVars, err := h.Registry.GetAppEnvs(app.ID)
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: entries,
+	})
+}
+
+// ListAppRevisions handles GET /api/v1/apps/{id}/revisions
+func (h *Handler) ListAppRevisions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	limit := 0
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	revisions, err := h.Registry.ListRevisions(id, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []*api.AppRevision{}
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: revisions,
+	})
+}
+
+// ListSyncHistory handles GET /api/v1/apps/{id}/history
+func (h *Handler) ListSyncHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	limit := 0
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	records, err := h.Registry.ListSyncHistory(id, limit, 0)
 	if err != nil {
-		_ = h.Registry.UpdateStatus(app.ID, "error", nil)
-		http.Error(w, fmt.Sprintf("failed to load app envs: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Derive from Background so the sync survives reverse-proxy or client
-	// disconnects. The reconciler already does this; match that behaviour
-	// and honour the same configurable timeout (default 5m, override with
-	// CONOPS_SYNC_TIMEOUT).
-	syncCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	progress := newSyncProgressReporter(h.Registry, h.Logger, app.ID, syncProgressFlushInterval)
-	output, err := h.Applier.Apply(
-		syncCtx,
-		app.ID,
-		"",
-		envVars,
-		app.RepoURL,
-		app.Branch,
-		app.ComposePath,
-		"",
-		deployKey,
-		progress.Update,
-	)
-	progress.Flush()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []*api.SyncRecord{}
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: records,
+	})
+}
+
+// GetSyncRecord handles GET /api/v1/apps/{id}/history/{sync}
+func (h *Handler) GetSyncRecord(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	record, err := h.Registry.GetSyncRecord(id, chi.URLParam(r, "sync"))
 	if err != nil {
-		now := time.Now()
-		_ = h.Registry.UpdateSyncResult(
-			app.ID,
-			"error",
-			now,
-			app.LastSyncedCommit,
-			app.LastSyncedCommitMessage,
-			output,
-			err.Error(),
-		)
-		if h.Logger != nil {
-			h.Logger.Error("Force sync failed", "id", app.ID, "error", err)
-		}
-		http.Error(w, "force sync failed", http.StatusInternalServerError)
-		return
-	}
-
-	now := time.Now()
-	if err := h.Registry.UpdateSyncResult(
-		app.ID,
-		"synced",
-		now,
-		app.LastSeenCommit,
-		app.LastSeenCommitMessage,
-		output,
-		"",
-	); err != nil && h.Logger != nil {
-		h.Logger.Warn("Failed to update sync status after force sync", "id", app.ID, "error", err)
+		if errors.Is(err, store.ErrSyncRecordNotFound) {
+			http.Error(w, "sync not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(api.APIResponse{
-		Message: "App synced successfully",
+		Data: record,
 	})
 }
+
+type createAppTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateAppToken handles POST /api/v1/apps/{id}/tokens
+func (h *Handler) CreateAppToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var req createAppTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.Registry.CreateAppToken(id, req.Name, req.Scopes)
+	if err != nil {
+		status := http.StatusInternalServerError
+		errText := strings.ToLower(err.Error())
+		if strings.Contains(errText, "required") || strings.Contains(errText, "invalid") {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry := NewAuditEntry(r, AuditActionTokenCreate, id)
+	entry.Changes = map[string]api.FieldChange{
+		"token": {To: token.Name + " (" + strings.Join(token.Scopes, ", ") + ")"},
+	}
+	h.recordAudit(entry, nil)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Token created; store it now, it cannot be shown again",
+		Data:    token,
+	})
+}
+
+// ListAppTokens handles GET /api/v1/apps/{id}/tokens
+func (h *Handler) ListAppTokens(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tokens, err := h.Registry.ListAppTokens(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tokens == nil {
+		tokens = []*api.AppToken{}
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: tokens,
+	})
+}
+
+// RevokeAppToken handles DELETE /api/v1/apps/{id}/tokens/{tokenID}
+func (h *Handler) RevokeAppToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	tokenID := chi.URLParam(r, "tokenID")
+	if err := h.Registry.RevokeAppToken(id, tokenID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrTokenNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry := NewAuditEntry(r, AuditActionTokenRevoke, id)
+	entry.Changes = map[string]api.FieldChange{"token": {From: tokenID}}
+	h.recordAudit(entry, nil)
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Token revoked",
+	})
+}
+
+// GetVersion handles GET /api/v1/version
+func (h *Handler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	commit, buildDate, goVersion := version.Build()
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: api.VersionInfo{
+			Version:          version.Version,
+			Channel:          version.Channel(version.Version),
+			APIVersion:       version.APIVersion,
+			MinClientVersion: version.MinClientVersion,
+			SchemaVersion:    store.SchemaVersion,
+			Commit:           commit,
+			BuildDate:        buildDate,
+			GoVersion:        goVersion,
+		},
+	})
+}
+
+// GetOpenAPISpec handles GET /api/v1/openapi.yaml
+func (h *Handler) GetOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(api.OpenAPISpec)
+}
+
+// VersionHeaders advertises the controller version on every response so
+// clients can detect skew without calling GetVersion.
+func VersionHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(version.HeaderVersion, version.Version)
+		w.Header().Set(version.HeaderAPIVersion, strconv.Itoa(version.APIVersion))
+		w.Header().Set(version.HeaderMinClientVersion, version.MinClientVersion)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) recordAudit(entry *api.AuditEntry, actionErr error) {
+	if actionErr != nil {
+		entry.Outcome = auditOutcomeError
+	}
+	if err := h.Registry.RecordAudit(entry); err != nil && h.Logger != nil {
+		h.Logger.Warn("Failed to record audit entry", "action", entry.Action, "app_id", entry.AppID, "error", err)
+	}
+}
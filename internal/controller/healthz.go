@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/go-chi/chi/v5"
+)
+
+// HealthStatusCodesEnv overrides the HTTP status the app health endpoint
+// answers with for each state, e.g. "drifted=503,stopped=200".
+const HealthStatusCodesEnv = "CONOPS_HEALTHZ_STATUS_CODES"
+
+// App health states.
+const (
+	HealthSynced  = "synced"
+	HealthDrifted = "drifted"
+	HealthError   = "error"
+	HealthStopped = "stopped"
+)
+
+var healthStates = []string{HealthSynced, HealthDrifted, HealthError, HealthStopped}
+
+// RuntimeInspector lists the containers of a compose project.
+type RuntimeInspector interface {
+	InspectProjectContainers(ctx context.Context, projectName string) ([]compose.ServiceContainer, error)
+}
+
+// DefaultHealthStatusCodes reports drift as healthy, since the reconciler
+// repairs it and every deploy drifts until it finishes, and failures and
+// stopped apps as unavailable.
+func DefaultHealthStatusCodes() map[string]int {
+	return map[string]int{
+		HealthSynced:  http.StatusOK,
+		HealthDrifted: http.StatusOK,
+		HealthError:   http.StatusServiceUnavailable,
+		HealthStopped: http.StatusServiceUnavailable,
+	}
+}
+
+// LoadHealthStatusCodesFromEnv returns the default status codes with the
+// overrides from HealthStatusCodesEnv applied.
+func LoadHealthStatusCodesFromEnv() (map[string]int, error) {
+	codes := DefaultHealthStatusCodes()
+	value := strings.TrimSpace(os.Getenv(HealthStatusCodesEnv))
+	if value == "" {
+		return codes, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		state, code, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: %s", HealthStatusCodesEnv, value)
+		}
+		state = strings.TrimSpace(state)
+		parsed, err := parseHealthStatusCode(state, strings.TrimSpace(code))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", HealthStatusCodesEnv, err)
+		}
+		codes[state] = parsed
+	}
+	return codes, nil
+}
+
+func parseHealthStatusCode(state, value string) (int, error) {
+	if _, ok := DefaultHealthStatusCodes()[state]; !ok {
+		return 0, fmt.Errorf("unknown state %q, want one of %s", state, strings.Join(healthStates, ", "))
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 200 || code > 599 {
+		return 0, fmt.Errorf("%q is not an HTTP status code for %s", value, state)
+	}
+	return code, nil
+}
+
+// AppHealth summarises app and its running containers. history is the
+// app's sync history, newest first, and containers is nil when the runtime
+// cannot be inspected.
+func AppHealth(app *App, containers []compose.ServiceContainer, history []*api.SyncRecord, now time.Time) api.AppHealth {
+	health := api.AppHealth{
+		AppID:  app.ID,
+		Status: app.Status,
+		Commit: app.LastSyncedCommit,
+	}
+
+	var runtime compose.ProjectRuntimeState
+	for _, container := range containers {
+		runtime.Add(container)
+	}
+	health.Running = runtime.RunningCount
+	health.Total = runtime.ContainerCount
+
+	switch {
+	case app.Status == StatusStopped:
+		health.State = HealthStopped
+	case app.Status == "error" || app.Status == StatusBlockedUnsigned:
+		health.State = HealthError
+		health.Reason = app.Status
+		if app.Status == "error" {
+			health.Reason = "sync_failed"
+		}
+	case containers != nil && runtimeStateDriftReason(runtime) != "":
+		health.State = HealthDrifted
+		health.Reason = runtimeStateDriftReason(runtime)
+	case app.LastSyncedCommit == "" || app.LastSeenCommit != app.LastSyncedCommit:
+		health.State = HealthDrifted
+		health.Reason = "commit_pending"
+	default:
+		health.State = HealthSynced
+	}
+
+	var lastSuccess time.Time
+	for _, record := range history {
+		if record.Status == "synced" {
+			lastSuccess = record.FinishedAt
+			break
+		}
+	}
+	if lastSuccess.IsZero() && app.Status == "synced" {
+		// Syncs from before the history was kept.
+		lastSuccess = app.LastSyncAt
+	}
+	if !lastSuccess.IsZero() {
+		age := int64(now.Sub(lastSuccess).Seconds())
+		health.LastSuccessAt = &lastSuccess
+		health.LastSuccessAgeSeconds = &age
+	}
+	return health
+}
+
+// GetAppHealth handles GET /api/v1/apps/{id}/healthz. The response status
+// follows HealthStatusCodes; a query parameter per state, e.g.
+// ?drifted=503, overrides it for the request.
+func (h *Handler) GetAppHealth(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	codes := h.HealthStatusCodes
+	if codes == nil {
+		codes = DefaultHealthStatusCodes()
+	}
+	query := r.URL.Query()
+	for _, state := range healthStates {
+		if value := strings.TrimSpace(query.Get(state)); value != "" {
+			code, err := parseHealthStatusCode(state, value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if codes[state] != code {
+				codes = maps.Clone(codes)
+				codes[state] = code
+			}
+		}
+	}
+
+	var containers []compose.ServiceContainer
+	if h.Inspector != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		containers, err = h.Inspector.InspectProjectContainers(ctx, compose.ProjectNameForApp(app.ID))
+		if err != nil {
+			http.Error(w, "failed to inspect containers", http.StatusServiceUnavailable)
+			return
+		}
+		if containers == nil {
+			containers = []compose.ServiceContainer{}
+		}
+	}
+	history, err := h.Registry.ListSyncHistory(app.ID, 0, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	health := AppHealth(app, containers, history, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(codes[health.State])
+	json.NewEncoder(w).Encode(health)
+}
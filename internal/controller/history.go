@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/notify"
+	"github.com/google/uuid"
+)
+
+// RecordSync appends a finished sync to its app's history.
+func (r *Registry) RecordSync(record *api.SyncRecord) error {
+	return r.store.CreateSyncRecord(context.Background(), record)
+}
+
+// ListSyncHistory returns an app's sync history, newest first, skipping
+// the offset newest syncs.
+func (r *Registry) ListSyncHistory(appID string, limit, offset int) ([]*api.SyncRecord, error) {
+	return r.store.ListSyncRecords(context.Background(), appID, limit, offset)
+}
+
+// GetSyncRecord returns one of an app's syncs with its transcript. A sync
+// recorded before transcripts were split into steps gets a single "sync"
+// step holding its whole output.
+func (r *Registry) GetSyncRecord(appID, id string) (*api.SyncRecord, error) {
+	record, err := r.store.GetSyncRecord(context.Background(), appID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(record.Steps) == 0 && record.Output != "" {
+		status := "succeeded"
+		if record.Status != "synced" {
+			status = "failed"
+		}
+		record.Steps = []api.SyncStep{{
+			Name:       "sync",
+			Status:     status,
+			StartedAt:  record.StartedAt,
+			DurationMs: record.FinishedAt.Sub(record.StartedAt).Milliseconds(),
+			Log:        record.Output,
+		}}
+	}
+	return record, nil
+}
+
+// maxSyncRecordOutput caps the transcript kept with each sync record, and
+// the log of each of its steps. The end of a transcript says why a sync
+// failed, so the start is dropped.
+const maxSyncRecordOutput = 256 << 10
+
+func truncateSyncOutput(output string) string {
+	if len(output) > maxSyncRecordOutput {
+		return "...(truncated)\n" + output[len(output)-maxSyncRecordOutput:]
+	}
+	return output
+}
+
+func newSyncRecord(app *App, opts SyncOptions, startedAt time.Time) *api.SyncRecord {
+	return &api.SyncRecord{
+		ID:        uuid.NewString(),
+		AppID:     app.ID,
+		StartedAt: startedAt,
+		Trigger:   opts.Trigger,
+		Actor:     opts.Actor,
+		Commit:    targetCommit(app, opts),
+	}
+}
+
+// recordSync completes record with the outcome and transcript of the sync,
+// which is nil when it failed before anything ran, and stores it.
+func (s *Syncer) recordSync(record *api.SyncRecord, transcript *compose.Transcript, syncErr error) {
+	record.FinishedAt = time.Now()
+	record.Status = "synced"
+	if transcript != nil {
+		record.Output = truncateSyncOutput(transcript.String())
+		for _, step := range transcript.Steps(syncErr != nil) {
+			status := "succeeded"
+			if step.Failed {
+				status = "failed"
+			}
+			record.Steps = append(record.Steps, api.SyncStep{
+				Name:       step.Name,
+				Status:     status,
+				StartedAt:  step.StartedAt,
+				DurationMs: step.Duration.Milliseconds(),
+				Log:        truncateSyncOutput(step.Log),
+			})
+		}
+	}
+	if syncErr != nil {
+		record.Status = "error"
+		record.Error = syncErr.Error()
+	}
+	if err := s.Registry.RecordSync(record); err != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to record sync history", "app_id", record.AppID, "error", err)
+	}
+}
+
+// rollback re-applies the last synced commit after a rollout failed once
+// the stack was already being replaced, so the app goes back to containers
+// known to work. The rollback is a sync of its own in the history. The
+// returned output and error describe the failed sync followed by the
+// rollback; the app stays in error because its desired commit did not
+// deploy.
+func (s *Syncer) rollback(app *App, opts SyncOptions, failed *api.SyncRecord, deployKey []byte, envVars map[string]string, output string, syncErr error) (string, error) {
+	var rollout *compose.RolloutError
+	previous := app.LastSyncedCommit
+	if !s.AutoRollback || !errors.As(syncErr, &rollout) || previous == "" || previous == failed.Commit {
+		return output, syncErr
+	}
+	if s.Logger != nil {
+		s.Logger.Warn("Rolling back failed sync", "app_id", app.ID, "failed_commit", failed.Commit, "commit", previous)
+	}
+
+	// The failed attempt may have used up the sync timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	rollbackOpts := SyncOptions{Trigger: SyncTriggerRollback, Actor: opts.Actor, Commit: previous, Timeout: opts.Timeout}
+	startedAt := time.Now()
+	record := newSyncRecord(app, rollbackOpts, startedAt)
+	record.RollbackOf = failed.ID
+
+	output += "\n\n=== Rollback ===\nre-applying last synced commit " + previous
+	progress := newSyncProgressReporter(s.Registry, s.Logger, app.ID, syncProgressFlushInterval)
+	rollbackTranscript, err := s.apply(ctx, app, rollbackOpts, record, deployKey, envVars, func(current string) {
+		progress.Update(output + "\n\n" + current)
+	})
+	progress.Flush()
+	output += "\n\n" + rollbackTranscript.String()
+	s.recordSync(record, rollbackTranscript, err)
+
+	event := notify.Event{
+		Type:            notify.EventSyncRolledBack,
+		Trigger:         SyncTriggerRollback,
+		Commit:          previous,
+		Status:          "synced",
+		Reason:          fmt.Sprintf("sync of %s failed: %s", failed.Commit, syncErr),
+		DurationSeconds: time.Since(startedAt).Seconds(),
+	}
+	if err != nil {
+		event.Status = "error"
+		event.Error = err.Error()
+	}
+	s.Registry.Notifier().Publish(app, event)
+
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Error("Rollback failed", "app_id", app.ID, "commit", previous, "error", err)
+		}
+		return output, fmt.Errorf("%w; rollback to %s failed: %v", syncErr, previous, err)
+	}
+	if s.Logger != nil {
+		s.Logger.Info("Rolled back to last synced commit", "app_id", app.ID, "commit", previous)
+	}
+	return output, fmt.Errorf("%w; rolled back to %s", syncErr, previous)
+}
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conops/conops/internal/compose"
+)
+
+const (
+	// ImagePruneEnv selects which images are pruned after deployments:
+	// "off", "dangling" or "unused".
+	ImagePruneEnv = "CONOPS_IMAGE_PRUNE"
+	// ImagePruneUntilEnv limits pruning to images created longer ago.
+	ImagePruneUntilEnv = "CONOPS_IMAGE_PRUNE_UNTIL"
+	// ImagePruneLabelsEnv limits pruning by image labels.
+	ImagePruneLabelsEnv = "CONOPS_IMAGE_PRUNE_LABELS"
+	// ImagePruneIntervalEnv is the least time between two prunes.
+	ImagePruneIntervalEnv = "CONOPS_IMAGE_PRUNE_INTERVAL"
+)
+
+// Image prune modes.
+const (
+	ImagePruneOff      = "off"
+	ImagePruneDangling = "dangling"
+	ImagePruneUnused   = "unused"
+)
+
+// imagePruneCheckInterval is how often a requested prune is retried while
+// syncs are running or the last prune was too recent.
+const imagePruneCheckInterval = 30 * time.Second
+
+// imagePruneTimeout bounds one prune.
+const imagePruneTimeout = 10 * time.Minute
+
+// ImagePruneConfig is the image garbage collection policy.
+type ImagePruneConfig struct {
+	Mode string
+	// Until keeps images created less than this long ago; zero prunes
+	// regardless of age.
+	Until time.Duration
+	// Labels are label filters: "key" or "key=value" prunes only matching
+	// images, "!key" or "!key=value" spares them.
+	Labels   []string
+	Interval time.Duration
+}
+
+// LoadImagePruneConfigFromEnv reads the prune policy. Pruning is off by
+// default; when enabled, hosts are pruned at most hourly.
+func LoadImagePruneConfigFromEnv() (ImagePruneConfig, error) {
+	cfg := ImagePruneConfig{Mode: ImagePruneOff, Interval: time.Hour}
+	if value := strings.ToLower(strings.TrimSpace(os.Getenv(ImagePruneEnv))); value != "" {
+		switch value {
+		case ImagePruneOff, ImagePruneDangling, ImagePruneUnused:
+			cfg.Mode = value
+		default:
+			return ImagePruneConfig{}, fmt.Errorf("invalid %s: %s", ImagePruneEnv, value)
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv(ImagePruneUntilEnv)); value != "" {
+		until, err := time.ParseDuration(value)
+		if err != nil || until < 0 {
+			return ImagePruneConfig{}, fmt.Errorf("invalid %s: %s", ImagePruneUntilEnv, value)
+		}
+		cfg.Until = until
+	}
+	if value := strings.TrimSpace(os.Getenv(ImagePruneLabelsEnv)); value != "" {
+		for _, label := range strings.Split(value, ",") {
+			label = strings.TrimSpace(label)
+			if key := strings.TrimPrefix(label, "!"); key == "" || strings.HasPrefix(key, "=") || strings.ContainsAny(key, " \t") {
+				return ImagePruneConfig{}, fmt.Errorf("invalid %s: %s", ImagePruneLabelsEnv, value)
+			}
+			cfg.Labels = append(cfg.Labels, label)
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv(ImagePruneIntervalEnv)); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return ImagePruneConfig{}, fmt.Errorf("invalid %s: %s", ImagePruneIntervalEnv, value)
+		}
+		cfg.Interval = interval
+	}
+	return cfg, nil
+}
+
+// Filters returns the policy as `docker image prune` filters.
+func (c ImagePruneConfig) Filters() []string {
+	var filters []string
+	if c.Until > 0 {
+		filters = append(filters, "until="+c.Until.String())
+	}
+	for _, label := range c.Labels {
+		if key, ok := strings.CutPrefix(label, "!"); ok {
+			filters = append(filters, "label!="+key)
+		} else {
+			filters = append(filters, "label="+label)
+		}
+	}
+	return filters
+}
+
+// RuntimeImagePruner removes images no longer needed on the host.
+type RuntimeImagePruner interface {
+	PruneImages(ctx context.Context, all bool, filters []string) (string, error)
+}
+
+// ImagePruner prunes images after successful deployments, so the layers of
+// images that were replaced do not fill the host's disk. A prune waits
+// until no sync is running, since an image pulled by a sync in progress is
+// not used by any container yet.
+type ImagePruner struct {
+	Runtime RuntimeImagePruner
+	Config  ImagePruneConfig
+	Queue   *SyncQueue
+	Logger  *slog.Logger
+	// Endpoints lists the remote docker hosts apps deploy to, which are
+	// pruned along with the local daemon.
+	Endpoints compose.EndpointResolver
+
+	mu      sync.Mutex
+	pending bool
+	last    time.Time
+}
+
+// Request notes that a deployment finished; the next due check prunes.
+func (p *ImagePruner) Request() {
+	if p == nil || p.Config.Mode == ImagePruneOff {
+		return
+	}
+	p.mu.Lock()
+	p.pending = true
+	p.mu.Unlock()
+}
+
+// Run prunes requested images until ctx is done.
+func (p *ImagePruner) Run(ctx context.Context) {
+	if p.Config.Mode == ImagePruneOff {
+		return
+	}
+	ticker := time.NewTicker(imagePruneCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.PruneIfDue(ctx, time.Now())
+		}
+	}
+}
+
+// PruneIfDue prunes when a deployment finished since the last prune, the
+// last prune is at least Interval ago and no sync is running. It reports
+// whether it pruned.
+func (p *ImagePruner) PruneIfDue(ctx context.Context, now time.Time) bool {
+	p.mu.Lock()
+	due := p.pending && (p.last.IsZero() || now.Sub(p.last) >= p.Config.Interval)
+	p.mu.Unlock()
+	if !due || len(p.Queue.Jobs("")) > 0 {
+		return false
+	}
+
+	endpoints := []compose.DockerEndpoint{{}}
+	if p.Endpoints != nil {
+		remote, err := p.Endpoints.DockerEndpoints()
+		if err != nil && p.Logger != nil {
+			p.Logger.Error("Failed to list docker hosts to prune", "error", err)
+		}
+		endpoints = append(endpoints, remote...)
+	}
+
+	ok := true
+	for _, endpoint := range endpoints {
+		if err := p.prune(ctx, endpoint); err != nil {
+			ok = false
+		}
+	}
+
+	p.mu.Lock()
+	p.pending = false
+	p.last = now
+	p.mu.Unlock()
+	return ok
+}
+
+func (p *ImagePruner) prune(ctx context.Context, endpoint compose.DockerEndpoint) error {
+	pruneCtx, cancel := context.WithTimeout(compose.WithDockerEndpoint(ctx, endpoint), imagePruneTimeout)
+	defer cancel()
+	output, err := p.Runtime.PruneImages(pruneCtx, p.Config.Mode == ImagePruneUnused, p.Config.Filters())
+	if p.Logger != nil {
+		if err != nil {
+			p.Logger.Error("Image prune failed", "docker_host", endpoint.String(), "error", err)
+		} else {
+			p.Logger.Info("Pruned images", "docker_host", endpoint.String(), "mode", p.Config.Mode, "filters", p.Config.Filters(), "reclaimed", compose.ReclaimedSpace(output))
+		}
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+)
+
+// ImageResolver reports the image digests an app's services run.
+type ImageResolver interface {
+	ImageDigests(ctx context.Context, appID string) (map[string]string, error)
+}
+
+// pinnedImages returns the digests a successful earlier sync of the same
+// commit deployed, so re-applying a commit reproduces its images. It
+// returns nil when the app does not pin images or the commit has no
+// recorded digests yet.
+func (s *Syncer) pinnedImages(app *App, opts SyncOptions) map[string]string {
+	if !app.PinImages || opts.Commit == "" {
+		return nil
+	}
+	records, err := s.Registry.ListSyncHistory(app.ID, 0, 0)
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Warn("Failed to load pinned images", "app_id", app.ID, "error", err)
+		}
+		return nil
+	}
+	for _, record := range records {
+		if record.Commit == opts.Commit && record.Status == "synced" && len(record.Images) > 0 {
+			return record.Images
+		}
+	}
+	return nil
+}
+
+// recordImages stores the digests the app's services run in record, for
+// pinning or the attestation, and appends them to transcript. A failed
+// lookup is logged, not a sync failure.
+func (s *Syncer) recordImages(ctx context.Context, app *App, record *api.SyncRecord, transcript *compose.Transcript) {
+	resolver, ok := s.Applier.(ImageResolver)
+	if (!app.PinImages && s.Attestor == nil) || !ok {
+		return
+	}
+	images, err := resolver.ImageDigests(ctx, app.ID)
+	transcript.Step(StepImageDigests)
+	transcript.Section("Image digests")
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Warn("Failed to resolve image digests", "app_id", app.ID, "error", err)
+		}
+		transcript.Line("failed to resolve: " + err.Error())
+		return
+	}
+	record.Images = images
+
+	for _, service := range slices.Sorted(maps.Keys(images)) {
+		transcript.Line(fmt.Sprintf("%s: %s", service, images[service]))
+	}
+	if len(images) == 0 {
+		transcript.Line("no service runs a registry image")
+	}
+}
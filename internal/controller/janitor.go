@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// JanitorIntervalEnv sets how often the janitor looks for directories
+	// to remove.
+	JanitorIntervalEnv = "CONOPS_JANITOR_INTERVAL"
+	// DiskBudgetEnv caps the space the repo cache and runtime directories
+	// may take together, e.g. "10GiB".
+	DiskBudgetEnv = "CONOPS_DISK_BUDGET"
+)
+
+// JanitorConfig is the directory garbage collection policy.
+type JanitorConfig struct {
+	// Interval is the time between two sweeps; zero disables the janitor.
+	Interval time.Duration
+	// Budget is the most bytes the cache and runtime directories may use;
+	// zero means no budget.
+	Budget int64
+}
+
+// LoadJanitorConfigFromEnv reads the janitor policy. It sweeps hourly with
+// no disk budget by default.
+func LoadJanitorConfigFromEnv() (JanitorConfig, error) {
+	cfg := JanitorConfig{Interval: time.Hour}
+	if value := strings.TrimSpace(os.Getenv(JanitorIntervalEnv)); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return JanitorConfig{}, fmt.Errorf("invalid %s: %s", JanitorIntervalEnv, value)
+		}
+		cfg.Interval = interval
+	}
+	if value := strings.TrimSpace(os.Getenv(DiskBudgetEnv)); value != "" {
+		budget, err := parseByteSize(value)
+		if err != nil {
+			return JanitorConfig{}, fmt.Errorf("invalid %s: %s", DiskBudgetEnv, value)
+		}
+		cfg.Budget = budget
+	}
+	return cfg, nil
+}
+
+// byteSizeUnits are the suffixes parseByteSize accepts, longest first so
+// "MiB" is not read as "B".
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40},
+	{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+	{"b", 1},
+}
+
+// parseByteSize parses sizes such as "512MiB", "10GB" or a plain number of
+// bytes.
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := 1.0
+	for _, unit := range byteSizeUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = strings.TrimSpace(number), unit.multiplier
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(size * multiplier), nil
+}
+
+// RepoCacheEvicter removes an app's repo cache, which its poller clones
+// again on its next check.
+type RepoCacheEvicter interface {
+	RemoveCache(appID string) error
+}
+
+// Janitor removes the repo cache and runtime directories of apps that are
+// no longer registered: deleting an app leaves its repo cache, and a failed
+// destroy its runtime directory. With a disk budget it also evicts the
+// repo caches of registered apps, largest first, until the directories fit:
+// go-git never repacks, so a fresh clone is far smaller than a long-lived
+// one. Runtime directories of registered apps hold the checkout their
+// stack runs from and are never evicted.
+type Janitor struct {
+	Registry   *Registry
+	CacheDir   string
+	RuntimeDir string
+	Cache      RepoCacheEvicter
+	// Queue is consulted so a directory is never removed mid-sync.
+	Queue  *SyncQueue
+	Config JanitorConfig
+	Logger *slog.Logger
+}
+
+// JanitorResult reports one sweep.
+type JanitorResult struct {
+	// Removed lists the directories removed, orphans and evicted caches.
+	Removed   []string
+	Reclaimed int64
+	// Used is the size of the directories after the sweep.
+	Used   int64
+	Failed map[string]error
+}
+
+// janitorDir is an app directory found by a sweep.
+type janitorDir struct {
+	path  string
+	appID string
+	cache bool
+	size  int64
+}
+
+// Run sweeps every Interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context) {
+	if j.Config.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(j.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.Sweep(ctx); err != nil && j.Logger != nil {
+				j.Logger.Error("Directory sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+// Sweep removes orphaned app directories and, over the disk budget, evicts
+// repo caches. The directories are read before the registry, so the
+// directory of an app registered in between is not taken for an orphan.
+func (j *Janitor) Sweep(ctx context.Context) (JanitorResult, error) {
+	result := JanitorResult{Failed: make(map[string]error)}
+	var dirs []janitorDir
+	for _, root := range []struct {
+		path  string
+		cache bool
+	}{{j.CacheDir, true}, {j.RuntimeDir, false}} {
+		found, err := listAppDirs(root.path, root.cache)
+		if err != nil {
+			return result, err
+		}
+		dirs = append(dirs, found...)
+	}
+	registered, err := j.Registry.AppIDs(ctx)
+	if err != nil {
+		return result, fmt.Errorf("list apps: %w", err)
+	}
+
+	var kept []janitorDir
+	for _, dir := range dirs {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		dir.size = dirSize(dir.path)
+		if registered[dir.appID] || j.Queue.busy(dir.appID) {
+			result.Used += dir.size
+			kept = append(kept, dir)
+			continue
+		}
+		if j.Logger != nil {
+			j.Logger.Info("Removing directory of deleted app", "app_id", dir.appID, "dir", dir.path, "bytes", dir.size)
+		}
+		j.remove(dir, &result)
+	}
+
+	if j.Config.Budget > 0 && result.Used > j.Config.Budget {
+		sort.Slice(kept, func(a, b int) bool { return kept[a].size > kept[b].size })
+		for _, dir := range kept {
+			if result.Used <= j.Config.Budget {
+				break
+			}
+			if !dir.cache || j.Queue.busy(dir.appID) {
+				continue
+			}
+			if j.Logger != nil {
+				j.Logger.Info("Evicting repo cache over the disk budget", "app_id", dir.appID, "dir", dir.path, "bytes", dir.size, "used", result.Used, "budget", j.Config.Budget)
+			}
+			if j.remove(dir, &result) {
+				result.Used -= dir.size
+			}
+		}
+		if result.Used > j.Config.Budget && j.Logger != nil {
+			j.Logger.Warn("App directories exceed the disk budget", "used", result.Used, "budget", j.Config.Budget)
+		}
+	}
+
+	if j.Logger != nil && (len(result.Removed) > 0 || len(result.Failed) > 0) {
+		j.Logger.Info("Directory sweep finished", "removed", len(result.Removed), "reclaimed", result.Reclaimed, "used", result.Used, "failed", len(result.Failed))
+	}
+	return result, nil
+}
+
+// remove deletes dir, through the watcher for repo caches so a poll in
+// progress is not cut short, and reports whether it did.
+func (j *Janitor) remove(dir janitorDir, result *JanitorResult) bool {
+	var err error
+	if dir.cache && j.Cache != nil {
+		err = j.Cache.RemoveCache(dir.appID)
+	} else {
+		err = os.RemoveAll(dir.path)
+	}
+	if err != nil {
+		result.Failed[dir.path] = err
+		if j.Logger != nil {
+			j.Logger.Error("Failed to remove app directory", "app_id", dir.appID, "dir", dir.path, "error", err)
+		}
+		return false
+	}
+	result.Removed = append(result.Removed, dir.path)
+	result.Reclaimed += dir.size
+	return true
+}
+
+// listAppDirs returns the app directories under root, which are named
+// after the app ID. Only directories laid out like the watcher's clones or
+// the executor's workspaces are taken, so a root shared with other files
+// loses nothing else; hidden entries, such as the readiness probe's scratch
+// files, are skipped too. A missing root has none.
+func listAppDirs(root string, cache bool) ([]janitorDir, error) {
+	if strings.TrimSpace(root) == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", root, err)
+	}
+	var dirs []janitorDir
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		markers := []string{"repo", "diff"}
+		if cache {
+			markers = []string{".git"}
+		}
+		if !slices.ContainsFunc(markers, func(marker string) bool {
+			_, err := os.Stat(filepath.Join(root, entry.Name(), marker))
+			return err == nil
+		}) {
+			continue
+		}
+		dirs = append(dirs, janitorDir{path: filepath.Join(root, entry.Name()), appID: entry.Name(), cache: cache})
+	}
+	return dirs, nil
+}
+
+// dirSize returns the bytes the files under path take; files that vanish
+// while it walks are skipped.
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// AppIDs returns the IDs of every registered app. Unlike List it fails when
+// the store does, so callers do not mistake an outage for no apps.
+func (r *Registry) AppIDs(ctx context.Context) (map[string]bool, error) {
+	apps, err := r.store.ListApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		ids[app.ID] = true
+	}
+	return ids, nil
+}
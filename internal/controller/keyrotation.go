@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/credentials"
+)
+
+// AuditActionRotateKey records replacing the credential encryption key.
+const AuditActionRotateKey = "key.rotate"
+
+// KeyRotationResult reports a key rotation.
+type KeyRotationResult struct {
+	// Apps is how many apps' credentials were re-encrypted.
+	Apps int `json:"apps"`
+	// KeyFile is empty when the key lives in Vault.
+	KeyFile string `json:"key_file,omitempty"`
+	// KeySource names where the new key is kept.
+	KeySource string `json:"key_source"`
+}
+
+// RotateKey re-encrypts every app's deploy key, env vars, docker TLS
+// material and SOPS key with a new key in one transaction, then replaces
+// the key file with it. With Vault transit, Vault rotates its key instead.
+// Credential reads and writes wait until it is done. When re-encryption
+// fails the stored credentials are left as they were and a key file stays
+// in use; a rotated Vault key is not undone, but its older versions still
+// decrypt them.
+func (r *Registry) RotateKey() (*KeyRotationResult, error) {
+	if r.credentials == nil || !r.credentials.Enabled() {
+		return nil, fmt.Errorf("encryption support is unavailable: set %s", credentials.EncryptionKeyEnv)
+	}
+	r.credentialsMu.Lock()
+	defer r.credentialsMu.Unlock()
+
+	rotation, err := r.credentials.BeginKeyRotation()
+	if err != nil {
+		return nil, err
+	}
+	apps, err := r.store.ReencryptAppCredentials(context.Background(), func(ciphertext, nonce []byte) ([]byte, []byte, error) {
+		plaintext, err := r.credentials.Decrypt(ciphertext, nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer zeroBytes(plaintext)
+		return rotation.Next.Encrypt(plaintext)
+	})
+	if err != nil {
+		rotation.Abort()
+		return nil, fmt.Errorf("failed to re-encrypt credentials, they were left unchanged: %w", err)
+	}
+	result := &KeyRotationResult{Apps: apps, KeyFile: r.credentials.KeyFile(), KeySource: r.credentials.KeySource()}
+	if err := rotation.Commit(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// RotateKey handles POST /api/v1/admin/rotate-key
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	entry := NewAuditEntry(r, AuditActionRotateKey, "")
+	result, err := h.Registry.RotateKey()
+	if result != nil {
+		entry.Changes = map[string]api.FieldChange{"apps": {To: fmt.Sprint(result.Apps)}}
+	}
+	h.recordAudit(entry, err)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errText := err.Error(); strings.Contains(errText, "unavailable") || strings.Contains(errText, "cannot be rotated") {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if h.Logger != nil {
+		h.Logger.Info("Encryption key rotated", "apps", result.Apps, "key_file", result.KeyFile, "actor", entry.Actor)
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: fmt.Sprintf("Encryption key rotated; re-encrypted the credentials of %d apps", result.Apps),
+		Data:    result,
+	})
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/store"
+	"github.com/google/uuid"
+)
+
+// leaderCheckInterval is how often a standby tries to take the leader lock
+// and the leader checks that it still holds it.
+const leaderCheckInterval = 5 * time.Second
+
+// Replica roles reported by the probes.
+const (
+	RoleLeader  = "leader"
+	RoleStandby = "standby"
+)
+
+// LeaderElection lets several controllers share one store while only the
+// one holding the store's leader lock watches repos and deploys. Every
+// controller serves the API and UI.
+type LeaderElection struct {
+	// Locker is nil for stores only one controller can use, which makes
+	// this controller the leader for as long as it runs.
+	Locker   store.LeaderLocker
+	Interval time.Duration
+	Logger   *slog.Logger
+
+	leader atomic.Bool
+}
+
+// IsLeader reports whether this controller holds the leader lock.
+func (e *LeaderElection) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Role returns RoleLeader or RoleStandby.
+func (e *LeaderElection) Role() string {
+	if e.IsLeader() {
+		return RoleLeader
+	}
+	return RoleStandby
+}
+
+// Run competes for the leader lock until ctx is done and runs lead while it
+// holds it. lead must return once its context is done; the lock is released
+// only after it has, so the next leader never overlaps with this one.
+func (e *LeaderElection) Run(ctx context.Context, lead func(ctx context.Context)) {
+	if e.Locker == nil {
+		e.leader.Store(true)
+		lead(ctx)
+		e.leader.Store(false)
+		return
+	}
+	interval := e.Interval
+	if interval <= 0 {
+		interval = leaderCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	waiting := false
+	for ctx.Err() == nil {
+		lock, err := e.Locker.TryLockLeader(ctx)
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				e.log().Warn("Failed to take the leader lock", "error", err)
+			}
+		case lock != nil:
+			e.hold(ctx, lock, ticker.C, lead)
+			waiting = false
+		case !waiting:
+			e.log().Info("Another controller is the leader; standing by")
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElection) hold(ctx context.Context, lock store.LeaderLock, tick <-chan time.Time, lead func(ctx context.Context)) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	e.leader.Store(true)
+	e.log().Info("Took the leader lock; watching repos and deploying")
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	lost := false
+	for !lost {
+		select {
+		case <-ctx.Done():
+			lost = true
+		case <-done:
+			lost = true
+		case <-tick:
+			checkCtx, stop := context.WithTimeout(ctx, probeTimeout)
+			if err := lock.Check(checkCtx); err != nil && ctx.Err() == nil {
+				e.log().Error("Lost the leader lock; stopping deploys", "error", err)
+				lost = true
+			}
+			stop()
+		}
+	}
+
+	// Turn away runtime requests while the loops wind down.
+	e.leader.Store(false)
+	cancel()
+	<-done
+
+	releaseCtx, stop := context.WithTimeout(context.Background(), probeTimeout)
+	defer stop()
+	if err := lock.Release(releaseCtx); err != nil {
+		e.log().Warn("Failed to release the leader lock", "error", err)
+		return
+	}
+	e.log().Info("Released the leader lock")
+}
+
+func (e *LeaderElection) log() *slog.Logger {
+	if e.Logger != nil {
+		return e.Logger
+	}
+	return slog.Default()
+}
+
+// standbyRetryAfter is the Retry-After, in seconds, of requests a standby
+// turns away.
+const standbyRetryAfter = "5"
+
+// rejectOnStandby answers 503 to requests that change containers directly
+// when this controller is not the leader, since the leader may be syncing
+// the same app. It reports whether it did.
+func (h *Handler) rejectOnStandby(w http.ResponseWriter) bool {
+	if h.Leader == nil || h.Leader() {
+		return false
+	}
+	w.Header().Set("Retry-After", standbyRetryAfter)
+	http.Error(w, "this controller is on standby; retry against the leader", http.StatusServiceUnavailable)
+	return true
+}
+
+// handOffSync asks the leader to sync app by scheduling it for now; the
+// leader's scheduler starts it on its next check.
+func (r *Registry) handOffSync(appID, actor string) (*api.ScheduledSync, error) {
+	schedule := &api.ScheduledSync{
+		ID:        uuid.NewString(),
+		AppID:     appID,
+		RunAt:     time.Now().UTC(),
+		Actor:     actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := r.store.CreateScheduledSync(context.Background(), schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// handOffSync answers a sync request made to a standby by scheduling the
+// sync for the leader. It is audited like a schedule; the leader audits the
+// sync itself when it runs.
+func (h *Handler) handOffSync(w http.ResponseWriter, r *http.Request, app *App) {
+	schedule, err := h.Registry.handOffSync(app.ID, requestActor(r))
+	entry := NewAuditEntry(r, AuditActionSchedule, app.ID)
+	if schedule != nil {
+		entry.Changes = map[string]api.FieldChange{
+			"schedule": {To: schedule.RunAt.Format(time.RFC3339) + " (latest)"},
+		}
+	}
+	h.recordAudit(entry, err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Sync handed to the leader",
+		Data:    schedule,
+	})
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultLogTail is how many lines the logs endpoint returns without a tail
+// parameter.
+const defaultLogTail = 200
+
+// RuntimeLogStreamer streams the logs of one service of an app's project.
+type RuntimeLogStreamer interface {
+	StreamServiceLogs(ctx context.Context, appID, composePath string, profiles []string, service string, tail int, follow bool, w io.Writer) error
+}
+
+// GetServiceLogs handles GET /api/v1/apps/{id}/services/{service}/logs
+func (h *Handler) GetServiceLogs(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	service := chi.URLParam(r, "service")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.LogStreamer == nil {
+		http.Error(w, "the runtime cannot stream logs", http.StatusNotImplemented)
+		return
+	}
+
+	tail := defaultLogTail
+	if value := r.URL.Query().Get("tail"); value == "all" {
+		tail = -1
+	} else if value != "" {
+		if tail, err = strconv.Atoi(value); err != nil || tail < 0 {
+			http.Error(w, "invalid tail: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+	follow := false
+	if value := r.URL.Query().Get("follow"); value != "" {
+		if follow, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "invalid follow: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+	if !h.checkService(r.Context(), w, app, service) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	out := &flushWriter{w: w, rc: http.NewResponseController(w)}
+	// Services often log their own config; mask the app's secrets in it.
+	masked := h.Registry.Redactor(app.ID).Writer(out)
+	err = h.LogStreamer.StreamServiceLogs(r.Context(), app.ID, app.ComposePath, app.Profiles, service, tail, follow, masked)
+	if closeErr := masked.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+	case !out.wrote:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		// The status line is gone; the log simply ends early.
+		if h.Logger != nil {
+			h.Logger.Warn("Service log stream failed", "app_id", app.ID, "service", service, "error", err)
+		}
+	}
+}
+
+// flushWriter sends every write to the client right away, so a followed log
+// arrives line by line as a chunked response.
+type flushWriter struct {
+	w     io.Writer
+	rc    *http.ResponseController
+	wrote bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.wrote = true
+	n, err := f.w.Write(p)
+	if err == nil {
+		err = f.rc.Flush()
+	}
+	return n, err
+}
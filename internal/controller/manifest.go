@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/gates"
+)
+
+// RuntimePlanner reports how the runtime would apply an app.
+type RuntimePlanner interface {
+	Plan(appID, composePath string, profiles []string, envFile string, serviceEnvs map[string]string) (compose.Plan, error)
+}
+
+// BuildManifest combines an app, its service environments and the runtime
+// plan into the effective configuration the next sync applies.
+func BuildManifest(app *App, serviceEnvs map[string]string, plan compose.Plan) *api.AppManifest {
+	manifest := &api.AppManifest{
+		AppID:        app.ID,
+		ProjectName:  plan.ProjectName,
+		RepoURL:      app.RepoURL,
+		Branch:       app.Branch,
+		Track:        app.Track,
+		WatchPaths:   effectiveWatchPaths(app),
+		TargetCommit: app.LastSeenCommit,
+		SyncedCommit: app.LastSyncedCommit,
+		WorkingDir:   plan.WorkingDir,
+		ComposeFiles: plan.ComposeFiles,
+		EnvFile:      plan.EnvFile,
+		Profiles:     plan.Profiles,
+		EnvVars:      envVarNames(serviceEnvs),
+		PreDeploy:    plan.PreDeployCommands(app.PreDeploy),
+		Command:      plan.Command,
+		PostDeploy:   plan.PostDeployCommands(app.PostDeploy),
+	}
+	if manifest.Branch == "" {
+		manifest.Branch = "main"
+	}
+	if manifest.Profiles == nil {
+		manifest.Profiles = []string{}
+	}
+	for _, gate := range []string{gates.Promote, gates.Health} {
+		if app.GateScript != "" && gates.Defines(app.GateScript, gate) {
+			manifest.Gates = append(manifest.Gates, gate)
+		}
+	}
+	return manifest
+}
+
+// envVarNames lists the variable names set per service, sorted, without
+// their values.
+func envVarNames(serviceEnvs map[string]string) map[string][]string {
+	names := make(map[string][]string, len(serviceEnvs))
+	for service, raw := range serviceEnvs {
+		var keys []string
+		for _, line := range strings.Split(raw, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, _, _ := strings.Cut(line, "=")
+			keys = append(keys, strings.TrimSpace(key))
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		names[service] = keys
+	}
+	return names
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// AuditActionPause records holding an app's automatic syncs.
+	AuditActionPause = "app.pause"
+	// AuditActionResume records handing a paused app back to the reconciler.
+	AuditActionResume = "app.resume"
+)
+
+var (
+	// ErrAlreadyPaused is returned when pausing an app that is paused.
+	ErrAlreadyPaused = errors.New("app is already paused")
+	// ErrNotPaused is returned when resuming an app that is not paused.
+	ErrNotPaused = errors.New("app is not paused")
+)
+
+// Pause holds the app's automatic syncs. Its containers keep running and
+// new commits are still recorded, but the reconciler deploys nothing until
+// the app is resumed.
+func (r *Registry) Pause(id string) (*App, error) {
+	app, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if app.Paused {
+		return nil, ErrAlreadyPaused
+	}
+	if err := r.store.UpdateAppPaused(context.Background(), id, true); err != nil {
+		return nil, err
+	}
+	app.Paused = true
+	return app, nil
+}
+
+// Resume hands a paused app back to the reconciler. When it runs another
+// commit than the desired one, e.g. after a rollback, it is marked pending
+// so the reconciler deploys the desired commit.
+func (r *Registry) Resume(id string) (*App, error) {
+	app, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !app.Paused {
+		return nil, ErrNotPaused
+	}
+	if err := r.store.UpdateAppPaused(context.Background(), id, false); err != nil {
+		return nil, err
+	}
+	app.Paused = false
+	if app.Status == "synced" && app.LastSeenCommit != "" && app.LastSeenCommit != app.LastSyncedCommit {
+		if err := r.requeue(app); err != nil {
+			return nil, err
+		}
+	}
+	return app, nil
+}
+
+// PauseApp handles POST /api/v1/apps/{id}/pause
+func (h *Handler) PauseApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionPause, id)
+	app, err := h.Registry.Pause(id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrAlreadyPaused) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry.Changes = map[string]api.FieldChange{"paused": {From: "false", To: "true"}}
+	h.recordAudit(entry, nil)
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "App paused; automatic syncs are held until it is resumed",
+		Data:    app,
+	})
+}
+
+// ResumeApp handles POST /api/v1/apps/{id}/resume
+func (h *Handler) ResumeApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionResume, id)
+	app, err := h.Registry.Resume(id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotPaused) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry.Changes = map[string]api.FieldChange{"paused": {From: "true", To: "false"}}
+	h.recordAudit(entry, nil)
+
+	message := "App resumed"
+	switch app.Status {
+	case "pending":
+		message = "App resumed; the next reconcile deploys its desired commit"
+	case StatusAwaitingApproval:
+		message = "App resumed; its desired commit is awaiting approval"
+	}
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: message,
+		Data:    app,
+	})
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/notify"
+	"github.com/google/uuid"
+)
+
+// PreviewSweepIntervalEnv sets how often expired preview apps are looked for.
+const PreviewSweepIntervalEnv = "CONOPS_PREVIEW_SWEEP_INTERVAL"
+
+// AuditActionExpire records the removal of an expired preview app.
+const AuditActionExpire = "app.expire"
+
+// previewSweeperActor is the audit actor of sweeper removals.
+const previewSweeperActor = "preview-sweeper"
+
+// RuntimePurger removes an app's stack together with its volumes and images.
+type RuntimePurger interface {
+	Purge(ctx context.Context, appID, composePath string, envVars map[string]string) (string, error)
+}
+
+// PreviewSweeper deletes preview apps (apps with a preview_ttl) that have
+// been synced or failed for longer than their TTL, so abandoned previews do
+// not pile up on the host.
+type PreviewSweeper struct {
+	Registry *Registry
+	Purger   RuntimePurger
+	// Queue is consulted so an app is never removed mid-sync.
+	Queue    *SyncQueue
+	Logger   *slog.Logger
+	Interval time.Duration
+}
+
+// PreviewSweepResult reports one sweep.
+type PreviewSweepResult struct {
+	Checked int
+	Removed []string // app IDs
+	Failed  map[string]error
+}
+
+// LoadPreviewSweepIntervalFromEnv returns the sweep interval, 5m by default.
+// Zero disables the sweeper.
+func LoadPreviewSweepIntervalFromEnv() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(PreviewSweepIntervalEnv))
+	if value == "" {
+		return 5 * time.Minute, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", PreviewSweepIntervalEnv, value)
+	}
+	return interval, nil
+}
+
+// Run sweeps every Interval until ctx is done.
+func (s *PreviewSweeper) Run(ctx context.Context) {
+	if s.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx, time.Now())
+		}
+	}
+}
+
+// Sweep removes every preview app that expired by now.
+func (s *PreviewSweeper) Sweep(ctx context.Context, now time.Time) PreviewSweepResult {
+	result := PreviewSweepResult{Failed: make(map[string]error)}
+	for _, app := range s.Registry.List() {
+		if app.PreviewTTL == "" {
+			continue
+		}
+		result.Checked++
+		idle, expired := previewExpired(app, now)
+		if !expired || s.Queue.busy(app.ID) {
+			continue
+		}
+		if err := s.remove(ctx, app, idle); err != nil {
+			result.Failed[app.ID] = err
+			if s.Logger != nil {
+				s.Logger.Error("Failed to remove expired preview", "app_id", app.ID, "error", err)
+			}
+			continue
+		}
+		result.Removed = append(result.Removed, app.ID)
+	}
+
+	if s.Logger != nil && (len(result.Removed) > 0 || len(result.Failed) > 0) {
+		s.Logger.Info("Preview sweep finished", "previews", result.Checked, "removed", len(result.Removed), "failed", len(result.Failed))
+	}
+	return result
+}
+
+// PreviewExpiry returns when app will be swept. Only settled previews
+// expire: pending and syncing apps are still being worked on, and apps that
+// never synced have no idle time yet, so ok is false for them.
+func PreviewExpiry(app *api.App) (time.Time, bool) {
+	if app.Status != "synced" && app.Status != "error" {
+		return time.Time{}, false
+	}
+	ttl, err := time.ParseDuration(app.PreviewTTL)
+	if err != nil || ttl <= 0 || app.LastSyncAt.IsZero() {
+		return time.Time{}, false
+	}
+	return app.LastSyncAt.Add(ttl), true
+}
+
+// previewExpired reports how long app has been idle and whether that
+// exceeds its TTL.
+func previewExpired(app *api.App, now time.Time) (time.Duration, bool) {
+	expiresAt, ok := PreviewExpiry(app)
+	if !ok {
+		return 0, false
+	}
+	return now.Sub(app.LastSyncAt), !now.Before(expiresAt)
+}
+
+func (s *PreviewSweeper) remove(ctx context.Context, app *api.App, idle time.Duration) error {
+	if s.Logger != nil {
+		s.Logger.Info("Removing expired preview", "app_id", app.ID, "name", app.Name, "ttl", app.PreviewTTL, "idle", idle.Round(time.Second).String())
+	}
+
+	entry := &api.AuditEntry{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		Actor:     previewSweeperActor,
+		Action:    AuditActionExpire,
+		AppID:     app.ID,
+		Outcome:   auditOutcomeSuccess,
+		Changes:   map[string]api.FieldChange{"name": {From: app.Name}},
+	}
+
+	if s.Purger != nil {
+		purgeCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		_, err := s.Purger.Purge(purgeCtx, app.ID, app.ComposePath, nil)
+		cancel()
+		if err != nil {
+			s.audit(entry, err)
+			return fmt.Errorf("purge runtime: %w", err)
+		}
+	}
+	s.Registry.Notifier().Publish(app, notify.Event{
+		Type:   notify.EventPreviewExpired,
+		Commit: app.LastSyncedCommit,
+		Status: app.Status,
+		Reason: fmt.Sprintf("idle for %s (ttl %s)", idle.Round(time.Minute), app.PreviewTTL),
+	})
+	if err := s.Registry.Delete(app.ID); err != nil {
+		s.audit(entry, err)
+		return fmt.Errorf("delete app: %w", err)
+	}
+	s.audit(entry, nil)
+	return nil
+}
+
+func (s *PreviewSweeper) audit(entry *api.AuditEntry, err error) {
+	if err != nil {
+		entry.Outcome = auditOutcomeError
+	}
+	if recordErr := s.Registry.RecordAudit(entry); recordErr != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to record audit entry", "action", entry.Action, "app_id", entry.AppID, "error", recordErr)
+	}
+}
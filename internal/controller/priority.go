@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority classes, highest first. Apps without one are normal.
+const (
+	PriorityCritical = "critical"
+	PriorityHigh     = "high"
+	PriorityNormal   = "normal"
+	PriorityLow      = "low"
+)
+
+// Priorities lists the priority classes, highest first.
+var Priorities = []string{PriorityCritical, PriorityHigh, PriorityNormal, PriorityLow}
+
+// normalizePriority validates a priority class. Normal is stored as empty
+// so apps that never set one and apps set back to normal look the same.
+func normalizePriority(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", PriorityNormal:
+		return "", nil
+	case PriorityCritical, PriorityHigh, PriorityLow:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid priority %q: must be one of %s", value, strings.Join(Priorities, ", "))
+}
+
+// priorityRank orders apps by priority; lower ranks go first.
+func priorityRank(app *App) int {
+	switch app.Priority {
+	case PriorityCritical:
+		return 0
+	case PriorityHigh:
+		return 1
+	case PriorityLow:
+		return 3
+	}
+	return 2
+}
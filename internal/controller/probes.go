@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/store"
+)
+
+// probeTimeout bounds each readiness check.
+const probeTimeout = 5 * time.Second
+
+// Probe statuses.
+const (
+	ProbeOK          = "ok"
+	ProbeUnavailable = "unavailable"
+)
+
+// RuntimePreflighter checks that the runtime can deploy, e.g. that docker
+// answers.
+type RuntimePreflighter interface {
+	Preflight(ctx context.Context) error
+}
+
+// Probes serves the controller's own liveness and readiness checks for
+// load balancers and orchestrators.
+type Probes struct {
+	Store store.Store
+	// Runtime is checked for readiness when set.
+	Runtime RuntimePreflighter
+	// CacheDir is the git watcher's clone directory, which must be
+	// writable.
+	CacheDir string
+	// Healthy reports whether the reconcile loop is making progress.
+	Healthy func() bool
+	// Role, when set, reports whether this controller is the leader or
+	// a standby.
+	Role func() string
+}
+
+// Live handles GET /healthz. It fails only when the reconcile loop is
+// wedged, which a restart fixes.
+func (p *Probes) Live(w http.ResponseWriter, r *http.Request) {
+	p.writeReport(w, p.LiveChecks())
+}
+
+// LiveChecks returns the result of each liveness check by name.
+func (p *Probes) LiveChecks() map[string]string {
+	checks := map[string]string{"reconciler": ProbeOK}
+	if p.Healthy != nil && !p.Healthy() {
+		checks["reconciler"] = "no progress within the sync timeout"
+	}
+	return checks
+}
+
+// Ready handles GET /readyz. It fails while the controller cannot serve
+// or deploy: the store does not answer, docker is unusable, or the repo
+// cache is not writable.
+func (p *Probes) Ready(w http.ResponseWriter, r *http.Request) {
+	p.writeReport(w, p.ReadyChecks(r.Context()))
+}
+
+// ReadyChecks returns the result of each readiness check by name.
+func (p *Probes) ReadyChecks(ctx context.Context) map[string]string {
+	checks := map[string]string{
+		"store":     p.check(ctx, p.Store.Ping),
+		"git_cache": p.check(ctx, p.checkCacheDir),
+	}
+	if p.Runtime != nil {
+		checks["docker"] = p.check(ctx, p.Runtime.Preflight)
+	}
+	return checks
+}
+
+func (p *Probes) check(ctx context.Context, check func(ctx context.Context) error) string {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	if err := check(ctx); err != nil {
+		return err.Error()
+	}
+	return ProbeOK
+}
+
+func (p *Probes) checkCacheDir(ctx context.Context) error {
+	if err := os.MkdirAll(p.CacheDir, 0755); err != nil {
+		return fmt.Errorf("create %s: %w", p.CacheDir, err)
+	}
+	file, err := os.CreateTemp(p.CacheDir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", p.CacheDir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func (p *Probes) writeReport(w http.ResponseWriter, checks map[string]string) {
+	report := api.ProbeReport{Status: ProbeOK, Checks: checks}
+	if p.Role != nil {
+		report.Role = p.Role()
+	}
+	for _, result := range checks {
+		if result != ProbeOK {
+			report.Status = ProbeUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != ProbeOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
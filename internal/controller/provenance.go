@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/provenance"
+)
+
+// approverLookback bounds the audit entries searched for a commit's
+// approval.
+const approverLookback = 200
+
+// ComposeDigester reports the digest of the compose file an app's last
+// apply deployed.
+type ComposeDigester interface {
+	ComposeDigest(appID, composePath string) (string, error)
+}
+
+// attest signs the provenance of a successful apply into record, pushes it
+// when a push URL is configured and notes the outcome in transcript.
+// Failures are logged; they never fail the sync.
+func (s *Syncer) attest(ctx context.Context, app *App, opts SyncOptions, record *api.SyncRecord, transcript *compose.Transcript) {
+	if s.Attestor == nil {
+		return
+	}
+
+	deployment := provenance.Deployment{
+		SyncID:      record.ID,
+		AppID:       app.ID,
+		AppName:     app.Name,
+		RepoURL:     app.RepoURL,
+		Ref:         applyBranch(app),
+		Commit:      record.Commit,
+		ComposePath: app.ComposePath,
+		Profiles:    app.Profiles,
+		Trigger:     record.Trigger,
+		Actor:       record.Actor,
+		Approver:    s.approver(app.ID, record.Commit),
+		Images:      record.Images,
+		StartedAt:   record.StartedAt,
+		FinishedAt:  time.Now(),
+	}
+	if digester, ok := s.Applier.(ComposeDigester); ok {
+		digest, err := digester.ComposeDigest(app.ID, app.ComposePath)
+		if err != nil && s.Logger != nil {
+			s.Logger.Warn("Failed to hash compose file for attestation", "app_id", app.ID, "error", err)
+		}
+		deployment.ComposeDigest = digest
+	}
+
+	attestation, err := s.Attestor.Attest(deployment)
+	transcript.Step(StepAttestation)
+	transcript.Section("Attestation")
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Warn("Failed to sign attestation", "app_id", app.ID, "error", err)
+		}
+		transcript.Line("failed to sign: " + err.Error())
+		return
+	}
+	record.Attestation = attestation
+
+	transcript.Line("signed provenance with key " + s.Attestor.KeyID())
+	if s.Attestor.Pushes() {
+		if err := s.Attestor.Push(ctx, attestation); err != nil {
+			if s.Logger != nil {
+				s.Logger.Warn("Failed to push attestation", "app_id", app.ID, "error", err)
+			}
+			transcript.Line("push failed: " + err.Error())
+		} else {
+			transcript.Line("pushed")
+		}
+	}
+}
+
+// approver returns who approved commit for app, or "" when it was not held
+// for approval.
+func (s *Syncer) approver(appID, commit string) string {
+	entries, err := s.Registry.ListAudit(appID, approverLookback)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.Action == AuditActionApprove && entry.Outcome == auditOutcomeSuccess && entry.Changes["commit"].To == commit {
+			return entry.Actor
+		}
+	}
+	return ""
+}
+
+// GetAttestationKey handles GET /api/v1/attestation/key
+func (h *Handler) GetAttestationKey(w http.ResponseWriter, r *http.Request) {
+	if h.Syncer.Attestor == nil {
+		http.Error(w, "attestations are not enabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(h.Syncer.Attestor.PublicKeyPEM())
+}
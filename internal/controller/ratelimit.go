@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// RateLimitEnv is the sustained requests per second each client may
+	// make to the API and UI; unset or 0 leaves requests unlimited.
+	RateLimitEnv = "CONOPS_RATE_LIMIT"
+	// RateLimitBurstEnv is how many requests a client may make at once
+	// before the rate applies.
+	RateLimitBurstEnv = "CONOPS_RATE_LIMIT_BURST"
+	// TrustedProxiesEnv lists the addresses or CIDRs of reverse proxies
+	// whose X-Forwarded-For header names the client.
+	TrustedProxiesEnv = "CONOPS_TRUSTED_PROXIES"
+	// MaxBodySizeEnv caps request bodies, e.g. "1MiB".
+	MaxBodySizeEnv = "CONOPS_MAX_BODY_SIZE"
+	// MaxImportSizeEnv caps the body of a state import, which holds every
+	// app.
+	MaxImportSizeEnv = "CONOPS_MAX_IMPORT_SIZE"
+)
+
+// rateClientIdle is how long a client's bucket is kept after its last
+// request; a returning client starts with a full burst either way.
+const rateClientIdle = 10 * time.Minute
+
+// RequestLimits protects the controller from clients sending too many or
+// too large requests.
+type RequestLimits struct {
+	// Rate is the sustained requests per second per client; zero disables
+	// rate limiting.
+	Rate  float64
+	Burst int
+	// TrustedProxies are the peers whose X-Forwarded-For is believed.
+	TrustedProxies []netip.Prefix
+	MaxBody        int64
+	MaxImportBody  int64
+}
+
+// LoadRequestLimitsFromEnv reads the limits. Rate limiting is off unless
+// CONOPS_RATE_LIMIT is set, so dashboards and scripts polling the API keep
+// working after an upgrade; bursts default to 60. Bodies are capped at
+// 1 MiB, or 64 MiB for imports.
+func LoadRequestLimitsFromEnv() (RequestLimits, error) {
+	limits := RequestLimits{Burst: 60, MaxBody: 1 << 20, MaxImportBody: 64 << 20}
+	if value := strings.TrimSpace(os.Getenv(RateLimitEnv)); value != "" {
+		perSecond, err := strconv.ParseFloat(value, 64)
+		if err != nil || perSecond < 0 || math.IsInf(perSecond, 0) {
+			return RequestLimits{}, fmt.Errorf("invalid %s: %s", RateLimitEnv, value)
+		}
+		limits.Rate = perSecond
+	}
+	if value := strings.TrimSpace(os.Getenv(RateLimitBurstEnv)); value != "" {
+		burst, err := strconv.Atoi(value)
+		if err != nil || burst < 1 {
+			return RequestLimits{}, fmt.Errorf("invalid %s: %s", RateLimitBurstEnv, value)
+		}
+		limits.Burst = burst
+	}
+	if value := strings.TrimSpace(os.Getenv(TrustedProxiesEnv)); value != "" {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(entry)
+				if addrErr != nil {
+					return RequestLimits{}, fmt.Errorf("invalid %s: %s", TrustedProxiesEnv, entry)
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			limits.TrustedProxies = append(limits.TrustedProxies, prefix.Masked())
+		}
+	}
+	for _, setting := range []struct {
+		env    string
+		target *int64
+	}{{MaxBodySizeEnv, &limits.MaxBody}, {MaxImportSizeEnv, &limits.MaxImportBody}} {
+		if value := strings.TrimSpace(os.Getenv(setting.env)); value != "" {
+			size, err := parseByteSize(value)
+			if err != nil || size <= 0 {
+				return RequestLimits{}, fmt.Errorf("invalid %s: %s", setting.env, value)
+			}
+			*setting.target = size
+		}
+	}
+	return limits, nil
+}
+
+// RateLimiter hands each client a token bucket and answers 429 Too Many
+// Requests, with a Retry-After, once a client has used it up.
+type RateLimiter struct {
+	limits RequestLimits
+
+	mu      sync.Mutex
+	clients map[string]*rateClient
+	swept   time.Time
+}
+
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a limiter enforcing limits.Rate and limits.Burst.
+func NewRateLimiter(limits RequestLimits) *RateLimiter {
+	return &RateLimiter{limits: limits, clients: make(map[string]*rateClient)}
+}
+
+// Enabled reports whether requests are rate limited.
+func (l *RateLimiter) Enabled() bool {
+	return l != nil && l.limits.Rate > 0
+}
+
+// ByIP limits requests per client address. It runs before authentication,
+// so callers with bad credentials are limited too.
+func (l *RateLimiter) ByIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.Enabled() && !l.allow(w, "ip:"+ClientIP(r, l.limits.TrustedProxies)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ByToken limits the requests of each app token on top of ByIP, so a token
+// used from many CI runners at once cannot flood the controller. It must
+// run after Authenticate; other callers pass through.
+func (l *RateLimiter) ByToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := principalFrom(r); l.Enabled() && p.token != nil && !l.allow(w, "token:"+p.token.ID) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from key's bucket, or answers the request when the
+// bucket is empty.
+func (l *RateLimiter) allow(w http.ResponseWriter, key string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	if now.Sub(l.swept) >= time.Minute {
+		for id, client := range l.clients {
+			if now.Sub(client.lastSeen) >= rateClientIdle {
+				delete(l.clients, id)
+			}
+		}
+		l.swept = now
+	}
+	client, ok := l.clients[key]
+	if !ok {
+		client = &rateClient{limiter: rate.NewLimiter(rate.Limit(l.limits.Rate), l.limits.Burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+	reservation := client.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	http.Error(w, "Rate limit exceeded; retry later", http.StatusTooManyRequests)
+	return false
+}
+
+// ClientIP returns the address of the client that sent r. Behind trusted
+// proxies it is the last X-Forwarded-For entry they did not add.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(peer, trusted) {
+		return host
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		if !isTrustedProxy(addr, trusted) {
+			return addr.String()
+		}
+	}
+	return host
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxBodySize answers 413 Request Entity Too Large for bodies declared
+// larger than limit and cuts off longer ones, which then fail to decode.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, fmt.Sprintf("Request body is larger than %d bytes", limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
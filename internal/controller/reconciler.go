@@ -2,21 +2,51 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/gates"
+	"github.com/conops/conops/internal/notify"
+	"github.com/conops/conops/internal/signing"
 )
 
+// ReconcilerRuntime is the runtime the reconciler applies apps to and reads
+// drift from.
+type ReconcilerRuntime interface {
+	RuntimeApplier
+	SnapshotProjects(ctx context.Context) (map[string]compose.ProjectRuntimeState, error)
+}
+
 // ReconcilerConfig controls how the monolith applies desired state.
 type ReconcilerConfig struct {
 	Interval    time.Duration
 	SyncTimeout time.Duration
 	RetryErrors bool
+	// RetryBackoff is how long a failed app waits before its first retry;
+	// further failures wait 2, 10 and then 60 times as long, up to
+	// RetryBackoffMax.
+	// Zero retries on every pass.
+	RetryBackoff    time.Duration
+	RetryBackoffMax time.Duration
+	// AutoRollback re-applies the last synced commit when a sync fails
+	// after its containers were replaced.
+	AutoRollback bool
+	// RecoveryCooldown delays resuming syncs interrupted by a restart so
+	// a host coming back from an outage can settle first.
+	RecoveryCooldown time.Duration
+	// RecoveryMaxSyncs caps the interrupted syncs resumed per reconcile
+	// pass; zero resumes all of them at once.
+	RecoveryMaxSyncs int
+	// MaxConcurrentSyncs caps the apps a reconcile pass syncs at once.
+	MaxConcurrentSyncs int
 }
 
 // LoadReconcilerConfigFromEnv loads reconciler config from environment variables.
@@ -44,30 +74,88 @@ func LoadReconcilerConfigFromEnv() (ReconcilerConfig, error) {
 	}
 
 	retryErrors := strings.EqualFold(os.Getenv("CONOPS_RETRY_ERRORS"), "true")
+	autoRollback := strings.EqualFold(os.Getenv("CONOPS_AUTO_ROLLBACK"), "true")
+
+	backoff := 30 * time.Second
+	if value := strings.TrimSpace(os.Getenv("CONOPS_RETRY_BACKOFF")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return ReconcilerConfig{}, fmt.Errorf("invalid CONOPS_RETRY_BACKOFF: %s", value)
+		}
+		backoff = parsed
+	}
+
+	backoffMax := 30 * time.Minute
+	if value := strings.TrimSpace(os.Getenv("CONOPS_RETRY_BACKOFF_MAX")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return ReconcilerConfig{}, fmt.Errorf("invalid CONOPS_RETRY_BACKOFF_MAX: %s", value)
+		}
+		backoffMax = parsed
+	}
+
+	cooldown := 30 * time.Second
+	if value := strings.TrimSpace(os.Getenv("CONOPS_RECOVERY_COOLDOWN")); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return ReconcilerConfig{}, fmt.Errorf("invalid CONOPS_RECOVERY_COOLDOWN: %s", value)
+		}
+		cooldown = parsed
+	}
+
+	maxRecovery := 2
+	if value := strings.TrimSpace(os.Getenv("CONOPS_RECOVERY_MAX_SYNCS")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return ReconcilerConfig{}, fmt.Errorf("invalid CONOPS_RECOVERY_MAX_SYNCS: %s", value)
+		}
+		maxRecovery = parsed
+	}
+
+	maxConcurrent := 4
+	if value := strings.TrimSpace(os.Getenv("CONOPS_MAX_CONCURRENT_SYNCS")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return ReconcilerConfig{}, fmt.Errorf("invalid CONOPS_MAX_CONCURRENT_SYNCS: %s", value)
+		}
+		maxConcurrent = parsed
+	}
 
 	return ReconcilerConfig{
-		Interval:    interval,
-		SyncTimeout: timeout,
-		RetryErrors: retryErrors,
+		Interval:           interval,
+		SyncTimeout:        timeout,
+		RetryErrors:        retryErrors,
+		RetryBackoff:       backoff,
+		RetryBackoffMax:    backoffMax,
+		AutoRollback:       autoRollback,
+		RecoveryCooldown:   cooldown,
+		RecoveryMaxSyncs:   maxRecovery,
+		MaxConcurrentSyncs: maxConcurrent,
 	}, nil
 }
 
 // Reconciler applies desired state directly on the host (monolith mode).
 type Reconciler struct {
 	Registry *Registry
-	Executor *compose.ComposeExecutor
+	Executor ReconcilerRuntime
+	Syncer   *Syncer
 	Logger   *slog.Logger
 	Config   ReconcilerConfig
 
-	mu      sync.Mutex
-	running bool
+	mu           sync.Mutex
+	running      bool
+	lastProgress time.Time
+	startedAt    time.Time
 }
 
 // NewReconciler creates a new reconciler.
-func NewReconciler(registry *Registry, executor *compose.ComposeExecutor, logger *slog.Logger, cfg ReconcilerConfig) *Reconciler {
+func NewReconciler(registry *Registry, executor ReconcilerRuntime, logger *slog.Logger, cfg ReconcilerConfig) *Reconciler {
+	syncer := NewSyncer(registry, executor, logger)
+	syncer.AutoRollback = cfg.AutoRollback
 	return &Reconciler{
 		Registry: registry,
 		Executor: executor,
+		Syncer:   syncer,
 		Logger:   logger,
 		Config:   cfg,
 	}
@@ -75,6 +163,9 @@ func NewReconciler(registry *Registry, executor *compose.ComposeExecutor, logger
 
 // Run starts the reconciliation loop.
 func (r *Reconciler) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.startedAt = time.Now()
+	r.mu.Unlock()
 	r.reconcileOnce()
 
 	ticker := time.NewTicker(r.Config.Interval)
@@ -83,6 +174,10 @@ func (r *Reconciler) Run(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
+			// A stopped loop, e.g. on a standby controller, is not wedged.
+			r.mu.Lock()
+			r.lastProgress = time.Time{}
+			r.mu.Unlock()
 			if r.Logger != nil {
 				r.Logger.Info("Reconciler stopped")
 			}
@@ -101,9 +196,11 @@ func (r *Reconciler) reconcileOnce() {
 	}
 	r.running = true
 	r.mu.Unlock()
+	r.markProgress()
 	defer func() {
 		r.mu.Lock()
 		r.running = false
+		r.lastProgress = time.Now()
 		r.mu.Unlock()
 	}()
 
@@ -117,9 +214,17 @@ func (r *Reconciler) reconcileOnce() {
 	}
 
 	apps := r.Registry.List()
+	resume := r.resumableSyncs(apps)
+	// Higher priorities sync first; after a reboot every app drifts at
+	// once and shared infrastructure such as a proxy must come back before
+	// the apps behind it.
+	slices.SortStableFunc(apps, func(a, b *App) int { return priorityRank(a) - priorityRank(b) })
+
+	var due []*App
 	for _, app := range apps {
+		r.markProgress()
 		if app.Status == "syncing" {
-			if r.syncLooksStale(app) {
+			if resume[app.ID] {
 				r.requeuePending(app, "recovering_interrupted_sync")
 			} else {
 				// Skip apps with an active in-flight sync.
@@ -130,27 +235,119 @@ func (r *Reconciler) reconcileOnce() {
 		if app.LastSeenCommit == "" {
 			continue
 		}
+		// A paused app keeps what it runs, drift included.
+		if app.Paused {
+			continue
+		}
 
 		if app.Status == "synced" && runtimeSnapshot != nil {
 			if reason := r.runtimeDriftReason(app.ID, runtimeSnapshot); reason != "" {
+				r.Registry.Notifier().Publish(app, notify.Event{
+					Type:   notify.EventDriftDetected,
+					Commit: app.LastSyncedCommit,
+					Status: app.Status,
+					Reason: reason,
+				})
 				r.requeuePending(app, reason)
 			}
 		}
 
 		switch app.Status {
 		case "pending":
+			// Due now.
 		case "error":
-			if !r.Config.RetryErrors {
+			if !r.Config.RetryErrors || !r.retryDue(app, time.Now()) {
 				continue
 			}
 		default:
 			continue
 		}
 
-		if err := r.syncApp(app); err != nil && r.Logger != nil {
-			r.Logger.Error("App sync failed", "app_id", app.ID, "error", err)
+		// A freeze holds new commits; re-applying the synced one, e.g. to
+		// repair drift, still goes ahead.
+		if app.LastSeenCommit != app.LastSyncedCommit {
+			if until := FrozenUntil(app, time.Now()); !until.IsZero() {
+				if r.Logger != nil {
+					r.Logger.Debug("App rollout held by freeze window", "app_id", app.ID, "commit", app.LastSeenCommit, "until", until.Format(time.RFC3339))
+				}
+				continue
+			}
 		}
+
+		due = append(due, app)
 	}
+	r.syncAll(due)
+}
+
+// syncAll syncs apps, in priority order, MaxConcurrentSyncs at a time. A
+// priority class finishes before the next one starts, so a slow sync only
+// holds up the apps of its own class.
+func (r *Reconciler) syncAll(apps []*App) {
+	workers := max(r.Config.MaxConcurrentSyncs, 1)
+	for len(apps) > 0 {
+		class := 1
+		for class < len(apps) && priorityRank(apps[class]) == priorityRank(apps[0]) {
+			class++
+		}
+
+		slots := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for _, app := range apps[:class] {
+			slots <- struct{}{}
+			r.markProgress()
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					r.markProgress()
+					wg.Done()
+				}()
+				r.reconcileApp(app)
+			}()
+		}
+		wg.Wait()
+		apps = apps[class:]
+	}
+}
+
+func (r *Reconciler) reconcileApp(app *App) {
+	err := r.syncApp(app)
+	if err == nil || r.Logger == nil {
+		return
+	}
+	if errors.Is(err, ErrSyncInProgress) {
+		// A manual sync got there first.
+		return
+	}
+	var gateErr *gates.FailedError
+	if errors.As(err, &gateErr) && gateErr.Gate == gates.Promote {
+		r.Logger.Info("App rollout held by promote gate", "app_id", app.ID, "reason", gateErr.Reason)
+		return
+	}
+	var unverified *signing.UnverifiedError
+	if errors.As(err, &unverified) {
+		r.Logger.Warn("App rollout blocked by signing policy", "app_id", app.ID, "commit", unverified.Commit, "reason", unverified.Reason)
+		return
+	}
+	r.Logger.Error("App sync failed", "app_id", app.ID, "error", err)
+}
+
+// Healthy reports whether the reconcile loop is still making progress. Each
+// sync is bounded by SyncTimeout, so a loop silent for longer than that plus
+// two intervals is considered wedged.
+func (r *Reconciler) Healthy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastProgress.IsZero() {
+		return true
+	}
+	return time.Since(r.lastProgress) < r.Config.SyncTimeout+2*r.Config.Interval+time.Minute
+}
+
+func (r *Reconciler) markProgress() {
+	r.mu.Lock()
+	r.lastProgress = time.Now()
+	r.mu.Unlock()
 }
 
 func (r *Reconciler) captureRuntimeSnapshot() (map[string]compose.ProjectRuntimeState, error) {
@@ -165,13 +362,21 @@ func (r *Reconciler) captureRuntimeSnapshot() (map[string]compose.ProjectRuntime
 
 func (r *Reconciler) runtimeDriftReason(appID string, snapshot map[string]compose.ProjectRuntimeState) string {
 	projectName := compose.ProjectNameForApp(appID)
-	state, ok := snapshot[projectName]
-	if !ok || state.ContainerCount == 0 {
+	return runtimeStateDriftReason(snapshot[projectName])
+}
+
+// runtimeStateDriftReason says how a project's containers differ from a
+// fully running, healthy stack, or returns "" when they do not.
+func runtimeStateDriftReason(state compose.ProjectRuntimeState) string {
+	if state.ContainerCount == 0 {
 		return "runtime_missing"
 	}
 	if state.UnhealthyCount > 0 {
 		return "runtime_unhealthy"
 	}
+	if state.RestartingCount > 0 {
+		return "runtime_restarting"
+	}
 	if state.ExitedCount > 0 {
 		return "runtime_exited"
 	}
@@ -181,6 +386,44 @@ func (r *Reconciler) runtimeDriftReason(appID string, snapshot map[string]compos
 	return ""
 }
 
+// resumableSyncs picks the interrupted syncs to resume in this pass: none
+// during the startup cooldown, then at most RecoveryMaxSyncs of them,
+// highest priority first.
+func (r *Reconciler) resumableSyncs(apps []*App) map[string]bool {
+	var interrupted []*App
+	for _, app := range apps {
+		if app.Status == "syncing" && r.syncLooksStale(app) && !r.Syncer.Queue.busy(app.ID) {
+			interrupted = append(interrupted, app)
+		}
+	}
+	if len(interrupted) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	startedAt := r.startedAt
+	r.mu.Unlock()
+	if wait := r.Config.RecoveryCooldown - time.Since(startedAt); wait > 0 {
+		if r.Logger != nil {
+			r.Logger.Info("Deferring interrupted syncs during startup cooldown", "apps", len(interrupted), "remaining", wait.Round(time.Second).String())
+		}
+		return nil
+	}
+
+	slices.SortStableFunc(interrupted, func(a, b *App) int { return priorityRank(a) - priorityRank(b) })
+	if limit := r.Config.RecoveryMaxSyncs; limit > 0 && len(interrupted) > limit {
+		if r.Logger != nil {
+			r.Logger.Info("Resuming interrupted syncs in batches", "apps", len(interrupted), "batch", limit)
+		}
+		interrupted = interrupted[:limit]
+	}
+	resume := make(map[string]bool, len(interrupted))
+	for _, app := range interrupted {
+		resume[app.ID] = true
+	}
+	return resume
+}
+
 func (r *Reconciler) requeuePending(app *App, reason string) {
 	if app.Status == "pending" {
 		return
@@ -213,71 +456,11 @@ func (r *Reconciler) syncLooksStale(app *App) bool {
 }
 
 func (r *Reconciler) syncApp(app *App) error {
-	syncStartedAt := time.Now()
-	if err := r.Registry.UpdateStatus(app.ID, "syncing", &syncStartedAt); err != nil && r.Logger != nil {
-		r.Logger.Warn("Failed to mark app syncing", "app_id", app.ID, "error", err)
-	}
-
-	deployKey, err := r.Registry.GetDeployKey(app.ID)
-	if err != nil {
-		_ = r.Registry.UpdateStatus(app.ID, "error", nil)
-		return fmt.Errorf("failed to load app credentials: %w", err)
-	}
-	defer zeroBytes(deployKey)
-
-	envVars, err := r.Registry.GetAppEnvs(app.ID)
-	if err != nil {
-		_ = r.Registry.UpdateStatus(app.ID, "error", nil)
-		return fmt.Errorf("failed to load app envs: %w", err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), r.Config.SyncTimeout)
-	defer cancel()
-
-	progress := newSyncProgressReporter(r.Registry, r.Logger, app.ID, syncProgressFlushInterval)
-	output, err := r.Executor.Apply(
-		ctx,
-		app.ID,
-		"",
-		envVars,
-		app.RepoURL,
-		app.Branch,
-		app.ComposePath,
-		app.LastSeenCommit,
-		deployKey,
-		progress.Update,
-	)
-	progress.Flush()
-	if err != nil {
-		if r.Logger != nil {
-			r.Logger.Error("Sync apply failed", "app_id", app.ID, "commit", app.LastSeenCommit, "output", truncateOutput(output))
-		}
-		now := time.Now()
-		_ = r.Registry.UpdateSyncResult(
-			app.ID,
-			"error",
-			now,
-			app.LastSyncedCommit,
-			app.LastSyncedCommitMessage,
-			output,
-			err.Error(),
-		)
-		return err
-	}
-
-	now := time.Now()
-	if err := r.Registry.UpdateSyncResult(
-		app.ID,
-		"synced",
-		now,
-		app.LastSeenCommit,
-		app.LastSeenCommitMessage,
-		output,
-		"",
-	); err != nil && r.Logger != nil {
-		r.Logger.Warn("Failed to update app status", "app_id", app.ID, "error", err)
-	}
-	return nil
+	return r.Syncer.Sync(app, SyncOptions{
+		Trigger: SyncTriggerReconcile,
+		Commit:  app.LastSeenCommit,
+		Timeout: r.Config.SyncTimeout,
+	})
 }
 
 func truncateOutput(value string) string {
@@ -5,12 +5,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
 	"github.com/conops/conops/internal/credentials"
+	"github.com/conops/conops/internal/gates"
+	"github.com/conops/conops/internal/hooks"
+	"github.com/conops/conops/internal/notify"
 	"github.com/conops/conops/internal/repoauth"
+	"github.com/conops/conops/internal/semver"
+	"github.com/conops/conops/internal/signing"
 	"github.com/conops/conops/internal/store"
 	"github.com/google/uuid"
 )
@@ -22,6 +32,13 @@ type App = api.App
 type Registry struct {
 	store       store.Store
 	credentials *credentials.Service
+	hooks       *hooks.Runner
+	notifier    *notify.Notifier
+	retryPolicy *RetryPolicy
+
+	// credentialsMu is held for reading while credentials are encrypted or
+	// decrypted and for writing while the key is rotated.
+	credentialsMu sync.RWMutex
 }
 
 // NewRegistry creates a new application registry with the given store backend.
@@ -32,6 +49,26 @@ func NewRegistry(s store.Store, credentialService *credentials.Service) *Registr
 	}
 }
 
+// SetHooks installs the extension hooks used for validation, sync and status events.
+func (r *Registry) SetHooks(runner *hooks.Runner) {
+	r.hooks = runner
+}
+
+// Hooks returns the configured extension hooks. The result may be nil.
+func (r *Registry) Hooks() *hooks.Runner {
+	return r.hooks
+}
+
+// SetNotifier installs the notifier that receives sync and drift events.
+func (r *Registry) SetNotifier(notifier *notify.Notifier) {
+	r.notifier = notifier
+}
+
+// Notifier returns the configured notifier. The result may be nil.
+func (r *Registry) Notifier() *notify.Notifier {
+	return r.notifier
+}
+
 // Add registers a new application.
 func (r *Registry) Add(app *api.App) error {
 	return r.AddWithDeployKey(app, "")
@@ -68,15 +105,8 @@ func (r *Registry) AddWithDeployKeyAndEnvs(app *api.App, deployKey string, servi
 		return fmt.Errorf("encryption support is unavailable: set %s", credentials.EncryptionKeyEnv)
 	}
 
-	// Set defaults if missing
-	if app.Branch == "" {
-		app.Branch = "main"
-	}
-	if app.ComposePath == "" {
-		app.ComposePath = "compose.yaml"
-	}
-	if app.PollInterval == "" {
-		app.PollInterval = "30s"
+	if err := r.validateApp(app); err != nil {
+		return err
 	}
 	// New apps should enter the reconciliation pipeline immediately.
 	app.Status = "pending"
@@ -91,6 +121,8 @@ func (r *Registry) AddWithDeployKeyAndEnvs(app *api.App, deployKey string, servi
 		return nil
 	}
 
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
 	cred := &store.AppCredential{
 		AppID: app.ID,
 	}
@@ -135,6 +167,89 @@ func (r *Registry) AddWithDeployKeyAndEnvs(app *api.App, deployKey string, servi
 	return nil
 }
 
+// validateApp fills in the defaults of app and normalizes and validates its
+// settings, as for every app that enters the store.
+func (r *Registry) validateApp(app *api.App) error {
+	// Set defaults if missing
+	if app.Branch == "" {
+		app.Branch = "main"
+	}
+	if app.ComposePath == "" {
+		app.ComposePath = "compose.yaml"
+	}
+	if app.PollInterval == "" {
+		app.PollInterval = "30s"
+	}
+	if err := gates.Validate(app.GateScript); err != nil {
+		return fmt.Errorf("invalid gate script: %w", err)
+	}
+	webhooks, err := normalizeWebhooks(app.NotifyWebhooks)
+	if err != nil {
+		return err
+	}
+	app.NotifyWebhooks = webhooks
+	if err := normalizeSlack(app); err != nil {
+		return err
+	}
+	if err := normalizeNotifyRouting(app); err != nil {
+		return err
+	}
+	signingKeys, err := signing.NormalizeKeys(app.SigningKeys)
+	if err != nil {
+		return err
+	}
+	app.SigningKeys = signingKeys
+	if err := validateTrack(app.Track); err != nil {
+		return err
+	}
+	watchPaths, err := normalizeWatchPaths(app.WatchPaths)
+	if err != nil {
+		return err
+	}
+	app.WatchPaths = watchPaths
+	profiles, err := normalizeProfiles(app.Profiles)
+	if err != nil {
+		return err
+	}
+	app.Profiles = profiles
+	envFile, err := normalizeEnvFile(app.EnvFile)
+	if err != nil {
+		return err
+	}
+	app.EnvFile = envFile
+	previewTTL, err := normalizePreviewTTL(app.PreviewTTL)
+	if err != nil {
+		return err
+	}
+	app.PreviewTTL = previewTTL
+	priority, err := normalizePriority(app.Priority)
+	if err != nil {
+		return err
+	}
+	app.Priority = priority
+	freezeWindows, err := normalizeFreezeWindows(app.FreezeWindows)
+	if err != nil {
+		return err
+	}
+	app.FreezeWindows = freezeWindows
+	postDeploy, err := normalizePostDeploy(app.PostDeploy)
+	if err != nil {
+		return err
+	}
+	app.PostDeploy = postDeploy
+	preDeploy, err := normalizePreDeploy(app.PreDeploy)
+	if err != nil {
+		return err
+	}
+	app.PreDeploy = preDeploy
+	dockerHost, err := normalizeDockerHost(app.DockerHost)
+	if err != nil {
+		return err
+	}
+	app.DockerHost = dockerHost
+	return r.validateWithHooks(app)
+}
+
 // AddWithDeployKey registers a new application and stores deploy-key credentials when provided.
 func (r *Registry) AddWithDeployKey(app *api.App, deployKey string) error {
 	return r.AddWithDeployKeyAndEnvs(app, deployKey, nil)
@@ -142,7 +257,12 @@ func (r *Registry) AddWithDeployKey(app *api.App, deployKey string) error {
 
 // Get retrieves an application by ID.
 func (r *Registry) Get(id string) (*api.App, error) {
-	return r.store.GetApp(context.Background(), id)
+	app, err := r.store.GetApp(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	r.withNextRetry(app)
+	return app, nil
 }
 
 // List returns all registered applications.
@@ -152,9 +272,21 @@ func (r *Registry) List() []*api.App {
 		// Log error? For now return empty list to be safe for UI.
 		return []*api.App{}
 	}
+	r.withNextRetry(apps...)
 	return apps
 }
 
+// Query returns the page of applications query selects and the number
+// that match it in total.
+func (r *Registry) Query(query store.AppQuery) ([]*api.App, int, error) {
+	apps, total, err := r.store.QueryApps(context.Background(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+	r.withNextRetry(apps...)
+	return apps, total, nil
+}
+
 // Delete removes an application by ID.
 func (r *Registry) Delete(id string) error {
 	if err := r.store.DeleteAppCredential(context.Background(), id); err != nil {
@@ -163,36 +295,290 @@ func (r *Registry) Delete(id string) error {
 	return r.store.DeleteApp(context.Background(), id)
 }
 
+// AppUpdate lists the editable app fields. Nil fields are left unchanged.
+type AppUpdate struct {
+	Name         *string
+	Branch       *string
+	ComposePath  *string
+	PollInterval *string
+	GateScript   *string
+	// NotifyWebhooks replaces the app's webhook list when non-nil.
+	NotifyWebhooks  *[]string
+	SlackWebhookURL *string
+	SlackChannel    *string
+	// NotifyEvents replaces the event types the app's own targets receive
+	// when non-nil; an empty list subscribes to every event.
+	NotifyEvents *[]string
+	// QuietHours sets the daily window in which only failures reach the
+	// app's own targets; an empty value turns quiet hours off.
+	QuietHours *string
+	// SigningKeys replaces the app's allowed commit signers when non-nil; an
+	// empty list turns signature verification off.
+	SigningKeys *[]string
+	// Track sets the semver constraint to follow; an empty value returns the
+	// app to tracking its branch.
+	Track *string
+	// WatchPaths replaces the paths whose changes trigger a sync when
+	// non-nil; an empty list watches the compose file's directory.
+	WatchPaths *[]string
+	// Profiles replaces the enabled compose profiles when non-nil.
+	Profiles *[]string
+	// EnvFile sets the repo-relative .env file passed to compose; an empty
+	// value lets compose read its default .env.
+	EnvFile *string
+	// PreviewTTL sets how long a synced or failed preview app lives without
+	// a new sync; an empty value makes the app permanent.
+	PreviewTTL *string
+	// Priority sets the app's priority class; an empty value means normal.
+	Priority *string
+	// RequireApproval turns the manual approval of new commits on or off.
+	RequireApproval *bool
+	// PinImages turns re-applying a commit with its recorded image digests
+	// on or off.
+	PinImages *bool
+	// FreezeWindows replaces the windows during which new commits are not
+	// deployed when non-nil; an empty list removes them.
+	FreezeWindows *[]string
+	// PostDeploy replaces the commands run after a successful up when
+	// non-nil; an empty list removes them.
+	PostDeploy *[]string
+	// PreDeploy replaces the checks run before up when non-nil; an empty
+	// list removes them.
+	PreDeploy *[]string
+	// DockerHost moves the app to another docker daemon when non-nil; an
+	// empty value is the controller's own.
+	DockerHost *string
+	// RepoAuthMethod switches how the repository is cloned when non-nil;
+	// switching to public removes the stored deploy key.
+	RepoAuthMethod *string
+	// DeployKey replaces the app's deploy key when non-empty; the key is
+	// kept when it is nil or empty.
+	DeployKey *string
+	// ServiceEnvs replaces the app's environment variables when non-nil; an
+	// empty map clears them.
+	ServiceEnvs map[string]string
+}
+
 // UpdateApp updates an application's editable fields and optionally its environment variables.
-func (r *Registry) UpdateApp(id, name, branch, composePath, pollInterval string, serviceEnvs map[string]string) error {
+func (r *Registry) UpdateApp(id string, update AppUpdate) error {
 	if strings.TrimSpace(id) == "" {
 		return fmt.Errorf("app id is required")
 	}
-	if strings.TrimSpace(name) == "" {
+
+	// Verify app exists
+	existing, err := r.store.GetApp(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("app not found: %w", err)
+	}
+
+	candidate := *existing
+	if update.Name != nil {
+		candidate.Name = strings.TrimSpace(*update.Name)
+	}
+	if update.Branch != nil {
+		candidate.Branch = strings.TrimSpace(*update.Branch)
+	}
+	if update.ComposePath != nil {
+		candidate.ComposePath = strings.TrimSpace(*update.ComposePath)
+	}
+	if update.PollInterval != nil {
+		candidate.PollInterval = strings.TrimSpace(*update.PollInterval)
+	}
+	if update.GateScript != nil {
+		candidate.GateScript = *update.GateScript
+	}
+	if update.NotifyWebhooks != nil {
+		webhooks, err := normalizeWebhooks(*update.NotifyWebhooks)
+		if err != nil {
+			return err
+		}
+		candidate.NotifyWebhooks = webhooks
+	}
+	if update.SlackWebhookURL != nil {
+		candidate.SlackWebhookURL = *update.SlackWebhookURL
+	}
+	if update.SlackChannel != nil {
+		candidate.SlackChannel = *update.SlackChannel
+	}
+	if err := normalizeSlack(&candidate); err != nil {
+		return err
+	}
+	if update.NotifyEvents != nil {
+		candidate.NotifyEvents = *update.NotifyEvents
+	}
+	if update.QuietHours != nil {
+		candidate.QuietHours = *update.QuietHours
+	}
+	if err := normalizeNotifyRouting(&candidate); err != nil {
+		return err
+	}
+	if update.SigningKeys != nil {
+		signingKeys, err := signing.NormalizeKeys(*update.SigningKeys)
+		if err != nil {
+			return err
+		}
+		candidate.SigningKeys = signingKeys
+	}
+	if update.Track != nil {
+		candidate.Track = strings.TrimSpace(*update.Track)
+		if err := validateTrack(candidate.Track); err != nil {
+			return err
+		}
+	}
+	if update.WatchPaths != nil {
+		watchPaths, err := normalizeWatchPaths(*update.WatchPaths)
+		if err != nil {
+			return err
+		}
+		candidate.WatchPaths = watchPaths
+	}
+	if update.Profiles != nil {
+		profiles, err := normalizeProfiles(*update.Profiles)
+		if err != nil {
+			return err
+		}
+		candidate.Profiles = profiles
+	}
+	if update.EnvFile != nil {
+		envFile, err := normalizeEnvFile(*update.EnvFile)
+		if err != nil {
+			return err
+		}
+		candidate.EnvFile = envFile
+	}
+	if update.PreviewTTL != nil {
+		previewTTL, err := normalizePreviewTTL(*update.PreviewTTL)
+		if err != nil {
+			return err
+		}
+		candidate.PreviewTTL = previewTTL
+	}
+	if update.Priority != nil {
+		priority, err := normalizePriority(*update.Priority)
+		if err != nil {
+			return err
+		}
+		candidate.Priority = priority
+	}
+	if update.RequireApproval != nil {
+		candidate.RequireApproval = *update.RequireApproval
+	}
+	if update.PinImages != nil {
+		candidate.PinImages = *update.PinImages
+	}
+	if update.FreezeWindows != nil {
+		freezeWindows, err := normalizeFreezeWindows(*update.FreezeWindows)
+		if err != nil {
+			return err
+		}
+		candidate.FreezeWindows = freezeWindows
+	}
+	if update.PostDeploy != nil {
+		postDeploy, err := normalizePostDeploy(*update.PostDeploy)
+		if err != nil {
+			return err
+		}
+		candidate.PostDeploy = postDeploy
+	}
+	if update.PreDeploy != nil {
+		preDeploy, err := normalizePreDeploy(*update.PreDeploy)
+		if err != nil {
+			return err
+		}
+		candidate.PreDeploy = preDeploy
+	}
+	if update.DockerHost != nil {
+		dockerHost, err := normalizeDockerHost(*update.DockerHost)
+		if err != nil {
+			return err
+		}
+		candidate.DockerHost = dockerHost
+	}
+	deployKey := ""
+	if update.DeployKey != nil {
+		deployKey = repoauth.NormalizeDeployKey(*update.DeployKey)
+	}
+	if update.RepoAuthMethod != nil {
+		candidate.RepoAuthMethod = repoauth.NormalizeMethod(*update.RepoAuthMethod)
+		if candidate.RepoAuthMethod == "" {
+			return fmt.Errorf("unsupported repo auth method")
+		}
+	} else if deployKey != "" {
+		candidate.RepoAuthMethod = repoauth.MethodDeployKey
+	}
+	if deployKey != "" && candidate.RepoAuthMethod != repoauth.MethodDeployKey {
+		return fmt.Errorf("a deploy key needs repo auth method %s", repoauth.MethodDeployKey)
+	}
+	// Switching to deploy keys needs a key; one already stored is kept.
+	if candidate.RepoAuthMethod == repoauth.MethodDeployKey && (deployKey != "" || existing.RepoAuthMethod != repoauth.MethodDeployKey) {
+		if err := repoauth.ValidateCreateInput(candidate.RepoURL, candidate.RepoAuthMethod, deployKey); err != nil {
+			return err
+		}
+		if r.credentials == nil || !r.credentials.Enabled() {
+			return fmt.Errorf("deploy key support is unavailable: set %s", credentials.EncryptionKeyEnv)
+		}
+	}
+
+	if candidate.Name == "" {
 		return fmt.Errorf("app name is required")
 	}
-	if strings.TrimSpace(branch) == "" {
+	if candidate.Branch == "" {
 		return fmt.Errorf("branch is required")
 	}
-	if strings.TrimSpace(composePath) == "" {
+	if candidate.ComposePath == "" {
 		return fmt.Errorf("compose path is required")
 	}
-	if strings.TrimSpace(pollInterval) == "" {
+	if candidate.PollInterval == "" {
 		return fmt.Errorf("poll interval is required")
 	}
+	if parsed, err := time.ParseDuration(candidate.PollInterval); err != nil || parsed <= 0 {
+		return fmt.Errorf("invalid poll interval: %s", candidate.PollInterval)
+	}
+	if err := gates.Validate(candidate.GateScript); err != nil {
+		return fmt.Errorf("invalid gate script: %w", err)
+	}
+	if err := r.validateWithHooks(&candidate); err != nil {
+		return err
+	}
 
-	// Verify app exists
-	if _, err := r.store.GetApp(context.Background(), id); err != nil {
-		return fmt.Errorf("app not found: %w", err)
+	// Store a new deploy key before the app uses it.
+	if deployKey != "" {
+		if err := r.setDeployKey(id, deployKey); err != nil {
+			return err
+		}
 	}
 
 	// Update app fields
-	if err := r.store.UpdateApp(context.Background(), id, name, branch, composePath, pollInterval); err != nil {
+	if err := r.store.UpdateApp(context.Background(), &candidate); err != nil {
 		return fmt.Errorf("failed to update app: %w", err)
 	}
+	if existing.RepoAuthMethod == repoauth.MethodDeployKey && candidate.RepoAuthMethod != repoauth.MethodDeployKey {
+		if err := r.store.UpdateAppDeployKey(context.Background(), id, nil, nil); err != nil {
+			return fmt.Errorf("failed to remove deploy key: %w", err)
+		}
+	}
+
+	// The desired commit belongs to the old branch; clear it so the reconciler
+	// waits for the git watcher to resolve the new branch head.
+	if existing.Branch != candidate.Branch {
+		status := "pending"
+		if existing.Status == StatusStopped {
+			status = StatusStopped
+		}
+		if err := r.store.UpdateAppCommit(context.Background(), id, "", "", status); err != nil {
+			return fmt.Errorf("failed to reset desired commit: %w", err)
+		}
+	} else if existing.Status == StatusAwaitingApproval && !candidate.RequireApproval {
+		// Nothing to wait for any more; deploy the held commit.
+		if err := r.UpdateStatus(id, "pending", nil); err != nil {
+			return fmt.Errorf("failed to release held commit: %w", err)
+		}
+	}
 
 	// Update environment variables if provided
-	if serviceEnvs != nil {
+	if serviceEnvs := update.ServiceEnvs; serviceEnvs != nil {
+		r.credentialsMu.RLock()
+		defer r.credentialsMu.RUnlock()
 		hasEnvVars := len(serviceEnvs) > 0
 
 		if hasEnvVars && (r.credentials == nil || !r.credentials.Enabled()) {
@@ -245,6 +631,8 @@ func (r *Registry) UpdateApp(id, name, branch, composePath, pollInterval string,
 
 // GetAppEnvs returns the decrypted environment variables for the app.
 func (r *Registry) GetAppEnvs(id string) (map[string]string, error) {
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
 	credential, err := r.store.GetAppCredential(context.Background(), id)
 	if err != nil {
 		if errors.Is(err, store.ErrCredentialNotFound) {
@@ -275,8 +663,27 @@ func (r *Registry) GetAppEnvs(id string) (map[string]string, error) {
 	return envs, nil
 }
 
+// setDeployKey encrypts and stores a validated deploy key for the app.
+func (r *Registry) setDeployKey(id, deployKey string) error {
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
+	plaintext := []byte(deployKey)
+	defer zeroBytes(plaintext)
+
+	ciphertext, nonce, err := r.credentials.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt deploy key: %w", err)
+	}
+	if err := r.store.UpdateAppDeployKey(context.Background(), id, ciphertext, nonce); err != nil {
+		return fmt.Errorf("failed to store deploy key: %w", err)
+	}
+	return nil
+}
+
 // GetDeployKey returns the decrypted deploy key for the app if configured.
 func (r *Registry) GetDeployKey(id string) ([]byte, error) {
+	r.credentialsMu.RLock()
+	defer r.credentialsMu.RUnlock()
 	credential, err := r.store.GetAppCredential(context.Background(), id)
 	if err != nil {
 		if errors.Is(err, store.ErrCredentialNotFound) {
@@ -309,13 +716,55 @@ func (r *Registry) UpdateCommit(id, commitHash string) error {
 }
 
 // UpdateCommitWithMessage updates latest desired commit hash and subject.
+// Apps that require approval hold the commit in awaiting_approval; stopped
+// apps record it and stay stopped.
 func (r *Registry) UpdateCommitWithMessage(id, commitHash, commitMessage string) error {
-	return r.store.UpdateAppCommit(context.Background(), id, commitHash, commitMessage)
+	app, err := r.store.GetApp(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	status := "pending"
+	switch {
+	case app.Status == StatusStopped:
+		status = StatusStopped
+	case app.RequireApproval && commitHash != "" && commitHash != app.LastSyncedCommit:
+		status = StatusAwaitingApproval
+	}
+	if err := r.store.UpdateAppCommit(context.Background(), id, commitHash, commitMessage, status); err != nil {
+		return err
+	}
+	r.notifyStatusChange(id, app.Status, status, "")
+	if status == StatusAwaitingApproval {
+		r.Notifier().Publish(app, notify.Event{
+			Type:   notify.EventApprovalRequired,
+			Commit: commitHash,
+			Status: status,
+		})
+	}
+	return nil
+}
+
+// SkipCommit records a commit that changed none of the app's watched paths
+// as both seen and synced, so it is not redeployed and the app keeps its
+// status.
+func (r *Registry) SkipCommit(id, commitHash, commitMessage string) error {
+	return r.store.SkipAppCommit(context.Background(), id, commitHash, commitMessage)
+}
+
+// UpdateCommitInfo stores metadata for the app's desired commit. It is a
+// no-op when the desired commit has moved on since info was read.
+func (r *Registry) UpdateCommitInfo(id, commitHash string, info *api.CommitInfo) error {
+	return r.store.UpdateAppCommitInfo(context.Background(), id, commitHash, info)
 }
 
 // UpdateStatus updates app status and optionally the last sync time.
 func (r *Registry) UpdateStatus(id, status string, lastSyncAt *time.Time) error {
-	return r.store.UpdateAppStatus(context.Background(), id, status, lastSyncAt)
+	previous := r.statusBeforeChange(id)
+	if err := r.store.UpdateAppStatus(context.Background(), id, status, lastSyncAt); err != nil {
+		return err
+	}
+	r.notifyStatusChange(id, previous, status, "")
+	return nil
 }
 
 // UpdateSyncResult stores sync execution metadata.
@@ -328,7 +777,8 @@ func (r *Registry) UpdateSyncResult(
 	syncOutput string,
 	syncError string,
 ) error {
-	return r.store.UpdateAppSyncResult(
+	previous := r.statusBeforeChange(id)
+	if err := r.store.UpdateAppSyncResult(
 		context.Background(),
 		id,
 		status,
@@ -337,7 +787,16 @@ func (r *Registry) UpdateSyncResult(
 		syncedCommitMessage,
 		syncOutput,
 		syncError,
-	)
+	); err != nil {
+		return err
+	}
+	r.notifyStatusChange(id, previous, status, syncError)
+	return nil
+}
+
+// ResetSyncFailures clears the app's count of consecutive failed syncs.
+func (r *Registry) ResetSyncFailures(id string) error {
+	return r.store.ResetAppSyncFailures(context.Background(), id)
 }
 
 // UpdateSyncProgress stores in-flight sync logs while status is syncing.
@@ -345,6 +804,193 @@ func (r *Registry) UpdateSyncProgress(id string, lastSyncAt time.Time, syncOutpu
 	return r.store.UpdateAppSyncProgress(context.Background(), id, lastSyncAt, syncOutput)
 }
 
+func (r *Registry) validateWithHooks(app *api.App) error {
+	if !r.hooks.Has(hooks.EventValidateApp) {
+		return nil
+	}
+	payload := hooks.Payload{
+		Event:     hooks.EventValidateApp,
+		Timestamp: time.Now(),
+		App:       hookApp(app),
+	}
+	if err := r.hooks.Check(context.Background(), payload); err != nil {
+		return fmt.Errorf("invalid app configuration: %w", err)
+	}
+	return nil
+}
+
+// statusBeforeChange returns the current status when status_change hooks are
+// configured; it skips the extra read otherwise.
+func (r *Registry) statusBeforeChange(id string) string {
+	if !r.hooks.Has(hooks.EventStatusChange) {
+		return ""
+	}
+	app, err := r.store.GetApp(context.Background(), id)
+	if err != nil {
+		return ""
+	}
+	return app.Status
+}
+
+func (r *Registry) notifyStatusChange(id, previous, status, syncError string) {
+	if previous == status || !r.hooks.Has(hooks.EventStatusChange) {
+		return
+	}
+	app, err := r.store.GetApp(context.Background(), id)
+	if err != nil {
+		return
+	}
+	r.hooks.Dispatch(hooks.Payload{
+		Event:          hooks.EventStatusChange,
+		Timestamp:      time.Now(),
+		App:            hookApp(app),
+		Commit:         app.LastSeenCommit,
+		Status:         status,
+		PreviousStatus: previous,
+		Error:          syncError,
+	})
+}
+
+// normalizeWebhooks trims and de-duplicates webhook URLs, rejecting invalid ones.
+func normalizeWebhooks(values []string) ([]string, error) {
+	var webhooks []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || slices.Contains(webhooks, value) {
+			continue
+		}
+		if err := notify.ValidateWebhookURL(value); err != nil {
+			return nil, fmt.Errorf("invalid notify webhook: %w", err)
+		}
+		webhooks = append(webhooks, value)
+	}
+	return webhooks, nil
+}
+
+func normalizeSlack(app *api.App) error {
+	app.SlackWebhookURL = strings.TrimSpace(app.SlackWebhookURL)
+	app.SlackChannel = strings.TrimSpace(app.SlackChannel)
+	if app.SlackWebhookURL != "" {
+		if err := notify.ValidateWebhookURL(app.SlackWebhookURL); err != nil {
+			return fmt.Errorf("invalid slack webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+func normalizeNotifyRouting(app *api.App) error {
+	events, err := notify.NormalizeEvents(app.NotifyEvents)
+	if err != nil {
+		return err
+	}
+	app.NotifyEvents = events
+	quietHours, err := notify.NormalizeQuietHours(app.QuietHours)
+	if err != nil {
+		return err
+	}
+	app.QuietHours = quietHours
+	return nil
+}
+
+func validateTrack(track string) error {
+	if track == "" {
+		return nil
+	}
+	if _, err := semver.ParseConstraint(track); err != nil {
+		return fmt.Errorf("invalid track: %w", err)
+	}
+	return nil
+}
+
+// normalizeEnvFile makes an env file path repo-relative and keeps it inside
+// the checkout.
+func normalizeEnvFile(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	for _, segment := range strings.Split(value, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid env file %q: must stay inside the repository", value)
+		}
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+value), "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("invalid env file %q: must name a file", value)
+	}
+	return cleaned, nil
+}
+
+// minPreviewTTL keeps a preview from being swept before anyone could look
+// at it.
+const minPreviewTTL = time.Minute
+
+func normalizePreviewTTL(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < minPreviewTTL {
+		return "", fmt.Errorf("invalid preview ttl %q: must be a duration of at least %s", value, minPreviewTTL)
+	}
+	return value, nil
+}
+
+// normalizePostDeploy trims post-deploy entries to "service: command",
+// dropping blank ones. Order is kept; commands run in sequence.
+func normalizePostDeploy(entries []string) ([]string, error) {
+	var normalized []string
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		service, command, err := compose.ParsePostDeploy(entry)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, service+": "+command)
+	}
+	return normalized, nil
+}
+
+// normalizePreDeploy validates pre-deploy checks, dropping blank ones.
+func normalizePreDeploy(entries []string) ([]string, error) {
+	var normalized []string
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		check, err := compose.ParsePreDeploy(entry)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, check.String())
+	}
+	return normalized, nil
+}
+
+// profileNamePattern is the profile name syntax compose accepts.
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// normalizeProfiles trims and de-duplicates compose profile names.
+func normalizeProfiles(profiles []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, profile := range profiles {
+		profile = strings.TrimSpace(profile)
+		if profile == "" || seen[profile] {
+			continue
+		}
+		if !profileNamePattern.MatchString(profile) {
+			return nil, fmt.Errorf("invalid profile %q", profile)
+		}
+		seen[profile] = true
+		normalized = append(normalized, profile)
+	}
+	return normalized, nil
+}
+
 func zeroBytes(value []byte) {
 	for i := range value {
 		value[i] = 0
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/go-chi/chi/v5"
+)
+
+// AuditActionRestart records restarting one service of an app.
+const AuditActionRestart = "app.restart"
+
+// RuntimeRestarter restarts one service of an app's running project.
+type RuntimeRestarter interface {
+	RestartService(ctx context.Context, appID, composePath string, profiles []string, service string) (string, error)
+}
+
+// RestartService handles POST /api/v1/apps/{id}/services/{service}/restart
+func (h *Handler) RestartService(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	service := chi.URLParam(r, "service")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.Restarter == nil {
+		http.Error(w, "the runtime cannot restart services", http.StatusNotImplemented)
+		return
+	}
+	if app.Status == StatusStopped {
+		http.Error(w, "app is stopped; start it first", http.StatusConflict)
+		return
+	}
+	if h.rejectOnStandby(w) {
+		return
+	}
+	if h.Syncer.Queue.busy(app.ID) {
+		http.Error(w, "app is syncing; restart the service once the sync finishes", http.StatusConflict)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	if !h.checkService(ctx, w, app, service) {
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionRestart, app.ID)
+	entry.Changes = map[string]api.FieldChange{"service": {To: service}}
+	_, err = h.Restarter.RestartService(ctx, app.ID, app.ComposePath, app.Profiles, service)
+	h.recordAudit(entry, err)
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to restart service", "app_id", app.ID, "service", service, "error", err)
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.Logger != nil {
+		h.Logger.Info("Service restarted", "app_id", app.ID, "service", service, "actor", entry.Actor)
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Service " + service + " restarted",
+		Data:    app,
+	})
+}
+
+// checkService answers 404 and returns false when the app's project has no
+// container for service. Without an inspector every service passes.
+func (h *Handler) checkService(ctx context.Context, w http.ResponseWriter, app *App, service string) bool {
+	if h.Inspector == nil {
+		return true
+	}
+	containers, err := h.Inspector.InspectProjectContainers(ctx, compose.ProjectNameForApp(app.ID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !slices.ContainsFunc(containers, func(c compose.ServiceContainer) bool { return c.Service == service }) {
+		http.Error(w, "service not found: "+service, http.StatusNotFound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package controller
+
+import "time"
+
+// retryBackoffSteps are the waits after consecutive failures, as multiples
+// of Backoff: with the default of 30s an app is retried after 30s, 1m, 5m
+// and then every 30m.
+var retryBackoffSteps = []time.Duration{1, 2, 10, 60}
+
+// RetryPolicy is how the reconciler retries apps in error: Backoff after
+// the first failure, then stepping up along retryBackoffSteps, never more
+// than BackoffMax. A zero Backoff retries on every pass.
+type RetryPolicy struct {
+	Backoff    time.Duration
+	BackoffMax time.Duration
+}
+
+// RetryPolicy returns the policy the reconciler retries errors with, or nil
+// when it leaves them alone.
+func (c ReconcilerConfig) RetryPolicy() *RetryPolicy {
+	if !c.RetryErrors {
+		return nil
+	}
+	return &RetryPolicy{Backoff: c.RetryBackoff, BackoffMax: c.RetryBackoffMax}
+}
+
+// Delay is how long an app waits after its failures-th consecutive failed
+// sync.
+func (p *RetryPolicy) Delay(failures int) time.Duration {
+	step := min(max(failures, 1), len(retryBackoffSteps)) - 1
+	return min(p.Backoff*retryBackoffSteps[step], p.BackoffMax)
+}
+
+// NextRetryAt returns when app, if in error, is retried next. It is zero
+// when the policy is nil or app is not in error; a retry held only by the
+// reconcile interval is due at the last sync attempt.
+func (p *RetryPolicy) NextRetryAt(app *App) time.Time {
+	if p == nil || app.Status != "error" || app.LastSyncAt.IsZero() {
+		return time.Time{}
+	}
+	if p.Backoff <= 0 {
+		return app.LastSyncAt
+	}
+	return app.LastSyncAt.Add(p.Delay(app.SyncFailures))
+}
+
+// retryDue reports whether a failed app has waited out its backoff since
+// its last sync attempt.
+func (r *Reconciler) retryDue(app *App, now time.Time) bool {
+	due := r.Config.RetryPolicy().NextRetryAt(app)
+	if now.Before(due) {
+		if r.Logger != nil {
+			r.Logger.Debug("App retry held by backoff", "app_id", app.ID, "failures", app.SyncFailures, "until", due.Format(time.RFC3339))
+		}
+		return false
+	}
+	return true
+}
+
+// SetRetryPolicy installs the reconciler's retry policy so apps report when
+// they are retried next. A nil policy reports no retries.
+func (r *Registry) SetRetryPolicy(policy *RetryPolicy) {
+	r.retryPolicy = policy
+}
+
+// withNextRetry fills in NextRetryAt for apps read from the store.
+func (r *Registry) withNextRetry(apps ...*App) {
+	for _, app := range apps {
+		if app == nil {
+			continue
+		}
+		if at := r.retryPolicy.NextRetryAt(app); !at.IsZero() {
+			app.NextRetryAt = &at
+		}
+	}
+}
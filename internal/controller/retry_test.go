@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		failures int
+		want     time.Duration
+	}{
+		{"reset counts as the first failure", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}, 0, 30 * time.Second},
+		{"first failure", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}, 1, 30 * time.Second},
+		{"second failure", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}, 2, time.Minute},
+		{"third failure", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}, 3, 5 * time.Minute},
+		{"last step", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}, 4, 30 * time.Minute},
+		{"past the last step", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}, 50, 30 * time.Minute},
+		{"capped by the maximum", RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 2 * time.Minute}, 3, 2 * time.Minute},
+		{"maximum below the backoff", RetryPolicy{Backoff: time.Minute, BackoffMax: 10 * time.Second}, 1, 10 * time.Second},
+		{"zero backoff", RetryPolicy{BackoffMax: 30 * time.Minute}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.failures); got != tt.want {
+				t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyNextRetryAt(t *testing.T) {
+	lastSync := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	policy := &RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 30 * time.Minute}
+	tests := []struct {
+		name   string
+		policy *RetryPolicy
+		app    App
+		want   time.Time
+	}{
+		{"nil policy", nil, App{Status: "error", LastSyncAt: lastSync, SyncFailures: 2}, time.Time{}},
+		{"not in error", policy, App{Status: "synced", LastSyncAt: lastSync, SyncFailures: 2}, time.Time{}},
+		{"never synced", policy, App{Status: "error", SyncFailures: 2}, time.Time{}},
+		{"zero backoff", &RetryPolicy{BackoffMax: 30 * time.Minute}, App{Status: "error", LastSyncAt: lastSync, SyncFailures: 2}, lastSync},
+		{"failures reset", policy, App{Status: "error", LastSyncAt: lastSync}, lastSync.Add(30 * time.Second)},
+		{"stepped up", policy, App{Status: "error", LastSyncAt: lastSync, SyncFailures: 3}, lastSync.Add(5 * time.Minute)},
+		{"capped", &RetryPolicy{Backoff: 30 * time.Second, BackoffMax: 2 * time.Minute}, App{Status: "error", LastSyncAt: lastSync, SyncFailures: 4}, lastSync.Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.NextRetryAt(&tt.app); !got.Equal(tt.want) {
+				t.Errorf("NextRetryAt = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/go-chi/chi/v5"
+)
+
+// AuditActionRollback records a manual rollback to an earlier commit.
+const AuditActionRollback = "app.rollback"
+
+// ErrNoRollbackTarget is returned when an app has no earlier deployed
+// commit to roll back to.
+var ErrNoRollbackTarget = errors.New("no earlier synced commit to roll back to")
+
+// RollbackTarget returns the commit a rollback of app goes back to: the
+// last commit synced before the one it runs now was first deployed. Rolling
+// back again thus keeps going back in time instead of returning to the
+// commit rolled back from.
+func (r *Registry) RollbackTarget(app *App) (string, error) {
+	records, err := r.ListSyncHistory(app.ID, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	// Records are newest first.
+	older := records
+	for i, record := range records {
+		if record.Status == "synced" && record.Commit == app.LastSyncedCommit {
+			older = records[i+1:]
+		}
+	}
+	for _, record := range older {
+		if record.Status == "synced" && record.Commit != "" && record.Commit != app.LastSyncedCommit {
+			return record.Commit, nil
+		}
+	}
+	return "", ErrNoRollbackTarget
+}
+
+type rollbackAppRequest struct {
+	// Commit is a full commit hash or, with a RevisionResolver, a tag or an
+	// abbreviated hash; empty rolls back to the previously synced commit.
+	Commit string `json:"commit"`
+}
+
+// RollbackApp handles POST /api/v1/apps/{id}/rollback
+//
+// The app is paused first: otherwise the reconciler would roll it forward
+// again, on drift or the next commit. Resuming it deploys the desired
+// commit. Like a manual sync the rollback runs in the background; callers
+// poll the job for the outcome.
+func (h *Handler) RollbackApp(w http.ResponseWriter, r *http.Request) {
+	if h.Applier == nil {
+		http.Error(w, "runtime applier is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.rejectOnStandby(w) {
+		return
+	}
+
+	// The UI posts a form; API clients send JSON or no body at all.
+	var req rollbackAppRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Commit = r.FormValue("commit")
+	}
+	commit := strings.TrimSpace(req.Commit)
+	if commit == "" {
+		if commit, err = h.Registry.RollbackTarget(app); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrNoRollbackTarget) {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	} else if !isFullCommitHash(commit) {
+		if h.Revisions == nil {
+			http.Error(w, fmt.Sprintf("invalid commit %q: want a full commit hash", commit), http.StatusBadRequest)
+			return
+		}
+		resolved, err := h.Revisions.ResolveRevision(app, commit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid commit %q: %v", commit, err), http.StatusBadRequest)
+			return
+		}
+		commit = resolved
+	}
+	if commit == app.LastSyncedCommit {
+		http.Error(w, "app already runs commit "+commit, http.StatusConflict)
+		return
+	}
+	if h.Syncer.Queue.busy(app.ID) {
+		http.Error(w, "app is syncing; roll back once the sync finishes", http.StatusConflict)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionRollback, app.ID)
+	entry.Changes = map[string]api.FieldChange{"commit": {From: app.LastSyncedCommit, To: commit}}
+	paused := false
+	if !app.Paused {
+		if _, err := h.Registry.Pause(app.ID); err != nil && !errors.Is(err, ErrAlreadyPaused) {
+			h.recordAudit(entry, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entry.Changes["paused"] = api.FieldChange{From: "false", To: "true"}
+		paused = true
+	}
+
+	// Start rather than Enqueue: a rollback coalesced into a waiting sync
+	// would deploy that sync's commit instead.
+	job, err := h.Syncer.Start(app, SyncOptions{
+		Trigger: SyncTriggerRollback,
+		Actor:   requestActor(r),
+		Commit:  commit,
+		Timeout: manualSyncTimeout,
+	}, func(err error) {
+		h.recordAudit(entry, err)
+		if err != nil {
+			if h.Logger != nil {
+				h.Logger.Error("Rollback failed", "id", app.ID, "commit", commit, "error", err)
+			}
+			return
+		}
+		if h.Logger != nil {
+			h.Logger.Info("App rolled back", "app_id", app.ID, "commit", commit, "actor", entry.Actor)
+		}
+	})
+	if err != nil {
+		// A sync started since the check above. Nothing was rolled back, so
+		// the app must not stay paused.
+		h.recordAudit(entry, err)
+		if paused {
+			if _, err := h.Registry.Resume(app.ID); err != nil && h.Logger != nil {
+				h.Logger.Error("Failed to resume app after a refused rollback", "id", app.ID, "error", err)
+			}
+		}
+		http.Error(w, "app is syncing; roll back once the sync finishes", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Location", h.BasePath+"/api/v1/apps/"+app.ID+"/syncs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Rolling back to " + commit + "; automatic syncs are paused until the app is resumed",
+		Data:    job,
+	})
+}
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// AuditActionSchedule records a deploy queued for a later time.
+	AuditActionSchedule = "app.schedule"
+	// AuditActionUnschedule records the cancellation of a scheduled deploy.
+	AuditActionUnschedule = "app.unschedule"
+)
+
+// syncSchedulerSource is the audit source of the syncs the scheduler runs.
+const syncSchedulerSource = "scheduler"
+
+// ScheduleSync queues a sync of app at runAt. An empty commit deploys the
+// latest commit on the branch when the sync runs; otherwise it must be a
+// full commit hash, since the runtime fetches it by name.
+func (r *Registry) ScheduleSync(appID string, runAt time.Time, commit, actor string) (*api.ScheduledSync, error) {
+	if _, err := r.Get(appID); err != nil {
+		return nil, err
+	}
+	if runAt.IsZero() {
+		return nil, fmt.Errorf("invalid run time: at is required")
+	}
+	if !runAt.After(time.Now()) {
+		return nil, fmt.Errorf("invalid run time: %s is in the past", runAt.Format(time.RFC3339))
+	}
+	commit = strings.ToLower(strings.TrimSpace(commit))
+	if commit != "" && !isFullCommitHash(commit) {
+		return nil, fmt.Errorf("invalid commit %q: want a full commit hash", commit)
+	}
+
+	schedule := &api.ScheduledSync{
+		ID:        uuid.NewString(),
+		AppID:     appID,
+		RunAt:     runAt.UTC(),
+		Commit:    commit,
+		Actor:     actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := r.store.CreateScheduledSync(context.Background(), schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ListScheduledSyncs returns the upcoming syncs of appID, or of every app
+// when appID is empty, soonest first.
+func (r *Registry) ListScheduledSyncs(appID string) ([]*api.ScheduledSync, error) {
+	return r.store.ListScheduledSyncs(context.Background(), appID)
+}
+
+// CancelScheduledSync removes one of an app's scheduled syncs.
+func (r *Registry) CancelScheduledSync(appID, id string) error {
+	return r.store.DeleteScheduledSync(context.Background(), appID, id)
+}
+
+// isFullCommitHash accepts SHA-1 and SHA-256 object names.
+func isFullCommitHash(value string) bool {
+	if len(value) != 40 && len(value) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// SyncScheduler runs scheduled syncs once they are due. A sync whose app is
+// busy waits for the next check, so it never coalesces into, and loses its
+// commit to, a queued sync. Syncs that fell due while the controller was
+// down run on the first check after it starts.
+type SyncScheduler struct {
+	Registry *Registry
+	// Syncer must share its queue with the reconciler and the API.
+	Syncer   *Syncer
+	Logger   *slog.Logger
+	Interval time.Duration
+}
+
+// Run checks for due syncs now and then every Interval until ctx is done.
+func (s *SyncScheduler) Run(ctx context.Context) {
+	if s.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Dispatch(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch starts every scheduled sync due by now and returns how many
+// started. The syncs run in the background.
+func (s *SyncScheduler) Dispatch(now time.Time) int {
+	schedules, err := s.Registry.ListScheduledSyncs("")
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Error("Failed to list scheduled syncs", "error", err)
+		}
+		return 0
+	}
+
+	started := 0
+	for _, schedule := range schedules {
+		if schedule.RunAt.After(now) {
+			break
+		}
+		if s.start(schedule, now) {
+			started++
+		}
+	}
+	return started
+}
+
+func (s *SyncScheduler) start(schedule *api.ScheduledSync, now time.Time) bool {
+	app, err := s.Registry.Get(schedule.AppID)
+	if err != nil {
+		// Deleting an app removes its schedules; nothing to run.
+		return false
+	}
+	opts := SyncOptions{
+		Trigger: SyncTriggerScheduled,
+		Actor:   schedule.Actor,
+		Commit:  schedule.Commit,
+		Timeout: manualSyncTimeout,
+	}
+	// Claim the app before removing the schedule so the sync runs once even
+	// if the controller stops in between.
+	if !s.Syncer.Queue.begin(app.ID, opts) {
+		return false
+	}
+	if err := s.Registry.CancelScheduledSync(app.ID, schedule.ID); err != nil {
+		s.Syncer.Queue.abandon(app.ID)
+		if !errors.Is(err, store.ErrScheduledSyncNotFound) && s.Logger != nil {
+			s.Logger.Error("Failed to remove scheduled sync", "app_id", app.ID, "schedule_id", schedule.ID, "error", err)
+		}
+		return false
+	}
+	if s.Logger != nil {
+		s.Logger.Info("Starting scheduled sync", "app_id", app.ID, "schedule_id", schedule.ID, "commit", schedule.Commit, "run_at", schedule.RunAt.Format(time.RFC3339), "late", now.Sub(schedule.RunAt).Round(time.Second).String())
+	}
+
+	entry := &api.AuditEntry{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		Actor:     schedule.Actor,
+		Source:    syncSchedulerSource,
+		Action:    AuditActionSync,
+		AppID:     app.ID,
+		Outcome:   auditOutcomeSuccess,
+		Changes:   map[string]api.FieldChange{"schedule": {From: schedule.ID}},
+	}
+	go func() {
+		err := s.Syncer.sync(app, opts)
+		defer s.Syncer.Queue.finish(app.ID, err)
+		if err != nil {
+			entry.Outcome = auditOutcomeError
+			if s.Logger != nil {
+				s.Logger.Error("Scheduled sync failed", "app_id", app.ID, "schedule_id", schedule.ID, "error", err)
+			}
+		}
+		if recordErr := s.Registry.RecordAudit(entry); recordErr != nil && s.Logger != nil {
+			s.Logger.Warn("Failed to record audit entry", "action", entry.Action, "app_id", entry.AppID, "error", recordErr)
+		}
+	}()
+	return true
+}
+
+type scheduleSyncRequest struct {
+	At time.Time `json:"at"`
+	// Commit defaults to the latest commit on the branch at run time.
+	Commit string `json:"commit"`
+}
+
+// ScheduleSync handles POST /api/v1/apps/{id}/schedules
+func (h *Handler) ScheduleSync(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var req scheduleSyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.Registry.ScheduleSync(id, req.At, req.Commit, requestActor(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid") {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry := NewAuditEntry(r, AuditActionSchedule, id)
+	target := schedule.Commit
+	if target == "" {
+		target = "latest"
+	}
+	entry.Changes = map[string]api.FieldChange{
+		"schedule": {To: schedule.RunAt.Format(time.RFC3339) + " (" + target + ")"},
+	}
+	h.recordAudit(entry, nil)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Sync scheduled for " + schedule.RunAt.Format(time.RFC3339),
+		Data:    schedule,
+	})
+}
+
+// ListScheduledSyncs handles GET /api/v1/apps/{id}/schedules
+func (h *Handler) ListScheduledSyncs(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	schedules, err := h.Registry.ListScheduledSyncs(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if schedules == nil {
+		schedules = []*api.ScheduledSync{}
+	}
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Data: schedules,
+	})
+}
+
+// CancelScheduledSync handles DELETE /api/v1/apps/{id}/schedules/{scheduleID}
+func (h *Handler) CancelScheduledSync(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	scheduleID := chi.URLParam(r, "scheduleID")
+	if err := h.Registry.CancelScheduledSync(id, scheduleID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrScheduledSyncNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry := NewAuditEntry(r, AuditActionUnschedule, id)
+	entry.Changes = map[string]api.FieldChange{"schedule": {From: scheduleID}}
+	h.recordAudit(entry, nil)
+
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "Scheduled sync cancelled",
+	})
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/conops/conops/internal/credentials"
+	"github.com/conops/conops/internal/redact"
+)
+
+// appRedactor masks an app's env values and deploy key, along with common
+// token patterns, in output shown to users or stored.
+func appRedactor(deployKey []byte, serviceEnvs map[string]string) *redact.Redactor {
+	secrets := redact.New(string(deployKey))
+	for _, rawEnv := range serviceEnvs {
+		for _, line := range strings.Split(rawEnv, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			_, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			// A reference is not secret; what it resolves to is masked
+			// by the executor.
+			if credentials.IsSecretReference(value) {
+				continue
+			}
+			secrets.Add(value)
+		}
+	}
+	return secrets
+}
+
+// Redactor returns a redactor for the app's secrets. Secrets that cannot be
+// read are left out; the token patterns are masked regardless.
+func (r *Registry) Redactor(appID string) *redact.Redactor {
+	deployKey, _ := r.GetDeployKey(appID)
+	defer zeroBytes(deployKey)
+	envVars, _ := r.GetAppEnvs(appID)
+	return appRedactor(deployKey, envVars)
+}
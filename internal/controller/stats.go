@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/go-chi/chi/v5"
+)
+
+// StatsIntervalEnv sets how often resource usage snapshots are taken.
+const StatsIntervalEnv = "CONOPS_STATS_INTERVAL"
+
+// statsTimeout bounds the snapshot of one app.
+const statsTimeout = 30 * time.Second
+
+// RuntimeStatsReader takes resource usage snapshots of a compose project.
+type RuntimeStatsReader interface {
+	ProjectStats(ctx context.Context, projectName string) ([]compose.ServiceStats, error)
+}
+
+// StatsCollector keeps the latest resource usage snapshot of every running
+// app. Taking a snapshot samples the containers for a moment, which is too
+// slow for the detail page's refresh, so the API and the UI read the
+// snapshot collected in the background.
+type StatsCollector struct {
+	Registry *Registry
+	Runtime  RuntimeStatsReader
+	Logger   *slog.Logger
+	Interval time.Duration
+
+	mu        sync.Mutex
+	snapshots map[string]api.AppStats
+}
+
+// LoadStatsIntervalFromEnv returns the snapshot interval, 15s by default.
+// Zero disables the collector.
+func LoadStatsIntervalFromEnv() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(StatsIntervalEnv))
+	if value == "" {
+		return 15 * time.Second, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", StatsIntervalEnv, value)
+	}
+	return interval, nil
+}
+
+// Run collects snapshots right away and then every Interval until ctx is
+// done.
+func (c *StatsCollector) Run(ctx context.Context) {
+	if c.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.Collect(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect replaces the snapshots with fresh ones of every app that has
+// been deployed and is not stopped, and returns how many it took.
+func (c *StatsCollector) Collect(ctx context.Context, now time.Time) int {
+	snapshots := make(map[string]api.AppStats)
+	for _, app := range c.Registry.List() {
+		if app.Status == StatusStopped || app.LastSyncedCommit == "" {
+			continue
+		}
+		statsCtx, cancel := context.WithTimeout(ctx, statsTimeout)
+		stats, err := c.Runtime.ProjectStats(statsCtx, compose.ProjectNameForApp(app.ID))
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0
+			}
+			if c.Logger != nil {
+				c.Logger.Warn("Failed to collect container stats", "app_id", app.ID, "error", err)
+			}
+			continue
+		}
+		snapshot := api.AppStats{AppID: app.ID, CollectedAt: now, Services: make([]api.ServiceStats, 0, len(stats))}
+		for _, s := range stats {
+			snapshot.Services = append(snapshot.Services, api.ServiceStats{
+				Service:          s.Service,
+				Container:        s.Name,
+				Running:          s.Running,
+				CPUPercent:       s.CPUPercent,
+				MemoryBytes:      s.MemoryBytes,
+				MemoryLimitBytes: s.MemoryLimitBytes,
+				MemoryPercent:    s.MemoryPercent,
+				RestartCount:     s.RestartCount,
+			})
+		}
+		snapshots[app.ID] = snapshot
+	}
+
+	c.mu.Lock()
+	c.snapshots = snapshots
+	c.mu.Unlock()
+	return len(snapshots)
+}
+
+// Snapshot returns the latest snapshot of an app, if one was taken.
+func (c *StatsCollector) Snapshot(appID string) (api.AppStats, bool) {
+	if c == nil {
+		return api.AppStats{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot, ok := c.snapshots[appID]
+	return snapshot, ok
+}
+
+// GetAppStats handles GET /api/v1/apps/{id}/stats
+func (h *Handler) GetAppStats(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.Stats == nil || h.Stats.Interval <= 0 {
+		http.Error(w, "resource usage collection is disabled", http.StatusNotImplemented)
+		return
+	}
+
+	snapshot, ok := h.Stats.Snapshot(id)
+	message := ""
+	if !ok {
+		// Not deployed, stopped, or not sampled since it was started.
+		snapshot = api.AppStats{AppID: id, Services: []api.ServiceStats{}}
+		message = "No resource usage collected for this app yet"
+	}
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: message,
+		Data:    snapshot,
+	})
+}
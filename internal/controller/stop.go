@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/notify"
+	"github.com/go-chi/chi/v5"
+)
+
+// StatusStopped marks an app whose stack was taken down while it stays
+// registered. The reconciler and the git watcher leave it down until it is
+// started again.
+const StatusStopped = "stopped"
+
+const (
+	// AuditActionStop records taking an app's stack down.
+	AuditActionStop = "app.stop"
+	// AuditActionStart records starting a stopped app again.
+	AuditActionStart = "app.start"
+)
+
+// ErrNotStopped is returned when starting an app that is not stopped.
+var ErrNotStopped = errors.New("app is not stopped")
+
+// Start hands a stopped app back to the reconciler, which deploys its
+// desired commit. A commit that arrived while the app was stopped still
+// needs its approval when the app requires one.
+func (r *Registry) Start(id string) (*App, error) {
+	app, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if app.Status != StatusStopped {
+		return nil, ErrNotStopped
+	}
+	if err := r.requeue(app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// requeue marks app pending so the reconciler deploys its desired commit,
+// or awaiting_approval when that commit still needs an approval.
+func (r *Registry) requeue(app *App) error {
+	status := "pending"
+	if app.RequireApproval && app.LastSeenCommit != "" && app.LastSeenCommit != app.LastSyncedCommit {
+		status = StatusAwaitingApproval
+	}
+	if err := r.UpdateStatus(app.ID, status, nil); err != nil {
+		return err
+	}
+	app.Status = status
+	if status == StatusAwaitingApproval {
+		r.Notifier().Publish(app, notify.Event{
+			Type:   notify.EventApprovalRequired,
+			Commit: app.LastSeenCommit,
+			Status: status,
+		})
+	}
+	return nil
+}
+
+// StopApp handles POST /api/v1/apps/{id}/down
+func (h *Handler) StopApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if h.rejectOnStandby(w) {
+		return
+	}
+	if h.Syncer.Queue.busy(app.ID) {
+		http.Error(w, "app is syncing; stop it once the sync finishes", http.StatusConflict)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionStop, app.ID)
+	entry.Changes = map[string]api.FieldChange{"status": {From: app.Status, To: StatusStopped}}
+	if h.Cleaner != nil {
+		cleanupCtx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+		defer cancel()
+
+		if _, err := h.Cleaner.Destroy(cleanupCtx, app.ID, app.ComposePath, nil); err != nil {
+			h.recordAudit(entry, err)
+			if h.Logger != nil {
+				h.Logger.Error("Failed to stop app runtime", "id", app.ID, "error", err)
+			}
+			http.Error(w, "failed to stop running containers", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := h.Registry.UpdateStatus(app.ID, StatusStopped, nil); err != nil {
+		h.recordAudit(entry, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.recordAudit(entry, nil)
+	if h.Logger != nil {
+		h.Logger.Info("App stopped", "app_id", app.ID, "actor", entry.Actor)
+	}
+
+	app.Status = StatusStopped
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: "App stopped",
+		Data:    app,
+	})
+}
+
+// StartApp handles POST /api/v1/apps/{id}/up
+func (h *Handler) StartApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	entry := NewAuditEntry(r, AuditActionStart, id)
+	app, err := h.Registry.Start(id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotStopped) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	entry.Changes = map[string]api.FieldChange{"status": {From: StatusStopped, To: app.Status}}
+	h.recordAudit(entry, nil)
+
+	message := "App started; the next reconcile deploys it"
+	if app.Status == StatusAwaitingApproval {
+		message = "App started; its new commit is awaiting approval"
+	}
+	json.NewEncoder(w).Encode(api.APIResponse{
+		Message: message,
+		Data:    app,
+	})
+}
@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/google/uuid"
+)
+
+// ErrSyncInProgress is returned by Sync when another sync of the same app is
+// already running.
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+const (
+	SyncJobRunning   = "running"
+	SyncJobQueued    = "queued"
+	SyncJobSucceeded = "succeeded"
+	SyncJobFailed    = "failed"
+)
+
+// finishedJobTTL is how long the outcome of a finished job can be looked up.
+const finishedJobTTL = time.Hour
+
+// SyncQueue serialises syncs per app. At most one sync runs for an app at a
+// time and at most one more waits behind it; further requests are coalesced
+// into the waiting job instead of piling up. The reconciler's syncer and the
+// API's syncer must share one queue.
+type SyncQueue struct {
+	mu       sync.Mutex
+	apps     map[string]*syncSlot
+	finished map[string]*api.SyncJob
+}
+
+type syncSlot struct {
+	running *api.SyncJob
+	queued  *queuedSync
+}
+
+type queuedSync struct {
+	job  *api.SyncJob
+	run  func() error
+	done []func(error)
+}
+
+// NewSyncQueue creates an empty sync queue.
+func NewSyncQueue() *SyncQueue {
+	return &SyncQueue{apps: make(map[string]*syncSlot), finished: make(map[string]*api.SyncJob)}
+}
+
+// begin claims app's slot for a sync and reports false when one is already
+// running.
+func (q *SyncQueue) begin(appID string, opts SyncOptions) bool {
+	if q == nil {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	slot := q.slot(appID)
+	if slot.running != nil {
+		return false
+	}
+	slot.running = newSyncJob(appID, opts)
+	markStarted(slot.running)
+	return true
+}
+
+// finish records err as the outcome of app's running sync, releases its
+// slot and starts the queued job, if any. The slot is handed over under the
+// lock so a reconcile pass cannot slip in between.
+func (q *SyncQueue) finish(appID string, err error) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	slot, ok := q.apps[appID]
+	if !ok {
+		return
+	}
+	if slot.running != nil {
+		q.recordLocked(slot.running, err)
+	}
+	q.releaseLocked(appID, slot)
+}
+
+// abandon releases app's slot like finish, for a claimed sync that never
+// ran, so it has no outcome to record.
+func (q *SyncQueue) abandon(appID string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if slot, ok := q.apps[appID]; ok {
+		q.releaseLocked(appID, slot)
+	}
+}
+
+func (q *SyncQueue) releaseLocked(appID string, slot *syncSlot) {
+	slot.running = nil
+	if next := slot.queued; next != nil {
+		slot.queued = nil
+		q.startLocked(appID, slot, next)
+		return
+	}
+	delete(q.apps, appID)
+}
+
+// enqueue schedules run once app's current sync finishes, or straight away
+// when the app has gone idle in the meantime, and passes its result to done.
+// coalesced reports whether an already queued job absorbed the request; its
+// done is then called with that job's result.
+func (q *SyncQueue) enqueue(appID string, opts SyncOptions, run func() error, done func(error)) (job api.SyncJob, coalesced bool) {
+	queued := &queuedSync{job: newSyncJob(appID, opts), run: run}
+	if done != nil {
+		queued.done = append(queued.done, done)
+	}
+	if q == nil {
+		markStarted(queued.job)
+		go func() {
+			err := run()
+			for _, done := range queued.done {
+				done(err)
+			}
+		}()
+		return *queued.job, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	slot := q.slot(appID)
+	if slot.running == nil {
+		q.startLocked(appID, slot, queued)
+		return *queued.job, false
+	}
+	if existing := slot.queued; existing != nil {
+		existing.job.Requests++
+		if done != nil {
+			existing.done = append(existing.done, done)
+		}
+		return *existing.job, true
+	}
+	slot.queued = queued
+	return *queued.job, false
+}
+
+// start runs run in the background like enqueue, but only when app is idle:
+// it reports false instead of queueing or coalescing behind another sync.
+func (q *SyncQueue) start(appID string, opts SyncOptions, run func() error, done func(error)) (api.SyncJob, bool) {
+	if q == nil {
+		job, _ := q.enqueue(appID, opts, run, done)
+		return job, true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	slot := q.slot(appID)
+	if slot.running != nil {
+		return api.SyncJob{}, false
+	}
+	queued := &queuedSync{job: newSyncJob(appID, opts), run: run}
+	if done != nil {
+		queued.done = append(queued.done, done)
+	}
+	q.startLocked(appID, slot, queued)
+	return *queued.job, true
+}
+
+// startLocked marks queued as app's running sync and runs it. Once started a
+// job no longer accepts coalesced requests, so its done list is final.
+func (q *SyncQueue) startLocked(appID string, slot *syncSlot, queued *queuedSync) {
+	markStarted(queued.job)
+	slot.running = queued.job
+	go func() {
+		err := queued.run()
+		for _, done := range queued.done {
+			done(err)
+		}
+		q.finish(appID, err)
+	}()
+}
+
+// busy reports whether a sync of app is running.
+func (q *SyncQueue) busy(appID string) bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	slot, ok := q.apps[appID]
+	return ok && slot.running != nil
+}
+
+// Wait blocks until no sync is running or queued, or ctx is done.
+func (q *SyncQueue) Wait(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		q.mu.Lock()
+		idle := len(q.apps) == 0
+		q.mu.Unlock()
+		if idle {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Jobs returns the running and queued jobs, oldest first. An empty appID
+// lists every app.
+func (q *SyncQueue) Jobs(appID string) []api.SyncJob {
+	jobs := []api.SyncJob{}
+	if q == nil {
+		return jobs
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, slot := range q.apps {
+		if appID != "" && id != appID {
+			continue
+		}
+		if slot.running != nil {
+			jobs = append(jobs, *slot.running)
+		}
+		if slot.queued != nil {
+			jobs = append(jobs, *slot.queued.job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].EnqueuedAt.Before(jobs[j].EnqueuedAt)
+	})
+	return jobs
+}
+
+// Job returns app's job with the given ID while it is queued or running,
+// and for finishedJobTTL after it finished.
+func (q *SyncQueue) Job(appID, jobID string) (api.SyncJob, bool) {
+	if q == nil {
+		return api.SyncJob{}, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if slot, ok := q.apps[appID]; ok {
+		if slot.running != nil && slot.running.ID == jobID {
+			return *slot.running, true
+		}
+		if slot.queued != nil && slot.queued.job.ID == jobID {
+			return *slot.queued.job, true
+		}
+	}
+	if job, ok := q.finished[jobID]; ok && job.AppID == appID {
+		return *job, true
+	}
+	return api.SyncJob{}, false
+}
+
+// recordLocked keeps the outcome of job and forgets the outcomes that are
+// older than finishedJobTTL.
+func (q *SyncQueue) recordLocked(job *api.SyncJob, err error) {
+	now := time.Now()
+	for id, finished := range q.finished {
+		if now.Sub(*finished.FinishedAt) > finishedJobTTL {
+			delete(q.finished, id)
+		}
+	}
+	result := *job
+	result.State = SyncJobSucceeded
+	result.FinishedAt = &now
+	if err != nil {
+		result.State = SyncJobFailed
+		result.Error = err.Error()
+	}
+	q.finished[job.ID] = &result
+}
+
+func (q *SyncQueue) slot(appID string) *syncSlot {
+	slot, ok := q.apps[appID]
+	if !ok {
+		slot = &syncSlot{}
+		q.apps[appID] = slot
+	}
+	return slot
+}
+
+func newSyncJob(appID string, opts SyncOptions) *api.SyncJob {
+	return &api.SyncJob{
+		ID:         uuid.NewString(),
+		AppID:      appID,
+		State:      SyncJobQueued,
+		Trigger:    opts.Trigger,
+		Actor:      opts.Actor,
+		Requests:   1,
+		EnqueuedAt: time.Now(),
+	}
+}
+
+func markStarted(job *api.SyncJob) {
+	now := time.Now()
+	job.State = SyncJobRunning
+	job.StartedAt = &now
+}
@@ -0,0 +1,443 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/gates"
+	"github.com/conops/conops/internal/hooks"
+	"github.com/conops/conops/internal/notify"
+	"github.com/conops/conops/internal/provenance"
+	"github.com/conops/conops/internal/redact"
+	"github.com/conops/conops/internal/signing"
+)
+
+const (
+	SyncTriggerReconcile = "reconcile"
+	SyncTriggerManual    = "manual"
+	// SyncTriggerScheduled marks a deploy queued for a later time.
+	SyncTriggerScheduled = "scheduled"
+	// SyncTriggerRollback marks the re-apply of the last synced commit
+	// after a failed rollout.
+	SyncTriggerRollback = "rollback"
+)
+
+// Steps the syncer adds to the applier's transcript.
+const (
+	StepPreSyncHooks = "pre-sync-hooks"
+	StepImageDigests = "image-digests"
+	StepAttestation  = "attestation"
+)
+
+// StatusBlockedUnsigned marks an app whose target commit failed its signing
+// policy. The app stays blocked until a new commit arrives or the allowed
+// keys change.
+const StatusBlockedUnsigned = "blocked_unsigned"
+
+// CommitVerifier checks a commit against an app's allowed signing keys and
+// returns the fingerprint of the key that signed it.
+type CommitVerifier interface {
+	VerifyCommit(app *App, commit string) (string, error)
+}
+
+// SyncOptions controls a single sync run.
+type SyncOptions struct {
+	// Trigger records why the sync ran (reconcile or manual).
+	Trigger string
+	// Actor records who requested a manual sync.
+	Actor string
+	// Commit pins the checkout. An empty value applies the latest commit on the branch.
+	Commit  string
+	Timeout time.Duration
+}
+
+// Syncer applies an app's desired state and records the outcome. The
+// reconciler and manual force syncs share it so both follow one lifecycle.
+type Syncer struct {
+	Registry *Registry
+	Applier  RuntimeApplier
+	Verifier CommitVerifier
+	Queue    *SyncQueue
+	Logger   *slog.Logger
+	// AutoRollback re-applies the last synced commit when a sync fails
+	// after its containers were replaced.
+	AutoRollback bool
+	// Attestor, when set, signs the provenance of every successful sync.
+	Attestor *provenance.Signer
+	// Pruner, when set, is asked to prune images after every successful
+	// sync.
+	Pruner *ImagePruner
+}
+
+// NewSyncer creates a syncer backed by the given runtime applier.
+func NewSyncer(registry *Registry, applier RuntimeApplier, logger *slog.Logger) *Syncer {
+	return &Syncer{
+		Registry: registry,
+		Applier:  applier,
+		Queue:    NewSyncQueue(),
+		Logger:   logger,
+	}
+}
+
+// Sync runs one apply for app and persists the result. The returned error is
+// the apply failure, if any; bookkeeping failures are only logged. It returns
+// ErrSyncInProgress without doing anything when the app is already syncing.
+func (s *Syncer) Sync(app *App, opts SyncOptions) error {
+	if !s.Queue.begin(app.ID, opts) {
+		return ErrSyncInProgress
+	}
+	err := s.sync(app, opts)
+	s.Queue.finish(app.ID, err)
+	return err
+}
+
+// Enqueue runs a sync of app in the background, after the one in progress if
+// there is one, and reports its result to done. If a sync is already waiting
+// the request is coalesced into it. The app is reloaded before the queued
+// sync runs so it applies the settings and commit current at that time.
+func (s *Syncer) Enqueue(app *App, opts SyncOptions, done func(error)) (api.SyncJob, bool) {
+	return s.Queue.enqueue(app.ID, opts, s.backgroundSync(app, opts), done)
+}
+
+// Start runs a sync of app in the background like Enqueue, but returns
+// ErrSyncInProgress instead of queueing it when the app is already syncing.
+// It is for syncs whose options must not be coalesced into another's.
+func (s *Syncer) Start(app *App, opts SyncOptions, done func(error)) (api.SyncJob, error) {
+	job, ok := s.Queue.start(app.ID, opts, s.backgroundSync(app, opts), done)
+	if !ok {
+		return job, ErrSyncInProgress
+	}
+	return job, nil
+}
+
+func (s *Syncer) backgroundSync(app *App, opts SyncOptions) func() error {
+	return func() error {
+		current, err := s.Registry.Get(app.ID)
+		if err == nil {
+			err = s.sync(current, opts)
+		}
+		if err != nil && s.Logger != nil {
+			s.Logger.Error("Sync failed", "app_id", app.ID, "trigger", opts.Trigger, "error", err)
+		}
+		return err
+	}
+}
+
+func (s *Syncer) sync(app *App, opts SyncOptions) error {
+	// Derive from Background so the sync survives reverse-proxy or client
+	// disconnects when triggered over HTTP.
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	if app.Track != "" && opts.Commit == "" {
+		// Tag-tracking apps deploy the tag the watcher resolved, never a
+		// branch head.
+		if app.LastSeenCommit == "" {
+			return fmt.Errorf("no tag matching %q has been fetched yet", app.Track)
+		}
+		opts.Commit = app.LastSeenCommit
+	}
+	opts, err := s.checkSignature(app, opts)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPromotion(ctx, app, opts); err != nil {
+		return err
+	}
+
+	syncStartedAt := time.Now()
+	if err := s.Registry.UpdateStatus(app.ID, "syncing", &syncStartedAt); err != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to mark app syncing", "app_id", app.ID, "error", err)
+	}
+	// Someone asked for this sync, so a failure starts the retry backoff
+	// over instead of extending it.
+	if opts.Trigger == SyncTriggerManual || opts.Trigger == SyncTriggerScheduled {
+		if err := s.Registry.ResetSyncFailures(app.ID); err != nil && s.Logger != nil {
+			s.Logger.Warn("Failed to reset sync failures", "app_id", app.ID, "error", err)
+		}
+	}
+	record := newSyncRecord(app, opts, syncStartedAt)
+
+	deployKey, err := s.Registry.GetDeployKey(app.ID)
+	if err != nil {
+		_ = s.Registry.UpdateStatus(app.ID, "error", nil)
+		err = fmt.Errorf("failed to load app credentials: %w", err)
+		s.recordSync(record, nil, err)
+		return err
+	}
+	defer zeroBytes(deployKey)
+
+	envVars, err := s.Registry.GetAppEnvs(app.ID)
+	if err != nil {
+		_ = s.Registry.UpdateStatus(app.ID, "error", nil)
+		err = fmt.Errorf("failed to load app envs: %w", err)
+		s.recordSync(record, nil, err)
+		return err
+	}
+
+	hookRunner := s.Registry.Hooks()
+	if err := hookRunner.Check(ctx, hookPayload(hooks.EventPreSync, app, opts)); err != nil {
+		err = appRedactor(deployKey, envVars).Error(err)
+		transcript := compose.NewTranscript(ctx)
+		transcript.Step(StepPreSyncHooks)
+		transcript.Section("Pre-sync hooks")
+		transcript.Line(err.Error())
+		s.recordFailure(app, transcript.String(), err)
+		s.recordSync(record, transcript, err)
+		s.publish(app, opts, syncStartedAt, err)
+		return err
+	}
+
+	progress := newSyncProgressReporter(s.Registry, s.Logger, app.ID, syncProgressFlushInterval)
+	transcript, err := s.apply(ctx, app, opts, record, deployKey, envVars, progress.Update)
+	progress.Flush()
+	s.recordSync(record, transcript, err)
+	output := transcript.String()
+
+	post := hookPayload(hooks.EventPostSync, app, opts)
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Error("Sync failed", "app_id", app.ID, "commit", opts.Commit, "trigger", opts.Trigger, "output", truncateOutput(output))
+		}
+		output, err = s.rollback(app, opts, record, deployKey, envVars, output, err)
+		s.recordFailure(app, output, err)
+		post.Status = "error"
+		post.Error = err.Error()
+		hookRunner.Dispatch(post)
+		s.publish(app, opts, syncStartedAt, err)
+		return err
+	}
+
+	// A sync pinned to another commit than the desired one, e.g. a
+	// rollback, leaves that commit running.
+	syncedCommit, syncedCommitMessage := app.LastSeenCommit, app.LastSeenCommitMessage
+	if opts.Commit != "" && opts.Commit != app.LastSeenCommit {
+		syncedCommit, syncedCommitMessage = opts.Commit, ""
+	}
+	now := time.Now()
+	if err := s.Registry.UpdateSyncResult(
+		app.ID,
+		"synced",
+		now,
+		syncedCommit,
+		syncedCommitMessage,
+		output,
+		"",
+	); err != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to update app status", "app_id", app.ID, "error", err)
+	}
+	post.Status = "synced"
+	hookRunner.Dispatch(post)
+	s.publish(app, opts, syncStartedAt, nil)
+	s.Pruner.Request()
+	return nil
+}
+
+// apply runs the applier and the health gate for app and records the
+// image digests a successful apply deployed in record. The app's secrets
+// are masked in the transcript, the progress reports and the error.
+func (s *Syncer) apply(ctx context.Context, app *App, opts SyncOptions, record *api.SyncRecord, deployKey []byte, envVars map[string]string, onProgress func(string)) (*compose.Transcript, error) {
+	secrets := appRedactor(deployKey, envVars)
+	ctx = redact.WithRedactor(ctx, secrets)
+	transcript, err := s.Applier.Apply(ctx, compose.ApplyOptions{
+		AppID:        app.ID,
+		EnvVars:      envVars,
+		RepoURL:      app.RepoURL,
+		Branch:       applyBranch(app),
+		ComposePath:  app.ComposePath,
+		Profiles:     app.Profiles,
+		PreDeploy:    app.PreDeploy,
+		PostDeploy:   app.PostDeploy,
+		EnvFile:      app.EnvFile,
+		CommitHash:   opts.Commit,
+		DeployKey:    deployKey,
+		PinnedImages: s.pinnedImages(app, opts),
+		OnProgress: func(current string) {
+			if onProgress != nil {
+				onProgress(secrets.String(current))
+			}
+		},
+	})
+	if err != nil {
+		return transcript, secrets.Error(err)
+	}
+	if err := s.checkHealth(ctx, app, opts, transcript); err != nil {
+		// The new containers are already running.
+		return transcript, secrets.Error(&compose.RolloutError{Err: err})
+	}
+	s.recordImages(ctx, app, record, transcript)
+	s.attest(ctx, app, opts, record, transcript)
+	return transcript, nil
+}
+
+// publish notifies subscribers about the outcome of a sync.
+func (s *Syncer) publish(app *App, opts SyncOptions, startedAt time.Time, syncErr error) {
+	event := notify.Event{
+		Type:            notify.EventSyncSucceeded,
+		Trigger:         opts.Trigger,
+		Commit:          targetCommit(app, opts),
+		Status:          "synced",
+		PreviousFailed:  app.Status == "error" || app.LastSyncError != "",
+		DurationSeconds: time.Since(startedAt).Seconds(),
+	}
+	if syncErr != nil {
+		event.Type = notify.EventSyncFailed
+		event.Status = "error"
+		event.Error = syncErr.Error()
+		var unverified *signing.UnverifiedError
+		if errors.As(syncErr, &unverified) {
+			event.Status = StatusBlockedUnsigned
+		}
+	}
+	s.Registry.Notifier().Publish(app, event)
+}
+
+// checkSignature enforces the app's signing policy before anything changes.
+// The sync is pinned to the verified commit so a manual sync cannot deploy a
+// newer commit that has not been checked.
+func (s *Syncer) checkSignature(app *App, opts SyncOptions) (SyncOptions, error) {
+	if len(app.SigningKeys) == 0 {
+		return opts, nil
+	}
+
+	commit := targetCommit(app, opts)
+	var err error
+	switch {
+	case commit == "":
+		err = &signing.UnverifiedError{Reason: "no commit has been fetched yet"}
+	case s.Verifier == nil:
+		err = &signing.UnverifiedError{Commit: commit, Reason: "commit verification is not configured"}
+	default:
+		var signer string
+		if signer, err = s.Verifier.VerifyCommit(app, commit); err == nil {
+			if s.Logger != nil {
+				s.Logger.Info("Commit signature verified", "app_id", app.ID, "commit", commit, "signer", signer)
+			}
+			opts.Commit = commit
+			return opts, nil
+		}
+	}
+
+	if err := s.Registry.UpdateSyncResult(
+		app.ID,
+		StatusBlockedUnsigned,
+		time.Now(),
+		app.LastSyncedCommit,
+		app.LastSyncedCommitMessage,
+		"=== Signature check ===\n"+err.Error(),
+		err.Error(),
+	); err != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to record blocked rollout", "app_id", app.ID, "error", err)
+	}
+	s.publish(app, opts, time.Now(), err)
+	return opts, err
+}
+
+// checkPromotion evaluates the app's promote gate before anything changes. A
+// held rollout leaves the app pending so the reconciler re-evaluates it.
+func (s *Syncer) checkPromotion(ctx context.Context, app *App, opts SyncOptions) error {
+	if !gates.Defines(app.GateScript, gates.Promote) {
+		return nil
+	}
+	transcript, err := gates.Evaluate(ctx, app.GateScript, gates.Promote, gateInput(app, opts))
+	if err == nil {
+		return nil
+	}
+	if err := s.Registry.UpdateSyncResult(
+		app.ID,
+		"pending",
+		time.Now(),
+		app.LastSyncedCommit,
+		app.LastSyncedCommitMessage,
+		"=== Promote gate ===\n"+transcript+err.Error(),
+		err.Error(),
+	); err != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to record held rollout", "app_id", app.ID, "error", err)
+	}
+	return err
+}
+
+// checkHealth evaluates the app's health gate after a successful apply and
+// appends what it printed to the sync's transcript.
+func (s *Syncer) checkHealth(ctx context.Context, app *App, opts SyncOptions, transcript *compose.Transcript) error {
+	if !gates.Defines(app.GateScript, gates.Health) {
+		return nil
+	}
+	gateOutput, err := gates.Evaluate(ctx, app.GateScript, gates.Health, gateInput(app, opts))
+	transcript.Step(compose.StepHealthGate)
+	transcript.Section("Health gate")
+	transcript.WriteString(gateOutput)
+	if err != nil {
+		transcript.WriteString(err.Error())
+	}
+	return err
+}
+
+func (s *Syncer) recordFailure(app *App, output string, syncErr error) {
+	if err := s.Registry.UpdateSyncResult(
+		app.ID,
+		"error",
+		time.Now(),
+		app.LastSyncedCommit,
+		app.LastSyncedCommitMessage,
+		output,
+		syncErr.Error(),
+	); err != nil && s.Logger != nil {
+		s.Logger.Warn("Failed to record sync failure", "app_id", app.ID, "error", err)
+	}
+}
+
+func hookPayload(event string, app *App, opts SyncOptions) hooks.Payload {
+	return hooks.Payload{
+		Event:     event,
+		Timestamp: time.Now(),
+		App:       hookApp(app),
+		Trigger:   opts.Trigger,
+		Commit:    targetCommit(app, opts),
+	}
+}
+
+func gateInput(app *App, opts SyncOptions) gates.Input {
+	return gates.Input{
+		AppID:          app.ID,
+		AppName:        app.Name,
+		RepoURL:        app.RepoURL,
+		Branch:         app.Branch,
+		ComposePath:    app.ComposePath,
+		Commit:         targetCommit(app, opts),
+		PreviousCommit: app.LastSyncedCommit,
+		Trigger:        opts.Trigger,
+	}
+}
+
+// applyBranch is the branch handed to the applier. Tag-tracking apps pass
+// none, so the pinned commit is checked out from the remote default branch.
+func applyBranch(app *App) string {
+	if app.Track != "" {
+		return ""
+	}
+	return app.Branch
+}
+
+// targetCommit is the commit a sync is expected to deploy.
+func targetCommit(app *App, opts SyncOptions) string {
+	if opts.Commit != "" {
+		return opts.Commit
+	}
+	return app.LastSeenCommit
+}
+
+// hookApp strips bulky transcript data before an app is handed to hooks.
+func hookApp(app *App) *App {
+	if app == nil {
+		return nil
+	}
+	clone := *app
+	clone.LastSyncOutput = ""
+	return &clone
+}
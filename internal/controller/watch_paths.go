@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// normalizeWatchPaths trims watch paths, makes them repo-relative and rejects
+// patterns that could never match.
+func normalizeWatchPaths(paths []string) ([]string, error) {
+	var normalized []string
+	for _, value := range paths {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		cleaned := strings.TrimPrefix(path.Clean("/"+value), "/")
+		if cleaned == "" {
+			cleaned = "."
+		}
+		for _, segment := range strings.Split(value, "/") {
+			if segment == ".." {
+				return nil, fmt.Errorf("invalid watch path %q: must stay inside the repository", value)
+			}
+		}
+		if _, err := path.Match(cleaned, ""); err != nil {
+			return nil, fmt.Errorf("invalid watch path %q: %w", value, err)
+		}
+		normalized = append(normalized, cleaned)
+	}
+	return normalized, nil
+}
+
+// effectiveWatchPaths returns the app's watch paths, defaulting to the
+// directory that holds its compose file plus its env file.
+func effectiveWatchPaths(app *App) []string {
+	if len(app.WatchPaths) > 0 {
+		return app.WatchPaths
+	}
+	dir := strings.TrimPrefix(path.Dir(path.Clean("/"+app.ComposePath)), "/")
+	if dir == "" {
+		dir = "."
+	}
+	paths := []string{dir}
+	if app.EnvFile != "" && !watchPathMatches(paths, app.EnvFile) {
+		paths = append(paths, app.EnvFile)
+	}
+	return paths
+}
+
+// changedWatchedPath reports the first file changed between from and to that
+// falls under one of patterns, or "" when none does.
+func changedWatchedPath(from, to *object.Commit, patterns []string) (string, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to read tree of %s: %w", from.Hash, err)
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to read tree of %s: %w", to.Hash, err)
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s..%s: %w", from.Hash, to.Hash, err)
+	}
+	for _, change := range changes {
+		// Renames touch both names; either side counts.
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && watchPathMatches(patterns, name) {
+				return name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// watchPathMatches reports whether file is covered by any pattern. A pattern
+// without wildcards covers that file or everything below that directory;
+// otherwise "*", "?" and "[...]" match within a path segment and "**" matches
+// any number of segments.
+func watchPathMatches(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if pattern == "." || pattern == "**" {
+			return true
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			if file == pattern || strings.HasPrefix(file, pattern+"/") {
+				return true
+			}
+			continue
+		}
+		if globMatch(strings.Split(pattern, "/"), strings.Split(file, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches path segments, letting a trailing pattern match a whole
+// directory so "services/*" covers files nested below each service.
+func globMatch(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if globMatch(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// KMS settings. Setting KMSKeyEnv wraps the key file's data key with a
+// cloud KMS key, so the file alone does not decrypt anything.
+const (
+	// KMSKeyEnv is aws-kms://<key id or arn> or
+	// gcp-kms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>.
+	KMSKeyEnv = "CONOPS_KMS_KEY"
+	// KMSEndpointEnv overrides the KMS API endpoint, e.g. for a VPC
+	// endpoint.
+	KMSEndpointEnv = "CONOPS_KMS_ENDPOINT"
+)
+
+// wrappedKeyPrefix marks a key file holding a data key wrapped by KMS.
+const wrappedKeyPrefix = "kms:"
+
+// kmsTimeout bounds each call to KMS and its credential sources.
+const kmsTimeout = 10 * time.Second
+
+// keyWrapper encrypts the data key with a key that never leaves KMS.
+type keyWrapper interface {
+	wrap(key []byte) ([]byte, error)
+	unwrap(wrapped []byte) ([]byte, error)
+	name() string
+}
+
+// kmsFromEnv returns the wrapper KMSKeyEnv selects, or nil when it is unset.
+func kmsFromEnv() (keyWrapper, error) {
+	uri := strings.TrimSpace(os.Getenv(KMSKeyEnv))
+	client := &http.Client{Timeout: kmsTimeout}
+	endpoint := strings.TrimRight(strings.TrimSpace(os.Getenv(KMSEndpointEnv)), "/")
+	switch {
+	case uri == "":
+		return nil, nil
+	case strings.HasPrefix(uri, "aws-kms://"):
+		return newAWSKMS(client, strings.TrimPrefix(uri, "aws-kms://"), endpoint)
+	case strings.HasPrefix(uri, "gcp-kms://"):
+		return newGCPKMS(client, strings.TrimPrefix(uri, "gcp-kms://"), endpoint)
+	default:
+		return nil, fmt.Errorf("invalid %s: must start with aws-kms:// or gcp-kms://", KMSKeyEnv)
+	}
+}
+
+// encodeKeyFile returns the key file content for key, wrapped when wrapper
+// is set.
+func encodeKeyFile(key []byte, wrapper keyWrapper) (string, error) {
+	if wrapper == nil {
+		return base64.StdEncoding.EncodeToString(key) + "\n", nil
+	}
+	wrapped, err := wrapper.wrap(key)
+	if err != nil {
+		return "", fmt.Errorf("failed wrapping encryption key with %s: %w", wrapper.name(), err)
+	}
+	return wrappedKeyPrefix + base64.StdEncoding.EncodeToString(wrapped) + "\n", nil
+}
+
+// decodeKeyFile parses a key file's content, unwrapping it with wrapper if
+// KMS wrapped it. It reports whether it was wrapped.
+func decodeKeyFile(content, path string, wrapper keyWrapper) ([]byte, bool, error) {
+	content = strings.TrimSpace(content)
+	encoded, wrapped := strings.CutPrefix(content, wrappedKeyPrefix)
+	if !wrapped {
+		key, err := parseKey(content, "key file "+path)
+		return key, false, err
+	}
+	if wrapper == nil {
+		return nil, true, fmt.Errorf("key file %s is wrapped by KMS: set %s", path, KMSKeyEnv)
+	}
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	key, err := wrapper.unwrap(blob)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed unwrapping encryption key with %s: %w", wrapper.name(), err)
+	}
+	if len(key) != 32 {
+		zeroBytes(key)
+		return nil, true, fmt.Errorf("key file %s does not wrap a 32-byte key", path)
+	}
+	return key, true, nil
+}
@@ -0,0 +1,279 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsKMS wraps keys with AWS KMS, signing its requests with SigV4.
+// Credentials come from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, the
+// ECS task role or the EC2 instance role, in that order.
+type awsKMS struct {
+	client   *http.Client
+	keyID    string
+	region   string
+	endpoint string
+}
+
+func newAWSKMS(client *http.Client, keyID, endpoint string) (*awsKMS, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("invalid %s: missing key id", KMSKeyEnv)
+	}
+	region := envOr("AWS_REGION", "AWS_DEFAULT_REGION")
+	// arn:aws:kms:<region>:<account>:key/<id>
+	if parts := strings.Split(keyID, ":"); len(parts) >= 6 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return nil, fmt.Errorf("%s needs a key ARN or AWS_REGION", KMSKeyEnv)
+	}
+	if endpoint == "" {
+		endpoint = "https://kms." + region + ".amazonaws.com"
+	}
+	return &awsKMS{client: client, keyID: keyID, region: region, endpoint: endpoint}, nil
+}
+
+func (k *awsKMS) name() string {
+	return "aws-kms://" + k.keyID
+}
+
+func (k *awsKMS) wrap(key []byte) ([]byte, error) {
+	var resp struct {
+		CiphertextBlob []byte
+	}
+	if err := k.call("Encrypt", map[string]interface{}{"KeyId": k.keyID, "Plaintext": key}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+func (k *awsKMS) unwrap(wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte
+	}
+	if err := k.call("Decrypt", map[string]interface{}{"KeyId": k.keyID, "CiphertextBlob": wrapped}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// call invokes a KMS action. Byte slices travel base64-encoded, which is
+// how encoding/json writes and reads them.
+func (k *awsKMS) call(action string, body, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+	return awsCall(ctx, k.client, k.endpoint, k.region, "kms", "TrentService."+action, body, out)
+}
+
+// awsCall invokes target, e.g. "TrentService.Encrypt", on an AWS JSON API.
+func awsCall(ctx context.Context, client *http.Client, endpoint, region, service, target string, body, out interface{}) error {
+	_, action, _ := strings.Cut(target, ".")
+	creds, err := awsCredentialsFromEnv(ctx, client)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	signAWSRequest(req, payload, creds, region, service, time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &awsErr)
+		if awsErr.Type == "" {
+			return fmt.Errorf("%s %s answered %d", service, action, resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s answered %d: %s %s", service, action, resp.StatusCode, awsErr.Type, awsErr.Message)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+	return nil
+}
+
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"Token"`
+}
+
+// Where the ECS agent and EC2 instance metadata serve role credentials.
+const (
+	awsECSCredentialsHost = "http://169.254.170.2"
+	awsIMDSHost           = "http://169.254.169.254"
+)
+
+func awsCredentialsFromEnv(ctx context.Context, client *http.Client) (*awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		var creds awsCredentials
+		if err := getJSON(ctx, client, awsECSCredentialsHost+uri, nil, &creds); err != nil {
+			return nil, fmt.Errorf("failed reading ECS task credentials: %w", err)
+		}
+		return &creds, nil
+	}
+
+	// EC2 instance metadata, IMDSv2.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, awsIMDSHost+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("no AWS credentials: set AWS_ACCESS_KEY_ID or run with an instance role (%v)", err)
+	}
+	token, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("no AWS credentials: instance metadata answered %d", resp.StatusCode)
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": string(token)}
+	var role string
+	if err := getText(ctx, client, awsIMDSHost+"/latest/meta-data/iam/security-credentials/", headers, &role); err != nil {
+		return nil, fmt.Errorf("no AWS credentials: the instance has no role: %w", err)
+	}
+	role, _, _ = strings.Cut(strings.TrimSpace(role), "\n")
+	var creds awsCredentials
+	if err := getJSON(ctx, client, awsIMDSHost+"/latest/meta-data/iam/security-credentials/"+role, headers, &creds); err != nil {
+		return nil, fmt.Errorf("failed reading instance role credentials: %w", err)
+	}
+	return &creds, nil
+}
+
+// signAWSRequest adds a SigV4 Authorization header to req, whose body is
+// payload.
+func signAWSRequest(req *http.Request, payload []byte, creds *awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func getText(ctx context.Context, client *http.Client, url string, headers map[string]string, out *string) error {
+	data, err := get(ctx, client, url, headers)
+	if err != nil {
+		return err
+	}
+	*out = string(data)
+	return nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, out interface{}) error {
+	data, err := get(ctx, client, url, headers)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func get(ctx context.Context, client *http.Client, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %d", url, resp.StatusCode)
+	}
+	return data, nil
+}
@@ -0,0 +1,288 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// awsTestCredentials are the example credentials of AWS's SigV4 test suite.
+var awsTestCredentials = &awsCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSignAWSRequestKnownAnswers(t *testing.T) {
+	signedAt := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		header        map[string]string
+		service       string
+		authorization string
+	}{
+		{
+			// get-vanilla from the SigV4 test suite.
+			name:          "get vanilla",
+			method:        http.MethodGet,
+			url:           "https://example.amazonaws.com/",
+			service:       "service",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			// post-vanilla from the SigV4 test suite.
+			name:          "post vanilla",
+			method:        http.MethodPost,
+			url:           "https://example.amazonaws.com/",
+			service:       "service",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			// The IAM ListUsers example of the SigV4 documentation.
+			name:          "query and content type",
+			method:        http.MethodGet,
+			url:           "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			header:        map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			service:       "iam",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			signAWSRequest(req, nil, awsTestCredentials, "us-east-1", tt.service, signedAt)
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q, want 20150830T123600Z", got)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization =\n%s\nwant\n%s", got, tt.authorization)
+			}
+		})
+	}
+}
+
+func TestSignAWSRequestSignsSessionToken(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://kms.us-east-1.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := *awsTestCredentials
+	creds.SessionToken = "session-token"
+	signAWSRequest(req, []byte("{}"), &creds, "us-east-1", "kms", time.Now())
+
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session-token" {
+		t.Errorf("X-Amz-Security-Token = %q, want session-token", got)
+	}
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization %q does not sign the session token", got)
+	}
+}
+
+// fakeAWSKMS serves Encrypt and Decrypt, "encrypting" by prefixing the
+// plaintext.
+func fakeAWSKMS(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/x-amz-json-1.1" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := r.Header.Get("Authorization"); !strings.HasPrefix(got, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(got, "/us-east-1/kms/aws4_request") {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req struct {
+			KeyId          string
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.KeyId != "alias/conops" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"NotFoundException","message":"Alias is not found."}`))
+			return
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": append([]byte("wrapped:"), req.Plaintext...)})
+		case "TrentService.Decrypt":
+			plaintext, ok := bytes.CutPrefix(req.CiphertextBlob, []byte("wrapped:"))
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": plaintext})
+		default:
+			t.Errorf("unexpected X-Amz-Target %q", r.Header.Get("X-Amz-Target"))
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAWSKMSWrapRoundTrip(t *testing.T) {
+	server := fakeAWSKMS(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", awsTestCredentials.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", awsTestCredentials.SecretAccessKey)
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	kms, err := newAWSKMS(server.Client(), "alias/conops", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := bytes.Repeat([]byte{7}, 32)
+	wrapped, err := kms.wrap(key)
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+	unwrapped, err := kms.unwrap(wrapped)
+	if err != nil {
+		t.Fatalf("unwrap: %v", err)
+	}
+	if !bytes.Equal(unwrapped, key) {
+		t.Errorf("unwrap = %x, want %x", unwrapped, key)
+	}
+
+	if _, err := kms.unwrap([]byte("garbage")); err == nil || !strings.Contains(err.Error(), "400: InvalidCiphertextException") {
+		t.Errorf("unwrap of garbage: err = %v, want the KMS error", err)
+	}
+	missing, err := newAWSKMS(server.Client(), "alias/missing", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := missing.wrap(key); err == nil || !strings.Contains(err.Error(), "NotFoundException Alias is not found.") {
+		t.Errorf("wrap with a missing key: err = %v, want the KMS error", err)
+	}
+}
+
+func TestNewAWSKMSRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	kms, err := newAWSKMS(http.DefaultClient, "arn:aws:kms:eu-west-1:111122223333:key/1234abcd", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kms.region != "eu-west-1" || kms.endpoint != "https://kms.eu-west-1.amazonaws.com" {
+		t.Errorf("region %q endpoint %q, want the key ARN's region", kms.region, kms.endpoint)
+	}
+	if _, err := newAWSKMS(http.DefaultClient, "alias/conops", ""); err == nil {
+		t.Error("a key alias without AWS_REGION was accepted")
+	}
+}
+
+// redirectTransport sends every request to server, so the fixed metadata
+// addresses can be served by a test server.
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAWSCredentialsFromInstanceMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, "imds-token")
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			io.WriteString(w, "conops-role\n")
+		case "/latest/meta-data/iam/security-credentials/conops-role":
+			io.WriteString(w, `{"Code":"Success","AccessKeyId":"ASIAEXAMPLE","SecretAccessKey":"secret","Token":"session"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+
+	client := &http.Client{Transport: redirectTransport{server}}
+	creds, err := awsCredentialsFromEnv(t.Context(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := awsCredentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}
+	if *creds != want {
+		t.Errorf("credentials = %+v, want %+v", *creds, want)
+	}
+}
+
+func TestAWSCredentialsFromECS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/credentials/task" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, `{"AccessKeyId":"ASIATASK","SecretAccessKey":"task-secret","Token":"task-session"}`)
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "/v2/credentials/task")
+
+	creds, err := awsCredentialsFromEnv(t.Context(), &http.Client{Transport: redirectTransport{server}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := awsCredentials{AccessKeyID: "ASIATASK", SecretAccessKey: "task-secret", SessionToken: "task-session"}
+	if *creds != want {
+		t.Errorf("credentials = %+v, want %+v", *creds, want)
+	}
+}
+
+func TestAWSCredentialsPreferEnvironment(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "/v2/credentials/task")
+
+	// No request may be made: the client fails every one.
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return nil, io.EOF
+	})}
+	creds, err := awsCredentialsFromEnv(t.Context(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || creds.SecretAccessKey != "secret" {
+		t.Errorf("credentials = %+v, want the environment's", *creds)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,212 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// gcpKMSScope is the OAuth scope KMS calls need.
+const gcpKMSScope = "https://www.googleapis.com/auth/cloudkms"
+
+// gcpKMS wraps keys with Google Cloud KMS. It authenticates with the
+// service account key GOOGLE_APPLICATION_CREDENTIALS names or else the
+// metadata server's default service account.
+type gcpKMS struct {
+	client   *http.Client
+	key      string
+	endpoint string
+}
+
+func newGCPKMS(client *http.Client, key, endpoint string) (*gcpKMS, error) {
+	if !strings.HasPrefix(key, "projects/") || !strings.Contains(key, "/cryptoKeys/") {
+		return nil, fmt.Errorf("invalid %s: want gcp-kms://projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>", KMSKeyEnv)
+	}
+	if endpoint == "" {
+		endpoint = "https://cloudkms.googleapis.com"
+	}
+	return &gcpKMS{client: client, key: key, endpoint: endpoint}, nil
+}
+
+func (k *gcpKMS) name() string {
+	return "gcp-kms://" + k.key
+}
+
+func (k *gcpKMS) wrap(key []byte) ([]byte, error) {
+	var resp struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := k.call("encrypt", map[string][]byte{"plaintext": key}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Ciphertext, nil
+}
+
+func (k *gcpKMS) unwrap(wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := k.call("decrypt", map[string][]byte{"ciphertext": wrapped}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+func (k *gcpKMS) call(method string, body, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+	token, err := gcpAccessToken(ctx, k.client)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint+"/v1/"+k.key+":"+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var gcpErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &gcpErr) == nil && gcpErr.Error.Message != "" {
+			return fmt.Errorf("kms %s answered %d: %s", method, resp.StatusCode, gcpErr.Error.Message)
+		}
+		return fmt.Errorf("kms %s answered %d", method, resp.StatusCode)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid kms response: %w", err)
+	}
+	return nil
+}
+
+// gcpMetadataHost is the metadata server; GCE_METADATA_HOST overrides it as
+// in Google's own client libraries.
+const gcpMetadataHost = "metadata.google.internal"
+
+func gcpAccessToken(ctx context.Context, client *http.Client) (string, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		if err := gcpServiceAccountToken(ctx, client, path, &token); err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	}
+
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = gcpMetadataHost
+	}
+	tokenURL := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token?scopes=" + url.QueryEscape(gcpKMSScope)
+	if err := getJSON(ctx, client, tokenURL, map[string]string{"Metadata-Flavor": "Google"}, &token); err != nil {
+		return "", fmt.Errorf("no Google credentials: set GOOGLE_APPLICATION_CREDENTIALS or run with a service account (%v)", err)
+	}
+	return token.AccessToken, nil
+}
+
+// gcpServiceAccountToken exchanges a JWT signed with the service account's
+// key for an access token.
+func gcpServiceAccountToken(ctx context.Context, client *http.Client, path string, out interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed reading GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	var account struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return fmt.Errorf("invalid GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	if account.Type != "service_account" {
+		return fmt.Errorf("invalid GOOGLE_APPLICATION_CREDENTIALS: want a service account key, got %q", account.Type)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return fmt.Errorf("invalid GOOGLE_APPLICATION_CREDENTIALS: no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("invalid GOOGLE_APPLICATION_CREDENTIALS: private key is not RSA")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": gcpKMSScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return fmt.Errorf("failed signing token request: %w", err)
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed requesting Google access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("google token endpoint answered %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,196 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gcpTestKey = "projects/p/locations/global/keyRings/conops/cryptoKeys/master"
+
+// fakeGCPKMS serves encrypt and decrypt of gcpTestKey for callers with
+// token, "encrypting" by prefixing the plaintext.
+func fakeGCPKMS(t *testing.T, mux *http.ServeMux, token string) {
+	t.Helper()
+	handle := func(method string, convert func(map[string][]byte) (map[string][]byte, bool)) {
+		mux.HandleFunc("POST /v1/"+gcpTestKey+":"+method, func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "Bearer "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":{"code":401,"message":"Request had invalid authentication credentials."}}`))
+				return
+			}
+			var body map[string][]byte
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode %s request: %v", method, err)
+			}
+			out, ok := convert(body)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"code":400,"message":"Decryption failed."}}`))
+				return
+			}
+			json.NewEncoder(w).Encode(out)
+		})
+	}
+	handle("encrypt", func(body map[string][]byte) (map[string][]byte, bool) {
+		return map[string][]byte{"ciphertext": append([]byte("wrapped:"), body["plaintext"]...)}, true
+	})
+	handle("decrypt", func(body map[string][]byte) (map[string][]byte, bool) {
+		plaintext, ok := bytes.CutPrefix(body["ciphertext"], []byte("wrapped:"))
+		return map[string][]byte{"plaintext": plaintext}, ok
+	})
+}
+
+func TestGCPKMSWrapRoundTripWithServiceAccount(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	tokenURI := server.URL + "/token"
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse token request: %v", err)
+			return
+		}
+		if got := r.PostForm.Get("grant_type"); got != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", got)
+		}
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("assertion has %d parts, want 3", len(parts))
+			return
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Errorf("decode assertion: %v", err)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("assertion signature: %v", err)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Errorf("decode assertion: %v", err)
+			return
+		}
+		var claims struct {
+			Iss   string `json:"iss"`
+			Scope string `json:"scope"`
+			Aud   string `json:"aud"`
+			Iat   int64  `json:"iat"`
+			Exp   int64  `json:"exp"`
+		}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Errorf("decode claims: %v", err)
+			return
+		}
+		if claims.Iss != "conops@p.iam.gserviceaccount.com" || claims.Scope != gcpKMSScope || claims.Aud != tokenURI || claims.Exp <= claims.Iat {
+			t.Errorf("claims = %+v", claims)
+		}
+		io.WriteString(w, `{"access_token":"sa-token","token_type":"Bearer","expires_in":3600}`)
+	})
+	fakeGCPKMS(t, mux, "sa-token")
+
+	account, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "conops@p.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	accountFile := filepath.Join(t.TempDir(), "account.json")
+	if err := os.WriteFile(accountFile, account, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", accountFile)
+
+	kms, err := newGCPKMS(server.Client(), gcpTestKey, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := bytes.Repeat([]byte{9}, 32)
+	wrapped, err := kms.wrap(key)
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+	unwrapped, err := kms.unwrap(wrapped)
+	if err != nil {
+		t.Fatalf("unwrap: %v", err)
+	}
+	if !bytes.Equal(unwrapped, key) {
+		t.Errorf("unwrap = %x, want %x", unwrapped, key)
+	}
+	if _, err := kms.unwrap([]byte("garbage")); err == nil || !strings.Contains(err.Error(), "400: Decryption failed.") {
+		t.Errorf("unwrap of garbage: err = %v, want the KMS error", err)
+	}
+}
+
+func TestGCPKMSWrapWithMetadataServer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /computeMetadata/v1/instance/service-accounts/default/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if got := r.URL.Query().Get("scopes"); got != gcpKMSScope {
+			t.Errorf("scopes = %q, want %q", got, gcpKMSScope)
+		}
+		io.WriteString(w, `{"access_token":"metadata-token","expires_in":3599,"token_type":"Bearer"}`)
+	})
+	fakeGCPKMS(t, mux, "metadata-token")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	kms, err := newGCPKMS(server.Client(), gcpTestKey, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := kms.wrap([]byte("data key"))
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+	if string(wrapped) != "wrapped:data key" {
+		t.Errorf("wrap = %q", wrapped)
+	}
+}
+
+func TestGCPServiceAccountTokenRejectsOtherCredentials(t *testing.T) {
+	accountFile := filepath.Join(t.TempDir(), "account.json")
+	if err := os.WriteFile(accountFile, []byte(`{"type":"authorized_user"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var token struct{}
+	err := gcpServiceAccountToken(t.Context(), http.DefaultClient, accountFile, &token)
+	if err == nil || !strings.Contains(err.Error(), `got "authorized_user"`) {
+		t.Errorf("err = %v, want a refused credential type", err)
+	}
+}
+
+func TestNewGCPKMSValidatesKey(t *testing.T) {
+	if _, err := newGCPKMS(http.DefaultClient, "projects/p/keyRings/r", ""); err == nil {
+		t.Error("a key name without cryptoKeys was accepted")
+	}
+}
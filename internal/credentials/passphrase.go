@@ -0,0 +1,87 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// MinPassphraseLength is the shortest passphrase SealWithPassphrase accepts.
+const MinPassphraseLength = 12
+
+// scrypt cost parameters, the interactive-login recommendation.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// PassphraseBox is data sealed with a key derived from a passphrase, for
+// moving credentials between controllers that do not share a key.
+type PassphraseBox struct {
+	KDF        string `json:"kdf"` // "scrypt"
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// SealWithPassphrase encrypts plaintext with AES-GCM under a scrypt key
+// derived from passphrase and a random salt.
+func SealWithPassphrase(passphrase string, plaintext []byte) (*PassphraseBox, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed generating salt: %w", err)
+	}
+	aead, err := passphraseAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed generating nonce: %w", err)
+	}
+	return &PassphraseBox{
+		KDF:        "scrypt",
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	}, nil
+}
+
+// OpenWithPassphrase decrypts a box sealed by SealWithPassphrase.
+func OpenWithPassphrase(passphrase string, box *PassphraseBox) ([]byte, error) {
+	if box == nil {
+		return nil, nil
+	}
+	if box.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation %q", box.KDF)
+	}
+	aead, err := passphraseAEAD(passphrase, box.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, box.Nonce, box.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted data")
+	}
+	return plaintext, nil
+}
+
+func passphraseAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed deriving key: %w", err)
+	}
+	defer zeroBytes(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,117 @@
+package credentials
+
+import (
+	"fmt"
+	"os"
+)
+
+// stagedKeySuffix names the file a new key waits in until its rotation is
+// committed.
+const stagedKeySuffix = ".new"
+
+// KeyRotation replaces the key in a key file. The new key is staged in a
+// file beside it first, so it is on disk before anything is encrypted with
+// it; Commit moves it into place. With Vault transit the key is rotated in
+// Vault instead and there is no file.
+type KeyRotation struct {
+	// Next encrypts with the new key.
+	Next *Service
+
+	current *Service
+	path    string
+	staged  string
+}
+
+// BeginKeyRotation generates a new key for s's key file and stages it, or
+// rotates the Vault transit key. A key given in CONOPS_ENCRYPTION_KEY cannot
+// be rotated this way.
+func (s *Service) BeginKeyRotation() (*KeyRotation, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("credential encryption is disabled: %s is not set", EncryptionKeyEnv)
+	}
+	if s.transit != nil {
+		if err := s.transit.rotate(); err != nil {
+			return nil, err
+		}
+		// Committing also drops the local key: nothing needs it once
+		// every credential is encrypted by Vault.
+		return &KeyRotation{Next: &Service{transit: s.transit, source: s.source}, current: s}, nil
+	}
+	path := s.KeyFile()
+	if path == "" {
+		return nil, fmt.Errorf("key from %s cannot be rotated: replace it or use %s", EncryptionKeyEnv, EncryptionKeyFileEnv)
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encodeKeyFile(key, s.wrapper)
+	if err != nil {
+		zeroBytes(key)
+		return nil, err
+	}
+	staged := path + stagedKeySuffix
+	if err := writeKeyFile(staged, encoded); err != nil {
+		zeroBytes(key)
+		return nil, err
+	}
+	next, err := newServiceWithKey(key, s.source)
+	if err != nil {
+		_ = os.Remove(staged)
+		return nil, err
+	}
+	next.wrapper = s.wrapper
+	return &KeyRotation{Next: next, current: s, path: path, staged: staged}, nil
+}
+
+// writeKeyFile writes and syncs a staged key file, since credentials may
+// be encrypted with the key as soon as it returns.
+func writeKeyFile(path, encoded string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed creating staged key file: %w", err)
+	}
+	if _, err := file.WriteString(encoded); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed writing staged key file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed writing staged key file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed closing staged key file: %w", err)
+	}
+	return nil
+}
+
+// StagedFile is where the new key waits until Commit.
+func (k *KeyRotation) StagedFile() string {
+	return k.staged
+}
+
+// Commit switches the service the rotation began from to the new key and
+// replaces the key file with it. Call it once everything is re-encrypted; if
+// the key file cannot be replaced, the service still uses the new key and
+// the error names the staged file to move into place.
+func (k *KeyRotation) Commit() error {
+	aead := k.Next.cipher()
+	k.current.mu.Lock()
+	k.current.aead = aead
+	k.current.mu.Unlock()
+	if k.staged == "" {
+		return nil
+	}
+	if err := os.Rename(k.staged, k.path); err != nil {
+		return fmt.Errorf("failed replacing key file, move %s to %s before restarting: %w", k.staged, k.path, err)
+	}
+	return nil
+}
+
+// Abort discards the new key, leaving the key file as it was.
+func (k *KeyRotation) Abort() {
+	if k.staged != "" {
+		_ = os.Remove(k.staged)
+	}
+}
@@ -0,0 +1,138 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// AWSSecretsEndpointEnv overrides the Secrets Manager endpoint, e.g. for a
+// VPC endpoint.
+const AWSSecretsEndpointEnv = "CONOPS_AWS_SECRETS_ENDPOINT"
+
+// Secret reference schemes an env value can start with.
+const (
+	// vault:<path>#<field> reads a field of a KV secret, e.g.
+	// vault:secret/data/app#PASSWORD for KV version 2.
+	vaultRefScheme = "vault:"
+	// aws-sm:<secret id or arn>[#<field>] reads a Secrets Manager secret,
+	// or one field of a JSON secret.
+	awsSMRefScheme = "aws-sm:"
+)
+
+// SecretResolver looks up env values that reference a secret store, so the
+// secret itself is never stored by conops.
+type SecretResolver struct {
+	vault  *vaultClient
+	client *http.Client
+}
+
+// NewSecretResolverFromEnv returns a resolver using the Vault settings and
+// AWS credentials of the environment. A scheme whose store is not
+// configured fails when a reference to it is resolved.
+func NewSecretResolverFromEnv() (*SecretResolver, error) {
+	vault, err := vaultClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &SecretResolver{vault: vault, client: &http.Client{Timeout: kmsTimeout}}, nil
+}
+
+// IsReference reports whether value is a secret reference.
+func (r *SecretResolver) IsReference(value string) bool {
+	return IsSecretReference(value)
+}
+
+// IsSecretReference reports whether an env value references a secret store
+// rather than holding the secret.
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, vaultRefScheme) || strings.HasPrefix(value, awsSMRefScheme)
+}
+
+// Resolve returns the secret ref points at.
+func (r *SecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, vaultRefScheme):
+		path, field, ok := strings.Cut(strings.TrimPrefix(ref, vaultRefScheme), "#")
+		if !ok || path == "" || field == "" {
+			return "", fmt.Errorf("invalid secret reference %s: want vault:<path>#<field>", ref)
+		}
+		return r.resolveVault(ctx, strings.Trim(path, "/"), field)
+	case strings.HasPrefix(ref, awsSMRefScheme):
+		id, field, _ := strings.Cut(strings.TrimPrefix(ref, awsSMRefScheme), "#")
+		if id == "" {
+			return "", fmt.Errorf("invalid secret reference %s: want aws-sm:<secret id>[#<field>]", ref)
+		}
+		return r.resolveAWS(ctx, id, field)
+	default:
+		return "", fmt.Errorf("invalid secret reference %s", ref)
+	}
+}
+
+func (r *SecretResolver) resolveVault(ctx context.Context, path, field string) (string, error) {
+	if r.vault == nil {
+		return "", fmt.Errorf("secret reference vault:%s needs %s", path, VaultAddrEnv)
+	}
+	var data map[string]interface{}
+	if err := r.vault.callContext(ctx, http.MethodGet, path, nil, &data); err != nil {
+		return "", fmt.Errorf("failed reading vault secret %s: %w", path, err)
+	}
+	// KV version 2 nests the fields under data.data.
+	if nested, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		data = nested
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	return secretString(value), nil
+}
+
+func (r *SecretResolver) resolveAWS(ctx context.Context, id, field string) (string, error) {
+	region := envOr("AWS_REGION", "AWS_DEFAULT_REGION")
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(id, ":"); len(parts) >= 7 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("secret reference aws-sm:%s needs a secret ARN or AWS_REGION", id)
+	}
+	endpoint := strings.TrimRight(strings.TrimSpace(os.Getenv(AWSSecretsEndpointEnv)), "/")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	var resp struct {
+		SecretString *string
+	}
+	if err := awsCall(ctx, r.client, endpoint, region, "secretsmanager", "secretsmanager.GetSecretValue", map[string]string{"SecretId": id}, &resp); err != nil {
+		return "", fmt.Errorf("failed reading secret %s: %w", id, err)
+	}
+	if resp.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary; only string secrets can be referenced", id)
+	}
+	if field == "" {
+		return *resp.SecretString, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(*resp.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not JSON, so it has no field %s", id, field)
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", id, field)
+	}
+	return secretString(value), nil
+}
+
+// secretString renders a JSON field as an env value.
+func secretString(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 const EncryptionKeyEnv = "CONOPS_ENCRYPTION_KEY"
@@ -17,16 +18,41 @@ const EncryptionKeyFileEnv = "CONOPS_ENCRYPTION_KEY_FILE"
 
 // Service encrypts/decrypts sensitive values for at-rest storage.
 type Service struct {
+	mu     sync.RWMutex
 	aead   cipher.AEAD
 	source string
+	// transit, when set, encrypts instead of aead, which then only
+	// decrypts credentials stored before Vault was configured.
+	transit *vaultTransit
+	// wrapper, when set, keeps the key file's key wrapped by KMS.
+	wrapper keyWrapper
 }
 
 // NewServiceFromEnv initializes the encryption service.
 // Priority:
-// 1. CONOPS_ENCRYPTION_KEY (raw/base64, 32 bytes)
-// 2. Key file (CONOPS_ENCRYPTION_KEY_FILE or default path), auto-generated on first run.
+// 1. Vault transit (CONOPS_VAULT_TRANSIT_KEY), with a local key, if one is
+// set or its file exists, kept to decrypt older credentials.
+// 2. CONOPS_ENCRYPTION_KEY (raw/base64, 32 bytes)
+// 3. Key file (CONOPS_ENCRYPTION_KEY_FILE or default path), auto-generated on first run.
+// With CONOPS_KMS_KEY the key file holds the key wrapped by KMS; a file
+// holding a plain key is wrapped in place.
 func NewServiceFromEnv(defaultKeyPath string) (*Service, error) {
+	wrapper, err := kmsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	transit, err := vaultTransitFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if transit != nil {
+		return newVaultService(transit, wrapper, defaultKeyPath)
+	}
+
 	raw := strings.TrimSpace(os.Getenv(EncryptionKeyEnv))
+	if raw != "" && wrapper != nil {
+		return nil, fmt.Errorf("%s wraps the key file; unset %s", KMSKeyEnv, EncryptionKeyEnv)
+	}
 	if raw != "" {
 		key, err := parseKey(raw, EncryptionKeyEnv)
 		if err != nil {
@@ -43,11 +69,54 @@ func NewServiceFromEnv(defaultKeyPath string) (*Service, error) {
 		return nil, fmt.Errorf("missing default encryption key path")
 	}
 
-	key, source, err := loadOrCreateKeyFile(keyPath)
+	key, source, err := loadOrCreateKeyFile(keyPath, wrapper)
+	if err != nil {
+		return nil, err
+	}
+	service, err := newServiceWithKey(key, source)
 	if err != nil {
 		return nil, err
 	}
-	return newServiceWithKey(key, source)
+	service.wrapper = wrapper
+	return service, nil
+}
+
+func newVaultService(transit *vaultTransit, wrapper keyWrapper, defaultKeyPath string) (*Service, error) {
+	// Fail at startup rather than on the first sync.
+	if _, err := transit.encrypt([]byte("conops")); err != nil {
+		return nil, err
+	}
+	service := &Service{transit: transit, source: transit.source()}
+
+	var key []byte
+	var err error
+	if raw := strings.TrimSpace(os.Getenv(EncryptionKeyEnv)); raw != "" {
+		if key, err = parseKey(raw, EncryptionKeyEnv); err != nil {
+			return nil, err
+		}
+	} else {
+		keyPath := strings.TrimSpace(os.Getenv(EncryptionKeyFileEnv))
+		if keyPath == "" {
+			keyPath = defaultKeyPath
+		}
+		existing, err := os.ReadFile(keyPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed reading key file: %w", err)
+		}
+		if err == nil {
+			if key, _, err = decodeKeyFile(string(existing), keyPath, wrapper); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if key != nil {
+		local, err := newServiceWithKey(key, "")
+		if err != nil {
+			return nil, err
+		}
+		service.aead = local.aead
+	}
+	return service, nil
 }
 
 func newServiceWithKey(key []byte, source string) (*Service, error) {
@@ -79,13 +148,19 @@ func parseKey(value, source string) ([]byte, error) {
 	return nil, fmt.Errorf("%s must be 32 raw bytes or base64 for 32 bytes", source)
 }
 
-func loadOrCreateKeyFile(path string) ([]byte, string, error) {
+func loadOrCreateKeyFile(path string, wrapper keyWrapper) ([]byte, string, error) {
 	existing, err := os.ReadFile(path)
 	if err == nil {
-		key, parseErr := parseKey(strings.TrimSpace(string(existing)), "key file "+path)
+		key, wrapped, parseErr := decodeKeyFile(string(existing), path, wrapper)
 		if parseErr != nil {
 			return nil, "", parseErr
 		}
+		if wrapper != nil && !wrapped {
+			if err := wrapKeyFile(path, key, wrapper); err != nil {
+				zeroBytes(key)
+				return nil, "", err
+			}
+		}
 		return key, "file:" + path, nil
 	}
 	if !errors.Is(err, os.ErrNotExist) {
@@ -99,16 +174,20 @@ func loadOrCreateKeyFile(path string) ([]byte, string, error) {
 		}
 	}
 
-	key := make([]byte, 32)
-	if _, randErr := rand.Read(key); randErr != nil {
-		return nil, "", fmt.Errorf("failed generating encryption key: %w", randErr)
+	key, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	encoded, err := encodeKeyFile(key, wrapper)
+	if err != nil {
+		zeroBytes(key)
+		return nil, "", err
 	}
-	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
 
 	file, createErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
 	if createErr != nil {
 		if errors.Is(createErr, os.ErrExist) {
-			return loadOrCreateKeyFile(path)
+			return loadOrCreateKeyFile(path, wrapper)
 		}
 		return nil, "", fmt.Errorf("failed creating key file: %w", createErr)
 	}
@@ -123,9 +202,44 @@ func loadOrCreateKeyFile(path string) ([]byte, string, error) {
 	return key, "file:" + path, nil
 }
 
+// wrapKeyFile replaces a key file holding a plain key with the key wrapped
+// by KMS. The key itself is unchanged, so nothing needs re-encrypting.
+func wrapKeyFile(path string, key []byte, wrapper keyWrapper) error {
+	encoded, err := encodeKeyFile(key, wrapper)
+	if err != nil {
+		return err
+	}
+	staged := path + stagedKeySuffix
+	if err := writeKeyFile(staged, encoded); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, path); err != nil {
+		_ = os.Remove(staged)
+		return fmt.Errorf("failed replacing key file: %w", err)
+	}
+	return nil
+}
+
+func generateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed generating encryption key: %w", err)
+	}
+	return key, nil
+}
+
 // Enabled reports whether encryption is configured.
 func (s *Service) Enabled() bool {
-	return s != nil && s.aead != nil
+	return s != nil && (s.transit != nil || s.cipher() != nil)
+}
+
+func (s *Service) cipher() cipher.AEAD {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.aead
 }
 
 // KeySource returns where the encryption key was loaded from.
@@ -133,30 +247,58 @@ func (s *Service) KeySource() string {
 	if s == nil {
 		return ""
 	}
+	if s.wrapper != nil && s.transit == nil {
+		return s.source + " wrapped by " + s.wrapper.name()
+	}
 	return s.source
 }
 
+// KeyFile returns the path of the key file the key was loaded from, or ""
+// when it was given in CONOPS_ENCRYPTION_KEY.
+func (s *Service) KeyFile() string {
+	if s == nil {
+		return ""
+	}
+	path, _ := strings.CutPrefix(s.source, "file:")
+	if path == s.source {
+		return ""
+	}
+	return path
+}
+
 // Encrypt seals plaintext.
 func (s *Service) Encrypt(plaintext []byte) ([]byte, []byte, error) {
-	if !s.Enabled() {
+	if s != nil && s.transit != nil {
+		ciphertext, err := s.transit.encrypt(plaintext)
+		return ciphertext, nil, err
+	}
+	aead := s.cipher()
+	if aead == nil {
 		return nil, nil, fmt.Errorf("credential encryption is disabled: %s is not set", EncryptionKeyEnv)
 	}
 
-	nonce := make([]byte, s.aead.NonceSize())
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, nil, fmt.Errorf("failed generating nonce: %w", err)
 	}
 
-	ciphertext := s.aead.Seal(nil, nonce, plaintext, nil)
+	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
 	return ciphertext, nonce, nil
 }
 
 // Decrypt opens encrypted data.
 func (s *Service) Decrypt(ciphertext, nonce []byte) ([]byte, error) {
-	if !s.Enabled() {
+	if s != nil && s.transit != nil && strings.HasPrefix(string(ciphertext), vaultCiphertextPrefix) {
+		return s.transit.decrypt(ciphertext)
+	}
+	aead := s.cipher()
+	if aead == nil && s != nil && s.transit != nil {
+		return nil, fmt.Errorf("credential was encrypted with a local key, which is not loaded: set %s or %s", EncryptionKeyEnv, EncryptionKeyFileEnv)
+	}
+	if aead == nil {
 		return nil, fmt.Errorf("credential encryption is disabled: %s is not set", EncryptionKeyEnv)
 	}
-	plaintext, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed decrypting credential: %w", err)
 	}
@@ -0,0 +1,242 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault settings. Setting VaultTransitKeyEnv makes Vault encrypt credentials
+// instead of a local key; env values can reference KV secrets either way.
+const (
+	VaultAddrEnv         = "CONOPS_VAULT_ADDR"
+	VaultTokenEnv        = "CONOPS_VAULT_TOKEN"
+	VaultTokenFileEnv    = "CONOPS_VAULT_TOKEN_FILE"
+	VaultNamespaceEnv    = "CONOPS_VAULT_NAMESPACE"
+	VaultCACertEnv       = "CONOPS_VAULT_CACERT"
+	VaultTransitMountEnv = "CONOPS_VAULT_TRANSIT_MOUNT"
+	VaultTransitKeyEnv   = "CONOPS_VAULT_TRANSIT_KEY"
+)
+
+// vaultCiphertextPrefix starts every transit ciphertext, e.g. "vault:v1:".
+const vaultCiphertextPrefix = "vault:"
+
+// vaultTimeout bounds each call to Vault.
+const vaultTimeout = 10 * time.Second
+
+// vaultClient calls Vault's HTTP API with a token.
+type vaultClient struct {
+	client    *http.Client
+	addr      string
+	namespace string
+	token     string
+	tokenFile string
+}
+
+// vaultTransit encrypts with a named key of Vault's transit engine, so the
+// key never leaves Vault.
+type vaultTransit struct {
+	*vaultClient
+	mount string
+	key   string
+}
+
+// vaultClientFromEnv returns a client for the Vault the environment names,
+// or nil when no address is set. VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE
+// and VAULT_CACERT are used when the CONOPS_ variables are not set.
+func vaultClientFromEnv() (*vaultClient, error) {
+	vault := &vaultClient{
+		addr:      strings.TrimRight(envOr(VaultAddrEnv, "VAULT_ADDR"), "/"),
+		namespace: envOr(VaultNamespaceEnv, "VAULT_NAMESPACE"),
+		token:     envOr(VaultTokenEnv, "VAULT_TOKEN"),
+		tokenFile: strings.TrimSpace(os.Getenv(VaultTokenFileEnv)),
+	}
+	if vault.addr == "" {
+		return nil, nil
+	}
+	if _, err := url.Parse(vault.addr); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", VaultAddrEnv, err)
+	}
+	if vault.token == "" && vault.tokenFile == "" {
+		return nil, fmt.Errorf("%s is set but neither %s nor %s is", VaultAddrEnv, VaultTokenEnv, VaultTokenFileEnv)
+	}
+
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile := envOr(VaultCACertEnv, "VAULT_CACERT"); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading %s: %w", VaultCACertEnv, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid %s: no certificates in %s", VaultCACertEnv, caFile)
+		}
+		httpTransport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	vault.client = &http.Client{Timeout: vaultTimeout, Transport: httpTransport}
+	return vault, nil
+}
+
+// vaultTransitFromEnv returns the transit client the environment configures,
+// or nil when VaultTransitKeyEnv is unset.
+func vaultTransitFromEnv() (*vaultTransit, error) {
+	key := strings.TrimSpace(os.Getenv(VaultTransitKeyEnv))
+	if key == "" {
+		return nil, nil
+	}
+	vault, err := vaultClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if vault == nil {
+		return nil, fmt.Errorf("%s is set but %s is not", VaultTransitKeyEnv, VaultAddrEnv)
+	}
+	transit := &vaultTransit{
+		vaultClient: vault,
+		mount:       strings.Trim(strings.TrimSpace(os.Getenv(VaultTransitMountEnv)), "/"),
+		key:         key,
+	}
+	if transit.mount == "" {
+		transit.mount = "transit"
+	}
+	return transit, nil
+}
+
+func envOr(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return strings.TrimSpace(os.Getenv(fallback))
+}
+
+func (v *vaultTransit) source() string {
+	return "vault:" + v.addr + "/v1/" + v.mount + "/keys/" + v.key
+}
+
+func (v *vaultTransit) encrypt(plaintext []byte) ([]byte, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := v.call(http.MethodPost, v.mount+"/encrypt/"+url.PathEscape(v.key), body, &resp); err != nil {
+		return nil, fmt.Errorf("failed encrypting credential with vault: %w", err)
+	}
+	if !strings.HasPrefix(resp.Ciphertext, vaultCiphertextPrefix) {
+		return nil, fmt.Errorf("failed encrypting credential with vault: unexpected ciphertext")
+	}
+	return []byte(resp.Ciphertext), nil
+}
+
+func (v *vaultTransit) decrypt(ciphertext []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := v.call(http.MethodPost, v.mount+"/decrypt/"+url.PathEscape(v.key), map[string]string{"ciphertext": string(ciphertext)}, &resp); err != nil {
+		return nil, fmt.Errorf("failed decrypting credential with vault: %w", err)
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed decrypting credential with vault: %w", err)
+	}
+	return plaintext, nil
+}
+
+// rotate makes Vault add a new version of the key. Older versions still
+// decrypt until they are trimmed in Vault.
+func (v *vaultTransit) rotate() error {
+	if err := v.call(http.MethodPost, v.mount+"/keys/"+url.PathEscape(v.key)+"/rotate", nil, nil); err != nil {
+		return fmt.Errorf("failed rotating vault key %s: %w", v.key, err)
+	}
+	return nil
+}
+
+// call sends body, if any, to path under /v1/ and decodes the response's
+// data into out.
+func (v *vaultClient) call(method, path string, body, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	return v.callContext(ctx, method, path, body, out)
+}
+
+func (v *vaultClient) callContext(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := v.readToken()
+	if err != nil {
+		return err
+	}
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+"/v1/"+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("vault answered %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("vault answered %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("invalid vault response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("invalid vault response: %w", err)
+	}
+	return nil
+}
+
+// readToken rereads the token file on every call, so a token renewed by
+// Vault Agent is picked up without a restart.
+func (v *vaultClient) readToken() (string, error) {
+	if v.tokenFile == "" {
+		return v.token, nil
+	}
+	data, err := os.ReadFile(v.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed reading %s: %w", VaultTokenFileEnv, err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s %s is empty", VaultTokenFileEnv, v.tokenFile)
+	}
+	return token, nil
+}
@@ -0,0 +1,207 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault serves the transit engine at "transit" and a KV version 2
+// secret at secret/data/app to callers with one of tokens. Ciphertexts name
+// the key version that "encrypted" them.
+type fakeVault struct {
+	tokens map[string]bool
+
+	mu       sync.Mutex
+	version  int
+	requests []string
+}
+
+func newFakeVault(t *testing.T, tokens ...string) (*fakeVault, *httptest.Server) {
+	t.Helper()
+	vault := &fakeVault{tokens: make(map[string]bool), version: 1}
+	for _, token := range tokens {
+		vault.tokens[token] = true
+	}
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+	return vault, server
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+" ns="+r.Header.Get("X-Vault-Namespace"))
+	if !f.tokens[r.Header.Get("X-Vault-Token")] {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"errors":["permission denied"]}`)
+		return
+	}
+	var body map[string]string
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	switch r.Method + " " + r.URL.Path {
+	case "POST /v1/transit/encrypt/conops":
+		plaintext, err := base64.StdEncoding.DecodeString(body["plaintext"])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"errors":["plaintext is not base64"]}`)
+			return
+		}
+		ciphertext := "vault:v" + strconv.Itoa(f.version) + ":" + base64.StdEncoding.EncodeToString(plaintext)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": ciphertext}})
+	case "POST /v1/transit/decrypt/conops":
+		parts := strings.SplitN(body["ciphertext"], ":", 3)
+		if len(parts) != 3 || parts[0] != "vault" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"errors":["invalid ciphertext: no prefix"]}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": parts[2]}})
+	case "POST /v1/transit/keys/conops/rotate":
+		f.version++
+		w.WriteHeader(http.StatusNoContent)
+	case "GET /v1/secret/data/app":
+		io.WriteString(w, `{"data":{"data":{"PASSWORD":"hunter2","PORT":5432},"metadata":{"version":3}}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"errors":[]}`)
+	}
+}
+
+func setVaultEnv(t *testing.T, addr string) {
+	t.Helper()
+	for _, name := range []string{VaultTokenEnv, VaultTokenFileEnv, VaultNamespaceEnv, VaultCACertEnv, VaultTransitMountEnv, "VAULT_ADDR", "VAULT_TOKEN", "VAULT_NAMESPACE", "VAULT_CACERT"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv(VaultAddrEnv, addr)
+}
+
+func TestVaultTransitRoundTrip(t *testing.T) {
+	vault, server := newFakeVault(t, "root-token")
+	setVaultEnv(t, server.URL+"/")
+	t.Setenv(VaultTokenEnv, "root-token")
+	t.Setenv(VaultNamespaceEnv, "team-a")
+	t.Setenv(VaultTransitKeyEnv, "conops")
+
+	transit, err := vaultTransitFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := transit.source(), "vault:"+server.URL+"/v1/transit/keys/conops"; got != want {
+		t.Errorf("source = %q, want %q", got, want)
+	}
+
+	ciphertext, err := transit.encrypt([]byte("db password"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(string(ciphertext), "vault:v1:") {
+		t.Errorf("ciphertext = %q, want a vault:v1: ciphertext", ciphertext)
+	}
+	if err := transit.rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	rotated, err := transit.encrypt([]byte("db password"))
+	if err != nil {
+		t.Fatalf("encrypt after rotate: %v", err)
+	}
+	if !strings.HasPrefix(string(rotated), "vault:v2:") {
+		t.Errorf("ciphertext after rotate = %q, want a vault:v2: ciphertext", rotated)
+	}
+	for _, c := range [][]byte{ciphertext, rotated} {
+		plaintext, err := transit.decrypt(c)
+		if err != nil {
+			t.Fatalf("decrypt %q: %v", c, err)
+		}
+		if string(plaintext) != "db password" {
+			t.Errorf("decrypt %q = %q, want db password", c, plaintext)
+		}
+	}
+
+	if _, err := transit.decrypt([]byte("garbage")); err == nil || !strings.Contains(err.Error(), "vault answered 400: invalid ciphertext: no prefix") {
+		t.Errorf("decrypt of garbage: err = %v, want the vault error", err)
+	}
+	for _, request := range vault.requests {
+		if !strings.HasSuffix(request, " ns=team-a") {
+			t.Errorf("request %q was not sent to the namespace", request)
+		}
+	}
+}
+
+func TestVaultTokenFileIsReread(t *testing.T) {
+	_, server := newFakeVault(t, "first", "renewed")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setVaultEnv(t, server.URL)
+	t.Setenv(VaultTokenFileEnv, tokenFile)
+	t.Setenv(VaultTransitKeyEnv, "conops")
+
+	transit, err := vaultTransitFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transit.encrypt([]byte("a")); err != nil {
+		t.Fatalf("encrypt with the first token: %v", err)
+	}
+	if err := os.WriteFile(tokenFile, []byte("renewed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transit.encrypt([]byte("a")); err != nil {
+		t.Fatalf("encrypt with the renewed token: %v", err)
+	}
+	if err := os.WriteFile(tokenFile, []byte("revoked\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transit.encrypt([]byte("a")); err == nil || !strings.Contains(err.Error(), "vault answered 403: permission denied") {
+		t.Errorf("encrypt with a revoked token: err = %v, want permission denied", err)
+	}
+}
+
+func TestVaultClientFromEnvRequiresToken(t *testing.T) {
+	setVaultEnv(t, "https://vault.example.com")
+	if _, err := vaultClientFromEnv(); err == nil {
+		t.Error("an address without a token was accepted")
+	}
+	setVaultEnv(t, "")
+	if vault, err := vaultClientFromEnv(); vault != nil || err != nil {
+		t.Errorf("vaultClientFromEnv without an address = %v, %v; want nil, nil", vault, err)
+	}
+}
+
+func TestSecretResolverReadsVaultKV(t *testing.T) {
+	_, server := newFakeVault(t, "root-token")
+	setVaultEnv(t, server.URL)
+	t.Setenv(VaultTokenEnv, "root-token")
+
+	resolver, err := NewSecretResolverFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ref, want := range map[string]string{
+		"vault:secret/data/app#PASSWORD": "hunter2",
+		"vault:/secret/data/app/#PORT":   "5432",
+	} {
+		got, err := resolver.Resolve(t.Context(), ref)
+		if err != nil {
+			t.Fatalf("resolve %s: %v", ref, err)
+		}
+		if got != want {
+			t.Errorf("resolve %s = %q, want %q", ref, got, want)
+		}
+	}
+	if _, err := resolver.Resolve(t.Context(), "vault:secret/data/app#MISSING"); err == nil || !strings.Contains(err.Error(), "has no field MISSING") {
+		t.Errorf("resolve a missing field: err = %v", err)
+	}
+}
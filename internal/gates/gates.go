@@ -0,0 +1,322 @@
+package gates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Gate functions an app script may define.
+const (
+	// Promote runs before a sync; a failing promote gate holds the rollout
+	// and the reconciler re-evaluates it on the next pass.
+	Promote = "promote"
+	// Health runs after a successful apply; a failing health gate marks the
+	// sync as failed.
+	Health = "health"
+)
+
+// MaxScriptSize bounds the size of a stored gate script.
+const MaxScriptSize = 64 * 1024
+
+const (
+	maxExecutionSteps  = 50_000_000
+	maxResponseBody    = 1 << 20
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// Input describes the sync a gate is evaluated for. Scripts see it as the
+// single ctx argument.
+type Input struct {
+	AppID          string
+	AppName        string
+	RepoURL        string
+	Branch         string
+	ComposePath    string
+	Commit         string
+	PreviousCommit string
+	Trigger        string
+}
+
+// FailedError is returned when a gate rejects a sync or the script errors.
+type FailedError struct {
+	Gate   string
+	Reason string
+}
+
+func (e *FailedError) Error() string {
+	if e.Gate == Promote {
+		return "promote gate held the rollout: " + e.Reason
+	}
+	return e.Gate + " gate failed: " + e.Reason
+}
+
+var fileOptions = &syntax.FileOptions{
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// Validate parses a script and checks that it defines at least one gate
+// function taking a single ctx argument.
+func Validate(src string) error {
+	if strings.TrimSpace(src) == "" {
+		return nil
+	}
+	if len(src) > MaxScriptSize {
+		return fmt.Errorf("script exceeds %d bytes", MaxScriptSize)
+	}
+
+	globals, err := load(newThread(context.Background(), nil), src)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, name := range []string{Promote, Health} {
+		value, ok := globals[name]
+		if !ok {
+			continue
+		}
+		fn, ok := value.(*starlark.Function)
+		if !ok {
+			return fmt.Errorf("%s must be a function", name)
+		}
+		if fn.NumParams() != 1 {
+			return fmt.Errorf("%s must take exactly one argument (ctx)", name)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("script must define %s(ctx) or %s(ctx)", Promote, Health)
+	}
+	return nil
+}
+
+// Defines reports whether src declares the named gate function.
+func Defines(src, gate string) bool {
+	if strings.TrimSpace(src) == "" {
+		return false
+	}
+	file, err := fileOptions.Parse("gate.star", src, 0)
+	if err != nil {
+		return false
+	}
+	for _, stmt := range file.Stmts {
+		if def, ok := stmt.(*syntax.DefStmt); ok && def.Name.Name == gate {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate runs the named gate function. It returns the script's log output
+// and a *FailedError when the gate did not pass. Scripts without that gate
+// pass trivially.
+func Evaluate(ctx context.Context, src, gate string, input Input) (string, error) {
+	if !Defines(src, gate) {
+		return "", nil
+	}
+
+	var transcript strings.Builder
+	thread := newThread(ctx, &transcript)
+	stop := context.AfterFunc(ctx, func() {
+		thread.Cancel(ctx.Err().Error())
+	})
+	defer stop()
+
+	globals, err := load(thread, src)
+	if err != nil {
+		return transcript.String(), &FailedError{Gate: gate, Reason: err.Error()}
+	}
+	fn, ok := globals[gate].(*starlark.Function)
+	if !ok {
+		return transcript.String(), &FailedError{Gate: gate, Reason: gate + " is not a function"}
+	}
+
+	result, err := starlark.Call(thread, fn, starlark.Tuple{input.value()}, nil)
+	if err != nil {
+		return transcript.String(), &FailedError{Gate: gate, Reason: scriptError(err)}
+	}
+
+	switch value := result.(type) {
+	case starlark.Bool:
+		if !value {
+			return transcript.String(), &FailedError{Gate: gate, Reason: gate + " returned False"}
+		}
+		return transcript.String(), nil
+	case starlark.String:
+		// A string result is a rejection reason.
+		reason := strings.TrimSpace(string(value))
+		if reason == "" {
+			reason = gate + " returned an empty reason"
+		}
+		return transcript.String(), &FailedError{Gate: gate, Reason: reason}
+	default:
+		return transcript.String(), &FailedError{
+			Gate:   gate,
+			Reason: fmt.Sprintf("%s must return True, False or a reason string, got %s", gate, result.Type()),
+		}
+	}
+}
+
+func load(thread *starlark.Thread, src string) (starlark.StringDict, error) {
+	globals, err := starlark.ExecFileOptions(fileOptions, thread, "gate.star", src, predeclared())
+	if err != nil {
+		return nil, errors.New(scriptError(err))
+	}
+	return globals, nil
+}
+
+func newThread(ctx context.Context, transcript io.Writer) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: "gate",
+		Print: func(_ *starlark.Thread, msg string) {
+			if transcript != nil {
+				fmt.Fprintln(transcript, msg)
+			}
+		},
+	}
+	thread.SetMaxExecutionSteps(maxExecutionSteps)
+	thread.SetLocal("context", ctx)
+	return thread
+}
+
+func threadContext(thread *starlark.Thread) context.Context {
+	if ctx, ok := thread.Local("context").(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+func scriptError(err error) string {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return evalErr.Backtrace()
+	}
+	return err.Error()
+}
+
+func (in Input) value() starlark.Value {
+	app := starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"id":           starlark.String(in.AppID),
+		"name":         starlark.String(in.AppName),
+		"repo_url":     starlark.String(in.RepoURL),
+		"branch":       starlark.String(in.Branch),
+		"compose_path": starlark.String(in.ComposePath),
+	})
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"app":             app,
+		"commit":          starlark.String(in.Commit),
+		"previous_commit": starlark.String(in.PreviousCommit),
+		"trigger":         starlark.String(in.Trigger),
+	})
+}
+
+func predeclared() starlark.StringDict {
+	return starlark.StringDict{
+		"json":      json.Module,
+		"struct":    starlark.NewBuiltin("struct", starlarkstruct.Make),
+		"http_get":  starlark.NewBuiltin("http_get", httpGet),
+		"http_post": starlark.NewBuiltin("http_post", httpPost),
+		"sleep":     starlark.NewBuiltin("sleep", sleep),
+	}
+}
+
+func httpGet(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var url string
+	var headers *starlark.Dict
+	timeout := starlark.Value(starlark.MakeInt(int(defaultHTTPTimeout / time.Second)))
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &url, "headers?", &headers, "timeout?", &timeout); err != nil {
+		return nil, err
+	}
+	return doRequest(thread, b.Name(), http.MethodGet, url, "", headers, timeout)
+}
+
+func httpPost(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var url, body string
+	var headers *starlark.Dict
+	timeout := starlark.Value(starlark.MakeInt(int(defaultHTTPTimeout / time.Second)))
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &url, "body?", &body, "headers?", &headers, "timeout?", &timeout); err != nil {
+		return nil, err
+	}
+	return doRequest(thread, b.Name(), http.MethodPost, url, body, headers, timeout)
+}
+
+func doRequest(thread *starlark.Thread, name, method, url, body string, headers *starlark.Dict, timeout starlark.Value) (starlark.Value, error) {
+	seconds, ok := starlark.AsFloat(timeout)
+	if !ok || seconds <= 0 {
+		return nil, fmt.Errorf("%s: timeout must be a positive number of seconds", name)
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("%s: url must be http or https", name)
+	}
+
+	ctx, cancel := context.WithTimeout(threadContext(thread), time.Duration(seconds*float64(time.Second)))
+	defer cancel()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if headers != nil {
+		for _, item := range headers.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: header names must be strings", name)
+			}
+			value, ok := starlark.AsString(item[1])
+			if !ok {
+				return nil, fmt.Errorf("%s: header %q must be a string", name, key)
+			}
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed reading response: %w", name, err)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"status": starlark.MakeInt(resp.StatusCode),
+		"body":   starlark.String(data),
+	}), nil
+}
+
+func sleep(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &value); err != nil {
+		return nil, err
+	}
+	seconds, ok := starlark.AsFloat(value)
+	if !ok || seconds < 0 {
+		return nil, fmt.Errorf("%s: expected a non-negative number of seconds", b.Name())
+	}
+
+	timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return starlark.None, nil
+	case <-threadContext(thread).Done():
+		return nil, fmt.Errorf("%s: %w", b.Name(), threadContext(thread).Err())
+	}
+}
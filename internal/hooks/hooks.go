@@ -0,0 +1,305 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+)
+
+// HooksFileEnv points at the JSON file that declares extension hooks.
+const HooksFileEnv = "CONOPS_HOOKS_FILE"
+
+const defaultHookTimeout = 30 * time.Second
+
+// Extension points a hook can subscribe to.
+const (
+	EventPreSync      = "pre_sync"
+	EventPostSync     = "post_sync"
+	EventStatusChange = "status_change"
+	EventValidateApp  = "validate_app"
+)
+
+// Hook is one external extension, either an executable or an HTTP endpoint.
+type Hook struct {
+	Name    string            `json:"name"`
+	Events  []string          `json:"events"`
+	Command []string          `json:"command,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Timeout string            `json:"timeout,omitempty"`
+	// FailOpen lets blocking events proceed when the hook itself cannot be
+	// reached or times out. Explicit rejections always block.
+	FailOpen bool `json:"fail_open,omitempty"`
+
+	timeout time.Duration
+}
+
+// Payload is the JSON document sent to hooks on stdin or as the request body.
+type Payload struct {
+	Event          string    `json:"event"`
+	Timestamp      time.Time `json:"timestamp"`
+	App            *api.App  `json:"app,omitempty"`
+	Trigger        string    `json:"trigger,omitempty"`
+	Commit         string    `json:"commit,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Response is the optional JSON document a hook may return. A missing or
+// unparsable response is treated as allow when the hook succeeded.
+type Response struct {
+	Allow   *bool  `json:"allow,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// RejectedError is returned when a blocking hook refuses to let an operation proceed.
+type RejectedError struct {
+	Hook    string
+	Event   string
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s hook %q rejected the operation", e.Event, e.Hook)
+	}
+	return fmt.Sprintf("%s hook %q rejected the operation: %s", e.Event, e.Hook, e.Message)
+}
+
+type fileConfig struct {
+	Hooks []Hook `json:"hooks"`
+}
+
+// Runner dispatches payloads to configured hooks. A nil Runner has no hooks.
+type Runner struct {
+	hooks  []Hook
+	client *http.Client
+	logger *slog.Logger
+}
+
+// LoadFromEnv reads hook definitions from CONOPS_HOOKS_FILE. It returns an
+// empty runner when the variable is unset.
+func LoadFromEnv(logger *slog.Logger) (*Runner, error) {
+	path := strings.TrimSpace(os.Getenv(HooksFileEnv))
+	if path == "" {
+		return NewRunner(nil, logger)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s: %w", HooksFileEnv, err)
+	}
+	var cfg fileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid hooks file %s: %w", path, err)
+	}
+	return NewRunner(cfg.Hooks, logger)
+}
+
+// NewRunner validates hook definitions and returns a runner for them.
+func NewRunner(definitions []Hook, logger *slog.Logger) (*Runner, error) {
+	runner := &Runner{
+		client: &http.Client{},
+		logger: logger,
+	}
+	for i, hook := range definitions {
+		hook.Name = strings.TrimSpace(hook.Name)
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		if len(hook.Command) == 0 && strings.TrimSpace(hook.URL) == "" {
+			return nil, fmt.Errorf("hook %q needs a command or url", hook.Name)
+		}
+		if len(hook.Command) > 0 && strings.TrimSpace(hook.URL) != "" {
+			return nil, fmt.Errorf("hook %q cannot set both command and url", hook.Name)
+		}
+		if len(hook.Events) == 0 {
+			return nil, fmt.Errorf("hook %q subscribes to no events", hook.Name)
+		}
+		for _, event := range hook.Events {
+			switch event {
+			case EventPreSync, EventPostSync, EventStatusChange, EventValidateApp:
+			default:
+				return nil, fmt.Errorf("hook %q has unknown event %q", hook.Name, event)
+			}
+		}
+		hook.timeout = defaultHookTimeout
+		if value := strings.TrimSpace(hook.Timeout); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("hook %q has invalid timeout %q", hook.Name, value)
+			}
+			hook.timeout = parsed
+		}
+		runner.hooks = append(runner.hooks, hook)
+	}
+	return runner, nil
+}
+
+// Count returns the number of configured hooks.
+func (r *Runner) Count() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.hooks)
+}
+
+// Has reports whether any hook subscribes to event.
+func (r *Runner) Has(event string) bool {
+	return len(r.subscribers(event)) > 0
+}
+
+// Check runs every hook subscribed to a blocking event (pre_sync,
+// validate_app) in order and returns the first rejection.
+func (r *Runner) Check(ctx context.Context, payload Payload) error {
+	for _, hook := range r.subscribers(payload.Event) {
+		resp, err := r.invoke(ctx, hook, payload)
+		if err != nil {
+			var rejected *RejectedError
+			if !errors.As(err, &rejected) && hook.FailOpen {
+				r.warn("Hook failed; continuing because fail_open is set", hook, payload, err)
+				continue
+			}
+			return err
+		}
+		if resp.Allow != nil && !*resp.Allow {
+			return &RejectedError{Hook: hook.Name, Event: payload.Event, Message: resp.Message}
+		}
+	}
+	return nil
+}
+
+// Dispatch delivers a non-blocking event (post_sync, status_change) in the
+// background. Failures are logged and never affect the caller.
+func (r *Runner) Dispatch(payload Payload) {
+	subscribers := r.subscribers(payload.Event)
+	if len(subscribers) == 0 {
+		return
+	}
+	go func() {
+		for _, hook := range subscribers {
+			if _, err := r.invoke(context.Background(), hook, payload); err != nil {
+				r.warn("Hook delivery failed", hook, payload, err)
+			}
+		}
+	}()
+}
+
+func (r *Runner) subscribers(event string) []Hook {
+	if r == nil {
+		return nil
+	}
+	var matched []Hook
+	for _, hook := range r.hooks {
+		if slices.Contains(hook.Events, event) {
+			matched = append(matched, hook)
+		}
+	}
+	return matched
+}
+
+func (r *Runner) invoke(ctx context.Context, hook Hook, payload Payload) (Response, error) {
+	if payload.Timestamp.IsZero() {
+		payload.Timestamp = time.Now()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hook.timeout)
+	defer cancel()
+
+	if len(hook.Command) > 0 {
+		return r.invokeCommand(ctx, hook, payload.Event, body)
+	}
+	return r.invokeHTTP(ctx, hook, payload.Event, body)
+}
+
+func (r *Runner) invokeCommand(ctx context.Context, hook Hook, event string, body []byte) (Response, error) {
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "CONOPS_HOOK_EVENT="+event)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = parseResponse(stdout.Bytes()).Message
+			}
+			if message == "" {
+				message = fmt.Sprintf("exit code %d", exitErr.ExitCode())
+			}
+			return Response{}, &RejectedError{Hook: hook.Name, Event: event, Message: message}
+		}
+		return Response{}, fmt.Errorf("hook %q failed to run: %w", hook.Name, err)
+	}
+	return parseResponse(stdout.Bytes()), nil
+}
+
+func (r *Runner) invokeHTTP(ctx context.Context, hook Hook, event string, body []byte) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("hook %q has invalid url: %w", hook.Name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Conops-Event", event)
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("hook %q request failed: %w", hook.Name, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	parsed := parseResponse(respBody)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := parsed.Message
+		if message == "" {
+			message = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		}
+		return Response{}, &RejectedError{Hook: hook.Name, Event: event, Message: message}
+	}
+	return parsed, nil
+}
+
+func parseResponse(body []byte) Response {
+	var resp Response
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return resp
+	}
+	_ = json.Unmarshal(trimmed, &resp)
+	return resp
+}
+
+func (r *Runner) warn(msg string, hook Hook, payload Payload, err error) {
+	if r.logger == nil {
+		return
+	}
+	appID := ""
+	if payload.App != nil {
+		appID = payload.App.ID
+	}
+	r.logger.Warn(msg, "hook", hook.Name, "event", payload.Event, "app_id", appID, "error", err)
+}
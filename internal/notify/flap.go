@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFlapReset is how long a failure must stay quiet before the next
+// occurrence starts a fresh series.
+const DefaultFlapReset = time.Hour
+
+// flapTracker collapses repeated identical failures for an app into an
+// escalating series: the 1st, 2nd, 4th, 8th… occurrence is delivered with
+// its running count and the others are dropped. A recovery clears the series.
+type flapTracker struct {
+	reset time.Duration
+
+	mu     sync.Mutex
+	series map[string]*flapSeries
+}
+
+type flapSeries struct {
+	fingerprint string
+	count       int
+	first       time.Time
+	last        time.Time
+}
+
+func newFlapTracker(reset time.Duration) *flapTracker {
+	return &flapTracker{reset: reset, series: make(map[string]*flapSeries)}
+}
+
+// admit records event for appID, annotates it with the occurrence count and
+// reports whether it should be delivered.
+func (t *flapTracker) admit(appID string, event *Event) bool {
+	if t == nil || t.reset <= 0 || appID == "" {
+		return true
+	}
+	now := event.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch event.Type {
+	case EventSyncSucceeded:
+		// Drift series are left alone: a crash-looping container alternates
+		// drift and successful re-syncs, so only a quiet period ends them.
+		key := appID + "\x00" + EventSyncFailed
+		if series, ok := t.series[key]; ok {
+			delete(t.series, key)
+			if series.count > 1 {
+				event.Occurrences = series.count
+				first := series.first
+				event.FirstSeen = &first
+			}
+		}
+		return true
+	case EventSyncFailed, EventDriftDetected:
+	default:
+		return true
+	}
+
+	key := appID + "\x00" + event.Type
+	fingerprint := failureFingerprint(*event)
+	series, ok := t.series[key]
+	if !ok || series.fingerprint != fingerprint || now.Sub(series.last) >= t.reset {
+		t.prune(now)
+		series = &flapSeries{fingerprint: fingerprint, first: now}
+		t.series[key] = series
+	}
+	series.count++
+	series.last = now
+
+	if series.count&(series.count-1) != 0 {
+		return false
+	}
+	if series.count > 1 {
+		event.Occurrences = series.count
+		first := series.first
+		event.FirstSeen = &first
+	}
+	return true
+}
+
+// prune drops series that have gone quiet, including those of deleted apps.
+func (t *flapTracker) prune(now time.Time) {
+	for key, series := range t.series {
+		if now.Sub(series.last) >= t.reset {
+			delete(t.series, key)
+		}
+	}
+}
+
+// failureFingerprint identifies "the same failure": the same commit failing
+// with the same first line of error, or the same drift reason.
+func failureFingerprint(event Event) string {
+	message := strings.TrimSpace(event.Error)
+	if line, _, found := strings.Cut(message, "\n"); found {
+		message = line
+	}
+	return strings.Join([]string{event.Commit, event.Status, event.Reason, message}, "\x00")
+}
@@ -0,0 +1,325 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/google/uuid"
+)
+
+// Environment variables for globally configured notifications.
+const (
+	WebhookURLsEnv     = "CONOPS_WEBHOOK_URLS"
+	WebhookSecretEnv   = "CONOPS_WEBHOOK_SECRET"
+	SlackWebhookURLEnv = "CONOPS_SLACK_WEBHOOK_URL"
+	SlackBotTokenEnv   = "CONOPS_SLACK_BOT_TOKEN"
+	SlackChannelEnv    = "CONOPS_SLACK_CHANNEL"
+	// FlapResetEnv sets how long a repeated failure must stay quiet before
+	// it is reported as new; 0 reports every failure.
+	FlapResetEnv = "CONOPS_NOTIFY_FLAP_RESET"
+	// ExternalURLEnv is the public base URL of the controller, used to link
+	// notifications back to the UI.
+	ExternalURLEnv = "CONOPS_EXTERNAL_URL"
+)
+
+// Event types published by the controller.
+const (
+	EventSyncSucceeded = "sync.succeeded"
+	EventSyncFailed    = "sync.failed"
+	EventDriftDetected = "drift.detected"
+	// EventPreviewExpired is published just before an expired preview app
+	// is removed.
+	EventPreviewExpired = "preview.expired"
+	// EventSyncRolledBack is published after a failed sync was followed by
+	// a re-apply of the last synced commit; Status reports whether that
+	// worked.
+	EventSyncRolledBack = "sync.rolled_back"
+	// EventApprovalRequired is published when a new commit of an app that
+	// requires approval starts waiting for one.
+	EventApprovalRequired = "approval.required"
+)
+
+const (
+	deliveryAttempts = 4
+	deliveryTimeout  = 10 * time.Second
+	retryBaseDelay   = time.Second
+)
+
+// AppSummary identifies the app an event refers to.
+type AppSummary struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	RepoURL string `json:"repo_url"`
+	Branch  string `json:"branch"`
+}
+
+// Event is the JSON document delivered to notification targets.
+type Event struct {
+	// ID is stable across retries so receivers can de-duplicate deliveries.
+	ID        string     `json:"id"`
+	Type      string     `json:"event"`
+	Timestamp time.Time  `json:"timestamp"`
+	App       AppSummary `json:"app"`
+	Trigger   string     `json:"trigger,omitempty"`
+	Commit    string     `json:"commit,omitempty"`
+	Status    string     `json:"status,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	// Reason explains drift events, e.g. "runtime_exited".
+	Reason string `json:"reason,omitempty"`
+	// PreviousFailed is set on sync events when the app's prior sync had
+	// failed, so receivers can tell recoveries from routine successes.
+	PreviousFailed  bool    `json:"previous_failed,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	// Occurrences counts how many times this failure has repeated. On a
+	// recovery it is the number of failures that preceded it.
+	Occurrences int        `json:"occurrences,omitempty"`
+	FirstSeen   *time.Time `json:"first_seen,omitempty"`
+	// URL links to the app in the controller UI when CONOPS_EXTERNAL_URL is set.
+	URL string `json:"url,omitempty"`
+}
+
+// Sink delivers a single event to one destination.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// PermanentError marks a delivery failure that retrying will not fix.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Config holds the global notification settings.
+type Config struct {
+	// Sinks receive events for every app.
+	Sinks []Sink
+	// WebhookSecret signs per-app webhook deliveries.
+	WebhookSecret string
+	// SlackToken is used for apps that name a Slack channel.
+	SlackToken  string
+	ExternalURL string
+	// FlapReset ends a series of repeated failures after this much quiet.
+	// Zero disables de-duplication.
+	FlapReset time.Duration
+}
+
+// Notifier fans events out to global sinks and to the app's own targets.
+// A nil Notifier drops every event.
+type Notifier struct {
+	cfg    Config
+	flaps  *flapTracker
+	logger *slog.Logger
+
+	mu           sync.Mutex
+	lastDelivery Delivery
+}
+
+// Delivery describes the outcome of sending one event to one sink.
+type Delivery struct {
+	At    time.Time
+	Sink  string
+	Event string
+	AppID string
+	// Error is empty when the sink accepted the event.
+	Error string
+}
+
+// NewNotifier creates a notifier from cfg.
+func NewNotifier(cfg Config, logger *slog.Logger) *Notifier {
+	cfg.ExternalURL = strings.TrimRight(strings.TrimSpace(cfg.ExternalURL), "/")
+	return &Notifier{
+		cfg:    cfg,
+		flaps:  newFlapTracker(cfg.FlapReset),
+		logger: logger,
+	}
+}
+
+// LoadFromEnv builds a notifier from the CONOPS_WEBHOOK_*, CONOPS_SLACK_*
+// and CONOPS_EXTERNAL_URL variables.
+func LoadFromEnv(logger *slog.Logger) (*Notifier, error) {
+	cfg := Config{
+		WebhookSecret: os.Getenv(WebhookSecretEnv),
+		SlackToken:    strings.TrimSpace(os.Getenv(SlackBotTokenEnv)),
+		ExternalURL:   os.Getenv(ExternalURLEnv),
+		FlapReset:     DefaultFlapReset,
+	}
+	if value := strings.TrimSpace(os.Getenv(FlapResetEnv)); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s: %s", FlapResetEnv, value)
+		}
+		cfg.FlapReset = parsed
+	}
+
+	for _, raw := range strings.Split(os.Getenv(WebhookURLsEnv), ",") {
+		target := strings.TrimSpace(raw)
+		if target == "" {
+			continue
+		}
+		if err := ValidateWebhookURL(target); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", WebhookURLsEnv, err)
+		}
+		cfg.Sinks = append(cfg.Sinks, NewWebhookSink(target, cfg.WebhookSecret))
+	}
+
+	if target := strings.TrimSpace(os.Getenv(SlackWebhookURLEnv)); target != "" {
+		if err := ValidateWebhookURL(target); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", SlackWebhookURLEnv, err)
+		}
+		cfg.Sinks = append(cfg.Sinks, NewSlackWebhookSink(target))
+	}
+	if channel := strings.TrimSpace(os.Getenv(SlackChannelEnv)); channel != "" {
+		if cfg.SlackToken == "" {
+			return nil, fmt.Errorf("%s requires %s", SlackChannelEnv, SlackBotTokenEnv)
+		}
+		cfg.Sinks = append(cfg.Sinks, NewSlackBotSink(cfg.SlackToken, channel))
+	}
+	return NewNotifier(cfg, logger), nil
+}
+
+// Count returns the number of globally configured sinks.
+func (n *Notifier) Count() int {
+	if n == nil {
+		return 0
+	}
+	return len(n.cfg.Sinks)
+}
+
+// Publish delivers event in the background to every global sink and to the
+// targets configured on app, filtered by its notify events and quiet hours.
+// Repeats of an identical failure are collapsed into an escalating series
+// (see CONOPS_NOTIFY_FLAP_RESET). Deliveries are retried with backoff;
+// failures are logged and never affect the caller.
+func (n *Notifier) Publish(app *api.App, event Event) {
+	if n == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if app != nil && !n.flaps.admit(app.ID, &event) {
+		if n.logger != nil {
+			n.logger.Debug("Suppressed repeated failure notification", "app_id", app.ID, "event", event.Type)
+		}
+		return
+	}
+	sinks := n.sinksFor(app, event)
+	if len(sinks) == 0 {
+		return
+	}
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if app != nil {
+		event.App = AppSummary{
+			ID:      app.ID,
+			Name:    app.Name,
+			RepoURL: app.RepoURL,
+			Branch:  app.Branch,
+		}
+		if n.cfg.ExternalURL != "" && event.URL == "" {
+			event.URL = n.cfg.ExternalURL + "/ui/apps/" + app.ID
+		}
+	}
+
+	for _, sink := range sinks {
+		go n.deliver(sink, event)
+	}
+}
+
+// sinksFor returns the global sinks plus app's own targets, unless app's
+// routing (see appWants) holds event back from them.
+func (n *Notifier) sinksFor(app *api.App, event Event) []Sink {
+	sinks := append([]Sink(nil), n.cfg.Sinks...)
+	if app == nil {
+		return sinks
+	}
+	if !appWants(app, event) {
+		if n.logger != nil {
+			n.logger.Debug("App notification routing skipped its own targets", "app_id", app.ID, "event", event.Type)
+		}
+		return sinks
+	}
+	for _, target := range app.NotifyWebhooks {
+		sinks = append(sinks, NewWebhookSink(target, n.cfg.WebhookSecret))
+	}
+	if app.SlackWebhookURL != "" {
+		sinks = append(sinks, NewSlackWebhookSink(app.SlackWebhookURL))
+	}
+	if app.SlackChannel != "" {
+		if n.cfg.SlackToken == "" {
+			if n.logger != nil {
+				n.logger.Warn("App names a Slack channel but no bot token is configured", "app_id", app.ID, "env", SlackBotTokenEnv)
+			}
+		} else {
+			sinks = append(sinks, NewSlackBotSink(n.cfg.SlackToken, app.SlackChannel))
+		}
+	}
+	return sinks
+}
+
+func (n *Notifier) deliver(sink Sink, event Event) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		err := sink.Send(ctx, event)
+		cancel()
+		if err == nil {
+			n.recordDelivery(sink, event, nil)
+			return
+		}
+
+		var permanent *PermanentError
+		if errors.As(err, &permanent) || attempt == deliveryAttempts {
+			n.recordDelivery(sink, event, err)
+			if n.logger != nil {
+				n.logger.Warn("Notification delivery failed", "sink", sink.Name(), "event", event.Type, "app_id", event.App.ID, "attempts", attempt, "error", err)
+			}
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (n *Notifier) recordDelivery(sink Sink, event Event, err error) {
+	delivery := Delivery{At: time.Now(), Sink: sink.Name(), Event: event.Type, AppID: event.App.ID}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	n.mu.Lock()
+	n.lastDelivery = delivery
+	n.mu.Unlock()
+}
+
+// LastDelivery returns the most recent finished delivery; its time is zero
+// when nothing was sent yet.
+func (n *Notifier) LastDelivery() Delivery {
+	if n == nil {
+		return Delivery{}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.lastDelivery
+}
+
+// ValidateWebhookURL checks that value is an absolute http(s) URL.
+func ValidateWebhookURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("webhook url must be an absolute http or https url: %s", value)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+)
+
+// Events lists the event types an app's own targets can subscribe to.
+var Events = []string{EventSyncSucceeded, EventSyncFailed, EventDriftDetected, EventPreviewExpired, EventSyncRolledBack, EventApprovalRequired}
+
+// NormalizeEvents trims and de-duplicates event types and rejects unknown
+// ones. An empty result subscribes to every event.
+func NormalizeEvents(events []string) ([]string, error) {
+	var normalized []string
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if event == "" || slices.Contains(normalized, event) {
+			continue
+		}
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("invalid notify event %q: must be one of %s", event, strings.Join(Events, ", "))
+		}
+		normalized = append(normalized, event)
+	}
+	return normalized, nil
+}
+
+// QuietHours is a daily window during which an app's own targets only hear
+// about failures. The window may wrap past midnight.
+type QuietHours struct {
+	Start    int // minutes after midnight
+	End      int
+	Location *time.Location
+}
+
+// ParseQuietHours parses "22:00-07:00", optionally followed by an IANA time
+// zone such as "22:00-07:00 Europe/Berlin". Without a zone the controller's
+// local time is used.
+func ParseQuietHours(value string) (QuietHours, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: want HH:MM-HH:MM [zone]", value)
+	}
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: want HH:MM-HH:MM [zone]", value)
+	}
+	quiet := QuietHours{Location: time.Local}
+	var err error
+	if quiet.Start, err = parseClock(start); err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: %w", value, err)
+	}
+	if quiet.End, err = parseClock(end); err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: %w", value, err)
+	}
+	if quiet.Start == quiet.End {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: start and end must differ", value)
+	}
+	if len(fields) == 2 {
+		if quiet.Location, err = time.LoadLocation(fields[1]); err != nil {
+			return QuietHours{}, fmt.Errorf("invalid quiet hours %q: unknown time zone %s", value, fields[1])
+		}
+	}
+	return quiet, nil
+}
+
+// NormalizeQuietHours validates value and returns it in canonical form.
+func NormalizeQuietHours(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	quiet, err := ParseQuietHours(value)
+	if err != nil {
+		return "", err
+	}
+	return quiet.String(), nil
+}
+
+// Contains reports whether t falls inside the window.
+func (q QuietHours) Contains(t time.Time) bool {
+	local := t.In(q.Location)
+	minute := local.Hour()*60 + local.Minute()
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
+}
+
+func (q QuietHours) String() string {
+	window := fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+	if q.Location == nil || q.Location == time.Local {
+		return window
+	}
+	return window + " " + q.Location.String()
+}
+
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// appWants reports whether app's own targets should receive event, given its
+// event subscriptions and quiet hours.
+func appWants(app *api.App, event Event) bool {
+	if len(app.NotifyEvents) > 0 && !slices.Contains(app.NotifyEvents, event.Type) {
+		return false
+	}
+	if app.QuietHours == "" || event.Type == EventSyncFailed {
+		return true
+	}
+	quiet, err := ParseQuietHours(app.QuietHours)
+	if err != nil {
+		return true
+	}
+	return !quiet.Contains(event.Timestamp)
+}
@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// SlackSink posts sync failures and recoveries to Slack, either through an
+// incoming webhook or with a bot token and channel.
+type SlackSink struct {
+	WebhookURL string
+	Token      string
+	Channel    string
+	Client     *http.Client
+}
+
+// NewSlackWebhookSink posts to a Slack incoming webhook.
+func NewSlackWebhookSink(webhookURL string) *SlackSink {
+	return &SlackSink{WebhookURL: webhookURL, Client: http.DefaultClient}
+}
+
+// NewSlackBotSink posts to channel with chat.postMessage.
+func NewSlackBotSink(token, channel string) *SlackSink {
+	return &SlackSink{Token: token, Channel: channel, Client: http.DefaultClient}
+}
+
+// Name identifies the sink in logs.
+func (s *SlackSink) Name() string {
+	if s.Channel != "" {
+		return "slack:" + s.Channel
+	}
+	return "slack:webhook"
+}
+
+// Send posts event when it is a fresh failure or a recovery. Repeated
+// failures and routine successes are skipped to keep channels quiet.
+func (s *SlackSink) Send(ctx context.Context, event Event) error {
+	if !slackWants(event) {
+		return nil
+	}
+
+	message := slackMessage(event)
+	target := s.WebhookURL
+	if target == "" {
+		target = slackPostMessageURL
+		message["channel"] = s.Channel
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to encode slack message: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.WebhookURL == "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("slack returned HTTP %d", resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &PermanentError{Err: fmt.Errorf("slack returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))}
+	}
+	if s.WebhookURL != "" {
+		return nil
+	}
+
+	// chat.postMessage reports failures in the body with HTTP 200.
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("invalid slack response: %w", err)
+	}
+	if !result.OK {
+		if result.Error == "ratelimited" {
+			return fmt.Errorf("slack error: %s", result.Error)
+		}
+		return &PermanentError{Err: fmt.Errorf("slack error: %s", result.Error)}
+	}
+	return nil
+}
+
+func slackWants(event Event) bool {
+	switch event.Type {
+	case EventSyncFailed:
+		// Escalations of a repeating failure are posted; other repeats are not.
+		return !event.PreviousFailed || event.Occurrences > 1
+	case EventSyncSucceeded:
+		return event.PreviousFailed
+	default:
+		return false
+	}
+}
+
+func slackMessage(event Event) map[string]any {
+	appName := event.App.Name
+	if appName == "" {
+		appName = event.App.ID
+	}
+	if event.URL != "" {
+		appName = fmt.Sprintf("<%s|%s>", event.URL, appName)
+	}
+
+	headline := fmt.Sprintf(":white_check_mark: *%s* recovered", appName)
+	if event.Occurrences > 1 {
+		headline += fmt.Sprintf(" after %d failures", event.Occurrences)
+	}
+	if event.Type == EventSyncFailed {
+		headline = fmt.Sprintf(":x: *%s* failed to sync", appName)
+		if event.Occurrences > 1 {
+			headline = fmt.Sprintf(":rotating_light: *%s* has failed to sync %d times", appName, event.Occurrences)
+			if event.FirstSeen != nil {
+				headline += " since " + event.FirstSeen.UTC().Format("2006-01-02 15:04 MST")
+			}
+		}
+	}
+
+	var details []string
+	if event.Commit != "" {
+		details = append(details, "*Commit:* `"+shortCommit(event.Commit)+"`")
+	}
+	if event.App.Branch != "" {
+		details = append(details, "*Branch:* "+event.App.Branch)
+	}
+	if event.Status != "" {
+		details = append(details, "*Status:* "+event.Status)
+	}
+	if event.DurationSeconds > 0 {
+		duration := time.Duration(event.DurationSeconds * float64(time.Second))
+		if duration >= time.Second {
+			duration = duration.Round(time.Second)
+		} else {
+			duration = duration.Round(time.Millisecond)
+		}
+		details = append(details, "*Duration:* "+duration.String())
+	}
+	if event.Trigger != "" {
+		details = append(details, "*Trigger:* "+event.Trigger)
+	}
+
+	text := headline
+	if len(details) > 0 {
+		text += "\n" + strings.Join(details, "  ·  ")
+	}
+	if event.Error != "" {
+		text += "\n```" + truncate(event.Error, 500) + "```"
+	}
+
+	return map[string]any{
+		"text": text,
+		"blocks": []map[string]any{
+			{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": text},
+			},
+		},
+	}
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func truncate(value string, max int) string {
+	value = strings.TrimSpace(value)
+	if len(value) <= max {
+		return value
+	}
+	return value[:max] + "…"
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed
+// with "sha256=". It is only sent when a secret is configured.
+const SignatureHeader = "X-Conops-Signature"
+
+// WebhookSink POSTs events as JSON to a URL.
+type WebhookSink struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a sink for url, signing bodies with secret when set.
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{
+		URL:    url,
+		Secret: secret,
+		Client: http.DefaultClient,
+	}
+}
+
+// Name identifies the sink in logs without leaking credentials embedded in
+// the URL.
+func (s *WebhookSink) Name() string {
+	parsed, err := url.Parse(s.URL)
+	if err != nil {
+		return "webhook"
+	}
+	return "webhook:" + parsed.Host
+}
+
+// Send delivers one event. 4xx responses other than 429 are permanent failures.
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to encode event: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Conops-Event", event.Type)
+	req.Header.Set("X-Conops-Delivery", event.ID)
+	req.Header.Set("X-Conops-Timestamp", strconv.FormatInt(event.Timestamp.Unix(), 10))
+	if s.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(s.Secret, body))
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// Sign returns the hex HMAC-SHA256 of body using secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,432 @@
+// Package provenance signs SLSA-style provenance for deployments: an in-toto
+// statement naming the deployed images, the source commit and compose file
+// it came from, the controller that applied it and who asked for it,
+// wrapped in a DSSE envelope signed with the controller's ed25519 key.
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/version"
+)
+
+const (
+	// EnabledEnv turns attestations on when set to 1 or true.
+	EnabledEnv = "CONOPS_ATTESTATIONS"
+	// KeyFileEnv overrides where the signing key is read from, or
+	// generated on first run.
+	KeyFileEnv = "CONOPS_ATTESTATION_KEY_FILE"
+	// BuilderIDEnv overrides the controller identity recorded as builder.
+	BuilderIDEnv = "CONOPS_ATTESTATION_BUILDER_ID"
+	// PushURLEnv is an optional URL every attestation is POSTed to, e.g.
+	// an evidence store or artifact registry upload endpoint.
+	PushURLEnv = "CONOPS_ATTESTATION_URL"
+	// PushTokenEnv is sent as a bearer token with each push.
+	PushTokenEnv = "CONOPS_ATTESTATION_TOKEN"
+)
+
+const (
+	// PayloadType is the DSSE payload type of an in-toto statement.
+	PayloadType   = "application/vnd.in-toto+json"
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// BuildType identifies the conops deployment predicate layout.
+	BuildType = "https://github.com/conops/conops/deployment/v1"
+
+	pushTimeout = 10 * time.Second
+)
+
+// Deployment describes one successful sync to attest.
+type Deployment struct {
+	SyncID      string
+	AppID       string
+	AppName     string
+	RepoURL     string
+	Ref         string // branch or tracked tag
+	Commit      string
+	ComposePath string
+	// ComposeDigest is the hex SHA-256 of the compose file as deployed.
+	ComposeDigest string
+	Profiles      []string
+	Trigger       string
+	Actor         string
+	Approver      string
+	// Images maps each service to the digest reference it runs.
+	Images     map[string]string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Statement is an in-toto v1 statement with a SLSA provenance predicate.
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []Resource `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     Predicate  `json:"predicate"`
+}
+
+// Resource is an in-toto resource descriptor.
+type Resource struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is a SLSA v1 provenance predicate.
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string         `json:"buildType"`
+	ExternalParameters   map[string]any `json:"externalParameters"`
+	InternalParameters   map[string]any `json:"internalParameters,omitempty"`
+	ResolvedDependencies []Resource     `json:"resolvedDependencies"`
+}
+
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type Metadata struct {
+	InvocationID string    `json:"invocationId"`
+	StartedOn    time.Time `json:"startedOn"`
+	FinishedOn   time.Time `json:"finishedOn"`
+}
+
+// Signer builds and signs attestations with the controller's key.
+type Signer struct {
+	key       ed25519.PrivateKey
+	keyID     string
+	source    string
+	builderID string
+	pushURL   string
+	pushToken string
+	client    *http.Client
+}
+
+// LoadFromEnv returns the signer configured by the CONOPS_ATTESTATION*
+// variables, or nil when attestations are off. The key is read from
+// CONOPS_ATTESTATION_KEY_FILE or defaultKeyPath and generated there on
+// first run.
+func LoadFromEnv(defaultKeyPath string) (*Signer, error) {
+	if value := strings.TrimSpace(os.Getenv(EnabledEnv)); value != "1" && !strings.EqualFold(value, "true") {
+		return nil, nil
+	}
+
+	keyPath := strings.TrimSpace(os.Getenv(KeyFileEnv))
+	if keyPath == "" {
+		keyPath = defaultKeyPath
+	}
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	builderID := strings.TrimSpace(os.Getenv(BuilderIDEnv))
+	if builderID == "" {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = "localhost"
+		}
+		builderID = "conops://" + hostname
+	}
+
+	pushURL := strings.TrimSpace(os.Getenv(PushURLEnv))
+	if pushURL != "" {
+		parsed, err := url.Parse(pushURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid %s: %s", PushURLEnv, pushURL)
+		}
+	}
+
+	return NewSigner(key, builderID, pushURL, strings.TrimSpace(os.Getenv(PushTokenEnv)), "file:"+keyPath), nil
+}
+
+// NewSigner creates a signer for key. pushURL may be empty.
+func NewSigner(key ed25519.PrivateKey, builderID, pushURL, pushToken, source string) *Signer {
+	return &Signer{
+		key:       key,
+		keyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		source:    source,
+		builderID: builderID,
+		pushURL:   pushURL,
+		pushToken: pushToken,
+		client:    http.DefaultClient,
+	}
+}
+
+// KeyID returns the hex SHA-256 of the PKIX encoding of pub, the keyid
+// signatures carry.
+func KeyID(pub ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyID returns the signer's key ID.
+func (s *Signer) KeyID() string { return s.keyID }
+
+// KeySource returns where the signing key was loaded from.
+func (s *Signer) KeySource() string { return s.source }
+
+// Pushes reports whether attestations are also pushed to a URL.
+func (s *Signer) Pushes() bool { return s.pushURL != "" }
+
+// PublicKeyPEM returns the verification key as a PEM "PUBLIC KEY" block.
+func (s *Signer) PublicKeyPEM() []byte {
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// Attest builds the provenance statement for d and signs it.
+func (s *Signer) Attest(d Deployment) (*api.Attestation, error) {
+	payload, err := json.Marshal(s.statement(d))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statement: %w", err)
+	}
+	return &api.Attestation{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []api.AttestationSignature{{
+			KeyID: s.keyID,
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, pae(PayloadType, payload))),
+		}},
+	}, nil
+}
+
+func (s *Signer) statement(d Deployment) Statement {
+	var subjects []Resource
+	for _, service := range slices.Sorted(maps.Keys(d.Images)) {
+		name, digest, ok := strings.Cut(d.Images[service], "@")
+		algorithm, value, valid := strings.Cut(digest, ":")
+		if !ok || !valid || slices.ContainsFunc(subjects, func(r Resource) bool { return r.Name == name && r.Digest[algorithm] == value }) {
+			continue
+		}
+		subjects = append(subjects, Resource{Name: name, Digest: map[string]string{algorithm: value}})
+	}
+
+	dependencies := []Resource{{
+		URI:    "git+" + d.RepoURL + "@" + d.Ref,
+		Digest: map[string]string{"gitCommit": d.Commit},
+	}}
+	if d.ComposeDigest != "" {
+		compose := Resource{Name: d.ComposePath, Digest: map[string]string{"sha256": d.ComposeDigest}}
+		dependencies = append(dependencies, compose)
+		// Stacks without registry images still get a subject.
+		if len(subjects) == 0 {
+			subjects = append(subjects, compose)
+		}
+	}
+
+	external := map[string]any{
+		"repository":  d.RepoURL,
+		"ref":         d.Ref,
+		"composePath": d.ComposePath,
+		"trigger":     d.Trigger,
+	}
+	if len(d.Profiles) > 0 {
+		external["profiles"] = d.Profiles
+	}
+	if d.Actor != "" {
+		external["actor"] = d.Actor
+	}
+	if d.Approver != "" {
+		external["approver"] = d.Approver
+	}
+	internal := map[string]any{"appId": d.AppID, "appName": d.AppName}
+	if len(d.Images) > 0 {
+		internal["services"] = d.Images
+	}
+
+	return Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType:            BuildType,
+				ExternalParameters:   external,
+				InternalParameters:   internal,
+				ResolvedDependencies: dependencies,
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: s.builderID, Version: map[string]string{"conops": version.Version}},
+				Metadata: Metadata{
+					InvocationID: d.SyncID,
+					StartedOn:    d.StartedAt.UTC(),
+					FinishedOn:   d.FinishedAt.UTC(),
+				},
+			},
+		},
+	}
+}
+
+// Push POSTs the attestation as JSON to the configured URL. It does nothing
+// without one.
+func (s *Signer) Push(ctx context.Context, attestation *api.Attestation) error {
+	if s.pushURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(attestation)
+	if err != nil {
+		return fmt.Errorf("failed to encode attestation: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.dsse.envelope.v1+json")
+	if s.pushToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.pushToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("attestation push returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Verify checks that attestation carries a valid signature by pub and
+// returns its statement.
+func Verify(attestation *api.Attestation, pub ed25519.PublicKey) (*Statement, error) {
+	if attestation == nil {
+		return nil, errors.New("no attestation")
+	}
+	payload, err := base64.StdEncoding.DecodeString(attestation.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation payload: %w", err)
+	}
+	verified := false
+	for _, signature := range attestation.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err == nil && ed25519.Verify(pub, pae(attestation.PayloadType, payload), sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("no signature matches the key")
+	}
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("invalid statement: %w", err)
+	}
+	return &statement, nil
+}
+
+// ParsePublicKey reads a PEM "PUBLIC KEY" block holding an ed25519 key.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("not a PEM public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key")
+	}
+	return pub, nil
+}
+
+// pae is the DSSE pre-authentication encoding signatures are made over.
+func pae(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
+
+func loadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	existing, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(existing)
+		if block == nil || block.Type != "PRIVATE KEY" {
+			return nil, fmt.Errorf("attestation key file %s is not a PEM private key", path)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attestation key file %s: %w", path, err)
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("attestation key file %s does not hold an ed25519 key", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed reading attestation key file: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed creating attestation key dir: %w", err)
+		}
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed generating attestation key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return loadOrCreateKey(path)
+		}
+		return nil, fmt.Errorf("failed creating attestation key file: %w", err)
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed writing attestation key file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed closing attestation key file: %w", err)
+	}
+	return key, nil
+}
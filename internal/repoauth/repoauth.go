@@ -4,14 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/knownhosts"
 )
@@ -21,11 +25,34 @@ const (
 	MethodDeployKey = "deploy_key"
 
 	KnownHostsPathEnv = "CONOPS_KNOWN_HOSTS_FILE"
+	// SSHKnownHostsEnv holds extra known_hosts lines, typically the output of
+	// ssh-keyscan for a self-hosted Git server.
+	SSHKnownHostsEnv = "CONOPS_SSH_KNOWN_HOSTS"
+	// AcceptNewHostKeysEnv enables trust-on-first-use for SSH hosts that
+	// have no known key.
+	AcceptNewHostKeysEnv = "CONOPS_SSH_ACCEPT_NEW_HOST_KEYS"
 )
 
+// generatedKnownHosts accumulates bootstrapped host keys, keyed by the
+// normalized known_hosts host, in a file shared by every app.
 var generatedKnownHosts struct {
-	mu   sync.Mutex
-	path string
+	mu    sync.Mutex
+	path  string
+	lines map[string][]string
+}
+
+// hostKeyProvider bootstraps host keys for a well-known Git host. fetch
+// returns authorized_keys style entries over HTTPS; fallback is used when
+// it fails.
+type hostKeyProvider struct {
+	fetch    func() ([]string, error)
+	fallback []string
+}
+
+var hostKeyProviders = map[string]hostKeyProvider{
+	"github.com":    {fetch: fetchGitHubSSHKeys, fallback: githubFallbackSSHKeys},
+	"gitlab.com":    {fallback: gitlabSSHKeys},
+	"bitbucket.org": {fetch: fetchBitbucketSSHKeys, fallback: bitbucketFallbackSSHKeys},
 }
 
 var githubFallbackSSHKeys = []string{
@@ -34,6 +61,14 @@ var githubFallbackSSHKeys = []string{
 	"ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQCj7ndNxQowgcQnjshcLrqPEiiphnt+VTTvDP6mHBL9j1aNUkY4Ue1gvwnGLVlOhGeYrnZaMgRK6+PKCUXaDbC7qtbW8gIkhL7aGCsOr/C56SJMy/BCZfxd1nWzAOxSDPgVsmerOBYfNqltV9/hWCqBywINIR+5dIg6JTJ72pcEpEjcYgXkE2YEFXV1JHnsKgbLWNlhScqb2UmyRkQyytRLtL+38TGxkxCflmO+5Z8CSSNY7GidjMIZ7Q4zMjA2n1nGrlTDkzwDCsw+wqFPGQA179cnfGWOWRVruj16z6XyvxvjJwbz0wQZ75XK5tKSb7FNyeIEs4TT4jk+S4dhPeAUC5y+bDYirYgM4GC7uEnztnZyaVWQ7B381AK4Qdrwt51ZqExKbQpTUNn+EjqoTwvqNj4kqx5QUCI0ThS/YkOxJCXmPUWZbhjpCg56i+2aB6CmK2JGhn57K5mj0MNdBXA4/WnwH6XoPWJzK5Nyu2zB3nAZp+S5hpQs+p1vN1/wsjk=",
 }
 
+var gitlabSSHKeys = []string{
+	"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAfuCHKVTjquxvt6CM6tdG4SLp1Btn/nOeHHE5UOzRdf",
+}
+
+var bitbucketFallbackSSHKeys = []string{
+	"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIIazEu89wgQZ4bqs3d63QSMzYVa0MuJ2e2gKTKqu+UUO",
+}
+
 // NormalizeMethod canonicalizes repo auth methods.
 func NormalizeMethod(value string) string {
 	switch strings.ToLower(strings.TrimSpace(value)) {
@@ -67,13 +102,9 @@ func ValidateCreateInput(repoURL, method, deployKey string) error {
 		return fmt.Errorf("deploy key is required for private repositories")
 	}
 
-	host, err := hostFromRepoURL(repoURL)
-	if err != nil {
+	if _, err := hostFromRepoURL(repoURL); err != nil {
 		return err
 	}
-	if !strings.EqualFold(host, "github.com") {
-		return fmt.Errorf("deploy key mode currently supports only github.com")
-	}
 	if !isSSHRepoURL(repoURL) {
 		return fmt.Errorf("deploy key mode requires an SSH repo URL")
 	}
@@ -105,8 +136,11 @@ func NormalizeDeployKey(value string) string {
 	return normalized + "\n"
 }
 
-// ResolveKnownHostsPath returns the known_hosts file used for strict host verification.
-func ResolveKnownHostsPath() (string, error) {
+// ResolveKnownHostsPath returns a known_hosts file that can verify the SSH
+// host of repoURL. An explicit CONOPS_KNOWN_HOSTS_FILE is always used as-is.
+// Otherwise the user and system files are used when they know the host, and
+// a generated file is bootstrapped for it as a last resort.
+func ResolveKnownHostsPath(repoURL string) (string, error) {
 	if path := strings.TrimSpace(os.Getenv(KnownHostsPathEnv)); path != "" {
 		if info, err := os.Stat(path); err == nil && !info.IsDir() {
 			return path, nil
@@ -114,66 +148,161 @@ func ResolveKnownHostsPath() (string, error) {
 		return "", fmt.Errorf("%s points to an invalid file", KnownHostsPathEnv)
 	}
 
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		defaultPath := filepath.Join(homeDir, ".ssh", "known_hosts")
-		if info, statErr := os.Stat(defaultPath); statErr == nil && !info.IsDir() {
-			return defaultPath, nil
-		}
+	host, port, err := SSHHostPort(repoURL)
+	if err != nil {
+		return "", err
 	}
+	address := net.JoinHostPort(host, port)
 
-	systemPath := "/etc/ssh/ssh_known_hosts"
-	if info, err := os.Stat(systemPath); err == nil && !info.IsDir() {
-		return systemPath, nil
+	var candidates []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(homeDir, ".ssh", "known_hosts"))
+	}
+	candidates = append(candidates, "/etc/ssh/ssh_known_hosts")
+	for _, path := range candidates {
+		if KnowsHost(path, address) {
+			return path, nil
+		}
 	}
 
 	generatedKnownHosts.mu.Lock()
 	defer generatedKnownHosts.mu.Unlock()
 
-	if generatedKnownHosts.path != "" {
+	path, err := bootstrapKnownHosts(host, port)
+	if err != nil {
+		return "", fmt.Errorf("no known host key for %s; set %s or %s (%w)", address, KnownHostsPathEnv, SSHKnownHostsEnv, err)
+	}
+	return path, nil
+}
+
+// KnowsHost reports whether the known_hosts file at path has a key for
+// address (host:port).
+func KnowsHost(path, address string) bool {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return false
+	}
+	db, err := gitssh.NewKnownHostsDb(path)
+	if err != nil {
+		return false
+	}
+	return len(db.HostKeyAlgorithms(address)) > 0
+}
+
+// HostKeyCallback returns a strict host-key callback for the known_hosts
+// file at path, plus the host key algorithms to offer for address so the
+// server presents a key type the file actually contains.
+func HostKeyCallback(path, address string) (ssh.HostKeyCallback, []string, error) {
+	db, err := gitssh.NewKnownHostsDb(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db.HostKeyCallback(), db.HostKeyAlgorithms(address), nil
+}
+
+// bootstrapKnownHosts adds keys for host to the generated known_hosts file.
+// Keys come from CONOPS_SSH_KNOWN_HOSTS, then the provider table, then, when
+// explicitly enabled, from the server itself. Callers hold the mutex.
+func bootstrapKnownHosts(host, port string) (string, error) {
+	if generatedKnownHosts.lines == nil {
+		generatedKnownHosts.lines = make(map[string][]string)
+		if err := loadConfiguredKnownHosts(); err != nil {
+			return "", err
+		}
+	}
+
+	entry := knownhosts.Normalize(net.JoinHostPort(host, port))
+	if len(generatedKnownHosts.lines[entry]) > 0 && generatedKnownHosts.path != "" {
 		if info, err := os.Stat(generatedKnownHosts.path); err == nil && !info.IsDir() {
 			return generatedKnownHosts.path, nil
 		}
 	}
 
-	path, err := bootstrapGitHubKnownHosts()
-	if err != nil {
-		return "", fmt.Errorf("known_hosts file not found; set %s (%w)", KnownHostsPathEnv, err)
+	if len(generatedKnownHosts.lines[entry]) == 0 {
+		keys, err := hostKeysFor(host, port)
+		if err != nil {
+			return "", err
+		}
+		for _, key := range keys {
+			if _, _, _, _, parseErr := ssh.ParseAuthorizedKey([]byte(key)); parseErr != nil {
+				return "", fmt.Errorf("invalid ssh key material for known_hosts generation: %w", parseErr)
+			}
+			generatedKnownHosts.lines[entry] = append(generatedKnownHosts.lines[entry], entry+" "+strings.TrimSpace(key))
+		}
 	}
-	generatedKnownHosts.path = path
-	return path, nil
+	return writeGeneratedKnownHosts()
 }
 
-// NewHostKeyCallback returns a strict host-key callback.
-func NewHostKeyCallback(path string) (ssh.HostKeyCallback, error) {
-	return knownhosts.New(path)
+// loadConfiguredKnownHosts seeds the generated file with CONOPS_SSH_KNOWN_HOSTS.
+func loadConfiguredKnownHosts() error {
+	raw := os.Getenv(SSHKnownHostsEnv)
+	// Allow single-line env files that escape newlines.
+	if strings.Contains(raw, `\n`) && !strings.Contains(raw, "\n") {
+		raw = strings.ReplaceAll(raw, `\n`, "\n")
+	}
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hosts, _, found := strings.Cut(line, " ")
+		if !found {
+			return fmt.Errorf("invalid %s line: %q", SSHKnownHostsEnv, line)
+		}
+		if _, _, _, _, _, err := ssh.ParseKnownHosts([]byte(line)); err != nil {
+			return fmt.Errorf("invalid %s line: %w", SSHKnownHostsEnv, err)
+		}
+		for _, host := range strings.Split(hosts, ",") {
+			generatedKnownHosts.lines[host] = append(generatedKnownHosts.lines[host], line)
+		}
+	}
+	return nil
 }
 
-func bootstrapGitHubKnownHosts() (string, error) {
-	keys, err := fetchGitHubSSHKeys()
-	if err != nil || len(keys) == 0 {
-		keys = githubFallbackSSHKeys
+func hostKeysFor(host, port string) ([]string, error) {
+	if provider, ok := hostKeyProviders[strings.ToLower(host)]; ok && port == "22" {
+		if provider.fetch != nil {
+			if keys, err := provider.fetch(); err == nil && len(keys) > 0 {
+				return keys, nil
+			}
+		}
+		return provider.fallback, nil
 	}
-
-	for _, key := range keys {
-		if _, _, _, _, parseErr := ssh.ParseAuthorizedKey([]byte(key)); parseErr != nil {
-			return "", fmt.Errorf("invalid ssh key material for known_hosts generation: %w", parseErr)
+	if strings.EqualFold(strings.TrimSpace(os.Getenv(AcceptNewHostKeysEnv)), "true") {
+		key, err := scanHostKey(net.JoinHostPort(host, port))
+		if err != nil {
+			return nil, err
 		}
+		return []string{strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))}, nil
 	}
+	return nil, fmt.Errorf("host is not a known provider and %s is not enabled", AcceptNewHostKeysEnv)
+}
 
+func writeGeneratedKnownHosts() (string, error) {
 	knownHostsDir := filepath.Join(os.TempDir(), "conops-known-hosts")
 	if err := os.MkdirAll(knownHostsDir, 0700); err != nil {
 		return "", fmt.Errorf("failed creating known_hosts dir: %w", err)
 	}
 
-	knownHostsPath := filepath.Join(knownHostsDir, "known_hosts")
+	entries := make([]string, 0, len(generatedKnownHosts.lines))
+	for entry := range generatedKnownHosts.lines {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	seen := make(map[string]bool)
 	var b strings.Builder
-	for _, key := range keys {
-		b.WriteString("github.com ")
-		b.WriteString(strings.TrimSpace(key))
-		b.WriteByte('\n')
+	for _, entry := range entries {
+		for _, line := range generatedKnownHosts.lines[entry] {
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
 	}
 
+	knownHostsPath := filepath.Join(knownHostsDir, "known_hosts")
 	tempPath := knownHostsPath + ".tmp"
 	if err := os.WriteFile(tempPath, []byte(b.String()), 0600); err != nil {
 		return "", fmt.Errorf("failed writing known_hosts file: %w", err)
@@ -182,9 +311,33 @@ func bootstrapGitHubKnownHosts() (string, error) {
 		return "", fmt.Errorf("failed finalizing known_hosts file: %w", err)
 	}
 
+	generatedKnownHosts.path = knownHostsPath
 	return knownHostsPath, nil
 }
 
+// scanHostKey records the key presented by an SSH server (trust on first use).
+func scanHostKey(address string) (ssh.PublicKey, error) {
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "git",
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyCaptured
+		},
+		Timeout: 10 * time.Second,
+	}
+	conn, err := ssh.Dial("tcp", address, config)
+	if conn != nil {
+		conn.Close()
+	}
+	if hostKey == nil {
+		return nil, fmt.Errorf("failed to scan host key for %s: %w", address, err)
+	}
+	return hostKey, nil
+}
+
+var errHostKeyCaptured = errors.New("host key captured")
+
 func fetchGitHubSSHKeys() ([]string, error) {
 	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/meta", nil)
 	if err != nil {
@@ -214,6 +367,33 @@ func fetchGitHubSSHKeys() ([]string, error) {
 	return payload.SSHKeys, nil
 }
 
+// fetchBitbucketSSHKeys reads bitbucket.org's published known_hosts lines.
+func fetchBitbucketSSHKeys() ([]string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("https://bitbucket.org/site/ssh")
+	if err != nil {
+		return nil, fmt.Errorf("failed requesting bitbucket host keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bitbucket host key request failed with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, line := range strings.Split(string(body), "\n") {
+		// Lines are "bitbucket.org <type> <key>"; keep the key part.
+		if _, key, found := strings.Cut(strings.TrimSpace(line), " "); found {
+			keys = append(keys, strings.TrimSpace(key))
+		}
+	}
+	return keys, nil
+}
+
 // BuildSSHCommand builds a strict SSH command for git.
 func BuildSSHCommand(keyPath, knownHostsPath string) string {
 	return fmt.Sprintf(
@@ -235,6 +415,37 @@ func isSSHRepoURL(repoURL string) bool {
 	return strings.Contains(trimmed, "@") && strings.Contains(trimmed, ":")
 }
 
+// SSHHostPort returns the host and port an SSH repo URL connects to. The
+// scp-like form (git@host:org/repo.git) always uses port 22.
+func SSHHostPort(repoURL string) (string, string, error) {
+	host, err := hostFromRepoURL(repoURL)
+	if err != nil {
+		return "", "", err
+	}
+	port := "22"
+	if strings.Contains(repoURL, "://") {
+		if parsed, err := url.Parse(strings.TrimSpace(repoURL)); err == nil && parsed.Port() != "" {
+			port = parsed.Port()
+		}
+	}
+	return host, port, nil
+}
+
+// UserFromRepoURL returns the SSH user in repoURL, defaulting to "git".
+func UserFromRepoURL(repoURL string) string {
+	trimmed := strings.TrimSpace(repoURL)
+	if strings.Contains(trimmed, "://") {
+		if parsed, err := url.Parse(trimmed); err == nil && parsed.User != nil && parsed.User.Username() != "" {
+			return parsed.User.Username()
+		}
+		return "git"
+	}
+	if user, _, found := strings.Cut(trimmed, "@"); found && user != "" {
+		return user
+	}
+	return "git"
+}
+
 func hostFromRepoURL(repoURL string) (string, error) {
 	trimmed := strings.TrimSpace(repoURL)
 	if trimmed == "" {
@@ -0,0 +1,316 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Build metadata is discarded.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// Parse reads a tag such as v1.2.3 or 1.2.3-rc.1. An optional leading "v" is
+// allowed; all three components are required.
+func Parse(tag string) (Version, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(tag), "v")
+	if i := strings.IndexByte(value, '+'); i >= 0 {
+		value = value[:i]
+	}
+	var v Version
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		v.Pre = value[i+1:]
+		value = value[:i]
+		if v.Pre == "" {
+			return Version{}, fmt.Errorf("invalid version %q", tag)
+		}
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", tag)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := parseNumber(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q", tag)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 following semver precedence.
+func Compare(a, b Version) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePre(a.Pre, b.Pre)
+}
+
+// comparePre orders pre-release identifiers; a release sorts after any of
+// its pre-releases.
+func comparePre(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if left[i] == right[i] {
+			continue
+		}
+		ln, lerr := strconv.Atoi(left[i])
+		rn, rerr := strconv.Atoi(right[i])
+		switch {
+		case lerr == nil && rerr == nil:
+			if ln < rn {
+				return -1
+			}
+			return 1
+		case lerr == nil:
+			return -1
+		case rerr == nil:
+			return 1
+		case left[i] < right[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(left) < len(right):
+		return -1
+	case len(left) > len(right):
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a set of version ranges, any of which may match.
+type Constraint struct {
+	raw        string
+	ranges     [][]comparator
+	prerelease bool
+}
+
+type comparator struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint reads a constraint such as "v1.2.x", "~1.4", "^2.0.0" or
+// ">=1.2.0 <2.0.0". Ranges may be joined with "||".
+func ParseConstraint(value string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(value), prerelease: strings.Contains(value, "-")}
+	if c.raw == "" {
+		return Constraint{}, fmt.Errorf("constraint is empty")
+	}
+	for _, group := range strings.Split(c.raw, "||") {
+		var comparators []comparator
+		for _, term := range strings.Fields(strings.ReplaceAll(group, ",", " ")) {
+			parsed, err := parseTerm(term)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid constraint %q: %w", c.raw, err)
+			}
+			comparators = append(comparators, parsed...)
+		}
+		if len(comparators) == 0 {
+			return Constraint{}, fmt.Errorf("invalid constraint %q: empty range", c.raw)
+		}
+		c.ranges = append(c.ranges, comparators)
+	}
+	return c, nil
+}
+
+func (c Constraint) String() string { return c.raw }
+
+// Match reports whether v satisfies the constraint. Pre-releases only match
+// constraints that mention one.
+func (c Constraint) Match(v Version) bool {
+	if v.Pre != "" && !c.prerelease {
+		return false
+	}
+	for _, comparators := range c.ranges {
+		matched := true
+		for _, cmp := range comparators {
+			if !cmp.match(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Highest returns the tag with the greatest version that satisfies c.
+func (c Constraint) Highest(tags []string) (string, bool) {
+	var best string
+	var bestVersion Version
+	found := false
+	for _, tag := range tags {
+		v, err := Parse(tag)
+		if err != nil || !c.Match(v) {
+			continue
+		}
+		if !found || Compare(v, bestVersion) > 0 {
+			best, bestVersion, found = tag, v, true
+		}
+	}
+	return best, found
+}
+
+func (cmp comparator) match(v Version) bool {
+	result := Compare(v, cmp.version)
+	switch cmp.op {
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	default:
+		return result == 0
+	}
+}
+
+// parseTerm expands one constraint term into comparators.
+func parseTerm(term string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			term = strings.TrimSpace(term[len(candidate):])
+			break
+		}
+	}
+	if term == "*" || term == "x" || term == "X" {
+		if op != "" && op != "=" {
+			return nil, fmt.Errorf("%s cannot be combined with a wildcard", op)
+		}
+		return []comparator{{op: ">=", version: Version{Pre: "0"}}}, nil
+	}
+
+	base, specified, err := parsePartial(term)
+	if err != nil {
+		return nil, err
+	}
+	lower := comparator{op: ">=", version: base}
+
+	switch op {
+	case "", "=":
+		if specified == 3 {
+			return []comparator{{op: "=", version: base}}, nil
+		}
+		return []comparator{lower, {op: "<", version: bump(base, specified-1)}}, nil
+	case "~":
+		// ~1.2.3 and ~1.2 allow patch updates; ~1 allows minor updates.
+		position := 1
+		if specified == 1 {
+			position = 0
+		}
+		return []comparator{lower, {op: "<", version: bump(base, position)}}, nil
+	case "^":
+		// ^ allows updates that do not change the left-most non-zero part.
+		position := specified - 1
+		for i, n := range []int{base.Major, base.Minor, base.Patch}[:specified] {
+			if n != 0 {
+				position = i
+				break
+			}
+		}
+		return []comparator{lower, {op: "<", version: bump(base, position)}}, nil
+	case ">", "<=":
+		if specified < 3 {
+			// >1.2 means >=1.3.0 and <=1.2 means <1.3.0.
+			upper := bump(base, specified-1)
+			if op == ">" {
+				return []comparator{{op: ">=", version: upper}}, nil
+			}
+			return []comparator{{op: "<", version: upper}}, nil
+		}
+		return []comparator{{op: op, version: base}}, nil
+	default:
+		return []comparator{{op: op, version: base}}, nil
+	}
+}
+
+// parsePartial reads a possibly incomplete version such as 1, v1.2 or
+// 1.2.x and reports how many components were given.
+func parsePartial(term string) (Version, int, error) {
+	value := strings.TrimPrefix(term, "v")
+	var v Version
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		v.Pre = value[i+1:]
+		value = value[:i]
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 || value == "" {
+		return Version{}, 0, fmt.Errorf("invalid version %q", term)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	specified := 0
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := parseNumber(part)
+		if err != nil {
+			return Version{}, 0, fmt.Errorf("invalid version %q", term)
+		}
+		*numbers[i] = n
+		specified++
+	}
+	if specified == 0 {
+		return Version{}, 0, fmt.Errorf("invalid version %q", term)
+	}
+	if v.Pre != "" && specified < 3 {
+		return Version{}, 0, fmt.Errorf("pre-release requires a full version: %q", term)
+	}
+	return v, specified, nil
+}
+
+// bump returns the smallest version above every version sharing base's
+// components up to position (0 = major, 1 = minor, 2 = patch).
+func bump(base Version, position int) Version {
+	switch position {
+	case 0:
+		return Version{Major: base.Major + 1, Pre: "0"}
+	case 1:
+		return Version{Major: base.Major, Minor: base.Minor + 1, Pre: "0"}
+	default:
+		return Version{Major: base.Major, Minor: base.Minor, Patch: base.Patch + 1, Pre: "0"}
+	}
+}
+
+func parseNumber(value string) (int, error) {
+	if value == "" || (len(value) > 1 && value[0] == '0') {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return strconv.Atoi(value)
+}
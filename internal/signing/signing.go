@@ -0,0 +1,250 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	pgpKeyHeader       = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	sshSignatureHeader = "-----BEGIN SSH SIGNATURE-----"
+	sshSignatureFooter = "-----END SSH SIGNATURE-----"
+	sshSigMagic        = "SSHSIG"
+	// gitNamespace is the namespace git uses for SSH commit signatures.
+	gitNamespace = "git"
+)
+
+// UnverifiedError is returned when a commit is unsigned or its signature
+// does not match any allowed key.
+type UnverifiedError struct {
+	Commit string
+	Reason string
+}
+
+func (e *UnverifiedError) Error() string {
+	if e.Commit == "" {
+		return "no verified commit to deploy: " + e.Reason
+	}
+	return fmt.Sprintf("commit %s is not signed by an allowed key: %s", shortHash(e.Commit), e.Reason)
+}
+
+// NormalizeKeys trims and validates an allow-list of signing keys. Each entry
+// is either an armored OpenPGP public key block or one or more SSH public
+// keys in authorized_keys format, one per line. SSH entries are split so the
+// result holds one key per element.
+func NormalizeKeys(entries []string) ([]string, error) {
+	var keys []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, pgpKeyHeader) {
+			if _, err := openpgp.ReadArmoredKeyRing(strings.NewReader(entry)); err != nil {
+				return nil, fmt.Errorf("invalid signing key: %w", err)
+			}
+			keys = append(keys, entry)
+			continue
+		}
+		for _, line := range strings.Split(entry, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err != nil {
+				return nil, fmt.Errorf("invalid signing key %q: %w", truncate(line, 40), err)
+			}
+			keys = append(keys, line)
+		}
+	}
+	return keys, nil
+}
+
+// Fingerprints describes each key by its fingerprint so key lists can be
+// compared and displayed without the full key material.
+func Fingerprints(keys []string) []string {
+	fingerprints := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fingerprints = append(fingerprints, fingerprint(key))
+	}
+	return fingerprints
+}
+
+func fingerprint(key string) string {
+	if strings.Contains(key, pgpKeyHeader) {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil || len(entities) == 0 {
+			return "openpgp:invalid"
+		}
+		parts := make([]string, 0, len(entities))
+		for _, entity := range entities {
+			parts = append(parts, "openpgp:"+strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)))
+		}
+		return strings.Join(parts, ",")
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		return "ssh:invalid"
+	}
+	return ssh.FingerprintSHA256(publicKey)
+}
+
+// VerifyCommit checks that commit carries a valid GPG or SSH signature from
+// one of keys and returns the fingerprint of the key that signed it.
+func VerifyCommit(commit *object.Commit, keys []string) (string, error) {
+	hash := commit.Hash.String()
+	signature := strings.TrimSpace(commit.PGPSignature)
+	if signature == "" {
+		return "", &UnverifiedError{Commit: hash, Reason: "commit is unsigned"}
+	}
+
+	var pgpKeys, sshKeys []string
+	for _, key := range keys {
+		if strings.Contains(key, pgpKeyHeader) {
+			pgpKeys = append(pgpKeys, key)
+		} else {
+			sshKeys = append(sshKeys, key)
+		}
+	}
+
+	if strings.HasPrefix(signature, sshSignatureHeader) {
+		payload, err := signedPayload(commit)
+		if err != nil {
+			return "", err
+		}
+		signer, err := verifySSH(payload, signature, sshKeys)
+		if err != nil {
+			return "", &UnverifiedError{Commit: hash, Reason: err.Error()}
+		}
+		return signer, nil
+	}
+
+	if len(pgpKeys) == 0 {
+		return "", &UnverifiedError{Commit: hash, Reason: "commit has a GPG signature but no GPG keys are allowed"}
+	}
+	entity, err := commit.Verify(strings.Join(pgpKeys, "\n"))
+	if err != nil {
+		return "", &UnverifiedError{Commit: hash, Reason: err.Error()}
+	}
+	return "openpgp:" + strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)), nil
+}
+
+func signedPayload(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, fmt.Errorf("failed to encode commit: %w", err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// verifySSH checks an armored SSH signature (PROTOCOL.sshsig) over payload.
+func verifySSH(payload []byte, armored string, allowed []string) (string, error) {
+	if len(allowed) == 0 {
+		return "", errors.New("commit has an SSH signature but no SSH keys are allowed")
+	}
+
+	body := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(armored, sshSignatureHeader)), sshSignatureFooter))
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if !bytes.HasPrefix(raw, []byte(sshSigMagic)) {
+		return "", errors.New("malformed SSH signature: missing SSHSIG preamble")
+	}
+
+	var blob struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(raw[len(sshSigMagic):], &blob); err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if blob.Version != 1 {
+		return "", fmt.Errorf("unsupported SSH signature version %d", blob.Version)
+	}
+	if blob.Namespace != gitNamespace {
+		return "", fmt.Errorf("SSH signature namespace is %q, expected %q", blob.Namespace, gitNamespace)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(blob.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("malformed SSH signature key: %w", err)
+	}
+	if !sshKeyAllowed(publicKey, allowed) {
+		return "", fmt.Errorf("signing key %s is not allowed", ssh.FingerprintSHA256(publicKey))
+	}
+
+	var digest hash.Hash
+	switch blob.HashAlgorithm {
+	case "sha256":
+		digest = sha256.New()
+	case "sha512":
+		digest = sha512.New()
+	default:
+		return "", fmt.Errorf("unsupported SSH signature hash %q", blob.HashAlgorithm)
+	}
+	digest.Write(payload)
+
+	signed := append([]byte(sshSigMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{blob.Namespace, blob.Reserved, blob.HashAlgorithm, digest.Sum(nil)})...)
+
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(blob.Signature, &signature); err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if err := publicKey.Verify(signed, &signature); err != nil {
+		return "", fmt.Errorf("SSH signature does not verify: %w", err)
+	}
+	return ssh.FingerprintSHA256(publicKey), nil
+}
+
+func sshKeyAllowed(publicKey ssh.PublicKey, allowed []string) bool {
+	marshaled := publicKey.Marshal()
+	for _, key := range allowed {
+		candidate, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err == nil && bytes.Equal(candidate.Marshal(), marshaled) {
+			return true
+		}
+	}
+	return false
+}
+
+func shortHash(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max] + "…"
+}
@@ -0,0 +1,531 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/conops/conops/internal/api"
+)
+
+// appColumns is the projection shared by every app query. Keep it in sync
+// with scanApp.
+const appColumns = `
+		id,
+		name,
+		repo_url,
+		repo_auth_method,
+		branch,
+		compose_path,
+		poll_interval,
+		COALESCE(last_seen_commit, ''),
+		COALESCE(last_seen_commit_message, ''),
+		COALESCE(last_synced_commit, ''),
+		COALESCE(last_synced_commit_message, ''),
+		COALESCE(last_sync_output, ''),
+		COALESCE(last_sync_error, ''),
+		last_sync_at,
+		status,
+		COALESCE(gate_script, ''),
+		COALESCE(notify_webhooks, ''),
+		COALESCE(slack_webhook_url, ''),
+		COALESCE(slack_channel, ''),
+		COALESCE(signing_keys, ''),
+		COALESCE(track, ''),
+		COALESCE(watch_paths, ''),
+		COALESCE(last_seen_commit_info, ''),
+		COALESCE(profiles, ''),
+		COALESCE(notify_events, ''),
+		COALESCE(quiet_hours, ''),
+		COALESCE(env_file, ''),
+		COALESCE(preview_ttl, ''),
+		COALESCE(post_deploy, ''),
+		COALESCE(pre_deploy, ''),
+		COALESCE(priority, ''),
+		COALESCE(require_approval, FALSE),
+		COALESCE(pin_images, FALSE),
+		COALESCE(freeze_windows, ''),
+		COALESCE(docker_host, ''),
+		COALESCE(sync_failures, 0),
+		COALESCE(paused, FALSE)`
+
+// appInsertColumns lists the columns written by CreateApp, in the order
+// returned by appInsertValues.
+var appInsertColumns = []string{
+	"id",
+	"name",
+	"repo_url",
+	"repo_auth_method",
+	"branch",
+	"compose_path",
+	"poll_interval",
+	"last_seen_commit",
+	"last_seen_commit_message",
+	"last_synced_commit",
+	"last_synced_commit_message",
+	"last_sync_output",
+	"last_sync_error",
+	"last_sync_at",
+	"status",
+	"gate_script",
+	"notify_webhooks",
+	"slack_webhook_url",
+	"slack_channel",
+	"signing_keys",
+	"track",
+	"watch_paths",
+	"last_seen_commit_info",
+	"profiles",
+	"notify_events",
+	"quiet_hours",
+	"env_file",
+	"preview_ttl",
+	"post_deploy",
+	"pre_deploy",
+	"priority",
+	"require_approval",
+	"pin_images",
+	"freeze_windows",
+	"docker_host",
+}
+
+// rowScanner is satisfied by both database/sql and pgx rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApp(row rowScanner) (*api.App, error) {
+	var app api.App
+	var notifyWebhooks, signingKeys, watchPaths, commitInfo, profiles, notifyEvents, postDeploy, preDeploy, freezeWindows string
+	if err := row.Scan(
+		&app.ID,
+		&app.Name,
+		&app.RepoURL,
+		&app.RepoAuthMethod,
+		&app.Branch,
+		&app.ComposePath,
+		&app.PollInterval,
+		&app.LastSeenCommit,
+		&app.LastSeenCommitMessage,
+		&app.LastSyncedCommit,
+		&app.LastSyncedCommitMessage,
+		&app.LastSyncOutput,
+		&app.LastSyncError,
+		&app.LastSyncAt,
+		&app.Status,
+		&app.GateScript,
+		&notifyWebhooks,
+		&app.SlackWebhookURL,
+		&app.SlackChannel,
+		&signingKeys,
+		&app.Track,
+		&watchPaths,
+		&commitInfo,
+		&profiles,
+		&notifyEvents,
+		&app.QuietHours,
+		&app.EnvFile,
+		&app.PreviewTTL,
+		&postDeploy,
+		&preDeploy,
+		&app.Priority,
+		&app.RequireApproval,
+		&app.PinImages,
+		&freezeWindows,
+		&app.DockerHost,
+		&app.SyncFailures,
+		&app.Paused,
+	); err != nil {
+		return nil, err
+	}
+	app.NotifyWebhooks = decodeStringList(notifyWebhooks)
+	app.SigningKeys = decodeStringList(signingKeys)
+	app.WatchPaths = decodeStringList(watchPaths)
+	app.LastSeenCommitInfo = decodeCommitInfo(commitInfo)
+	app.Profiles = decodeStringList(profiles)
+	app.NotifyEvents = decodeStringList(notifyEvents)
+	app.PostDeploy = decodeStringList(postDeploy)
+	app.PreDeploy = decodeStringList(preDeploy)
+	app.FreezeWindows = decodeStringList(freezeWindows)
+	return &app, nil
+}
+
+func appInsertValues(app *api.App) []any {
+	return []any{
+		app.ID,
+		app.Name,
+		app.RepoURL,
+		app.RepoAuthMethod,
+		app.Branch,
+		app.ComposePath,
+		app.PollInterval,
+		app.LastSeenCommit,
+		app.LastSeenCommitMessage,
+		app.LastSyncedCommit,
+		app.LastSyncedCommitMessage,
+		app.LastSyncOutput,
+		app.LastSyncError,
+		app.LastSyncAt,
+		app.Status,
+		app.GateScript,
+		encodeStringList(app.NotifyWebhooks),
+		app.SlackWebhookURL,
+		app.SlackChannel,
+		encodeStringList(app.SigningKeys),
+		app.Track,
+		encodeStringList(app.WatchPaths),
+		encodeCommitInfo(app.LastSeenCommitInfo),
+		encodeStringList(app.Profiles),
+		encodeStringList(app.NotifyEvents),
+		app.QuietHours,
+		app.EnvFile,
+		app.PreviewTTL,
+		encodeStringList(app.PostDeploy),
+		encodeStringList(app.PreDeploy),
+		app.Priority,
+		app.RequireApproval,
+		app.PinImages,
+		encodeStringList(app.FreezeWindows),
+		app.DockerHost,
+	}
+}
+
+// insertAppQuery builds the CreateApp statement using the given placeholder style.
+func insertAppQuery(placeholder func(i int) string) string {
+	marks := make([]string, len(appInsertColumns))
+	for i := range marks {
+		marks[i] = placeholder(i + 1)
+	}
+	return fmt.Sprintf(
+		"INSERT INTO apps (%s) VALUES (%s)",
+		strings.Join(appInsertColumns, ", "),
+		strings.Join(marks, ", "),
+	)
+}
+
+// AppQuery selects a page of apps. Zero values match every app.
+type AppQuery struct {
+	// Statuses keeps apps in any of the given states.
+	Statuses []string
+	// Search keeps apps whose ID, name or repo URL contains it, ignoring case.
+	Search string
+	// Sort is a column from appSortColumns, descending when prefixed with
+	// "-". The default is by name.
+	Sort string
+	// Limit caps the page size; zero returns every match.
+	Limit  int
+	Offset int
+	// Summary leaves out each app's last sync output.
+	Summary bool
+}
+
+// appSortColumns maps the sort keys AppQuery accepts to the columns they
+// order by.
+var appSortColumns = map[string]string{
+	"id":           "id",
+	"name":         "LOWER(name)",
+	"status":       "status",
+	"last_sync_at": "last_sync_at",
+}
+
+// AppSortKeys lists the sort keys AppQuery accepts.
+func AppSortKeys() []string {
+	keys := make([]string, 0, len(appSortColumns))
+	for key := range appSortColumns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// queryAppsClauses builds the WHERE and ORDER BY clauses and their
+// arguments for query, returning an error for an unknown sort key.
+func queryAppsClauses(query AppQuery, placeholder func(i int) string) (where, order string, args []any, err error) {
+	var conditions []string
+	if len(query.Statuses) > 0 {
+		marks := make([]string, len(query.Statuses))
+		for i, status := range query.Statuses {
+			args = append(args, status)
+			marks[i] = placeholder(len(args))
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(marks, ", ")+")")
+	}
+	if search := strings.TrimSpace(query.Search); search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+		var matches []string
+		for _, column := range []string{"id", "name", "repo_url"} {
+			args = append(args, pattern)
+			matches = append(matches, fmt.Sprintf(`LOWER(COALESCE(%s, '')) LIKE %s ESCAPE '\'`, column, placeholder(len(args))))
+		}
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	key, direction := strings.TrimPrefix(query.Sort, "-"), "ASC"
+	if strings.HasPrefix(query.Sort, "-") {
+		direction = "DESC"
+	}
+	if key == "" {
+		key = "name"
+	}
+	column, ok := appSortColumns[key]
+	if !ok {
+		return "", "", nil, fmt.Errorf("invalid sort %q: use one of %s", query.Sort, strings.Join(AppSortKeys(), ", "))
+	}
+	// NULLs sort last either way, which the two dialects disagree on by default.
+	order = fmt.Sprintf("ORDER BY %s IS NULL, %s %s, id %s", column, column, direction, direction)
+	return where, order, args, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// queryAppsColumns is appColumns, without the sync output for summaries.
+func queryAppsColumns(query AppQuery) string {
+	if query.Summary {
+		return strings.Replace(appColumns, "COALESCE(last_sync_output, '')", "''", 1)
+	}
+	return appColumns
+}
+
+func sqlitePlaceholder(int) string { return "?" }
+
+func postgresPlaceholder(i int) string { return fmt.Sprintf("$%d", i) }
+
+// encodeStringList stores list-valued app settings as a JSON array in a TEXT
+// column. Empty lists are stored as an empty string.
+func encodeStringList(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeStringList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// encodeCommitInfo stores commit metadata as JSON in a TEXT column. Nil is
+// stored as an empty string.
+func encodeCommitInfo(info *api.CommitInfo) string {
+	if info == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(info)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// decodeCommitInfo reverses encodeCommitInfo; unreadable values decode to nil.
+func decodeCommitInfo(value string) *api.CommitInfo {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var info api.CommitInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+func scanAppToken(row rowScanner) (*api.AppToken, error) {
+	var token api.AppToken
+	var scopes string
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&token.ID,
+		&token.AppID,
+		&token.Name,
+		&scopes,
+		&token.CreatedAt,
+		&lastUsedAt,
+	); err != nil {
+		return nil, err
+	}
+	token.Scopes = decodeStringList(scopes)
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
+
+// syncRecordColumns are selected by ListSyncRecords in scanSyncRecord order.
+const syncRecordColumns = `id, app_id, started_at, finished_at, sync_trigger, actor, commit_hash, status, error, rollback_of, images, attestation`
+
+func syncRecordValues(record *api.SyncRecord) []any {
+	return []any{
+		record.ID,
+		record.AppID,
+		record.StartedAt,
+		record.FinishedAt,
+		record.Trigger,
+		record.Actor,
+		record.Commit,
+		record.Status,
+		record.Error,
+		record.RollbackOf,
+		encodeImages(record.Images),
+		encodeAttestation(record.Attestation),
+	}
+}
+
+// scanSyncRecord scans syncRecordColumns, followed by any columns extra
+// points at.
+func scanSyncRecord(row rowScanner, extra ...any) (*api.SyncRecord, error) {
+	var record api.SyncRecord
+	var images, attestation string
+	if err := row.Scan(append([]any{
+		&record.ID,
+		&record.AppID,
+		&record.StartedAt,
+		&record.FinishedAt,
+		&record.Trigger,
+		&record.Actor,
+		&record.Commit,
+		&record.Status,
+		&record.Error,
+		&record.RollbackOf,
+		&images,
+		&attestation,
+	}, extra...)...); err != nil {
+		return nil, err
+	}
+	record.Images = decodeImages(images)
+	record.Attestation = decodeAttestation(attestation)
+	return &record, nil
+}
+
+// scheduledSyncColumns are selected by ListScheduledSyncs in
+// scanScheduledSync order.
+const scheduledSyncColumns = `id, app_id, run_at, commit_hash, actor, created_at`
+
+func scanScheduledSync(row rowScanner) (*api.ScheduledSync, error) {
+	var schedule api.ScheduledSync
+	if err := row.Scan(
+		&schedule.ID,
+		&schedule.AppID,
+		&schedule.RunAt,
+		&schedule.Commit,
+		&schedule.Actor,
+		&schedule.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func encodeImages(images map[string]string) string {
+	if len(images) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(images)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeImages(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+	var images map[string]string
+	if err := json.Unmarshal([]byte(value), &images); err != nil {
+		return nil
+	}
+	return images
+}
+
+func encodeAttestation(attestation *api.Attestation) string {
+	if attestation == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(attestation)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeAttestation(value string) *api.Attestation {
+	if value == "" {
+		return nil
+	}
+	var attestation api.Attestation
+	if err := json.Unmarshal([]byte(value), &attestation); err != nil {
+		return nil
+	}
+	return &attestation
+}
+
+func encodeSyncSteps(steps []api.SyncStep) string {
+	if len(steps) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(steps)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func decodeSyncSteps(value string) []api.SyncStep {
+	if value == "" {
+		return nil
+	}
+	var steps []api.SyncStep
+	if err := json.Unmarshal([]byte(value), &steps); err != nil {
+		return nil
+	}
+	return steps
+}
+
+func encodeRevision(revision *api.AppRevision) (spec, changes string, err error) {
+	encoded, err := json.Marshal(revision.Spec)
+	if err != nil {
+		return "", "", err
+	}
+	changes, err = encodeAuditChanges(revision.Changes)
+	if err != nil {
+		return "", "", err
+	}
+	return string(encoded), changes, nil
+}
+
+func scanRevision(row rowScanner) (*api.AppRevision, error) {
+	var revision api.AppRevision
+	var spec, changes string
+	if err := row.Scan(
+		&revision.AppID,
+		&revision.Revision,
+		&revision.CreatedAt,
+		&revision.Actor,
+		&revision.Source,
+		&revision.Action,
+		&spec,
+		&changes,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(spec), &revision.Spec); err != nil {
+		return nil, fmt.Errorf("invalid revision spec: %w", err)
+	}
+	revision.Changes = decodeAuditChanges(changes)
+	return &revision, nil
+}
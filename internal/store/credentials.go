@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// reencryptCredential runs reencrypt on each ciphertext the credential has.
+func reencryptCredential(credential *AppCredential, reencrypt func(ciphertext, nonce []byte) ([]byte, []byte, error)) error {
+	fields := []struct {
+		ciphertext, nonce *[]byte
+	}{
+		{&credential.DeployKeyCiphertext, &credential.DeployKeyNonce},
+		{&credential.EnvCiphertext, &credential.EnvNonce},
+		{&credential.DockerTLSCiphertext, &credential.DockerTLSNonce},
+		{&credential.SOPSKeyCiphertext, &credential.SOPSKeyNonce},
+	}
+	for _, field := range fields {
+		if len(*field.ciphertext) == 0 {
+			continue
+		}
+		ciphertext, nonce, err := reencrypt(*field.ciphertext, *field.nonce)
+		if err != nil {
+			return fmt.Errorf("app %s: %w", credential.AppID, err)
+		}
+		*field.ciphertext, *field.nonce = ciphertext, nonce
+	}
+	return nil
+}
+
+const selectAllCredentials = `SELECT app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce, docker_tls_ciphertext, docker_tls_nonce, sops_key_ciphertext, sops_key_nonce FROM app_credentials ORDER BY app_id`
+
+func (s *SQLiteStore) ReencryptAppCredentials(ctx context.Context, reencrypt func(ciphertext, nonce []byte) ([]byte, []byte, error)) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, selectAllCredentials)
+	if err != nil {
+		return 0, err
+	}
+	var stored []*AppCredential
+	for rows.Next() {
+		credential := &AppCredential{}
+		if err := rows.Scan(&credential.AppID, &credential.DeployKeyCiphertext, &credential.DeployKeyNonce, &credential.EnvCiphertext, &credential.EnvNonce, &credential.DockerTLSCiphertext, &credential.DockerTLSNonce, &credential.SOPSKeyCiphertext, &credential.SOPSKeyNonce); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stored = append(stored, credential)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query := `
+	UPDATE app_credentials
+	SET
+		deploy_key_ciphertext = ?,
+		deploy_key_nonce = ?,
+		env_ciphertext = ?,
+		env_nonce = ?,
+		docker_tls_ciphertext = ?,
+		docker_tls_nonce = ?,
+		sops_key_ciphertext = ?,
+		sops_key_nonce = ?
+	WHERE app_id = ?
+	`
+	for _, credential := range stored {
+		if err := reencryptCredential(credential, reencrypt); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, query, credential.DeployKeyCiphertext, credential.DeployKeyNonce, credential.EnvCiphertext, credential.EnvNonce, credential.DockerTLSCiphertext, credential.DockerTLSNonce, credential.SOPSKeyCiphertext, credential.SOPSKeyNonce, credential.AppID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(stored), nil
+}
+
+func (s *PostgresStore) ReencryptAppCredentials(ctx context.Context, reencrypt func(ciphertext, nonce []byte) ([]byte, []byte, error)) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Hold off credential writes from other controllers sharing the store.
+	if _, err := tx.Exec(ctx, `LOCK TABLE app_credentials IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(ctx, selectAllCredentials)
+	if err != nil {
+		return 0, err
+	}
+	var stored []*AppCredential
+	for rows.Next() {
+		credential := &AppCredential{}
+		if err := rows.Scan(&credential.AppID, &credential.DeployKeyCiphertext, &credential.DeployKeyNonce, &credential.EnvCiphertext, &credential.EnvNonce, &credential.DockerTLSCiphertext, &credential.DockerTLSNonce, &credential.SOPSKeyCiphertext, &credential.SOPSKeyNonce); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stored = append(stored, credential)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query := `
+	UPDATE app_credentials
+	SET
+		deploy_key_ciphertext = $1,
+		deploy_key_nonce = $2,
+		env_ciphertext = $3,
+		env_nonce = $4,
+		docker_tls_ciphertext = $5,
+		docker_tls_nonce = $6,
+		sops_key_ciphertext = $7,
+		sops_key_nonce = $8
+	WHERE app_id = $9
+	`
+	for _, credential := range stored {
+		if err := reencryptCredential(credential, reencrypt); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, query, credential.DeployKeyCiphertext, credential.DeployKeyNonce, credential.EnvCiphertext, credential.EnvNonce, credential.DockerTLSCiphertext, credential.DockerTLSNonce, credential.SOPSKeyCiphertext, credential.SOPSKeyNonce, credential.AppID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(stored), nil
+}
@@ -2,7 +2,9 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/conops/conops/internal/api"
@@ -10,19 +12,53 @@ import (
 
 var ErrCredentialNotFound = errors.New("app credential not found")
 
+var ErrTokenNotFound = errors.New("app token not found")
+
+var ErrScheduledSyncNotFound = errors.New("scheduled sync not found")
+
+var ErrSyncRecordNotFound = errors.New("sync record not found")
+
+// DefaultAuditLimit caps audit queries that do not specify a limit.
+const DefaultAuditLimit = 100
+
 // Store defines the interface for data persistence.
 type Store interface {
 	CreateApp(ctx context.Context, app *api.App) error
 	GetApp(ctx context.Context, id string) (*api.App, error)
 	ListApps(ctx context.Context) ([]*api.App, error)
+	// QueryApps returns the page of apps query selects and how many apps
+	// match it in total.
+	QueryApps(ctx context.Context, query AppQuery) ([]*api.App, int, error)
 	DeleteApp(ctx context.Context, id string) error
-	UpdateApp(ctx context.Context, id, name, branch, composePath, pollInterval string) error
+	UpdateApp(ctx context.Context, app *api.App) error
 	UpsertAppCredential(ctx context.Context, credential *AppCredential) error
 	GetAppCredential(ctx context.Context, id string) (*AppCredential, error)
 	DeleteAppCredential(ctx context.Context, id string) error
 	UpdateAppCredentials(ctx context.Context, appID string, envCiphertext, envNonce []byte) error
-	UpdateAppCommit(ctx context.Context, id, commitHash, commitMessage string) error
+	// UpdateAppDockerTLS replaces the app's docker TLS material; nil
+	// ciphertext removes it.
+	UpdateAppDockerTLS(ctx context.Context, appID string, ciphertext, nonce []byte) error
+	// UpdateAppDeployKey replaces the app's deploy key; nil ciphertext
+	// removes it.
+	UpdateAppDeployKey(ctx context.Context, appID string, ciphertext, nonce []byte) error
+	// UpdateAppSOPSKey replaces the key that decrypts the app's SOPS env
+	// file; nil ciphertext removes it.
+	UpdateAppSOPSKey(ctx context.Context, appID string, ciphertext, nonce []byte) error
+	// ReencryptAppCredentials replaces every stored ciphertext with what
+	// reencrypt returns for it, in one transaction: if reencrypt fails for
+	// any, nothing changes. It returns how many apps' credentials it
+	// rewrote.
+	ReencryptAppCredentials(ctx context.Context, reencrypt func(ciphertext, nonce []byte) ([]byte, []byte, error)) (int, error)
+	UpdateAppCommit(ctx context.Context, id, commitHash, commitMessage, status string) error
+	// SkipAppCommit records a commit that changed none of the app's watched
+	// paths as both seen and synced without touching its status.
+	SkipAppCommit(ctx context.Context, id, commitHash, commitMessage string) error
+	// UpdateAppCommitInfo stores metadata for commitHash, provided it
+	// is still the app's desired commit.
+	UpdateAppCommitInfo(ctx context.Context, id, commitHash string, info *api.CommitInfo) error
 	UpdateAppStatus(ctx context.Context, id, status string, lastSyncAt *time.Time) error
+	// UpdateAppSyncResult also counts consecutive failures: an "error"
+	// status adds one and "synced" resets the count.
 	UpdateAppSyncResult(
 		ctx context.Context,
 		id string,
@@ -34,9 +70,52 @@ type Store interface {
 		syncError string,
 	) error
 	UpdateAppSyncProgress(ctx context.Context, id string, lastSyncAt time.Time, syncOutput string) error
+	ResetAppSyncFailures(ctx context.Context, id string) error
+	UpdateAppPaused(ctx context.Context, id string, paused bool) error
+	CreateAuditEntry(ctx context.Context, entry *api.AuditEntry) error
+	ListAuditEntries(ctx context.Context, appID string, limit int) ([]*api.AuditEntry, error)
+	CreateAppRevision(ctx context.Context, revision *api.AppRevision) error
+	ListAppRevisions(ctx context.Context, appID string, limit int) ([]*api.AppRevision, error)
+	CreateSyncRecord(ctx context.Context, record *api.SyncRecord) error
+	// ListSyncRecords returns an app's sync history, newest first, skipping
+	// the offset newest records. Their output is not loaded.
+	ListSyncRecords(ctx context.Context, appID string, limit, offset int) ([]*api.SyncRecord, error)
+	// GetSyncRecord returns one of an app's syncs with its output and steps.
+	GetSyncRecord(ctx context.Context, appID, id string) (*api.SyncRecord, error)
+	// CreateAppToken stores token under the hash of its secret.
+	CreateAppToken(ctx context.Context, token *api.AppToken, tokenHash string) error
+	ListAppTokens(ctx context.Context, appID string) ([]*api.AppToken, error)
+	GetAppTokenByHash(ctx context.Context, tokenHash string) (*api.AppToken, error)
+	DeleteAppToken(ctx context.Context, appID, id string) error
+	TouchAppToken(ctx context.Context, id string, usedAt time.Time) error
+	CreateScheduledSync(ctx context.Context, schedule *api.ScheduledSync) error
+	// ListScheduledSyncs returns the scheduled syncs of appID, or of every
+	// app when appID is empty, soonest first.
+	ListScheduledSyncs(ctx context.Context, appID string) ([]*api.ScheduledSync, error)
+	DeleteScheduledSync(ctx context.Context, appID, id string) error
+	SchemaVersion(ctx context.Context) (int, error)
+	// Ping checks that the database answers.
+	Ping(ctx context.Context) error
 	Close()
 }
 
+// LeaderLocker is implemented by stores that several controllers can share.
+// At most one controller holds the leader lock at a time.
+type LeaderLocker interface {
+	// TryLockLeader takes the leader lock if it is free and returns nil
+	// when another controller holds it.
+	TryLockLeader(ctx context.Context) (LeaderLock, error)
+}
+
+// LeaderLock is a held leader lock.
+type LeaderLock interface {
+	// Check fails once the lock may have been lost, e.g. because the
+	// database session holding it dropped.
+	Check(ctx context.Context) error
+	// Release gives the lock up.
+	Release(ctx context.Context) error
+}
+
 // AppCredential stores encrypted app-level credentials.
 type AppCredential struct {
 	AppID               string
@@ -44,4 +123,40 @@ type AppCredential struct {
 	DeployKeyNonce      []byte
 	EnvCiphertext       []byte
 	EnvNonce            []byte
+	DockerTLSCiphertext []byte
+	DockerTLSNonce      []byte
+	SOPSKeyCiphertext   []byte
+	SOPSKeyNonce        []byte
+}
+
+func normalizeAuditLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultAuditLimit
+	}
+	if limit > 1000 {
+		return 1000
+	}
+	return limit
+}
+
+func encodeAuditChanges(changes map[string]api.FieldChange) (string, error) {
+	if len(changes) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func decodeAuditChanges(value string) map[string]api.FieldChange {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var changes map[string]api.FieldChange
+	if err := json.Unmarshal([]byte(value), &changes); err != nil {
+		return nil
+	}
+	return changes
 }
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresLeaderLock is the session advisory lock the leader holds. Unlike
+// the migration lock it lives as long as the connection that took it.
+const postgresLeaderLock = `hashtext('conops_leader')`
+
+// TryLockLeader takes the leader lock on a connection of its own, which it
+// keeps until the lock is released; Postgres drops the lock with the session.
+func (s *PostgresStore) TryLockLeader(ctx context.Context) (LeaderLock, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(`+postgresLeaderLock+`)`).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("try leader lock: %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+	return &postgresLeaderLockConn{conn: conn}, nil
+}
+
+type postgresLeaderLockConn struct {
+	conn *pgxpool.Conn
+}
+
+func (l *postgresLeaderLockConn) Check(ctx context.Context) error {
+	return l.conn.Ping(ctx)
+}
+
+func (l *postgresLeaderLockConn) Release(ctx context.Context) error {
+	defer l.conn.Release()
+	if _, err := l.conn.Exec(ctx, `SELECT pg_advisory_unlock(`+postgresLeaderLock+`)`); err != nil {
+		// Closing the session releases the lock as well.
+		l.conn.Conn().Close(ctx)
+		return fmt.Errorf("release leader lock: %w", err)
+	}
+	return nil
+}
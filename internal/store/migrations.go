@@ -0,0 +1,337 @@
+package store
+
+// migration is one numbered schema change. Both backends apply the same
+// list in order, each with its own dialect, and record every applied
+// version in schema_migrations. Released migrations are never edited;
+// change the schema by appending a new one and bumping SchemaVersion.
+type migration struct {
+	Version     int
+	Description string
+	SQLite      []string
+	Postgres    []string
+	// Columns are added only where missing. The baseline uses them to bring
+	// databases from before numbered migrations up to date; new migrations
+	// use plain ALTER TABLE statements instead.
+	Columns []columnAdd
+}
+
+// columnAdd adds a column to Table. Postgres falls back to the SQLite
+// definition when the types agree.
+type columnAdd struct {
+	Table    string
+	SQLite   string
+	Postgres string
+}
+
+func (c columnAdd) postgres() string {
+	if c.Postgres != "" {
+		return c.Postgres
+	}
+	return c.SQLite
+}
+
+// migrations is the schema history. Versions up to 21 predate numbered
+// migrations and were applied ad hoc; the baseline recreates them.
+var migrations = []migration{
+	{
+		Version:     21,
+		Description: "baseline schema",
+		SQLite: []string{
+			`CREATE TABLE IF NOT EXISTS apps (
+				id TEXT PRIMARY KEY,
+				name TEXT,
+				repo_url TEXT,
+				repo_auth_method TEXT NOT NULL DEFAULT 'public',
+				branch TEXT,
+				compose_path TEXT,
+				poll_interval TEXT,
+				last_seen_commit TEXT,
+				last_seen_commit_message TEXT,
+				last_synced_commit TEXT,
+				last_synced_commit_message TEXT,
+				last_sync_output TEXT,
+				last_sync_error TEXT,
+				last_sync_at DATETIME,
+				status TEXT,
+				gate_script TEXT NOT NULL DEFAULT '',
+				notify_webhooks TEXT NOT NULL DEFAULT '',
+				slack_webhook_url TEXT NOT NULL DEFAULT '',
+				slack_channel TEXT NOT NULL DEFAULT '',
+				signing_keys TEXT NOT NULL DEFAULT '',
+				track TEXT NOT NULL DEFAULT '',
+				watch_paths TEXT NOT NULL DEFAULT '',
+				last_seen_commit_info TEXT NOT NULL DEFAULT '',
+				profiles TEXT NOT NULL DEFAULT '',
+				notify_events TEXT NOT NULL DEFAULT '',
+				quiet_hours TEXT NOT NULL DEFAULT '',
+				env_file TEXT NOT NULL DEFAULT '',
+				preview_ttl TEXT NOT NULL DEFAULT '',
+				post_deploy TEXT NOT NULL DEFAULT '',
+				pre_deploy TEXT NOT NULL DEFAULT '',
+				priority TEXT NOT NULL DEFAULT '',
+				require_approval BOOLEAN NOT NULL DEFAULT FALSE,
+				pin_images BOOLEAN NOT NULL DEFAULT FALSE,
+				freeze_windows TEXT NOT NULL DEFAULT '',
+				docker_host TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE IF NOT EXISTS app_credentials (
+				app_id TEXT PRIMARY KEY,
+				deploy_key_ciphertext BLOB,
+				deploy_key_nonce BLOB,
+				env_ciphertext BLOB,
+				env_nonce BLOB,
+				docker_tls_ciphertext BLOB,
+				docker_tls_nonce BLOB,
+				FOREIGN KEY(app_id) REFERENCES apps(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id TEXT PRIMARY KEY,
+				created_at DATETIME NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				source TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL,
+				method TEXT NOT NULL DEFAULT '',
+				path TEXT NOT NULL DEFAULT '',
+				app_id TEXT NOT NULL DEFAULT '',
+				changes TEXT NOT NULL DEFAULT '',
+				outcome TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_app_created ON audit_log(app_id, created_at)`,
+			`CREATE TABLE IF NOT EXISTS app_revisions (
+				app_id TEXT NOT NULL,
+				revision INTEGER NOT NULL,
+				created_at DATETIME NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				source TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL DEFAULT '',
+				spec TEXT NOT NULL,
+				changes TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (app_id, revision)
+			)`,
+			`CREATE TABLE IF NOT EXISTS app_tokens (
+				id TEXT PRIMARY KEY,
+				app_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				last_used_at DATETIME,
+				FOREIGN KEY(app_id) REFERENCES apps(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_app_tokens_app ON app_tokens(app_id)`,
+			`CREATE TABLE IF NOT EXISTS sync_history (
+				id TEXT PRIMARY KEY,
+				app_id TEXT NOT NULL,
+				started_at DATETIME NOT NULL,
+				finished_at DATETIME NOT NULL,
+				sync_trigger TEXT NOT NULL DEFAULT '',
+				actor TEXT NOT NULL DEFAULT '',
+				commit_hash TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL,
+				error TEXT NOT NULL DEFAULT '',
+				rollback_of TEXT NOT NULL DEFAULT '',
+				images TEXT NOT NULL DEFAULT '',
+				attestation TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY(app_id) REFERENCES apps(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sync_history_app_started ON sync_history(app_id, started_at)`,
+			`CREATE TABLE IF NOT EXISTS scheduled_syncs (
+				id TEXT PRIMARY KEY,
+				app_id TEXT NOT NULL,
+				run_at DATETIME NOT NULL,
+				commit_hash TEXT NOT NULL DEFAULT '',
+				actor TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(app_id) REFERENCES apps(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_scheduled_syncs_run_at ON scheduled_syncs(run_at)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE IF NOT EXISTS apps (
+				id TEXT PRIMARY KEY,
+				name TEXT,
+				repo_url TEXT,
+				repo_auth_method TEXT NOT NULL DEFAULT 'public',
+				branch TEXT,
+				compose_path TEXT,
+				poll_interval TEXT,
+				last_seen_commit TEXT,
+				last_seen_commit_message TEXT NOT NULL DEFAULT '',
+				last_synced_commit TEXT NOT NULL DEFAULT '',
+				last_synced_commit_message TEXT NOT NULL DEFAULT '',
+				last_sync_output TEXT NOT NULL DEFAULT '',
+				last_sync_error TEXT NOT NULL DEFAULT '',
+				last_sync_at TIMESTAMPTZ,
+				status TEXT,
+				gate_script TEXT NOT NULL DEFAULT '',
+				notify_webhooks TEXT NOT NULL DEFAULT '',
+				slack_webhook_url TEXT NOT NULL DEFAULT '',
+				slack_channel TEXT NOT NULL DEFAULT '',
+				signing_keys TEXT NOT NULL DEFAULT '',
+				track TEXT NOT NULL DEFAULT '',
+				watch_paths TEXT NOT NULL DEFAULT '',
+				last_seen_commit_info TEXT NOT NULL DEFAULT '',
+				profiles TEXT NOT NULL DEFAULT '',
+				notify_events TEXT NOT NULL DEFAULT '',
+				quiet_hours TEXT NOT NULL DEFAULT '',
+				env_file TEXT NOT NULL DEFAULT '',
+				preview_ttl TEXT NOT NULL DEFAULT '',
+				post_deploy TEXT NOT NULL DEFAULT '',
+				pre_deploy TEXT NOT NULL DEFAULT '',
+				priority TEXT NOT NULL DEFAULT '',
+				require_approval BOOLEAN NOT NULL DEFAULT FALSE,
+				pin_images BOOLEAN NOT NULL DEFAULT FALSE,
+				freeze_windows TEXT NOT NULL DEFAULT '',
+				docker_host TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE IF NOT EXISTS app_credentials (
+				app_id TEXT PRIMARY KEY REFERENCES apps(id) ON DELETE CASCADE,
+				deploy_key_ciphertext BYTEA,
+				deploy_key_nonce BYTEA,
+				env_ciphertext BYTEA,
+				env_nonce BYTEA,
+				docker_tls_ciphertext BYTEA,
+				docker_tls_nonce BYTEA
+			)`,
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id TEXT PRIMARY KEY,
+				created_at TIMESTAMPTZ NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				source TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL,
+				method TEXT NOT NULL DEFAULT '',
+				path TEXT NOT NULL DEFAULT '',
+				app_id TEXT NOT NULL DEFAULT '',
+				changes TEXT NOT NULL DEFAULT '',
+				outcome TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_app_created ON audit_log(app_id, created_at)`,
+			`CREATE TABLE IF NOT EXISTS app_revisions (
+				app_id TEXT NOT NULL,
+				revision INTEGER NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				source TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL DEFAULT '',
+				spec TEXT NOT NULL,
+				changes TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (app_id, revision)
+			)`,
+			`CREATE TABLE IF NOT EXISTS app_tokens (
+				id TEXT PRIMARY KEY,
+				app_id TEXT NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL,
+				last_used_at TIMESTAMPTZ
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_app_tokens_app ON app_tokens(app_id)`,
+			`CREATE TABLE IF NOT EXISTS sync_history (
+				id TEXT PRIMARY KEY,
+				app_id TEXT NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+				started_at TIMESTAMPTZ NOT NULL,
+				finished_at TIMESTAMPTZ NOT NULL,
+				sync_trigger TEXT NOT NULL DEFAULT '',
+				actor TEXT NOT NULL DEFAULT '',
+				commit_hash TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL,
+				error TEXT NOT NULL DEFAULT '',
+				rollback_of TEXT NOT NULL DEFAULT '',
+				images TEXT NOT NULL DEFAULT '',
+				attestation TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sync_history_app_started ON sync_history(app_id, started_at)`,
+			`CREATE TABLE IF NOT EXISTS scheduled_syncs (
+				id TEXT PRIMARY KEY,
+				app_id TEXT NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+				run_at TIMESTAMPTZ NOT NULL,
+				commit_hash TEXT NOT NULL DEFAULT '',
+				actor TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_scheduled_syncs_run_at ON scheduled_syncs(run_at)`,
+		},
+		Columns: []columnAdd{
+			{Table: "apps", SQLite: "repo_auth_method TEXT NOT NULL DEFAULT 'public'"},
+			{Table: "apps", SQLite: "last_seen_commit_message TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "last_synced_commit TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "last_synced_commit_message TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "last_sync_output TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "last_sync_error TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "gate_script TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "notify_webhooks TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "slack_webhook_url TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "slack_channel TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "signing_keys TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "track TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "watch_paths TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "last_seen_commit_info TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "profiles TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "notify_events TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "quiet_hours TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "env_file TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "preview_ttl TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "post_deploy TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "pre_deploy TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "priority TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "require_approval BOOLEAN NOT NULL DEFAULT FALSE"},
+			{Table: "apps", SQLite: "pin_images BOOLEAN NOT NULL DEFAULT FALSE"},
+			{Table: "apps", SQLite: "freeze_windows TEXT NOT NULL DEFAULT ''"},
+			{Table: "apps", SQLite: "docker_host TEXT NOT NULL DEFAULT ''"},
+			{Table: "app_credentials", SQLite: "env_ciphertext BLOB", Postgres: "env_ciphertext BYTEA"},
+			{Table: "app_credentials", SQLite: "env_nonce BLOB", Postgres: "env_nonce BYTEA"},
+			{Table: "app_credentials", SQLite: "docker_tls_ciphertext BLOB", Postgres: "docker_tls_ciphertext BYTEA"},
+			{Table: "app_credentials", SQLite: "docker_tls_nonce BLOB", Postgres: "docker_tls_nonce BYTEA"},
+			{Table: "sync_history", SQLite: "images TEXT NOT NULL DEFAULT ''"},
+			{Table: "sync_history", SQLite: "attestation TEXT NOT NULL DEFAULT ''"},
+		},
+	},
+	{
+		Version:     22,
+		Description: "count consecutive sync failures",
+		SQLite:      []string{`ALTER TABLE apps ADD COLUMN sync_failures INTEGER NOT NULL DEFAULT 0`},
+		Postgres:    []string{`ALTER TABLE apps ADD COLUMN sync_failures INTEGER NOT NULL DEFAULT 0`},
+	},
+	{
+		Version:     23,
+		Description: "store SOPS decryption keys",
+		SQLite: []string{
+			`ALTER TABLE app_credentials ADD COLUMN sops_key_ciphertext BLOB`,
+			`ALTER TABLE app_credentials ADD COLUMN sops_key_nonce BLOB`,
+		},
+		Postgres: []string{
+			`ALTER TABLE app_credentials ADD COLUMN sops_key_ciphertext BYTEA`,
+			`ALTER TABLE app_credentials ADD COLUMN sops_key_nonce BYTEA`,
+		},
+	},
+	{
+		Version:     24,
+		Description: "keep the output of each sync",
+		SQLite:      []string{`ALTER TABLE sync_history ADD COLUMN output TEXT NOT NULL DEFAULT ''`},
+		Postgres:    []string{`ALTER TABLE sync_history ADD COLUMN output TEXT NOT NULL DEFAULT ''`},
+	},
+	{
+		Version:     25,
+		Description: "pause automatic syncs",
+		SQLite:      []string{`ALTER TABLE apps ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE`},
+		Postgres:    []string{`ALTER TABLE apps ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE`},
+	},
+	{
+		Version:     26,
+		Description: "split the output of each sync into steps",
+		SQLite:      []string{`ALTER TABLE sync_history ADD COLUMN steps TEXT NOT NULL DEFAULT ''`},
+		Postgres:    []string{`ALTER TABLE sync_history ADD COLUMN steps TEXT NOT NULL DEFAULT ''`},
+	},
+}
+
+// pendingMigrations returns the migrations not yet recorded, in order.
+func pendingMigrations(applied map[int]bool) []migration {
+	var pending []migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
@@ -38,179 +38,107 @@ func NewPostgresStore(ctx context.Context, connString string) (*PostgresStore, e
 }
 
 func (s *PostgresStore) migrate(ctx context.Context) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS apps (
-		id TEXT PRIMARY KEY,
-		name TEXT,
-		repo_url TEXT,
-		repo_auth_method TEXT NOT NULL DEFAULT 'public',
-		branch TEXT,
-		compose_path TEXT,
-		poll_interval TEXT,
-		last_seen_commit TEXT,
-		last_seen_commit_message TEXT NOT NULL DEFAULT '',
-		last_synced_commit TEXT NOT NULL DEFAULT '',
-		last_synced_commit_message TEXT NOT NULL DEFAULT '',
-		last_sync_output TEXT NOT NULL DEFAULT '',
-		last_sync_error TEXT NOT NULL DEFAULT '',
-		last_sync_at TIMESTAMPTZ,
-		status TEXT
-	);
-	`
-	if _, err := s.pool.Exec(ctx, query); err != nil {
+	// Run every pending migration in one transaction so a failed upgrade
+	// leaves the database as it was. The advisory lock serialises
+	// concurrent migrators.
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
 		return err
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE apps ADD COLUMN IF NOT EXISTS repo_auth_method TEXT NOT NULL DEFAULT 'public'`); err != nil {
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('conops_schema'))`); err != nil {
 		return err
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE apps ADD COLUMN IF NOT EXISTS last_seen_commit_message TEXT NOT NULL DEFAULT ''`); err != nil {
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
 		return err
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE apps ADD COLUMN IF NOT EXISTS last_synced_commit TEXT NOT NULL DEFAULT ''`); err != nil {
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, description TEXT NOT NULL, applied_at TIMESTAMPTZ NOT NULL)`); err != nil {
 		return err
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE apps ADD COLUMN IF NOT EXISTS last_synced_commit_message TEXT NOT NULL DEFAULT ''`); err != nil {
+	current, applied, err := readPostgresSchema(ctx, tx)
+	if err != nil {
 		return err
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE apps ADD COLUMN IF NOT EXISTS last_sync_output TEXT NOT NULL DEFAULT ''`); err != nil {
+	if err := checkSchemaVersion(current); err != nil {
 		return err
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE apps ADD COLUMN IF NOT EXISTS last_sync_error TEXT NOT NULL DEFAULT ''`); err != nil {
-		return err
+
+	for _, m := range pendingMigrations(applied) {
+		for _, statement := range m.Postgres {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+			}
+		}
+		for _, column := range m.Columns {
+			if _, err := tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", column.Table, column.postgres())); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+			}
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, description, applied_at) VALUES ($1, $2, $3)`, m.Version, m.Description, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
 	}
 
-	credentialsQuery := `
-	CREATE TABLE IF NOT EXISTS app_credentials (
-		app_id TEXT PRIMARY KEY REFERENCES apps(id) ON DELETE CASCADE,
-		deploy_key_ciphertext BYTEA,
-		deploy_key_nonce BYTEA,
-		env_ciphertext BYTEA,
-		env_nonce BYTEA
-	);
-	`
-	if _, err := s.pool.Exec(ctx, credentialsQuery); err != nil {
-		return err
+	// Releases before numbered migrations only read schema_version; keep
+	// it current so they still refuse a newer database.
+	if current != SchemaVersion {
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_version`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_version (version) VALUES ($1)`, SchemaVersion); err != nil {
+			return err
+		}
 	}
 
-	// Add env_ciphertext and env_nonce to app_credentials for legacy schemas
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE app_credentials ADD COLUMN IF NOT EXISTS env_ciphertext BYTEA`); err != nil {
-		return err
+	return tx.Commit(ctx)
+}
+
+type postgresQueryer interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// readPostgresSchema returns the database's schema version and the
+// numbered migrations recorded in it.
+func readPostgresSchema(ctx context.Context, q postgresQueryer) (int, map[int]bool, error) {
+	var legacy int
+	if err := q.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&legacy); err != nil {
+		return 0, nil, fmt.Errorf("failed to read schema version: %w", err)
 	}
-	if _, err := s.pool.Exec(ctx, `ALTER TABLE app_credentials ADD COLUMN IF NOT EXISTS env_nonce BYTEA`); err != nil {
-		return err
+	rows, err := q.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read schema migrations: %w", err)
 	}
-
-	return nil
+	defer rows.Close()
+	applied := make(map[int]bool)
+	current := legacy
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return 0, nil, fmt.Errorf("failed to read schema migrations: %w", err)
+		}
+		applied[version] = true
+		current = max(current, version)
+	}
+	return current, applied, rows.Err()
 }
 
 func (s *PostgresStore) CreateApp(ctx context.Context, app *api.App) error {
-	query := `
-	INSERT INTO apps (
-		id,
-		name,
-		repo_url,
-		repo_auth_method,
-		branch,
-		compose_path,
-		poll_interval,
-		last_seen_commit,
-		last_seen_commit_message,
-		last_synced_commit,
-		last_synced_commit_message,
-		last_sync_output,
-		last_sync_error,
-		last_sync_at,
-		status
-	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-	`
-	_, err := s.pool.Exec(
-		ctx,
-		query,
-		app.ID,
-		app.Name,
-		app.RepoURL,
-		app.RepoAuthMethod,
-		app.Branch,
-		app.ComposePath,
-		app.PollInterval,
-		app.LastSeenCommit,
-		app.LastSeenCommitMessage,
-		app.LastSyncedCommit,
-		app.LastSyncedCommitMessage,
-		app.LastSyncOutput,
-		app.LastSyncError,
-		app.LastSyncAt,
-		app.Status,
-	)
+	_, err := s.pool.Exec(ctx, insertAppQuery(postgresPlaceholder), appInsertValues(app)...)
 	return err
 }
 
 func (s *PostgresStore) GetApp(ctx context.Context, id string) (*api.App, error) {
-	query := `
-	SELECT
-		id,
-		name,
-		repo_url,
-		repo_auth_method,
-		branch,
-		compose_path,
-		poll_interval,
-		COALESCE(last_seen_commit, ''),
-		COALESCE(last_seen_commit_message, ''),
-		COALESCE(last_synced_commit, ''),
-		COALESCE(last_synced_commit_message, ''),
-		COALESCE(last_sync_output, ''),
-		COALESCE(last_sync_error, ''),
-		last_sync_at,
-		status
+	query := `SELECT` + appColumns + `
 	FROM apps
 	WHERE id = $1
 	`
-	row := s.pool.QueryRow(ctx, query, id)
-
-	var app api.App
-	err := row.Scan(
-		&app.ID,
-		&app.Name,
-		&app.RepoURL,
-		&app.RepoAuthMethod,
-		&app.Branch,
-		&app.ComposePath,
-		&app.PollInterval,
-		&app.LastSeenCommit,
-		&app.LastSeenCommitMessage,
-		&app.LastSyncedCommit,
-		&app.LastSyncedCommitMessage,
-		&app.LastSyncOutput,
-		&app.LastSyncError,
-		&app.LastSyncAt,
-		&app.Status,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return &app, nil
+	return scanApp(s.pool.QueryRow(ctx, query, id))
 }
 
 func (s *PostgresStore) ListApps(ctx context.Context) ([]*api.App, error) {
-	query := `
-	SELECT
-		id,
-		name,
-		repo_url,
-		repo_auth_method,
-		branch,
-		compose_path,
-		poll_interval,
-		COALESCE(last_seen_commit, ''),
-		COALESCE(last_seen_commit_message, ''),
-		COALESCE(last_synced_commit, ''),
-		COALESCE(last_synced_commit_message, ''),
-		COALESCE(last_sync_output, ''),
-		COALESCE(last_sync_error, ''),
-		last_sync_at,
-		status
+	query := `SELECT` + appColumns + `
 	FROM apps
 	`
 	rows, err := s.pool.Query(ctx, query)
@@ -221,35 +149,63 @@ func (s *PostgresStore) ListApps(ctx context.Context) ([]*api.App, error) {
 
 	var apps []*api.App
 	for rows.Next() {
-		var app api.App
-		if err := rows.Scan(
-			&app.ID,
-			&app.Name,
-			&app.RepoURL,
-			&app.RepoAuthMethod,
-			&app.Branch,
-			&app.ComposePath,
-			&app.PollInterval,
-			&app.LastSeenCommit,
-			&app.LastSeenCommitMessage,
-			&app.LastSyncedCommit,
-			&app.LastSyncedCommitMessage,
-			&app.LastSyncOutput,
-			&app.LastSyncError,
-			&app.LastSyncAt,
-			&app.Status,
-		); err != nil {
+		app, err := scanApp(rows)
+		if err != nil {
 			continue
 		}
-		apps = append(apps, &app)
+		apps = append(apps, app)
 	}
 	return apps, nil
 }
 
+func (s *PostgresStore) QueryApps(ctx context.Context, query AppQuery) ([]*api.App, int, error) {
+	where, order, args, err := queryAppsClauses(query, postgresPlaceholder)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM apps `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	statement := `SELECT` + queryAppsColumns(query) + `
+	FROM apps
+	` + where + `
+	` + order
+	if query.Limit > 0 {
+		statement += fmt.Sprintf(` LIMIT %s OFFSET %s`, postgresPlaceholder(len(args)+1), postgresPlaceholder(len(args)+2))
+		args = append(args, query.Limit, query.Offset)
+	}
+	rows, err := s.pool.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	apps := []*api.App{}
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			continue
+		}
+		apps = append(apps, app)
+	}
+	return apps, total, rows.Err()
+}
+
 func (s *PostgresStore) DeleteApp(ctx context.Context, id string) error {
 	if _, err := s.pool.Exec(ctx, `DELETE FROM app_credentials WHERE app_id = $1`, id); err != nil {
 		return err
 	}
+	if _, err := s.pool.Exec(ctx, `DELETE FROM app_tokens WHERE app_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := s.pool.Exec(ctx, `DELETE FROM sync_history WHERE app_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := s.pool.Exec(ctx, `DELETE FROM scheduled_syncs WHERE app_id = $1`, id); err != nil {
+		return err
+	}
 	query := `DELETE FROM apps WHERE id = $1`
 	ct, err := s.pool.Exec(ctx, query, id)
 	if err != nil {
@@ -261,17 +217,65 @@ func (s *PostgresStore) DeleteApp(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *PostgresStore) UpdateApp(ctx context.Context, id, name, branch, composePath, pollInterval string) error {
+func (s *PostgresStore) UpdateApp(ctx context.Context, app *api.App) error {
 	query := `
 	UPDATE apps
 	SET
 		name = $1,
 		branch = $2,
 		compose_path = $3,
-		poll_interval = $4
-	WHERE id = $5
+		poll_interval = $4,
+		gate_script = $5,
+		notify_webhooks = $6,
+		slack_webhook_url = $7,
+		slack_channel = $8,
+		signing_keys = $9,
+		track = $10,
+		watch_paths = $11,
+		profiles = $12,
+		notify_events = $13,
+		quiet_hours = $14,
+		env_file = $15,
+		preview_ttl = $16,
+		post_deploy = $17,
+		pre_deploy = $18,
+		priority = $19,
+		require_approval = $20,
+		pin_images = $21,
+		freeze_windows = $22,
+		docker_host = $23,
+		repo_auth_method = $24
+	WHERE id = $25
 	`
-	ct, err := s.pool.Exec(ctx, query, name, branch, composePath, pollInterval, id)
+	ct, err := s.pool.Exec(
+		ctx,
+		query,
+		app.Name,
+		app.Branch,
+		app.ComposePath,
+		app.PollInterval,
+		app.GateScript,
+		encodeStringList(app.NotifyWebhooks),
+		app.SlackWebhookURL,
+		app.SlackChannel,
+		encodeStringList(app.SigningKeys),
+		app.Track,
+		encodeStringList(app.WatchPaths),
+		encodeStringList(app.Profiles),
+		encodeStringList(app.NotifyEvents),
+		app.QuietHours,
+		app.EnvFile,
+		app.PreviewTTL,
+		encodeStringList(app.PostDeploy),
+		encodeStringList(app.PreDeploy),
+		app.Priority,
+		app.RequireApproval,
+		app.PinImages,
+		encodeStringList(app.FreezeWindows),
+		app.DockerHost,
+		app.RepoAuthMethod,
+		app.ID,
+	)
 	if err != nil {
 		return err
 	}
@@ -283,26 +287,30 @@ func (s *PostgresStore) UpdateApp(ctx context.Context, id, name, branch, compose
 
 func (s *PostgresStore) UpsertAppCredential(ctx context.Context, credential *AppCredential) error {
 	query := `
-	INSERT INTO app_credentials (app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO app_credentials (app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce, docker_tls_ciphertext, docker_tls_nonce, sops_key_ciphertext, sops_key_nonce)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	ON CONFLICT (app_id) DO UPDATE SET
 		deploy_key_ciphertext = CASE WHEN EXCLUDED.deploy_key_ciphertext IS NOT NULL THEN EXCLUDED.deploy_key_ciphertext ELSE app_credentials.deploy_key_ciphertext END,
 		deploy_key_nonce = CASE WHEN EXCLUDED.deploy_key_nonce IS NOT NULL THEN EXCLUDED.deploy_key_nonce ELSE app_credentials.deploy_key_nonce END,
 		env_ciphertext = CASE WHEN EXCLUDED.env_ciphertext IS NOT NULL THEN EXCLUDED.env_ciphertext ELSE app_credentials.env_ciphertext END,
-		env_nonce = CASE WHEN EXCLUDED.env_nonce IS NOT NULL THEN EXCLUDED.env_nonce ELSE app_credentials.env_nonce END
+		env_nonce = CASE WHEN EXCLUDED.env_nonce IS NOT NULL THEN EXCLUDED.env_nonce ELSE app_credentials.env_nonce END,
+		docker_tls_ciphertext = CASE WHEN EXCLUDED.docker_tls_ciphertext IS NOT NULL THEN EXCLUDED.docker_tls_ciphertext ELSE app_credentials.docker_tls_ciphertext END,
+		docker_tls_nonce = CASE WHEN EXCLUDED.docker_tls_nonce IS NOT NULL THEN EXCLUDED.docker_tls_nonce ELSE app_credentials.docker_tls_nonce END,
+		sops_key_ciphertext = CASE WHEN EXCLUDED.sops_key_ciphertext IS NOT NULL THEN EXCLUDED.sops_key_ciphertext ELSE app_credentials.sops_key_ciphertext END,
+		sops_key_nonce = CASE WHEN EXCLUDED.sops_key_nonce IS NOT NULL THEN EXCLUDED.sops_key_nonce ELSE app_credentials.sops_key_nonce END
 	`
-	_, err := s.pool.Exec(ctx, query, credential.AppID, credential.DeployKeyCiphertext, credential.DeployKeyNonce, credential.EnvCiphertext, credential.EnvNonce)
+	_, err := s.pool.Exec(ctx, query, credential.AppID, credential.DeployKeyCiphertext, credential.DeployKeyNonce, credential.EnvCiphertext, credential.EnvNonce, credential.DockerTLSCiphertext, credential.DockerTLSNonce, credential.SOPSKeyCiphertext, credential.SOPSKeyNonce)
 	return err
 }
 
 func (s *PostgresStore) GetAppCredential(ctx context.Context, id string) (*AppCredential, error) {
-	query := `SELECT app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce FROM app_credentials WHERE app_id = $1`
+	query := `SELECT app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce, docker_tls_ciphertext, docker_tls_nonce, sops_key_ciphertext, sops_key_nonce FROM app_credentials WHERE app_id = $1`
 	row := s.pool.QueryRow(ctx, query, id)
 
 	credential := &AppCredential{}
-	var deployKeyCiphertext, deployKeyNonce, envCiphertext, envNonce []byte
+	var deployKeyCiphertext, deployKeyNonce, envCiphertext, envNonce, dockerTLSCiphertext, dockerTLSNonce, sopsKeyCiphertext, sopsKeyNonce []byte
 
-	if err := row.Scan(&credential.AppID, &deployKeyCiphertext, &deployKeyNonce, &envCiphertext, &envNonce); err != nil {
+	if err := row.Scan(&credential.AppID, &deployKeyCiphertext, &deployKeyNonce, &envCiphertext, &envNonce, &dockerTLSCiphertext, &dockerTLSNonce, &sopsKeyCiphertext, &sopsKeyNonce); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrCredentialNotFound
 		}
@@ -312,6 +320,10 @@ func (s *PostgresStore) GetAppCredential(ctx context.Context, id string) (*AppCr
 	credential.DeployKeyNonce = deployKeyNonce
 	credential.EnvCiphertext = envCiphertext
 	credential.EnvNonce = envNonce
+	credential.DockerTLSCiphertext = dockerTLSCiphertext
+	credential.DockerTLSNonce = dockerTLSNonce
+	credential.SOPSKeyCiphertext = sopsKeyCiphertext
+	credential.SOPSKeyNonce = sopsKeyNonce
 
 	return credential, nil
 }
@@ -339,9 +351,66 @@ func (s *PostgresStore) UpdateAppCredentials(ctx context.Context, appID string,
 	return nil
 }
 
-func (s *PostgresStore) UpdateAppCommit(ctx context.Context, id, commitHash, commitMessage string) error {
-	query := `UPDATE apps SET last_seen_commit = $1, last_seen_commit_message = $2, status = $3 WHERE id = $4`
-	ct, err := s.pool.Exec(ctx, query, commitHash, commitMessage, "pending", id)
+func (s *PostgresStore) UpdateAppDockerTLS(ctx context.Context, appID string, ciphertext, nonce []byte) error {
+	query := `
+	INSERT INTO app_credentials (app_id, docker_tls_ciphertext, docker_tls_nonce)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (app_id) DO UPDATE SET
+		docker_tls_ciphertext = EXCLUDED.docker_tls_ciphertext,
+		docker_tls_nonce = EXCLUDED.docker_tls_nonce
+	`
+	_, err := s.pool.Exec(ctx, query, appID, ciphertext, nonce)
+	return err
+}
+
+func (s *PostgresStore) UpdateAppDeployKey(ctx context.Context, appID string, ciphertext, nonce []byte) error {
+	query := `
+	INSERT INTO app_credentials (app_id, deploy_key_ciphertext, deploy_key_nonce)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (app_id) DO UPDATE SET
+		deploy_key_ciphertext = EXCLUDED.deploy_key_ciphertext,
+		deploy_key_nonce = EXCLUDED.deploy_key_nonce
+	`
+	_, err := s.pool.Exec(ctx, query, appID, ciphertext, nonce)
+	return err
+}
+
+func (s *PostgresStore) UpdateAppSOPSKey(ctx context.Context, appID string, ciphertext, nonce []byte) error {
+	query := `
+	INSERT INTO app_credentials (app_id, sops_key_ciphertext, sops_key_nonce)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (app_id) DO UPDATE SET
+		sops_key_ciphertext = EXCLUDED.sops_key_ciphertext,
+		sops_key_nonce = EXCLUDED.sops_key_nonce
+	`
+	_, err := s.pool.Exec(ctx, query, appID, ciphertext, nonce)
+	return err
+}
+
+func (s *PostgresStore) UpdateAppCommit(ctx context.Context, id, commitHash, commitMessage, status string) error {
+	query := `UPDATE apps SET last_seen_commit = $1, last_seen_commit_message = $2, last_seen_commit_info = '', status = $3 WHERE id = $4`
+	ct, err := s.pool.Exec(ctx, query, commitHash, commitMessage, status, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("app not found")
+	}
+	return nil
+}
+
+func (s *PostgresStore) SkipAppCommit(ctx context.Context, id, commitHash, commitMessage string) error {
+	query := `
+	UPDATE apps
+	SET
+		last_seen_commit = $1,
+		last_seen_commit_message = $2,
+		last_synced_commit = $1,
+		last_synced_commit_message = $2,
+		last_seen_commit_info = ''
+	WHERE id = $3
+	`
+	ct, err := s.pool.Exec(ctx, query, commitHash, commitMessage, id)
 	if err != nil {
 		return err
 	}
@@ -351,6 +420,12 @@ func (s *PostgresStore) UpdateAppCommit(ctx context.Context, id, commitHash, com
 	return nil
 }
 
+func (s *PostgresStore) UpdateAppCommitInfo(ctx context.Context, id, commitHash string, info *api.CommitInfo) error {
+	query := `UPDATE apps SET last_seen_commit_info = $1 WHERE id = $2 AND last_seen_commit = $3`
+	_, err := s.pool.Exec(ctx, query, encodeCommitInfo(info), id, commitHash)
+	return err
+}
+
 func (s *PostgresStore) UpdateAppSyncResult(
 	ctx context.Context,
 	id string,
@@ -369,7 +444,8 @@ func (s *PostgresStore) UpdateAppSyncResult(
 		last_synced_commit = $3,
 		last_synced_commit_message = $4,
 		last_sync_output = $5,
-		last_sync_error = $6
+		last_sync_error = $6,
+		sync_failures = CASE $1 WHEN 'error' THEN sync_failures + 1 WHEN 'synced' THEN 0 ELSE sync_failures END
 	WHERE id = $7
 	`
 	ct, err := s.pool.Exec(
@@ -436,6 +512,302 @@ func (s *PostgresStore) UpdateAppStatus(ctx context.Context, id, status string,
 	return nil
 }
 
+func (s *PostgresStore) UpdateAppPaused(ctx context.Context, id string, paused bool) error {
+	ct, err := s.pool.Exec(ctx, `UPDATE apps SET paused = $1 WHERE id = $2`, paused, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("app not found")
+	}
+	return nil
+}
+
+func (s *PostgresStore) ResetAppSyncFailures(ctx context.Context, id string) error {
+	ct, err := s.pool.Exec(ctx, `UPDATE apps SET sync_failures = 0 WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return fmt.Errorf("app not found")
+	}
+	return nil
+}
+
+func (s *PostgresStore) CreateAuditEntry(ctx context.Context, entry *api.AuditEntry) error {
+	changes, err := encodeAuditChanges(entry.Changes)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO audit_log (id, created_at, actor, source, action, method, path, app_id, changes, outcome)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = s.pool.Exec(
+		ctx,
+		query,
+		entry.ID,
+		entry.CreatedAt,
+		entry.Actor,
+		entry.Source,
+		entry.Action,
+		entry.Method,
+		entry.Path,
+		entry.AppID,
+		changes,
+		entry.Outcome,
+	)
+	return err
+}
+
+func (s *PostgresStore) ListAuditEntries(ctx context.Context, appID string, limit int) ([]*api.AuditEntry, error) {
+	query := `
+	SELECT id, created_at, actor, source, action, method, path, app_id, changes, outcome
+	FROM audit_log
+	WHERE ($1 = '' OR app_id = $1)
+	ORDER BY created_at DESC
+	LIMIT $2
+	`
+	rows, err := s.pool.Query(ctx, query, appID, normalizeAuditLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*api.AuditEntry
+	for rows.Next() {
+		var entry api.AuditEntry
+		var changes string
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.CreatedAt,
+			&entry.Actor,
+			&entry.Source,
+			&entry.Action,
+			&entry.Method,
+			&entry.Path,
+			&entry.AppID,
+			&changes,
+			&entry.Outcome,
+		); err != nil {
+			return nil, err
+		}
+		entry.Changes = decodeAuditChanges(changes)
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// CreateAppRevision stores revision as the next version of its app and sets
+// revision.Revision.
+func (s *PostgresStore) CreateAppRevision(ctx context.Context, revision *api.AppRevision) error {
+	spec, changes, err := encodeRevision(revision)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO app_revisions (app_id, revision, created_at, actor, source, action, spec, changes)
+	SELECT $1::text, COALESCE(MAX(revision), 0) + 1, $2::timestamptz, $3::text, $4::text, $5::text, $6::text, $7::text
+	FROM app_revisions WHERE app_id = $1
+	RETURNING revision
+	`
+	return s.pool.QueryRow(
+		ctx,
+		query,
+		revision.AppID,
+		revision.CreatedAt,
+		revision.Actor,
+		revision.Source,
+		revision.Action,
+		spec,
+		changes,
+	).Scan(&revision.Revision)
+}
+
+func (s *PostgresStore) ListAppRevisions(ctx context.Context, appID string, limit int) ([]*api.AppRevision, error) {
+	query := `
+	SELECT app_id, revision, created_at, actor, source, action, spec, changes
+	FROM app_revisions
+	WHERE app_id = $1
+	ORDER BY revision DESC
+	LIMIT $2
+	`
+	rows, err := s.pool.Query(ctx, query, appID, normalizeAuditLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*api.AppRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *PostgresStore) CreateSyncRecord(ctx context.Context, record *api.SyncRecord) error {
+	query := `INSERT INTO sync_history (` + syncRecordColumns + `, output, steps) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := s.pool.Exec(ctx, query, append(syncRecordValues(record), record.Output, encodeSyncSteps(record.Steps))...)
+	return err
+}
+
+func (s *PostgresStore) GetSyncRecord(ctx context.Context, appID, id string) (*api.SyncRecord, error) {
+	query := `SELECT ` + syncRecordColumns + `, output, steps FROM sync_history WHERE app_id = $1 AND id = $2`
+	var output, steps string
+	record, err := scanSyncRecord(s.pool.QueryRow(ctx, query, appID, id), &output, &steps)
+	if err == pgx.ErrNoRows {
+		return nil, ErrSyncRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.Output = output
+	record.Steps = decodeSyncSteps(steps)
+	return record, nil
+}
+
+func (s *PostgresStore) ListSyncRecords(ctx context.Context, appID string, limit, offset int) ([]*api.SyncRecord, error) {
+	query := `
+	SELECT ` + syncRecordColumns + `
+	FROM sync_history
+	WHERE app_id = $1
+	ORDER BY started_at DESC
+	LIMIT $2 OFFSET $3
+	`
+	rows, err := s.pool.Query(ctx, query, appID, normalizeAuditLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*api.SyncRecord
+	for rows.Next() {
+		record, err := scanSyncRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *PostgresStore) CreateAppToken(ctx context.Context, token *api.AppToken, tokenHash string) error {
+	query := `
+	INSERT INTO app_tokens (id, app_id, name, token_hash, scopes, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := s.pool.Exec(ctx, query, token.ID, token.AppID, token.Name, tokenHash, encodeStringList(token.Scopes), token.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) ListAppTokens(ctx context.Context, appID string) ([]*api.AppToken, error) {
+	query := `
+	SELECT id, app_id, name, scopes, created_at, last_used_at
+	FROM app_tokens
+	WHERE app_id = $1
+	ORDER BY created_at
+	`
+	rows, err := s.pool.Query(ctx, query, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*api.AppToken
+	for rows.Next() {
+		token, err := scanAppToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *PostgresStore) GetAppTokenByHash(ctx context.Context, tokenHash string) (*api.AppToken, error) {
+	query := `
+	SELECT id, app_id, name, scopes, created_at, last_used_at
+	FROM app_tokens
+	WHERE token_hash = $1
+	`
+	token, err := scanAppToken(s.pool.QueryRow(ctx, query, tokenHash))
+	if err == pgx.ErrNoRows {
+		return nil, ErrTokenNotFound
+	}
+	return token, err
+}
+
+func (s *PostgresStore) DeleteAppToken(ctx context.Context, appID, id string) error {
+	ct, err := s.pool.Exec(ctx, `DELETE FROM app_tokens WHERE app_id = $1 AND id = $2`, appID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) CreateScheduledSync(ctx context.Context, schedule *api.ScheduledSync) error {
+	query := `INSERT INTO scheduled_syncs (` + scheduledSyncColumns + `) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := s.pool.Exec(ctx, query, schedule.ID, schedule.AppID, schedule.RunAt, schedule.Commit, schedule.Actor, schedule.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) ListScheduledSyncs(ctx context.Context, appID string) ([]*api.ScheduledSync, error) {
+	query := `
+	SELECT ` + scheduledSyncColumns + `
+	FROM scheduled_syncs
+	WHERE $1 = '' OR app_id = $1
+	ORDER BY run_at
+	`
+	rows, err := s.pool.Query(ctx, query, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*api.ScheduledSync
+	for rows.Next() {
+		schedule, err := scanScheduledSync(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (s *PostgresStore) DeleteScheduledSync(ctx context.Context, appID, id string) error {
+	ct, err := s.pool.Exec(ctx, `DELETE FROM scheduled_syncs WHERE app_id = $1 AND id = $2`, appID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrScheduledSyncNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) TouchAppToken(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := s.pool.Exec(ctx, `UPDATE app_tokens SET last_used_at = $1 WHERE id = $2`, usedAt, id)
+	return err
+}
+
+// SchemaVersion reports the schema version recorded in the database.
+func (s *PostgresStore) SchemaVersion(ctx context.Context) (int, error) {
+	version, _, err := readPostgresSchema(ctx, s.pool)
+	return version, err
+}
+
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
 func (s *PostgresStore) Close() {
 	s.pool.Close()
 }
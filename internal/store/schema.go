@@ -0,0 +1,27 @@
+package store
+
+import "fmt"
+
+// SchemaVersion is the database schema version this binary creates and
+// understands: the version of the last entry in migrations. Older binaries
+// refuse to run against a database migrated past their own.
+const SchemaVersion = 26
+
+// SchemaTooNewError is returned when the database was migrated by a newer
+// release than the running binary.
+type SchemaTooNewError struct {
+	Database  int
+	Supported int
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than this binary supports (%d); upgrade conops instead of downgrading", e.Database, e.Supported)
+}
+
+// checkSchemaVersion rejects databases written by a newer schema.
+func checkSchemaVersion(current int) error {
+	if current > SchemaVersion {
+		return &SchemaTooNewError{Database: current, Supported: SchemaVersion}
+	}
+	return nil
+}
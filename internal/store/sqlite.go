@@ -17,7 +17,7 @@ type SQLiteStore struct {
 
 // NewSQLiteStore initializes the SQLite database and creates necessary tables.
 func NewSQLiteStore(path string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite", path)
+	db, err := sql.Open("sqlite", sqliteDSN(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -26,185 +26,134 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	if _, err := db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	if err := migrateSQLite(db); err != nil {
+		return nil, err
 	}
 
-	query := `
-	CREATE TABLE IF NOT EXISTS apps (
-		id TEXT PRIMARY KEY,
-		name TEXT,
-		repo_url TEXT,
-		repo_auth_method TEXT NOT NULL DEFAULT 'public',
-		branch TEXT,
-		compose_path TEXT,
-		poll_interval TEXT,
-		last_seen_commit TEXT,
-		last_seen_commit_message TEXT,
-		last_synced_commit TEXT,
-		last_synced_commit_message TEXT,
-		last_sync_output TEXT,
-		last_sync_error TEXT,
-		last_sync_at DATETIME,
-		status TEXT
-	);
-	`
-	if _, err := db.Exec(query); err != nil {
-		return nil, fmt.Errorf("failed to create apps table: %w", err)
-	}
+	return &SQLiteStore{db: db}, nil
+}
 
-	if err := addSQLiteColumnIfMissing(db, "apps", "repo_auth_method TEXT NOT NULL DEFAULT 'public'"); err != nil {
-		return nil, err
+// sqliteDSN applies per-connection pragmas through the DSN so every pooled
+// connection gets them, not just the one that happens to run a PRAGMA
+// statement. foreign_keys enables cascading deletes and busy_timeout makes
+// concurrent writers, such as overlapping syncs or `conops migrate` during
+// an upgrade, wait instead of failing immediately with SQLITE_BUSY.
+func sqliteDSN(path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+}
+
+// migrateSQLite applies pending migrations in one transaction, so a failed
+// upgrade leaves the database as it was.
+func migrateSQLite(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL);`); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
 	}
-	if err := addSQLiteColumnIfMissing(db, "apps", "last_seen_commit_message TEXT NOT NULL DEFAULT ''"); err != nil {
-		return nil, err
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, description TEXT NOT NULL, applied_at DATETIME NOT NULL);`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
 	}
-	if err := addSQLiteColumnIfMissing(db, "apps", "last_synced_commit TEXT NOT NULL DEFAULT ''"); err != nil {
-		return nil, err
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
 	}
-	if err := addSQLiteColumnIfMissing(db, "apps", "last_synced_commit_message TEXT NOT NULL DEFAULT ''"); err != nil {
-		return nil, err
+	defer tx.Rollback()
+
+	// Take the write lock before reading the version so two processes
+	// cannot migrate concurrently.
+	if _, err := tx.Exec(`UPDATE schema_version SET version = version;`); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
 	}
-	if err := addSQLiteColumnIfMissing(db, "apps", "last_sync_output TEXT NOT NULL DEFAULT ''"); err != nil {
-		return nil, err
+	current, applied, err := readSQLiteSchema(tx)
+	if err != nil {
+		return err
 	}
-	if err := addSQLiteColumnIfMissing(db, "apps", "last_sync_error TEXT NOT NULL DEFAULT ''"); err != nil {
-		return nil, err
+	if err := checkSchemaVersion(current); err != nil {
+		return err
 	}
 
-	credentialsQuery := `
-	CREATE TABLE IF NOT EXISTS app_credentials (
-		app_id TEXT PRIMARY KEY,
-		deploy_key_ciphertext BLOB,
-		deploy_key_nonce BLOB,
-		env_ciphertext BLOB,
-		env_nonce BLOB,
-		FOREIGN KEY(app_id) REFERENCES apps(id) ON DELETE CASCADE
-	);
-	`
-	if _, err := db.Exec(credentialsQuery); err != nil {
-		return nil, fmt.Errorf("failed to create app_credentials table: %w", err)
+	for _, m := range pendingMigrations(applied) {
+		for _, statement := range m.SQLite {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+			}
+		}
+		for _, column := range m.Columns {
+			if err := addSQLiteColumnIfMissing(tx, column.Table, column.SQLite); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?);`, m.Version, m.Description, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
 	}
 
-	// Add env_ciphertext and env_nonce columns to app_credentials
-	if err := addSQLiteColumnIfMissing(db, "app_credentials", "env_ciphertext BLOB"); err != nil {
-		return nil, err
+	// Releases before numbered migrations only read schema_version; keep
+	// it current so they still refuse a newer database.
+	if current != SchemaVersion {
+		if _, err := tx.Exec(`DELETE FROM schema_version;`); err != nil {
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?);`, SchemaVersion); err != nil {
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
 	}
-	if err := addSQLiteColumnIfMissing(db, "app_credentials", "env_nonce BLOB"); err != nil {
-		return nil, err
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
 	}
+	return nil
+}
 
-	return &SQLiteStore{db: db}, nil
+type sqliteQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// readSQLiteSchema returns the database's schema version and the numbered
+// migrations recorded in it. Databases from before numbered migrations
+// have only a schema_version.
+func readSQLiteSchema(q sqliteQueryer) (int, map[int]bool, error) {
+	var legacy int
+	if err := q.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version;`).Scan(&legacy); err != nil {
+		return 0, nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	rows, err := q.Query(`SELECT version FROM schema_migrations;`)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read schema migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	current := legacy
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return 0, nil, fmt.Errorf("failed to read schema migrations: %w", err)
+		}
+		applied[version] = true
+		current = max(current, version)
+	}
+	return current, applied, rows.Err()
 }
 
 func (s *SQLiteStore) CreateApp(ctx context.Context, app *api.App) error {
-	query := `
-	INSERT INTO apps (
-		id,
-		name,
-		repo_url,
-		repo_auth_method,
-		branch,
-		compose_path,
-		poll_interval,
-		last_seen_commit,
-		last_seen_commit_message,
-		last_synced_commit,
-		last_synced_commit_message,
-		last_sync_output,
-		last_sync_error,
-		last_sync_at,
-		status
-	)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	_, err := s.db.ExecContext(
-		ctx,
-		query,
-		app.ID,
-		app.Name,
-		app.RepoURL,
-		app.RepoAuthMethod,
-		app.Branch,
-		app.ComposePath,
-		app.PollInterval,
-		app.LastSeenCommit,
-		app.LastSeenCommitMessage,
-		app.LastSyncedCommit,
-		app.LastSyncedCommitMessage,
-		app.LastSyncOutput,
-		app.LastSyncError,
-		app.LastSyncAt,
-		app.Status,
-	)
+	_, err := s.db.ExecContext(ctx, insertAppQuery(sqlitePlaceholder), appInsertValues(app)...)
 	return err
 }
 
 func (s *SQLiteStore) GetApp(ctx context.Context, id string) (*api.App, error) {
-	query := `
-	SELECT
-		id,
-		name,
-		repo_url,
-		repo_auth_method,
-		branch,
-		compose_path,
-		poll_interval,
-		COALESCE(last_seen_commit, ''),
-		COALESCE(last_seen_commit_message, ''),
-		COALESCE(last_synced_commit, ''),
-		COALESCE(last_synced_commit_message, ''),
-		COALESCE(last_sync_output, ''),
-		COALESCE(last_sync_error, ''),
-		last_sync_at,
-		status
+	query := `SELECT` + appColumns + `
 	FROM apps
 	WHERE id = ?
 	`
-	row := s.db.QueryRowContext(ctx, query, id)
-
-	var app api.App
-	err := row.Scan(
-		&app.ID,
-		&app.Name,
-		&app.RepoURL,
-		&app.RepoAuthMethod,
-		&app.Branch,
-		&app.ComposePath,
-		&app.PollInterval,
-		&app.LastSeenCommit,
-		&app.LastSeenCommitMessage,
-		&app.LastSyncedCommit,
-		&app.LastSyncedCommitMessage,
-		&app.LastSyncOutput,
-		&app.LastSyncError,
-		&app.LastSyncAt,
-		&app.Status,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return &app, nil
+	return scanApp(s.db.QueryRowContext(ctx, query, id))
 }
 
 func (s *SQLiteStore) ListApps(ctx context.Context) ([]*api.App, error) {
-	query := `
-	SELECT
-		id,
-		name,
-		repo_url,
-		repo_auth_method,
-		branch,
-		compose_path,
-		poll_interval,
-		COALESCE(last_seen_commit, ''),
-		COALESCE(last_seen_commit_message, ''),
-		COALESCE(last_synced_commit, ''),
-		COALESCE(last_synced_commit_message, ''),
-		COALESCE(last_sync_output, ''),
-		COALESCE(last_sync_error, ''),
-		last_sync_at,
-		status
+	query := `SELECT` + appColumns + `
 	FROM apps
 	`
 	rows, err := s.db.QueryContext(ctx, query)
@@ -215,31 +164,50 @@ func (s *SQLiteStore) ListApps(ctx context.Context) ([]*api.App, error) {
 
 	var apps []*api.App
 	for rows.Next() {
-		var app api.App
-		if err := rows.Scan(
-			&app.ID,
-			&app.Name,
-			&app.RepoURL,
-			&app.RepoAuthMethod,
-			&app.Branch,
-			&app.ComposePath,
-			&app.PollInterval,
-			&app.LastSeenCommit,
-			&app.LastSeenCommitMessage,
-			&app.LastSyncedCommit,
-			&app.LastSyncedCommitMessage,
-			&app.LastSyncOutput,
-			&app.LastSyncError,
-			&app.LastSyncAt,
-			&app.Status,
-		); err != nil {
+		app, err := scanApp(rows)
+		if err != nil {
 			continue
 		}
-		apps = append(apps, &app)
+		apps = append(apps, app)
 	}
 	return apps, nil
 }
 
+func (s *SQLiteStore) QueryApps(ctx context.Context, query AppQuery) ([]*api.App, int, error) {
+	where, order, args, err := queryAppsClauses(query, sqlitePlaceholder)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM apps `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	statement := `SELECT` + queryAppsColumns(query) + `
+	FROM apps
+	` + where + `
+	` + order
+	if query.Limit > 0 {
+		statement += ` LIMIT ? OFFSET ?`
+		args = append(args, query.Limit, query.Offset)
+	}
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	apps := []*api.App{}
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			continue
+		}
+		apps = append(apps, app)
+	}
+	return apps, total, rows.Err()
+}
+
 func (s *SQLiteStore) DeleteApp(ctx context.Context, id string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -250,6 +218,15 @@ func (s *SQLiteStore) DeleteApp(ctx context.Context, id string) error {
 	if _, err := tx.ExecContext(ctx, `DELETE FROM app_credentials WHERE app_id = ?`, id); err != nil {
 		return err
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM app_tokens WHERE app_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sync_history WHERE app_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scheduled_syncs WHERE app_id = ?`, id); err != nil {
+		return err
+	}
 
 	query := `DELETE FROM apps WHERE id = ?`
 	result, err := tx.ExecContext(ctx, query, id)
@@ -267,17 +244,65 @@ func (s *SQLiteStore) DeleteApp(ctx context.Context, id string) error {
 	return tx.Commit()
 }
 
-func (s *SQLiteStore) UpdateApp(ctx context.Context, id, name, branch, composePath, pollInterval string) error {
+func (s *SQLiteStore) UpdateApp(ctx context.Context, app *api.App) error {
 	query := `
 	UPDATE apps
 	SET
 		name = ?,
 		branch = ?,
 		compose_path = ?,
-		poll_interval = ?
+		poll_interval = ?,
+		gate_script = ?,
+		notify_webhooks = ?,
+		slack_webhook_url = ?,
+		slack_channel = ?,
+		signing_keys = ?,
+		track = ?,
+		watch_paths = ?,
+		profiles = ?,
+		notify_events = ?,
+		quiet_hours = ?,
+		env_file = ?,
+		preview_ttl = ?,
+		post_deploy = ?,
+		pre_deploy = ?,
+		priority = ?,
+		require_approval = ?,
+		pin_images = ?,
+		freeze_windows = ?,
+		docker_host = ?,
+		repo_auth_method = ?
 	WHERE id = ?
 	`
-	result, err := s.db.ExecContext(ctx, query, name, branch, composePath, pollInterval, id)
+	result, err := s.db.ExecContext(
+		ctx,
+		query,
+		app.Name,
+		app.Branch,
+		app.ComposePath,
+		app.PollInterval,
+		app.GateScript,
+		encodeStringList(app.NotifyWebhooks),
+		app.SlackWebhookURL,
+		app.SlackChannel,
+		encodeStringList(app.SigningKeys),
+		app.Track,
+		encodeStringList(app.WatchPaths),
+		encodeStringList(app.Profiles),
+		encodeStringList(app.NotifyEvents),
+		app.QuietHours,
+		app.EnvFile,
+		app.PreviewTTL,
+		encodeStringList(app.PostDeploy),
+		encodeStringList(app.PreDeploy),
+		app.Priority,
+		app.RequireApproval,
+		app.PinImages,
+		encodeStringList(app.FreezeWindows),
+		app.DockerHost,
+		app.RepoAuthMethod,
+		app.ID,
+	)
 	if err != nil {
 		return err
 	}
@@ -293,26 +318,30 @@ func (s *SQLiteStore) UpdateApp(ctx context.Context, id, name, branch, composePa
 
 func (s *SQLiteStore) UpsertAppCredential(ctx context.Context, credential *AppCredential) error {
 	query := `
-	INSERT INTO app_credentials (app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce)
-	VALUES (?, ?, ?, ?, ?)
+	INSERT INTO app_credentials (app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce, docker_tls_ciphertext, docker_tls_nonce, sops_key_ciphertext, sops_key_nonce)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(app_id) DO UPDATE SET
 		deploy_key_ciphertext = CASE WHEN excluded.deploy_key_ciphertext IS NOT NULL THEN excluded.deploy_key_ciphertext ELSE app_credentials.deploy_key_ciphertext END,
 		deploy_key_nonce = CASE WHEN excluded.deploy_key_nonce IS NOT NULL THEN excluded.deploy_key_nonce ELSE app_credentials.deploy_key_nonce END,
 		env_ciphertext = CASE WHEN excluded.env_ciphertext IS NOT NULL THEN excluded.env_ciphertext ELSE app_credentials.env_ciphertext END,
-		env_nonce = CASE WHEN excluded.env_nonce IS NOT NULL THEN excluded.env_nonce ELSE app_credentials.env_nonce END
+		env_nonce = CASE WHEN excluded.env_nonce IS NOT NULL THEN excluded.env_nonce ELSE app_credentials.env_nonce END,
+		docker_tls_ciphertext = CASE WHEN excluded.docker_tls_ciphertext IS NOT NULL THEN excluded.docker_tls_ciphertext ELSE app_credentials.docker_tls_ciphertext END,
+		docker_tls_nonce = CASE WHEN excluded.docker_tls_nonce IS NOT NULL THEN excluded.docker_tls_nonce ELSE app_credentials.docker_tls_nonce END,
+		sops_key_ciphertext = CASE WHEN excluded.sops_key_ciphertext IS NOT NULL THEN excluded.sops_key_ciphertext ELSE app_credentials.sops_key_ciphertext END,
+		sops_key_nonce = CASE WHEN excluded.sops_key_nonce IS NOT NULL THEN excluded.sops_key_nonce ELSE app_credentials.sops_key_nonce END
 	`
-	_, err := s.db.ExecContext(ctx, query, credential.AppID, credential.DeployKeyCiphertext, credential.DeployKeyNonce, credential.EnvCiphertext, credential.EnvNonce)
+	_, err := s.db.ExecContext(ctx, query, credential.AppID, credential.DeployKeyCiphertext, credential.DeployKeyNonce, credential.EnvCiphertext, credential.EnvNonce, credential.DockerTLSCiphertext, credential.DockerTLSNonce, credential.SOPSKeyCiphertext, credential.SOPSKeyNonce)
 	return err
 }
 
 func (s *SQLiteStore) GetAppCredential(ctx context.Context, id string) (*AppCredential, error) {
-	query := `SELECT app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce FROM app_credentials WHERE app_id = ?`
+	query := `SELECT app_id, deploy_key_ciphertext, deploy_key_nonce, env_ciphertext, env_nonce, docker_tls_ciphertext, docker_tls_nonce, sops_key_ciphertext, sops_key_nonce FROM app_credentials WHERE app_id = ?`
 	row := s.db.QueryRowContext(ctx, query, id)
 
 	credential := &AppCredential{}
-	var deployKeyCiphertext, deployKeyNonce, envCiphertext, envNonce []byte
+	var deployKeyCiphertext, deployKeyNonce, envCiphertext, envNonce, dockerTLSCiphertext, dockerTLSNonce, sopsKeyCiphertext, sopsKeyNonce []byte
 
-	if err := row.Scan(&credential.AppID, &deployKeyCiphertext, &deployKeyNonce, &envCiphertext, &envNonce); err != nil {
+	if err := row.Scan(&credential.AppID, &deployKeyCiphertext, &deployKeyNonce, &envCiphertext, &envNonce, &dockerTLSCiphertext, &dockerTLSNonce, &sopsKeyCiphertext, &sopsKeyNonce); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrCredentialNotFound
 		}
@@ -323,6 +352,10 @@ func (s *SQLiteStore) GetAppCredential(ctx context.Context, id string) (*AppCred
 	credential.DeployKeyNonce = deployKeyNonce
 	credential.EnvCiphertext = envCiphertext
 	credential.EnvNonce = envNonce
+	credential.DockerTLSCiphertext = dockerTLSCiphertext
+	credential.DockerTLSNonce = dockerTLSNonce
+	credential.SOPSKeyCiphertext = sopsKeyCiphertext
+	credential.SOPSKeyNonce = sopsKeyNonce
 
 	return credential, nil
 }
@@ -354,9 +387,70 @@ func (s *SQLiteStore) UpdateAppCredentials(ctx context.Context, appID string, en
 	return nil
 }
 
-func (s *SQLiteStore) UpdateAppCommit(ctx context.Context, id, commitHash, commitMessage string) error {
-	query := `UPDATE apps SET last_seen_commit = ?, last_seen_commit_message = ?, status = ? WHERE id = ?`
-	result, err := s.db.ExecContext(ctx, query, commitHash, commitMessage, "pending", id)
+func (s *SQLiteStore) UpdateAppDockerTLS(ctx context.Context, appID string, ciphertext, nonce []byte) error {
+	query := `
+	INSERT INTO app_credentials (app_id, docker_tls_ciphertext, docker_tls_nonce)
+	VALUES (?, ?, ?)
+	ON CONFLICT(app_id) DO UPDATE SET
+		docker_tls_ciphertext = excluded.docker_tls_ciphertext,
+		docker_tls_nonce = excluded.docker_tls_nonce
+	`
+	_, err := s.db.ExecContext(ctx, query, appID, ciphertext, nonce)
+	return err
+}
+
+func (s *SQLiteStore) UpdateAppDeployKey(ctx context.Context, appID string, ciphertext, nonce []byte) error {
+	query := `
+	INSERT INTO app_credentials (app_id, deploy_key_ciphertext, deploy_key_nonce)
+	VALUES (?, ?, ?)
+	ON CONFLICT(app_id) DO UPDATE SET
+		deploy_key_ciphertext = excluded.deploy_key_ciphertext,
+		deploy_key_nonce = excluded.deploy_key_nonce
+	`
+	_, err := s.db.ExecContext(ctx, query, appID, ciphertext, nonce)
+	return err
+}
+
+func (s *SQLiteStore) UpdateAppSOPSKey(ctx context.Context, appID string, ciphertext, nonce []byte) error {
+	query := `
+	INSERT INTO app_credentials (app_id, sops_key_ciphertext, sops_key_nonce)
+	VALUES (?, ?, ?)
+	ON CONFLICT(app_id) DO UPDATE SET
+		sops_key_ciphertext = excluded.sops_key_ciphertext,
+		sops_key_nonce = excluded.sops_key_nonce
+	`
+	_, err := s.db.ExecContext(ctx, query, appID, ciphertext, nonce)
+	return err
+}
+
+func (s *SQLiteStore) UpdateAppCommit(ctx context.Context, id, commitHash, commitMessage, status string) error {
+	query := `UPDATE apps SET last_seen_commit = ?, last_seen_commit_message = ?, last_seen_commit_info = '', status = ? WHERE id = ?`
+	result, err := s.db.ExecContext(ctx, query, commitHash, commitMessage, status, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("app not found")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SkipAppCommit(ctx context.Context, id, commitHash, commitMessage string) error {
+	query := `
+	UPDATE apps
+	SET
+		last_seen_commit = ?,
+		last_seen_commit_message = ?,
+		last_synced_commit = ?,
+		last_synced_commit_message = ?,
+		last_seen_commit_info = ''
+	WHERE id = ?
+	`
+	result, err := s.db.ExecContext(ctx, query, commitHash, commitMessage, commitHash, commitMessage, id)
 	if err != nil {
 		return err
 	}
@@ -370,6 +464,12 @@ func (s *SQLiteStore) UpdateAppCommit(ctx context.Context, id, commitHash, commi
 	return nil
 }
 
+func (s *SQLiteStore) UpdateAppCommitInfo(ctx context.Context, id, commitHash string, info *api.CommitInfo) error {
+	query := `UPDATE apps SET last_seen_commit_info = ? WHERE id = ? AND last_seen_commit = ?`
+	_, err := s.db.ExecContext(ctx, query, encodeCommitInfo(info), id, commitHash)
+	return err
+}
+
 func (s *SQLiteStore) UpdateAppSyncResult(
 	ctx context.Context,
 	id string,
@@ -388,7 +488,8 @@ func (s *SQLiteStore) UpdateAppSyncResult(
 		last_synced_commit = ?,
 		last_synced_commit_message = ?,
 		last_sync_output = ?,
-		last_sync_error = ?
+		last_sync_error = ?,
+		sync_failures = CASE ? WHEN 'error' THEN sync_failures + 1 WHEN 'synced' THEN 0 ELSE sync_failures END
 	WHERE id = ?
 	`
 	result, err := s.db.ExecContext(
@@ -400,6 +501,7 @@ func (s *SQLiteStore) UpdateAppSyncResult(
 		syncedCommitMessage,
 		syncOutput,
 		syncError,
+		status,
 		id,
 	)
 	if err != nil {
@@ -471,13 +573,326 @@ func (s *SQLiteStore) UpdateAppStatus(ctx context.Context, id, status string, la
 	return nil
 }
 
+func (s *SQLiteStore) UpdateAppPaused(ctx context.Context, id string, paused bool) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE apps SET paused = ? WHERE id = ?`, paused, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("app not found")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ResetAppSyncFailures(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE apps SET sync_failures = 0 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("app not found")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) CreateAuditEntry(ctx context.Context, entry *api.AuditEntry) error {
+	changes, err := encodeAuditChanges(entry.Changes)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO audit_log (id, created_at, actor, source, action, method, path, app_id, changes, outcome)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = s.db.ExecContext(
+		ctx,
+		query,
+		entry.ID,
+		entry.CreatedAt,
+		entry.Actor,
+		entry.Source,
+		entry.Action,
+		entry.Method,
+		entry.Path,
+		entry.AppID,
+		changes,
+		entry.Outcome,
+	)
+	return err
+}
+
+func (s *SQLiteStore) ListAuditEntries(ctx context.Context, appID string, limit int) ([]*api.AuditEntry, error) {
+	query := `
+	SELECT id, created_at, actor, source, action, method, path, app_id, changes, outcome
+	FROM audit_log
+	WHERE (? = '' OR app_id = ?)
+	ORDER BY created_at DESC
+	LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, appID, appID, normalizeAuditLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*api.AuditEntry
+	for rows.Next() {
+		var entry api.AuditEntry
+		var changes string
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.CreatedAt,
+			&entry.Actor,
+			&entry.Source,
+			&entry.Action,
+			&entry.Method,
+			&entry.Path,
+			&entry.AppID,
+			&changes,
+			&entry.Outcome,
+		); err != nil {
+			return nil, err
+		}
+		entry.Changes = decodeAuditChanges(changes)
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// CreateAppRevision stores revision as the next version of its app and sets
+// revision.Revision.
+func (s *SQLiteStore) CreateAppRevision(ctx context.Context, revision *api.AppRevision) error {
+	spec, changes, err := encodeRevision(revision)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO app_revisions (app_id, revision, created_at, actor, source, action, spec, changes)
+	SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ?, ?, ?
+	FROM app_revisions WHERE app_id = ?
+	RETURNING revision
+	`
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		revision.AppID,
+		revision.CreatedAt,
+		revision.Actor,
+		revision.Source,
+		revision.Action,
+		spec,
+		changes,
+		revision.AppID,
+	).Scan(&revision.Revision)
+}
+
+func (s *SQLiteStore) ListAppRevisions(ctx context.Context, appID string, limit int) ([]*api.AppRevision, error) {
+	query := `
+	SELECT app_id, revision, created_at, actor, source, action, spec, changes
+	FROM app_revisions
+	WHERE app_id = ?
+	ORDER BY revision DESC
+	LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, appID, normalizeAuditLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*api.AppRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLiteStore) CreateSyncRecord(ctx context.Context, record *api.SyncRecord) error {
+	query := `INSERT INTO sync_history (` + syncRecordColumns + `, output, steps) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, query, append(syncRecordValues(record), record.Output, encodeSyncSteps(record.Steps))...)
+	return err
+}
+
+func (s *SQLiteStore) GetSyncRecord(ctx context.Context, appID, id string) (*api.SyncRecord, error) {
+	query := `SELECT ` + syncRecordColumns + `, output, steps FROM sync_history WHERE app_id = ? AND id = ?`
+	var output, steps string
+	record, err := scanSyncRecord(s.db.QueryRowContext(ctx, query, appID, id), &output, &steps)
+	if err == sql.ErrNoRows {
+		return nil, ErrSyncRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.Output = output
+	record.Steps = decodeSyncSteps(steps)
+	return record, nil
+}
+
+func (s *SQLiteStore) ListSyncRecords(ctx context.Context, appID string, limit, offset int) ([]*api.SyncRecord, error) {
+	query := `
+	SELECT ` + syncRecordColumns + `
+	FROM sync_history
+	WHERE app_id = ?
+	ORDER BY started_at DESC
+	LIMIT ? OFFSET ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, appID, normalizeAuditLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*api.SyncRecord
+	for rows.Next() {
+		record, err := scanSyncRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteStore) CreateAppToken(ctx context.Context, token *api.AppToken, tokenHash string) error {
+	query := `
+	INSERT INTO app_tokens (id, app_id, name, token_hash, scopes, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.ExecContext(ctx, query, token.ID, token.AppID, token.Name, tokenHash, encodeStringList(token.Scopes), token.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) ListAppTokens(ctx context.Context, appID string) ([]*api.AppToken, error) {
+	query := `
+	SELECT id, app_id, name, scopes, created_at, last_used_at
+	FROM app_tokens
+	WHERE app_id = ?
+	ORDER BY created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*api.AppToken
+	for rows.Next() {
+		token, err := scanAppToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *SQLiteStore) GetAppTokenByHash(ctx context.Context, tokenHash string) (*api.AppToken, error) {
+	query := `
+	SELECT id, app_id, name, scopes, created_at, last_used_at
+	FROM app_tokens
+	WHERE token_hash = ?
+	`
+	token, err := scanAppToken(s.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrTokenNotFound
+	}
+	return token, err
+}
+
+func (s *SQLiteStore) DeleteAppToken(ctx context.Context, appID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM app_tokens WHERE app_id = ? AND id = ?`, appID, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) CreateScheduledSync(ctx context.Context, schedule *api.ScheduledSync) error {
+	query := `INSERT INTO scheduled_syncs (` + scheduledSyncColumns + `) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, query, schedule.ID, schedule.AppID, schedule.RunAt, schedule.Commit, schedule.Actor, schedule.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) ListScheduledSyncs(ctx context.Context, appID string) ([]*api.ScheduledSync, error) {
+	query := `
+	SELECT ` + scheduledSyncColumns + `
+	FROM scheduled_syncs
+	WHERE ? = '' OR app_id = ?
+	ORDER BY run_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, appID, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*api.ScheduledSync
+	for rows.Next() {
+		schedule, err := scanScheduledSync(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteScheduledSync(ctx context.Context, appID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM scheduled_syncs WHERE app_id = ? AND id = ?`, appID, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrScheduledSyncNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) TouchAppToken(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE app_tokens SET last_used_at = ? WHERE id = ?`, usedAt, id)
+	return err
+}
+
+// SchemaVersion reports the schema version recorded in the database.
+func (s *SQLiteStore) SchemaVersion(ctx context.Context) (int, error) {
+	version, _, err := readSQLiteSchema(s.db)
+	return version, err
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 func (s *SQLiteStore) Close() {
 	s.db.Close()
 }
 
-func addSQLiteColumnIfMissing(db *sql.DB, tableName, columnDDL string) error {
+func addSQLiteColumnIfMissing(tx *sql.Tx, tableName, columnDDL string) error {
 	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", tableName, columnDDL)
-	if _, err := db.Exec(query); err != nil {
+	if _, err := tx.Exec(query); err != nil {
 		errText := strings.ToLower(err.Error())
 		if strings.Contains(errText, "duplicate column name") {
 			return nil
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/conops/conops/internal/controller"
+)
+
+// dashboardFailureLimit caps the failing apps listed on the dashboard.
+const dashboardFailureLimit = 10
+
+// dashboardCheckTimeout bounds the controller checks run for a dashboard
+// refresh.
+const dashboardCheckTimeout = 5 * time.Second
+
+// DashboardView is the fleet-wide overview on the landing page.
+type DashboardView struct {
+	Total      int
+	Synced     int
+	InProgress int // pending or syncing
+	Failing    int // error or blocked_unsigned
+	Held       int // stopped or awaiting approval
+
+	// Failures lists the failing apps, most recent failure first.
+	Failures []FailureView
+
+	// Controller checks, sorted by name; empty when the probes are not set.
+	Checks  []CheckView
+	Healthy bool
+	Role    string // "leader" or "standby"; empty without leader election
+
+	LastPoll         ActivityView
+	LastNotification ActivityView
+}
+
+// FailureView is a failing app on the dashboard.
+type FailureView struct {
+	ID         string
+	Name       string
+	Status     string
+	Error      string
+	Failures   int
+	At         string
+	AtRelative string
+	failedAt   time.Time
+}
+
+// CheckView is the result of one controller health check.
+type CheckView struct {
+	Name   string
+	Result string
+	OK     bool
+}
+
+// ActivityView is the controller's most recent poll or notification.
+type ActivityView struct {
+	Seen       bool
+	AppID      string
+	AppName    string
+	Detail     string
+	Error      string
+	At         string
+	AtRelative string
+}
+
+// ServeDashboardPage handles the dashboard, the UI's landing page.
+func (h *Handler) ServeDashboardPage(w http.ResponseWriter, r *http.Request) {
+	data := AppsPageData{
+		Page: "dashboard",
+	}
+	if err := h.Tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ServeDashboardFragment handles the HTMX request for the dashboard's
+// figures.
+func (h *Handler) ServeDashboardFragment(w http.ResponseWriter, r *http.Request) {
+	data := AppsPageData{
+		Page:      "dashboard",
+		Dashboard: h.loadDashboard(r.Context()),
+	}
+	if err := h.Tmpl.ExecuteTemplate(w, "dashboard-content", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) loadDashboard(ctx context.Context) DashboardView {
+	var view DashboardView
+	names := make(map[string]string)
+	for _, app := range h.Registry.List() {
+		names[app.ID] = app.Name
+		view.Total++
+		switch app.Status {
+		case "synced":
+			view.Synced++
+		case "pending", "syncing":
+			view.InProgress++
+		case "error", "blocked_unsigned":
+			view.Failing++
+			view.Failures = append(view.Failures, FailureView{
+				ID:         app.ID,
+				Name:       app.Name,
+				Status:     app.Status,
+				Error:      app.LastSyncError,
+				Failures:   app.SyncFailures,
+				At:         formatTime(app.LastSyncAt),
+				AtRelative: relativeTime(app.LastSyncAt),
+				failedAt:   app.LastSyncAt,
+			})
+		default:
+			view.Held++
+		}
+	}
+	sort.SliceStable(view.Failures, func(i, j int) bool {
+		return view.Failures[i].failedAt.After(view.Failures[j].failedAt)
+	})
+	if len(view.Failures) > dashboardFailureLimit {
+		view.Failures = view.Failures[:dashboardFailureLimit]
+	}
+
+	if h.Probes != nil {
+		ctx, cancel := context.WithTimeout(ctx, dashboardCheckTimeout)
+		defer cancel()
+		checks := h.Probes.LiveChecks()
+		for name, result := range h.Probes.ReadyChecks(ctx) {
+			checks[name] = result
+		}
+		view.Healthy = true
+		for name, result := range checks {
+			ok := result == controller.ProbeOK
+			view.Healthy = view.Healthy && ok
+			view.Checks = append(view.Checks, CheckView{Name: name, Result: result, OK: ok})
+		}
+		sort.Slice(view.Checks, func(i, j int) bool {
+			return view.Checks[i].Name < view.Checks[j].Name
+		})
+		if h.Probes.Role != nil {
+			view.Role = h.Probes.Role()
+		}
+	}
+
+	if h.Watcher != nil {
+		if poll := h.Watcher.LastPoll(); !poll.At.IsZero() {
+			view.LastPoll = ActivityView{
+				Seen:       true,
+				AppID:      poll.AppID,
+				AppName:    names[poll.AppID],
+				Error:      poll.Error,
+				At:         formatTime(poll.At),
+				AtRelative: relativeTime(poll.At),
+			}
+		}
+	}
+	if delivery := h.Registry.Notifier().LastDelivery(); !delivery.At.IsZero() {
+		view.LastNotification = ActivityView{
+			Seen:       true,
+			AppID:      delivery.AppID,
+			AppName:    names[delivery.AppID],
+			Detail:     delivery.Event + " to " + delivery.Sink,
+			Error:      delivery.Error,
+			At:         formatTime(delivery.At),
+			AtRelative: relativeTime(delivery.At),
+		}
+	}
+	return view
+}
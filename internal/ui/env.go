@@ -0,0 +1,220 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/controller"
+	"github.com/conops/conops/internal/credentials"
+	"github.com/go-chi/chi/v5"
+)
+
+// EnvVarView is one row of the environment editor. Stored values are never
+// sent to the browser: Value is only set for secret references, which are
+// not secret, and for values typed into a form that is shown again.
+type EnvVarView struct {
+	Service string
+	Key     string
+	Value   string
+	// Stored is set for rows that exist in the store; an empty Value then
+	// keeps the stored value.
+	Stored      bool
+	OrigService string
+	OrigKey     string
+}
+
+// envVar is one KEY=VALUE line of a service's environment.
+type envVar struct {
+	key, value string
+}
+
+// parseServiceEnv splits a service's dotenv content into its variables,
+// in order. Blank lines and comments are dropped.
+func parseServiceEnv(rawEnv string) []envVar {
+	var vars []envVar
+	for _, line := range strings.Split(rawEnv, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		vars = append(vars, envVar{key: strings.TrimSpace(key), value: value})
+	}
+	return vars
+}
+
+// toEnvVarViews lists the stored variables by service, masking their values.
+func toEnvVarViews(serviceEnvs map[string]string) []EnvVarView {
+	services := make([]string, 0, len(serviceEnvs))
+	for service := range serviceEnvs {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	var views []EnvVarView
+	for _, service := range services {
+		for _, variable := range parseServiceEnv(serviceEnvs[service]) {
+			view := EnvVarView{
+				Service:     service,
+				Key:         variable.key,
+				Stored:      true,
+				OrigService: service,
+				OrigKey:     variable.key,
+			}
+			if credentials.IsSecretReference(strings.Trim(strings.TrimSpace(variable.value), `"'`)) {
+				view.Value = variable.value
+			}
+			views = append(views, view)
+		}
+	}
+	return views
+}
+
+// HandleSaveEnv replaces the app's environment variables with the rows of
+// the editor on the detail page.
+func (h *Handler) HandleSaveEnv(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	stored, err := h.Registry.GetAppEnvs(id)
+	if err != nil {
+		http.Error(w, "Failed to load app environment variables", http.StatusInternalServerError)
+		return
+	}
+
+	rows := envRowsFromForm(r)
+	serviceEnvs, err := buildServiceEnvs(rows, stored)
+	if err != nil {
+		h.renderDetailPage(w, r, http.StatusBadRequest, id, rows, err.Error())
+		return
+	}
+	if err := h.Registry.UpdateApp(id, controller.AppUpdate{ServiceEnvs: serviceEnvs}); err != nil {
+		h.renderDetailPage(w, r, http.StatusConflict, id, rows, err.Error())
+		return
+	}
+
+	entry := controller.NewAuditEntry(r, controller.AuditActionUpdate, id)
+	// Env values are secrets; record that they changed, not what they are.
+	entry.Changes = map[string]api.FieldChange{"service_envs": {From: "(redacted)", To: "(redacted)"}}
+	_ = h.Registry.RecordAudit(entry)
+
+	// New values reach the containers with the next sync. A held commit
+	// still needs its approval, and a stopped app stays down.
+	if app.Status != controller.StatusAwaitingApproval && app.Status != controller.StatusStopped {
+		_ = h.Registry.UpdateStatus(id, "pending", nil)
+	}
+
+	http.Redirect(w, r, h.BasePath+"/ui/apps/"+id, http.StatusSeeOther)
+}
+
+// envRowsFromForm reads the editor's rows, which arrive as parallel lists.
+func envRowsFromForm(r *http.Request) []EnvVarView {
+	services := r.Form["env_service"]
+	keys := r.Form["env_key"]
+	values := r.Form["env_value"]
+	origServices := r.Form["env_orig_service"]
+	origKeys := r.Form["env_orig_key"]
+	field := func(list []string, i int) string {
+		if i < len(list) {
+			return list[i]
+		}
+		return ""
+	}
+
+	var rows []EnvVarView
+	for i := range services {
+		row := EnvVarView{
+			Service:     strings.TrimSpace(field(services, i)),
+			Key:         strings.TrimSpace(field(keys, i)),
+			Value:       strings.ReplaceAll(field(values, i), "\r\n", "\n"),
+			OrigService: field(origServices, i),
+			OrigKey:     field(origKeys, i),
+		}
+		row.Stored = row.OrigKey != ""
+		if row.Service == "" && row.Key == "" && row.Value == "" && !row.Stored {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// buildServiceEnvs turns editor rows into the stored format, taking the
+// stored value for rows whose value was left empty.
+func buildServiceEnvs(rows []EnvVarView, stored map[string]string) (map[string]string, error) {
+	storedValues := make(map[string]string)
+	for service, rawEnv := range stored {
+		for _, variable := range parseServiceEnv(rawEnv) {
+			storedValues[service+"\x00"+variable.key] = variable.value
+		}
+	}
+
+	var services []string
+	lines := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if row.Service == "" {
+			return nil, fmt.Errorf("variable %s needs a service", row.Key)
+		}
+		if row.Key == "" || strings.ContainsAny(row.Key, "= \t#") {
+			return nil, fmt.Errorf("invalid variable name %q for service %s", row.Key, row.Service)
+		}
+		if seen[row.Service+"\x00"+row.Key] {
+			return nil, fmt.Errorf("variable %s is set twice for service %s", row.Key, row.Service)
+		}
+		seen[row.Service+"\x00"+row.Key] = true
+
+		value := row.Value
+		if value == "" && row.Stored {
+			storedValue, ok := storedValues[row.OrigService+"\x00"+row.OrigKey]
+			if !ok {
+				return nil, fmt.Errorf("variable %s of service %s changed meanwhile; enter its value again", row.OrigKey, row.OrigService)
+			}
+			value = storedValue
+		}
+		if strings.Contains(value, "\n") {
+			return nil, fmt.Errorf("value of %s for service %s must fit on one line", row.Key, row.Service)
+		}
+		if _, ok := lines[row.Service]; !ok {
+			services = append(services, row.Service)
+		}
+		lines[row.Service] = append(lines[row.Service], row.Key+"="+value)
+	}
+
+	serviceEnvs := make(map[string]string, len(services))
+	for _, service := range services {
+		serviceEnvs[service] = strings.Join(lines[service], "\n") + "\n"
+	}
+	return serviceEnvs, nil
+}
+
+// renderDetailPage shows the detail page again with the editor's rows and
+// an error. Typed values are shown again; stored ones stay masked.
+func (h *Handler) renderDetailPage(w http.ResponseWriter, r *http.Request, statusCode int, id string, rows []EnvVarView, errorMessage string) {
+	detail, err := h.loadAppDetail(r, id)
+	if err != nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	detail.Env = rows
+	detail.LogServices = h.logServices(detail.Services)
+	w.WriteHeader(statusCode)
+	data := AppsPageData{
+		Page:  "detail",
+		App:   detail,
+		Error: errorMessage,
+	}
+	if err := h.Tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/conops/conops/internal/controller"
+)
+
+// appFilters are the status chips of the apps list, in display order.
+// Paused is a flag rather than a status, so each chip matches apps its own
+// way.
+var appFilters = []appFilter{
+	{"synced", func(app *controller.App) bool { return app.Status == "synced" }},
+	{"pending", func(app *controller.App) bool { return app.Status == "pending" || app.Status == "syncing" }},
+	{"error", func(app *controller.App) bool { return app.Status == "error" || app.Status == "blocked_unsigned" }},
+	{"paused", func(app *controller.App) bool { return app.Paused }},
+}
+
+type appFilter struct {
+	Name  string
+	Match func(app *controller.App) bool
+}
+
+// AppsFilterView is the search and status filter of the apps list.
+type AppsFilterView struct {
+	Search   string
+	Statuses []string
+	// Facets has a chip per filter, counting the apps that match the search.
+	Facets []FacetView
+	// Total is how many apps match the search before the chips apply.
+	Total int
+}
+
+// FacetView is one status chip of the apps list.
+type FacetView struct {
+	Name     string
+	Count    int
+	Selected bool
+}
+
+// Active tells whether the filter leaves out any apps.
+func (f AppsFilterView) Active() bool {
+	return f.Search != "" || len(f.Statuses) > 0
+}
+
+// parseAppsFilter reads the q and status parameters of the apps list. status
+// takes a comma-separated list of chips and may be repeated.
+func parseAppsFilter(values url.Values) (AppsFilterView, error) {
+	filter := AppsFilterView{Search: strings.TrimSpace(values.Get("q"))}
+	for _, value := range values["status"] {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if status == "" || slices.Contains(filter.Statuses, status) {
+				continue
+			}
+			if !slices.ContainsFunc(appFilters, func(f appFilter) bool { return f.Name == status }) {
+				return filter, fmt.Errorf("invalid status %q: use synced, pending, error or paused", status)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	for _, f := range appFilters {
+		filter.Facets = append(filter.Facets, FacetView{
+			Name:     f.Name,
+			Selected: slices.Contains(filter.Statuses, f.Name),
+		})
+	}
+	return filter, nil
+}
+
+// apply counts the apps of each chip and keeps the apps matching any of the
+// selected chips, or all of them when none is.
+func (f *AppsFilterView) apply(apps []*controller.App) []*controller.App {
+	f.Total = len(apps)
+	var kept []*controller.App
+	for _, app := range apps {
+		keep := len(f.Statuses) == 0
+		for i, filter := range appFilters {
+			if filter.Match(app) {
+				f.Facets[i].Count++
+				keep = keep || f.Facets[i].Selected
+			}
+		}
+		if keep {
+			kept = append(kept, app)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/conops/conops/internal/compose"
+	"github.com/conops/conops/internal/controller"
+)
+
+// Graph layout, in SVG user units. Services are laid out in columns by
+// startup depth: services with no dependencies on the left, each dependent
+// one column right of the deepest service it waits for.
+const (
+	graphNodeWidth  = 168
+	graphNodeHeight = 44
+	graphColumnGap  = 72
+	graphRowGap     = 16
+	graphPadding    = 8
+)
+
+// GraphView is the service dependency graph on the detail page.
+type GraphView struct {
+	Width      int
+	Height     int
+	NodeWidth  int
+	NodeHeight int
+	Nodes      []GraphNodeView
+	Edges      []GraphEdgeView
+}
+
+// GraphNodeView is one service box in the dependency graph.
+type GraphNodeView struct {
+	Service  string
+	X, Y     int
+	Networks string // comma-separated
+	Status   string // "running", "exited" or "" when no container exists
+	Health   string
+}
+
+// GraphEdgeView is an arrow from a dependency to the service waiting for it.
+type GraphEdgeView struct {
+	From, To       string
+	X1, Y1, X2, Y2 int
+	MidX           int
+	Condition      string // "started", "healthy" or "completed"
+	Optional       bool   // depends_on with required: false
+}
+
+// graphCache keeps rendered dependency graphs so the detail page, which
+// refreshes every few seconds, only runs `docker compose config` after a
+// sync changed the checkout.
+type graphCache struct {
+	mu      sync.Mutex
+	entries map[string]graphCacheEntry
+}
+
+type graphCacheEntry struct {
+	key   string
+	nodes []compose.ServiceNode
+}
+
+func newGraphCache() *graphCache {
+	return &graphCache{entries: make(map[string]graphCacheEntry)}
+}
+
+// serviceGraph returns app's dependency graph, reading it at most once per
+// synced commit, compose path and profile set. Failures are cached too and
+// render as no graph.
+func (h *Handler) serviceGraph(ctx context.Context, app *controller.App) []compose.ServiceNode {
+	if h.Executor == nil || h.graphs == nil || strings.TrimSpace(app.LastSyncedCommit) == "" {
+		return nil
+	}
+	key := strings.Join([]string{app.LastSyncedCommit, app.ComposePath, strings.Join(app.Profiles, ",")}, "|")
+
+	h.graphs.mu.Lock()
+	entry, ok := h.graphs.entries[app.ID]
+	h.graphs.mu.Unlock()
+	if ok && entry.key == key {
+		return entry.nodes
+	}
+
+	nodes, err := h.Executor.ServiceGraph(ctx, app.ID, app.ComposePath, app.Profiles)
+	if err != nil {
+		nodes = nil
+	}
+	h.graphs.mu.Lock()
+	h.graphs.entries[app.ID] = graphCacheEntry{key: key, nodes: nodes}
+	h.graphs.mu.Unlock()
+	return nodes
+}
+
+// toGraphView lays out nodes and colours them with the app's container
+// state. Graphs without any depends_on are not worth drawing and yield nil.
+func toGraphView(nodes []compose.ServiceNode, services []ServiceView) *GraphView {
+	byName := make(map[string]compose.ServiceNode, len(nodes))
+	hasEdges := false
+	for _, node := range nodes {
+		byName[node.Service] = node
+		if len(node.DependsOn) > 0 {
+			hasEdges = true
+		}
+	}
+	if !hasEdges {
+		return nil
+	}
+
+	depth := make(map[string]int, len(nodes))
+	visiting := make(map[string]bool)
+	var depthOf func(name string) int
+	depthOf = func(name string) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if visiting[name] {
+			// Compose rejects cycles; stay finite if one slips through.
+			return 0
+		}
+		visiting[name] = true
+		d := 0
+		for _, dependency := range byName[name].DependsOn {
+			if _, ok := byName[dependency.Service]; ok {
+				d = max(d, depthOf(dependency.Service)+1)
+			}
+		}
+		visiting[name] = false
+		depth[name] = d
+		return d
+	}
+
+	state := make(map[string]ServiceView, len(services))
+	for _, service := range services {
+		// With replicas the first unhealthy or stopped container wins.
+		if current, ok := state[service.Service]; ok && current.Status != "running" {
+			continue
+		}
+		state[service.Service] = service
+	}
+
+	graph := &GraphView{NodeWidth: graphNodeWidth, NodeHeight: graphNodeHeight}
+	rows := make(map[int]int)
+	positions := make(map[string]GraphNodeView, len(nodes))
+	for _, node := range nodes {
+		column := depthOf(node.Service)
+		view := GraphNodeView{
+			Service:  node.Service,
+			X:        graphPadding + column*(graphNodeWidth+graphColumnGap),
+			Y:        graphPadding + rows[column]*(graphNodeHeight+graphRowGap),
+			Networks: strings.Join(node.Networks, ", "),
+			Status:   state[node.Service].Status,
+			Health:   state[node.Service].Health,
+		}
+		rows[column]++
+		positions[node.Service] = view
+		graph.Nodes = append(graph.Nodes, view)
+		graph.Width = max(graph.Width, view.X+graphNodeWidth+graphPadding)
+		graph.Height = max(graph.Height, view.Y+graphNodeHeight+graphPadding)
+	}
+
+	for _, node := range nodes {
+		to := positions[node.Service]
+		for _, dependency := range node.DependsOn {
+			from, ok := positions[dependency.Service]
+			if !ok {
+				// The dependency is outside the enabled profiles.
+				continue
+			}
+			edge := GraphEdgeView{
+				From:      dependency.Service,
+				To:        node.Service,
+				X1:        from.X + graphNodeWidth,
+				Y1:        from.Y + graphNodeHeight/2,
+				X2:        to.X,
+				Y2:        to.Y + graphNodeHeight/2,
+				Condition: conditionLabel(dependency.Condition),
+				Optional:  !dependency.Required,
+			}
+			edge.MidX = (edge.X1 + edge.X2) / 2
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	return graph
+}
+
+func conditionLabel(condition string) string {
+	switch condition {
+	case "service_healthy":
+		return "healthy"
+	case "service_completed_successfully":
+		return "completed"
+	default:
+		return "started"
+	}
+}
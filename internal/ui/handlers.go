@@ -1,33 +1,66 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"net/http"
-	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/conops/conops/internal/api"
 	"github.com/conops/conops/internal/compose"
 	"github.com/conops/conops/internal/controller"
+	"github.com/conops/conops/internal/signing"
+	"github.com/conops/conops/internal/store"
 	"github.com/go-chi/chi/v5"
 )
 
+// Runtime is what the UI reads from the container runtime.
+type Runtime interface {
+	InspectProjectContainers(ctx context.Context, projectName string) ([]compose.ServiceContainer, error)
+	ServiceGraph(ctx context.Context, appID, composePath string, profiles []string) ([]compose.ServiceNode, error)
+}
+
 // Handler manages UI requests.
 type Handler struct {
 	Registry *controller.Registry
-	Executor *compose.ComposeExecutor
+	Executor Runtime
 	Tmpl     *template.Template
+	// Stats, when set, adds resource usage to the services table.
+	Stats *controller.StatsCollector
+	// Probes and Watcher, when set, add the controller's health and last
+	// poll to the dashboard.
+	Probes  *controller.Probes
+	Watcher *controller.GitWatcher
+	// LogStreamer, when the runtime supports it, lets the log viewer follow
+	// container logs.
+	LogStreamer controller.RuntimeLogStreamer
+	// BasePath is the path prefix the controller is served under, e.g.
+	// "/conops", or empty. Templates put it before every link as {{base}}.
+	BasePath string
+
+	graphs *graphCache
 }
 
 // ServiceView is the view model for a container in the detail page.
 type ServiceView struct {
-	Service string
-	Image   string
-	Status  string // "running" or "exited"
-	Health  string // "healthy", "unhealthy", "starting", or ""
-	Ports   string
+	Service  string
+	Name     string // container name
+	Image    string
+	Status   string // docker's state, e.g. "running", "restarting" or "exited"
+	Health   string // "healthy", "unhealthy", "starting", or ""
+	ExitCode int    // of an exited or dead container
+	Ports    string
+
+	// Resource usage from the latest stats snapshot; HasStats is false
+	// until the container was sampled.
+	HasStats bool
+	CPU      string // e.g. "1.2%"
+	Memory   string // e.g. "48.3 MiB / 1.9 GiB"
+	Restarts int
 }
 
 // AppView is the view model for an app in the list.
@@ -37,7 +70,9 @@ type AppView struct {
 	RepoURL             string
 	RepoShort           string // e.g. "org/repo" extracted from full URL
 	Branch              string
+	Track               string
 	Status              string
+	Paused              bool
 	LastSyncAt          string
 	LastSyncAtRelative  string
 	SyncedCommitShort   string
@@ -52,67 +87,174 @@ type AppDetailView struct {
 	RepoURL                 string
 	RepoAuth                string
 	Branch                  string
+	Track                   string
 	ComposePath             string
+	WatchPaths              []string
+	Profiles                []string
+	EnvFile                 string
 	PollInterval            string
+	PreviewTTL              string
+	PreviewExpires          string // when the sweeper removes the preview; empty until it settles
+	Priority                string // empty for normal
+	RequireApproval         bool
+	PinImages               bool
+	FreezeWindows           []string
+	DockerHost              string // empty for the controller's own daemon
+	FrozenUntil             string // set while a freeze window holds a new commit
+	PreDeploy               []string
+	PostDeploy              []string
+	SigningKeys             []string // fingerprints of allowed commit signers
+	NotifyTargets           string   // e.g. "2 webhooks, Slack #deploys"; empty without app targets
+	NotifyEvents            string   // comma-separated; empty means every event
+	QuietHours              string
 	LastSeenCommit          string
 	LastSeenCommitMessage   string
 	LastSeenCommitShort     string
+	DesiredCommit           *CommitView // nil until the watcher has read the commit
 	LastSyncedCommit        string
 	LastSyncedCommitMessage string
 	LastSyncedCommitShort   string
 	LastSyncOutput          string
 	LastSyncError           string
-	Status                  string
-	LastSyncAt              string
-	LastSyncAtRelative      string
+	SyncFailures            int
+	// NextRetry says when the reconciler retries an app in error; empty
+	// when it does not.
+	NextRetry          string
+	Status             string
+	Paused             bool // automatic syncs are held
+	LastSyncAt         string
+	LastSyncAtRelative string
 
 	// Runtime container information
 	Services       []ServiceView
 	ContainerCount int
 	RunningCount   int
-	HealthLabel    string // "Healthy", "Degraded", "Down", "No data"
-	InSync         bool   // true when desired commit == synced commit
+	HealthLabel    string     // "Healthy", "Degraded", "Down", "No data"
+	InSync         bool       // true when desired commit == synced commit
+	Graph          *GraphView // nil when no service declares depends_on
+	StatsAt        string     // relative time of the stats snapshot; empty without one
+
+	// Configuration history, newest first
+	Revisions []RevisionView
+
+	// Environment variables, values masked; only loaded for the full page
+	Env []EnvVarView
+	// Services the log viewer can follow; only loaded for the full page
+	LogServices []string
+}
+
+// RevisionView is one entry in an app's configuration history.
+type RevisionView struct {
+	Revision   int
+	Action     string
+	Actor      string
+	Source     string
+	At         string
+	AtRelative string
+	Changes    []ChangeView
+}
+
+// ChangeView is one changed field within a revision.
+type ChangeView struct {
+	Field string
+	From  string
+	To    string
+}
+
+// CommitView describes the desired commit and what it changes.
+type CommitView struct {
+	Author              string
+	Committer           string // empty when it matches the author
+	CommittedAt         string
+	CommittedAtRelative string
+	Body                string // message without the subject line
+	BaseShort           string
+	FilesChanged        int
+	Additions           int
+	Deletions           int
+	Files               []api.ChangedFile
+	MoreFiles           int // files beyond those listed
 }
 
 // AppFormData is the view model for the new app form.
 type AppFormData struct {
-	Name         string
-	RepoURL      string
-	RepoAuth     string
-	DeployKey    string
-	Branch       string
-	ComposePath  string
-	PollInterval string
-	ServiceEnvs  map[string]string
+	Name            string
+	RepoURL         string
+	RepoAuth        string
+	DeployKey       string
+	Branch          string
+	Track           string
+	ComposePath     string
+	WatchPaths      string // comma-separated
+	Profiles        string // comma-separated
+	EnvFile         string
+	PollInterval    string
+	PreviewTTL      string
+	Priority        string
+	RequireApproval bool   // hold new commits until they are approved
+	PinImages       bool   // re-apply commits with their recorded digests
+	FreezeWindows   string // one window per line
+	DockerHost      string // editable on the new app form only
+	ServiceEnvs     map[string]string
+	GateScript      string
+	PreDeploy       string // one check per line, edit form only
+	PostDeploy      string // one "service: command" per line, edit form only
+
+	// Notification routing, edit form only.
+	NotifyWebhooks  string // one per line
+	SlackWebhookURL string
+	SlackChannel    string
+	NotifyEvents    []string
+	QuietHours      string
+}
+
+// Subscribed reports whether event is selected in the form.
+func (f AppFormData) Subscribed(event string) bool {
+	for _, selected := range f.NotifyEvents {
+		if selected == event {
+			return true
+		}
+	}
+	return false
 }
 
 // AppsPageData is the data passed to the apps page template.
 type AppsPageData struct {
-	Page  string
-	Apps  []AppView
-	App   AppDetailView
-	Form  AppFormData
-	Error string
+	Page      string
+	Apps      []AppView
+	App       AppDetailView
+	Form      AppFormData
+	Dashboard DashboardView
+	Filter    AppsFilterView
+	Error     string
 }
 
 // NewHandler creates a new UI handler.
-func NewHandler(registry *controller.Registry, executor *compose.ComposeExecutor, templateDir string) (*Handler, error) {
-	tmpl, err := template.ParseGlob(filepath.Join(templateDir, "*.html"))
+func NewHandler(registry *controller.Registry, executor Runtime, templates fs.FS) (*Handler, error) {
+	logStreamer, _ := executor.(controller.RuntimeLogStreamer)
+	h := &Handler{
+		Registry:    registry,
+		Executor:    executor,
+		LogStreamer: logStreamer,
+		graphs:      newGraphCache(),
+	}
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"base": func() string { return h.BasePath },
+	}).ParseFS(templates, "*.html")
 	if err != nil {
 		return nil, err
 	}
-
-	return &Handler{
-		Registry: registry,
-		Executor: executor,
-		Tmpl:     tmpl,
-	}, nil
+	h.Tmpl = tmpl
+	return h, nil
 }
 
 // ServeAppsPage handles the main apps list page request.
 func (h *Handler) ServeAppsPage(w http.ResponseWriter, r *http.Request) {
+	// The filter fills in the search box and chips; a bad one is left out.
+	filter, _ := parseAppsFilter(r.URL.Query())
 	data := AppsPageData{
-		Page: "list",
+		Page:   "list",
+		Filter: filter,
 	}
 	if err := h.Tmpl.ExecuteTemplate(w, "base", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -142,6 +284,13 @@ func (h *Handler) ServeAppDetailPage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "App not found", http.StatusNotFound)
 		return
 	}
+	envs, err := h.Registry.GetAppEnvs(id)
+	if err != nil {
+		http.Error(w, "Failed to load app environment variables", http.StatusInternalServerError)
+		return
+	}
+	detail.Env = toEnvVarViews(envs)
+	detail.LogServices = h.logServices(detail.Services)
 
 	data := AppsPageData{
 		Page: "detail",
@@ -171,13 +320,32 @@ func (h *Handler) ServeEditAppPage(w http.ResponseWriter, r *http.Request) {
 	data := AppsPageData{
 		Page: "edit",
 		Form: AppFormData{
-			Name:         app.Name,
-			RepoURL:      app.RepoURL,
-			RepoAuth:     app.RepoAuthMethod,
-			Branch:       app.Branch,
-			ComposePath:  app.ComposePath,
-			PollInterval: app.PollInterval,
-			ServiceEnvs:  envVars,
+			Name:            app.Name,
+			RepoURL:         app.RepoURL,
+			RepoAuth:        app.RepoAuthMethod,
+			Branch:          app.Branch,
+			Track:           app.Track,
+			ComposePath:     app.ComposePath,
+			WatchPaths:      strings.Join(app.WatchPaths, ", "),
+			Profiles:        strings.Join(app.Profiles, ", "),
+			EnvFile:         app.EnvFile,
+			PollInterval:    app.PollInterval,
+			PreviewTTL:      app.PreviewTTL,
+			Priority:        app.Priority,
+			RequireApproval: app.RequireApproval,
+			PinImages:       app.PinImages,
+			FreezeWindows:   strings.Join(app.FreezeWindows, "\n"),
+			DockerHost:      app.DockerHost,
+			ServiceEnvs:     envVars,
+			GateScript:      app.GateScript,
+			PreDeploy:       strings.Join(app.PreDeploy, "\n"),
+			PostDeploy:      strings.Join(app.PostDeploy, "\n"),
+
+			NotifyWebhooks:  strings.Join(app.NotifyWebhooks, "\n"),
+			SlackWebhookURL: app.SlackWebhookURL,
+			SlackChannel:    app.SlackChannel,
+			NotifyEvents:    app.NotifyEvents,
+			QuietHours:      app.QuietHours,
 		},
 		App: AppDetailView{
 			ID: app.ID,
@@ -208,12 +376,19 @@ func (h *Handler) ServeAppDetailFragment(w http.ResponseWriter, r *http.Request)
 
 // ServeAppsFragment handles the HTMX request for the apps list.
 func (h *Handler) ServeAppsFragment(w http.ResponseWriter, r *http.Request) {
-	apps := h.Registry.List()
-
-	// Sort apps by name for consistent display
-	sort.Slice(apps, func(i, j int) bool {
-		return apps[i].Name < apps[j].Name
-	})
+	filter, err := parseAppsFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The chips are counted over every app matching the search, so they are
+	// applied here rather than in the query.
+	apps, _, err := h.Registry.Query(store.AppQuery{Search: filter.Search, Summary: true})
+	if err != nil {
+		http.Error(w, "Failed to load apps", http.StatusInternalServerError)
+		return
+	}
+	apps = filter.apply(apps)
 
 	viewModels := make([]AppView, len(apps))
 	for i, app := range apps {
@@ -221,7 +396,8 @@ func (h *Handler) ServeAppsFragment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := AppsPageData{
-		Apps: viewModels,
+		Apps:   viewModels,
+		Filter: filter,
 	}
 
 	if err := h.Tmpl.ExecuteTemplate(w, "apps-list", data); err != nil {
@@ -245,12 +421,32 @@ func (h *Handler) HandleEditApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	form := AppFormData{
-		Name:        strings.TrimSpace(r.FormValue("name")),
-		RepoURL:     app.RepoURL,        // RepoURL is not editable
-		RepoAuth:    app.RepoAuthMethod, // RepoAuth is not editable
-		Branch:      strings.TrimSpace(r.FormValue("branch")),
-		ComposePath: strings.TrimSpace(r.FormValue("compose_path")),
-		ServiceEnvs: make(map[string]string),
+		Name:            strings.TrimSpace(r.FormValue("name")),
+		RepoURL:         app.RepoURL, // RepoURL is not editable
+		RepoAuth:        strings.TrimSpace(r.FormValue("repo_auth_method")),
+		DeployKey:       strings.TrimSpace(r.FormValue("deploy_key")),
+		DockerHost:      app.DockerHost, // DockerHost is not editable
+		Branch:          strings.TrimSpace(r.FormValue("branch")),
+		Track:           strings.TrimSpace(r.FormValue("track")),
+		ComposePath:     strings.TrimSpace(r.FormValue("compose_path")),
+		WatchPaths:      strings.TrimSpace(r.FormValue("watch_paths")),
+		Profiles:        strings.TrimSpace(r.FormValue("profiles")),
+		EnvFile:         strings.TrimSpace(r.FormValue("env_file")),
+		PreviewTTL:      strings.TrimSpace(r.FormValue("preview_ttl")),
+		Priority:        strings.TrimSpace(r.FormValue("priority")),
+		RequireApproval: r.FormValue("require_approval") == "on",
+		PinImages:       r.FormValue("pin_images") == "on",
+		FreezeWindows:   strings.TrimSpace(r.FormValue("freeze_windows")),
+		ServiceEnvs:     make(map[string]string),
+		GateScript:      strings.ReplaceAll(r.FormValue("gate_script"), "\r\n", "\n"),
+		PreDeploy:       strings.TrimSpace(r.FormValue("pre_deploy")),
+		PostDeploy:      strings.TrimSpace(r.FormValue("post_deploy")),
+
+		NotifyWebhooks:  strings.TrimSpace(r.FormValue("notify_webhooks")),
+		SlackWebhookURL: strings.TrimSpace(r.FormValue("slack_webhook_url")),
+		SlackChannel:    strings.TrimSpace(r.FormValue("slack_channel")),
+		NotifyEvents:    r.Form["notify_events"],
+		QuietHours:      strings.TrimSpace(r.FormValue("quiet_hours")),
 	}
 
 	// Parse poll_interval
@@ -270,6 +466,9 @@ func (h *Handler) HandleEditApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set defaults if missing
+	if form.RepoAuth == "" {
+		form.RepoAuth = app.RepoAuthMethod
+	}
 	if form.Branch == "" {
 		form.Branch = "main"
 	}
@@ -283,12 +482,59 @@ func (h *Handler) HandleEditApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update the app
-	if err := h.Registry.UpdateApp(id, form.Name, form.Branch, form.ComposePath, pollInterval, form.ServiceEnvs); err != nil {
+	watchPaths := splitList(form.WatchPaths)
+	profiles := splitList(form.Profiles)
+	webhooks := splitList(form.NotifyWebhooks)
+	notifyEvents := append([]string{}, form.NotifyEvents...)
+	// Commands may contain commas, so only lines separate them.
+	preDeploy := strings.Split(form.PreDeploy, "\n")
+	postDeploy := strings.Split(form.PostDeploy, "\n")
+	freezeWindows := strings.Split(form.FreezeWindows, "\n")
+	update := controller.AppUpdate{
+		Name:            &form.Name,
+		Branch:          &form.Branch,
+		Track:           &form.Track,
+		ComposePath:     &form.ComposePath,
+		WatchPaths:      &watchPaths,
+		Profiles:        &profiles,
+		EnvFile:         &form.EnvFile,
+		PollInterval:    &pollInterval,
+		PreviewTTL:      &form.PreviewTTL,
+		Priority:        &form.Priority,
+		RequireApproval: &form.RequireApproval,
+		PinImages:       &form.PinImages,
+		FreezeWindows:   &freezeWindows,
+		GateScript:      &form.GateScript,
+		PreDeploy:       &preDeploy,
+		PostDeploy:      &postDeploy,
+		RepoAuthMethod:  &form.RepoAuth,
+		DeployKey:       &form.DeployKey,
+		ServiceEnvs:     form.ServiceEnvs,
+
+		NotifyWebhooks:  &webhooks,
+		SlackWebhookURL: &form.SlackWebhookURL,
+		SlackChannel:    &form.SlackChannel,
+		NotifyEvents:    &notifyEvents,
+		QuietHours:      &form.QuietHours,
+	}
+	if err := h.Registry.UpdateApp(id, update); err != nil {
 		h.renderEditAppPage(w, http.StatusConflict, id, form, err.Error())
 		return
 	}
 
-	http.Redirect(w, r, "/ui/apps/"+id, http.StatusSeeOther)
+	entry := controller.NewAuditEntry(r, controller.AuditActionUpdate, id)
+	if updated, err := h.Registry.Get(id); err == nil {
+		entry.Changes = controller.DiffAppFields(app, updated)
+	}
+	if form.DeployKey != "" {
+		if entry.Changes == nil {
+			entry.Changes = make(map[string]api.FieldChange)
+		}
+		entry.Changes["deploy_key"] = api.FieldChange{From: "(redacted)", To: "(redacted)"}
+	}
+	_ = h.Registry.RecordAudit(entry)
+
+	http.Redirect(w, r, h.BasePath+"/ui/apps/"+id, http.StatusSeeOther)
 }
 
 // HandleAddApp processes the form submission to add a new app.
@@ -299,13 +545,23 @@ func (h *Handler) HandleAddApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	form := AppFormData{
-		Name:        strings.TrimSpace(r.FormValue("name")),
-		RepoURL:     strings.TrimSpace(r.FormValue("repo_url")),
-		RepoAuth:    strings.TrimSpace(r.FormValue("repo_auth_method")),
-		DeployKey:   strings.TrimSpace(r.FormValue("deploy_key")),
-		Branch:      strings.TrimSpace(r.FormValue("branch")),
-		ComposePath: strings.TrimSpace(r.FormValue("compose_path")),
-		ServiceEnvs: make(map[string]string),
+		Name:            strings.TrimSpace(r.FormValue("name")),
+		RepoURL:         strings.TrimSpace(r.FormValue("repo_url")),
+		RepoAuth:        strings.TrimSpace(r.FormValue("repo_auth_method")),
+		DeployKey:       strings.TrimSpace(r.FormValue("deploy_key")),
+		Branch:          strings.TrimSpace(r.FormValue("branch")),
+		Track:           strings.TrimSpace(r.FormValue("track")),
+		ComposePath:     strings.TrimSpace(r.FormValue("compose_path")),
+		WatchPaths:      strings.TrimSpace(r.FormValue("watch_paths")),
+		Profiles:        strings.TrimSpace(r.FormValue("profiles")),
+		EnvFile:         strings.TrimSpace(r.FormValue("env_file")),
+		PreviewTTL:      strings.TrimSpace(r.FormValue("preview_ttl")),
+		Priority:        strings.TrimSpace(r.FormValue("priority")),
+		RequireApproval: r.FormValue("require_approval") == "on",
+		PinImages:       r.FormValue("pin_images") == "on",
+		FreezeWindows:   strings.TrimSpace(r.FormValue("freeze_windows")),
+		DockerHost:      strings.TrimSpace(r.FormValue("docker_host")),
+		ServiceEnvs:     make(map[string]string),
 	}
 
 	// Parse service env vars from the form: env_service_X=name and env_value_X=content
@@ -340,11 +596,21 @@ func (h *Handler) HandleAddApp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	app := &controller.App{
-		Name:           form.Name,
-		RepoURL:        form.RepoURL,
-		RepoAuthMethod: form.RepoAuth,
-		Branch:         form.Branch,
-		ComposePath:    form.ComposePath,
+		Name:            form.Name,
+		RepoURL:         form.RepoURL,
+		RepoAuthMethod:  form.RepoAuth,
+		Branch:          form.Branch,
+		Track:           form.Track,
+		ComposePath:     form.ComposePath,
+		WatchPaths:      splitList(form.WatchPaths),
+		Profiles:        splitList(form.Profiles),
+		EnvFile:         form.EnvFile,
+		PreviewTTL:      form.PreviewTTL,
+		Priority:        form.Priority,
+		RequireApproval: form.RequireApproval,
+		PinImages:       form.PinImages,
+		FreezeWindows:   strings.Split(form.FreezeWindows, "\n"),
+		DockerHost:      form.DockerHost,
 	}
 
 	if err := h.Registry.AddWithDeployKeyAndEnvs(app, deployKey, form.ServiceEnvs); err != nil {
@@ -352,12 +618,16 @@ func (h *Handler) HandleAddApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	entry := controller.NewAuditEntry(r, controller.AuditActionCreate, app.ID)
+	entry.Changes = controller.DiffAppFields(nil, app)
+	_ = h.Registry.RecordAudit(entry)
+
 	if isHTMXRequest(r) {
 		h.ServeAppsFragment(w, r)
 		return
 	}
 
-	http.Redirect(w, r, "/ui/apps/"+app.ID, http.StatusSeeOther)
+	http.Redirect(w, r, h.BasePath+"/ui/apps/"+app.ID, http.StatusSeeOther)
 }
 
 func (h *Handler) loadAppDetail(r *http.Request, id string) (AppDetailView, error) {
@@ -367,6 +637,9 @@ func (h *Handler) loadAppDetail(r *http.Request, id string) (AppDetailView, erro
 	}
 
 	detail := toAppDetailView(app)
+	if revisions, err := h.Registry.ListRevisions(app.ID, revisionHistoryLimit); err == nil {
+		detail.Revisions = toRevisionViews(revisions)
+	}
 
 	// Fetch runtime container information if executor is available.
 	if h.Executor != nil {
@@ -375,6 +648,10 @@ func (h *Handler) loadAppDetail(r *http.Request, id string) (AppDetailView, erro
 		if inspectErr == nil {
 			enrichWithContainerData(&detail, containers)
 		}
+		if snapshot, ok := h.Stats.Snapshot(app.ID); ok {
+			enrichWithStats(&detail, snapshot)
+		}
+		detail.Graph = toGraphView(h.serviceGraph(r.Context(), app), detail.Services)
 	}
 
 	return detail, nil
@@ -423,11 +700,14 @@ func enrichWithContainerData(detail *AppDetailView, containers []compose.Service
 			unhealthyCount++
 		}
 		detail.Services = append(detail.Services, ServiceView{
-			Service: c.Service,
-			Image:   c.Image,
-			Status:  c.Status,
-			Health:  c.Health,
-			Ports:   c.Ports,
+			Service:  c.Service,
+			Name:     c.Name,
+			Image:    c.Image,
+			Status:   c.Status,
+			Health:   c.Health,
+			ExitCode: c.ExitCode,
+			Ports:    c.Ports,
+			Restarts: c.RestartCount,
 		})
 	}
 	detail.HealthLabel = healthLabel(detail.ContainerCount, detail.RunningCount, unhealthyCount)
@@ -444,7 +724,9 @@ func toAppView(app *controller.App) AppView {
 		RepoURL:             app.RepoURL,
 		RepoShort:           shortRepoURL(app.RepoURL),
 		Branch:              app.Branch,
+		Track:               app.Track,
 		Status:              app.Status,
+		Paused:              app.Paused,
 		LastSyncAt:          formatTime(app.LastSyncAt),
 		LastSyncAtRelative:  relativeTime(app.LastSyncAt),
 		SyncedCommitShort:   shortHash(app.LastSyncedCommit),
@@ -464,17 +746,39 @@ func toAppDetailView(app *controller.App) AppDetailView {
 		RepoURL:                 app.RepoURL,
 		RepoAuth:                fallbackString(app.RepoAuthMethod, "public"),
 		Branch:                  app.Branch,
+		Track:                   app.Track,
 		ComposePath:             app.ComposePath,
+		WatchPaths:              app.WatchPaths,
+		Profiles:                app.Profiles,
+		EnvFile:                 app.EnvFile,
 		PollInterval:            app.PollInterval,
+		PreviewTTL:              app.PreviewTTL,
+		PreviewExpires:          previewExpires(app),
+		Priority:                app.Priority,
+		RequireApproval:         app.RequireApproval,
+		PinImages:               app.PinImages,
+		FreezeWindows:           app.FreezeWindows,
+		DockerHost:              app.DockerHost,
+		FrozenUntil:             frozenUntil(app),
+		PreDeploy:               app.PreDeploy,
+		PostDeploy:              app.PostDeploy,
+		SigningKeys:             signing.Fingerprints(app.SigningKeys),
+		NotifyTargets:           notifyTargets(app),
+		NotifyEvents:            strings.Join(app.NotifyEvents, ", "),
+		QuietHours:              app.QuietHours,
 		LastSeenCommit:          fallbackString(app.LastSeenCommit, "n/a"),
 		LastSeenCommitMessage:   fallbackString(app.LastSeenCommitMessage, "n/a"),
 		LastSeenCommitShort:     shortHash(app.LastSeenCommit),
+		DesiredCommit:           toCommitView(app.LastSeenCommitInfo),
 		LastSyncedCommit:        fallbackString(app.LastSyncedCommit, "n/a"),
 		LastSyncedCommitMessage: fallbackString(app.LastSyncedCommitMessage, "n/a"),
 		LastSyncedCommitShort:   shortHash(app.LastSyncedCommit),
 		LastSyncOutput:          strings.TrimSpace(app.LastSyncOutput),
 		LastSyncError:           strings.TrimSpace(app.LastSyncError),
+		SyncFailures:            app.SyncFailures,
+		NextRetry:               nextRetry(app.NextRetryAt),
 		Status:                  app.Status,
+		Paused:                  app.Paused,
 		LastSyncAt:              formatTime(app.LastSyncAt),
 		LastSyncAtRelative:      relativeTime(app.LastSyncAt),
 		InSync:                  inSync,
@@ -482,6 +786,61 @@ func toAppDetailView(app *controller.App) AppDetailView {
 	}
 }
 
+func toCommitView(info *api.CommitInfo) *CommitView {
+	if info == nil || info.Author == "" {
+		return nil
+	}
+	view := &CommitView{
+		Author:              info.Author,
+		CommittedAt:         formatTime(info.CommittedAt),
+		CommittedAtRelative: relativeTime(info.CommittedAt),
+		BaseShort:           shortHash(info.Base),
+		FilesChanged:        info.FilesChanged,
+		Additions:           info.Additions,
+		Deletions:           info.Deletions,
+		Files:               info.Files,
+		MoreFiles:           info.FilesChanged - len(info.Files),
+	}
+	if info.Committer != info.Author {
+		view.Committer = info.Committer
+	}
+	if _, body, found := strings.Cut(info.Message, "\n"); found {
+		view.Body = strings.TrimSpace(body)
+	}
+	return view
+}
+
+// revisionHistoryLimit caps the history shown on the app detail page.
+const revisionHistoryLimit = 50
+
+func toRevisionViews(revisions []*api.AppRevision) []RevisionView {
+	views := make([]RevisionView, 0, len(revisions))
+	for _, revision := range revisions {
+		view := RevisionView{
+			Revision:   revision.Revision,
+			Action:     "updated",
+			Actor:      revision.Actor,
+			Source:     revision.Source,
+			At:         formatTime(revision.CreatedAt),
+			AtRelative: relativeTime(revision.CreatedAt),
+		}
+		if revision.Action == controller.AuditActionCreate {
+			view.Action = "created"
+		}
+		fields := make([]string, 0, len(revision.Changes))
+		for field := range revision.Changes {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			change := revision.Changes[field]
+			view.Changes = append(view.Changes, ChangeView{Field: field, From: change.From, To: change.To})
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
 func formatTime(value time.Time) string {
 	if value.IsZero() {
 		return "n/a"
@@ -489,6 +848,40 @@ func formatTime(value time.Time) string {
 	return value.Format(time.RFC3339)
 }
 
+func previewExpires(app *controller.App) string {
+	expiresAt, ok := controller.PreviewExpiry(app)
+	if !ok {
+		return ""
+	}
+	if !time.Now().Before(expiresAt) {
+		return "on the next sweep"
+	}
+	return "at " + formatTime(expiresAt)
+}
+
+// frozenUntil describes when the freeze holding app's new commit ends, or
+// returns "" when nothing is held.
+func frozenUntil(app *controller.App) string {
+	if app.LastSeenCommit == "" || app.LastSeenCommit == app.LastSyncedCommit || app.Status == controller.StatusAwaitingApproval {
+		return ""
+	}
+	until := controller.FrozenUntil(app, time.Now())
+	if until.IsZero() {
+		return ""
+	}
+	return formatTime(until)
+}
+
+func nextRetry(at *time.Time) string {
+	if at == nil {
+		return ""
+	}
+	if !at.After(time.Now()) {
+		return "on the next reconcile"
+	}
+	return "at " + formatTime(*at)
+}
+
 func relativeTime(value time.Time) string {
 	if value.IsZero() {
 		return "never"
@@ -562,6 +955,37 @@ func shortRepoURL(repoURL string) string {
 	return repoURL
 }
 
+// splitList splits a comma- or newline-separated form value.
+// notifyTargets summarises an app's own notification targets without
+// revealing webhook URLs, which may embed credentials.
+func notifyTargets(app *controller.App) string {
+	var targets []string
+	switch len(app.NotifyWebhooks) {
+	case 0:
+	case 1:
+		targets = append(targets, "1 webhook")
+	default:
+		targets = append(targets, fmt.Sprintf("%d webhooks", len(app.NotifyWebhooks)))
+	}
+	if app.SlackWebhookURL != "" {
+		targets = append(targets, "Slack webhook")
+	}
+	if app.SlackChannel != "" {
+		targets = append(targets, "Slack "+app.SlackChannel)
+	}
+	return strings.Join(targets, ", ")
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func fallbackString(value, fallback string) string {
 	if strings.TrimSpace(value) == "" {
 		return fallback
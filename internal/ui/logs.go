@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conops/conops/internal/compose"
+	"github.com/go-chi/chi/v5"
+)
+
+// logViewerTail is how many earlier lines of a service's log the log viewer
+// shows before following it.
+const logViewerTail = 500
+
+// syncLogPollInterval is how often the sync log stream looks for new
+// transcript output. Syncs store their progress every two seconds.
+const syncLogPollInterval = time.Second
+
+// logStreamHeartbeat keeps idle log streams from being closed by proxies.
+const logStreamHeartbeat = 15 * time.Second
+
+// ServeSyncLogStream handles the log viewer's server-sent event stream of
+// the app's sync transcript. It sends the transcript as "reset" and then the
+// output added to it as "append"; a new sync that replaces the transcript
+// starts with another "reset". "status" events follow the app's status.
+func (h *Handler) ServeSyncLogStream(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	events, err := newEventStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ticker := time.NewTicker(syncLogPollInterval)
+	defer ticker.Stop()
+	sent, status := "", ""
+	first := true
+	for {
+		app, err := h.Registry.Get(id)
+		if err != nil {
+			_ = events.send("status", "deleted")
+			return
+		}
+		output := app.LastSyncOutput
+		switch {
+		case first || !strings.HasPrefix(output, sent):
+			err = events.send("reset", output)
+		case output != sent:
+			err = events.send("append", output[len(sent):])
+		}
+		if err == nil && app.Status != status {
+			err = events.send("status", app.Status)
+		}
+		if err == nil {
+			err = events.keepAlive()
+		}
+		if err != nil {
+			return
+		}
+		sent, status, first = output, app.Status, false
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeServiceLogStream handles the log viewer's server-sent event stream of
+// a service's container logs: its recent lines as "append" events, then new
+// lines as they are logged. The stream ends with an "end" event when the
+// containers stop, or a "failure" event.
+func (h *Handler) ServeServiceLogStream(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	service := chi.URLParam(r, "service")
+	app, err := h.Registry.Get(id)
+	if err != nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	if h.LogStreamer == nil {
+		http.Error(w, "The runtime cannot stream logs", http.StatusNotImplemented)
+		return
+	}
+	containers, err := h.Executor.InspectProjectContainers(r.Context(), compose.ProjectNameForApp(app.ID))
+	if err != nil {
+		http.Error(w, "Failed to inspect containers", http.StatusInternalServerError)
+		return
+	}
+	if !slices.ContainsFunc(containers, func(c compose.ServiceContainer) bool { return c.Service == service }) {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+	events, err := newEventStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Services often log their own config; mask the app's secrets in it.
+	lines := &eventLineWriter{events: events, event: "append"}
+	masked := h.Registry.Redactor(app.ID).Writer(lines)
+	err = h.LogStreamer.StreamServiceLogs(r.Context(), app.ID, app.ComposePath, app.Profiles, service, logViewerTail, true, masked)
+	if closeErr := masked.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = lines.flush()
+	}
+	switch {
+	case errors.Is(err, context.Canceled), r.Context().Err() != nil:
+	case err != nil:
+		// The headers are gone; tell the viewer why its log ended. "error"
+		// is taken by EventSource for its own connection errors.
+		_ = events.send("failure", err.Error())
+	default:
+		_ = events.send("end", "")
+	}
+}
+
+// logServices lists the services the log viewer can follow, in the order of
+// the services table.
+func (h *Handler) logServices(services []ServiceView) []string {
+	if h.LogStreamer == nil {
+		return nil
+	}
+	var names []string
+	for _, service := range services {
+		if !slices.Contains(names, service.Service) {
+			names = append(names, service.Service)
+		}
+	}
+	return names
+}
+
+// eventStream writes server-sent events, flushing each one.
+type eventStream struct {
+	w        io.Writer
+	rc       *http.ResponseController
+	lastSent time.Time
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, error) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming is not supported: %w", err)
+	}
+	return &eventStream{w: w, rc: rc, lastSent: time.Now()}, nil
+}
+
+// send writes one event. Each line of data goes on a data field, which the
+// browser joins back with newlines.
+func (s *eventStream) send(event, data string) error {
+	var b strings.Builder
+	b.WriteString("event: " + event + "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// keepAlive writes a comment when nothing was sent for a while.
+func (s *eventStream) keepAlive() error {
+	if time.Since(s.lastSent) < logStreamHeartbeat {
+		return nil
+	}
+	return s.write(": keep-alive\n\n")
+}
+
+func (s *eventStream) write(text string) error {
+	if _, err := io.WriteString(s.w, text); err != nil {
+		return err
+	}
+	s.lastSent = time.Now()
+	return s.rc.Flush()
+}
+
+// eventLineWriter sends what is written to it as events of whole lines.
+type eventLineWriter struct {
+	events  *eventStream
+	event   string
+	pending []byte
+}
+
+func (w *eventLineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexByte(w.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if err := w.events.send(w.event, string(w.pending[:end+1])); err != nil {
+		return 0, err
+	}
+	w.pending = append(w.pending[:0], w.pending[end+1:]...)
+	return len(p), nil
+}
+
+func (w *eventLineWriter) flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.events.send(w.event, string(w.pending)+"\n")
+	w.pending = w.pending[:0]
+	return err
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/conops/conops/internal/api"
+)
+
+// enrichWithStats adds the usage of each container in snapshot to the
+// matching row of the services table.
+func enrichWithStats(detail *AppDetailView, snapshot api.AppStats) {
+	byContainer := make(map[string]api.ServiceStats, len(snapshot.Services))
+	for _, s := range snapshot.Services {
+		byContainer[s.Container] = s
+	}
+	for i := range detail.Services {
+		s, ok := byContainer[detail.Services[i].Name]
+		if !ok {
+			continue
+		}
+		view := &detail.Services[i]
+		view.HasStats = true
+		view.Restarts = s.RestartCount
+		if s.Running {
+			view.CPU = strconv.FormatFloat(s.CPUPercent, 'f', 1, 64) + "%"
+			view.Memory = formatBytes(s.MemoryBytes)
+			if s.MemoryLimitBytes > 0 {
+				view.Memory += " / " + formatBytes(s.MemoryLimitBytes)
+			}
+		}
+	}
+	detail.StatsAt = relativeTime(snapshot.CollectedAt)
+}
+
+// formatBytes prints a size in binary units, like docker stats does.
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
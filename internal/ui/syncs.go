@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// syncHistoryPageSize is how many syncs the timeline loads at a time.
+const syncHistoryPageSize = 10
+
+// SyncView is one sync in the timeline on the app detail page.
+type SyncView struct {
+	ID                string
+	Commit            string
+	CommitShort       string // empty when the branch head was deployed
+	Status            string
+	Trigger           string
+	Actor             string
+	Error             string
+	RollbackOf        string
+	StartedAt         string
+	StartedAtRelative string
+	Duration          string
+}
+
+// SyncStepView is one step of a sync's transcript, shown as a collapsible
+// section.
+type SyncStepView struct {
+	Name     string
+	Failed   bool
+	Duration string
+	Log      string
+}
+
+// SyncHistoryPage is one page of the timeline.
+type SyncHistoryPage struct {
+	AppID      string
+	Syncs      []SyncView
+	First      bool // the page starts the timeline
+	HasMore    bool
+	NextOffset int
+}
+
+// ServeSyncHistory handles the HTMX request for a page of the app's sync
+// history, newest first.
+func (h *Handler) ServeSyncHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.Registry.Get(id); err != nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	offset := 0
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	// One more than a page tells whether there is a next one.
+	records, err := h.Registry.ListSyncHistory(id, syncHistoryPageSize+1, offset)
+	if err != nil {
+		http.Error(w, "Failed to load sync history", http.StatusInternalServerError)
+		return
+	}
+	page := SyncHistoryPage{AppID: id, First: offset == 0}
+	if len(records) > syncHistoryPageSize {
+		records = records[:syncHistoryPageSize]
+		page.HasMore = true
+		page.NextOffset = offset + syncHistoryPageSize
+	}
+	for _, record := range records {
+		page.Syncs = append(page.Syncs, toSyncView(record))
+	}
+	if err := h.Tmpl.ExecuteTemplate(w, "sync-history-page", page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ServeSyncOutput handles the HTMX request for the transcript of one sync,
+// step by step.
+func (h *Handler) ServeSyncOutput(w http.ResponseWriter, r *http.Request) {
+	record, err := h.Registry.GetSyncRecord(chi.URLParam(r, "id"), chi.URLParam(r, "syncID"))
+	if err != nil {
+		if errors.Is(err, store.ErrSyncRecordNotFound) {
+			http.Error(w, "Sync not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load sync output", http.StatusInternalServerError)
+		return
+	}
+	steps := make([]SyncStepView, 0, len(record.Steps))
+	for _, step := range record.Steps {
+		steps = append(steps, SyncStepView{
+			Name:     step.Name,
+			Failed:   step.Status == "failed",
+			Duration: syncDuration(time.Duration(step.DurationMs) * time.Millisecond),
+			Log:      step.Log,
+		})
+	}
+	if err := h.Tmpl.ExecuteTemplate(w, "sync-output", steps); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func toSyncView(record *api.SyncRecord) SyncView {
+	view := SyncView{
+		ID:                record.ID,
+		Commit:            record.Commit,
+		Status:            record.Status,
+		Trigger:           record.Trigger,
+		Actor:             record.Actor,
+		Error:             record.Error,
+		RollbackOf:        record.RollbackOf,
+		StartedAt:         formatTime(record.StartedAt),
+		StartedAtRelative: relativeTime(record.StartedAt),
+		Duration:          syncDuration(record.FinishedAt.Sub(record.StartedAt)),
+	}
+	if record.Commit != "" {
+		view.CommitShort = shortHash(record.Commit)
+	}
+	return view
+}
+
+func syncDuration(d time.Duration) string {
+	if d < time.Second {
+		return "<1s"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,137 @@
+// Package version reports the release the controller was built from and the
+// compatibility range it advertises to clients.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Version is set at build time with
+// -ldflags "-X github.com/conops/conops/internal/version.Version=v1.2.3".
+var Version = "dev"
+
+// Commit and Date identify the source and time of the build. They are set
+// at build time like Version; otherwise Build falls back to the VCS stamp
+// the go tool embeds.
+var (
+	Commit = ""
+	Date   = ""
+)
+
+// Build returns the commit and build time of the binary, and the Go
+// release it was built with. Commit and date are empty when unknown.
+func Build() (commit, date, goVersion string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return Commit, Date, runtime.Version()
+	}
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.time":
+			date = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	commit = revision
+	if Commit != "" {
+		commit = Commit
+	}
+	if Date != "" {
+		date = Date
+	}
+	return commit, date, info.GoVersion
+}
+
+// APIVersion is bumped whenever the REST API changes shape in a way older
+// clients cannot handle.
+const APIVersion = 1
+
+// MinClientVersion is the oldest conops-ctl release that speaks APIVersion.
+const MinClientVersion = "v0.1.0"
+
+// Release channels.
+const (
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+	ChannelDev        = "dev"
+)
+
+// Headers set on every API response so clients can detect version skew
+// without an extra request.
+const (
+	HeaderVersion          = "X-Conops-Version"
+	HeaderAPIVersion       = "X-Conops-API-Version"
+	HeaderMinClientVersion = "X-Conops-Min-Client-Version"
+)
+
+// Channel classifies v as a stable release, a prerelease (v1.2.0-rc.1) or a
+// development build.
+func Channel(v string) string {
+	if _, ok := parse(v); !ok {
+		return ChannelDev
+	}
+	if strings.Contains(v, "-") {
+		return ChannelPrerelease
+	}
+	return ChannelStable
+}
+
+// Compare orders two release versions, returning -1, 0 or 1. ok is false
+// when either is not a semantic version, such as a "dev" build. Prerelease
+// suffixes are ignored.
+func Compare(a, b string) (result int, ok bool) {
+	left, ok := parse(a)
+	if !ok {
+		return 0, false
+	}
+	right, ok := parse(b)
+	if !ok {
+		return 0, false
+	}
+	for i := range left {
+		switch {
+		case left[i] < right[i]:
+			return -1, true
+		case left[i] > right[i]:
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// SameMinor reports whether a and b share a major and minor version.
+func SameMinor(a, b string) bool {
+	left, okLeft := parse(a)
+	right, okRight := parse(b)
+	return okLeft && okRight && left[0] == right[0] && left[1] == right[1]
+}
+
+func parse(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,477 @@
+// Package client talks to a ConOps controller over the API described in
+// internal/api/openapi.yaml, which the controller also serves at
+// /api/v1/openapi.yaml.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls one controller. The zero HTTPClient uses a client with a
+// one minute timeout.
+type Client struct {
+	// BaseURL is the controller's address, e.g. "http://localhost:8080".
+	BaseURL string
+	// Token is sent as a bearer token when set: the admin token or an app
+	// token.
+	Token      string
+	HTTPClient *http.Client
+	UserAgent  string
+}
+
+// New returns a client for the controller at baseURL.
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Error is a response with a 4xx or 5xx status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ListApps returns a page of apps. It needs the admin token.
+func (c *Client) ListApps(ctx context.Context, opts ListAppsOptions) (*Page, error) {
+	query := url.Values{}
+	if len(opts.Statuses) > 0 {
+		query.Set("status", strings.Join(opts.Statuses, ","))
+	}
+	if opts.Search != "" {
+		query.Set("q", opts.Search)
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	if opts.Summary {
+		query.Set("fields", "summary")
+	}
+
+	page := &Page{}
+	resp, err := c.call(ctx, http.MethodGet, "/apps/", query, nil, &page.Apps)
+	if err != nil {
+		return nil, err
+	}
+	if page.Total, err = strconv.Atoi(resp.Header.Get("X-Total-Count")); err != nil {
+		page.Total = len(page.Apps)
+	}
+	return page, nil
+}
+
+// GetApp returns one app.
+func (c *Client) GetApp(ctx context.Context, id string) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id), nil, nil, &app)
+	return &app, err
+}
+
+// RegisterApp registers an app and returns it with its ID.
+func (c *Client) RegisterApp(ctx context.Context, req RegisterAppRequest) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPost, "/apps/", nil, req, &app)
+	return &app, err
+}
+
+// UpdateApp changes the fields set in req and returns the updated app.
+func (c *Client) UpdateApp(ctx context.Context, id string, req UpdateAppRequest) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPatch, "/apps/"+url.PathEscape(id), nil, req, &app)
+	return &app, err
+}
+
+// DeleteApp removes an app and its containers, and its volumes when
+// removeVolumes is set.
+func (c *Client) DeleteApp(ctx context.Context, id string, removeVolumes bool) error {
+	var query url.Values
+	if removeVolumes {
+		query = url.Values{"volumes": {"true"}}
+	}
+	_, err := c.call(ctx, http.MethodDelete, "/apps/"+url.PathEscape(id), query, nil, nil)
+	return err
+}
+
+// GetAppManifest returns what a sync of the app would run.
+func (c *Client) GetAppManifest(ctx context.Context, id string) (*AppManifest, error) {
+	var manifest AppManifest
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/manifest", nil, nil, &manifest)
+	return &manifest, err
+}
+
+// GetAppDiff returns the difference between the deployed and the target
+// compose configuration.
+func (c *Client) GetAppDiff(ctx context.Context, id string) (*AppDiff, error) {
+	var diff AppDiff
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/diff", nil, nil, &diff)
+	return &diff, err
+}
+
+// ListAppRevisions returns the app's configuration revisions, newest
+// first. A zero limit uses the controller's default.
+func (c *Client) ListAppRevisions(ctx context.Context, id string, limit int) ([]*AppRevision, error) {
+	var revisions []*AppRevision
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/revisions", limitQuery(limit), nil, &revisions)
+	return revisions, err
+}
+
+// ListSyncHistory returns the app's syncs, newest first.
+func (c *Client) ListSyncHistory(ctx context.Context, id string, limit int) ([]*SyncRecord, error) {
+	var records []*SyncRecord
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/history", limitQuery(limit), nil, &records)
+	return records, err
+}
+
+// GetSyncRecord returns one sync of the app with the steps it ran.
+func (c *Client) GetSyncRecord(ctx context.Context, id, syncID string) (*SyncRecord, error) {
+	var record SyncRecord
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/history/"+url.PathEscape(syncID), nil, nil, &record)
+	return &record, err
+}
+
+// GetAppHealth returns the app's health. Unlike the other calls, an app
+// in error or stopped is not an error here.
+func (c *Client) GetAppHealth(ctx context.Context, id string) (*AppHealth, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/healthz", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, responseError(resp)
+	}
+	var health AppHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &health, nil
+}
+
+// SyncApp starts deploying the app's latest commit and returns the job
+// without waiting for it; poll GetSyncJob for the outcome. When another sync
+// of the app is running, the sync is queued behind it. A standby controller
+// hands the sync to the leader and returns a job with only its ID, app and
+// actor set, which GetSyncJob does not know.
+func (c *Client) SyncApp(ctx context.Context, id string) (*SyncJob, error) {
+	var job *SyncJob
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/sync", nil, nil, &job)
+	return job, err
+}
+
+// GetSyncJob returns a sync job of the app while it is queued or running,
+// and for an hour after it finished with its outcome.
+func (c *Client) GetSyncJob(ctx context.Context, appID, jobID string) (*SyncJob, error) {
+	var job SyncJob
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(appID)+"/syncs/"+url.PathEscape(jobID), nil, nil, &job)
+	return &job, err
+}
+
+// ApproveApp approves the commit held for approval; a non-empty commit
+// must match it.
+func (c *Client) ApproveApp(ctx context.Context, id, commit string) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/approve", nil, map[string]string{"commit": commit}, &app)
+	return &app, err
+}
+
+// StopApp takes the app's containers down and pauses reconciling it.
+func (c *Client) StopApp(ctx context.Context, id string) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/down", nil, nil, &app)
+	return &app, err
+}
+
+// StartApp resumes a stopped app.
+func (c *Client) StartApp(ctx context.Context, id string) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/up", nil, nil, &app)
+	return &app, err
+}
+
+// PauseApp holds the app's automatic syncs; its containers keep running.
+func (c *Client) PauseApp(ctx context.Context, id string) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/pause", nil, nil, &app)
+	return &app, err
+}
+
+// ResumeApp hands a paused app back to the reconciler.
+func (c *Client) ResumeApp(ctx context.Context, id string) (*App, error) {
+	var app App
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/resume", nil, nil, &app)
+	return &app, err
+}
+
+// RollbackApp pauses the app and starts deploying commit, a hash or tag, or
+// the commit synced before the current one when commit is empty. It returns
+// without waiting for the deploy; poll GetSyncJob for the outcome.
+func (c *Client) RollbackApp(ctx context.Context, id, commit string) (*SyncJob, error) {
+	var job SyncJob
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/rollback", nil, map[string]string{"commit": commit}, &job)
+	return &job, err
+}
+
+// GetAppStats returns the resource usage of the app's services.
+func (c *Client) GetAppStats(ctx context.Context, id string) (*AppStats, error) {
+	var stats AppStats
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/stats", nil, nil, &stats)
+	return &stats, err
+}
+
+// ListAppContainers returns the containers of the app's compose project.
+func (c *Client) ListAppContainers(ctx context.Context, id string) ([]Container, error) {
+	var containers []Container
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/containers", nil, nil, &containers)
+	return containers, err
+}
+
+// RestartService restarts the containers of one of the app's services.
+func (c *Client) RestartService(ctx context.Context, id, service string) error {
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/services/"+url.PathEscape(service)+"/restart", nil, nil, nil)
+	return err
+}
+
+// ServiceLogs streams a service's logs until ctx ends, or until the
+// existing lines are written when follow is false. A negative tail
+// returns every line. The caller closes the reader. Following a stream
+// for longer than HTTPClient's timeout needs a client without one.
+func (c *Client) ServiceLogs(ctx context.Context, id, service string, tail int, follow bool) (io.ReadCloser, error) {
+	query := url.Values{"follow": {strconv.FormatBool(follow)}}
+	if tail < 0 {
+		query.Set("tail", "all")
+	} else {
+		query.Set("tail", strconv.Itoa(tail))
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/services/"+url.PathEscape(service)+"/logs?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// ScheduleSync schedules a sync of commit, or of the branch's latest
+// commit when commit is empty, at the given time.
+func (c *Client) ScheduleSync(ctx context.Context, id string, at time.Time, commit string) (*ScheduledSync, error) {
+	var schedule ScheduledSync
+	body := map[string]interface{}{"at": at, "commit": commit}
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/schedules", nil, body, &schedule)
+	return &schedule, err
+}
+
+// ListScheduledSyncs returns the app's pending scheduled syncs, soonest
+// first.
+func (c *Client) ListScheduledSyncs(ctx context.Context, id string) ([]*ScheduledSync, error) {
+	var schedules []*ScheduledSync
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/schedules", nil, nil, &schedules)
+	return schedules, err
+}
+
+// CancelScheduledSync cancels a scheduled sync.
+func (c *Client) CancelScheduledSync(ctx context.Context, id, scheduleID string) error {
+	_, err := c.call(ctx, http.MethodDelete, "/apps/"+url.PathEscape(id)+"/schedules/"+url.PathEscape(scheduleID), nil, nil, nil)
+	return err
+}
+
+// CreateAppToken issues a token for one app. Its secret is in Token and
+// is not shown again.
+func (c *Client) CreateAppToken(ctx context.Context, id, name string, scopes []string) (*AppToken, error) {
+	var token AppToken
+	body := map[string]interface{}{"name": name, "scopes": scopes}
+	_, err := c.call(ctx, http.MethodPost, "/apps/"+url.PathEscape(id)+"/tokens", nil, body, &token)
+	return &token, err
+}
+
+// ListAppTokens returns the app's tokens without their secrets.
+func (c *Client) ListAppTokens(ctx context.Context, id string) ([]*AppToken, error) {
+	var tokens []*AppToken
+	_, err := c.call(ctx, http.MethodGet, "/apps/"+url.PathEscape(id)+"/tokens", nil, nil, &tokens)
+	return tokens, err
+}
+
+// RevokeAppToken revokes one of the app's tokens.
+func (c *Client) RevokeAppToken(ctx context.Context, id, tokenID string) error {
+	_, err := c.call(ctx, http.MethodDelete, "/apps/"+url.PathEscape(id)+"/tokens/"+url.PathEscape(tokenID), nil, nil, nil)
+	return err
+}
+
+// ListAudit returns audit entries, newest first, of one app or of all
+// when appID is empty.
+func (c *Client) ListAudit(ctx context.Context, appID string, limit int) ([]*AuditEntry, error) {
+	query := limitQuery(limit)
+	if appID != "" {
+		query.Set("app_id", appID)
+	}
+	var entries []*AuditEntry
+	_, err := c.call(ctx, http.MethodGet, "/audit", query, nil, &entries)
+	return entries, err
+}
+
+// ListPreviews returns the preview apps and when they expire.
+func (c *Client) ListPreviews(ctx context.Context) ([]*PreviewStatus, error) {
+	var previews []*PreviewStatus
+	_, err := c.call(ctx, http.MethodGet, "/previews", nil, nil, &previews)
+	return previews, err
+}
+
+// ListSyncJobs returns running and queued syncs of one app, or of all
+// when appID is empty.
+func (c *Client) ListSyncJobs(ctx context.Context, appID string) ([]*SyncJob, error) {
+	var query url.Values
+	if appID != "" {
+		query = url.Values{"app_id": {appID}}
+	}
+	var jobs []*SyncJob
+	_, err := c.call(ctx, http.MethodGet, "/jobs", query, nil, &jobs)
+	return jobs, err
+}
+
+// Export dumps the controller's state with credentials sealed under
+// passphrase. The returned JSON is what Import takes.
+func (c *Client) Export(ctx context.Context, passphrase string) (json.RawMessage, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/export", map[string]string{"passphrase": passphrase})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Import loads an export written by Export.
+func (c *Client) Import(ctx context.Context, export json.RawMessage, passphrase string) (*ImportResult, error) {
+	var result ImportResult
+	body := map[string]interface{}{"passphrase": passphrase, "export": export}
+	_, err := c.call(ctx, http.MethodPost, "/import", nil, body, &result)
+	return &result, err
+}
+
+// RotateKey re-encrypts all credentials with a new encryption key.
+func (c *Client) RotateKey(ctx context.Context) (*KeyRotationResult, error) {
+	var result KeyRotationResult
+	_, err := c.call(ctx, http.MethodPost, "/admin/rotate-key", nil, nil, &result)
+	return &result, err
+}
+
+// GetVersion returns the controller's release and API version.
+func (c *Client) GetVersion(ctx context.Context) (*VersionInfo, error) {
+	var info VersionInfo
+	_, err := c.call(ctx, http.MethodGet, "/version", nil, nil, &info)
+	return &info, err
+}
+
+// GetAttestationKey returns the PEM-encoded key that signs deployment
+// attestations.
+func (c *Client) GetAttestationKey(ctx context.Context) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/attestation/key", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// call sends a request and decodes the data of the JSON envelope into out
+// unless out is nil.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) (*http.Response, error) {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp, responseError(resp)
+	}
+	if out == nil {
+		return resp, nil
+	}
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return resp, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+"/api/v1"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: time.Minute}
+	}
+	return httpClient.Do(req)
+}
+
+// responseError reads an error response, which is plain text.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+func limitQuery(limit int) url.Values {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
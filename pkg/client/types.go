@@ -0,0 +1,138 @@
+package client
+
+import (
+	"github.com/conops/conops/internal/api"
+	"github.com/conops/conops/internal/compose"
+)
+
+// The controller's own types, so callers outside this module can name them.
+type (
+	App           = api.App
+	CommitInfo    = api.CommitInfo
+	ChangedFile   = api.ChangedFile
+	AuditEntry    = api.AuditEntry
+	FieldChange   = api.FieldChange
+	AppSpec       = api.AppSpec
+	AppRevision   = api.AppRevision
+	PreviewStatus = api.PreviewStatus
+	AppManifest   = api.AppManifest
+	AppDiff       = api.AppDiff
+	AppHealth     = api.AppHealth
+	AppStats      = api.AppStats
+	ServiceStats  = api.ServiceStats
+	Container     = api.ServiceContainer
+	SyncJob       = api.SyncJob
+	ScheduledSync = api.ScheduledSync
+	SyncRecord    = api.SyncRecord
+	SyncStep      = api.SyncStep
+	Attestation   = api.Attestation
+	AppToken      = api.AppToken
+	VersionInfo   = api.VersionInfo
+	DockerTLS     = compose.DockerTLS
+)
+
+// RegisterAppRequest is the body of RegisterApp.
+type RegisterAppRequest struct {
+	Name           string `json:"name"`
+	RepoURL        string `json:"repo_url"`
+	RepoAuthMethod string `json:"repo_auth_method,omitempty"`
+	DeployKey      string `json:"deploy_key,omitempty"`
+	Branch         string `json:"branch,omitempty"`
+	ComposePath    string `json:"compose_path"`
+	PollInterval   string `json:"poll_interval,omitempty"`
+	// ServiceEnvs holds KEY=VALUE lines per service.
+	ServiceEnvs     map[string]string `json:"service_envs,omitempty"`
+	GateScript      string            `json:"gate_script,omitempty"`
+	NotifyWebhooks  []string          `json:"notify_webhooks,omitempty"`
+	SlackWebhookURL string            `json:"slack_webhook_url,omitempty"`
+	SlackChannel    string            `json:"slack_channel,omitempty"`
+	NotifyEvents    []string          `json:"notify_events,omitempty"`
+	QuietHours      string            `json:"quiet_hours,omitempty"`
+	SigningKeys     []string          `json:"signing_keys,omitempty"`
+	Track           string            `json:"track,omitempty"`
+	WatchPaths      []string          `json:"watch_paths,omitempty"`
+	Profiles        []string          `json:"profiles,omitempty"`
+	EnvFile         string            `json:"env_file,omitempty"`
+	PreviewTTL      string            `json:"preview_ttl,omitempty"`
+	PostDeploy      []string          `json:"post_deploy,omitempty"`
+	PreDeploy       []string          `json:"pre_deploy,omitempty"`
+	Priority        string            `json:"priority,omitempty"`
+	RequireApproval bool              `json:"require_approval,omitempty"`
+	PinImages       bool              `json:"pin_images,omitempty"`
+	FreezeWindows   []string          `json:"freeze_windows,omitempty"`
+	DockerHost      string            `json:"docker_host,omitempty"`
+	DockerTLS       *DockerTLS        `json:"docker_tls,omitempty"`
+	SOPSKey         string            `json:"sops_key,omitempty"`
+}
+
+// UpdateAppRequest is the body of UpdateApp. Nil fields are left as they
+// are; an empty value clears the setting.
+type UpdateAppRequest struct {
+	Name            *string            `json:"name,omitempty"`
+	Branch          *string            `json:"branch,omitempty"`
+	ComposePath     *string            `json:"compose_path,omitempty"`
+	PollInterval    *string            `json:"poll_interval,omitempty"`
+	ServiceEnvs     *map[string]string `json:"service_envs,omitempty"`
+	GateScript      *string            `json:"gate_script,omitempty"`
+	NotifyWebhooks  *[]string          `json:"notify_webhooks,omitempty"`
+	SlackWebhookURL *string            `json:"slack_webhook_url,omitempty"`
+	SlackChannel    *string            `json:"slack_channel,omitempty"`
+	NotifyEvents    *[]string          `json:"notify_events,omitempty"`
+	QuietHours      *string            `json:"quiet_hours,omitempty"`
+	SigningKeys     *[]string          `json:"signing_keys,omitempty"`
+	Track           *string            `json:"track,omitempty"`
+	WatchPaths      *[]string          `json:"watch_paths,omitempty"`
+	Profiles        *[]string          `json:"profiles,omitempty"`
+	EnvFile         *string            `json:"env_file,omitempty"`
+	PreviewTTL      *string            `json:"preview_ttl,omitempty"`
+	PostDeploy      *[]string          `json:"post_deploy,omitempty"`
+	PreDeploy       *[]string          `json:"pre_deploy,omitempty"`
+	Priority        *string            `json:"priority,omitempty"`
+	RequireApproval *bool              `json:"require_approval,omitempty"`
+	PinImages       *bool              `json:"pin_images,omitempty"`
+	FreezeWindows   *[]string          `json:"freeze_windows,omitempty"`
+	DockerHost      *string            `json:"docker_host,omitempty"`
+	// DockerTLS replaces the docker host's TLS material; an empty value
+	// removes it.
+	DockerTLS *DockerTLS `json:"docker_tls,omitempty"`
+	// SOPSKey replaces the key that decrypts the app's SOPS env file; an
+	// empty value removes it.
+	SOPSKey *string `json:"sops_key,omitempty"`
+}
+
+// ListAppsOptions filters, sorts and pages ListApps. Zero values list
+// every app by name.
+type ListAppsOptions struct {
+	Statuses []string
+	// Search matches the ID, name or repo URL, ignoring case.
+	Search string
+	// Sort is id, name, status or last_sync_at, prefixed with "-" to
+	// reverse.
+	Sort    string
+	Page    int
+	PerPage int
+	// Summary leaves out each app's last sync output.
+	Summary bool
+}
+
+// Page is one page of ListApps.
+type Page struct {
+	Apps []*App
+	// Total counts every app matching the filters.
+	Total int
+}
+
+// ImportResult lists the apps an import created and the ones it skipped
+// because they already exist.
+type ImportResult struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+// KeyRotationResult reports how many apps' credentials a key rotation
+// re-encrypted and where the new key is kept: a key file, or Vault.
+type KeyRotationResult struct {
+	Apps      int    `json:"apps"`
+	KeyFile   string `json:"key_file,omitempty"`
+	KeySource string `json:"key_source"`
+}
@@ -0,0 +1,37 @@
+// Package web holds the UI's templates and static assets. They are built
+// into the binary, so the controller serves its UI from any working
+// directory.
+package web
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// DirEnv points the controller at a web directory on disk instead of the
+// assets in the binary, e.g. to work on the templates without rebuilding.
+const DirEnv = "CONOPS_WEB_DIR"
+
+//go:embed templates static
+var embedded embed.FS
+
+// Assets returns the UI's templates and static files: those under dir when
+// it is set, else the ones built into the binary.
+func Assets(dir string) (templates, static fs.FS, err error) {
+	root := fs.FS(embedded)
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, nil, fmt.Errorf("invalid web directory: %w", err)
+		}
+		root = os.DirFS(dir)
+	}
+	if templates, err = fs.Sub(root, "templates"); err != nil {
+		return nil, nil, err
+	}
+	if static, err = fs.Sub(root, "static"); err != nil {
+		return nil, nil, err
+	}
+	return templates, static, nil
+}